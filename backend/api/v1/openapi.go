@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"social-scribe/backend/internal/services"
+	"strings"
+	"sync"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}/]+)\}`)
+
+var (
+	openAPIOnce sync.Once
+	openAPIJson []byte
+	openAPIErr  error
+)
+
+// OpenAPISpec builds an OpenAPI 3 document from the route table. Request and
+// response bodies are not described; the document lists every endpoint with
+// its auth scope, rate limit and path parameters.
+func OpenAPISpec() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, route := range Routes() {
+		path := "/api/v1" + route.Path
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+
+		operation := map[string]interface{}{
+			"operationId":     route.Name,
+			"summary":         route.Summary,
+			"x-auth-scope":    route.Auth.String(),
+			"x-rate-limit":    route.RateLimit.Requests,
+			"x-rate-window-s": int(route.RateLimit.Window.Seconds()),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{"description": "Success"},
+				"429": map[string]interface{}{"description": "Rate limit exceeded"},
+			},
+		}
+
+		parameters := []map[string]interface{}{}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		switch route.Auth {
+		case AuthUser, AuthAdminUser:
+			cookieAuth := map[string][]string{"sessionCookie": {}}
+			if route.Method != http.MethodGet {
+				cookieAuth["csrfToken"] = []string{}
+			}
+			security := []map[string][]string{cookieAuth}
+			if route.Scope != "" {
+				security = append(security, map[string][]string{"oauth2": {route.Scope}})
+			}
+			operation["security"] = security
+		case AuthAdmin:
+			operation["security"] = []map[string][]string{{"adminToken": {}}}
+		}
+
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "Social Scribe API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"sessionCookie": map[string]string{"type": "apiKey", "in": "cookie", "name": "session_token"},
+				"adminToken":    map[string]string{"type": "apiKey", "in": "header", "name": "X-Admin-Token"},
+				"csrfToken":     map[string]string{"type": "apiKey", "in": "header", "name": services.CSRFHeader},
+				"oauth2": map[string]interface{}{
+					"type": "oauth2",
+					"flows": map[string]interface{}{
+						"authorizationCode": map[string]interface{}{
+							"authorizationUrl": "/api/v1/oauth/authorize",
+							"tokenUrl":         "/api/v1/oauth/token",
+							"scopes":           services.OAuthScopes,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIJson, openAPIErr = json.Marshal(OpenAPISpec())
+	})
+	if openAPIErr != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPIJson)
+}
@@ -1,87 +1,390 @@
 package v1
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"social-scribe/backend/internal/config"
 	"social-scribe/backend/internal/handlers"
+	"social-scribe/backend/internal/metrics"
 	"social-scribe/backend/internal/middlewares"
+	"social-scribe/backend/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes() *mux.Router {
-	router := mux.NewRouter()
-	apiV1 := router.PathPrefix("/api/v1").Subrouter()
+// AuthScope is the authentication a route requires.
+type AuthScope int
 
-	// Unprotected routes
-	apiV1.HandleFunc("/user/signup", handlers.SignupUserHandler).Methods(http.MethodPost)
-	apiV1.HandleFunc("/user/login", handlers.LoginUserHandler).Methods(http.MethodPost)
-	apiV1.HandleFunc("/user/getinfo", handlers.GetUserInfoHandler).Methods(http.MethodGet)
-	// Protected routes with rate limiting
-	apiV1.Handle("/user/scheduled_posts",
-		middlewares.AuthMiddleware(100, time.Minute, http.HandlerFunc(handlers.GetUserScheduledBlogsHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+const (
+	// AuthPublic routes need no session and are rate limited per client IP.
+	AuthPublic AuthScope = iota
+	// AuthUser routes need a valid session and are rate limited per user.
+	AuthUser
+	// AuthAdmin routes need the operator admin token.
+	AuthAdmin
+	// AuthAdminUser routes need the session of a user with the admin role.
+	AuthAdminUser
+)
 
-	apiV1.Handle("/user/blogs",
-		middlewares.AuthMiddleware(200, time.Minute, http.HandlerFunc(handlers.GetUserBlogsHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+func (a AuthScope) String() string {
+	switch a {
+	case AuthPublic:
+		return "public"
+	case AuthUser:
+		return "user"
+	case AuthAdmin:
+		return "admin"
+	case AuthAdminUser:
+		return "admin-user"
+	default:
+		return "unknown"
+	}
+}
 
-	apiV1.Handle("/user/notifications",
-		middlewares.AuthMiddleware(150, time.Minute, http.HandlerFunc(handlers.GetUserNotificationsHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+// RateLimit is the number of requests allowed per window for one client.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
 
-	apiV1.Handle("/user/notifications/clear",
-		middlewares.AuthMiddleware(20, time.Minute, http.HandlerFunc(handlers.ClearUserNotificationsHandler)),
-	).Methods(http.MethodDelete, http.MethodOptions)
+func perMinute(requests int) RateLimit {
+	return RateLimit{Requests: requests, Window: time.Minute}
+}
 
-	apiV1.Handle("/blogs/schedule",
-		middlewares.AuthMiddleware(6, time.Minute, http.HandlerFunc(handlers.ScheduleBlogHandler)),
-	).Methods(http.MethodPost, http.MethodOptions)
+const defaultRouteTimeout = 15 * time.Second
 
-	apiV1.Handle("/blogs/schedule/delete",
-		middlewares.AuthMiddleware(30, time.Minute, http.HandlerFunc(handlers.GetUserSharedBlogsHandler)),
-	).Methods(http.MethodDelete, http.MethodOptions)
+// Route declares an endpoint together with the policies applied to it. Every
+// route must state its auth scope and rate limit explicitly; RegisterRoutes
+// refuses to start with an incomplete declaration.
+type Route struct {
+	Name    string
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+	Auth    AuthScope
+	// Scope lets OAuth clients holding this scope call an AuthUser route with
+	// a bearer token.
+	Scope string
+	// ConsentExempt lets users who haven't accepted the current terms write
+	// through an AuthUser route, such as accepting them or ending sessions.
+	ConsentExempt bool
+	// Writes marks a GET route that changes state, such as an OAuth callback
+	// storing the tokens, so it is refused during maintenance like other
+	// writes.
+	Writes    bool
+	RateLimit RateLimit
+	Timeout   time.Duration
+	Summary   string
+}
 
-	apiV1.Handle("/blogs/user/share",
-		middlewares.AuthMiddleware(50, time.Minute, http.HandlerFunc(handlers.ShareBlogHandler)),
-	).Methods(http.MethodPost, http.MethodOptions)
+// routeTable declares every route, bound to h.
+func routeTable(h *handlers.Handlers) []Route {
+	return []Route{
+		// Unprotected routes
+		{Name: "signup", Method: http.MethodPost, Path: "/user/signup", Handler: h.SignupUserHandler, Auth: AuthPublic, RateLimit: perMinute(10), Summary: "Create an account"},
+		{Name: "login", Method: http.MethodPost, Path: "/user/login", Handler: h.LoginUserHandler, Auth: AuthPublic, RateLimit: perMinute(20), Summary: "Log in with username and password"},
+		{Name: "getinfo", Method: http.MethodGet, Path: "/user/getinfo", Handler: h.GetUserInfoHandler, Auth: AuthPublic, RateLimit: perMinute(60), Summary: "Get the logged in user, if any"},
+		{Name: "logout", Method: http.MethodPost, Path: "/user/logout", Handler: h.LogoutUserHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "End the current session and clear the session cookie"},
+		{Name: "public-profile", Method: http.MethodGet, Path: "/u/{handle}", Handler: h.GetPublicProfileHandler, Auth: AuthPublic, RateLimit: perMinute(60), Summary: "Get the public profile of a user who made theirs public"},
+		{Name: "newsletter-unsubscribe", Method: http.MethodGet, Path: "/newsletter/unsubscribe", Handler: h.NewsletterUnsubscribePageHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "Show the page confirming an unsubscribe from the link in a newsletter"},
+		{Name: "newsletter-unsubscribe-one-click", Method: http.MethodPost, Path: "/newsletter/unsubscribe", Handler: h.NewsletterUnsubscribeHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "Unsubscribe from a newsletter, from its confirmation page or by a mail client's one-click request"},
+		{Name: "oauth-token", Method: http.MethodPost, Path: "/oauth/token", Handler: h.OAuthTokenHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "Exchange an authorization code for an access token"},
+		{Name: "oauth-revoke", Method: http.MethodPost, Path: "/oauth/revoke", Handler: h.OAuthRevokeHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "Revoke an access token"},
+		{Name: "sso-login", Method: http.MethodGet, Path: "/sso/login", Handler: h.SSOLoginHandler, Auth: AuthPublic, Writes: true, RateLimit: perMinute(20), Summary: "Start single sign-on for a team email domain"},
+		{Name: "sso-callback", Method: http.MethodGet, Path: "/sso/callback", Handler: h.SSOCallbackHandler, Auth: AuthPublic, Writes: true, RateLimit: perMinute(20), Summary: "OIDC redirect target completing single sign-on"},
+		{Name: "google-login", Method: http.MethodGet, Path: "/auth/google/login", Handler: h.GoogleLoginHandler, Auth: AuthPublic, Writes: true, RateLimit: perMinute(20), Summary: "Start Sign in with Google"},
+		{Name: "google-callback", Method: http.MethodGet, Path: "/auth/google/callback", Handler: h.GoogleCallbackHandler, Auth: AuthPublic, Writes: true, RateLimit: perMinute(20), Summary: "OAuth redirect target completing Sign in with Google"},
+		{Name: "magic-link", Method: http.MethodPost, Path: "/auth/magic-link", Handler: h.RequestMagicLinkHandler, Auth: AuthPublic, RateLimit: perMinute(5), Summary: "Email a one-time login link"},
+		{Name: "magic-link-page", Method: http.MethodGet, Path: "/auth/magic-link/verify", Handler: h.MagicLinkPageHandler, Auth: AuthPublic, RateLimit: perMinute(20), Summary: "Show the page confirming a login from an emailed link"},
+		{Name: "magic-link-verify", Method: http.MethodPost, Path: "/auth/magic-link/verify", Handler: h.VerifyMagicLinkHandler, Auth: AuthPublic, RateLimit: perMinute(20), Summary: "Log in with an emailed link, from its confirmation page"},
+		{Name: "scim-list-users", Method: http.MethodGet, Path: "/scim/v2/Users", Handler: h.ListSCIMUsersHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: list or filter team members"},
+		{Name: "scim-create-user", Method: http.MethodPost, Path: "/scim/v2/Users", Handler: h.CreateSCIMUserHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: provision a team member"},
+		{Name: "scim-get-user", Method: http.MethodGet, Path: "/scim/v2/Users/{id}", Handler: h.GetSCIMUserHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: get a team member"},
+		{Name: "scim-replace-user", Method: http.MethodPut, Path: "/scim/v2/Users/{id}", Handler: h.ReplaceSCIMUserHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: replace a team member"},
+		{Name: "scim-patch-user", Method: http.MethodPatch, Path: "/scim/v2/Users/{id}", Handler: h.PatchSCIMUserHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: update or deactivate a team member"},
+		{Name: "scim-delete-user", Method: http.MethodDelete, Path: "/scim/v2/Users/{id}", Handler: h.DeleteSCIMUserHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "SCIM: deprovision a team member"},
+		{Name: "platforms", Method: http.MethodGet, Path: "/platforms", Handler: h.GetPlatformsHandler, Auth: AuthPublic, RateLimit: perMinute(60), Summary: "List the platforms blogs are shared to and what their posts can hold"},
+		{Name: "hashnode-webhook-receiver", Method: http.MethodPost, Path: "/webhook/hashnode/{userId}", Handler: h.HashnodeWebhookHandler, Auth: AuthPublic, RateLimit: perMinute(120), Summary: "Receive Hashnode webhook deliveries"},
 
-	apiV1.Handle("/blogs/user/shared-blogs",
-		middlewares.AuthMiddleware(100, time.Minute, http.HandlerFunc(handlers.GetUserSharedBlogsHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+		// Protected routes with rate limiting
+		{Name: "scheduled-posts", Method: http.MethodGet, Path: "/user/scheduled_posts", Handler: h.GetUserScheduledBlogsHandler, Auth: AuthUser, Scope: "schedules:read", RateLimit: perMinute(100), Summary: "List queued scheduled posts"},
+		{Name: "csrf", Method: http.MethodGet, Path: "/csrf", Handler: h.CSRFTokenHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the CSRF token to send in the X-CSRF-Token header of state-changing requests"},
+		{Name: "sessions", Method: http.MethodGet, Path: "/user/sessions", Handler: h.ListSessionsHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "List active sessions"},
+		{Name: "revoke-other-sessions", Method: http.MethodDelete, Path: "/user/sessions", Handler: h.RevokeOtherSessionsHandler, Auth: AuthUser, ConsentExempt: true, RateLimit: perMinute(10), Summary: "Sign out every other session"},
+		{Name: "revoke-session", Method: http.MethodDelete, Path: "/user/sessions/{id}", Handler: h.RevokeSessionHandler, Auth: AuthUser, ConsentExempt: true, RateLimit: perMinute(30), Summary: "Sign out one session"},
+		{Name: "login-history", Method: http.MethodGet, Path: "/user/logins", Handler: h.GetLoginHistoryHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "List recent sign-ins, flagging new devices and countries"},
+		{Name: "public-profile-settings", Method: http.MethodGet, Path: "/user/public-profile", Handler: h.GetPublicProfileSettingsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the settings of the public profile"},
+		{Name: "update-public-profile-settings", Method: http.MethodPut, Path: "/user/public-profile", Handler: h.UpdatePublicProfileSettingsHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Turn the public profile on or off and choose what it shows"},
+		{Name: "consent", Method: http.MethodGet, Path: "/user/consent", Handler: h.GetConsentHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the current terms versions and the user's acceptances"},
+		{Name: "accept-consent", Method: http.MethodPost, Path: "/user/consent", Handler: h.AcceptConsentHandler, Auth: AuthUser, ConsentExempt: true, RateLimit: perMinute(10), Summary: "Accept the current terms of service and privacy policy"},
+		{Name: "refresh-session", Method: http.MethodPost, Path: "/session/refresh", Handler: h.RefreshSessionHandler, Auth: AuthUser, ConsentExempt: true, RateLimit: perMinute(10), Summary: "Exchange the session for a new one with a full lifetime"},
+		{Name: "change-email", Method: http.MethodPost, Path: "/user/email", Handler: h.ChangeEmailHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Start changing the account email"},
+		{Name: "confirm-email-change", Method: http.MethodPost, Path: "/user/email/confirm", Handler: h.ConfirmEmailChangeHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Confirm the new email with its OTP"},
+		{Name: "change-password", Method: http.MethodPost, Path: "/user/password", Handler: h.ChangePasswordHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Change the password and sign out other sessions"},
+		{Name: "profile", Method: http.MethodGet, Path: "/user/profile", Handler: h.GetUserProfileHandler, Auth: AuthUser, RateLimit: perMinute(100), Summary: "Get the detailed user profile"},
+		{Name: "get-preferences", Method: http.MethodGet, Path: "/user/preferences", Handler: h.GetUserPreferencesHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get user preferences"},
+		{Name: "update-preferences", Method: http.MethodPut, Path: "/user/preferences", Handler: h.UpdateUserPreferencesHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Update user preferences"},
+		{Name: "blogs", Method: http.MethodGet, Path: "/user/blogs", Handler: h.GetUserBlogsHandler, Auth: AuthUser, Scope: "blogs:read", RateLimit: perMinute(200), Summary: "List the user's blogs"},
+		{Name: "search", Method: http.MethodGet, Path: "/user/search", Handler: h.SearchHandler, Auth: AuthUser, Scope: "blogs:read", RateLimit: perMinute(60), Summary: "Search posts, past captions and scheduled shares"},
+		{Name: "campaigns", Method: http.MethodGet, Path: "/user/campaigns", Handler: h.GetCampaignsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "List campaigns"},
+		{Name: "create-campaign", Method: http.MethodPost, Path: "/user/campaigns", Handler: h.CreateCampaignHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(20), Summary: "Create a campaign with a UTM tag"},
+		{Name: "campaign-attach", Method: http.MethodPost, Path: "/user/campaigns/{id}/shares", Handler: h.AttachCampaignBlogHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(60), Summary: "Attach a blog's shares to a campaign"},
+		{Name: "campaign-detach", Method: http.MethodDelete, Path: "/user/campaigns/{id}/shares/{blogId}", Handler: h.DetachCampaignBlogHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(60), Summary: "Detach a blog from a campaign"},
+		{Name: "campaign-analytics", Method: http.MethodGet, Path: "/user/campaigns/{id}/analytics", Handler: h.GetCampaignAnalyticsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Aggregated share analytics of a campaign"},
+		{Name: "platform-analytics", Method: http.MethodGet, Path: "/user/analytics/platforms", Handler: h.GetPlatformAnalyticsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Engagement of the shared blogs compared across platforms"},
+		{Name: "topic-analytics", Method: http.MethodGet, Path: "/user/analytics/topics", Handler: h.GetTopicAnalyticsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Engagement of the shared blogs' topics on each platform, with insights"},
+		{Name: "posting-goal", Method: http.MethodGet, Path: "/user/goal", Handler: h.GetPostingGoalHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Get the weekly posting goal with this week's progress and streaks"},
+		{Name: "set-posting-goal", Method: http.MethodPut, Path: "/user/goal", Handler: h.SetPostingGoalHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Set how many blogs to share a week, optionally with a reminder"},
+		{Name: "delete-posting-goal", Method: http.MethodDelete, Path: "/user/goal", Handler: h.DeletePostingGoalHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the posting goal"},
+		{Name: "notifications", Method: http.MethodGet, Path: "/user/notifications", Handler: h.GetUserNotificationsHandler, Auth: AuthUser, RateLimit: perMinute(150), Summary: "List notifications"},
+		{Name: "clear-notifications", Method: http.MethodDelete, Path: "/user/notifications/clear", Handler: h.ClearUserNotificationsHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Clear notifications"},
+		{Name: "schedule", Method: http.MethodPost, Path: "/blogs/schedule", Handler: h.ScheduleBlogHandler, Auth: AuthUser, Scope: "schedules:write", RateLimit: perMinute(6), Summary: "Schedule a blog share"},
+		{Name: "schedule-import", Method: http.MethodPost, Path: "/blogs/schedule/import", Handler: h.ImportQueueHandler, Auth: AuthUser, Scope: "schedules:write", RateLimit: perMinute(2), Summary: "Schedule posts in bulk from a Buffer or Hootsuite CSV export"},
+		{Name: "schedule-delete", Method: http.MethodDelete, Path: "/blogs/schedule/delete", Handler: h.GetUserSharedBlogsHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "Delete a scheduled share"},
+		{Name: "share", Method: http.MethodPost, Path: "/blogs/user/share", Handler: h.ShareBlogHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(50), Timeout: 60 * time.Second, Summary: "Share a blog now"},
+		{Name: "defer-share", Method: http.MethodPost, Path: "/blogs/share-on-publish", Handler: h.DeferShareHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Share a blog when Hashnode publishes it"},
+		{Name: "list-deferred-shares", Method: http.MethodGet, Path: "/blogs/share-on-publish", Handler: h.GetDeferredSharesHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(100), Summary: "List pending share-on-publish plans"},
+		{Name: "cancel-deferred-share", Method: http.MethodDelete, Path: "/blogs/share-on-publish", Handler: h.CancelDeferredShareHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Cancel a share-on-publish plan"},
+		{Name: "approve-deferred-share", Method: http.MethodPost, Path: "/blogs/share-on-publish/approve", Handler: h.ApproveDeferredShareHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Share a published post that awaits your approval"},
+		{Name: "shared-blogs", Method: http.MethodGet, Path: "/blogs/user/shared-blogs", Handler: h.GetUserSharedBlogsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(100), Summary: "List shared blogs"},
+		{Name: "shared-blog-topics", Method: http.MethodPut, Path: "/blogs/user/shared-blogs/{id}/topics", Handler: h.SetSharedBlogTopicsHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Assign the topics of a shared blog"},
+		{Name: "cancel-scheduled-blog", Method: http.MethodDelete, Path: "/user/scheduled-blogs/cancel", Handler: h.CancelScheduledBlogHandler, Auth: AuthUser, Scope: "schedules:write", RateLimit: perMinute(40), Summary: "Cancel a scheduled share"},
+		{Name: "connect-twitter", Method: http.MethodGet, Path: "/user/connect-twitter", Handler: h.ConnectXhandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the X (Twitter) OAuth flow"},
+		{Name: "twitter-callback", Method: http.MethodGet, Path: "/user/twitter-callback", Handler: h.XcallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "X (Twitter) OAuth callback"},
+		{Name: "connect-linkedin", Method: http.MethodGet, Path: "/user/connect-linkedin", Handler: h.ConnectLinkedInHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the LinkedIn OAuth flow, with pages=true to also post for LinkedIn Pages"},
+		{Name: "linkedin-callback", Method: http.MethodGet, Path: "/user/linkedin-callback", Handler: h.LinkedCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "LinkedIn OAuth callback"},
+		{Name: "linkedin-pages", Method: http.MethodGet, Path: "/user/linkedin/pages", Handler: h.GetLinkedInPagesHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List the LinkedIn Pages you can post for and the one shares go to"},
+		{Name: "set-linkedin-page", Method: http.MethodPut, Path: "/user/linkedin/page", Handler: h.SetLinkedInPageHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Choose the LinkedIn Page shares go to instead of your profile"},
+		{Name: "connect-mastodon", Method: http.MethodGet, Path: "/user/connect-mastodon", Handler: h.ConnectMastodonHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the OAuth flow with the user's Mastodon server"},
+		{Name: "mastodon-callback", Method: http.MethodGet, Path: "/user/mastodon-callback", Handler: h.MastodonCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "Mastodon OAuth callback"},
+		{Name: "connect-reddit", Method: http.MethodGet, Path: "/user/connect-reddit", Handler: h.ConnectRedditHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the Reddit OAuth flow"},
+		{Name: "reddit-callback", Method: http.MethodGet, Path: "/user/reddit-callback", Handler: h.RedditCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "Reddit OAuth callback"},
+		{Name: "reddit-settings", Method: http.MethodPut, Path: "/user/reddit", Handler: h.UpdateRedditSettingsHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Choose the subreddit and flair for Reddit shares"},
+		{Name: "reddit-flairs", Method: http.MethodGet, Path: "/user/reddit/flairs", Handler: h.GetRedditFlairsHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "List the link flairs of a subreddit"},
+		{Name: "discord-webhook", Method: http.MethodGet, Path: "/user/discord", Handler: h.GetDiscordWebhookHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Discord webhook"},
+		{Name: "set-discord-webhook", Method: http.MethodPut, Path: "/user/discord", Handler: h.SetDiscordWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Discord webhook to announce blogs through"},
+		{Name: "delete-discord-webhook", Method: http.MethodDelete, Path: "/user/discord", Handler: h.DeleteDiscordWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Discord webhook"},
+		{Name: "test-discord-webhook", Method: http.MethodPost, Path: "/user/discord/test", Handler: h.TestDiscordWebhookHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Post a test message through the Discord webhook"},
+		{Name: "teams-webhook", Method: http.MethodGet, Path: "/user/teams", Handler: h.GetTeamsWebhookHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Microsoft Teams webhook"},
+		{Name: "set-teams-webhook", Method: http.MethodPut, Path: "/user/teams", Handler: h.SetTeamsWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Microsoft Teams webhook to announce blogs through"},
+		{Name: "delete-teams-webhook", Method: http.MethodDelete, Path: "/user/teams", Handler: h.DeleteTeamsWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Microsoft Teams webhook"},
+		{Name: "newsletter", Method: http.MethodGet, Path: "/user/newsletter", Handler: h.GetNewsletterHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get how shared blogs are sent as newsletters"},
+		{Name: "set-newsletter", Method: http.MethodPut, Path: "/user/newsletter", Handler: h.SetNewsletterHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Send shared blogs as newsletters, built in or through Buttondown or Mailchimp"},
+		{Name: "delete-newsletter", Method: http.MethodDelete, Path: "/user/newsletter", Handler: h.DeleteNewsletterHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Stop sending newsletters"},
+		{Name: "newsletter-subscribers", Method: http.MethodGet, Path: "/user/newsletter/subscribers", Handler: h.ListNewsletterSubscribersHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List the subscribers of the built-in newsletter"},
+		{Name: "add-newsletter-subscribers", Method: http.MethodPost, Path: "/user/newsletter/subscribers", Handler: h.AddNewsletterSubscribersHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Upload subscribers to the built-in newsletter as CSV or one address per line"},
+		{Name: "delete-newsletter-subscriber", Method: http.MethodDelete, Path: "/user/newsletter/subscribers/{email}", Handler: h.DeleteNewsletterSubscriberHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "Remove a subscriber from the built-in newsletter"},
+		{Name: "substack-account", Method: http.MethodGet, Path: "/user/substack", Handler: h.GetSubstackAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Substack account"},
+		{Name: "set-substack-account", Method: http.MethodPut, Path: "/user/substack", Handler: h.SetSubstackAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Substack account with a browser session cookie to post Notes"},
+		{Name: "delete-substack-account", Method: http.MethodDelete, Path: "/user/substack", Handler: h.DeleteSubstackAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Substack account"},
+		{Name: "connect-google-business", Method: http.MethodGet, Path: "/user/connect-google-business", Handler: h.ConnectGoogleBusinessHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the Google Business Profile OAuth flow"},
+		{Name: "google-business-callback", Method: http.MethodGet, Path: "/user/google-business-callback", Handler: h.GoogleBusinessCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "Google Business Profile OAuth callback"},
+		{Name: "google-business-account", Method: http.MethodGet, Path: "/user/google-business", Handler: h.GetGoogleBusinessAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Google Business Profile and its locations"},
+		{Name: "set-google-business-location", Method: http.MethodPut, Path: "/user/google-business/location", Handler: h.SetGoogleBusinessLocationHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Choose the business location posts are published to"},
+		{Name: "delete-google-business-account", Method: http.MethodDelete, Path: "/user/google-business", Handler: h.DeleteGoogleBusinessAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect Google Business Profile"},
+		{Name: "instagram-account", Method: http.MethodGet, Path: "/user/instagram", Handler: h.GetInstagramAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the Instagram account caption kits are made for"},
+		{Name: "set-instagram-account", Method: http.MethodPut, Path: "/user/instagram", Handler: h.SetInstagramAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Turn on Instagram caption kits, which the user posts by hand"},
+		{Name: "delete-instagram-account", Method: http.MethodDelete, Path: "/user/instagram", Handler: h.DeleteInstagramAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Turn off Instagram caption kits"},
+		{Name: "share-webhook", Method: http.MethodGet, Path: "/user/share-webhook", Handler: h.GetShareWebhookHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the webhook shares are posted to"},
+		{Name: "set-share-webhook", Method: http.MethodPut, Path: "/user/share-webhook", Handler: h.SetShareWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Register a webhook that receives shares as signed JSON"},
+		{Name: "delete-share-webhook", Method: http.MethodDelete, Path: "/user/share-webhook", Handler: h.DeleteShareWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the share webhook"},
+		{Name: "test-share-webhook", Method: http.MethodPost, Path: "/user/share-webhook/test", Handler: h.TestShareWebhookHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Send a ping to the share webhook"},
+		{Name: "connect-youtube", Method: http.MethodGet, Path: "/user/connect-youtube", Handler: h.ConnectYouTubeHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the Google OAuth flow for a YouTube channel"},
+		{Name: "youtube-callback", Method: http.MethodGet, Path: "/user/youtube-callback", Handler: h.YouTubeCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "YouTube OAuth callback"},
+		{Name: "youtube-channel", Method: http.MethodGet, Path: "/user/youtube", Handler: h.GetYouTubeChannelHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the YouTube channel community post kits are made for"},
+		{Name: "delete-youtube-channel", Method: http.MethodDelete, Path: "/user/youtube", Handler: h.DeleteYouTubeChannelHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the YouTube channel"},
+		{Name: "blog-feed", Method: http.MethodGet, Path: "/user/feed", Handler: h.GetBlogFeedHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the RSS or Atom feed blogs are listed from"},
+		{Name: "set-blog-feed", Method: http.MethodPut, Path: "/user/feed", Handler: h.SetBlogFeedHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Register an RSS or Atom feed as a blog source"},
+		{Name: "delete-blog-feed", Method: http.MethodDelete, Path: "/user/feed", Handler: h.DeleteBlogFeedHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the feed and its items"},
+		{Name: "ghost-site", Method: http.MethodGet, Path: "/user/ghost", Handler: h.GetGhostSourceHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the Ghost site blogs are listed from"},
+		{Name: "set-ghost-site", Method: http.MethodPut, Path: "/user/ghost", Handler: h.SetGhostSourceHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Register a Ghost site as a blog source with its Content API key"},
+		{Name: "delete-ghost-site", Method: http.MethodDelete, Path: "/user/ghost", Handler: h.DeleteGhostSourceHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the Ghost site"},
+		{Name: "medium-profile", Method: http.MethodGet, Path: "/user/medium/source", Handler: h.GetMediumSourceHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the Medium profile blogs are listed from"},
+		{Name: "set-medium-profile", Method: http.MethodPut, Path: "/user/medium/source", Handler: h.SetMediumSourceHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Register a Medium profile as a blog source"},
+		{Name: "delete-medium-profile", Method: http.MethodDelete, Path: "/user/medium/source", Handler: h.DeleteMediumSourceHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the Medium profile"},
+		{Name: "manual-tasks", Method: http.MethodGet, Path: "/user/manual-tasks", Handler: h.GetManualTasksHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "List the shares to post by hand, such as Instagram caption kits"},
+		{Name: "manual-task-image", Method: http.MethodGet, Path: "/user/manual-tasks/{id}/image", Handler: h.GetManualTaskImageHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Download the image of a manual task"},
+		{Name: "complete-manual-task", Method: http.MethodPost, Path: "/user/manual-tasks/{id}/done", Handler: h.CompleteManualTaskHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Mark a manual task posted"},
+		{Name: "delete-manual-task", Method: http.MethodDelete, Path: "/user/manual-tasks/{id}", Handler: h.DeleteManualTaskHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(30), Summary: "Delete a manual task"},
+		{Name: "test-teams-webhook", Method: http.MethodPost, Path: "/user/teams/test", Handler: h.TestTeamsWebhookHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Post a test card through the Microsoft Teams webhook"},
+		{Name: "devto-account", Method: http.MethodGet, Path: "/user/devto", Handler: h.GetDevtoAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Dev.to account"},
+		{Name: "set-devto-account", Method: http.MethodPut, Path: "/user/devto", Handler: h.SetDevtoAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Dev.to account with its API key"},
+		{Name: "delete-devto-account", Method: http.MethodDelete, Path: "/user/devto", Handler: h.DeleteDevtoAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Dev.to account"},
+		{Name: "medium-account", Method: http.MethodGet, Path: "/user/medium", Handler: h.GetMediumAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Medium account"},
+		{Name: "set-medium-account", Method: http.MethodPut, Path: "/user/medium", Handler: h.SetMediumAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Medium account with an integration token"},
+		{Name: "delete-medium-account", Method: http.MethodDelete, Path: "/user/medium", Handler: h.DeleteMediumAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Medium account"},
+		{Name: "nostr-account", Method: http.MethodGet, Path: "/user/nostr", Handler: h.GetNostrAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Nostr key and relays"},
+		{Name: "set-nostr-account", Method: http.MethodPut, Path: "/user/nostr", Handler: h.SetNostrAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Nostr secret key and the relays notes go to"},
+		{Name: "delete-nostr-account", Method: http.MethodDelete, Path: "/user/nostr", Handler: h.DeleteNostrAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect Nostr"},
+		{Name: "lemmy-account", Method: http.MethodGet, Path: "/user/lemmy", Handler: h.GetLemmyAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Lemmy account and communities"},
+		{Name: "set-lemmy-account", Method: http.MethodPut, Path: "/user/lemmy", Handler: h.SetLemmyAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Log in to a Lemmy server and choose the communities for shares"},
+		{Name: "lemmy-communities", Method: http.MethodPut, Path: "/user/lemmy/communities", Handler: h.UpdateLemmyCommunitiesHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Choose the Lemmy communities for shares"},
+		{Name: "delete-lemmy-account", Method: http.MethodDelete, Path: "/user/lemmy", Handler: h.DeleteLemmyAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Lemmy account"},
+		{Name: "wordpress-account", Method: http.MethodGet, Path: "/user/wordpress", Handler: h.GetWordPressAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected WordPress site"},
+		{Name: "set-wordpress-account", Method: http.MethodPut, Path: "/user/wordpress", Handler: h.SetWordPressAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a WordPress site with an application password"},
+		{Name: "delete-wordpress-account", Method: http.MethodDelete, Path: "/user/wordpress", Handler: h.DeleteWordPressAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the WordPress site"},
+		{Name: "matrix-room", Method: http.MethodGet, Path: "/user/matrix", Handler: h.GetMatrixRoomHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Matrix room"},
+		{Name: "set-matrix-room", Method: http.MethodPut, Path: "/user/matrix", Handler: h.SetMatrixRoomHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect the Matrix room blogs are announced in"},
+		{Name: "delete-matrix-room", Method: http.MethodDelete, Path: "/user/matrix", Handler: h.DeleteMatrixRoomHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Matrix room"},
+		{Name: "connect-tumblr", Method: http.MethodGet, Path: "/user/connect-tumblr", Handler: h.ConnectTumblrHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(15), Summary: "Start the Tumblr OAuth flow"},
+		{Name: "tumblr-callback", Method: http.MethodGet, Path: "/user/tumblr-callback", Handler: h.TumblrCallbackHandler, Auth: AuthUser, Writes: true, RateLimit: perMinute(10), Summary: "Tumblr OAuth callback"},
+		{Name: "tumblr-account", Method: http.MethodGet, Path: "/user/tumblr", Handler: h.GetTumblrAccountHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the connected Tumblr blog"},
+		{Name: "delete-tumblr-account", Method: http.MethodDelete, Path: "/user/tumblr", Handler: h.DeleteTumblrAccountHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect the Tumblr account"},
+		{Name: "disconnect-platform", Method: http.MethodDelete, Path: "/connect/{platform}", Handler: h.DisconnectPlatformHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Disconnect Hashnode or a platform, revoking its token where the platform allows"},
+		{Name: "verify-hashnode", Method: http.MethodPost, Path: "/user/verify-hashnode", Handler: h.VerifyHashnodeHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Connect a Hashnode account"},
+		{Name: "hashnode-webhook-secret", Method: http.MethodPost, Path: "/user/hashnode-webhook", Handler: h.SetHashnodeWebhookSecretHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Store the Hashnode webhook secret"},
+		{Name: "hashnode-publications", Method: http.MethodGet, Path: "/user/hashnode/publications", Handler: h.GetHashnodePublicationsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List your Hashnode publications and the default one"},
+		{Name: "set-hashnode-publication", Method: http.MethodPut, Path: "/user/hashnode/publication", Handler: h.SetHashnodePublicationHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Choose the Hashnode publication blogs are listed from by default"},
+		{Name: "verify-email", Method: http.MethodPost, Path: "/user/verify-email", Handler: h.VerifyEmailHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Verify the email OTP"},
+		{Name: "register-oauth-client", Method: http.MethodPost, Path: "/developer/clients", Handler: h.RegisterOAuthClientHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Register a third-party OAuth client"},
+		{Name: "list-oauth-clients", Method: http.MethodGet, Path: "/developer/clients", Handler: h.GetOAuthClientsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List your OAuth clients"},
+		{Name: "delete-oauth-client", Method: http.MethodDelete, Path: "/developer/clients", Handler: h.DeleteOAuthClientHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Delete an OAuth client and its tokens"},
+		{Name: "oauth-consent", Method: http.MethodGet, Path: "/oauth/authorize", Handler: h.OAuthConsentHandler, Auth: AuthUser, RateLimit: perMinute(30), Summary: "Describe an authorization request for the consent screen"},
+		{Name: "oauth-authorize", Method: http.MethodPost, Path: "/oauth/authorize", Handler: h.OAuthAuthorizeHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Approve or deny an authorization request"},
+		{Name: "security-webhook", Method: http.MethodGet, Path: "/user/security-webhook", Handler: h.GetSecurityWebhookHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the security event webhook"},
+		{Name: "set-security-webhook", Method: http.MethodPut, Path: "/user/security-webhook", Handler: h.SetSecurityWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Register a webhook for login, session and connection events"},
+		{Name: "delete-security-webhook", Method: http.MethodDelete, Path: "/user/security-webhook", Handler: h.DeleteSecurityWebhookHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Remove the security event webhook"},
+		{Name: "test-security-webhook", Method: http.MethodPost, Path: "/user/security-webhook/test", Handler: h.TestSecurityWebhookHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Send a ping event to the security webhook"},
+		{Name: "api-keys", Method: http.MethodGet, Path: "/user/api-keys", Handler: h.GetAPIKeysHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List personal API keys"},
+		{Name: "create-api-key", Method: http.MethodPost, Path: "/user/api-keys", Handler: h.CreateAPIKeyHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Create a personal API key for scripts and CI"},
+		{Name: "revoke-api-key", Method: http.MethodDelete, Path: "/user/api-keys/{id}", Handler: h.RevokeAPIKeyHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Revoke a personal API key"},
+		{Name: "authorized-apps", Method: http.MethodGet, Path: "/user/authorized-apps", Handler: h.GetAuthorizedAppsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List apps with access to your account"},
+		{Name: "revoke-authorized-app", Method: http.MethodDelete, Path: "/user/authorized-apps", Handler: h.RevokeAuthorizedAppHandler, Auth: AuthUser, RateLimit: perMinute(20), Summary: "Revoke an app's access to your account"},
+		{Name: "oauth-apps", Method: http.MethodGet, Path: "/user/oauth-apps", Handler: h.GetOAuthAppsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the user's own X and LinkedIn apps"},
+		{Name: "set-oauth-app", Method: http.MethodPut, Path: "/user/oauth-apps/{platform}", Handler: h.SetOAuthAppHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Use your own X or LinkedIn app instead of the shared one"},
+		{Name: "delete-oauth-app", Method: http.MethodDelete, Path: "/user/oauth-apps/{platform}", Handler: h.DeleteOAuthAppHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Go back to the shared X or LinkedIn app"},
+		{Name: "team-regions", Method: http.MethodGet, Path: "/teams/regions", Handler: h.GetRegionsHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "List the storage regions available to new teams"},
+		{Name: "create-team", Method: http.MethodPost, Path: "/teams", Handler: h.CreateTeamHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Create a team owned by the caller"},
+		{Name: "get-team", Method: http.MethodGet, Path: "/teams/me", Handler: h.GetTeamHandler, Auth: AuthUser, RateLimit: perMinute(60), Summary: "Get the caller's team and members"},
+		{Name: "add-team-domain", Method: http.MethodPost, Path: "/teams/domains", Handler: h.AddTeamDomainHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Claim an email domain for the team"},
+		{Name: "verify-team-domain", Method: http.MethodPost, Path: "/teams/domains/verify", Handler: h.VerifyTeamDomainHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Verify a domain through its DNS TXT record"},
+		{Name: "update-team-sso", Method: http.MethodPut, Path: "/teams/sso", Handler: h.UpdateTeamSSOHandler, Auth: AuthUser, RateLimit: perMinute(10), Summary: "Configure OIDC single sign-on for the team"},
+		{Name: "create-team-scim-token", Method: http.MethodPost, Path: "/teams/scim-token", Handler: h.CreateSCIMTokenHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Issue the team's SCIM provisioning token"},
+		{Name: "team-calendar", Method: http.MethodGet, Path: "/teams/calendar", Handler: h.GetTeamCalendarHandler, Auth: AuthUser, Scope: "schedules:read", RateLimit: perMinute(60), Summary: "Team calendar of scheduled shares with conflicts between members"},
+		{Name: "team-library", Method: http.MethodGet, Path: "/teams/library", Handler: h.GetLibraryAssetsHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "List the team's reusable images, hashtag sets and caption snippets"},
+		{Name: "create-team-library-asset", Method: http.MethodPost, Path: "/teams/library", Handler: h.CreateLibraryAssetHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(20), Summary: "Add an asset to the team library"},
+		{Name: "team-library-asset", Method: http.MethodGet, Path: "/teams/library/{id}", Handler: h.GetLibraryAssetHandler, Auth: AuthUser, Scope: "shares:read", RateLimit: perMinute(60), Summary: "Get a team library asset"},
+		{Name: "update-team-library-asset", Method: http.MethodPut, Path: "/teams/library/{id}", Handler: h.UpdateLibraryAssetHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(20), Summary: "Update a team library asset"},
+		{Name: "delete-team-library-asset", Method: http.MethodDelete, Path: "/teams/library/{id}", Handler: h.DeleteLibraryAssetHandler, Auth: AuthUser, Scope: "shares:write", RateLimit: perMinute(20), Summary: "Delete a team library asset"},
+		{Name: "resend-otp", Method: http.MethodPost, Path: "/user/resend-otp", Handler: h.ResetEmailOtpHandler, Auth: AuthUser, RateLimit: perMinute(5), Summary: "Send a new email OTP"},
 
-	apiV1.Handle("/user/scheduled-blogs/cancel",
-		middlewares.AuthMiddleware(40, time.Minute, http.HandlerFunc(handlers.CancelScheduledBlogHandler)),
-	).Methods(http.MethodDelete, http.MethodOptions)
+		// Admin routes
+		{Name: "admin-provider-responses", Method: http.MethodGet, Path: "/admin/provider-responses", Handler: h.GetProviderResponsesHandler, Auth: AuthAdmin, RateLimit: perMinute(60), Summary: "Inspect archived provider responses"},
+		{Name: "admin-start-debug-capture", Method: http.MethodPost, Path: "/admin/debug-capture", Handler: h.StartDebugCaptureHandler, Auth: AuthAdmin, RateLimit: perMinute(20), Summary: "Capture requests for a user or issue a debug token"},
+		{Name: "admin-stop-debug-capture", Method: http.MethodDelete, Path: "/admin/debug-capture", Handler: h.StopDebugCaptureHandler, Auth: AuthAdmin, RateLimit: perMinute(20), Summary: "Stop capturing requests for a user or debug token"},
+		{Name: "admin-reload-config", Method: http.MethodPost, Path: "/admin/config/reload", Handler: h.ReloadConfigHandler, Auth: AuthAdmin, RateLimit: perMinute(10), Summary: "Reload the runtime configuration file"},
+		{Name: "admin-maintenance", Method: http.MethodGet, Path: "/admin/maintenance", Handler: h.GetMaintenanceHandler, Auth: AuthAdmin, RateLimit: perMinute(60), Summary: "Get the maintenance mode state"},
+		{Name: "admin-start-maintenance", Method: http.MethodPut, Path: "/admin/maintenance", Handler: h.StartMaintenanceHandler, Auth: AuthAdmin, RateLimit: perMinute(10), Summary: "Turn maintenance mode on: writes get 503 and the scheduler holds"},
+		{Name: "admin-end-maintenance", Method: http.MethodDelete, Path: "/admin/maintenance", Handler: h.EndMaintenanceHandler, Auth: AuthAdmin, RateLimit: perMinute(10), Summary: "Turn maintenance mode off"},
+		{Name: "admin-metrics", Method: http.MethodGet, Path: "/admin/metrics", Handler: metrics.Handler, Auth: AuthAdmin, RateLimit: perMinute(120), Summary: "Prometheus metrics"},
+		{Name: "admin-product-metrics", Method: http.MethodGet, Path: "/admin/metrics/product", Handler: h.GetProductMetricsHandler, Auth: AuthAdmin, RateLimit: perMinute(30), Summary: "Product adoption summary"},
+		{Name: "admin-audit-log", Method: http.MethodGet, Path: "/admin/users/{id}/audit", Handler: h.GetAuditLogHandler, Auth: AuthAdmin, RateLimit: perMinute(30), Summary: "List a user's audit events, such as accepting the terms"},
+		{Name: "admin-debug-captures", Method: http.MethodGet, Path: "/admin/debug-captures", Handler: h.GetDebugCapturesHandler, Auth: AuthAdmin, RateLimit: perMinute(60), Summary: "Inspect captured request/response pairs"},
+		{Name: "admin-rewrap-data-keys", Method: http.MethodPost, Path: "/admin/keys/rewrap", Handler: h.RewrapDataKeysHandler, Auth: AuthAdmin, RateLimit: perMinute(2), Summary: "Rewrap data keys with the current master key"},
+		{Name: "admin-rotate-data-key", Method: http.MethodPost, Path: "/admin/keys/users/{id}/rotate", Handler: h.RotateUserDataKeyHandler, Auth: AuthAdmin, RateLimit: perMinute(10), Summary: "Give a user a new data key"},
+		{Name: "admin-scheduler-drift", Method: http.MethodGet, Path: "/admin/scheduler/drift", Handler: h.GetSchedulerDriftHandler, Auth: AuthAdmin, RateLimit: perMinute(10), Summary: "Compare users' schedules with the scheduler's tasks"},
+		{Name: "admin-repair-scheduler-drift", Method: http.MethodPost, Path: "/admin/scheduler/drift/repair", Handler: h.RepairSchedulerDriftHandler, Auth: AuthAdmin, RateLimit: perMinute(2), Summary: "Queue missing scheduler tasks and remove orphaned ones"},
 
-	apiV1.Handle("/user/connect-twitter",
-		middlewares.AuthMiddleware(15, time.Minute, http.HandlerFunc(handlers.ConnectXhandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+		// Routes for users with the admin role
+		{Name: "admin-users", Method: http.MethodGet, Path: "/admin/users", Handler: h.ListUsersHandler, Auth: AuthAdminUser, RateLimit: perMinute(30), Summary: "List users"},
+		{Name: "admin-user-role", Method: http.MethodPut, Path: "/admin/users/{id}/role", Handler: h.UpdateUserRoleHandler, Auth: AuthAdminUser, RateLimit: perMinute(20), Summary: "Grant or take away the admin role"},
+		{Name: "admin-user-status", Method: http.MethodPut, Path: "/admin/users/{id}/status", Handler: h.UpdateUserStatusHandler, Auth: AuthAdminUser, RateLimit: perMinute(20), Summary: "Disable or re-enable an account"},
+		{Name: "admin-stats", Method: http.MethodGet, Path: "/admin/stats", Handler: h.GetSystemStatsHandler, Auth: AuthAdminUser, RateLimit: perMinute(30), Summary: "User and scheduler stats"},
+		{Name: "openapi", Method: http.MethodGet, Path: "/openapi.json", Handler: OpenAPIHandler, Auth: AuthPublic, RateLimit: perMinute(30), Summary: "OpenAPI description of this API"},
+	}
+}
 
-	apiV1.Handle("/user/twitter-callback",
-		middlewares.AuthMiddleware(10, time.Minute, http.HandlerFunc(handlers.XcallbackHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+// Routes returns the declared route table. Its handlers are not bound to any
+// Handlers and must not be called; RegisterRoutes binds them.
+func Routes() []Route {
+	return routeTable(nil)
+}
 
-	apiV1.Handle("/user/connect-linkedin",
-		middlewares.AuthMiddleware(15, time.Minute, http.HandlerFunc(handlers.ConnectLinkedInHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+// RegisterRoutes serves the route table with h.
+func RegisterRoutes(h *handlers.Handlers) *mux.Router {
+	router := mux.NewRouter()
+	apiV1 := router.PathPrefix("/api/v1").Subrouter()
 
-	apiV1.Handle("/user/linkedin-callback",
-		middlewares.AuthMiddleware(10, time.Minute, http.HandlerFunc(handlers.LinkedCallbackHandler)),
-	).Methods(http.MethodGet, http.MethodOptions)
+	for _, route := range routeTable(h) {
+		if err := route.validate(); err != nil {
+			panic(err)
+		}
+		methods := []string{route.Method}
+		if route.Auth == AuthUser {
+			methods = append(methods, http.MethodOptions)
+		}
+		apiV1.Handle(route.Path, route.chain()).Methods(methods...).Name(route.Name)
+	}
 
-	apiV1.Handle("/user/verify-hashnode",
-		middlewares.AuthMiddleware(10, time.Minute, http.HandlerFunc(handlers.VerifyHashnodeHandler)),
-	).Methods(http.MethodPost, http.MethodOptions)
+	return router
+}
 
-	apiV1.Handle("/user/verify-email",
-		middlewares.AuthMiddleware(10, time.Minute, http.HandlerFunc(handlers.VerifyEmailHandler)),
-	).Methods(http.MethodPost, http.MethodOptions)
+func (route Route) validate() error {
+	if route.Name == "" || route.Path == "" || route.Method == "" || route.Handler == nil {
+		return fmt.Errorf("route %q %s %s is incomplete", route.Name, route.Method, route.Path)
+	}
+	if route.RateLimit.Requests <= 0 || route.RateLimit.Window <= 0 {
+		return fmt.Errorf("route %q must declare a rate limit", route.Name)
+	}
+	if route.Auth != AuthPublic && route.Auth != AuthUser && route.Auth != AuthAdmin && route.Auth != AuthAdminUser {
+		return fmt.Errorf("route %q has an unknown auth scope", route.Name)
+	}
+	if route.Writes && route.Method != http.MethodGet {
+		return fmt.Errorf("route %q is marked as writing but only GET routes need to be", route.Name)
+	}
+	if route.ConsentExempt && route.Auth != AuthUser {
+		return fmt.Errorf("route %q is consent exempt but is not a user route", route.Name)
+	}
+	if route.Scope != "" {
+		if route.Auth != AuthUser {
+			return fmt.Errorf("route %q declares an OAuth scope but is not a user route", route.Name)
+		}
+		if _, ok := services.OAuthScopes[route.Scope]; !ok {
+			return fmt.Errorf("route %q declares unknown OAuth scope %q", route.Name, route.Scope)
+		}
+	}
+	return nil
+}
 
-	apiV1.Handle("/user/resend-otp",
-		middlewares.AuthMiddleware(5, time.Minute, http.HandlerFunc(handlers.ResetEmailOtpHandler)),
-	).Methods(http.MethodPost, http.MethodOptions)
+// chain wraps the handler with the timeout, auth, CSRF and rate limit
+// middlewares declared for the route. Rate limits can be overridden by name through the
+// reloadable config. Outside the admin routes, writes are refused during
+// maintenance, and user routes refuse writes until the current terms are
+// accepted.
+func (route Route) chain() http.Handler {
+	timeout := route.Timeout
+	if timeout == 0 {
+		timeout = defaultRouteTimeout
+	}
+	limit := func() int {
+		return config.Get().RateLimit(route.Name, route.RateLimit.Requests)
+	}
+	var handler http.Handler = http.TimeoutHandler(route.Handler, timeout, `{"success": false, "reason": "request timed out"}`)
 
-	return router
+	switch route.Auth {
+	case AuthUser:
+		handler = middlewares.DebugCaptureMiddleware(handler)
+		if !route.ConsentExempt {
+			handler = middlewares.ConsentMiddleware(handler)
+		}
+		handler = middlewares.CSRFMiddleware(route.Scope != "", handler)
+		if route.Scope != "" {
+			handler = middlewares.ScopedAuthMiddleware(route.Scope, limit, route.RateLimit.Window, handler)
+		} else {
+			handler = middlewares.AuthMiddleware(limit, route.RateLimit.Window, handler)
+		}
+	case AuthAdmin:
+		handler = middlewares.IPRateLimitMiddleware(limit, route.RateLimit.Window)(middlewares.AdminMiddleware(handler))
+	case AuthAdminUser:
+		handler = middlewares.CSRFMiddleware(false, middlewares.DebugCaptureMiddleware(handler))
+		handler = middlewares.AuthMiddleware(limit, route.RateLimit.Window, middlewares.AdminRoleMiddleware(handler))
+	default:
+		handler = middlewares.IPRateLimitMiddleware(limit, route.RateLimit.Window)(middlewares.DebugCaptureMiddleware(handler))
+	}
+	if route.Auth == AuthUser || route.Auth == AuthPublic {
+		handler = middlewares.MaintenanceMiddleware(route.Writes, handler)
+	}
+	return handler
 }
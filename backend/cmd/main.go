@@ -7,11 +7,25 @@ import (
 	"os"
 	"os/signal"
 	"social-scribe/backend/api/v1"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/feedsync"
+	"social-scribe/backend/internal/goals"
 	"social-scribe/backend/internal/handlers"
+	"social-scribe/backend/internal/maintenance"
+	"social-scribe/backend/internal/metrics"
+	"social-scribe/backend/internal/middlewares"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/newsletter"
+	"social-scribe/backend/internal/postsync"
+	"social-scribe/backend/internal/reporting"
 	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/retention"
 	"social-scribe/backend/internal/scheduler"
+	"social-scribe/backend/internal/services"
 	"syscall"
+	"time"
 
+	"github.com/joho/godotenv"
 	"github.com/rs/cors"
 )
 
@@ -19,40 +33,110 @@ func setupCors() *cors.Cors {
 	return cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://192.168.29.3:9696", "http://192.168.29.3:5173"},
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS", "PUT", "DELETE", "PATCH"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", middlewares.DebugTokenHeader, services.CSRFHeader},
 		AllowCredentials: true,
 	})
 }
 
 func main() {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		if err := reporting.InitSentry(dsn, os.Getenv("SENTRY_ENVIRONMENT")); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}
+
+	if err := config.Reload(); err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	if err := services.CheckCSRFSecret(); err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+
 	repo.InitMongoDb()
 	repo.InitRedis()
-	router := v1.RegisterRoutes()
+	useProviderStub()
 	hostname, err := os.Hostname()
 	if err != nil {
 		hostname = "MISSING"
 	}
 
 	taskScheduler := scheduler.NewScheduler()
-	handlers.InitScheduler(taskScheduler)
+	// Due shares wait out maintenance, whichever instance turned it on
+	maintenance.OnChange(func(state models.MaintenanceState) {
+		if state.Enabled {
+			taskScheduler.Hold()
+		} else {
+			taskScheduler.Release()
+		}
+	})
+	if err := maintenance.Refresh(); err != nil {
+		log.Printf("[ERROR] Failed to load the maintenance state: %v", err)
+	}
+	deps := handlers.DepsFromEnv()
+	deps.Scheduler = taskScheduler
+	router := v1.RegisterRoutes(handlers.New(deps))
+	metrics.NewGaugeFunc("socialscribe_scheduler_queued_tasks", "Scheduled shares waiting in the queue.", func() float64 {
+		return float64(taskScheduler.Stats().Queued)
+	})
 	defer taskScheduler.Stop()
+	reconciler := scheduler.NewReconciler(taskScheduler)
+	defer reconciler.Stop()
+	postSyncWorker := postsync.NewWorker()
+	defer postSyncWorker.Stop()
+	feedSyncWorker := feedsync.NewWorker()
+	defer feedSyncWorker.Stop()
+	retentionWorker := retention.NewWorker()
+	defer retentionWorker.Stop()
+	goalWorker := goals.NewWorker()
+	defer goalWorker.Stop()
+	newsletterWorker := newsletter.NewWorker()
+	defer newsletterWorker.Stop()
+
+	// Non-secret settings reload in place, so queued schedules survive tuning
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Println("[INFO] SIGHUP received, reloading configuration")
+			if err := config.Reload(); err != nil {
+				log.Printf("[ERROR] Config reload failed: %v", err)
+			}
+		}
+	}()
+	stopWatch := make(chan struct{})
+	go config.Watch(10*time.Second, stopWatch)
+	go maintenance.Watch(5*time.Second, stopWatch)
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-stop
+		close(stopWatch)
 		log.Println("[INFO] Shutting down gracefully...")
+		reconciler.Stop()
 		taskScheduler.Stop()
+		postSyncWorker.Stop()
+		feedSyncWorker.Stop()
+		retentionWorker.Stop()
+		goalWorker.Stop()
+		newsletterWorker.Stop()
+		reporting.Flush(2 * time.Second)
 		os.Exit(0)
 	}()
 
 	corsHandler := setupCors()
+	securityHeaders := middlewares.SecurityHeadersMiddleware(middlewares.SecurityHeadersConfigFromEnv())
+	handler := middlewares.RequestIDMiddleware(securityHeaders(middlewares.RecoveryMiddleware(corsHandler.Handler(router))))
 	port := os.Getenv("BACKEND_PORT")
 	if port == "" {
 		log.Printf("[DEBUG] Running on %s:9696", hostname)
-		log.Fatal(http.ListenAndServe(":9696", corsHandler.Handler(router)))
+		log.Fatal(http.ListenAndServe(":9696", handler))
 	} else {
 		log.Printf("[DEBUG] Running on %s:%s", hostname, port)
-		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), corsHandler.Handler(router)))
+		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), handler))
 	}
 }
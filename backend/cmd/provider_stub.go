@@ -0,0 +1,23 @@
+//go:build e2e
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"social-scribe/backend/internal/services"
+)
+
+// useProviderStub sends provider calls to the stub at PROVIDER_STUB_URL, when
+// set. Only e2e builds can do this.
+func useProviderStub() {
+	stubURL := os.Getenv("PROVIDER_STUB_URL")
+	if stubURL == "" {
+		return
+	}
+	if err := services.UseProviderStub(stubURL); err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	log.Printf("[WARN] Provider calls are going to the stub at %s", stubURL)
+}
@@ -0,0 +1,15 @@
+//go:build !e2e
+
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// useProviderStub only warns: provider stubs need a build with the e2e tag.
+func useProviderStub() {
+	if os.Getenv("PROVIDER_STUB_URL") != "" {
+		log.Printf("[WARN] PROVIDER_STUB_URL is ignored: the server was built without the e2e tag")
+	}
+}
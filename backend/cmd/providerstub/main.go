@@ -0,0 +1,27 @@
+// Command providerstub serves fake Hashnode, AI provider, LinkedIn and X APIs
+// for local development and demos. Start the server, built with the e2e tag,
+// with PROVIDER_STUB_URL pointing here and seeded demo accounts work without
+// real OAuth apps:
+//
+//	go run ./cmd/providerstub &
+//	PROVIDER_STUB_URL=http://localhost:8089 go run -tags e2e ./cmd
+//
+// Calls received are listed at /__admin/requests.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"social-scribe/backend/internal/providertest"
+)
+
+func main() {
+	addr := os.Getenv("PROVIDER_STUB_ADDR")
+	if addr == "" {
+		addr = ":8089"
+	}
+	log.Printf("[INFO] Provider stub listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, &providertest.Stub{}))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func configCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the running server's configuration",
+	}
+
+	reload := &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the configuration file on the server at --api-url",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := adminRequest(http.MethodPost, "/api/v1/admin/config/reload")
+			if err != nil {
+				return err
+			}
+			fmt.Println(body)
+			return nil
+		},
+	}
+
+	group.AddCommand(reload)
+	return group
+}
+
+// adminRequest calls the admin API of the server at apiURL and returns the
+// response body.
+func adminRequest(method, path string) (string, error) {
+	return adminRequestJSON(method, path, nil)
+}
+
+// adminRequestJSON is adminRequest with a JSON request body; a nil payload
+// sends none.
+func adminRequestJSON(method, path string, payload interface{}) (string, error) {
+	token := os.Getenv("ADMIN_API_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("ADMIN_API_TOKEN is not set")
+	}
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(apiURL, "/")+path, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Admin-Token", token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return strings.TrimSpace(string(respBody)), nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"social-scribe/backend/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+func keysCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage signing keys",
+	}
+
+	var keep int
+	rotate := &cobra.Command{
+		Use:   "rotate",
+		Short: "Generate a new session signing secret",
+		Long: `Generate a new session signing secret and print the SESSION_JWT_SECRETS
+value that puts it first. Older secrets stay listed so sessions they signed
+keep working; drop them once the longest session lifetime has passed.
+
+Deploy the printed value to every instance and restart them. Stored app
+secrets are sealed with per-user data keys wrapped by APP_CREDENTIALS_SECRETS;
+rotate that list the same way, then run "keys rewrap".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return fmt.Errorf("generating a secret: %w", err)
+			}
+
+			secrets := []string{base64.RawURLEncoding.EncodeToString(secret)}
+			for _, old := range strings.Split(os.Getenv("SESSION_JWT_SECRETS"), ",") {
+				old = strings.TrimSpace(old)
+				if old == "" || (keep >= 0 && len(secrets) > keep) {
+					continue
+				}
+				secrets = append(secrets, old)
+			}
+			fmt.Printf("SESSION_JWT_SECRETS=%s\n", strings.Join(secrets, ","))
+			return nil
+		},
+	}
+	rotate.Flags().IntVar(&keep, "keep", -1, "How many of the current secrets to keep; -1 keeps all")
+
+	rewrap := &cobra.Command{
+		Use:   "rewrap",
+		Short: "Wrap every data key with the current master key",
+		Long: `Wrap every user's data key with the first APP_CREDENTIALS_SECRETS secret,
+reseal app secrets stored before users had data keys, and seal platform
+credentials stored in plain text before credentials were sealed.
+
+Run it after putting a new secret first in APP_CREDENTIALS_SECRETS. Once it
+reports no failures, the older secrets can be dropped from the list. Users
+that failed are logged and picked up again by the next run.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			report, err := services.RewrapDataKeys()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Rewrapped %d users with master key %s, %d failed\n", report.Updated, report.MasterKeyID, report.Failed)
+			if report.Failed > 0 {
+				return fmt.Errorf("%d users still use an older master key", report.Failed)
+			}
+			return nil
+		},
+	}
+
+	group.AddCommand(rotate, rewrap)
+	return group
+}
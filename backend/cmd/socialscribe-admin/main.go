@@ -0,0 +1,64 @@
+// Command socialscribe-admin is the operator CLI. Commands that change data
+// go straight to the repositories, using the same MONGO_URI, MONGO_DB and
+// MONGO_REGIONS settings as the server; commands that act on a running server
+// call its admin API with ADMIN_API_TOKEN.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+var (
+	mongoURI string
+	mongoDB  string
+	apiURL   string
+	verbose  bool
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:           "socialscribe-admin",
+		Short:         "Administer a SocialScribe deployment",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// The repositories log every call; only show that when asked
+			if !verbose {
+				log.SetOutput(io.Discard)
+			}
+		},
+	}
+	root.PersistentFlags().StringVar(&mongoURI, "mongo-uri", envOr("MONGO_URI", "mongodb://localhost:27017"), "MongoDB connection string (MONGO_URI)")
+	root.PersistentFlags().StringVar(&mongoDB, "mongo-db", envOr("MONGO_DB", "social-scribe"), "MongoDB database (MONGO_DB)")
+	root.PersistentFlags().StringVar(&apiURL, "api-url", envOr("BACKEND_URL", "http://localhost:9696"), "Base URL of the running server (BACKEND_URL)")
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show server-side logging")
+
+	root.AddCommand(usersCommand(), tasksCommand(), keysCommand(), migrateCommand(), configCommand(), maintenanceCommand(), seedCommand())
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// connect opens the database for commands that work on it directly. The
+// connection is left to close with the process.
+func connect() error {
+	if err := repo.Connect(mongoURI, mongoDB); err != nil {
+		return fmt.Errorf("connecting to %s: %w", mongoURI, err)
+	}
+	return nil
+}
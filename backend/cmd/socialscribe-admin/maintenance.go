@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+func maintenanceCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Turn maintenance mode of the running servers on and off",
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether maintenance mode is on",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := adminRequest(http.MethodGet, "/api/v1/admin/maintenance")
+			if err != nil {
+				return err
+			}
+			fmt.Println(body)
+			return nil
+		},
+	}
+
+	var message string
+	var minutes int
+	start := &cobra.Command{
+		Use:   "start",
+		Short: "Turn maintenance mode on",
+		Long: `Turn maintenance mode on for every server sharing the database.
+
+Reads keep working, writes are refused with 503 and a Retry-After of
+--minutes (a minute when unset), and scheduled shares wait until maintenance
+ends. Running start again updates the message and the expected end.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := adminRequestJSON(http.MethodPut, "/api/v1/admin/maintenance", map[string]interface{}{
+				"message":         message,
+				"ends_in_minutes": minutes,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Println(body)
+			return nil
+		},
+	}
+	start.Flags().StringVar(&message, "message", "", "Message shown to refused clients")
+	start.Flags().IntVar(&minutes, "minutes", 0, "Expected length of the maintenance in minutes")
+
+	end := &cobra.Command{
+		Use:   "end",
+		Short: "Turn maintenance mode off",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := adminRequest(http.MethodDelete, "/api/v1/admin/maintenance")
+			if err != nil {
+				return err
+			}
+			fmt.Println(body)
+			return nil
+		},
+	}
+
+	group.AddCommand(status, start, end)
+	return group
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+func migrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Create indexes and backfill new fields",
+		Long: `Create indexes and backfill new fields. The server does the same on every
+start but only logs failures; run this before a deploy to catch them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			if err := repo.Migrate(); err != nil {
+				return err
+			}
+			fmt.Println("Database is up to date")
+			return nil
+		},
+	}
+}
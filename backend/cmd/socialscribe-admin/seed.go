@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/providertest"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const demoUserPrefix = "demo-"
+
+func seedCommand() *cobra.Command {
+	var (
+		count    int
+		password string
+		force    bool
+	)
+	seed := &cobra.Command{
+		Use:   "seed",
+		Short: "Create demo accounts for development (dev only)",
+		Long: `Create demo accounts for development and demos: connected to Hashnode,
+LinkedIn and X with fake credentials, with a history of shares, upcoming
+schedules and notifications spread over the past weeks so analytics have
+something to show.
+
+The credentials are sealed like real ones, so APP_CREDENTIALS_SECRETS
+must be set as it is for the server. Run the server built with -tags e2e and
+PROVIDER_STUB_URL pointing at go run ./cmd/providerstub so their Hashnode
+posts, shares and schedules work without real OAuth apps.
+Existing demo accounts are left alone. The command refuses to touch a
+database holding other users unless --force is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			if !force {
+				users, err := repo.ListUsers(0)
+				if err != nil {
+					return err
+				}
+				for _, user := range users {
+					if !strings.HasPrefix(user.UserName, demoUserPrefix) {
+						return fmt.Errorf("%s has real users (e.g. %s); seed a development database or pass --force", mongoDB, user.UserName)
+					}
+				}
+			}
+
+			hash, err := services.HashPassword(password)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			for i := 1; i <= count; i++ {
+				userName := fmt.Sprintf("%s%d", demoUserPrefix, i)
+				existing, err := repo.GetUserByName(userName)
+				if err != nil {
+					return err
+				}
+				if existing != nil {
+					fmt.Printf("%s already exists\n", userName)
+					continue
+				}
+				userID, err := seedUser(demoUser(userName, hash, i, now))
+				if err != nil {
+					return fmt.Errorf("seeding %s: %w", userName, err)
+				}
+				fmt.Printf("Created %s (%s)\n", userName, userID)
+			}
+			fmt.Printf("Log in with any demo-N account and the password %q. Restart a running server to queue the new schedules.\n", password)
+			return nil
+		},
+	}
+	seed.Flags().IntVar(&count, "users", 3, "Number of demo accounts")
+	seed.Flags().StringVar(&password, "password", "demo-password", "Password of every demo account")
+	seed.Flags().BoolVar(&force, "force", false, "Seed even if the database has non-demo users")
+	return seed
+}
+
+// demoUser builds the n-th demo account. Accounts differ in age, activity
+// and how much they have shared, so the product metrics have a spread.
+func demoUser(userName, passwordHash string, n int, now time.Time) models.User {
+	platforms := []string{"linkedin", "twitter"}
+	user := models.User{
+		UserName:         userName,
+		PassWord:         passwordHash,
+		Email:            userName + "@example.com",
+		Verified:         true,
+		EmailVerified:    true,
+		HashnodeVerified: true,
+		LinkedinVerified: true,
+		XVerified:        true,
+		HashnodeBlog:     "demo.example.com",
+		Plan:             models.PlanFree,
+		Region:           models.RegionDefault,
+		Preferences:      models.Preferences{DefaultPlatforms: platforms},
+		CreatedAt:        now.Add(-time.Duration(n*9+2) * 24 * time.Hour),
+		LastActiveAt:     now.Add(-time.Duration(n*n) * time.Hour),
+	}
+
+	posts := providertest.DemoPosts
+	// Older accounts have shared more; the newest post is left unshared
+	shared := len(posts) - 1 - (n-1)%(len(posts)-1)
+	for i := 0; i < shared; i++ {
+		post := posts[i]
+		sharedAt := now.Add(-time.Duration(n) * time.Hour).Add(-time.Duration((shared-1-i)*(n+2)) * 19 * time.Hour)
+		user.SharedBlogs = append(user.SharedBlogs, models.SharedBlog{
+			Blog:       demoBlog(post),
+			Platforms:  platforms,
+			SharedTime: sharedAt.Format(time.RFC3339),
+		})
+		user.AddNotification(fmt.Sprintf("Your blog %q was shared on LinkedIn and X", post.Title), sharedAt)
+	}
+	// The newest post is scheduled within the week the API accepts
+	last := posts[len(posts)-1]
+	user.ScheduledBlogs = append(user.ScheduledBlogs, models.ScheduledBlog{
+		Blog:          demoBlog(last),
+		Platforms:     platforms,
+		ScheduledTime: now.Add(time.Duration((n-1)%6+1) * 26 * time.Hour).UTC().Truncate(time.Minute),
+	})
+	return user
+}
+
+func demoBlog(post providertest.DemoPost) models.Blog {
+	return models.Blog{
+		Id:                post.ID,
+		Title:             post.Title,
+		Url:               post.URL(),
+		CoverImage:        models.Image{URL: post.CoverImage()},
+		Author:            models.Author{Name: providertest.DemoAuthor},
+		ReadTimeInMinutes: post.ReadTimeInMinutes,
+	}
+}
+
+// seedUser gives the user fake credentials, sealed like real ones, stores
+// them and queues their schedules.
+func seedUser(user models.User) (string, error) {
+	user.Id = primitive.NewObjectID()
+	credentials := []struct {
+		sealed, plaintext *string
+		value             string
+	}{
+		{&user.SealedHashnodePAT, &user.PlaintextHashnodePAT, "demo-hashnode-pat"},
+		{&user.SealedLinkedInOauthKey, &user.PlaintextLinkedInOauthKey, "demo-linkedin-token"},
+		{&user.SealedXOAuthToken, &user.PlaintextXOAuthToken, "demo-x-token"},
+		{&user.SealedXOAuthSecret, &user.PlaintextXOAuthSecret, "demo-x-secret"},
+	}
+	for _, credential := range credentials {
+		if err := services.SealCredential(&user, credential.sealed, credential.plaintext, credential.value); err != nil {
+			return "", err
+		}
+	}
+	userID, err := repo.CreateUser(user)
+	if err != nil {
+		return "", err
+	}
+	for _, scheduled := range user.ScheduledBlogs {
+		task := models.ScheduledBlogData{UserID: userID, ScheduledBlog: scheduled}
+		if err := repo.StoreScheduledTask(task); err != nil {
+			return "", err
+		}
+	}
+	return userID, nil
+}
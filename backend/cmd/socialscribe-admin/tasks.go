@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+func tasksCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "tasks",
+		Short: "Inspect and repair scheduled shares",
+	}
+
+	var userID string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List persisted scheduled shares",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			tasks, err := repo.GetScheduledTasks()
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(out, "USER\tBLOG\tREGION\tPLATFORMS\tSCHEDULED\tOVERDUE")
+			for _, task := range tasks {
+				if userID != "" && task.UserID != userID {
+					continue
+				}
+				scheduled := task.ScheduledBlog.ScheduledTime
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%t\n",
+					task.UserID, task.ScheduledBlog.Id, task.Region, strings.Join(task.ScheduledBlog.Platforms, ","),
+					scheduled.Format(time.RFC3339), scheduled.Before(now))
+			}
+			return out.Flush()
+		},
+	}
+	list.Flags().StringVar(&userID, "user", "", "Only list the tasks of this user id")
+
+	reset := &cobra.Command{
+		Use:   "reset <user-id> <blog-id>",
+		Short: "Clear a stuck scheduled share so it can be scheduled again",
+		Long: `Clear a stuck scheduled share so it can be scheduled again.
+
+The persisted task and the user's scheduled entry are both removed. Use it for
+shares that never fired or whose run failed half way; a task still queued on a
+running server fires anyway, so cancel healthy tasks through the API instead.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, blogID := args[0], args[1]
+			if err := connect(); err != nil {
+				return err
+			}
+			return resetTask(userID, blogID)
+		},
+	}
+
+	dedupe := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Remove blogs queued twice so the pending task index can be built",
+		Long: `Remove blogs queued twice so the pending task index can be built.
+
+Of the tasks queued for the same user, blog and platform, the first is kept.
+Run it once when migrate fails to create the unique scheduled task index, then
+run migrate again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			removed, err := repo.DropDuplicateScheduledTasks()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d duplicate scheduled tasks\n", removed)
+			return nil
+		},
+	}
+
+	group.AddCommand(list, reset, dedupe)
+	return group
+}
+
+func resetTask(userID, blogID string) error {
+	user, err := repo.GetUserById(userID)
+	if err != nil {
+		return fmt.Errorf("loading user %s: %w", userID, err)
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", userID)
+	}
+
+	task := models.ScheduledBlogData{UserID: userID}
+	task.ScheduledBlog.Id = blogID
+	if err := repo.DeleteScheduledTask(task); err != nil {
+		return fmt.Errorf("deleting the scheduled task: %w", err)
+	}
+
+	removed := false
+	for i := range user.ScheduledBlogs {
+		if user.ScheduledBlogs[i].Id == blogID {
+			user.ScheduledBlogs = append(user.ScheduledBlogs[:i], user.ScheduledBlogs[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if removed {
+		if err := repo.UpdateUser(userID, user); err != nil {
+			return fmt.Errorf("updating user %s: %w", userID, err)
+		}
+	}
+	fmt.Printf("Reset blog %s for user %s\n", blogID, userID)
+	return nil
+}
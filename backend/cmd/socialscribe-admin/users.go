@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+func usersCommand() *cobra.Command {
+	group := &cobra.Command{
+		Use:   "users",
+		Short: "Inspect user accounts",
+	}
+
+	var limit int64
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List users across every region, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := connect(); err != nil {
+				return err
+			}
+			users, err := repo.ListUsers(limit)
+			if err != nil {
+				return err
+			}
+			out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(out, "ID\tUSERNAME\tREGION\tPLAN\tROLE\tVERIFIED\tDISABLED\tSCHEDULED\tCREATED")
+			for _, user := range users {
+				role := user.Role
+				if role == models.RoleUser {
+					role = "user"
+				}
+				fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%t\t%t\t%d\t%s\n",
+					user.Id.Hex(), user.UserName, user.Region, user.Plan, role, user.Verified, user.Disabled,
+					len(user.ScheduledBlogs), user.CreatedAt.Format(time.RFC3339))
+			}
+			return out.Flush()
+		},
+	}
+	list.Flags().Int64Var(&limit, "limit", 100, "Maximum number of users to list; 0 for all")
+
+	setRole := &cobra.Command{
+		Use:   "set-role <user-id> <admin|user>",
+		Short: "Grant or take away the admin role",
+		Long: `Grant or take away the admin role.
+
+Admins manage other users through the API, so use this to appoint the first
+admin; later ones can be appointed by an existing admin.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, role := args[0], args[1]
+			switch role {
+			case "admin":
+				role = models.RoleAdmin
+			case "user":
+				role = models.RoleUser
+			default:
+				return fmt.Errorf("role must be admin or user, got %q", role)
+			}
+			if err := connect(); err != nil {
+				return err
+			}
+			user, err := repo.GetUserById(userID)
+			if err != nil {
+				return fmt.Errorf("loading user %s: %w", userID, err)
+			}
+			if user == nil {
+				return fmt.Errorf("user %s not found", userID)
+			}
+			user.Role = role
+			if err := repo.UpdateUser(userID, user); err != nil {
+				return fmt.Errorf("updating user %s: %w", userID, err)
+			}
+			fmt.Printf("User %s (%s) is now %s\n", userID, user.UserName, args[1])
+			return nil
+		},
+	}
+
+	group.AddCommand(list, setRole)
+	return group
+}
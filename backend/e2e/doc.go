@@ -0,0 +1,16 @@
+// Package e2e runs the signup, connect, schedule and fire flow against a
+// real server binary backed by MongoDB and Redis. Provider calls go to an
+// in-process stub (providertest.Stub) that records them, so the tests can
+// assert on what would have been posted to LinkedIn and X.
+//
+// The tests are behind the e2e build tag. make e2e starts MongoDB and Redis
+// from docker-compose.yml, runs them and tears everything down again:
+//
+//	make e2e
+//
+// To run against services that are already up:
+//
+//	E2E_MONGO_URI=mongodb://localhost:27017 E2E_REDIS_ADDR=localhost:6379 go test -tags e2e ./e2e -v
+//
+// Each run uses, then drops, its own database.
+package e2e
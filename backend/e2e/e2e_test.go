@@ -0,0 +1,353 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/providertest"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+var (
+	// baseURL is the API root of the server under test
+	baseURL string
+	stub    = &providertest.Stub{}
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run starts the stub and the server binary, and stops them again after the
+// tests. It returns the exit code.
+func run(m *testing.M) int {
+	mongoURI := envOr("E2E_MONGO_URI", "mongodb://localhost:27017")
+	redisAddr := envOr("E2E_REDIS_ADDR", "localhost:6379")
+	dbName := fmt.Sprintf("social-scribe-e2e-%d", time.Now().UnixNano())
+
+	// The tests read persisted state straight from the server's database
+	if err := repo.Connect(mongoURI, dbName); err != nil {
+		fmt.Fprintf(os.Stderr, "connecting to %s: %v\n", mongoURI, err)
+		return 2
+	}
+	defer func() {
+		if err := repo.Disconnect(dbName); err != nil {
+			fmt.Fprintf(os.Stderr, "dropping %s: %v\n", dbName, err)
+		}
+	}()
+
+	stubServer := httptest.NewServer(stub)
+	defer stubServer.Close()
+
+	// The server seals provider tokens with this key, and the tests open them
+	os.Setenv("APP_CREDENTIALS_SECRETS", "e2e-credentials-key")
+
+	dir, err := os.MkdirTemp("", "social-scribe-e2e")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating work dir: %v\n", err)
+		return 2
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "social-scribe")
+	// The e2e tag builds in the provider stub support
+	build := exec.Command("go", "build", "-tags", "e2e", "-o", binary, "./cmd")
+	build.Dir = ".."
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "building the server: %v\n", err)
+		return 2
+	}
+
+	port, err := freePort()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "finding a free port: %v\n", err)
+		return 2
+	}
+	logPath := filepath.Join(dir, "server.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating server log: %v\n", err)
+		return 2
+	}
+	defer logFile.Close()
+
+	server := exec.Command(binary)
+	// main loads ../../.env relative to its working directory
+	server.Dir = "../cmd"
+	server.Stdout, server.Stderr = logFile, logFile
+	server.Env = append(os.Environ(),
+		"BACKEND_PORT="+port,
+		"CONFIG_FILE=",
+		"MONGO_URI="+mongoURI,
+		"MONGO_DB="+dbName,
+		"REDIS_ADDR="+redisAddr,
+		"PROVIDER_STUB_URL="+stubServer.URL,
+		"LINKEDIN_CLIENT_ID=e2e-linkedin-client",
+		"LINKEDIN_CLIENT_SECRET=e2e-linkedin-secret",
+		"LINKEDIN_CALLBACK_URL=http://localhost:"+port+"/api/v1/user/linkedin-callback",
+		"TWITTER_CONSUMER_KEY=e2e-x-key",
+		"TWITTER_CONSUMER_SECRET=e2e-x-secret",
+		"TWITTER_CALLBACK_URL=http://localhost:"+port+"/api/v1/user/twitter-callback",
+	)
+	if err := server.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "starting the server: %v\n", err)
+		return 2
+	}
+	defer func() {
+		server.Process.Signal(syscall.SIGTERM)
+		server.Wait()
+	}()
+
+	baseURL = "http://localhost:" + port + "/api/v1"
+	code := 2
+	if err := waitForServer(30 * time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "waiting for the server: %v\n", err)
+	} else {
+		code = m.Run()
+	}
+	if code != 0 {
+		if serverLog, err := os.ReadFile(logPath); err == nil {
+			fmt.Fprintf(os.Stderr, "--- server log ---\n%s", serverLog)
+		}
+	}
+	return code
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func freePort() (string, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return fmt.Sprint(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+func waitForServer(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(baseURL + "/openapi.json")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no answer from %s after %s: %v", baseURL, timeout, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// client is a browser-like API client: it keeps cookies and doesn't follow
+// redirects, so OAuth hops can be checked one at a time.
+type client struct {
+	t    *testing.T
+	http *http.Client
+	// csrf is sent with every request once fetched
+	csrf string
+}
+
+func newClient(t *testing.T) *client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("creating cookie jar: %v", err)
+	}
+	return &client{t: t, http: &http.Client{
+		Jar:     jar,
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// do sends body, JSON encoded unless nil, and fails the test unless the
+// response status is want.
+func (c *client) do(method, path string, body interface{}, want int) *http.Response {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("encoding %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		c.t.Fatalf("building %s %s: %v", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.csrf != "" {
+		req.Header.Set(services.CSRFHeader, c.csrf)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != want {
+		c.t.Fatalf("%s %s: status %d, want %d; body %q", method, path, resp.StatusCode, want, data)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp
+}
+
+// fetchCSRF fetches the CSRF token of the client's session, which
+// state-changing requests must carry.
+func (c *client) fetchCSRF() {
+	c.t.Helper()
+	resp := c.do(http.MethodGet, "/csrf", nil, http.StatusOK)
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Token == "" {
+		c.t.Fatalf("decoding CSRF token: %v", err)
+	}
+	c.csrf = body.Token
+}
+
+// redirectQuery returns the query of the URL a redirect points at.
+func redirectQuery(t *testing.T, resp *http.Response) url.Values {
+	t.Helper()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("redirect without a location: %v", err)
+	}
+	return location.Query()
+}
+
+func TestScheduledShareFlow(t *testing.T) {
+	c := newClient(t)
+	userName := fmt.Sprintf("e2e%d", time.Now().UnixNano())
+
+	c.do(http.MethodPost, "/user/signup", map[string]string{"username": userName, "password": "correct-horse-battery"}, http.StatusCreated)
+	c.fetchCSRF()
+
+	// Connect Hashnode, LinkedIn and X
+	c.do(http.MethodPost, "/user/verify-hashnode", map[string]string{"key": "e2e-hashnode-pat"}, http.StatusOK)
+
+	resp := c.do(http.MethodGet, "/user/connect-linkedin", nil, http.StatusFound)
+	state := redirectQuery(t, resp).Get("state")
+	if state == "" {
+		t.Fatal("LinkedIn authorization URL has no state")
+	}
+	c.do(http.MethodGet, "/user/linkedin-callback?"+url.Values{"state": {state}, "code": {"e2e-code"}}.Encode(), nil, http.StatusSeeOther)
+
+	resp = c.do(http.MethodGet, "/user/connect-twitter", nil, http.StatusFound)
+	requestToken := redirectQuery(t, resp).Get("oauth_token")
+	c.do(http.MethodGet, "/user/twitter-callback?"+url.Values{"oauth_token": {requestToken}, "oauth_verifier": {"e2e-verifier"}}.Encode(), nil, http.StatusSeeOther)
+
+	user := loadUser(t, userName)
+	if !user.Verified || !user.HashnodeVerified || !user.LinkedinVerified || !user.XVerified {
+		t.Fatalf("user not fully connected: verified=%t hashnode=%t linkedin=%t x=%t", user.Verified, user.HashnodeVerified, user.LinkedinVerified, user.XVerified)
+	}
+	if user.PlaintextLinkedInOauthKey != "" || user.PlaintextXOAuthToken != "" {
+		t.Fatal("provider tokens stored unsealed")
+	}
+	linkedinToken, err := services.OpenCredential(user, user.SealedLinkedInOauthKey, user.PlaintextLinkedInOauthKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xToken, err := services.OpenCredential(user, user.SealedXOAuthToken, user.PlaintextXOAuthToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkedinToken != "fake-linkedin-token" || xToken != "fake-x-token" {
+		t.Fatalf("provider tokens not stored: linkedin %q, x %q", linkedinToken, xToken)
+	}
+
+	// Schedule a share a few seconds out and wait for it to fire
+	c.do(http.MethodPost, "/blogs/schedule", map[string]interface{}{
+		"blog": map[string]interface{}{
+			"id":             providertest.PostID,
+			"title":          "Benchmarking the share pipeline",
+			"url":            "https://blog.example.com/perf",
+			"coverImage":     map[string]string{"url": "https://cdn.example.com/cover.png"},
+			"author":         map[string]string{"name": "Perf Author"},
+			"platforms":      []string{"linkedin", "twitter"},
+			"scheduled_time": time.Now().Add(3 * time.Second).UTC().Format(time.RFC3339),
+		},
+	}, http.StatusOK)
+
+	if user = loadUser(t, userName); len(user.ScheduledBlogs) != 1 {
+		t.Fatalf("%d scheduled posts persisted, want 1", len(user.ScheduledBlogs))
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for len(user.SharedBlogs) == 0 || len(user.ScheduledBlogs) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduled share didn't fire: %d shared, %d still scheduled", len(user.SharedBlogs), len(user.ScheduledBlogs))
+		}
+		time.Sleep(500 * time.Millisecond)
+		user = loadUser(t, userName)
+	}
+
+	if got := user.SharedBlogs[0].Id; got != providertest.PostID {
+		t.Errorf("shared blog %q, want %q", got, providertest.PostID)
+	}
+	tasks, err := repo.GetScheduledTasks()
+	if err != nil {
+		t.Fatalf("loading scheduled tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.UserID == user.Id.Hex() {
+			t.Errorf("scheduled task for %s left behind after it fired", task.ScheduledBlog.Id)
+		}
+	}
+
+	// The stub saw the posts that would have gone out
+	assertPosted(t, "api.linkedin.com", "/v2/ugcPosts")
+	assertPosted(t, "api.twitter.com", "/1.1/statuses/update.json")
+}
+
+func loadUser(t *testing.T, userName string) *models.User {
+	t.Helper()
+	user, err := repo.GetUserByName(userName)
+	if err != nil || user == nil {
+		t.Fatalf("loading user %s: %v", userName, err)
+	}
+	return user
+}
+
+func assertPosted(t *testing.T, host, path string) {
+	t.Helper()
+	for _, req := range stub.Requests() {
+		if req.Method == http.MethodPost && req.Host == host && req.Path == path {
+			// X gets a form body and LinkedIn JSON; both carry the AI copy
+			if !strings.Contains(req.Body, "benchmarking") {
+				t.Errorf("post to %s%s doesn't carry the generated copy: %q", host, path, req.Body)
+			}
+			return
+		}
+	}
+	t.Errorf("nothing was posted to %s%s", host, path)
+}
@@ -0,0 +1,46 @@
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Domain errors returned by repositories and services. Callers wrap them with
+// fmt.Errorf("...: %w", ErrX) to add context; handlers map them to HTTP status
+// codes through HTTPStatus instead of guessing per call site.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrProviderRateLimited = errors.New("provider rate limited")
+	// ErrProviderAuth is a provider rejecting the credentials the user
+	// connected, not the user's own session, so it must not log them out.
+	ErrProviderAuth = errors.New("reconnect the provider account")
+)
+
+// HTTPStatus returns the status code for err, defaulting to 500 for errors
+// that don't wrap a domain error.
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrProviderRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrProviderAuth):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,396 @@
+// Package config holds the non-secret runtime settings that operators can tune
+// without restarting the server. Secrets and connection settings stay in the
+// environment; everything here is read from the JSON file named by
+// CONFIG_FILE and can be reloaded on SIGHUP or through the admin API.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultFrontendURL = "http://localhost:5173"
+
+const (
+	defaultSessionLifetime    = 24 * time.Hour
+	defaultRememberMeLifetime = 30 * 24 * time.Hour
+	// MaxSessionLifetime caps session_lifetime_hours. Anything that must
+	// outlast every session, such as JWT revocations, is kept this long.
+	MaxSessionLifetime = 30 * 24 * time.Hour
+)
+
+// PostingWindow restricts scheduled posts to the hours [StartHour, EndHour)
+// in UTC. A zero window allows any time; EndHour may be smaller than
+// StartHour for windows that wrap past midnight.
+type PostingWindow struct {
+	StartHour int `json:"start_hour"`
+	EndHour   int `json:"end_hour"`
+}
+
+// Allows reports whether t falls inside the window.
+func (pw PostingWindow) Allows(t time.Time) bool {
+	if pw.StartHour == pw.EndHour {
+		return true
+	}
+	hour := t.UTC().Hour()
+	if pw.StartHour < pw.EndHour {
+		return hour >= pw.StartHour && hour < pw.EndHour
+	}
+	return hour >= pw.StartHour || hour < pw.EndHour
+}
+
+// MaxSmoothingWindow caps smoothing.window_seconds.
+const MaxSmoothingWindow = 15 * time.Minute
+
+// Smoothing spreads scheduled shares that fall due together, so a burst of
+// them doesn't run into the providers' rate limits. The zero value starts
+// every share at its due time.
+type Smoothing struct {
+	// WindowSeconds is the most a share is started after its due time.
+	WindowSeconds int `json:"window_seconds"`
+	// PerMinute paces the shares started per platform, such as
+	// {"twitter": 30}. Platforms left out aren't paced.
+	PerMinute map[string]int `json:"per_minute"`
+}
+
+// Window returns how late a share may start.
+func (s Smoothing) Window() time.Duration {
+	return time.Duration(s.WindowSeconds) * time.Second
+}
+
+// Interval returns the gap kept between two shares started on platform, 0
+// when it isn't paced.
+func (s Smoothing) Interval(platform string) time.Duration {
+	perMinute := s.PerMinute[platform]
+	if s.WindowSeconds == 0 || perMinute <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(perMinute)
+}
+
+// CookiePolicy sets the attributes of the cookies the server hands out.
+type CookiePolicy struct {
+	// Secure marks cookies HTTPS-only. Unset, cookies are secure whenever
+	// the frontend is served over https.
+	Secure *bool `json:"secure"`
+	// SameSite is "lax" (the default) or "none"; "none" needs secure
+	// cookies and is what a frontend on another site requires. "strict" is
+	// refused: providers redirect back to the connect callbacks cross-site,
+	// and those need the session cookie.
+	SameSite string `json:"same_site"`
+	// Domain shares cookies with the subdomains of a parent domain; empty
+	// scopes them to the API host.
+	Domain string `json:"domain"`
+}
+
+// Terms names the current versions of the terms of service and privacy
+// policy. Users who haven't accepted them can't make requests that write;
+// an empty version isn't required.
+type Terms struct {
+	TermsOfService string `json:"terms_of_service"`
+	PrivacyPolicy  string `json:"privacy_policy"`
+}
+
+// maxTermsVersion caps the length of terms versions.
+const maxTermsVersion = 64
+
+// maxDisabledReason caps the length of the reasons platforms are disabled
+// for.
+const maxDisabledReason = 200
+
+type Config struct {
+	FrontendURL string `json:"frontend_url"`
+	// Cookies defaults to COOKIE_SECURE, COOKIE_SAMESITE and COOKIE_DOMAIN
+	// from the environment.
+	Cookies CookiePolicy `json:"cookies"`
+	// RateLimits overrides the per-minute request limit of routes by name.
+	RateLimits map[string]int `json:"rate_limits"`
+	// FeatureFlags switches features off by name; unknown flags are enabled.
+	FeatureFlags  map[string]bool `json:"feature_flags"`
+	PostingWindow PostingWindow   `json:"posting_window"`
+	Smoothing     Smoothing       `json:"smoothing"`
+	// SessionTokens selects what logins hand out: "opaque" (the default)
+	// tokens looked up in the session cache, or signed "jwt" tokens. Both
+	// are accepted whichever is selected, so switching is seamless.
+	SessionTokens string `json:"session_tokens"`
+	// SessionLifetimeHours is how long a session lasts from login or its
+	// last refresh; 0 means 24 hours.
+	SessionLifetimeHours int `json:"session_lifetime_hours"`
+	// RememberMeLifetimeHours replaces SessionLifetimeHours for logins that
+	// ask to be remembered; 0 means 30 days.
+	RememberMeLifetimeHours int `json:"remember_me_lifetime_hours"`
+	// CountryHeader names the request header in which the proxy in front of
+	// the server reports the client's country, such as CF-IPCountry. Empty,
+	// logins aren't located.
+	CountryHeader string `json:"country_header"`
+	Terms         Terms  `json:"terms"`
+	// DisabledPlatforms turns platform connectors off by name, such as
+	// while a provider has suspended the app's keys. The value is the reason
+	// shown to users.
+	DisabledPlatforms map[string]string `json:"disabled_platforms"`
+}
+
+// RateLimit returns the configured limit for a route, or fallback when the
+// route has no override.
+func (c *Config) RateLimit(route string, fallback int) int {
+	if limit, ok := c.RateLimits[route]; ok && limit > 0 {
+		return limit
+	}
+	return fallback
+}
+
+// SessionLifetime returns how long new and refreshed sessions last.
+func (c *Config) SessionLifetime() time.Duration {
+	if c.SessionLifetimeHours == 0 {
+		return defaultSessionLifetime
+	}
+	return time.Duration(c.SessionLifetimeHours) * time.Hour
+}
+
+// RememberMeLifetime returns how long sessions of "remember me" logins last.
+// It is never shorter than SessionLifetime.
+func (c *Config) RememberMeLifetime() time.Duration {
+	lifetime := defaultRememberMeLifetime
+	if c.RememberMeLifetimeHours != 0 {
+		lifetime = time.Duration(c.RememberMeLifetimeHours) * time.Hour
+	}
+	if lifetime < c.SessionLifetime() {
+		return c.SessionLifetime()
+	}
+	return lifetime
+}
+
+// CookieSecure reports whether cookies are marked Secure.
+func (c *Config) CookieSecure() bool {
+	if c.Cookies.Secure != nil {
+		return *c.Cookies.Secure
+	}
+	return strings.HasPrefix(c.FrontendURL, "https://")
+}
+
+// CookieSameSite returns the SameSite mode of cookies.
+func (c *Config) CookieSameSite() http.SameSite {
+	switch c.Cookies.SameSite {
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// ApplyCookiePolicy sets the Secure, SameSite and Domain attributes of a
+// cookie about to be set.
+func (c *Config) ApplyCookiePolicy(cookie *http.Cookie) {
+	cookie.Secure = c.CookieSecure()
+	cookie.SameSite = c.CookieSameSite()
+	cookie.Domain = c.Cookies.Domain
+}
+
+// JWTSessions reports whether new sessions get signed JWTs.
+func (c *Config) JWTSessions() bool {
+	return c.SessionTokens == "jwt"
+}
+
+// RequestCountry returns the upper-case ISO country code the proxy reported
+// for the request, or "" when it reported none or an unknown country.
+func (c *Config) RequestCountry(r *http.Request) string {
+	if c.CountryHeader == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(c.CountryHeader)))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' || country == "XX" {
+		return ""
+	}
+	return country
+}
+
+// FeatureEnabled reports whether a feature flag is on. Features default to on
+// so a missing config file never disables anything.
+func (c *Config) FeatureEnabled(flag string) bool {
+	enabled, ok := c.FeatureFlags[flag]
+	return !ok || enabled
+}
+
+// PlatformDisabled reports whether the platform's connector is turned off,
+// and why.
+func (c *Config) PlatformDisabled(platform string) (string, bool) {
+	reason, disabled := c.DisabledPlatforms[platform]
+	return reason, disabled
+}
+
+func (c *Config) validate() error {
+	for route, limit := range c.RateLimits {
+		if limit <= 0 {
+			return fmt.Errorf("rate limit for %q must be positive", route)
+		}
+	}
+	if c.PostingWindow.StartHour < 0 || c.PostingWindow.StartHour > 23 ||
+		c.PostingWindow.EndHour < 0 || c.PostingWindow.EndHour > 23 {
+		return fmt.Errorf("posting window hours must be between 0 and 23")
+	}
+	if c.Smoothing.WindowSeconds < 0 || c.Smoothing.Window() > MaxSmoothingWindow {
+		return fmt.Errorf("smoothing.window_seconds must be between 0 and %d", int(MaxSmoothingWindow.Seconds()))
+	}
+	for platform, perMinute := range c.Smoothing.PerMinute {
+		if perMinute <= 0 || perMinute > 6000 {
+			return fmt.Errorf("smoothing.per_minute for %q must be between 1 and 6000", platform)
+		}
+	}
+	if !strings.HasPrefix(c.FrontendURL, "http://") && !strings.HasPrefix(c.FrontendURL, "https://") {
+		return fmt.Errorf("frontend_url must be an http(s) URL")
+	}
+	if c.SessionLifetimeHours < 0 || time.Duration(c.SessionLifetimeHours)*time.Hour > MaxSessionLifetime {
+		return fmt.Errorf("session_lifetime_hours must be between 0 and %d", int(MaxSessionLifetime.Hours()))
+	}
+	if c.RememberMeLifetimeHours < 0 || time.Duration(c.RememberMeLifetimeHours)*time.Hour > MaxSessionLifetime {
+		return fmt.Errorf("remember_me_lifetime_hours must be between 0 and %d", int(MaxSessionLifetime.Hours()))
+	}
+	switch c.Cookies.SameSite {
+	case "", "lax":
+	case "strict":
+		return fmt.Errorf("cookies.same_site strict would keep the session cookie off the redirects back from OAuth providers; use lax")
+	case "none":
+		if !c.CookieSecure() {
+			return fmt.Errorf("cookies.same_site none requires secure cookies")
+		}
+	default:
+		return fmt.Errorf("cookies.same_site must be lax or none")
+	}
+	if strings.ContainsAny(c.Cookies.Domain, "/:; ") {
+		return fmt.Errorf("cookies.domain must be a bare domain name")
+	}
+	if strings.ContainsAny(c.CountryHeader, " :\r\n") {
+		return fmt.Errorf("country_header must be a header name")
+	}
+	if len(c.Terms.TermsOfService) > maxTermsVersion || len(c.Terms.PrivacyPolicy) > maxTermsVersion {
+		return fmt.Errorf("terms versions must be at most %d characters", maxTermsVersion)
+	}
+	for platform, reason := range c.DisabledPlatforms {
+		if len(reason) > maxDisabledReason {
+			return fmt.Errorf("disabled_platforms reason for %q must be at most %d characters", platform, maxDisabledReason)
+		}
+	}
+	switch c.SessionTokens {
+	case "", "opaque":
+	case "jwt":
+		if strings.TrimSpace(os.Getenv("SESSION_JWT_SECRETS")) == "" {
+			return fmt.Errorf("session_tokens is jwt but SESSION_JWT_SECRETS is not set")
+		}
+	default:
+		return fmt.Errorf("session_tokens must be opaque or jwt")
+	}
+	return nil
+}
+
+func defaults() *Config {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = defaultFrontendURL
+	}
+	cookies := CookiePolicy{
+		SameSite: strings.ToLower(strings.TrimSpace(os.Getenv("COOKIE_SAMESITE"))),
+		Domain:   strings.TrimSpace(os.Getenv("COOKIE_DOMAIN")),
+	}
+	if secure, err := strconv.ParseBool(os.Getenv("COOKIE_SECURE")); err == nil {
+		cookies.Secure = &secure
+	}
+	return &Config{
+		FrontendURL:       frontendURL,
+		Cookies:           cookies,
+		RateLimits:        map[string]int{},
+		FeatureFlags:      map[string]bool{},
+		DisabledPlatforms: map[string]string{},
+	}
+}
+
+var (
+	current  atomic.Pointer[Config]
+	reloadMu sync.Mutex
+	modTime  time.Time
+)
+
+// Get returns the active configuration. The returned value must be treated
+// as read-only; reloads swap in a new Config rather than mutating it.
+func Get() *Config {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	c := defaults()
+	current.CompareAndSwap(nil, c)
+	return current.Load()
+}
+
+// Reload re-reads CONFIG_FILE and atomically replaces the active
+// configuration. On any error the previous configuration stays in effect.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	c := defaults()
+	path := os.Getenv("CONFIG_FILE")
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat config file: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %v", err)
+		}
+		if err := json.Unmarshal(data, c); err != nil {
+			return fmt.Errorf("failed to parse config file: %v", err)
+		}
+		modTime = info.ModTime()
+	}
+	c.FrontendURL = strings.TrimRight(c.FrontendURL, "/")
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	current.Store(c)
+	log.Printf("[INFO] Configuration loaded (frontend %s, %d rate limit overrides, %d feature flags)", c.FrontendURL, len(c.RateLimits), len(c.FeatureFlags))
+	return nil
+}
+
+// Watch polls CONFIG_FILE and reloads it whenever its modification time
+// changes, until stop is closed.
+func Watch(interval time.Duration, stop <-chan struct{}) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			reloadMu.Lock()
+			changed := !info.ModTime().Equal(modTime)
+			reloadMu.Unlock()
+			if !changed {
+				continue
+			}
+			if err := Reload(); err != nil {
+				log.Printf("[ERROR] Config reload failed: %v", err)
+				// Do not retry the same broken file on every tick
+				reloadMu.Lock()
+				modTime = info.ModTime()
+				reloadMu.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCookiePolicy(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+	c := defaults()
+	cookie := &http.Cookie{Name: "session_token"}
+	c.ApplyCookiePolicy(cookie)
+	if !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.Domain != "" {
+		t.Errorf("https frontend default = secure %t, same site %v, domain %q; want secure lax cookies", cookie.Secure, cookie.SameSite, cookie.Domain)
+	}
+
+	t.Setenv("FRONTEND_URL", "http://localhost:5173")
+	t.Setenv("COOKIE_SAMESITE", "None")
+	t.Setenv("COOKIE_DOMAIN", "example.com")
+	c = defaults()
+	if err := c.validate(); err == nil {
+		t.Error("same_site none without secure cookies passed validation")
+	}
+	t.Setenv("COOKIE_SECURE", "true")
+	c = defaults()
+	if err := c.validate(); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	cookie = &http.Cookie{Name: "session_token"}
+	c.ApplyCookiePolicy(cookie)
+	if !cookie.Secure || cookie.SameSite != http.SameSiteNoneMode || cookie.Domain != "example.com" {
+		t.Errorf("env policy = secure %t, same site %v, domain %q", cookie.Secure, cookie.SameSite, cookie.Domain)
+	}
+
+	for _, sameSite := range []string{"sometimes", "strict"} {
+		c.Cookies.SameSite = sameSite
+		if err := c.validate(); err == nil {
+			t.Errorf("same_site %s passed validation", sameSite)
+		}
+	}
+}
+
+func TestRequestCountry(t *testing.T) {
+	c := defaults()
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("CF-IPCountry", "de")
+	if got := c.RequestCountry(r); got != "" {
+		t.Errorf("without a country header configured got %q", got)
+	}
+
+	c.CountryHeader = "CF-IPCountry"
+	for header, want := range map[string]string{"de": "DE", " US ": "US", "XX": "", "T1": "", "": "", "DEU": ""} {
+		r.Header.Set("CF-IPCountry", header)
+		if got := c.RequestCountry(r); got != want {
+			t.Errorf("RequestCountry(%q) = %q, want %q", header, got, want)
+		}
+	}
+
+	c.CountryHeader = "CF-IPCountry: DE"
+	if err := c.validate(); err == nil {
+		t.Error("country_header with a value passed validation")
+	}
+}
+
+func TestDisabledPlatforms(t *testing.T) {
+	c := defaults()
+	if _, disabled := c.PlatformDisabled("twitter"); disabled {
+		t.Error("a platform is disabled by default")
+	}
+	c.DisabledPlatforms["twitter"] = "Our X keys are suspended"
+	if reason, disabled := c.PlatformDisabled("twitter"); !disabled || reason != "Our X keys are suspended" {
+		t.Errorf("PlatformDisabled(twitter) = %q, %t", reason, disabled)
+	}
+	if err := c.validate(); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	c.DisabledPlatforms["twitter"] = strings.Repeat("x", maxDisabledReason+1)
+	if err := c.validate(); err == nil {
+		t.Error("an overlong reason passed validation")
+	}
+}
@@ -0,0 +1,113 @@
+package feedsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// lease must outlast a poll, or another instance polls the same feed
+	lease          = 5 * time.Minute
+	pollInterval   = 30 * time.Second
+	resyncInterval = services.FeedPollInterval
+	retryInterval  = 15 * time.Minute
+	// A throttled feed is left alone for a while
+	rateLimitBackoff = time.Hour
+)
+
+// Worker polls the users' feeds and adds their new items to the local posts
+// collection, where they are listed next to Hashnode posts. Feeds only hold
+// their latest items, so items that drop off a feed are kept.
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	clock  utils.Clock
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{ctx: ctx, cancel: cancel, clock: utils.GetClock()}
+	go w.run()
+	return w
+}
+
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
+func (w *Worker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Feed sync worker panicked: %v", r)
+			reporting.Report(w.ctx, fmt.Errorf("feed sync worker panicked: %v", r), map[string]string{
+				"component": "feedsync",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Feed sync worker started")
+	for {
+		w.syncDue()
+		if !w.sleep(pollInterval) {
+			log.Println("[INFO] Feed sync worker stopped")
+			return
+		}
+	}
+}
+
+// syncDue polls feeds until none is due.
+func (w *Worker) syncDue() {
+	for w.ctx.Err() == nil {
+		user, err := repo.ClaimFeedSync(w.clock.Now(), lease)
+		if err != nil || user == nil || user.Feed == nil {
+			return
+		}
+		userID := user.Id.Hex()
+		now := w.clock.Now()
+
+		err = syncFeed(userID, user.Feed.URL, now)
+		next := resyncInterval
+		lastError := ""
+		switch {
+		case err == nil:
+		case errors.Is(err, apperrors.ErrProviderRateLimited):
+			log.Printf("[WARN] The feed of user %s throttled the poll, retrying in %s", userID, rateLimitBackoff)
+			next, lastError = rateLimitBackoff, err.Error()
+		default:
+			log.Printf("[WARN] Failed to poll the feed of user %s: %v", userID, err)
+			next, lastError = retryInterval, err.Error()
+		}
+		repo.RecordFeedSync(userID, user.Feed.URL, now, lastError, now.Add(next))
+	}
+}
+
+// syncFeed adds the items of the feed at feedURL to the user's posts,
+// refreshing the ones added before.
+func syncFeed(userID, feedURL string, now time.Time) error {
+	_, posts, err := services.FetchFeed(feedURL, now.UTC())
+	if err != nil {
+		return err
+	}
+	return repo.UpsertPosts(userID, posts)
+}
+
+// sleep waits for d and reports false if the worker was stopped meanwhile.
+func (w *Worker) sleep(d time.Duration) bool {
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
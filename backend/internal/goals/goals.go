@@ -0,0 +1,105 @@
+package goals
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// A failed check is retried once the lease runs out
+	lease        = 10 * time.Minute
+	pollInterval = time.Minute
+)
+
+// Worker reminds users whose week is about to end under their posting goal.
+// Each user is only checked when their reminder is due, once a week.
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	clock  utils.Clock
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{ctx: ctx, cancel: cancel, clock: utils.GetClock()}
+	go w.run()
+	return w
+}
+
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
+func (w *Worker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Goal reminder worker panicked: %v", r)
+			reporting.Report(w.ctx, fmt.Errorf("goal reminder worker panicked: %v", r), map[string]string{
+				"component": "goals",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Goal reminder worker started")
+	for {
+		w.remindDue()
+		if !w.sleep(pollInterval) {
+			log.Println("[INFO] Goal reminder worker stopped")
+			return
+		}
+	}
+}
+
+// remindDue checks the goals of users until none is due.
+func (w *Worker) remindDue() {
+	for w.ctx.Err() == nil {
+		user, err := repo.ClaimGoalReminder(w.clock.Now(), lease)
+		if err != nil || user == nil {
+			return
+		}
+		if err := w.remind(user); err != nil {
+			log.Printf("[ERROR] Failed to check the posting goal of user %s: %v", user.Id.Hex(), err)
+		}
+	}
+}
+
+func (w *Worker) remind(user *models.User) error {
+	userID := user.Id.Hex()
+	now := w.clock.Now()
+	if user.PostingGoal == nil {
+		return nil
+	}
+	next := services.NextGoalReminder(user.PostingGoal, now)
+	message, ok := services.GoalReminder(user, now)
+	if !ok {
+		return repo.SetGoalReminderDue(userID, next)
+	}
+	user.AddNotification(message, now.UTC())
+	user.PostingGoal.RemindedWeek = services.GoalProgressAt(user, now).WeekStart
+	user.GoalReminderDueAt = next
+	if err := repo.UpdateUser(userID, user); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Reminded user %s of their posting goal", userID)
+	return nil
+}
+
+// sleep waits for d and reports false if the worker was stopped meanwhile.
+func (w *Worker) sleep(d time.Duration) bool {
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
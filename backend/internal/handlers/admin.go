@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/middlewares"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (h *Handlers) GetProviderResponsesHandler(w http.ResponseWriter, r *http.Request) {
+	userId := strings.TrimSpace(r.URL.Query().Get("user_id"))
+	if userId == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+	platform := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("platform")))
+
+	responses, err := repo.GetProviderResponses(userId, platform)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"responses": responses,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+const maxAuditEvents = 200
+
+// GetAuditLogHandler lists a user's latest audit events, newest first.
+func (h *Handlers) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["id"]
+	if userId == "" {
+		http.Error(w, "Missing user id", http.StatusBadRequest)
+		return
+	}
+	events, err := repo.GetAuditEvents(userId, maxAuditEvents)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"events": events,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+const (
+	defaultDebugCaptureTTL = 30 * time.Minute
+	maxDebugCaptureTTL     = 24 * time.Hour
+)
+
+// StartDebugCaptureHandler enables request capture either for a user, or for
+// any request presenting the debug token it returns.
+func (h *Handlers) StartDebugCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		UserId     string `json:"user_id"`
+		TTLMinutes int    `json:"ttl_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(requestBody.TTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultDebugCaptureTTL
+	}
+	if ttl > maxDebugCaptureTTL {
+		ttl = maxDebugCaptureTTL
+	}
+
+	response := map[string]interface{}{
+		"success":    true,
+		"expires_at": utils.Now().Add(ttl),
+	}
+	userId := strings.TrimSpace(requestBody.UserId)
+	if userId != "" {
+		if err := repo.EnableDebugCapture("user:"+userId, ttl); err != nil {
+			writeError(w, err)
+			return
+		}
+		response["user_id"] = userId
+		log.Printf("[INFO] Debug capture enabled for the user %s for %s", userId, ttl)
+	} else {
+		debugToken := uuid.New().String()
+		if err := repo.EnableDebugCapture("token:"+debugToken, ttl); err != nil {
+			writeError(w, err)
+			return
+		}
+		response["debug_token"] = debugToken
+		response["header"] = middlewares.DebugTokenHeader
+		log.Printf("[INFO] Debug token issued for %s", ttl)
+	}
+
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) StopDebugCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		UserId     string `json:"user_id"`
+		DebugToken string `json:"debug_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var subject string
+	switch {
+	case requestBody.UserId != "":
+		subject = "user:" + requestBody.UserId
+	case requestBody.DebugToken != "":
+		subject = "token:" + requestBody.DebugToken
+	default:
+		http.Error(w, "Missing user_id or debug_token", http.StatusBadRequest)
+		return
+	}
+	if err := repo.DisableDebugCapture(subject); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (h *Handlers) GetDebugCapturesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userId := strings.TrimSpace(query.Get("user_id"))
+	debugToken := strings.TrimSpace(query.Get("debug_token"))
+	requestId := strings.TrimSpace(query.Get("request_id"))
+	if userId == "" && debugToken == "" && requestId == "" {
+		http.Error(w, "Missing user_id, debug_token or request_id", http.StatusBadRequest)
+		return
+	}
+
+	captures, err := repo.GetDebugCaptures(userId, debugToken, requestId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"captures": captures,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// ReloadConfigHandler re-reads the runtime configuration file and returns the
+// configuration now in effect.
+func (h *Handlers) ReloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if err := config.Reload(); err != nil {
+		log.Printf("[ERROR] Config reload requested by admin failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responseJson, err := json.Marshal(config.Get())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetProductMetricsHandler summarises adoption for the product team: active
+// users, signups by cohort week, share volume and scheduler utilization.
+func (h *Handlers) GetProductMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := repo.GetProductStats(utils.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := map[string]interface{}{
+		"adoption": stats,
+	}
+	if h.taskScheduler != nil {
+		response["scheduler"] = h.taskScheduler.Stats()
+	}
+
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// RewrapDataKeysHandler runs the rewrap job after a master key rotation and
+// reports how many users it updated.
+func (h *Handlers) RewrapDataKeysHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := services.RewrapDataKeys()
+	if err != nil {
+		log.Printf("[ERROR] Rewrapping data keys failed: %v", err)
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Rewrapped the data keys of %d users with master key %s, %d failed", report.Updated, report.MasterKeyID, report.Failed)
+	responseJson, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// RotateUserDataKeyHandler gives a user a new data key, for when theirs may
+// have leaked. Their stored credentials are resealed with it.
+func (h *Handlers) RotateUserDataKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["id"]
+	user, err := repo.GetUserById(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	var previous string
+	if user.DataKey != nil {
+		previous = user.DataKey.Wrapped
+	}
+	if err := services.RotateUserDataKey(user); err != nil {
+		log.Printf("[ERROR] Failed to rotate the data key of user %s: %v", userId, err)
+		writeError(w, err)
+		return
+	}
+	if err := repo.UpdateUserKeys(userId, previous, user); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Rotated the data key of user %s to version %d", userId, user.DataKey.Version)
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"success": true,
+		"version": user.DataKey.Version,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetSchedulerDriftHandler reports the schedules stored on users that have
+// no task queued, and the tasks queued without a schedule, without repairing
+// either.
+func (h *Handlers) GetSchedulerDriftHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeSchedulerDrift(w, false)
+}
+
+// RepairSchedulerDriftHandler queues the tasks missing for users' schedules,
+// removes the tasks without one and reports what it repaired.
+func (h *Handlers) RepairSchedulerDriftHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeSchedulerDrift(w, true)
+}
+
+func (h *Handlers) writeSchedulerDrift(w http.ResponseWriter, repair bool) {
+	if h.taskScheduler == nil {
+		http.Error(w, "Scheduler is not running", http.StatusServiceUnavailable)
+		return
+	}
+	report, err := h.taskScheduler.CheckDrift(repair)
+	if err != nil {
+		log.Printf("[ERROR] Checking the scheduler for drift failed: %v", err)
+		writeError(w, err)
+		return
+	}
+	if repair {
+		log.Printf("[INFO] Repaired %d drifted scheduler tasks, %d failed", report.Repaired, report.Failed)
+	}
+	responseJson, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+func TestListUsersHandler(t *testing.T) {
+	requireMongo(t)
+	userID := newUser(t, nil)
+	for _, limit := range []string{"0", "1001", "many"} {
+		rec := serveRequest(h.ListUsersHandler, httptest.NewRequest(http.MethodGet, "/?limit="+limit, nil), "", nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("limit %s: status %d", limit, rec.Code)
+		}
+	}
+
+	var listed struct {
+		Users []struct {
+			Id string `json:"id"`
+		} `json:"users"`
+	}
+	decodeJSON(t, serveRequest(h.ListUsersHandler, httptest.NewRequest(http.MethodGet, "/?limit=1000", nil), "", nil), http.StatusOK, &listed)
+	var found bool
+	for _, user := range listed.Users {
+		found = found || user.Id == userID
+	}
+	if !found {
+		t.Errorf("user %s isn't listed in %+v", userID, listed.Users)
+	}
+}
+
+func TestUpdateUserRoleHandler(t *testing.T) {
+	requireMongo(t)
+	adminID := newUser(t, func(user *models.User) { user.Role = models.RoleAdmin })
+	userID := newUser(t, nil)
+	setRole := func(targetID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		return serveRequest(h.UpdateUserRoleHandler, req, adminID, map[string]string{"id": targetID})
+	}
+
+	for name, tc := range map[string]struct {
+		target, body string
+		status       int
+	}{
+		"unknown role": {userID, `{"role": "owner"}`, http.StatusBadRequest},
+		"own role":     {adminID, `{"role": ""}`, http.StatusBadRequest},
+		"unknown user": {"64b7f0c2a1b2c3d4e5f60718", `{"role": "admin"}`, http.StatusNotFound},
+	} {
+		if rec := setRole(tc.target, tc.body); rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", name, rec.Code, tc.status)
+		}
+	}
+
+	if rec := setRole(userID, `{"role": "admin"}`); rec.Code != http.StatusOK {
+		t.Fatalf("granting: status %d; body %q", rec.Code, rec.Body.String())
+	}
+	if user, err := repo.GetUserById(userID); err != nil || user == nil || user.Role != models.RoleAdmin {
+		t.Errorf("user after granting = %+v, %v", user, err)
+	}
+	if rec := setRole(userID, `{"role": ""}`); rec.Code != http.StatusOK {
+		t.Fatalf("taking away: status %d", rec.Code)
+	}
+	if user, err := repo.GetUserById(userID); err != nil || user == nil || user.Role != models.RoleUser {
+		t.Errorf("user after taking away = %+v, %v", user, err)
+	}
+}
+
+func TestDebugCaptureHandlers(t *testing.T) {
+	requireMongo(t)
+	userID := newUser(t, nil)
+	if rec := serve(h.StartDebugCaptureHandler, "", `{"user_id": "`+userID+`", "ttl_minutes": 5}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("starting for a user: status %d", rec.Code)
+	}
+	if !repo.IsDebugCaptureEnabled("user:" + userID) {
+		t.Error("capture isn't enabled for the user")
+	}
+
+	var issued struct {
+		DebugToken string `json:"debug_token"`
+		Header     string `json:"header"`
+	}
+	decodeJSON(t, serve(h.StartDebugCaptureHandler, "", `{}`, nil), http.StatusOK, &issued)
+	if issued.DebugToken == "" || issued.Header == "" || !repo.IsDebugCaptureEnabled("token:"+issued.DebugToken) {
+		t.Errorf("issued %+v, want an enabled debug token", issued)
+	}
+
+	if rec := serve(h.StopDebugCaptureHandler, "", `{}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("stopping without a subject: status %d", rec.Code)
+	}
+	for _, body := range []string{`{"user_id": "` + userID + `"}`, `{"debug_token": "` + issued.DebugToken + `"}`} {
+		if rec := serve(h.StopDebugCaptureHandler, "", body, nil); rec.Code != http.StatusOK {
+			t.Errorf("stopping %s: status %d", body, rec.Code)
+		}
+	}
+	if repo.IsDebugCaptureEnabled("user:"+userID) || repo.IsDebugCaptureEnabled("token:"+issued.DebugToken) {
+		t.Error("capture is still enabled after stopping")
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultAnalyticsRange = 90 * 24 * time.Hour
+	maxAnalyticsRange     = 366 * 24 * time.Hour
+)
+
+// analyticsRange reads the range analytics cover from the from and to RFC
+// 3339 query parameters, defaulting to the last 90 days.
+func analyticsRange(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	to := utils.Now()
+	var err error
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be an RFC 3339 time: %w", apperrors.ErrInvalidInput)
+		}
+	}
+	from := to.Add(-defaultAnalyticsRange)
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be an RFC 3339 time: %w", apperrors.ErrInvalidInput)
+		}
+	}
+	if !to.After(from) || to.Sub(from) > maxAnalyticsRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from and at most 366 days later: %w", apperrors.ErrInvalidInput)
+	}
+	return from, to, nil
+}
+
+func writeAnalytics(w http.ResponseWriter, analytics interface{}) {
+	responseJson, err := json.Marshal(analytics)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// loadAnalyticsUser validates the login and the range of an analytics
+// request and loads the user.
+func loadAnalyticsUser(w http.ResponseWriter, r *http.Request) (*models.User, time.Time, time.Time, bool) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	from, to, err := analyticsRange(r)
+	if err != nil {
+		writeError(w, err)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	return user, from, to, true
+}
+
+// GetPlatformAnalyticsHandler compares the engagement of the user's blogs
+// across the platforms they were shared to, over the range set as described
+// on analyticsRange.
+func (h *Handlers) GetPlatformAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	user, from, to, ok := loadAnalyticsUser(w, r)
+	if !ok {
+		return
+	}
+	writeAnalytics(w, services.CompareShareEngagement(user, from, to))
+}
+
+// GetTopicAnalyticsHandler compares how the topics of the user's blogs did
+// on each platform, over the range set as described on analyticsRange.
+func (h *Handlers) GetTopicAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	user, from, to, ok := loadAnalyticsUser(w, r)
+	if !ok {
+		return
+	}
+	writeAnalytics(w, services.TopicInsights(user, from, to))
+}
+
+// SetSharedBlogTopicsHandler replaces the topics of a shared blog with ones
+// the user assigned, e.g. when the AI got them wrong.
+func (h *Handlers) SetSharedBlogTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Topics []string `json:"topics"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	topics, err := services.ValidateTopics(requestBody.Topics)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	blogId := mux.Vars(r)["id"]
+	var blog *models.SharedBlog
+	for i := range user.SharedBlogs {
+		if user.SharedBlogs[i].Id == blogId {
+			blog = &user.SharedBlogs[i]
+			break
+		}
+	}
+	if blog == nil {
+		http.Error(w, "Shared blog not found", http.StatusNotFound)
+		return
+	}
+	blog.Topics = topics
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"blog_id": blogId,
+		"topics":  topics,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAPIKeys = 20
+	// maxAPIKeyLifetimeDays bounds expires_in_days; 0 creates a key that
+	// never expires
+	maxAPIKeyLifetimeDays = 365
+)
+
+// CreateAPIKeyHandler creates a personal access token for scripts and CI. It
+// is accepted as a bearer token on the routes its scopes cover, which are the
+// same scopes OAuth clients request. The token is only shown in this response.
+func (h *Handlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" || len(requestBody.Name) > 100 {
+		http.Error(w, "Key name must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+	scopes, err := services.ParseOAuthScopes(strings.Join(requestBody.Scopes, " "))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if requestBody.ExpiresInDays < 0 || requestBody.ExpiresInDays > maxAPIKeyLifetimeDays {
+		http.Error(w, "expires_in_days must be between 0 and 365", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := repo.GetUserAPIKeys(userId, utils.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(existing) >= maxAPIKeys {
+		http.Error(w, "API key limit reached", http.StatusConflict)
+		return
+	}
+
+	token, tokenHash, err := services.NewOAuthSecret(services.APIKeyPrefix)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key := models.APIKey{
+		Id:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+		UserID:    userId,
+		Name:      requestBody.Name,
+		Prefix:    token[:len(services.APIKeyPrefix)+6],
+		TokenHash: tokenHash,
+		Scopes:    scopes,
+		CreatedAt: utils.Now(),
+	}
+	if requestBody.ExpiresInDays > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(time.Duration(requestBody.ExpiresInDays) * 24 * time.Hour)
+	}
+	if err := repo.CreateAPIKey(key); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] API key %s created by user with ID %s", key.Id, userId)
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"key":   key,
+		"token": token,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := repo.GetUserAPIKeys(userId, utils.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"keys": keys,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyId := mux.Vars(r)["id"]
+	if err := repo.RevokeAPIKey(userId, keyId); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] API key %s revoked by user with ID %s", keyId, userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
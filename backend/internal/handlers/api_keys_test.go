@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	repo "social-scribe/backend/internal/repositories"
+)
+
+// createAPIKey creates a key for the user and returns its id and token.
+func createAPIKey(t *testing.T, userID string) (string, string) {
+	t.Helper()
+	var created struct {
+		Key struct {
+			Id string `json:"id"`
+		} `json:"key"`
+		Token string `json:"token"`
+	}
+	decodeJSON(t, serve(h.CreateAPIKeyHandler, userID, `{"name": "CI", "scopes": ["shares:read"], "expires_in_days": 30}`, nil), http.StatusCreated, &created)
+	return created.Key.Id, created.Token
+}
+
+// withAPIKey makes an unauthenticated request carrying the key as its bearer
+// token, which handlers accept through ValidateLogin.
+func withAPIKey(handler http.HandlerFunc, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return serveRequest(handler, req, "", nil)
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	create := func() http.HandlerFunc { return h.CreateAPIKeyHandler }
+	runCases(t, []handlerCase{
+		{name: "no session", handler: create, body: `{"name": "CI", "scopes": ["shares:read"]}`, status: http.StatusUnauthorized},
+		{name: "no name", handler: create, setup: anyUser, body: `{"scopes": ["shares:read"]}`, status: http.StatusBadRequest, text: "Key name"},
+		{name: "unknown scope", handler: create, setup: anyUser, body: `{"name": "CI", "scopes": ["everything"]}`, status: http.StatusBadRequest},
+		{name: "too long a lifetime", handler: create, setup: anyUser, body: `{"name": "CI", "scopes": ["shares:read"], "expires_in_days": 366}`, status: http.StatusBadRequest, text: "expires_in_days"},
+		{name: "created", handler: create, setup: userWith(nil), body: `{"name": "CI", "scopes": ["shares:read"]}`, status: http.StatusCreated},
+	})
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	userID := newUser(t, nil)
+	keyID, token := createAPIKey(t, userID)
+
+	var listed struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	decodeJSON(t, withAPIKey(h.GetAPIKeysHandler, token), http.StatusOK, &listed)
+	if len(listed.Keys) != 1 || listed.Keys[0]["id"] != keyID {
+		t.Fatalf("keys listed with the key = %+v", listed.Keys)
+	}
+	if _, ok := listed.Keys[0]["token_hash"]; ok {
+		t.Error("the key's hash is listed")
+	}
+	if rec := withAPIKey(h.GetAPIKeysHandler, "sspat_unknown"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("an unknown key: status %d", rec.Code)
+	}
+
+	// Another user can't revoke the key
+	if rec := serveRequest(h.RevokeAPIKeyHandler, httptest.NewRequest(http.MethodDelete, "/", nil), newUser(t, nil), map[string]string{"id": keyID}); rec.Code != http.StatusNotFound {
+		t.Errorf("revoking another user's key: status %d", rec.Code)
+	}
+	if rec := serveRequest(h.RevokeAPIKeyHandler, httptest.NewRequest(http.MethodDelete, "/", nil), userID, map[string]string{"id": keyID}); rec.Code != http.StatusOK {
+		t.Fatalf("revoking: status %d", rec.Code)
+	}
+	if rec := withAPIKey(h.GetAPIKeysHandler, token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("a revoked key: status %d", rec.Code)
+	}
+	decodeJSON(t, serve(h.GetAPIKeysHandler, userID, "", nil), http.StatusOK, &listed)
+	if len(listed.Keys) != 0 {
+		t.Errorf("keys after revoking = %+v", listed.Keys)
+	}
+}
+
+func TestAPIKeyOfDisabledAccount(t *testing.T) {
+	userID := newUser(t, nil)
+	_, token := createAPIKey(t, userID)
+	user, err := repo.GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	user.Disabled = true
+	if err := repo.UpdateUser(userID, user); err != nil {
+		t.Fatal(err)
+	}
+	if rec := withAPIKey(h.GetAPIKeysHandler, token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want the key refused", rec.Code)
+	}
+}
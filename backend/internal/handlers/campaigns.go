@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const maxCampaigns = 100
+
+func (h *Handlers) CreateCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		Name        string `json:"name"`
+		UTMCampaign string `json:"utm_campaign"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(requestBody.Name)
+	if name == "" || len(name) > 100 {
+		http.Error(w, "Campaign name must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+	tag, err := services.UTMCampaignTag(requestBody.UTMCampaign, name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	existing, err := repo.GetUserCampaigns(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(existing) >= maxCampaigns {
+		http.Error(w, "Campaign limit reached", http.StatusConflict)
+		return
+	}
+
+	campaign := models.Campaign{
+		UserID:      userId,
+		Name:        name,
+		UTMCampaign: tag,
+		BlogIDs:     []string{},
+		CreatedAt:   utils.Now(),
+	}
+	campaignId, err := repo.CreateCampaign(campaign)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Campaign %s created by user with ID %s", campaignId, userId)
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"id":       campaignId,
+		"campaign": campaign,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	campaigns, err := repo.GetUserCampaigns(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"campaigns": campaigns,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// AttachCampaignBlogHandler adds one of the user's blogs to a campaign, so its
+// shares count towards the campaign and link back with its UTM tag. The blog
+// must have been shared, be scheduled or be a synced post of the user.
+func (h *Handlers) AttachCampaignBlogHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		BlogId string `json:"blog_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	blogId := strings.TrimSpace(requestBody.BlogId)
+	if blogId == "" {
+		http.Error(w, "Missing blog id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	owned, err := ownsBlog(user, blogId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !owned {
+		http.Error(w, "Blog not found", http.StatusNotFound)
+		return
+	}
+
+	campaignId := mux.Vars(r)["id"]
+	if err := repo.AttachCampaignBlog(userId, campaignId, blogId); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Blog %s attached to campaign %s by user with ID %s", blogId, campaignId, userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (h *Handlers) DetachCampaignBlogHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := repo.DetachCampaignBlog(userId, vars["id"], vars["blogId"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// GetCampaignAnalyticsHandler reports the shares and engagement of a
+// campaign's blogs.
+func (h *Handlers) GetCampaignAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	campaign, err := repo.GetCampaign(userId, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if campaign == nil {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"campaign":  campaign,
+		"analytics": services.SummarizeCampaign(*campaign, user),
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func ownsBlog(user *models.User, blogId string) (bool, error) {
+	for _, blog := range user.SharedBlogs {
+		if blog.Id == blogId {
+			return true, nil
+		}
+	}
+	for _, blog := range user.ScheduledBlogs {
+		if blog.Id == blogId {
+			return true, nil
+		}
+	}
+	posts, err := repo.GetUserPosts(user.Id.Hex())
+	if err != nil {
+		return false, err
+	}
+	for _, post := range posts {
+		if post.Id == blogId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeConsent(w http.ResponseWriter, user *models.User, terms config.Terms) {
+	history := make([]models.ConsentRecord, 0, len(user.ConsentHistory))
+	for i := len(user.ConsentHistory) - 1; i >= 0; i-- {
+		history = append(history, user.ConsentHistory[i])
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"terms_of_service": terms.TermsOfService,
+		"privacy_policy":   terms.PrivacyPolicy,
+		"required":         !user.HasConsented(terms.TermsOfService, terms.PrivacyPolicy),
+		"history":          history,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetConsentHandler returns the current terms versions, whether the user
+// still has to accept them, and the user's acceptances, newest first.
+func (h *Handlers) GetConsentHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeConsent(w, user, config.Get().Terms)
+}
+
+// AcceptConsentHandler records the user's acceptance of the current terms.
+// The request names the versions the user was shown, so an acceptance of
+// terms that changed meanwhile is refused.
+func (h *Handlers) AcceptConsentHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		TermsOfService string `json:"terms_of_service"`
+		PrivacyPolicy  string `json:"privacy_policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	terms := config.Get().Terms
+	if terms.TermsOfService == "" && terms.PrivacyPolicy == "" {
+		http.Error(w, "There are no terms to accept", http.StatusBadRequest)
+		return
+	}
+	if requestBody.TermsOfService != terms.TermsOfService || requestBody.PrivacyPolicy != terms.PrivacyPolicy {
+		http.Error(w, "The terms changed, review them again", http.StatusConflict)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxSessionUserAgent {
+		userAgent = userAgent[:maxSessionUserAgent]
+	}
+	record := models.ConsentRecord{
+		TermsOfService: terms.TermsOfService,
+		PrivacyPolicy:  terms.PrivacyPolicy,
+		At:             utils.Now(),
+		IP:             utils.GetClientIP(r),
+		UserAgent:      userAgent,
+	}
+	if err := repo.RecordConsent(userId, record); err != nil {
+		writeError(w, err)
+		return
+	}
+	// The consent history already holds the acceptance, so a failure to
+	// audit it doesn't fail the request
+	err = repo.RecordAuditEvent(models.AuditEvent{
+		UserID:    userId,
+		Action:    models.AuditConsentAccepted,
+		At:        record.At,
+		IP:        record.IP,
+		UserAgent: record.UserAgent,
+		Details:   map[string]string{"terms_of_service": record.TermsOfService, "privacy_policy": record.PrivacyPolicy},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to audit the consent of user %s: %v", userId, err)
+	}
+	user.ConsentHistory = append(user.ConsentHistory, record)
+	log.Printf("[INFO] User with ID %s accepted terms of service %q and privacy policy %q", userId, terms.TermsOfService, terms.PrivacyPolicy)
+	writeConsent(w, user, terms)
+}
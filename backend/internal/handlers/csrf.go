@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"social-scribe/backend/internal/services"
+)
+
+// CSRFTokenHandler returns the CSRF token the frontend sends in the
+// X-CSRF-Token header of state-changing requests. Refreshing the session
+// changes the token, so it must be fetched again afterwards.
+func (h *Handlers) CSRFTokenHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]string{
+		"csrf_token": services.CSRFToken(cookie.Value),
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
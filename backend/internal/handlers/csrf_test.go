@@ -0,0 +1,36 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-scribe/backend/internal/services"
+)
+
+func TestCSRFTokenHandler(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "test-secret")
+	rec := httptest.NewRecorder()
+	h.CSRFTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a session: status %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "session"})
+	rec = httptest.NewRecorder()
+	h.CSRFTokenHandler(rec, req)
+	var body struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	decodeJSON(t, rec, http.StatusOK, &body)
+	if !services.ValidCSRFToken("session", body.CSRFToken) {
+		t.Errorf("token %q doesn't verify for the session", body.CSRFToken)
+	}
+	if services.ValidCSRFToken("other-session", body.CSRFToken) {
+		t.Error("the token verifies for another session")
+	}
+	if cache := rec.Header().Get("Cache-Control"); cache != "no-store" {
+		t.Errorf("Cache-Control %q, want no-store", cache)
+	}
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const maxWebhookPayloadSize = 1 << 20
+
+func hashnodeWebhookURL(userId string) string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return fmt.Sprintf("%s/api/v1/webhook/hashnode/%s", strings.TrimRight(backendURL, "/"), userId)
+}
+
+// SetHashnodeWebhookSecretHandler stores the secret Hashnode generated for the
+// webhook pointing at this user's receiver URL.
+func (h *Handlers) SetHashnodeWebhookSecretHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		log.Printf("[ERROR] User with id: %s not found", userId)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	var requestBody struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.Secret = strings.TrimSpace(requestBody.Secret)
+	if requestBody.Secret == "" {
+		http.Error(w, "Missing webhook secret", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SealCredential(user, &user.SealedHashnodeWebhookSecret, &user.PlaintextHashnodeWebhookSecret, requestBody.Secret); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.WebHookUrl = hashnodeWebhookURL(userId)
+	err = repo.UpdateUser(userId, user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"success":     true,
+		"webhook_url": user.WebHookUrl,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// DeferShareHandler binds a share plan to a Hashnode post that is not yet
+// published; the share runs when the post_published webhook is received.
+func (h *Handlers) DeferShareHandler(w http.ResponseWriter, r *http.Request) {
+	if !config.Get().FeatureEnabled("share_on_publish") {
+		http.Error(w, "Share on publish is currently disabled", http.StatusServiceUnavailable)
+		return
+	}
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		log.Printf("[ERROR] User with id: %s not found", userId)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.Verified {
+		http.Error(w, "User is not verified", http.StatusForbidden)
+		return
+	}
+	if user.SealedHashnodeWebhookSecret == "" && user.PlaintextHashnodeWebhookSecret == "" {
+		http.Error(w, "Hashnode webhook is not configured", http.StatusPreconditionFailed)
+		return
+	}
+
+	var requestBody struct {
+		PostId       string   `json:"post_id"`
+		Platforms    []string `json:"platforms"`
+		AssetIDs     []string `json:"asset_ids"`
+		LinkedInPage string   `json:"linkedin_page"`
+		XThread      bool     `json:"x_thread"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(requestBody.PostId) == "" {
+		http.Error(w, "Missing post id", http.StatusBadRequest)
+		return
+	}
+
+	platforms := requestBody.Platforms
+	if len(platforms) == 0 {
+		platforms = user.Preferences.DefaultPlatforms
+	}
+	if len(platforms) == 0 {
+		http.Error(w, "No platforms specified and no default platforms configured", http.StatusBadRequest)
+		return
+	}
+	for _, platform := range platforms {
+		if !services.IsValidPlatform(platform) {
+			http.Error(w, "Invalid platform: "+platform, http.StatusBadRequest)
+			return
+		}
+		if err := services.CheckPlatformEnabled(platform); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if _, err := services.ResolveShareAssets(user, requestBody.AssetIDs); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := services.ValidateLinkedInPage(user, requestBody.LinkedInPage); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	share := models.DeferredShare{
+		UserID:       userId,
+		PostID:       strings.TrimSpace(requestBody.PostId),
+		Platforms:    platforms,
+		AssetIDs:     requestBody.AssetIDs,
+		LinkedInPage: requestBody.LinkedInPage,
+		XThread:      requestBody.XThread,
+		CreatedAt:    utils.Now(),
+	}
+	if err := repo.StoreDeferredShare(share); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] Deferred share for post %s created by user with ID %s", share.PostID, userId)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (h *Handlers) GetDeferredSharesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	shares, err := repo.GetDeferredShares(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"deferred_shares": shares,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) CancelDeferredShareHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		PostId string `json:"post_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.PostId == "" {
+		http.Error(w, "Missing post id", http.StatusBadRequest)
+		return
+	}
+
+	err = repo.DeleteDeferredShare(userId, requestBody.PostId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] Deferred share for post %s cancelled by user with ID %s", requestBody.PostId, userId)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// HashnodeWebhookHandler receives Hashnode webhook deliveries for a user and
+// shares a freshly published post as planned, or as their AutoShare
+// preference says.
+func (h *Handlers) HashnodeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+	user, err := repo.GetUserById(userId)
+	if err != nil || user == nil {
+		http.Error(w, "Unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	secret, err := services.OpenCredential(user, user.SealedHashnodeWebhookSecret, user.PlaintextHashnodeWebhookSecret)
+	if err == nil {
+		err = services.VerifyHashnodeSignature(payload, r.Header.Get("x-hashnode-signature"), secret)
+	}
+	if err != nil {
+		log.Printf("[WARN] Rejected Hashnode webhook for the user %s: %v", userId, err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event models.HashnodeWebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// Nothing is shared for an account its team deactivated
+	if event.Data.EventType == "post_published" && event.Data.Post.Id != "" && !user.Disabled {
+		go runDeferredShare(userId, event.Data.Post.Id)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// runDeferredShare runs the share planned for a post that was just
+// published. Without a plan the user's AutoShare preference decides: the
+// post is shared to their default platforms, or such a share waits for
+// their approval.
+func runDeferredShare(userId, postId string) {
+	ctx := context.Background()
+	tags := map[string]string{
+		"component": "hashnode_webhook",
+		"user_id":   userId,
+		"post_id":   postId,
+	}
+	defer reporting.Recover(ctx, tags)
+
+	share, err := repo.TakeDeferredShare(userId, postId, false)
+	if err != nil {
+		reporting.Report(ctx, fmt.Errorf("failed to load deferred share: %w", err), tags)
+		return
+	}
+
+	user, err := repo.GetUserById(userId)
+	if err != nil || user == nil {
+		log.Printf("[ERROR] Error getting user or user not found: %v", userId)
+		return
+	}
+	if share == nil {
+		share = autoShare(user, postId)
+		if share == nil {
+			return
+		}
+	}
+	tags["platform"] = strings.Join(share.Platforms, ",")
+
+	if share.AwaitingApproval {
+		if err := repo.StoreDeferredShare(*share); err != nil {
+			reporting.Report(ctx, fmt.Errorf("failed to queue share for approval: %w", err), tags)
+			return
+		}
+		log.Printf("[INFO] Share of blog with ID %s awaits the approval of user ID %s", postId, userId)
+		user.AddNotification(fmt.Sprintf("Your newly published post %s is waiting for your approval to be shared on %s", postId, strings.Join(share.Platforms, ", ")), utils.Now())
+	} else {
+		processErr := services.ProcessSharedBlog(user, postId, share.Platforms, share.AssetIDs, share.LinkedInPage, share.XThread)
+		if processErr != nil {
+			log.Printf("[ERROR] Error processing deferred share for blog id %s and user id %s: %v", postId, userId, processErr)
+			reporting.Report(ctx, processErr, tags)
+			user.AddNotification(fmt.Sprintf("Failed to share your newly published post %s", postId), utils.Now())
+		} else {
+			log.Printf("[INFO] Deferred share executed for blog with ID %s and user ID %s", postId, userId)
+			user.AddNotification(fmt.Sprintf("Your newly published post %s was shared on %s", postId, strings.Join(share.Platforms, ", ")), utils.Now())
+		}
+	}
+
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Error updating user: %v", err)
+	}
+}
+
+// autoShare plans the share of a published post the user made no plan for,
+// as their AutoShare preference says. It returns nil when nothing is to be
+// shared.
+func autoShare(user *models.User, postId string) *models.DeferredShare {
+	mode := user.Preferences.AutoShare
+	if mode == models.AutoShareOff || !user.Verified || !config.Get().FeatureEnabled("share_on_publish") {
+		return nil
+	}
+	// Hashnode may deliver the event more than once
+	for _, blog := range user.SharedBlogs {
+		if blog.Id == postId {
+			return nil
+		}
+	}
+	if len(user.Preferences.DefaultPlatforms) == 0 {
+		log.Printf("[WARN] Not auto sharing post %s of user %s without default platforms", postId, user.Id.Hex())
+		return nil
+	}
+	return &models.DeferredShare{
+		UserID:           user.Id.Hex(),
+		PostID:           postId,
+		Platforms:        user.Preferences.DefaultPlatforms,
+		CreatedAt:        utils.Now(),
+		AwaitingApproval: mode == models.AutoShareApprove,
+	}
+}
+
+// ApproveDeferredShareHandler runs a share of a published post that awaits
+// the user's approval. Cancelling the share rejects it.
+func (h *Handlers) ApproveDeferredShareHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		PostId string `json:"post_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.PostId == "" {
+		http.Error(w, "Missing post id", http.StatusBadRequest)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.Verified {
+		http.Error(w, "User is not verified", http.StatusForbidden)
+		return
+	}
+
+	share, err := repo.TakeDeferredShare(userId, requestBody.PostId, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if share == nil {
+		http.Error(w, "No share awaits approval for this post", http.StatusNotFound)
+		return
+	}
+	err = services.ProcessSharedBlog(user, share.PostID, share.Platforms, share.AssetIDs, share.LinkedInPage, share.XThread)
+	if err != nil {
+		log.Printf("[ERROR] Failed to share approved blog %s of user %s: %v", share.PostID, userId, err)
+		// Left for the user to approve again
+		if err := repo.StoreDeferredShare(*share); err != nil {
+			log.Printf("[ERROR] Failed to restore the share of blog %s of user %s: %v", share.PostID, userId, err)
+		}
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Blog with ID %s shared on approval by user with ID %s", share.PostID, userId)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
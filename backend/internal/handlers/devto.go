@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeDevtoAccount(w http.ResponseWriter, account *models.DevtoAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetDevtoAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeDevtoAccount(w, user.Devto)
+}
+
+// SetDevtoAccountHandler connects the Dev.to account that articles are
+// republished to, after checking the API key with Dev.to. Its own articles
+// are then listed among the user's blogs to share.
+func (h *Handlers) SetDevtoAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	apiKey, err := services.NormalizeDevtoAPIKey(requestBody.APIKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	account, err := services.LookupDevtoAccount(userId, apiKey)
+	if err != nil {
+		log.Printf("[WARN] The Dev.to API key of user %s failed its check: %v", userId, err)
+		http.Error(w, "Dev.to rejected the API key", http.StatusBadRequest)
+		return
+	}
+	account.SealedAPIKey, err = services.SealUserSecret(user, apiKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account.ConnectedAt = utils.Now()
+	user.Devto = account
+	user.DevtoVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Dev.to account %s", userId, account.Username)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "devto", "action": "connected", "account": account.Username})
+	writeDevtoAccount(w, account)
+}
+
+func (h *Handlers) DeleteDevtoAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Devto == nil {
+		http.Error(w, "No Dev.to account connected", http.StatusNotFound)
+		return
+	}
+	user.Devto = nil
+	user.DevtoVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Dev.to account", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "devto", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// DisconnectPlatformHandler unlinks Hashnode or a platform blogs are shared
+// to. The stored credentials are dropped, after revoking them with the
+// platform where its API allows; a failed revocation still disconnects, and
+// the response tells whether it went through.
+func (h *Handlers) DisconnectPlatformHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	name := mux.Vars(r)["platform"]
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	revoked := false
+	if name == "hashnode" {
+		if !user.HashnodeVerified && user.SealedHashnodePAT == "" && user.PlaintextHashnodePAT == "" {
+			http.Error(w, "Hashnode is not connected", http.StatusNotFound)
+			return
+		}
+		// Hashnode has no API to revoke a personal access token
+		disconnectHashnode(user)
+	} else {
+		platform, ok := services.LookupPlatform(name)
+		if !ok {
+			http.Error(w, "Unknown platform", http.StatusNotFound)
+			return
+		}
+		if !platform.Connected(user) {
+			http.Error(w, platform.Title()+" is not connected", http.StatusNotFound)
+			return
+		}
+		revoked = h.revokePlatformToken(user, name)
+		platform.Disconnect(user)
+	}
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected %s, revoked upstream: %t", userId, name, revoked)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": name, "action": "disconnected"})
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"success":  true,
+		"revoked":  revoked,
+		"verified": user.Verified,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// disconnectHashnode drops the user's Hashnode token and blog, with the
+// synced posts, share-on-publish plans and webhooks of the blog.
+func disconnectHashnode(user *models.User) {
+	userId := user.Id.Hex()
+	if err := repo.DeleteSourcePosts(userId, ""); err != nil {
+		log.Printf("[WARN] Failed to delete posts of the disconnected blog of user %s: %v", userId, err)
+	}
+	if err := repo.DeleteUserDeferredShares(userId); err != nil {
+		log.Printf("[WARN] Failed to delete deferred shares of the disconnected blog of user %s: %v", userId, err)
+	}
+	services.DeletePublicationWebhooks(user)
+	user.SealedHashnodePAT, user.PlaintextHashnodePAT = "", ""
+	user.HashnodeBlog = ""
+	user.HashnodePublications = nil
+	user.SealedHashnodeWebhookSecret, user.PlaintextHashnodeWebhookSecret = "", ""
+	user.HashnodeVerified = false
+	user.PostsSyncDueAt = time.Time{}
+}
+
+// revokePlatformToken revokes the user's token with the platform and reports
+// whether it did. Only X and LinkedIn let tokens be revoked through their
+// APIs.
+func (h *Handlers) revokePlatformToken(user *models.User, name string) bool {
+	var err error
+	switch name {
+	case "twitter":
+		err = services.RevokeTwitterToken(user)
+	case "linkedin":
+		app, appErr := h.linkedinOAuth(user)
+		if appErr != nil {
+			err = appErr
+			break
+		}
+		token, tokenErr := services.LinkedInToken(user)
+		if tokenErr != nil {
+			err = tokenErr
+			break
+		}
+		err = services.RevokeLinkedInToken(user.Id.Hex(), app, token)
+	default:
+		return false
+	}
+	if err != nil {
+		log.Printf("[WARN] Failed to revoke the %s token of user %s: %v", name, user.Id.Hex(), err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeDiscordWebhook(w http.ResponseWriter, webhook *models.DiscordWebhook) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"webhook": webhook,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetDiscordWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeDiscordWebhook(w, user.DiscordWebhook)
+}
+
+// SetDiscordWebhookHandler connects the Discord channel webhook that blogs
+// are announced through, after checking with Discord that it exists.
+func (h *Handlers) SetDiscordWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	id, token, err := services.ParseDiscordWebhookURL(requestBody.URL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	webhook := &models.DiscordWebhook{ID: id, CreatedAt: utils.Now()}
+	if err := services.LookupDiscordWebhook(userId, webhook, token); err != nil {
+		log.Printf("[WARN] The Discord webhook of user %s failed its check: %v", userId, err)
+		http.Error(w, "Discord doesn't know this webhook", http.StatusBadRequest)
+		return
+	}
+	if err := services.SealCredential(user, &webhook.SealedToken, &webhook.PlaintextToken, token); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.DiscordWebhook = webhook
+	user.DiscordVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Discord webhook %s", userId, webhook.ID)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "discord", "action": "connected", "account": webhook.Name})
+	writeDiscordWebhook(w, webhook)
+}
+
+func (h *Handlers) DeleteDiscordWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.DiscordWebhook == nil {
+		http.Error(w, "No Discord webhook connected", http.StatusNotFound)
+		return
+	}
+	user.DiscordWebhook = nil
+	user.DiscordVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Discord webhook", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "discord", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// TestDiscordWebhookHandler posts a test message through the caller's
+// webhook and reports how Discord responded.
+func (h *Handlers) TestDiscordWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.DiscordWebhook == nil {
+		http.Error(w, "No Discord webhook connected", http.StatusNotFound)
+		return
+	}
+
+	messageURL, err := services.PingDiscordWebhook(user)
+	response := map[string]interface{}{
+		"success":     err == nil,
+		"message_url": messageURL,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
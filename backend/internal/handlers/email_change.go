@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const (
+	emailChangeTTL = 15 * time.Minute
+	// A pending change is dropped after this many wrong OTPs, so the code
+	// can't be guessed
+	maxEmailChangeFailures = 5
+)
+
+func emailChangeOTPHash(userId, otp string) string {
+	sum := sha256.Sum256([]byte(userId + ":" + otp))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkEmailUnclaimed returns ErrConflict when another account verified the
+// email address.
+func checkEmailUnclaimed(user *models.User, email string) error {
+	others, err := repo.GetUsersByEmail(email)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.Id != user.Id && other.EmailVerified {
+			return apperrors.ErrConflict
+		}
+	}
+	return nil
+}
+
+func writeEmailClaimError(w http.ResponseWriter, userId string, err error) {
+	if errors.Is(err, apperrors.ErrConflict) {
+		http.Error(w, `{"error": "This email address belongs to another account"}`, http.StatusConflict)
+		return
+	}
+	log.Printf("[ERROR] Failed to look up accounts with the new email of user %s: %v", userId, err)
+	http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+}
+
+// ChangeEmailHandler starts changing the account email. Once the OTP is sent
+// to the new address the account loses its verified email, but the new
+// address only replaces the old one once ConfirmEmailChangeHandler gets the
+// OTP back.
+func (h *Handlers) ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "Bad request: unable to decode JSON"}`, http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > 254 {
+		http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+	if !services.EmailConfigured() {
+		http.Error(w, `{"error": "Email change is not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, `{"error": "user id is not valid"}`, http.StatusNotFound)
+		return
+	}
+	if email == user.Email && user.EmailVerified {
+		http.Error(w, `{"error": "This is already your email address"}`, http.StatusBadRequest)
+		return
+	}
+	if err := checkEmailUnclaimed(user, email); err != nil {
+		writeEmailClaimError(w, userId, err)
+		return
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate an email change OTP for user %s: %v", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	otp := fmt.Sprintf("%06d", n.Int64())
+	change := models.PendingEmailChange{Email: email, OTPHash: emailChangeOTPHash(userId, otp)}
+	if err := repo.StoreEmailChange(userId, change, emailChangeTTL); err != nil {
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	message := fmt.Sprintf("Your SocialScribe verification code is %s.\n\nIt expires in %d minutes. If you didn't ask to change your email, change your password.\n", otp, int(emailChangeTTL.Minutes()))
+	if err := services.SendEmail(email, "Confirm your new email address", message); err != nil {
+		log.Printf("[ERROR] Failed to send the email change OTP of user %s: %v", userId, err)
+		// Nothing changed but the pending change, which no one has the OTP of
+		if err := repo.DeleteEmailChange(userId); err != nil {
+			log.Printf("[WARN] Failed to delete the unsent email change of user %s: %v", userId, err)
+		}
+		http.Error(w, `{"error": "Failed to send the verification email"}`, http.StatusBadGateway)
+		return
+	}
+
+	user.EmailVerified = false
+	services.RefreshVerified(user)
+	user.Verified = user.Verified && user.EmailVerified
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[INFO] Email change started for the user with ID %s", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// ConfirmEmailChangeHandler commits the pending email change once the OTP
+// sent to the new address is given back.
+func (h *Handlers) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Otp string `json:"otp"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "Bad request: unable to decode JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	change, err := repo.GetEmailChange(userId)
+	if err != nil {
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if change == nil {
+		http.Error(w, `{"error": "No email change is pending or the OTP expired"}`, http.StatusBadRequest)
+		return
+	}
+	otpHash := emailChangeOTPHash(userId, strings.TrimSpace(body.Otp))
+	if subtle.ConstantTimeCompare([]byte(otpHash), []byte(change.OTPHash)) != 1 {
+		failures, err := repo.RecordEmailChangeFailure(userId)
+		if err == nil && failures >= maxEmailChangeFailures {
+			repo.DeleteEmailChange(userId)
+			http.Error(w, `{"error": "Too many wrong OTPs, start the email change again"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error": "Invalid OTP"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, `{"error": "user id is not valid"}`, http.StatusNotFound)
+		return
+	}
+	// Another account may have verified the address since the change started
+	if err := checkEmailUnclaimed(user, change.Email); err != nil {
+		writeEmailClaimError(w, userId, err)
+		return
+	}
+	user.Email = change.Email
+	user.EmailVerified = true
+	services.RefreshVerified(user)
+	user.Verified = user.Verified && user.EmailVerified
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := repo.DeleteEmailChange(userId); err != nil {
+		log.Printf("[WARN] Failed to delete the confirmed email change of user %s: %v", userId, err)
+	}
+
+	log.Printf("[INFO] User with ID %s changed their email", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/middlewares"
+	"social-scribe/backend/internal/models"
+)
+
+// errorReasons are what clients are told for each status. The error itself
+// can name internal ids or quote a provider's reply, so it is only logged,
+// under the request id the response carries.
+var errorReasons = map[int]string{
+	http.StatusBadRequest:          "Invalid input",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "Not found",
+	http.StatusConflict:            "Conflict",
+	http.StatusTooManyRequests:     "The provider is rate limiting requests, try again later",
+	http.StatusFailedDependency:    "Reconnect the provider account",
+	http.StatusInternalServerError: "Internal server error",
+}
+
+// writeError is the single place where domain errors are turned into HTTP
+// responses. Errors are logged and answered with a generic reason and the
+// request id to quote; validation errors also list the fields that failed.
+func writeError(w http.ResponseWriter, err error) {
+	status := apperrors.HTTPStatus(err)
+	requestID := w.Header().Get(middlewares.RequestIDHeader)
+	if status == http.StatusInternalServerError {
+		log.Printf("[ERROR] Request %s: %v", requestID, err)
+	} else {
+		log.Printf("[INFO] Request %s failed with %d: %v", requestID, status, err)
+	}
+
+	response := map[string]interface{}{
+		"success":    false,
+		"reason":     errorReasons[status],
+		"request_id": requestID,
+	}
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		response["fields"] = validationErr.Fields
+	}
+	body, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeBlogFeed(w http.ResponseWriter, feed *models.BlogFeed) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"feed": feed,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetBlogFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeBlogFeed(w, user.Feed)
+}
+
+// SetBlogFeedHandler registers the RSS or Atom feed the user's blogs are
+// listed from. The feed is fetched right away, so a URL that isn't a feed is
+// refused and its items are listed without waiting for the first poll.
+// Replacing the feed drops the items of the previous one.
+func (h *Handlers) SetBlogFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(requestBody.URL) > 2048 {
+		http.Error(w, "Feed URL is too long", http.StatusBadRequest)
+		return
+	}
+	feedURL, err := services.ValidateFeedURL(requestBody.URL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	now := utils.Now()
+	title, posts, err := services.FetchFeed(feedURL, now)
+	if err != nil {
+		if apperrors.HTTPStatus(err) != http.StatusInternalServerError {
+			writeError(w, err)
+			return
+		}
+		log.Printf("[ERROR] Failed to fetch the feed %s of user %s: %v", feedURL, userId, err)
+		http.Error(w, "Failed to fetch the feed", http.StatusBadGateway)
+		return
+	}
+	if user.Feed != nil && user.Feed.URL != feedURL {
+		if err := repo.DeleteSourcePosts(userId, models.PostSourceFeed); err != nil {
+			log.Printf("[WARN] Failed to delete items of the previous feed of user %s: %v", userId, err)
+		}
+	}
+	if err := repo.UpsertPosts(userId, posts); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if title == "" {
+		title = feedURL
+	}
+	feed := &models.BlogFeed{URL: feedURL, Title: title, AddedAt: now, LastPolledAt: now}
+	if user.Feed != nil && user.Feed.URL == feedURL {
+		feed.AddedAt = user.Feed.AddedAt
+	}
+	user.Feed = feed
+	user.FeedSyncDueAt = now.Add(services.FeedPollInterval)
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s registered a feed with %d items", userId, len(posts))
+	writeBlogFeed(w, feed)
+}
+
+// DeleteBlogFeedHandler removes the user's feed along with its items.
+func (h *Handlers) DeleteBlogFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Feed == nil {
+		http.Error(w, "No feed registered", http.StatusNotFound)
+		return
+	}
+	if err := repo.DeleteSourcePosts(userId, models.PostSourceFeed); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Feed = nil
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s removed their feed", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
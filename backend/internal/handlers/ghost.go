@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeGhostSource(w http.ResponseWriter, source *models.GhostSource) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"site": source,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetGhostSourceHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeGhostSource(w, user.Ghost)
+}
+
+// SetGhostSourceHandler registers the Ghost site the user's blogs are listed
+// from, after checking the Content API key with the site.
+func (h *Handlers) SetGhostSourceHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		APIURL     string `json:"api_url"`
+		ContentKey string `json:"content_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	apiURL, err := services.NormalizeGhostAPIURL(requestBody.APIURL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	contentKey, err := services.NormalizeGhostContentKey(requestBody.ContentKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	source, err := services.LookupGhostSite(userId, apiURL, contentKey)
+	if errors.Is(err, apperrors.ErrProviderAuth) || errors.Is(err, apperrors.ErrNotFound) {
+		log.Printf("[WARN] The Ghost Content API key of user %s failed its check: %v", userId, err)
+		http.Error(w, "The site rejected the Content API key", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	source.SealedContentKey, err = services.SealUserSecret(user, contentKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	source.ConnectedAt = utils.Now()
+	user.Ghost = source
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Ghost site %s", userId, source.APIURL)
+	writeGhostSource(w, source)
+}
+
+func (h *Handlers) DeleteGhostSourceHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Ghost == nil {
+		http.Error(w, "No Ghost site connected", http.StatusNotFound)
+		return
+	}
+	user.Ghost = nil
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Ghost site", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
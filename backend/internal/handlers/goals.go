@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writePostingGoal(w http.ResponseWriter, user *models.User) {
+	response := map[string]interface{}{
+		"goal": user.PostingGoal,
+	}
+	if user.PostingGoal != nil {
+		response["progress"] = services.GoalProgressAt(user, utils.Now())
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetPostingGoalHandler returns the user's posting goal with their progress
+// this week and their streaks.
+func (h *Handlers) GetPostingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writePostingGoal(w, user)
+}
+
+// SetPostingGoalHandler sets how many blogs the user aims to share a week.
+func (h *Handlers) SetPostingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		SharesPerWeek int    `json:"shares_per_week"`
+		Timezone      string `json:"timezone"`
+		Reminder      bool   `json:"reminder"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	goal, err := services.NewPostingGoal(requestBody.SharesPerWeek, requestBody.Timezone, requestBody.Reminder)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	now := utils.Now()
+	goal.CreatedAt = now
+	if user.PostingGoal != nil {
+		goal.CreatedAt = user.PostingGoal.CreatedAt
+		goal.RemindedWeek = user.PostingGoal.RemindedWeek
+	}
+	user.PostingGoal = goal
+	user.GoalReminderDueAt = services.NextGoalReminder(goal, now)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s set a goal of %d shares a week", userId, goal.SharesPerWeek)
+	writePostingGoal(w, user)
+}
+
+func (h *Handlers) DeletePostingGoalHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.PostingGoal == nil {
+		http.Error(w, "No posting goal set", http.StatusNotFound)
+		return
+	}
+	user.PostingGoal = nil
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s removed their posting goal", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const googleBusinessStateCookie = "google_business_oauth_state"
+
+func writeGoogleBusinessAccount(w http.ResponseWriter, account *models.GoogleBusinessAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetGoogleBusinessAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeGoogleBusinessAccount(w, user.GoogleBusiness)
+}
+
+// ConnectGoogleBusinessHandler starts the Google OAuth flow for Business
+// Profile. Offline access with a fresh consent is asked for, since Google
+// only hands out a refresh token on consent.
+func (h *Handlers) ConnectGoogleBusinessHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	state := uuid.New().String()
+	if err := repo.SetCache(state, userId, 10*time.Minute); err != nil {
+		log.Printf("[ERROR] Failed to store state in cache: %v", err)
+		http.Error(w, "Failed to store state in cache", http.StatusInternalServerError)
+		return
+	}
+	setStateCookie(w, googleBusinessStateCookie, state, 10*time.Minute)
+
+	authURL := h.googleBusinessConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GoogleBusinessCallbackHandler connects the user's business locations.
+// With a single location it is chosen right away; otherwise the user is
+// sent to choose one, keeping their earlier choice if it is still listed.
+func (h *Handlers) GoogleBusinessCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	queryState := r.URL.Query().Get("state")
+	stateCookie, err := r.Cookie(googleBusinessStateCookie)
+	if err != nil || queryState == "" || stateCookie.Value != queryState {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	stateUser, exists := repo.GetCache(queryState)
+	if !exists || stateUser != userId {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	if err := repo.DeleteCache(queryState); err != nil {
+		log.Printf("[WARN] Failed to delete state from cache for the user id: %s and error is %s", userId, err)
+	}
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		log.Printf("[INFO] User %s didn't connect Google Business Profile: %s", userId, reason)
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?google_business_error="+url.QueryEscape(reason), http.StatusSeeOther)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		log.Printf("[ERROR] Missing authorization code")
+		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	token, err := h.googleBusinessConfig.Exchange(services.GoogleBusinessContext(context.Background()), code)
+	if err != nil {
+		log.Printf("[ERROR] Failed to exchange the Google code of user %s: %v", userId, err)
+		http.Error(w, "Failed to exchange token", http.StatusBadGateway)
+		return
+	}
+	if token.RefreshToken == "" {
+		log.Printf("[ERROR] Google returned no refresh token for user %s", userId)
+		http.Error(w, "Google didn't grant offline access", http.StatusBadGateway)
+		return
+	}
+	locations, err := services.LookupGoogleBusinessLocations(userId, token.AccessToken)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list the business locations of user %s: %v", userId, err)
+		http.Error(w, "Failed to reach Google Business Profile", http.StatusBadGateway)
+		return
+	}
+	sealed, err := services.SealUserSecret(user, token.RefreshToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	account := &models.GoogleBusinessAccount{Locations: locations, SealedRefreshToken: sealed, ConnectedAt: utils.Now()}
+	if user.GoogleBusiness != nil && services.ValidateGoogleBusinessLocation(account, user.GoogleBusiness.Location) == nil {
+		account.Location = user.GoogleBusiness.Location
+	}
+	if len(locations) == 1 {
+		account.Location = locations[0].Name
+	}
+	user.GoogleBusiness = account
+	user.GoogleBusinessVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected Google Business Profile with %d locations", userId, len(locations))
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "google_business", "action": "connected"})
+
+	redirect := config.Get().FrontendURL + "/verification"
+	if account.Location == "" {
+		redirect += "?google_business=choose_location"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// SetGoogleBusinessLocationHandler chooses the location "What's New" posts
+// are published to.
+func (h *Handlers) SetGoogleBusinessLocationHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Location string `json:"location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	location := strings.TrimSpace(requestBody.Location)
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err := services.ValidateGoogleBusinessLocation(user.GoogleBusiness, location); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.GoogleBusiness.Location = location
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s chose the business location %s", userId, location)
+	writeGoogleBusinessAccount(w, user.GoogleBusiness)
+}
+
+func (h *Handlers) DeleteGoogleBusinessAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.GoogleBusiness == nil {
+		http.Error(w, "No Google Business Profile connected", http.StatusNotFound)
+		return
+	}
+	user.GoogleBusiness = nil
+	user.GoogleBusinessVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected Google Business Profile", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "google_business", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
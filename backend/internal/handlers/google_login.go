@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const (
+	googleIssuer      = "https://accounts.google.com"
+	googleStateCookie = "google_state"
+)
+
+// googleClient returns the OAuth client registered with Google, or false when
+// Sign in with Google isn't configured.
+func googleClient() (models.TeamSSOConfig, bool) {
+	client := models.TeamSSOConfig{
+		Issuer:       googleIssuer,
+		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+	}
+	return client, client.ClientID != "" && client.ClientSecret != ""
+}
+
+func googleRedirectURI() string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/auth/google/callback"
+}
+
+// GoogleLoginHandler redirects to Google to sign in or sign up.
+func (h *Handlers) GoogleLoginHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := googleClient()
+	if !ok {
+		http.Error(w, "Sign in with Google is not enabled", http.StatusNotFound)
+		return
+	}
+	discovery, err := services.DiscoverOIDC(googleIssuer)
+	if err != nil {
+		log.Printf("[ERROR] OIDC discovery failed for Google: %v", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	state, nonce, verifier, err := services.NewOIDCRequestSecrets()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stateJson, err := json.Marshal(ssoLoginState{Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := repo.SetCache("google_state_"+state, string(stateJson), ssoStateTTL); err != nil {
+		writeError(w, err)
+		return
+	}
+	setLoginStateCookie(w, googleStateCookie, state, nonce)
+
+	http.Redirect(w, r, services.OIDCAuthURL(discovery, client, googleRedirectURI(), state, nonce, verifier, ""), http.StatusFound)
+}
+
+// GoogleCallbackHandler completes a Google sign-in, creating the account on
+// first use or linking it to an existing account with the same verified email.
+func (h *Handlers) GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	failureURL := config.Get().FrontendURL + "/login?google_error="
+	client, ok := googleClient()
+	if !ok {
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	if idpError := query.Get("error"); idpError != "" {
+		http.Redirect(w, r, failureURL+"denied", http.StatusSeeOther)
+		return
+	}
+
+	nonce, bound := takeLoginStateCookie(w, r, googleStateCookie, query.Get("state"))
+	if !bound {
+		log.Printf("[WARN] Google login rejected: the state doesn't match the browser's")
+		http.Redirect(w, r, failureURL+"state", http.StatusSeeOther)
+		return
+	}
+	stateKey := "google_state_" + query.Get("state")
+	cached, exists := repo.GetCache(stateKey)
+	if !exists {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+	repo.DeleteCache(stateKey)
+	var state ssoLoginState
+	stateValue, _ := cached.(models.CacheItem).Value.(string)
+	if err := json.Unmarshal([]byte(stateValue), &state); err != nil || subtle.ConstantTimeCompare([]byte(nonce), []byte(state.Nonce)) != 1 {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+
+	discovery, err := services.DiscoverOIDC(googleIssuer)
+	if err != nil {
+		log.Printf("[ERROR] OIDC discovery failed for Google: %v", err)
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	identity, err := services.ExchangeOIDCCode(discovery, client, googleRedirectURI(), query.Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("[WARN] Google login rejected: %v", err)
+		http.Redirect(w, r, failureURL+"rejected", http.StatusSeeOther)
+		return
+	}
+	// Linking by email is only safe when Google vouches for the address
+	if identity.Email == "" || !identity.EmailVerified {
+		log.Printf("[WARN] Google login rejected: unverified email %s", identity.Email)
+		http.Redirect(w, r, failureURL+"email", http.StatusSeeOther)
+		return
+	}
+
+	user, err := provisionGoogleUser(identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrForbidden):
+			log.Printf("[WARN] Google login rejected: %v", err)
+			http.Redirect(w, r, failureURL+"disabled", http.StatusSeeOther)
+		case errors.Is(err, apperrors.ErrConflict):
+			log.Printf("[WARN] Google login rejected: %v", err)
+			http.Redirect(w, r, failureURL+"linked", http.StatusSeeOther)
+		default:
+			log.Printf("[ERROR] Failed to provision Google user: %v", err)
+			http.Redirect(w, r, failureURL+"provisioning", http.StatusSeeOther)
+		}
+		return
+	}
+
+	if err := startSession(w, r, user.Id, false); err != nil {
+		http.Redirect(w, r, failureURL+"session", http.StatusSeeOther)
+		return
+	}
+	log.Printf("[INFO] User with ID %s signed in with Google", user.Id.Hex())
+	notifySecurityEvent(r, user, models.SecurityEventLogin, map[string]string{"method": "google"})
+	recordLogin(r, user, "google")
+	http.Redirect(w, r, config.Get().FrontendURL+"/", http.StatusSeeOther)
+}
+
+// provisionGoogleUser finds the account of a Google identity. An account with
+// the same verified email that isn't linked to another Google account yet is
+// linked on first sign-in; otherwise a new account is created.
+func provisionGoogleUser(identity *services.OIDCIdentity) (*models.User, error) {
+	user, err := repo.GetUserByGoogleId(identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if user.Disabled {
+			return nil, fmt.Errorf("user %s is disabled: %w", user.Id.Hex(), apperrors.ErrForbidden)
+		}
+		return user, nil
+	}
+
+	email := strings.ToLower(identity.Email)
+	matches, err := repo.GetUsersByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%d accounts use the email %s: %w", len(matches), email, apperrors.ErrConflict)
+	}
+	if len(matches) == 1 {
+		user = &matches[0]
+		if !user.EmailVerified || user.GoogleId != "" {
+			return nil, fmt.Errorf("user %s can't be linked to a Google account: %w", user.Id.Hex(), apperrors.ErrConflict)
+		}
+		if user.Disabled {
+			return nil, fmt.Errorf("user %s is disabled: %w", user.Id.Hex(), apperrors.ErrForbidden)
+		}
+		user.GoogleId = identity.Subject
+		if err := repo.UpdateUser(user.Id.Hex(), user); err != nil {
+			return nil, err
+		}
+		log.Printf("[INFO] Linked user %s to a Google account", user.Id.Hex())
+		return user, nil
+	}
+
+	user = &models.User{
+		Email:    email,
+		GoogleId: identity.Subject,
+	}
+	if err := createExternalUser(user); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Created user %s from a Google sign-in", user.Id.Hex())
+	return user, nil
+}
@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/metrics"
 	"social-scribe/backend/internal/models"
 	repo "social-scribe/backend/internal/repositories"
 	"social-scribe/backend/internal/scheduler"
 	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
 	"strings"
 	"time"
 
@@ -23,46 +28,158 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
-	"golang.org/x/crypto/bcrypt"
 	"math/rand"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/linkedin"
 )
 
-var twitterConfig = &oauth1.Config{}
-var linkedinConfig = &oauth2.Config{}
+// Deps is what the handlers need from outside the package.
+type Deps struct {
+	TwitterConfig  *oauth1.Config
+	LinkedInConfig *oauth2.Config
+	RedditConfig   *oauth2.Config
+	TumblrConfig   *oauth1.Config
+	// GoogleBusinessConfig is the Google app Business Profile posts are
+	// authorized through.
+	GoogleBusinessConfig *oauth2.Config
+	// YouTubeConfig is the Google app YouTube channels are looked up
+	// through.
+	YouTubeConfig *oauth2.Config
+	// Scheduler queues scheduled shares; handlers that schedule need it.
+	Scheduler *scheduler.Scheduler
+}
 
-func init() {
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
-	twitterConfig = &oauth1.Config{
-		ConsumerKey:    os.Getenv("TWITTER_CONSUMER_KEY"),
-		ConsumerSecret: os.Getenv("TWITTER_CONSUMER_SECRET"),
-		CallbackURL:    os.Getenv("TWITTER_CALLBACK_URL"),
-		Endpoint:       twitter.AuthorizeEndpoint,
-	}
-	linkedinConfig = &oauth2.Config{
-		ClientID:     os.Getenv("LINKEDIN_CLIENT_ID"),
-		ClientSecret: os.Getenv("LINKEDIN_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("LINKEDIN_CALLBACK_URL"),
-		Scopes:       []string{"openid", "profile", "email", "w_member_social"},
-		Endpoint:     linkedin.Endpoint,
+// DepsFromEnv builds the X, LinkedIn, Reddit, Tumblr, Google and YouTube
+// app configuration from the environment. The scheduler is left for the caller
+// to add.
+func DepsFromEnv() Deps {
+	return Deps{
+		TwitterConfig: &oauth1.Config{
+			ConsumerKey:    os.Getenv("TWITTER_CONSUMER_KEY"),
+			ConsumerSecret: os.Getenv("TWITTER_CONSUMER_SECRET"),
+			CallbackURL:    os.Getenv("TWITTER_CALLBACK_URL"),
+			Endpoint:       twitter.AuthorizeEndpoint,
+		},
+		LinkedInConfig: &oauth2.Config{
+			ClientID:     os.Getenv("LINKEDIN_CLIENT_ID"),
+			ClientSecret: os.Getenv("LINKEDIN_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("LINKEDIN_CALLBACK_URL"),
+			Scopes:       []string{"openid", "profile", "email", "w_member_social"},
+			Endpoint:     linkedin.Endpoint,
+		},
+		RedditConfig: &oauth2.Config{
+			ClientID:     os.Getenv("REDDIT_CLIENT_ID"),
+			ClientSecret: os.Getenv("REDDIT_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("REDDIT_CALLBACK_URL"),
+			Scopes:       services.RedditScopes,
+			Endpoint:     services.RedditEndpoint,
+		},
+		TumblrConfig: &oauth1.Config{
+			ConsumerKey:    os.Getenv("TUMBLR_CONSUMER_KEY"),
+			ConsumerSecret: os.Getenv("TUMBLR_CONSUMER_SECRET"),
+			CallbackURL:    os.Getenv("TUMBLR_CALLBACK_URL"),
+			Endpoint:       services.TumblrEndpoint,
+		},
+		GoogleBusinessConfig: &oauth2.Config{
+			ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("GOOGLE_BUSINESS_CALLBACK_URL"),
+			Scopes:       services.GoogleBusinessScopes,
+			Endpoint:     services.GoogleBusinessEndpoint,
+		},
+		YouTubeConfig: &oauth2.Config{
+			ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("YOUTUBE_CALLBACK_URL"),
+			Scopes:       services.YouTubeScopes,
+			Endpoint:     services.GoogleBusinessEndpoint,
+		},
 	}
+}
 
-	services.InitTwitterConfig(twitterConfig)
+// Handlers serves the API with one set of dependencies.
+type Handlers struct {
+	twitterConfig        *oauth1.Config
+	linkedinConfig       *oauth2.Config
+	redditConfig         *oauth2.Config
+	tumblrConfig         *oauth1.Config
+	googleBusinessConfig *oauth2.Config
+	youtubeConfig        *oauth2.Config
+	taskScheduler        *scheduler.Scheduler
+}
 
+// New returns handlers using deps. Missing OAuth configs are left empty, so
+// tests only need to supply what they exercise.
+func New(deps Deps) *Handlers {
+	h := &Handlers{
+		twitterConfig:        deps.TwitterConfig,
+		linkedinConfig:       deps.LinkedInConfig,
+		redditConfig:         deps.RedditConfig,
+		tumblrConfig:         deps.TumblrConfig,
+		googleBusinessConfig: deps.GoogleBusinessConfig,
+		youtubeConfig:        deps.YouTubeConfig,
+		taskScheduler:        deps.Scheduler,
+	}
+	if h.twitterConfig == nil {
+		h.twitterConfig = &oauth1.Config{}
+	}
+	if h.linkedinConfig == nil {
+		h.linkedinConfig = &oauth2.Config{}
+	}
+	if h.redditConfig == nil {
+		h.redditConfig = &oauth2.Config{}
+	}
+	if h.tumblrConfig == nil {
+		h.tumblrConfig = &oauth1.Config{}
+	}
+	if h.googleBusinessConfig == nil {
+		h.googleBusinessConfig = &oauth2.Config{}
+	}
+	if h.youtubeConfig == nil {
+		h.youtubeConfig = &oauth2.Config{}
+	}
+	// Posts to X and Tumblr are signed with the app credentials and Reddit
+	// and Google tokens are refreshed with them, which the share pipeline
+	// reads process-wide
+	services.InitTwitterConfig(h.twitterConfig)
+	services.InitRedditConfig(h.redditConfig)
+	services.InitTumblrConfig(h.tumblrConfig)
+	services.InitGoogleBusinessConfig(h.googleBusinessConfig)
+	return h
 }
 
-var taskScheduler *scheduler.Scheduler
+// twitterOAuth returns the X app configuration for the user, their own app
+// if they have one, with token requests going through the provider client.
+func (h *Handlers) twitterOAuth(user *models.User) (*oauth1.Config, error) {
+	twitter := *h.twitterConfig
+	twitter.HTTPClient = services.ProviderClient(services.ProviderTwitter)
+	if user.TwitterApp != nil {
+		secret, err := services.OpenUserSecret(user, user.TwitterApp.SealedSecret)
+		if err != nil {
+			return nil, err
+		}
+		twitter.ConsumerKey = user.TwitterApp.ClientID
+		twitter.ConsumerSecret = secret
+	}
+	return &twitter, nil
+}
 
-func InitScheduler(s *scheduler.Scheduler) {
-	taskScheduler = s
+// linkedinOAuth returns the LinkedIn app configuration for the user, their
+// own app if they have one.
+func (h *Handlers) linkedinOAuth(user *models.User) (*oauth2.Config, error) {
+	app := *h.linkedinConfig
+	if user.LinkedInApp != nil {
+		secret, err := services.OpenUserSecret(user, user.LinkedInApp.SealedSecret)
+		if err != nil {
+			return nil, err
+		}
+		app.ClientID = user.LinkedInApp.ClientID
+		app.ClientSecret = secret
+	}
+	return &app, nil
 }
 
-func SignupUserHandler(resp http.ResponseWriter, req *http.Request) {
+func (h *Handlers) SignupUserHandler(resp http.ResponseWriter, req *http.Request) {
 	if req.Body == nil {
 		http.Error(resp, `{"error": "Failed to parse credentials: body is empty"}`, http.StatusBadRequest)
 		return
@@ -99,7 +216,7 @@ func SignupUserHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.PassWord), bcrypt.DefaultCost)
+	hashedPassword, err := services.HashPassword(user.PassWord)
 	if err != nil {
 		http.Error(resp, `{"error": "Internal server error"}`, http.StatusInternalServerError)
 		log.Printf("[ERROR] Error hashing password for user '%s': %v", user.UserName, err)
@@ -107,38 +224,43 @@ func SignupUserHandler(resp http.ResponseWriter, req *http.Request) {
 	}
 
 	user.Verified = false
-	user.LinkedinVerified = false
 	user.EmailVerified = false
 	user.HashnodeVerified = false
-	user.XVerified = false
-	user.PassWord = string(hashedPassword)
+	// A new account starts without connections, whatever the body carried
+	for _, platform := range services.Platforms() {
+		platform.Disconnect(&user)
+	}
+	user.PassWord = hashedPassword
+	user.Email = ""
+	user.TeamID = ""
+	user.TeamRole = ""
+	user.Role = models.RoleUser
+	user.SSOSubject = ""
+	user.GoogleId = ""
+	user.Region = models.RegionDefault
+	user.Plan = models.PlanFree
+	user.CreatedAt = utils.Now()
+	user.LastActiveAt = user.CreatedAt
 
-	userId, err := repo.InsertUser(user)
+	userId, err := repo.CreateUser(user)
 	if err != nil {
-		log.Printf("[ERROR] Unable to create user %v: %v", user.UserName, err)
-		http.Error(resp, `{"error": "Failed to create user"}`, http.StatusInternalServerError)
+		writeError(resp, err)
 		return
 	}
+	if signups, err := metrics.Signups.GetMetricWithLabelValues(user.Plan); err != nil {
+		log.Printf("[WARN] Failed to count the signup: %v", err)
+	} else {
+		signups.Inc()
+	}
 
-	sessionToken := uuid.New().String()
-	expiration := time.Now().Add(24 * time.Hour)
-	err = repo.SetCache(sessionToken, userId, 24*time.Hour)
-	if err != nil {
+	user.Id, _ = primitive.ObjectIDFromHex(userId)
+	if err := startSession(resp, req, user.Id, false); err != nil {
+		log.Printf("[ERROR] Failed to create session for the user %s: %v", userId, err)
 		http.Error(resp, `{"error": "Failed to create session"}`, http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(resp, &http.Cookie{
-		Name:     "session_token",
-		Value:    sessionToken,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   false,
-		Expires:  expiration,
-	})
-
-	user.PassWord = ""
-	responseJson, err := json.Marshal(user)
+	responseJson, err := json.Marshal(userDTO(&user))
 	if err != nil {
 		resp.WriteHeader(http.StatusInternalServerError)
 		resp.Write([]byte(`{"success": false, "reason": "Failed unpacking user"}`))
@@ -152,7 +274,7 @@ func SignupUserHandler(resp http.ResponseWriter, req *http.Request) {
 	resp.Write([]byte(responseJson))
 }
 
-func LoginUserHandler(resp http.ResponseWriter, req *http.Request) {
+func (h *Handlers) LoginUserHandler(resp http.ResponseWriter, req *http.Request) {
 
 	if req.Body == nil {
 		http.Error(resp, `{"error": "Failed to parse login credentials: body is empty"}`, http.StatusBadRequest)
@@ -168,64 +290,304 @@ func LoginUserHandler(resp http.ResponseWriter, req *http.Request) {
 
 	data.Username = strings.ToLower(strings.TrimSpace(data.Username))
 	if len(data.Username) < 4 || len(data.Username) > 64 {
-		http.Error(resp, `{"error": "Username is should in range of minimum 4 to maximum 64 characters}`, http.StatusBadGateway)
+		http.Error(resp, `{"error": "The username should contain a minimum of 4 and maximum of 64 characters"}`, http.StatusBadRequest)
+		return
 	}
 	if len(data.Password) > 128 {
-		http.Error(resp, `{"error" : "password is too long, the maximum allowed length is 128 chars"}`, http.StatusBadGateway)
+		http.Error(resp, `{"error" : "password is too long, the maximum allowed length is 128 chars"}`, http.StatusBadRequest)
+		return
 	}
-	user, err := repo.GetUserByName(data.Username)
-	if user == nil {
-		http.Error(resp, `{"success": false, "reason": "Username and/or password is incorrect"}`, http.StatusBadRequest)
+	// The lockout is keyed on an address the client can't pick, or it
+	// could dodge its own lockout or lock out someone else's
+	clientIP := utils.TrustedClientIP(req)
+	if loginLockedOut(resp, clientIP, data.Username) {
 		return
 	}
+	user, err := repo.GetUserByName(data.Username)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the username %s and the error is %s", data.Username, err)
 		http.Error(resp, `{"error" : "Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.PassWord), []byte(data.Password))
+	if user == nil {
+		loginFailed(resp, clientIP, data.Username)
+		return
+	}
+	match, needsRehash, err := services.VerifyPassword(user.PassWord, data.Password)
 	if err != nil {
-		http.Error(resp, `{"success": false, "reason": "Username and/or password is incorrect"}`, http.StatusBadRequest)
+		log.Printf("[ERROR] Failed to verify password for the username %s and the error is %s", data.Username, err)
+		http.Error(resp, `{"error" : "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !match {
+		notifySecurityEvent(req, user, models.SecurityEventLoginFailed, map[string]string{"method": "password", "reason": "invalid_password"})
+		loginFailed(resp, clientIP, data.Username)
 		return
 	}
+	if user.Disabled {
+		http.Error(resp, `{"success": false, "reason": "This account has been deactivated by your team"}`, http.StatusForbidden)
+		return
+	}
+	if err := repo.ClearLoginFailures(repo.LoginScopeUser, data.Username); err != nil {
+		log.Printf("[WARN] Failed to clear login failures of %s: %v", data.Username, err)
+	}
+	if needsRehash {
+		rehashed, err := services.HashPassword(data.Password)
+		if err != nil {
+			log.Printf("[WARN] Failed to rehash password for the user %s: %v", user.Id.Hex(), err)
+		} else {
+			user.PassWord = rehashed
+			if err := repo.UpdateUser(user.Id.Hex(), user); err != nil {
+				log.Printf("[WARN] Failed to store rehashed password for the user %s: %v", user.Id.Hex(), err)
+			}
+		}
+	}
 
-	sessionToken := uuid.New().String()
-	expiration := time.Now().Add(24 * time.Hour)
-	err = repo.SetCache(sessionToken, user.Id, 24*time.Hour)
-	if err != nil {
+	if logins, err := metrics.Logins.GetMetricWithLabelValues(metrics.Cohort(user)); err != nil {
+		log.Printf("[WARN] Failed to count the login: %v", err)
+	} else {
+		logins.Inc()
+	}
+	if err := repo.TouchUserActivity(user.Id.Hex(), utils.Now()); err != nil {
+		log.Printf("[WARN] Failed to record activity for the user %s: %v", user.Id.Hex(), err)
+	}
+
+	if err := startSession(resp, req, user.Id, data.RememberMe); err != nil {
+		log.Printf("[ERROR] Failed to create session for the user %s: %v", user.Id.Hex(), err)
 		http.Error(resp, `{"error": "Failed to create session"}`, http.StatusInternalServerError)
 		return
 	}
+	notifySecurityEvent(req, user, models.SecurityEventLogin, map[string]string{"method": "password"})
+	recordLogin(req, user, "password")
 
-	http.SetCookie(resp, &http.Cookie{
+	responseJson, err := json.Marshal(userDTO(user))
+	if err != nil {
+		resp.WriteHeader(401)
+		resp.Write([]byte(`{"success": false, "reason": "Failed unpacking user"}`))
+		return
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Write([]byte(responseJson))
+}
+
+// startSession issues the session cookie for the user: an opaque token kept
+// in the session cache, or a signed JWT when session_tokens is jwt. Either
+// way the session is recorded with where it was started, so the user can
+// list and revoke it. Remembered sessions get the longer remember-me
+// lifetime.
+func startSession(w http.ResponseWriter, r *http.Request, userId primitive.ObjectID, rememberMe bool) error {
+	var sessionToken string
+	sessionTTL := config.Get().SessionLifetime()
+	if rememberMe {
+		sessionTTL = config.Get().RememberMeLifetime()
+	}
+	expiration := utils.Now().Add(sessionTTL)
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxSessionUserAgent {
+		userAgent = userAgent[:maxSessionUserAgent]
+	}
+	info := models.SessionInfo{CreatedAt: utils.Now(), IP: utils.GetClientIP(r), UserAgent: userAgent, RememberMe: rememberMe}
+
+	sessionKey := ""
+	if config.Get().JWTSessions() {
+		token, jti, err := services.IssueSessionJWT(userId.Hex(), sessionTTL)
+		if err != nil {
+			return err
+		}
+		sessionToken = token
+		sessionKey = repo.SessionJWTRecordKey(jti)
+	} else {
+		sessionToken = uuid.New().String()
+		sessionKey = sessionToken
+	}
+	if err := repo.StoreSession(sessionKey, userId, info, sessionTTL); err != nil {
+		return err
+	}
+	setCookie(w, &http.Cookie{
 		Name:     "session_token",
 		Value:    sessionToken,
 		HttpOnly: true,
 		Path:     "/",
-		Secure:   false,
 		Expires:  expiration,
 	})
+	return nil
+}
+
+// maxSessionUserAgent bounds the user agent stored with a session.
+const maxSessionUserAgent = 512
+
+// endSession invalidates a session token. Tokens that aren't ours are
+// ignored.
+func endSession(token string) error {
+	if services.IsSessionJWT(token) {
+		// JWTs stay valid until they expire unless revoked
+		claims, err := services.ParseSessionJWT(token)
+		if err != nil {
+			return nil
+		}
+		return repo.RevokeSessionJWT(claims.ID, time.Unix(claims.ExpiresAt, 0))
+	}
+	// Opaque session tokens are UUIDs; anything else is not ours to delete
+	if uuid.Validate(token) != nil {
+		return nil
+	}
+	return repo.DeleteCache(token)
+}
 
-	user.PassWord = ""
-	responseJson, err := json.Marshal(user)
+// RefreshSessionHandler swaps the caller's session for a new one with a full
+// lifetime, so active users aren't signed out when the old one runs out. The
+// old token stops working.
+func (h *Handlers) RefreshSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
 	if err != nil {
-		resp.WriteHeader(401)
-		resp.Write([]byte(`{"success": false, "reason": "Failed unpacking user"}`))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, `{"error": "user id is not valid"}`, http.StatusNotFound)
+		return
+	}
+	if user.Disabled {
+		http.Error(w, `{"success": false, "reason": "This account has been deactivated by your team"}`, http.StatusForbidden)
 		return
 	}
 
-	resp.WriteHeader(http.StatusAccepted)
-	resp.Header().Set("Content-Type", "application/json")
-	resp.Write([]byte(responseJson))
+	if err := startSession(w, r, user.Id, currentSessionRemembered(r)); err != nil {
+		log.Printf("[ERROR] Failed to refresh the session for the user %s: %v", userId, err)
+		http.Error(w, `{"error": "Failed to create session"}`, http.StatusInternalServerError)
+		return
+	}
+	// The new session is already issued; the old one expires on its own if
+	// this fails
+	if err := endSession(cookie.Value); err != nil {
+		log.Printf("[WARN] Failed to end the refreshed session for the user %s: %v", userId, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// LogoutUserHandler ends the caller's session. The token is deleted even when
+// it has already expired, and the cookie is cleared either way, so logging out
+// always succeeds from the browser's point of view.
+func (h *Handlers) LogoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		if err := endSession(cookie.Value); err != nil {
+			http.Error(w, `{"error": "Failed to end session"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	setCookie(w, &http.Cookie{
+		Name:     "session_token",
+		Value:    "",
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
 }
 
-func GetUserInfoHandler(resp http.ResponseWriter, req *http.Request) {
+// ChangePasswordHandler replaces the caller's password after checking the
+// current one, then signs out every other session so a leaked password or
+// session stops working.
+func (h *Handlers) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "Bad request: unable to decode JSON"}`, http.StatusBadRequest)
+		return
+	}
+	// Neither password is trimmed: login checks the password as typed
+	if len(body.CurrentPassword) > 128 {
+		http.Error(w, `{"error" : "password is too long, the maximum allowed length is 128 chars"}`, http.StatusBadRequest)
+		return
+	}
+	if len(body.NewPassword) < 8 || len(body.NewPassword) > 128 {
+		http.Error(w, `{"error": "The password should contain a minimum of 8 and maximum of 128 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, `{"error" : "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, `{"error": "user id is not valid"}`, http.StatusNotFound)
+		return
+	}
+
+	match, _, err := services.VerifyPassword(user.PassWord, body.CurrentPassword)
+	if err != nil {
+		log.Printf("[ERROR] Failed to verify password for the user %s and the error is %s", userId, err)
+		http.Error(w, `{"error" : "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !match {
+		http.Error(w, `{"success": false, "reason": "Current password is incorrect"}`, http.StatusForbidden)
+		return
+	}
+
+	hashedPassword, err := services.HashPassword(body.NewPassword)
+	if err != nil {
+		log.Printf("[ERROR] Error hashing password for the user %s: %v", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	user.PassWord = hashedPassword
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update password for the user %s: %v", userId, err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := signOutOtherSessions(w, r, user.Id); err != nil {
+		log.Printf("[ERROR] Failed to sign out other sessions of the user %s: %v", userId, err)
+		http.Error(w, `{"error": "Password changed but other sessions could not be signed out"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Password changed for the user %s", userId)
+	notifySecurityEvent(r, user, models.SecurityEventSessionRevoked, map[string]string{"scope": "others", "reason": "password_changed"})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (h *Handlers) GetUserInfoHandler(resp http.ResponseWriter, req *http.Request) {
 	userId, err := ValidateLogin(req)
 	if err != nil {
 		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(req.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to find user for the id: %s and error is %s", userId, err)
 		http.Error(resp, `{"error": ""}`, http.StatusInternalServerError)
@@ -235,12 +597,7 @@ func GetUserInfoHandler(resp http.ResponseWriter, req *http.Request) {
 		http.Error(resp, `{"error": "user id is not valid"}`, http.StatusNotFound)
 		return
 	}
-	user.PassWord = ""
-	user.HashnodePAT = ""
-	user.LinkedInOauthKey = ""
-	user.XOAuthToken = ""
-	user.XOAuthSecret = ""
-	responseJson, err := json.Marshal(user)
+	responseJson, err := json.Marshal(userDTO(user))
 	if err != nil {
 		resp.WriteHeader(401)
 		resp.Write([]byte(`{"success": false, "reason": "Failed unpacking"}`))
@@ -251,14 +608,41 @@ func GetUserInfoHandler(resp http.ResponseWriter, req *http.Request) {
 	resp.Write(responseJson)
 }
 
-func GetUserNotificationsHandler(resp http.ResponseWriter, req *http.Request) {
+func (h *Handlers) GetUserProfileHandler(resp http.ResponseWriter, req *http.Request) {
+	userId, err := ValidateLogin(req)
+	if err != nil {
+		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(req.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to find user for the id: %s and error is %s", userId, err)
+		http.Error(resp, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(resp, `{"error": "user id is not valid"}`, http.StatusNotFound)
+		return
+	}
+	responseJson, err := json.Marshal(userProfileDTO(user))
+	if err != nil {
+		http.Error(resp, `{"success": false, "reason": "Failed unpacking"}`, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+	resp.Write(responseJson)
+}
+
+func (h *Handlers) GetUserNotificationsHandler(resp http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	userId := vars["id"]
 	if len(userId) == 0 {
 		http.Error(resp, `{"error": "cant able parse id field, reason is missing id field in the request"}`, http.StatusBadRequest)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(req.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to find user for the id: %s and error is %s", userId, err)
 		http.Error(resp, `{"error": ""}`, http.StatusInternalServerError)
@@ -283,15 +667,14 @@ func GetUserNotificationsHandler(resp http.ResponseWriter, req *http.Request) {
 
 }
 
-func GetUserSharedBlogsHandler(resp http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	userId := vars["id"]
-	if len(userId) == 0 {
-		resp.WriteHeader(401)
-		resp.Write([]byte(`{"success" : false, "reason" : "user id not found in the request}`))
+func (h *Handlers) GetUserSharedBlogsHandler(resp http.ResponseWriter, req *http.Request) {
+	userId, err := ValidateLogin(req)
+	if err != nil {
+		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	// Share history is read-only, so a replica may serve it
+	user, err := repo.GetUserByIdStale(userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to find user for the id: %s and error is %s", userId, err)
 		resp.WriteHeader(500)
@@ -317,29 +700,43 @@ func GetUserSharedBlogsHandler(resp http.ResponseWriter, req *http.Request) {
 
 }
 
-func GetUserScheduledBlogsHandler(resp http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	userId := vars["id"]
-	if len(userId) == 0 {
-		resp.WriteHeader(401)
-		resp.Write([]byte(`"success" : false, "reason" : "user id not provided`))
-		return
-
-	}
-	user, err := repo.GetUserById(userId)
-	if user == nil {
-		resp.WriteHeader(401)
-		resp.Write([]byte(`"success" : false, "reason" : "user id is invalid`))
+func (h *Handlers) GetUserScheduledBlogsHandler(resp http.ResponseWriter, req *http.Request) {
+	userId, err := ValidateLogin(req)
+	if err != nil {
+		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if err != nil {
-		log.Printf("[ERROR] Failed to find user for the id: %s and error is %s", userId, err)
+	user, err := repo.GetRequestUser(req.Context(), userId)
+	if err != nil || user == nil {
 		resp.WriteHeader(500)
-		resp.Write([]byte(`{"success" : "false"}`))
+		resp.Write([]byte(`{"success" : false}`))
 		return
 	}
+	// The scheduler queue is the source of truth for what is still pending.
+	// Child tasks are listed once, as their parent schedule.
+	scheduledBlogs := []models.ScheduledBlog{}
+	listed := map[string]bool{}
+	for _, task := range h.taskScheduler.ListTasks(userId) {
+		if task.Platform == "" {
+			scheduledBlogs = append(scheduledBlogs, task.ScheduledBlog)
+			continue
+		}
+		if listed[task.ScheduledBlog.Id] {
+			continue
+		}
+		listed[task.ScheduledBlog.Id] = true
+		parent := task.ScheduledBlog
+		for _, blog := range user.ScheduledBlogs {
+			if blog.Id == parent.Id {
+				parent = blog
+				break
+			}
+		}
+		parent.Status = parent.RollupStatus()
+		scheduledBlogs = append(scheduledBlogs, parent)
+	}
 	response := map[string]interface{}{
-		"scheduled_blogs": user.ScheduledBlogs,
+		"scheduled_blogs": scheduledBlogs,
 	}
 	responseJson, err := json.Marshal(response)
 	if err != nil {
@@ -351,15 +748,14 @@ func GetUserScheduledBlogsHandler(resp http.ResponseWriter, req *http.Request) {
 	resp.Write(responseJson)
 }
 
-func ClearUserNotificationsHandler(resp http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	userId := vars["id"]
-	if len(userId) == 0 {
-		resp.WriteHeader(401)
-		resp.Write([]byte(`"success" : false, "reason" : "missing user id in the request"`))
+func (h *Handlers) ClearUserNotificationsHandler(resp http.ResponseWriter, req *http.Request) {
+	// Only the signed in user's own notifications can be cleared
+	userId, err := ValidateLogin(req)
+	if err != nil {
+		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(req.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] failed to get user for the id: %s and the error is %s", userId, err)
 		resp.WriteHeader(500)
@@ -373,6 +769,7 @@ func ClearUserNotificationsHandler(resp http.ResponseWriter, req *http.Request)
 
 	}
 	user.Notifications = []string{}
+	user.NotificationTimes = nil
 	err = repo.UpdateUser(userId, user)
 	if err != nil {
 		log.Printf("[ERROR] failed to update user with id: %s", userId)
@@ -384,7 +781,7 @@ func ClearUserNotificationsHandler(resp http.ResponseWriter, req *http.Request)
 	resp.Write([]byte(`{"success" : true, "message" : "notifications cleared sucessfully"}`))
 }
 
-func ScheduleUserBlogHandler(resp http.ResponseWriter, req *http.Request) {
+func (h *Handlers) ScheduleUserBlogHandler(resp http.ResponseWriter, req *http.Request) {
 	var blogData models.ScheduledBlogData
 	decoder := json.NewDecoder(req.Body)
 	defer req.Body.Close()
@@ -400,7 +797,7 @@ func ScheduleUserBlogHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := repo.GetUserById(blogData.UserID)
+	user, err := repo.GetRequestUser(req.Context(), blogData.UserID)
 	if err != nil {
 		resp.WriteHeader(http.StatusInternalServerError)
 		resp.Write([]byte(`{"success" : false}`))
@@ -427,14 +824,14 @@ func ScheduleUserBlogHandler(resp http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := blogData.ScheduledBlog.Validate(); err != nil {
-		http.Error(resp, err.Error(), http.StatusBadRequest)
+		writeError(resp, err)
 		return
 	}
 
 	durableFunctionURL := "https://<your-function-app>.azurewebsites.net/api/orchestrator"
 	reqBody, _ := json.Marshal(blogData)
 
-	durableResp, err := http.Post(durableFunctionURL, "application/json", bytes.NewBuffer(reqBody))
+	durableResp, err := services.ProviderClient(services.ProviderWeb).Post(durableFunctionURL, "application/json", bytes.NewBuffer(reqBody))
 	if err != nil || durableResp.StatusCode != http.StatusOK {
 		log.Printf("[DEBUG] Failed to create durable function, reason: %s", err)
 		resp.WriteHeader(http.StatusInternalServerError)
@@ -453,14 +850,15 @@ func ScheduleUserBlogHandler(resp http.ResponseWriter, req *http.Request) {
 	resp.Write([]byte("Blog scheduled validated"))
 }
 
-func GetUserBlogsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserBlogsHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	user, err := repo.GetUserById(userId)
+	// Blog lists are read-only, so a replica may serve them
+	user, err := repo.GetUserByIdStale(userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for id: %s - %v", userId, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -489,53 +887,79 @@ func GetUserBlogsHandler(w http.ResponseWriter, r *http.Request) {
 	case "shared":
 		responseBytes, jsonErr = json.Marshal(user.SharedBlogs)
 	default:
-		// Handle "all" case with GraphQL
-		endpoint := "https://gql.hashnode.com"
-		query := models.GraphQLQuery{
-			Query: fmt.Sprintf(`
-                query Publication {
-                    publication(host: "%s") {
-                        posts(first: 0) {
-                            edges {
-                                node {
-                                    title
-                                    url
-                                    id
-                                    coverImage { url }
-                                    author { name }
-                                    readTimeInMinutes
-                                }
-                            }
-                        }
-                    }
-                }`, user.HashnodeBlog),
-		}
-
-		queryBytes, err := json.Marshal(query)
+		hosts, err := services.SelectPublications(user, strings.TrimSpace(r.URL.Query().Get("publication")))
 		if err != nil {
-			log.Printf("[ERROR] Failed to marshal query: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
-
-		headers := map[string]string{"Content-Type": "application/json"}
-		gqlResponse, err := services.MakePostRequest(endpoint, queryBytes, headers)
+		synced, err := repo.GetUserPosts(userId)
 		if err != nil {
-			log.Printf("[ERROR] Failed to make request: %v", err)
+			log.Printf("[ERROR] Failed to get posts of user %s: %v", userId, err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
-
-		var gqlData models.GraphQLResponse
-		if err := json.Unmarshal(gqlResponse, &gqlData); err != nil {
-			log.Printf("[ERROR] Failed to unmarshal response: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+		posts := make([]models.PostNode, 0, len(synced))
+		feedItems := []models.PostNode{}
+		for _, post := range synced {
+			if post.Source == models.PostSourceFeed {
+				feedItems = append(feedItems, post.Node())
+				continue
+			}
+			// Posts synced before publications were told apart are the
+			// default one's
+			if post.Publication == "" {
+				post.Publication = user.HashnodeBlog
+			}
+			if slices.Contains(hosts, post.Publication) {
+				posts = append(posts, post.Node())
+			}
 		}
-
-		var posts []models.PostNode
-		for _, edge := range gqlData.Data.Publication.Posts.Edges {
-			posts = append(posts, edge.Node)
+		// Until the first sync finishes the posts come straight from Hashnode
+		if len(posts) == 0 {
+			for _, host := range hosts {
+				hostPosts, err := services.FetchPublicationPosts(host)
+				if err != nil {
+					log.Printf("[ERROR] Failed to fetch posts of %s: %v", host, err)
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				for _, post := range hostPosts {
+					post.Publication = host
+					posts = append(posts, post)
+				}
+			}
+		}
+		// Ghost posts, Medium stories, feed items and Dev.to articles follow
+		// the Hashnode posts in that order; a source that fails is left out
+		if user.Ghost != nil {
+			listedURLs := map[string]bool{}
+			for _, post := range posts {
+				listedURLs[post.URL] = true
+			}
+			ghostPosts, err := services.FetchGhostPosts(user, listedURLs)
+			if err != nil {
+				log.Printf("[WARN] Listing blogs of user %s without their Ghost posts: %v", userId, err)
+			}
+			posts = append(posts, ghostPosts...)
+		}
+		if user.MediumSource != nil {
+			stories, err := services.FetchMediumStories(user)
+			if err != nil {
+				log.Printf("[WARN] Listing blogs of user %s without their Medium stories: %v", userId, err)
+			}
+			posts = append(posts, stories...)
+		}
+		posts = append(posts, feedItems...)
+		if user.Devto != nil {
+			listedURLs := map[string]bool{}
+			for _, post := range posts {
+				listedURLs[post.URL] = true
+			}
+			articles, err := services.FetchDevtoArticles(user, listedURLs)
+			if err != nil {
+				log.Printf("[WARN] Listing blogs of user %s without their Dev.to articles: %v", userId, err)
+			}
+			posts = append(posts, articles...)
 		}
 		responseBytes, jsonErr = json.Marshal(posts)
 	}
@@ -577,13 +1001,13 @@ func GetUserBlogsHandler(w http.ResponseWriter, r *http.Request) {
 // 	return ioutil.ReadAll(response.Body)
 // }
 
-func ConnectXhandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ConnectXhandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		return
@@ -593,21 +1017,30 @@ func ConnectXhandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	requestToken, requestSecret, err := twitterConfig.RequestToken()
+	requestShareHistoryImport(r, userId, "twitter")
+	app, err := h.twitterOAuth(user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load the X app of user %s: %v", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	requestToken, requestSecret, err := app.RequestToken()
 	if err != nil {
 		fmt.Printf("error: %v", err)
 		http.Error(w, "Failed to get request token", http.StatusInternalServerError)
 		return
 	}
-	user.XOAuthToken = requestToken
-	user.XOAuthSecret = requestSecret
+	if err := services.SetXToken(user, requestToken, requestSecret); err != nil {
+		writeError(w, err)
+		return
+	}
 	err = repo.UpdateUser(userId, user)
 	if err != nil {
 		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
 		return
 	}
 
-	authorizationURL, err := twitterConfig.AuthorizationURL(requestToken)
+	authorizationURL, err := app.AuthorizationURL(requestToken)
 	if err != nil {
 		http.Error(w, "Failed to get authorization URL", http.StatusInternalServerError)
 		return
@@ -616,14 +1049,14 @@ func ConnectXhandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, authorizationURL.String(), http.StatusFound)
 }
 
-func XcallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) XcallbackHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userID)
+	user, err := repo.GetRequestUser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Failed to get user", http.StatusInternalServerError)
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userID, err)
@@ -636,30 +1069,36 @@ func XcallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := user.XOAuthToken
-	secret := user.XOAuthSecret
-
-	requestTokenData := &oauth1.Token{Token: token, TokenSecret: secret}
+	requestTokenData, err := services.XToken(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 	verifier := r.URL.Query().Get("oauth_verifier")
 	if verifier == "" {
 		log.Printf("[ERROR] Missing OAuth verifier for user with id: %s", userID)
 		http.Error(w, "Missing OAuth verifier", http.StatusBadRequest)
 		return
 	}
-	accessToken, accessSecret, err := twitterConfig.AccessToken(requestTokenData.Token, requestTokenData.TokenSecret, verifier)
+	app, err := h.twitterOAuth(user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load the X app of user %s: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	accessToken, accessSecret, err := app.AccessToken(requestTokenData.Token, requestTokenData.TokenSecret, verifier)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get access token for user with id: %s and error is %s", userID, err)
 		http.Error(w, "Failed to get access token", http.StatusInternalServerError)
 		return
 	}
-	user.XOAuthToken = accessToken
-	user.XOAuthSecret = accessSecret
-	user.XVerified = true
-	if (user.XVerified || user.LinkedinVerified) && user.HashnodeVerified {
-		user.Verified = true
-	} else {
-		user.Verified = false
+	firstConnection := !user.XVerified
+	if err := services.SetXToken(user, accessToken, accessSecret); err != nil {
+		writeError(w, err)
+		return
 	}
+	user.XVerified = true
+	services.RefreshVerified(user)
 	err = repo.UpdateUser(userID, user)
 	if err != nil {
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
@@ -668,36 +1107,18 @@ func XcallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[INFO] User with ID %s connected to X(twitter) Successfully", user.Id)
-	http.Redirect(w, r, "http://localhost:5173/verification", http.StatusSeeOther)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "twitter", "action": "connected"})
+	startShareHistoryImport(userID, "twitter", firstConnection)
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
 }
 
-// func PostTweetHandler(message string, blogId string, userToken *oauth1.Token) error {
-
-// 	client := twitterConfig.Client(oauth1.NoContext, userToken)
-
-// 	tweetURL := "https://api.twitter.com/1.1/statuses/update.json"
-// 	resp, err := client.PostForm(tweetURL, map[string][]string{"status": {message}})
-// 	if err != nil {
-// 		log.Printf("[ERROR] Failed to post tweet for the blog id : %s and the error is %s", blogId, err)
-// 		return err
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != http.StatusOK {
-// 		return errors.New("Failed to post tweet: " + resp.Status)
-// 	}
-
-// 	log.Printf("[INFO] Blog with ID %s shared on X(twitter) Successfully", blogId)
-// 	return nil
-// }
-
-func ConnectLinkedInHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ConnectLinkedInHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		return
@@ -706,6 +1127,7 @@ func ConnectLinkedInHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[ERROR] User with id: %s not found", userId)
 		return
 	}
+	requestShareHistoryImport(r, userId, "linkedin")
 	state := uuid.New().String()
 	err = repo.SetCache(state, userId, 10*time.Minute)
 	if err != nil {
@@ -714,19 +1136,24 @@ func ConnectLinkedInHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "oauth_state",
-		Value:    state,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   false,
-	})
+	setStateCookie(w, "oauth_state", state, 10*time.Minute)
 
-	authURL := linkedinConfig.AuthCodeURL(state)
+	app, err := h.linkedinOAuth(user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load the LinkedIn app of user %s: %v", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// Posting for Pages takes the organization scopes, which LinkedIn only
+	// grants to apps with Community Management API access
+	if r.URL.Query().Get("pages") == "true" {
+		app.Scopes = append(append([]string(nil), app.Scopes...), services.LinkedInPageScopes...)
+	}
+	authURL := app.AuthCodeURL(state)
 	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
-func LinkedCallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) LinkedCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	queryState := r.URL.Query().Get("state")
 	stateCookie, err := r.Cookie("oauth_state")
 	if err != nil || stateCookie.Value != queryState {
@@ -745,7 +1172,7 @@ func LinkedCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[WARN] Failed to delete state from cache for the user id: %s and error is %s", userId, err)
 	}
 
-	user, err := repo.GetUserById(userId.(string))
+	user, err := repo.GetRequestUser(r.Context(), userId.(string))
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		http.Error(w, "User not found", http.StatusNotFound)
@@ -764,19 +1191,37 @@ func LinkedCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
-	token, err := linkedinConfig.Exchange(ctx, code)
+	app, err := h.linkedinOAuth(user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load the LinkedIn app of user %s: %v", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, services.ProviderClient(services.ProviderLinkedIn))
+	token, err := app.Exchange(ctx, code)
 	if err != nil {
 		http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	user.LinkedInOauthKey = token.AccessToken
+	pages := []models.LinkedInPage{}
+	if scope, _ := token.Extra("scope").(string); services.LinkedInGrantsPages(scope) {
+		if pages, err = services.LookupLinkedInPages(userId.(string), token.AccessToken); err != nil {
+			log.Printf("[WARN] Failed to list the LinkedIn Pages of user %s: %v", userId, err)
+			pages = []models.LinkedInPage{}
+		}
+	}
+	firstConnection := !user.LinkedinVerified
+	if err := services.SealCredential(user, &user.SealedLinkedInOauthKey, &user.PlaintextLinkedInOauthKey, token.AccessToken); err != nil {
+		writeError(w, err)
+		return
+	}
 	user.LinkedinVerified = true
-	if (user.XVerified || user.LinkedinVerified) && user.HashnodeVerified {
-		user.Verified = true
-	} else {
-		user.Verified = false
+	user.LinkedInPages = pages
+	// The chosen Page is kept only while the member may still post for it
+	if services.ValidateLinkedInPage(user, user.LinkedInPageID) != nil {
+		user.LinkedInPageID = ""
 	}
+	services.RefreshVerified(user)
 	err = repo.UpdateUser(userId.(string), user)
 	if err != nil {
 		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
@@ -784,48 +1229,80 @@ func LinkedCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Printf("[INFO] User with ID %s connected to LinkedIn Successfully", user.Id)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "linkedin", "action": "connected"})
+	startShareHistoryImport(userId.(string), "linkedin", firstConnection)
 
-	// Redirect the user back to the frontend
-	http.Redirect(w, r, "http://localhost:5173/verification", http.StatusSeeOther)
+	// Redirect the user back to the frontend, which offers the Pages to
+	// post for when there are any
+	if len(pages) > 0 {
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?linkedin_pages=choose", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
 }
 
 
 func ValidateLogin(req *http.Request) (string, error) {
+	// Routes behind the auth middleware already carry the authenticated user,
+	// which may come from an OAuth access token rather than a session
+	if userID, err := utils.GetUserID(req.Context()); err == nil {
+		return userID, nil
+	}
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok && services.IsAPIKey(token) {
+		key, err := repo.GetAPIKeyByHash(services.HashOAuthSecret(token), utils.Now())
+		if err != nil {
+			return "", err
+		}
+		if key == nil {
+			return "", fmt.Errorf("invalid, revoked or expired API key: %w", apperrors.ErrUnauthorized)
+		}
+		user, err := repo.GetRequestUser(req.Context(), key.UserID)
+		if err != nil {
+			return "", err
+		}
+		if user == nil || user.Disabled {
+			return "", fmt.Errorf("API key of a disabled account: %w", apperrors.ErrUnauthorized)
+		}
+		return key.UserID, nil
+	}
+
 	cookie, err := req.Cookie("session_token")
 	if err != nil {
-		return "", fmt.Errorf("missing session token")
+		return "", fmt.Errorf("missing session token: %w", apperrors.ErrUnauthorized)
+	}
+	if services.IsSessionJWT(cookie.Value) {
+		return services.VerifySessionJWT(cookie.Value)
 	}
 
 	sessionData, exists := repo.GetCache(cookie.Value)
 	if !exists {
-		return "", fmt.Errorf("invalid or expired session")
+		return "", fmt.Errorf("invalid or expired session: %w", apperrors.ErrUnauthorized)
 	}
 
 	session, ok := sessionData.(models.CacheItem)
 	if !ok {
-		return "", fmt.Errorf("invalid session data format")
+		return "", fmt.Errorf("invalid session data format: %w", apperrors.ErrUnauthorized)
 	}
 
-	if session.ExpiresAt.Before(time.Now()) {
-		return "", fmt.Errorf("session expired")
+	if session.ExpiresAt.Before(utils.Now()) {
+		return "", fmt.Errorf("session expired: %w", apperrors.ErrUnauthorized)
 	}
 
 	// session.Value is actually a primitive.ObjectID, convert it to string.
 	oid, ok := session.Value.(primitive.ObjectID)
 	if !ok {
-		return "", fmt.Errorf("invalid session user id format")
+		return "", fmt.Errorf("invalid session user id format: %w", apperrors.ErrUnauthorized)
 	}
 	return oid.Hex(), nil
 }
 
-func VerifyHashnodeHandler(w http.ResponseWriter, r *http.Request) {
-	endpoint := "https://gql.hashnode.com"
+func (h *Handlers) VerifyHashnodeHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		return
@@ -846,89 +1323,66 @@ func VerifyHashnodeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `{"query":"query Me { me { publications(first:1) { edges { node { url id } } } } }"}`
-
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer([]byte(query)))
-	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+	publications, err := services.LookupHashnodePublications(hashnodeKey.Key)
+	if errors.Is(err, apperrors.ErrProviderAuth) {
+		http.Error(w, "Invalid Hashnode API key", http.StatusBadRequest)
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", hashnodeKey.Key)
-
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		http.Error(w, "Failed to make request", http.StatusInternalServerError)
+		log.Printf("[ERROR] Failed to look up the Hashnode publications of user %s: %v", userId, err)
+		writeError(w, err)
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, "Invalid Hashnode API key", http.StatusUnauthorized)
-		return
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+	if len(publications) == 0 {
+		http.Error(w, "No publications found", http.StatusNotFound)
 		return
 	}
-
-	var response struct {
-		Data struct {
-			Me struct {
-				Publications struct {
-					Edges []struct {
-						Node struct {
-							URL string `json:"url"`
-							ID  string `json:"id"`
-						} `json:"node"`
-					} `json:"edges"`
-				} `json:"publications"`
-			} `json:"me"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		http.Error(w, "Failed to parse response JSON", http.StatusInternalServerError)
-		return
+	url := services.DefaultPublication(user, publications)
+	previousHosts := services.PublicationHosts(user)
+	previousPublications := user.HashnodePublications
+	user.HashnodeBlog = url
+	user.HashnodePublications = publications
+
+	// Posts of publications no longer connected must not linger until the
+	// sync
+	hosts := services.PublicationHosts(user)
+	slices.Sort(previousHosts)
+	slices.Sort(hosts)
+	if !slices.Equal(previousHosts, hosts) {
+		if err := repo.DeleteSourcePosts(userId, ""); err != nil {
+			log.Printf("[WARN] Failed to delete posts of the previous publications of user %s: %v", userId, err)
+		}
 	}
-
-	// Check if we have at least one publication
-	if len(response.Data.Me.Publications.Edges) == 0 {
-		http.Error(w, "No publications found", http.StatusNotFound)
+	if err := services.SealCredential(user, &user.SealedHashnodePAT, &user.PlaintextHashnodePAT, hashnodeKey.Key); err != nil {
+		writeError(w, err)
 		return
 	}
-
-	// Extract `url` and `id`
-	node := response.Data.Me.Publications.Edges[0].Node
-	url := strings.ReplaceAll(node.URL, "https://", "")
-	id := node.ID
-
-	user.HashnodePAT = hashnodeKey.Key
 	user.HashnodeVerified = true
-	user.HashnodeBlog = url
-	if (user.XVerified || user.LinkedinVerified) && user.HashnodeVerified {
-		user.Verified = true
-	} else {
-		user.Verified = false
+	user.PostsSyncDueAt = utils.Now()
+	// Hashnode tells the receiver about new posts to share them on publish
+	if config.Get().FeatureEnabled("share_on_publish") {
+		if err := services.RegisterPublicationWebhooks(user, hashnodeWebhookURL(userId), previousPublications); err != nil {
+			log.Printf("[WARN] Failed to register the Hashnode webhooks of user %s: %v", userId, err)
+		}
 	}
+	services.RefreshVerified(user)
 	err = repo.UpdateUser(userId, user)
 	if err != nil {
 		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
 		return
 	}
-	fmt.Printf(`{"success": true, "url": "%s", "id": "%s"}`, url, id)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "hashnode", "action": "connected", "blog": url})
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"success": true}`))
 }
 
-func ShareBlogHandler(w http.ResponseWriter, req *http.Request) {
+func (h *Handlers) ShareBlogHandler(w http.ResponseWriter, req *http.Request) {
 	userId, err := ValidateLogin(req)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(req.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		return
@@ -943,8 +1397,12 @@ func ShareBlogHandler(w http.ResponseWriter, req *http.Request) {
 	}
 
 	var requestBody struct {
-		Id        string   `json:"id"`
-		Platforms []string `json:"platforms"`
+		Id           string   `json:"id"`
+		Platforms    []string `json:"platforms"`
+		AssetIDs     []string `json:"asset_ids"`
+		LinkedInPage string   `json:"linkedin_page"`
+		XThread      bool     `json:"x_thread"`
+		Publication  string   `json:"publication"`
 	}
 	if err := json.NewDecoder(req.Body).Decode(&requestBody); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -958,10 +1416,24 @@ func ShareBlogHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = services.ProcessSharedBlog(user, blogId, requestBody.Platforms)
+	platforms := requestBody.Platforms
+	if len(platforms) == 0 {
+		platforms = user.Preferences.DefaultPlatforms
+	}
+	if len(platforms) == 0 {
+		http.Error(w, "No platforms specified and no default platforms configured", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.CheckPostPublication(user, blogId, requestBody.Publication); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = services.ProcessSharedBlog(user, blogId, platforms, requestBody.AssetIDs, requestBody.LinkedInPage, requestBody.XThread)
 	if err != nil {
 		log.Printf("[ERROR] Failed to share blog: %v", err)
-		http.Error(w, "Failed to share blog", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	log.Printf("[INFO] Blog with ID %s shared successfully by user with ID %s", blogId, userId)
@@ -970,13 +1442,13 @@ func ShareBlogHandler(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-func ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -999,23 +1471,65 @@ func ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	blogData.UserID = userId
+	// Without a timezone the blog is kept in the one of the user's posting
+	// goal, so it follows the clocks there
+	if blogData.ScheduledBlog.Timezone == "" && user.PostingGoal != nil {
+		blogData.ScheduledBlog.Timezone = user.PostingGoal.Timezone
+	}
 	err = blogData.ScheduledBlog.Validate()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
+		return
+	}
+	for _, platform := range blogData.ScheduledBlog.Platforms {
+		if err := services.CheckPlatformEnabled(platform); err != nil {
+			writeError(w, err)
+			return
+		}
+		if !config.Get().PostingWindow.Allows(blogData.ScheduledBlog.PlatformTime(platform)) {
+			http.Error(w, "Scheduled time is outside the allowed posting window", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := services.ResolveShareAssets(user, blogData.ScheduledBlog.AssetIDs); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := services.ValidateLinkedInPage(user, blogData.ScheduledBlog.LinkedInPage); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := services.CheckPostPublication(user, blogData.ScheduledBlog.Id, blogData.ScheduledBlog.Publication); err != nil {
+		writeError(w, err)
 		return
 	}
 	//check if the user has already scheduled the blog
 	for i := range user.ScheduledBlogs {
 		if user.ScheduledBlogs[i].Id == blogData.ScheduledBlog.Id {
-			http.Error(w, "Blog already scheduled", http.StatusBadRequest)
+			http.Error(w, "Blog already scheduled", http.StatusConflict)
 			return
 		}
 	}
 
-	err = taskScheduler.AddTask(blogData)
-	if err != nil {
-		http.Error(w, "Failed to store scheduled task", http.StatusInternalServerError)
-		return
+	blogData.ScheduledBlog.AnchorLocalTime()
+	// With platform offsets the blog fans out into a child task per platform
+	blogData.ScheduledBlog.PlanChildren()
+	for i, task := range blogData.Tasks() {
+		err = h.taskScheduler.AddTask(task)
+		if err != nil {
+			// A concurrent submission queued the blog first; its tasks
+			// aren't ours to remove
+			if i == 0 && errors.Is(err, apperrors.ErrConflict) {
+				log.Printf("[INFO] Blog with ID %s of user %s was scheduled concurrently", blogData.ScheduledBlog.Id, userId)
+				writeError(w, err)
+				return
+			}
+			if removeErr := h.taskScheduler.RemoveTask(userId, blogData.ScheduledBlog.Id); removeErr != nil {
+				log.Printf("[ERROR] Failed to remove the tasks of blog %s after a failed schedule: %s", blogData.ScheduledBlog.Id, removeErr)
+			}
+			writeError(w, err)
+			return
+		}
 	}
 
 	user.ScheduledBlogs = append(user.ScheduledBlogs, blogData.ScheduledBlog)
@@ -1025,6 +1539,11 @@ func ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if schedules, err := metrics.Schedules.GetMetricWithLabelValues(metrics.Cohort(user)); err != nil {
+		log.Printf("[WARN] Failed to count the schedule: %v", err)
+	} else {
+		schedules.Inc()
+	}
 
 	log.Printf("[INFO] Blog with ID %s scheduled successfully by user with ID %s", blogData.ScheduledBlog.Id, userId)
 	w.WriteHeader(http.StatusOK)
@@ -1032,13 +1551,13 @@ func ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func CancelScheduledBlogHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CancelScheduledBlogHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1081,10 +1600,10 @@ func CancelScheduledBlogHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	err = taskScheduler.RemoveTask(blogId)
+	err = h.taskScheduler.RemoveTask(userId, blogId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to remove scheduled task with id: %s and error is %s", blogId, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	log.Printf("[INFO] Scheduled blog with ID %s cancelled successfully by user with ID %s", blogId, userId)
@@ -1092,13 +1611,13 @@ func CancelScheduledBlogHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1129,11 +1648,8 @@ func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.EmailVerified = true
-	if (user.XVerified || user.LinkedinVerified) && user.HashnodeVerified  && user.EmailVerified {
-		user.Verified = true
-	} else {
-		user.Verified = false
-	}
+	services.RefreshVerified(user)
+	user.Verified = user.Verified && user.EmailVerified
 	err = repo.UpdateUser(userId, user)
 	if err != nil {
 		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
@@ -1145,13 +1661,13 @@ func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-func ResetEmailOtpHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ResetEmailOtpHandler(w http.ResponseWriter, r *http.Request) {
 	userId, err := ValidateLogin(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	user, err := repo.GetUserById(userId)
+	user, err := repo.GetRequestUser(r.Context(), userId)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1180,163 +1696,3 @@ func ResetEmailOtpHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"success": true}`))
 }
-
-// func VerifyHashnodeHandler(w http.ResponseWriter, r *http.Request) {
-// 	endpoint := "https://gql.hashnode.com"
-// 	userId, err := ValidateLogin(r)
-// 	if err != nil {
-// 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-// 		return
-// 	}
-// 	user, err := repo.GetUserById(userId)
-// 	if err != nil {
-// 		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
-// 		return
-// 	}
-// 	if user == nil {
-// 		log.Printf("[ERROR] User with id: %s not found", userId)
-// 		return
-// 	}
-
-// 	var hashnodeKey models.HashnodeKey
-// 	err = json.NewDecoder(r.Body).Decode(&hashnodeKey)
-// 	if err != nil {
-// 		http.Error(w, "Failed to parse JSON", http.StatusBadRequest)
-// 		return
-// 	}
-// 	if hashnodeKey.Key == "" {
-// 		http.Error(w, "Missing Hashnode API key", http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	query := `{"query":"query Me { me { publications(first:1) { edges { node { url id } } } } }"}`
-
-// 	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer([]byte(query)))
-// 	if err != nil {
-// 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	req.Header.Set("Content-Type", "application/json")
-// 	req.Header.Set("Authorization", hashnodeKey.Key)
-
-// 	resp, err := http.DefaultClient.Do(req)
-// 	if err != nil {
-// 		http.Error(w, "Failed to make request", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != http.StatusOK {
-// 		http.Error(w, "Invalid Hashnode API key", http.StatusUnauthorized)
-// 		return
-// 	}
-
-// 	body, err := io.ReadAll(resp.Body)
-// 	if err != nil {
-// 		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	var response struct {
-// 		Data struct {
-// 			Me struct {
-// 				Publications struct {
-// 					Edges []struct {
-// 						Node struct {
-// 							URL string `json:"url"`
-// 							ID  string `json:"id"`
-// 						} `json:"node"`
-// 					} `json:"edges"`
-// 				} `json:"publications"`
-// 			} `json:"me"`
-// 		} `json:"data"`
-// 	}
-// 	if err := json.Unmarshal(body, &response); err != nil {
-// 		http.Error(w, "Failed to parse response JSON", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	// Check if we have at least one publication
-// 	if len(response.Data.Me.Publications.Edges) == 0 {
-// 		http.Error(w, "No publications found", http.StatusNotFound)
-// 		return
-// 	}
-
-// 	// Extract `url` and `id`
-// 	node := response.Data.Me.Publications.Edges[0].Node
-// 	url :=  strings.ReplaceAll(node.URL, "https://", "")
-// 	id := node.ID
-
-// 	// create a webhook for the user
-//     webhookUrl := fmt.Sprintf("https://localhost:9696/api/v1/user/webhook/%s", userId)
-
-// 	webhookInput := fmt.Sprintf(`{
-// 		"publicationId": %s,
-// 		"url": %s,
-// 		"events": ["POST_PUBLISHED"],
-// 		"secret": %s,
-// 	  }`, id, webhookUrl, userId)
-
-// 	queryStruct := models.GraphQLQuery{
-// 		Query: fmt.Sprintf(`mutation CreateWebhook(input: /"%s/") {
-// 		createWebhook(input: /"%s/") {
-// 		  webhook {
-// 			id
-// 			url
-// 			events
-// 			secret
-// 			createdAt
-// 		  }
-// 		}
-// 	  }`, webhookInput, webhookInput),
-// 	  }
-// 	queryBytes, err := json.Marshal(queryStruct)
-// 	if err != nil {
-// 		http.Error(w, "Failed to marshal query", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	req, err = http.NewRequest("POST", endpoint, queryBytes)
-// 	if err != nil {
-// 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	req.Header.Set("Content-Type", "application/json")
-// 	req.Header.Set("Authorization", hashnodeKey.Key)
-
-// 	resp, err = http.DefaultClient.Do(req)
-// 	if err != nil {
-// 		http.Error(w, "Failed to make request", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != http.StatusOK {
-// 		http.Error(w, "Invalid Hashnode API key", http.StatusUnauthorized)
-// 		return
-// 	}
-
-// 	body, err = io.ReadAll(resp.Body)
-// 	if err != nil {
-// 		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	// Update the user with the Hashnode API key
-// 	user.HashnodePAT = hashnodeKey.Key
-// 	user.HashnodeVerified = true
-// 	user.HashnodeBlog = url
-// 	user.WebHookUrl = webhookUrl
-// 	if (user.XVerified || user.LinkedinVerified) && user.EmailVerified && user.HashnodeVerified {
-// 		user.Verified = true
-// 	} else {
-// 		user.Verified = false
-// 	}
-// 	err = repo.UpdateUser(userId, user)
-// 	if err != nil {
-// 		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
-// 		return
-// 	}
-// 	w.WriteHeader(http.StatusOK)
-// 	fmt.Fprintf(w, `{"success": true, "url": "%s", "id": "%s"}`, url, id)
-// }
@@ -0,0 +1,866 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/providertest"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+// TestUserHandlersRequireSession checks the handlers that authenticate the
+// caller themselves. GetUserNotificationsHandler leaves that to the auth
+// middleware and is not listed.
+func TestUserHandlersRequireSession(t *testing.T) {
+	userHandlers := map[string]func() http.HandlerFunc{
+		"ChangePassword":             func() http.HandlerFunc { return h.ChangePasswordHandler },
+		"ChangeEmail":                func() http.HandlerFunc { return h.ChangeEmailHandler },
+		"ConfirmEmailChange":         func() http.HandlerFunc { return h.ConfirmEmailChangeHandler },
+		"RefreshSession":             func() http.HandlerFunc { return h.RefreshSessionHandler },
+		"LoginHistory":               func() http.HandlerFunc { return h.GetLoginHistoryHandler },
+		"Consent":                    func() http.HandlerFunc { return h.GetConsentHandler },
+		"AcceptConsent":              func() http.HandlerFunc { return h.AcceptConsentHandler },
+		"PublicProfileSettings":      func() http.HandlerFunc { return h.GetPublicProfileSettingsHandler },
+		"UpdatePublicProfile":        func() http.HandlerFunc { return h.UpdatePublicProfileSettingsHandler },
+		"ConnectMastodon":            func() http.HandlerFunc { return h.ConnectMastodonHandler },
+		"MastodonCallback":           func() http.HandlerFunc { return h.MastodonCallbackHandler },
+		"GetOAuthApps":               func() http.HandlerFunc { return h.GetOAuthAppsHandler },
+		"SetOAuthApp":                func() http.HandlerFunc { return h.SetOAuthAppHandler },
+		"DeleteOAuthApp":             func() http.HandlerFunc { return h.DeleteOAuthAppHandler },
+		"ConnectReddit":              func() http.HandlerFunc { return h.ConnectRedditHandler },
+		"RedditCallback":             func() http.HandlerFunc { return h.RedditCallbackHandler },
+		"RedditSettings":             func() http.HandlerFunc { return h.UpdateRedditSettingsHandler },
+		"RedditFlairs":               func() http.HandlerFunc { return h.GetRedditFlairsHandler },
+		"DiscordWebhook":             func() http.HandlerFunc { return h.GetDiscordWebhookHandler },
+		"SetDiscordWebhook":          func() http.HandlerFunc { return h.SetDiscordWebhookHandler },
+		"DeleteDiscordWebhook":       func() http.HandlerFunc { return h.DeleteDiscordWebhookHandler },
+		"TestDiscordWebhook":         func() http.HandlerFunc { return h.TestDiscordWebhookHandler },
+		"TeamsWebhook":               func() http.HandlerFunc { return h.GetTeamsWebhookHandler },
+		"SetTeamsWebhook":            func() http.HandlerFunc { return h.SetTeamsWebhookHandler },
+		"DeleteTeamsWebhook":         func() http.HandlerFunc { return h.DeleteTeamsWebhookHandler },
+		"TestTeamsWebhook":           func() http.HandlerFunc { return h.TestTeamsWebhookHandler },
+		"LinkedInPages":              func() http.HandlerFunc { return h.GetLinkedInPagesHandler },
+		"SetLinkedInPage":            func() http.HandlerFunc { return h.SetLinkedInPageHandler },
+		"HashnodePublications":       func() http.HandlerFunc { return h.GetHashnodePublicationsHandler },
+		"SetHashnodePublication":     func() http.HandlerFunc { return h.SetHashnodePublicationHandler },
+		"Newsletter":                 func() http.HandlerFunc { return h.GetNewsletterHandler },
+		"SetNewsletter":              func() http.HandlerFunc { return h.SetNewsletterHandler },
+		"DeleteNewsletter":           func() http.HandlerFunc { return h.DeleteNewsletterHandler },
+		"NewsletterSubscribers":      func() http.HandlerFunc { return h.ListNewsletterSubscribersHandler },
+		"AddNewsletterSubscribers":   func() http.HandlerFunc { return h.AddNewsletterSubscribersHandler },
+		"DeleteNewsletterSubscriber": func() http.HandlerFunc { return h.DeleteNewsletterSubscriberHandler },
+		"SubstackAccount":            func() http.HandlerFunc { return h.GetSubstackAccountHandler },
+		"SetSubstackAccount":         func() http.HandlerFunc { return h.SetSubstackAccountHandler },
+		"DeleteSubstackAccount":      func() http.HandlerFunc { return h.DeleteSubstackAccountHandler },
+		"ConnectGoogleBusiness":      func() http.HandlerFunc { return h.ConnectGoogleBusinessHandler },
+		"GoogleBusinessCallback":     func() http.HandlerFunc { return h.GoogleBusinessCallbackHandler },
+		"GoogleBusinessAccount":      func() http.HandlerFunc { return h.GetGoogleBusinessAccountHandler },
+		"SetGoogleBusinessLocation":  func() http.HandlerFunc { return h.SetGoogleBusinessLocationHandler },
+		"DeleteGoogleBusiness":       func() http.HandlerFunc { return h.DeleteGoogleBusinessAccountHandler },
+		"DisconnectPlatform":         func() http.HandlerFunc { return h.DisconnectPlatformHandler },
+		"InstagramAccount":           func() http.HandlerFunc { return h.GetInstagramAccountHandler },
+		"SetInstagramAccount":        func() http.HandlerFunc { return h.SetInstagramAccountHandler },
+		"DeleteInstagramAccount":     func() http.HandlerFunc { return h.DeleteInstagramAccountHandler },
+		"ShareWebhook":               func() http.HandlerFunc { return h.GetShareWebhookHandler },
+		"SetShareWebhook":            func() http.HandlerFunc { return h.SetShareWebhookHandler },
+		"DeleteShareWebhook":         func() http.HandlerFunc { return h.DeleteShareWebhookHandler },
+		"TestShareWebhook":           func() http.HandlerFunc { return h.TestShareWebhookHandler },
+		"ConnectYouTube":             func() http.HandlerFunc { return h.ConnectYouTubeHandler },
+		"YouTubeCallback":            func() http.HandlerFunc { return h.YouTubeCallbackHandler },
+		"YouTubeChannel":             func() http.HandlerFunc { return h.GetYouTubeChannelHandler },
+		"DeleteYouTubeChannel":       func() http.HandlerFunc { return h.DeleteYouTubeChannelHandler },
+		"BlogFeed":                   func() http.HandlerFunc { return h.GetBlogFeedHandler },
+		"SetBlogFeed":                func() http.HandlerFunc { return h.SetBlogFeedHandler },
+		"DeleteBlogFeed":             func() http.HandlerFunc { return h.DeleteBlogFeedHandler },
+		"GhostSource":                func() http.HandlerFunc { return h.GetGhostSourceHandler },
+		"SetGhostSource":             func() http.HandlerFunc { return h.SetGhostSourceHandler },
+		"DeleteGhostSource":          func() http.HandlerFunc { return h.DeleteGhostSourceHandler },
+		"MediumSource":               func() http.HandlerFunc { return h.GetMediumSourceHandler },
+		"SetMediumSource":            func() http.HandlerFunc { return h.SetMediumSourceHandler },
+		"DeleteMediumSource":         func() http.HandlerFunc { return h.DeleteMediumSourceHandler },
+		"ManualTasks":                func() http.HandlerFunc { return h.GetManualTasksHandler },
+		"ManualTaskImage":            func() http.HandlerFunc { return h.GetManualTaskImageHandler },
+		"CompleteManualTask":         func() http.HandlerFunc { return h.CompleteManualTaskHandler },
+		"DeleteManualTask":           func() http.HandlerFunc { return h.DeleteManualTaskHandler },
+		"DevtoAccount":               func() http.HandlerFunc { return h.GetDevtoAccountHandler },
+		"SetDevtoAccount":            func() http.HandlerFunc { return h.SetDevtoAccountHandler },
+		"DeleteDevtoAccount":         func() http.HandlerFunc { return h.DeleteDevtoAccountHandler },
+		"MediumAccount":              func() http.HandlerFunc { return h.GetMediumAccountHandler },
+		"SetMediumAccount":           func() http.HandlerFunc { return h.SetMediumAccountHandler },
+		"DeleteMediumAccount":        func() http.HandlerFunc { return h.DeleteMediumAccountHandler },
+		"NostrAccount":               func() http.HandlerFunc { return h.GetNostrAccountHandler },
+		"SetNostrAccount":            func() http.HandlerFunc { return h.SetNostrAccountHandler },
+		"DeleteNostrAccount":         func() http.HandlerFunc { return h.DeleteNostrAccountHandler },
+		"LemmyAccount":               func() http.HandlerFunc { return h.GetLemmyAccountHandler },
+		"SetLemmyAccount":            func() http.HandlerFunc { return h.SetLemmyAccountHandler },
+		"UpdateLemmyCommunities":     func() http.HandlerFunc { return h.UpdateLemmyCommunitiesHandler },
+		"DeleteLemmyAccount":         func() http.HandlerFunc { return h.DeleteLemmyAccountHandler },
+		"WordPressAccount":           func() http.HandlerFunc { return h.GetWordPressAccountHandler },
+		"SetWordPressAccount":        func() http.HandlerFunc { return h.SetWordPressAccountHandler },
+		"DeleteWordPressAccount":     func() http.HandlerFunc { return h.DeleteWordPressAccountHandler },
+		"MatrixRoom":                 func() http.HandlerFunc { return h.GetMatrixRoomHandler },
+		"SetMatrixRoom":              func() http.HandlerFunc { return h.SetMatrixRoomHandler },
+		"DeleteMatrixRoom":           func() http.HandlerFunc { return h.DeleteMatrixRoomHandler },
+		"ConnectTumblr":              func() http.HandlerFunc { return h.ConnectTumblrHandler },
+		"TumblrCallback":             func() http.HandlerFunc { return h.TumblrCallbackHandler },
+		"TumblrAccount":              func() http.HandlerFunc { return h.GetTumblrAccountHandler },
+		"DeleteTumblrAccount":        func() http.HandlerFunc { return h.DeleteTumblrAccountHandler },
+		"ListSessions":               func() http.HandlerFunc { return h.ListSessionsHandler },
+		"RevokeSession":              func() http.HandlerFunc { return h.RevokeSessionHandler },
+		"RevokeOtherSessions":        func() http.HandlerFunc { return h.RevokeOtherSessionsHandler },
+		"CSRFToken":                  func() http.HandlerFunc { return h.CSRFTokenHandler },
+		"ImportQueue":                func() http.HandlerFunc { return h.ImportQueueHandler },
+		"Search":                     func() http.HandlerFunc { return h.SearchHandler },
+		"GetUserInfo":                func() http.HandlerFunc { return h.GetUserInfoHandler },
+		"GetUserProfile":             func() http.HandlerFunc { return h.GetUserProfileHandler },
+		"ClearUserNotifications":     func() http.HandlerFunc { return h.ClearUserNotificationsHandler },
+		"GetUserSharedBlogs":         func() http.HandlerFunc { return h.GetUserSharedBlogsHandler },
+		"GetUserScheduledBlogs":      func() http.HandlerFunc { return h.GetUserScheduledBlogsHandler },
+		"GetUserBlogs":               func() http.HandlerFunc { return h.GetUserBlogsHandler },
+		"ConnectX":                   func() http.HandlerFunc { return h.ConnectXhandler },
+		"XCallback":                  func() http.HandlerFunc { return h.XcallbackHandler },
+		"ConnectLinkedIn":            func() http.HandlerFunc { return h.ConnectLinkedInHandler },
+		"VerifyHashnode":             func() http.HandlerFunc { return h.VerifyHashnodeHandler },
+		"ShareBlog":                  func() http.HandlerFunc { return h.ShareBlogHandler },
+		"ScheduleBlog":               func() http.HandlerFunc { return h.ScheduleBlogHandler },
+		"CancelScheduledBlog":        func() http.HandlerFunc { return h.CancelScheduledBlogHandler },
+		"VerifyEmail":                func() http.HandlerFunc { return h.VerifyEmailHandler },
+		"ResetEmailOtp":              func() http.HandlerFunc { return h.ResetEmailOtpHandler },
+		"SetHashnodeWebhookSecret":   func() http.HandlerFunc { return h.SetHashnodeWebhookSecretHandler },
+		"DeferShare":                 func() http.HandlerFunc { return h.DeferShareHandler },
+		"GetDeferredShares":          func() http.HandlerFunc { return h.GetDeferredSharesHandler },
+		"CancelDeferredShare":        func() http.HandlerFunc { return h.CancelDeferredShareHandler },
+		"ApproveDeferredShare":       func() http.HandlerFunc { return h.ApproveDeferredShareHandler },
+		"RegisterOAuthClient":        func() http.HandlerFunc { return h.RegisterOAuthClientHandler },
+		"GetOAuthClients":            func() http.HandlerFunc { return h.GetOAuthClientsHandler },
+		"DeleteOAuthClient":          func() http.HandlerFunc { return h.DeleteOAuthClientHandler },
+		"OAuthConsent":               func() http.HandlerFunc { return h.OAuthConsentHandler },
+		"OAuthAuthorize":             func() http.HandlerFunc { return h.OAuthAuthorizeHandler },
+		"GetAuthorizedApps":          func() http.HandlerFunc { return h.GetAuthorizedAppsHandler },
+		"CreateAPIKey":               func() http.HandlerFunc { return h.CreateAPIKeyHandler },
+		"CreateCampaign":             func() http.HandlerFunc { return h.CreateCampaignHandler },
+		"UpdateUserRole":             func() http.HandlerFunc { return h.UpdateUserRoleHandler },
+		"UpdateUserStatus":           func() http.HandlerFunc { return h.UpdateUserStatusHandler },
+		"GetCampaigns":               func() http.HandlerFunc { return h.GetCampaignsHandler },
+		"AttachCampaignBlog":         func() http.HandlerFunc { return h.AttachCampaignBlogHandler },
+		"DetachCampaignBlog":         func() http.HandlerFunc { return h.DetachCampaignBlogHandler },
+		"GetCampaignAnalytics":       func() http.HandlerFunc { return h.GetCampaignAnalyticsHandler },
+		"GetPlatformAnalytics":       func() http.HandlerFunc { return h.GetPlatformAnalyticsHandler },
+		"GetTopicAnalytics":          func() http.HandlerFunc { return h.GetTopicAnalyticsHandler },
+		"SetSharedBlogTopics":        func() http.HandlerFunc { return h.SetSharedBlogTopicsHandler },
+		"GetPostingGoal":             func() http.HandlerFunc { return h.GetPostingGoalHandler },
+		"SetPostingGoal":             func() http.HandlerFunc { return h.SetPostingGoalHandler },
+		"DeletePostingGoal":          func() http.HandlerFunc { return h.DeletePostingGoalHandler },
+		"GetSecurityWebhook":         func() http.HandlerFunc { return h.GetSecurityWebhookHandler },
+		"SetSecurityWebhook":         func() http.HandlerFunc { return h.SetSecurityWebhookHandler },
+		"DeleteSecurityWebhook":      func() http.HandlerFunc { return h.DeleteSecurityWebhookHandler },
+		"TestSecurityWebhook":        func() http.HandlerFunc { return h.TestSecurityWebhookHandler },
+		"GetTeamCalendar":            func() http.HandlerFunc { return h.GetTeamCalendarHandler },
+		"GetLibraryAssets":           func() http.HandlerFunc { return h.GetLibraryAssetsHandler },
+		"GetLibraryAsset":            func() http.HandlerFunc { return h.GetLibraryAssetHandler },
+		"CreateLibraryAsset":         func() http.HandlerFunc { return h.CreateLibraryAssetHandler },
+		"UpdateLibraryAsset":         func() http.HandlerFunc { return h.UpdateLibraryAssetHandler },
+		"DeleteLibraryAsset":         func() http.HandlerFunc { return h.DeleteLibraryAssetHandler },
+		"GetAPIKeys":                 func() http.HandlerFunc { return h.GetAPIKeysHandler },
+		"RevokeAPIKey":               func() http.HandlerFunc { return h.RevokeAPIKeyHandler },
+		"RevokeAuthorizedApp":        func() http.HandlerFunc { return h.RevokeAuthorizedAppHandler },
+		"GetUserPreferences":         func() http.HandlerFunc { return h.GetUserPreferencesHandler },
+		"UpdateUserPreferences":      func() http.HandlerFunc { return h.UpdateUserPreferencesHandler },
+		"CreateTeam":                 func() http.HandlerFunc { return h.CreateTeamHandler },
+		"GetTeam":                    func() http.HandlerFunc { return h.GetTeamHandler },
+		"AddTeamDomain":              func() http.HandlerFunc { return h.AddTeamDomainHandler },
+		"VerifyTeamDomain":           func() http.HandlerFunc { return h.VerifyTeamDomainHandler },
+		"UpdateTeamSSO":              func() http.HandlerFunc { return h.UpdateTeamSSOHandler },
+		"CreateSCIMToken":            func() http.HandlerFunc { return h.CreateSCIMTokenHandler },
+		"ListSCIMUsers":              func() http.HandlerFunc { return h.ListSCIMUsersHandler },
+	}
+
+	var cases []handlerCase
+	for name, handler := range userHandlers {
+		cases = append(cases, handlerCase{name: name, handler: handler, body: `{}`, status: http.StatusUnauthorized})
+	}
+	runCases(t, cases)
+}
+
+func TestSignupUserHandler(t *testing.T) {
+	signup := func() http.HandlerFunc { return h.SignupUserHandler }
+	runCases(t, []handlerCase{
+		{name: "invalid JSON", handler: signup, body: `{`, status: http.StatusBadRequest, text: "unable to decode JSON"},
+		{name: "short username", handler: signup, body: `{"username":"abc","password":"long enough"}`, status: http.StatusBadRequest, text: "minimum of 4"},
+		{name: "short password", handler: signup, body: `{"username":"someone","password":"short"}`, status: http.StatusBadRequest, text: "minimum of 8"},
+		{
+			name:    "username taken",
+			handler: signup,
+			setup: func(t *testing.T) string {
+				newUser(t, func(u *models.User) { u.UserName = "takenname" })
+				return ""
+			},
+			body:   `{"username":" Taken Name ","password":"long enough"}`,
+			status: http.StatusConflict,
+			json:   map[string]interface{}{"message": "Username already taken"},
+		},
+		{
+			name:       "created",
+			handler:    signup,
+			body:       `{"username":"freshname","password":"long enough"}`,
+			status:     http.StatusCreated,
+			json:       map[string]interface{}{"username": "freshname", "verified": false},
+			wantCookie: true,
+		},
+	})
+}
+
+func TestLoginUserHandler(t *testing.T) {
+	login := func() http.HandlerFunc { return h.LoginUserHandler }
+	incorrect := map[string]interface{}{"success": false, "reason": "Username and/or password is incorrect"}
+	runCases(t, []handlerCase{
+		{name: "invalid JSON", handler: login, body: `{`, status: http.StatusBadRequest, text: "unable to decode JSON"},
+		{name: "unknown user", handler: login, body: `{"username":"nobodyhere","password":"whatever1"}`, status: http.StatusBadRequest, json: incorrect},
+		{name: "username too short", handler: login, body: `{"username":"abc","password":"whatever1"}`, status: http.StatusBadRequest, json: map[string]interface{}{"error": "The username should contain a minimum of 4 and maximum of 64 characters"}},
+		{name: "password too long", handler: login, body: `{"username":"nobodyhere","password":"` + strings.Repeat("x", 129) + `"}`, status: http.StatusBadRequest, text: "password is too long"},
+		{
+			name:    "wrong password",
+			handler: login,
+			setup: func(t *testing.T) string {
+				newUser(t, func(u *models.User) { u.UserName = "loginwrong" })
+				return ""
+			},
+			body:   `{"username":"loginwrong","password":"not the password"}`,
+			status: http.StatusBadRequest,
+			json:   incorrect,
+		},
+		{
+			name:    "disabled account",
+			handler: login,
+			setup: func(t *testing.T) string {
+				newUser(t, func(u *models.User) { u.UserName = "logindisabled"; u.Disabled = true })
+				return ""
+			},
+			body:   `{"username":"logindisabled","password":"` + testPassword + `"}`,
+			status: http.StatusForbidden,
+			json:   map[string]interface{}{"success": false},
+		},
+		{
+			name:    "logged in",
+			handler: login,
+			setup: func(t *testing.T) string {
+				newUser(t, func(u *models.User) { u.UserName = "loginok" })
+				return ""
+			},
+			body:       `{"username":"LoginOK","password":"` + testPassword + `"}`,
+			status:     http.StatusAccepted,
+			json:       map[string]interface{}{"username": "loginok"},
+			wantCookie: true,
+		},
+	})
+}
+
+func TestLoginRememberMe(t *testing.T) {
+	newUser(t, func(u *models.User) { u.UserName = "loginremember" })
+	sessionLifetime := func(rememberMe bool) time.Duration {
+		t.Helper()
+		body := fmt.Sprintf(`{"username":"loginremember","password":%q,"remember_me":%t}`, testPassword, rememberMe)
+		rec := serve(h.LoginUserHandler, "", body, nil)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "session_token" {
+				return time.Until(c.Expires)
+			}
+		}
+		t.Fatal("no session cookie was set")
+		return 0
+	}
+
+	if got := sessionLifetime(false); got > 25*time.Hour {
+		t.Errorf("session lasts %s without remember me, want the 24h default", got)
+	}
+	if got := sessionLifetime(true); got < 29*24*time.Hour {
+		t.Errorf("session lasts %s with remember me, want 30 days", got)
+	}
+}
+
+func TestLogoutUserHandler(t *testing.T) {
+	logout := func() http.HandlerFunc { return h.LogoutUserHandler }
+	success := map[string]interface{}{"success": true}
+	runCases(t, []handlerCase{
+		{name: "without a session", handler: logout, status: http.StatusOK, json: success},
+		{name: "with a foreign cookie", handler: logout, cookie: &http.Cookie{Name: "session_token", Value: "not-a-token"}, status: http.StatusOK, json: success},
+	})
+}
+
+func TestGoogleLoginUnconfigured(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "")
+	runCases(t, []handlerCase{
+		{name: "login", handler: func() http.HandlerFunc { return h.GoogleLoginHandler }, status: http.StatusNotFound},
+		{name: "callback", handler: func() http.HandlerFunc { return h.GoogleCallbackHandler }, status: http.StatusSeeOther},
+	})
+}
+
+// TestGoogleCallbackState checks that a Google callback only completes in the
+// browser that started the sign-in, so a victim can't be signed in to an
+// account whose callback URL an attacker hands them.
+func TestGoogleCallbackState(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "client")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "secret")
+	loginStateCallback(t, h.GoogleCallbackHandler, "google_state", "google_state_", "google_error")
+}
+
+func TestSSOCallbackState(t *testing.T) {
+	loginStateCallback(t, h.SSOCallbackHandler, "sso_state", "sso_state_", "sso_error")
+
+	// The browser that started the sign-in gets past the check, to the team
+	// that no longer exists
+	if err := repo.SetCache("sso_state_own", `{"team_id":"64b7f0c2a1b2c3d4e5f60718","nonce":"nonce"}`, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/?code=code&state=own", nil)
+	req.AddCookie(&http.Cookie{Name: "sso_state", Value: "own.nonce"})
+	rec := httptest.NewRecorder()
+	h.SSOCallbackHandler(rec, req)
+	if location := rec.Header().Get("Location"); !strings.HasSuffix(location, "sso_error=unavailable") {
+		t.Errorf("status %d, location %q; want a redirect with sso_error=unavailable", rec.Code, location)
+	}
+}
+
+// loginStateCallback calls a sign-in callback with a pending state and checks
+// that it is rejected unless the browser's state cookie matches.
+func loginStateCallback(t *testing.T, callback http.HandlerFunc, cookieName, cachePrefix, errorParam string) {
+	t.Helper()
+	requireMongo(t)
+	state := fmt.Sprintf("state-%d", userSeq.Add(1))
+	if err := repo.SetCache(cachePrefix+state, `{"team_id":"64b7f0c2a1b2c3d4e5f60718","nonce":"nonce","verifier":"verifier"}`, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		cookie string
+		reason string
+	}{
+		{name: "without the cookie", reason: "state"},
+		{name: "with another flow's cookie", cookie: "attacker-state.nonce", reason: "state"},
+		{name: "with a malformed cookie", cookie: state, reason: "state"},
+		{name: "with another nonce", cookie: state + ".other", reason: "expired"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?code=code&state="+state, nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: cookieName, Value: tc.cookie})
+			}
+			rec := httptest.NewRecorder()
+			callback(rec, req)
+			if location := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || !strings.HasSuffix(location, errorParam+"="+tc.reason) {
+				t.Fatalf("status %d, location %q; want a redirect with %s=%s", rec.Code, location, errorParam, tc.reason)
+			}
+			if tc.cookie == "" {
+				return
+			}
+			var cleared bool
+			for _, c := range rec.Result().Cookies() {
+				cleared = cleared || (c.Name == cookieName && c.MaxAge < 0)
+			}
+			if !cleared {
+				t.Error("the state cookie wasn't cleared")
+			}
+		})
+	}
+}
+
+func TestGetPlatformsHandler(t *testing.T) {
+	rec := serve(h.GetPlatformsHandler, "", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var body struct {
+		Platforms []struct {
+			Name             string `json:"name"`
+			Title            string `json:"title"`
+			MaxLength        int    `json:"max_length"`
+			Threads          bool   `json:"threads"`
+			RequiresApproval bool   `json:"requires_approval"`
+		} `json:"platforms"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]int{}
+	for i, platform := range body.Platforms {
+		byName[platform.Name] = i
+	}
+	if len(body.Platforms) < 2 || body.Platforms[0].Name != "twitter" {
+		t.Fatalf("platforms = %+v", body.Platforms)
+	}
+	if twitter := body.Platforms[0]; twitter.Title != "X (Twitter)" || twitter.MaxLength != 280 || !twitter.Threads {
+		t.Errorf("twitter = %+v", twitter)
+	}
+	if i, ok := byName["instagram"]; !ok || !body.Platforms[i].RequiresApproval || body.Platforms[i].Threads {
+		t.Errorf("instagram is listed as %+v", body.Platforms[i])
+	}
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	change := func() http.HandlerFunc { return h.ChangePasswordHandler }
+	runCases(t, []handlerCase{
+		{name: "invalid JSON", handler: change, setup: anyUser, body: `{`, status: http.StatusBadRequest},
+		{name: "new password too short", handler: change, setup: anyUser, body: `{"current_password":"x","new_password":"short"}`, status: http.StatusBadRequest, text: "minimum of 8"},
+		{
+			name:    "wrong current password",
+			handler: change,
+			setup:   userWith(nil),
+			body:    `{"current_password":"not it","new_password":"another secret"}`,
+			status:  http.StatusForbidden,
+			json:    map[string]interface{}{"success": false, "reason": "Current password is incorrect"},
+		},
+		{
+			name:    "changed",
+			handler: change,
+			setup:   userWith(nil),
+			body:    `{"current_password":"` + testPassword + `","new_password":"another secret"}`,
+			status:  http.StatusOK,
+			json:    map[string]interface{}{"success": true},
+		},
+		{
+			name:    "spaces are part of the password",
+			handler: change,
+			setup:   userWith(nil),
+			body:    `{"current_password":"` + testPassword + `","new_password":"       x"}`,
+			status:  http.StatusOK,
+			json:    map[string]interface{}{"success": true},
+		},
+	})
+}
+
+func TestEmailChangeHandlers(t *testing.T) {
+	change := func() http.HandlerFunc { return h.ChangeEmailHandler }
+	confirm := func() http.HandlerFunc { return h.ConfirmEmailChangeHandler }
+	runCases(t, []handlerCase{
+		{name: "invalid JSON", handler: change, setup: anyUser, body: `{`, status: http.StatusBadRequest},
+		{name: "invalid email", handler: change, setup: anyUser, body: `{"email":"not an email"}`, status: http.StatusBadRequest, json: map[string]interface{}{"error": "Invalid email address"}},
+		{name: "display name is not an address", handler: change, setup: anyUser, body: `{"email":"Ada <ada@example.com>"}`, status: http.StatusBadRequest},
+		{
+			name:    "email not configured",
+			handler: change,
+			setup:   userWith(nil),
+			body:    `{"email":"New.Address@example.com"}`,
+			status:  http.StatusServiceUnavailable,
+		},
+		{
+			name:    "confirm without a pending change",
+			handler: confirm,
+			setup:   userWith(nil),
+			body:    `{"otp":"123456"}`,
+			status:  http.StatusBadRequest,
+			text:    "No email change is pending",
+		},
+	})
+}
+
+func TestEmailChangeUnsentKeepsEmail(t *testing.T) {
+	// Nothing listens on the SMTP port, so the OTP can't be sent
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	t.Setenv("SMTP_HOST", host)
+	t.Setenv("SMTP_PORT", port)
+	t.Setenv("SMTP_FROM", "noreply@example.com")
+
+	userID := newUser(t, func(user *models.User) {
+		verified(user)
+		user.Email = "old@example.com"
+	})
+	rec := serve(h.ChangeEmailHandler, userID, `{"email":"new@example.com"}`, nil)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status %d, want %d; body %q", rec.Code, http.StatusBadGateway, rec.Body.String())
+	}
+	user, err := repo.GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if !user.EmailVerified || !user.Verified || user.Email != "old@example.com" {
+		t.Errorf("an unsent change left email %q, verified %t/%t", user.Email, user.EmailVerified, user.Verified)
+	}
+	if change, err := repo.GetEmailChange(userID); err != nil || change != nil {
+		t.Errorf("an unsent change is pending: %+v, %v", change, err)
+	}
+}
+
+func TestConfirmEmailChangeClaimed(t *testing.T) {
+	newUser(t, func(user *models.User) {
+		user.Email = "claimed@example.com"
+		user.EmailVerified = true
+	})
+	userID := newUser(t, nil)
+	sum := sha256.Sum256([]byte(userID + ":123456"))
+	change := models.PendingEmailChange{Email: "claimed@example.com", OTPHash: hex.EncodeToString(sum[:])}
+	if err := repo.StoreEmailChange(userID, change, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(h.ConfirmEmailChangeHandler, userID, `{"otp":"123456"}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status %d, want %d; body %q", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	user, err := repo.GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if user.Email == "claimed@example.com" {
+		t.Error("the address of another account was taken")
+	}
+}
+
+func TestShareBlogHandler(t *testing.T) {
+	share := func() http.HandlerFunc { return h.ShareBlogHandler }
+	runCases(t, []handlerCase{
+		{name: "unverified user", handler: share, setup: userWith(nil), body: `{"id":"post","platforms":["linkedin"]}`, status: http.StatusForbidden, text: "not verified"},
+		{name: "invalid JSON", handler: share, setup: userWith(verified), body: `{`, status: http.StatusBadRequest},
+		{
+			name:    "missing blog id",
+			handler: share,
+			setup:   userWith(verified),
+			body:    `{"platforms":["linkedin"]}`,
+			status:  http.StatusUnauthorized,
+			json:    map[string]interface{}{"success": false, "reason": "missing blog id in the request"},
+		},
+		{name: "no platforms or defaults", handler: share, setup: userWith(verified), body: `{"id":"post"}`, status: http.StatusBadRequest, text: "No platforms specified"},
+		{
+			name:    "unknown platform",
+			handler: share,
+			setup:   userWith(verified),
+			body:    `{"id":"post","platforms":["myspace"]}`,
+			status:  http.StatusBadRequest,
+			json:    map[string]interface{}{"success": false},
+		},
+		{
+			name:    "shared",
+			handler: share,
+			setup:   userWith(verified),
+			body:    `{"id":"` + providertest.PostID + `","platforms":["linkedin","twitter"]}`,
+			status:  http.StatusOK,
+			json:    map[string]interface{}{"success": true},
+		},
+	})
+}
+
+func scheduleBody(t *testing.T, blogID string, at time.Time) string {
+	t.Helper()
+	body, err := json.Marshal(models.ScheduledBlogData{ScheduledBlog: scheduledBlog(blogID, at)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func scheduledBlog(blogID string, at time.Time) models.ScheduledBlog {
+	return models.ScheduledBlog{
+		Blog: models.Blog{
+			Id:         blogID,
+			Title:      "A scheduled post",
+			Url:        "https://blog.example.com/scheduled",
+			CoverImage: models.Image{URL: "https://cdn.example.com/cover.png"},
+			Author:     models.Author{Name: "Test Author"},
+		},
+		Platforms:     []string{"linkedin"},
+		ScheduledTime: at.Truncate(time.Second),
+	}
+}
+
+func TestScheduleBlogHandler(t *testing.T) {
+	schedule := func() http.HandlerFunc { return h.ScheduleBlogHandler }
+	tomorrow := utils.Now().Add(24 * time.Hour)
+	runCases(t, []handlerCase{
+		{name: "unverified user", handler: schedule, setup: userWith(nil), body: scheduleBody(t, "blog-1", tomorrow), status: http.StatusForbidden},
+		{name: "invalid JSON", handler: schedule, setup: userWith(verified), body: `{`, status: http.StatusBadRequest, text: "Failed to parse JSON"},
+		{name: "in the past", handler: schedule, setup: userWith(verified), body: scheduleBody(t, "blog-2", utils.Now().Add(-time.Hour)), status: http.StatusBadRequest, text: "in the past"},
+		{name: "too far ahead", handler: schedule, setup: userWith(verified), body: scheduleBody(t, "blog-3", utils.Now().Add(8*24*time.Hour)), status: http.StatusBadRequest, text: "more than 7 days"},
+		{
+			name:    "already scheduled",
+			handler: schedule,
+			setup: userWith(func(u *models.User) {
+				verified(u)
+				u.ScheduledBlogs = []models.ScheduledBlog{scheduledBlog("blog-dup", tomorrow)}
+			}),
+			body:   scheduleBody(t, "blog-dup", tomorrow),
+			status: http.StatusConflict,
+			text:   "Blog already scheduled",
+		},
+		{
+			// The task of a concurrent submission is queued before the user
+			// lists the blog
+			name:    "scheduled concurrently",
+			handler: schedule,
+			setup: func(t *testing.T) string {
+				userID := newUser(t, verified)
+				task := models.ScheduledBlogData{UserID: userID, ScheduledBlog: scheduledBlog("blog-race", tomorrow)}
+				if err := repo.StoreScheduledTask(task); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { repo.DeleteScheduledTask(task) })
+				return userID
+			},
+			body:   scheduleBody(t, "blog-race", tomorrow),
+			status: http.StatusConflict,
+			text:   `"reason":"Conflict"`,
+		},
+		{name: "scheduled", handler: schedule, setup: userWith(verified), body: scheduleBody(t, "blog-new", tomorrow), status: http.StatusOK, json: map[string]interface{}{"success": true}},
+	})
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	verify := func() http.HandlerFunc { return h.VerifyEmailHandler }
+	runCases(t, []handlerCase{
+		{name: "no OTP issued", handler: verify, setup: userWith(nil), body: `{"otp":"123456"}`, status: http.StatusBadRequest, text: "OTP expired"},
+		{
+			name:    "wrong OTP",
+			handler: verify,
+			setup: func(t *testing.T) string {
+				userID := newUser(t, nil)
+				if err := repo.SetCache("email_otp_"+userID, "123456", 5*time.Minute); err != nil {
+					t.Fatal(err)
+				}
+				return userID
+			},
+			body:   `{"otp":"654321"}`,
+			status: http.StatusBadRequest,
+			text:   "Invalid OTP",
+		},
+		{name: "invalid JSON", handler: verify, setup: userWith(nil), body: `{`, status: http.StatusBadRequest},
+	})
+}
+
+func TestSessionHandlers(t *testing.T) {
+	userID := newUser(t, nil)
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeSession := func(token string) {
+		info := models.SessionInfo{CreatedAt: utils.Now(), IP: "203.0.113.7", UserAgent: "test-agent"}
+		if err := repo.StoreSession(token, oid, info, time.Hour); err != nil {
+			t.Fatalf("storing session: %v", err)
+		}
+	}
+	currentToken, otherToken := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	storeSession(currentToken)
+	storeSession(otherToken)
+	current := &http.Cookie{Name: "session_token", Value: currentToken}
+
+	rec := serve(h.ListSessionsHandler, userID, "", current)
+	var listed struct {
+		Sessions []models.ActiveSession `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("listing sessions: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if len(listed.Sessions) != 2 {
+		t.Fatalf("%d sessions listed, want 2", len(listed.Sessions))
+	}
+	otherID := ""
+	for _, session := range listed.Sessions {
+		if session.IP != "203.0.113.7" || session.UserAgent != "test-agent" {
+			t.Errorf("session metadata not listed: %+v", session)
+		}
+		if !session.Current {
+			otherID = session.Id
+		}
+	}
+	if listed.Sessions[0].Current == listed.Sessions[1].Current {
+		t.Fatalf("expected exactly one current session: %+v", listed.Sessions)
+	}
+
+	revoke := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.AddCookie(current)
+		req = mux.SetURLVars(req.WithContext(utils.WithUserID(req.Context(), userID)), map[string]string{"id": id})
+		rec := httptest.NewRecorder()
+		h.RevokeSessionHandler(rec, req)
+		return rec
+	}
+	if rec := revoke("unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("revoking an unknown session: status %d, want 404", rec.Code)
+	}
+	if rec := revoke(otherID); rec.Code != http.StatusOK {
+		t.Fatalf("revoking a session: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if _, ok := repo.GetCache(otherToken); ok {
+		t.Error("revoked session still exists")
+	}
+
+	storeSession(otherToken)
+	if rec := serve(h.RevokeOtherSessionsHandler, userID, "", current); rec.Code != http.StatusOK {
+		t.Fatalf("revoking other sessions: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if _, ok := repo.GetCache(otherToken); ok {
+		t.Error("other session survived")
+	}
+	if _, ok := repo.GetCache(currentToken); !ok {
+		t.Error("current session was revoked")
+	}
+}
+
+// TestNewsletterUnsubscribePage checks that following the link in a
+// newsletter only shows the confirmation form; the tests run without a
+// database, so an unsubscribe attempt would fail.
+func TestNewsletterUnsubscribePage(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/newsletter/unsubscribe?user=u1&token=a%26b", nil)
+	rec := httptest.NewRecorder()
+	h.NewsletterUnsubscribePageHandler(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `<form method="post" action="unsubscribe?token=a%26b&amp;user=u1">`) {
+		t.Errorf("body %q has no confirmation form", body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.NewsletterUnsubscribePageHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/newsletter/unsubscribe?user=u1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("without a token: status %d", rec.Code)
+	}
+}
+
+// TestMagicLinkConfirm checks that opening an emailed login link, as mail
+// scanners do, only shows the confirmation form, and that confirming it logs
+// in once.
+func TestMagicLinkConfirm(t *testing.T) {
+	userID := newUser(t, func(user *models.User) { user.EmailVerified = true })
+	token, tokenHash, err := services.NewOAuthSecret("ssml_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreMagicLink(tokenHash, models.MagicLink{UserID: userID}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	target := "/api/v1/auth/magic-link/verify?token=" + token
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.MagicLinkPageHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<form method="post" action="verify?token=`+token+`">`) {
+			t.Fatalf("status %d, body %q; want the confirmation form", rec.Code, rec.Body.String())
+		}
+	}
+
+	verify := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.VerifyMagicLinkHandler(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec
+	}
+	rec := verify()
+	if location := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || strings.Contains(location, "magic_link_error") {
+		t.Fatalf("confirming: status %d, location %q", rec.Code, location)
+	}
+	checkResponse(t, rec, handlerCase{status: http.StatusSeeOther, wantCookie: true})
+	if location := verify().Header().Get("Location"); !strings.HasSuffix(location, "magic_link_error=expired") {
+		t.Errorf("confirming again: location %q, want the link to be used up", location)
+	}
+
+	rec = httptest.NewRecorder()
+	h.MagicLinkPageHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/magic-link/verify?token=other", nil))
+	if location := rec.Header().Get("Location"); !strings.HasSuffix(location, "magic_link_error=invalid") {
+		t.Errorf("a malformed token: location %q", location)
+	}
+}
+
+// useConfig makes the JSON document the active configuration for the test.
+func useConfig(t *testing.T, document string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	if err := config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("CONFIG_FILE")
+		config.Reload()
+	})
+}
+
+func TestAcceptConsentAudited(t *testing.T) {
+	useConfig(t, `{"terms": {"terms_of_service": "2026-03", "privacy_policy": "2026-01"}}`)
+	userID := newUser(t, nil)
+	accept := func() http.HandlerFunc { return h.AcceptConsentHandler }
+	runCases(t, []handlerCase{
+		{name: "outdated terms", handler: accept, setup: func(*testing.T) string { return userID }, body: `{"terms_of_service": "2025-12", "privacy_policy": "2026-01"}`, status: http.StatusConflict},
+		{name: "current terms", handler: accept, setup: func(*testing.T) string { return userID }, body: `{"terms_of_service": "2026-03", "privacy_policy": "2026-01"}`, status: http.StatusOK, json: map[string]interface{}{"required": false}},
+	})
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": userID})
+	rec := httptest.NewRecorder()
+	h.GetAuditLogHandler(rec, req)
+	var body struct {
+		Events []models.AuditEvent `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	if len(body.Events) != 1 || body.Events[0].Action != models.AuditConsentAccepted || body.Events[0].Details["terms_of_service"] != "2026-03" {
+		t.Errorf("audit events %+v, want the acceptance", body.Events)
+	}
+}
+
+func TestUpdateUserStatusPausesSchedules(t *testing.T) {
+	blog := scheduledBlog("paused-post", utils.Now().Add(24*time.Hour))
+	userID := newUser(t, func(user *models.User) {
+		user.ScheduledBlogs = []models.ScheduledBlog{blog}
+	})
+	if err := tasks.AddTask(models.ScheduledBlogData{UserID: userID, ScheduledBlog: blog}); err != nil {
+		t.Fatal(err)
+	}
+	adminID := newUser(t, func(user *models.User) { user.Role = models.RoleAdmin })
+	setStatus := func(body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		req = mux.SetURLVars(req.WithContext(utils.WithUserID(req.Context(), adminID)), map[string]string{"id": userID})
+		rec := httptest.NewRecorder()
+		h.UpdateUserStatusHandler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d; body %q", rec.Code, rec.Body.String())
+		}
+	}
+
+	setStatus(`{"disabled": true}`)
+	user, err := repo.GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if !user.Disabled || len(user.ScheduledBlogs) != 1 {
+		t.Errorf("disabled %t with schedules %+v, want the schedule kept", user.Disabled, user.ScheduledBlogs)
+	}
+	if queued := tasks.ListTasks(userID); len(queued) != 0 {
+		t.Errorf("a disabled user has queued tasks %+v", queued)
+	}
+
+	setStatus(`{"disabled": false}`)
+	if queued := tasks.ListTasks(userID); len(queued) != 1 || queued[0].ScheduledBlog.Id != blog.Id {
+		t.Errorf("queued tasks after enabling = %+v", queued)
+	}
+	if user, err = repo.GetUserById(userID); err != nil || user == nil || user.Disabled || len(user.ScheduledBlogs) != 1 {
+		t.Errorf("enabled user = %+v, %v", user, err)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/handlers"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/providertest"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/scheduler"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const testPassword = "correct-horse-battery"
+
+var (
+	h *handlers.Handlers
+	// tasks is the scheduler of h
+	tasks *scheduler.Scheduler
+	// connected is false when MONGO_TEST_URI isn't set and the cases skip
+	connected bool
+	userSeq   atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		h = handlers.New(handlers.Deps{})
+		os.Exit(m.Run())
+	}
+
+	dbName := fmt.Sprintf("social-scribe-handlers-%d", time.Now().UnixNano())
+	if err := repo.Connect(uri, dbName); err != nil {
+		fmt.Fprintf(os.Stderr, "connecting to %s: %v\n", uri, err)
+		os.Exit(2)
+	}
+	services.SetProviderTransport(&providertest.Transport{})
+	// The credentials of connected platforms are sealed
+	os.Setenv("APP_CREDENTIALS_SECRETS", "test-key")
+	tasks = scheduler.NewScheduler()
+	h = handlers.New(handlers.Deps{Scheduler: tasks})
+	connected = true
+
+	code := m.Run()
+	if err := repo.Disconnect(dbName); err != nil {
+		fmt.Fprintf(os.Stderr, "dropping %s: %v\n", dbName, err)
+	}
+	os.Exit(code)
+}
+
+// requireMongo skips tests that need the database when MONGO_TEST_URI isn't
+// set.
+func requireMongo(tb testing.TB) {
+	tb.Helper()
+	if !connected {
+		tb.Skip("MONGO_TEST_URI not set")
+	}
+}
+
+// handlerCase is one request to a handler and the response expected back.
+type handlerCase struct {
+	name    string
+	handler func() http.HandlerFunc
+	// setup prepares state and returns the user the request is made as, or
+	// "" for an unauthenticated request
+	setup  func(t *testing.T) string
+	body   string
+	cookie *http.Cookie
+	status int
+	// json lists fields the JSON body must contain; text is a substring of
+	// a plain-text body
+	json       map[string]interface{}
+	text       string
+	wantCookie bool
+}
+
+func runCases(t *testing.T, cases []handlerCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requireMongo(t)
+			userID := ""
+			if tc.setup != nil {
+				userID = tc.setup(t)
+			}
+			rec := serve(tc.handler(), userID, tc.body, tc.cookie)
+			checkResponse(t, rec, tc)
+		})
+	}
+}
+
+// serve calls the handler the way the auth middleware would after a
+// successful login, or with no user at all.
+func serve(handler http.HandlerFunc, userID, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return serveRequest(handler, req, userID, nil)
+}
+
+// serveRequest calls the handler with req as serve does, routed with the path
+// variables vars.
+func serveRequest(handler http.HandlerFunc, req *http.Request, userID string, vars map[string]string) *httptest.ResponseRecorder {
+	if userID != "" {
+		req = req.WithContext(repo.WithRequestUserCache(utils.WithUserID(req.Context(), userID)))
+	}
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// decodeJSON decodes the response body into v, failing the test unless the
+// status is want.
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, want int, v interface{}) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status %d, want %d; body %q", rec.Code, want, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+}
+
+func checkResponse(t *testing.T, rec *httptest.ResponseRecorder, tc handlerCase) {
+	t.Helper()
+	body := rec.Body.String()
+	if rec.Code != tc.status {
+		t.Fatalf("status %d, want %d; body %q", rec.Code, tc.status, body)
+	}
+	if tc.text != "" && !strings.Contains(body, tc.text) {
+		t.Errorf("body %q does not contain %q", body, tc.text)
+	}
+	if len(tc.json) > 0 {
+		var envelope map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+			t.Fatalf("body %q is not a JSON object: %v", body, err)
+		}
+		for key, want := range tc.json {
+			if got, ok := envelope[key]; !ok || got != want {
+				t.Errorf("%s = %v, want %v; body %q", key, got, want, body)
+			}
+		}
+	}
+	if tc.wantCookie {
+		var found bool
+		for _, c := range rec.Result().Cookies() {
+			found = found || (c.Name == "session_token" && c.Value != "")
+		}
+		if !found {
+			t.Error("no session cookie was set")
+		}
+	}
+}
+
+// newUser stores a user whose password is testPassword, after applying
+// mutate, and returns its id.
+func newUser(t *testing.T, mutate func(*models.User)) string {
+	t.Helper()
+	requireMongo(t)
+	hash, err := services.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	now := utils.Now()
+	user := models.User{
+		Id:           primitive.NewObjectID(),
+		UserName:     fmt.Sprintf("user%d", userSeq.Add(1)),
+		PassWord:     hash,
+		Plan:         models.PlanFree,
+		Region:       models.RegionDefault,
+		CreatedAt:    now,
+		LastActiveAt: now,
+	}
+	if mutate != nil {
+		mutate(&user)
+	}
+	id, err := repo.CreateUser(user)
+	if err != nil {
+		t.Fatalf("creating user %s: %v", user.UserName, err)
+	}
+	return id
+}
+
+// verified makes the user able to share to LinkedIn and X.
+func verified(user *models.User) {
+	user.Verified = true
+	user.EmailVerified = true
+	user.HashnodeVerified = true
+	user.LinkedinVerified = true
+	user.XVerified = true
+	mustSeal(user, &user.SealedLinkedInOauthKey, &user.PlaintextLinkedInOauthKey, "test-linkedin-token")
+	mustSeal(user, &user.SealedXOAuthToken, &user.PlaintextXOAuthToken, "test-x-token")
+	mustSeal(user, &user.SealedXOAuthSecret, &user.PlaintextXOAuthSecret, "test-x-secret")
+}
+
+// mustSeal seals a credential of a user being set up by newUser.
+func mustSeal(user *models.User, sealed, plaintext *string, secret string) {
+	if err := services.SealCredential(user, sealed, plaintext, secret); err != nil {
+		panic(err)
+	}
+}
+
+func userWith(mutate func(*models.User)) func(t *testing.T) string {
+	return func(t *testing.T) string { return newUser(t, mutate) }
+}
+
+// anyUser authenticates as a user that doesn't exist, for cases rejected
+// before the user is loaded.
+func anyUser(t *testing.T) string {
+	return "64b7f0c2a1b2c3d4e5f60718"
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeHashnodePublications(w http.ResponseWriter, user *models.User) {
+	publications := user.HashnodePublications
+	// Accounts connected before publications were stored only know the
+	// default one
+	if len(publications) == 0 && user.HashnodeBlog != "" {
+		publications = []models.HashnodePublication{{Host: user.HashnodeBlog}}
+	}
+	if publications == nil {
+		publications = []models.HashnodePublication{}
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"publications": publications,
+		"default":      user.HashnodeBlog,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetHashnodePublicationsHandler lists the publications of the connected
+// Hashnode account and the one blogs are listed from by default.
+func (h *Handlers) GetHashnodePublicationsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeHashnodePublications(w, user)
+}
+
+// SetHashnodePublicationHandler chooses the publication blogs are listed
+// from by default.
+func (h *Handlers) SetHashnodePublicationHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Publication string `json:"publication"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	publication := strings.TrimSpace(requestBody.Publication)
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.HashnodeVerified {
+		http.Error(w, "Hashnode is not connected", http.StatusPreconditionFailed)
+		return
+	}
+	if err := services.ValidateHashnodePublication(user, publication); err != nil {
+		writeError(w, err)
+		return
+	}
+	if publication != user.HashnodeBlog {
+		user.HashnodeBlog = publication
+		// Posts synced before publications were told apart count as the
+		// default one's until the sync tags them
+		user.PostsSyncDueAt = utils.Now()
+	}
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s chose Hashnode publication %q as default", userId, publication)
+	writeHashnodePublications(w, user)
+}
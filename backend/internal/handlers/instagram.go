@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func writeInstagramAccount(w http.ResponseWriter, account *models.InstagramAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetInstagramAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeInstagramAccount(w, user.Instagram)
+}
+
+// SetInstagramAccountHandler turns on Instagram caption kits for the user's
+// account. Nothing is posted with it, so only the username is asked for.
+func (h *Handlers) SetInstagramAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Handle string `json:"handle"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	handle, err := services.NormalizeInstagramHandle(requestBody.Handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	account := &models.InstagramAccount{Handle: handle, ConnectedAt: utils.Now()}
+	user.Instagram = account
+	user.InstagramVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s turned on Instagram caption kits for %s", userId, handle)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "instagram", "action": "connected", "account": handle})
+	writeInstagramAccount(w, account)
+}
+
+func (h *Handlers) DeleteInstagramAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Instagram == nil {
+		http.Error(w, "No Instagram account connected", http.StatusNotFound)
+		return
+	}
+	user.Instagram = nil
+	user.InstagramVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s turned off Instagram caption kits", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "instagram", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// GetManualTasksHandler lists the shares the user posts by hand, newest
+// first. Their images are served one at a time.
+func (h *Handlers) GetManualTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := repo.GetManualTasks(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"manual_tasks": tasks,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetManualTaskImageHandler serves the image of a manual task for the user
+// to download and post.
+func (h *Handlers) GetManualTaskImageHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	task, err := repo.GetManualTask(userId, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if task == nil || len(task.Image) == 0 {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", task.ImageType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(task.Image)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s-%s.jpg"`, task.Platform, task.Id.Hex()))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(task.Image)
+}
+
+// CompleteManualTaskHandler marks a manual task posted. Done tasks are
+// kept for a month.
+func (h *Handlers) CompleteManualTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskId := mux.Vars(r)["id"]
+	if err := repo.CompleteManualTask(userId, taskId, utils.Now()); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] User with ID %s completed the manual task %s", userId, taskId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+func (h *Handlers) DeleteManualTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := repo.DeleteManualTask(userId, mux.Vars(r)["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
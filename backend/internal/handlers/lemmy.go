@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeLemmyAccount(w http.ResponseWriter, account *models.LemmyAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetLemmyAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeLemmyAccount(w, user.Lemmy)
+}
+
+// SetLemmyAccountHandler logs in to the user's Lemmy server and connects
+// the account with the communities blog links go to. Only the session Lemmy
+// returns is kept, not the password.
+func (h *Handlers) SetLemmyAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Instance    string   `json:"instance"`
+		Username    string   `json:"username"`
+		Password    string   `json:"password"`
+		TOTP        string   `json:"totp"`
+		Communities []string `json:"communities"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Username == "" || requestBody.Password == "" {
+		http.Error(w, "Username and password are required", http.StatusBadRequest)
+		return
+	}
+	instance, err := services.NormalizeLemmyInstance(requestBody.Instance)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	jwt, username, err := services.LoginLemmy(userId, instance, requestBody.Username, requestBody.Password, requestBody.TOTP)
+	if errors.Is(err, apperrors.ErrProviderAuth) {
+		http.Error(w, "Lemmy rejected the login", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("[WARN] User %s failed to log in to the Lemmy server %s: %v", userId, instance, err)
+		writeError(w, err)
+		return
+	}
+	communities, err := services.ResolveLemmyCommunities(userId, instance, jwt, requestBody.Communities)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account := &models.LemmyAccount{Instance: instance, Username: username, Communities: communities, ConnectedAt: utils.Now()}
+	account.SealedJWT, err = services.SealUserSecret(user, jwt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Lemmy = account
+	user.LemmyVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Lemmy account %s@%s", userId, username, instance)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "lemmy", "action": "connected", "account": username + "@" + instance})
+	writeLemmyAccount(w, account)
+}
+
+// UpdateLemmyCommunitiesHandler changes the communities blog links are
+// submitted to.
+func (h *Handlers) UpdateLemmyCommunitiesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Communities []string `json:"communities"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Lemmy == nil {
+		http.Error(w, "No Lemmy account connected", http.StatusNotFound)
+		return
+	}
+	communities, err := services.ChangeLemmyCommunities(user, requestBody.Communities)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Lemmy.Communities = communities
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeLemmyAccount(w, user.Lemmy)
+}
+
+func (h *Handlers) DeleteLemmyAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Lemmy == nil {
+		http.Error(w, "No Lemmy account connected", http.StatusNotFound)
+		return
+	}
+	user.Lemmy = nil
+	user.LemmyVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Lemmy account", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "lemmy", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
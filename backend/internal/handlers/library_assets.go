@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxTeamLibraryAssets = 500
+
+// loadTeamMember returns the caller, failing unless they belong to a team.
+func loadTeamMember(r *http.Request) (*models.User, error) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		return nil, err
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s: %w", userId, apperrors.ErrNotFound)
+	}
+	if user.TeamID == "" {
+		return nil, fmt.Errorf("user is not in a team: %w", apperrors.ErrNotFound)
+	}
+	return user, nil
+}
+
+// canEditAsset reports whether user may change or delete the asset: its
+// creator and the team's owners and admins can.
+func canEditAsset(user *models.User, asset *models.LibraryAsset) bool {
+	return asset.CreatedBy == user.Id.Hex() || user.TeamRole == models.TeamRoleOwner || user.TeamRole == models.TeamRoleAdmin
+}
+
+func writeLibraryAsset(w http.ResponseWriter, status int, asset *models.LibraryAsset) {
+	responseJson, err := json.Marshal(asset)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJson)
+}
+
+// GetLibraryAssetsHandler lists the team's library, optionally filtered by
+// the kind query parameter.
+func (h *Handlers) GetLibraryAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != models.AssetImage && kind != models.AssetHashtags && kind != models.AssetCaption {
+		http.Error(w, "kind must be image, hashtags or caption", http.StatusBadRequest)
+		return
+	}
+
+	assets, err := repo.GetTeamLibraryAssets(user.TeamID, kind)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"assets": assets,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetLibraryAssetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	asset, err := repo.GetLibraryAsset(user.TeamID, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if asset == nil {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+	writeLibraryAsset(w, http.StatusOK, asset)
+}
+
+// CreateLibraryAssetHandler adds an image, hashtag set or caption snippet to
+// the caller's team library. Any member can contribute.
+func (h *Handlers) CreateLibraryAssetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var asset models.LibraryAsset
+	if err := json.NewDecoder(r.Body).Decode(&asset); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := asset.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, err := repo.CountTeamLibraryAssets(user.TeamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if count >= maxTeamLibraryAssets {
+		http.Error(w, "Library asset limit reached", http.StatusConflict)
+		return
+	}
+
+	now := utils.Now()
+	asset.Id = primitive.NilObjectID
+	asset.TeamID = user.TeamID
+	asset.CreatedBy = user.Id.Hex()
+	asset.CreatedAt = now
+	asset.UpdatedAt = now
+	assetId, err := repo.CreateLibraryAsset(asset)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	asset.Id, _ = primitive.ObjectIDFromHex(assetId)
+	log.Printf("[INFO] Library asset %s added to team %s by user with ID %s", assetId, user.TeamID, user.Id.Hex())
+	writeLibraryAsset(w, http.StatusCreated, &asset)
+}
+
+// UpdateLibraryAssetHandler replaces the content of an asset. Its kind and id
+// stay the same, so shares that reference it pick up the new content.
+func (h *Handlers) UpdateLibraryAssetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	asset, err := repo.GetLibraryAsset(user.TeamID, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if asset == nil {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+	if !canEditAsset(user, asset) {
+		http.Error(w, "Only the asset's creator or a team admin can change it", http.StatusForbidden)
+		return
+	}
+
+	var requestBody struct {
+		Name     string   `json:"name"`
+		ImageURL string   `json:"image_url"`
+		Hashtags []string `json:"hashtags"`
+		Text     string   `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	asset.Name = requestBody.Name
+	asset.ImageURL = requestBody.ImageURL
+	asset.Hashtags = requestBody.Hashtags
+	asset.Text = requestBody.Text
+	if err := asset.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	asset.UpdatedAt = utils.Now()
+	if err := repo.UpdateLibraryAsset(asset); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeLibraryAsset(w, http.StatusOK, asset)
+}
+
+func (h *Handlers) DeleteLibraryAssetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	assetId := mux.Vars(r)["id"]
+	asset, err := repo.GetLibraryAsset(user.TeamID, assetId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if asset == nil {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+	if !canEditAsset(user, asset) {
+		http.Error(w, "Only the asset's creator or a team admin can delete it", http.StatusForbidden)
+		return
+	}
+	if err := repo.DeleteLibraryAsset(user.TeamID, assetId); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Library asset %s deleted from team %s by user with ID %s", assetId, user.TeamID, user.Id.Hex())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
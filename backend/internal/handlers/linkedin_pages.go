@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+func writeLinkedInPages(w http.ResponseWriter, user *models.User) {
+	pages := user.LinkedInPages
+	if pages == nil {
+		pages = []models.LinkedInPage{}
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"pages":    pages,
+		"selected": user.LinkedInPageID,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetLinkedInPagesHandler lists the LinkedIn Pages the member may post for
+// and the one shares go to, empty for their own profile.
+func (h *Handlers) GetLinkedInPagesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeLinkedInPages(w, user)
+}
+
+// SetLinkedInPageHandler chooses the Page LinkedIn shares go to. An empty
+// page sends them to the member's own profile again.
+func (h *Handlers) SetLinkedInPageHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Page string `json:"page"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	page := strings.TrimSpace(requestBody.Page)
+	if page == models.LinkedInMemberProfile {
+		page = ""
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.LinkedinVerified {
+		http.Error(w, "LinkedIn is not connected", http.StatusPreconditionFailed)
+		return
+	}
+	if err := services.ValidateLinkedInPage(user, page); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.LinkedInPageID = page
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s chose LinkedIn page %q for shares", userId, page)
+	writeLinkedInPages(w, user)
+}
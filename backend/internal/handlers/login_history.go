@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+// recordLogin adds the request's sign-in to the user's login history and,
+// when it comes from a device or country the user hasn't signed in from
+// before, alerts them in their notifications and optionally by email.
+func recordLogin(r *http.Request, user *models.User, method string) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxSessionUserAgent {
+		userAgent = userAgent[:maxSessionUserAgent]
+	}
+	record := models.LoginRecord{
+		At:        utils.Now(),
+		Method:    method,
+		IP:        utils.GetClientIP(r),
+		UserAgent: userAgent,
+		Device:    services.DeviceLabel(userAgent),
+		Country:   config.Get().RequestCountry(r),
+	}
+	services.AssessLogin(user.LoginHistory, &record)
+
+	var alert string
+	if record.NewDevice || record.NewCountry {
+		alert = services.LoginAlertMessage(record)
+	}
+	userId := user.Id.Hex()
+	if err := repo.RecordLogin(userId, record, alert); err != nil {
+		log.Printf("[WARN] Failed to record a login of the user %s: %v", userId, err)
+		return
+	}
+	if alert != "" {
+		log.Printf("[INFO] Sign-in of the user %s from a new device or country (%s, %q)", userId, record.Device, record.Country)
+		go services.SendLoginAlertEmail(user, record)
+	}
+}
+
+// GetLoginHistoryHandler lists the user's recent sign-ins, newest first, with
+// the ones that raised an alert flagged.
+func (h *Handlers) GetLoginHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	logins := make([]models.LoginRecord, 0, len(user.LoginHistory))
+	for i := len(user.LoginHistory) - 1; i >= 0; i-- {
+		logins = append(logins, user.LoginHistory[i])
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"logins": logins,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	maxLoginFailuresPerUser = 5
+	// An address may front many users, so it gets more room than a username
+	maxLoginFailuresPerIP = 20
+)
+
+// loginLockedOut writes the lockout response when the client IP or the
+// username must wait before trying again. Throttling fails open: a cache
+// error doesn't block logins.
+func loginLockedOut(w http.ResponseWriter, ip, username string) bool {
+	if until, err := repo.LoginLockedUntil(repo.LoginScopeIP, ip); err == nil && !until.IsZero() {
+		writeLoginLockout(w, http.StatusTooManyRequests, until, "Too many failed login attempts from this address")
+		return true
+	}
+	if until, err := repo.LoginLockedUntil(repo.LoginScopeUser, username); err == nil && !until.IsZero() {
+		writeLoginLockout(w, http.StatusLocked, until, "This account is temporarily locked after too many failed login attempts")
+		return true
+	}
+	return false
+}
+
+// loginFailed records a failed login against the client IP and the username
+// and writes the response, which is a lockout once either reaches its limit.
+func loginFailed(w http.ResponseWriter, ip, username string) {
+	ipLockedUntil, err := repo.RecordLoginFailure(repo.LoginScopeIP, ip, maxLoginFailuresPerIP)
+	if err != nil {
+		log.Printf("[WARN] Failed to record login failure from %s: %v", ip, err)
+	}
+	userLockedUntil, err := repo.RecordLoginFailure(repo.LoginScopeUser, username, maxLoginFailuresPerUser)
+	if err != nil {
+		log.Printf("[WARN] Failed to record login failure of %s: %v", username, err)
+	}
+
+	switch {
+	case !ipLockedUntil.IsZero():
+		log.Printf("[WARN] Locked out logins from %s until %s", ip, ipLockedUntil.Format(time.RFC3339))
+		writeLoginLockout(w, http.StatusTooManyRequests, ipLockedUntil, "Too many failed login attempts from this address")
+	case !userLockedUntil.IsZero():
+		log.Printf("[WARN] Locked out logins of %s until %s", username, userLockedUntil.Format(time.RFC3339))
+		writeLoginLockout(w, http.StatusLocked, userLockedUntil, "This account is temporarily locked after too many failed login attempts")
+	default:
+		http.Error(w, `{"success": false, "reason": "Username and/or password is incorrect"}`, http.StatusBadRequest)
+	}
+}
+
+func writeLoginLockout(w http.ResponseWriter, status int, until time.Time, reason string) {
+	retryAfter := int(until.Sub(utils.Now()).Seconds() + 0.999)
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	responseJson, _ := json.Marshal(map[string]interface{}{
+		"success":     false,
+		"reason":      reason,
+		"retry_after": retryAfter,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(status)
+	w.Write(responseJson)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/metrics"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	magicLinkTTL         = 15 * time.Minute
+	magicLinkTokenPrefix = "ssml_"
+)
+
+func magicLinkURL(token string) string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/auth/magic-link/verify?token=" + url.QueryEscape(token)
+}
+
+// RequestMagicLinkHandler emails a one-time login link to the account with
+// the given verified email. It answers the same whether or not such an
+// account exists, so it can't be used to probe for addresses.
+func (h *Handlers) RequestMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email      string `json:"email"`
+		RememberMe bool   `json:"remember_me"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "Bad request: unable to decode JSON"}`, http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > 254 {
+		http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+	if !services.EmailConfigured() {
+		http.Error(w, `{"error": "Email login is not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	users, err := repo.GetUsersByEmail(email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to look up accounts for a magic link: %v", err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	var user *models.User
+	for i := range users {
+		if users[i].EmailVerified && !users[i].Disabled {
+			user = &users[i]
+			break
+		}
+	}
+
+	if user != nil {
+		token, tokenHash, err := services.NewOAuthSecret(magicLinkTokenPrefix)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		link := models.MagicLink{UserID: user.Id.Hex(), RememberMe: body.RememberMe}
+		if err := repo.StoreMagicLink(tokenHash, link, magicLinkTTL); err != nil {
+			http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		message := fmt.Sprintf("Use this link to log in to SocialScribe:\n\n%s\n\nIt works once and expires in %d minutes. If you didn't ask to log in, you can ignore this email.\n", magicLinkURL(token), int(magicLinkTTL.Minutes()))
+		if err := services.SendEmail(email, "Your SocialScribe login link", message); err != nil {
+			log.Printf("[ERROR] Failed to send the magic link of user %s: %v", link.UserID, err)
+			http.Error(w, `{"error": "Failed to send the login email"}`, http.StatusBadGateway)
+			return
+		}
+		log.Printf("[INFO] Magic link sent to the user with ID %s", link.UserID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// magicLinkPage asks the user to confirm the login. Link scanners and
+// prefetchers follow the link in the email too, so opening it must not use
+// it up.
+var magicLinkPage = template.Must(template.New("magic-link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Log in to SocialScribe</title></head>
+<body>
+<p>Log in to SocialScribe in this browser?</p>
+<form method="post" action="{{.}}"><button type="submit">Log in</button></form>
+</body>
+</html>
+`))
+
+// MagicLinkPageHandler is the target of an emailed login link. It only shows
+// a page that confirms with a POST.
+func (h *Handlers) MagicLinkPageHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if !strings.HasPrefix(token, magicLinkTokenPrefix) {
+		http.Redirect(w, r, config.Get().FrontendURL+"/login?magic_link_error=invalid", http.StatusSeeOther)
+		return
+	}
+	action := "verify?" + url.Values{"token": {token}}.Encode()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	magicLinkPage.Execute(w, action)
+}
+
+// VerifyMagicLinkHandler logs in a user who confirmed on the magic link page.
+// It starts a session and redirects to the frontend; the link can't be used
+// again.
+func (h *Handlers) VerifyMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	failureURL := config.Get().FrontendURL + "/login?magic_link_error="
+	token := r.URL.Query().Get("token")
+	if !strings.HasPrefix(token, magicLinkTokenPrefix) {
+		http.Redirect(w, r, failureURL+"invalid", http.StatusSeeOther)
+		return
+	}
+	link, err := repo.TakeMagicLink(services.HashOAuthSecret(token))
+	if err != nil {
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	if link == nil {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+
+	user, err := repo.GetUserById(link.UserID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", link.UserID, err)
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	if user == nil || !user.EmailVerified {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+	if user.Disabled {
+		http.Redirect(w, r, failureURL+"disabled", http.StatusSeeOther)
+		return
+	}
+
+	if err := startSession(w, r, user.Id, link.RememberMe); err != nil {
+		log.Printf("[ERROR] Failed to create session for the user %s: %v", link.UserID, err)
+		http.Redirect(w, r, failureURL+"session", http.StatusSeeOther)
+		return
+	}
+	if logins, err := metrics.Logins.GetMetricWithLabelValues(metrics.Cohort(user)); err != nil {
+		log.Printf("[WARN] Failed to count the login: %v", err)
+	} else {
+		logins.Inc()
+	}
+	if err := repo.TouchUserActivity(link.UserID, utils.Now()); err != nil {
+		log.Printf("[WARN] Failed to record activity for the user %s: %v", link.UserID, err)
+	}
+	log.Printf("[INFO] User with ID %s logged in with a magic link", link.UserID)
+	notifySecurityEvent(r, user, models.SecurityEventLogin, map[string]string{"method": "magic_link"})
+	recordLogin(r, user, "magic_link")
+	http.Redirect(w, r, config.Get().FrontendURL+"/", http.StatusSeeOther)
+}
@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+func TestRequestMagicLinkHandler(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	request := func() http.HandlerFunc { return h.RequestMagicLinkHandler }
+	runCases(t, []handlerCase{
+		{name: "not json", handler: request, body: `email`, status: http.StatusBadRequest},
+		{name: "invalid email", handler: request, body: `{"email": "someone"}`, status: http.StatusBadRequest, text: "Invalid email"},
+		{name: "display name", handler: request, body: `{"email": "Someone <someone@example.com>"}`, status: http.StatusBadRequest, text: "Invalid email"},
+		{name: "email not configured", handler: request, body: `{"email": "someone@example.com"}`, status: http.StatusServiceUnavailable},
+	})
+}
+
+// TestVerifyMagicLinkRejected checks that a link stops logging in once the
+// account it was sent for can't use it any more.
+func TestVerifyMagicLinkRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*models.User)
+		reason string
+	}{
+		{name: "disabled account", mutate: func(user *models.User) { user.EmailVerified, user.Disabled = true, true }, reason: "disabled"},
+		{name: "email no longer verified", reason: "expired"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userID := newUser(t, tc.mutate)
+			token, tokenHash, err := services.NewOAuthSecret("ssml_")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.StoreMagicLink(tokenHash, models.MagicLink{UserID: userID}, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			h.VerifyMagicLinkHandler(rec, httptest.NewRequest(http.MethodPost, "/?token="+token, nil))
+			if location := rec.Header().Get("Location"); !strings.HasSuffix(location, "magic_link_error="+tc.reason) {
+				t.Errorf("location %q, want magic_link_error=%s", location, tc.reason)
+			}
+			if len(rec.Result().Cookies()) != 0 {
+				t.Errorf("cookies set: %v", rec.Result().Cookies())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/maintenance"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	maxMaintenanceMessage = 500
+	maxMaintenanceMinutes = 7 * 24 * 60
+)
+
+func writeMaintenanceState(w http.ResponseWriter, state models.MaintenanceState) {
+	responseJson, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetMaintenanceHandler reports whether maintenance mode is on.
+func (h *Handlers) GetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	writeMaintenanceState(w, maintenance.Current())
+}
+
+// StartMaintenanceHandler turns maintenance mode on, or updates its message
+// and expected end. ends_in_minutes only sets the Retry-After clients are
+// given; maintenance lasts until it is turned off.
+func (h *Handlers) StartMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Message       string `json:"message"`
+		EndsInMinutes int    `json:"ends_in_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	message := strings.TrimSpace(requestBody.Message)
+	if len(message) > maxMaintenanceMessage {
+		http.Error(w, "message must be at most 500 characters", http.StatusBadRequest)
+		return
+	}
+	if requestBody.EndsInMinutes < 0 || requestBody.EndsInMinutes > maxMaintenanceMinutes {
+		http.Error(w, "ends_in_minutes must be between 0 and 10080", http.StatusBadRequest)
+		return
+	}
+	var endsAt time.Time
+	if requestBody.EndsInMinutes > 0 {
+		endsAt = utils.Now().Add(time.Duration(requestBody.EndsInMinutes) * time.Minute)
+	}
+
+	state, err := maintenance.Start(message, endsAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Maintenance mode turned on by admin until %v", endsAt)
+	writeMaintenanceState(w, state)
+}
+
+// EndMaintenanceHandler turns maintenance mode off.
+func (h *Handlers) EndMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if err := maintenance.End(); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Println("[INFO] Maintenance mode turned off by admin")
+	writeMaintenanceState(w, maintenance.Current())
+}
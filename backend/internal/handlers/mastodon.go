@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	mastodonStateCookie = "mastodon_oauth_state"
+	mastodonStateTTL    = 10 * time.Minute
+)
+
+func mastodonRedirectURI() string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/user/mastodon-callback"
+}
+
+// ConnectMastodonHandler starts the OAuth flow with the Mastodon server named
+// by the instance query parameter, registering SocialScribe there on first
+// use.
+func (h *Handlers) ConnectMastodonHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	instance, err := services.NormalizeMastodonInstance(r.URL.Query().Get("instance"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	app, err := services.MastodonApp(instance, mastodonRedirectURI())
+	if err != nil {
+		log.Printf("[ERROR] Failed to register with the Mastodon server %s: %v", instance, err)
+		http.Error(w, "Failed to reach the Mastodon server", http.StatusBadGateway)
+		return
+	}
+
+	state := uuid.New().String()
+	if err := repo.StoreMastodonAuth(state, models.MastodonAuth{UserID: userId, Instance: instance}, mastodonStateTTL); err != nil {
+		http.Error(w, "Failed to store state in cache", http.StatusInternalServerError)
+		return
+	}
+	setStateCookie(w, mastodonStateCookie, state, mastodonStateTTL)
+
+	http.Redirect(w, r, services.MastodonAuthorizeURL(instance, app, state), http.StatusFound)
+}
+
+// MastodonCallbackHandler completes the OAuth flow and connects the Mastodon
+// account to the user.
+func (h *Handlers) MastodonCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	queryState := r.URL.Query().Get("state")
+	stateCookie, err := r.Cookie(mastodonStateCookie)
+	if err != nil || queryState == "" || stateCookie.Value != queryState {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	auth, err := repo.TakeMastodonAuth(queryState)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if auth == nil || auth.UserID != userId {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		log.Printf("[ERROR] Missing authorization code")
+		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	app, err := services.MastodonApp(auth.Instance, mastodonRedirectURI())
+	if err != nil {
+		log.Printf("[ERROR] Failed to get the app of the Mastodon server %s: %v", auth.Instance, err)
+		http.Error(w, "Failed to reach the Mastodon server", http.StatusBadGateway)
+		return
+	}
+	accessToken, account, err := services.ConnectMastodon(userId, auth.Instance, app, code)
+	if err != nil {
+		log.Printf("[ERROR] Failed to connect the Mastodon account of user %s: %v", userId, err)
+		if errors.Is(err, apperrors.ErrProviderAuth) {
+			http.Error(w, "The Mastodon server rejected the authorization", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to exchange token", http.StatusBadGateway)
+		return
+	}
+
+	user.MastodonInstance = auth.Instance
+	user.MastodonAccount = account
+	if err := services.SealCredential(user, &user.SealedMastodonAccessToken, &user.PlaintextMastodonAccessToken, accessToken); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.MastodonVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected to Mastodon as %s", userId, account)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "mastodon", "action": "connected", "account": account})
+
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeMatrixRoom(w http.ResponseWriter, room *models.MatrixRoom) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"room": room,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetMatrixRoomHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeMatrixRoom(w, user.Matrix)
+}
+
+// SetMatrixRoomHandler connects the Matrix room blogs are announced in. The
+// account the access token belongs to joins the room unless it already
+// has.
+func (h *Handlers) SetMatrixRoomHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Homeserver  string `json:"homeserver"`
+		AccessToken string `json:"access_token"`
+		Room        string `json:"room"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	token := strings.TrimSpace(requestBody.AccessToken)
+	if token == "" {
+		http.Error(w, "Access token is required", http.StatusBadRequest)
+		return
+	}
+	homeserver, err := services.DiscoverMatrixHomeserver(requestBody.Homeserver)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	room, err := services.ConnectMatrixRoom(userId, homeserver, token, requestBody.Room)
+	if errors.Is(err, apperrors.ErrProviderAuth) {
+		log.Printf("[WARN] The Matrix access token of user %s failed its check: %v", userId, err)
+		http.Error(w, "The homeserver rejected the access token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	room.SealedToken, err = services.SealUserSecret(user, token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	room.ConnectedAt = utils.Now()
+	user.Matrix = room
+	user.MatrixVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Matrix room %s as %s", userId, room.RoomID, room.UserID)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "matrix", "action": "connected", "account": room.UserID})
+	writeMatrixRoom(w, room)
+}
+
+func (h *Handlers) DeleteMatrixRoomHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Matrix == nil {
+		http.Error(w, "No Matrix room connected", http.StatusNotFound)
+		return
+	}
+	user.Matrix = nil
+	user.MatrixVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Matrix room", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "matrix", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
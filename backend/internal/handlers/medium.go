@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeMediumAccount(w http.ResponseWriter, account *models.MediumAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetMediumAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeMediumAccount(w, user.Medium)
+}
+
+// SetMediumAccountHandler connects the Medium account that articles are
+// republished to, after checking the integration token with Medium.
+func (h *Handlers) SetMediumAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	token, err := services.NormalizeMediumToken(requestBody.Token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	account, err := services.LookupMediumAccount(userId, token)
+	if err != nil {
+		log.Printf("[WARN] The Medium integration token of user %s failed its check: %v", userId, err)
+		http.Error(w, "Medium rejected the integration token", http.StatusBadRequest)
+		return
+	}
+	account.SealedToken, err = services.SealUserSecret(user, token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account.ConnectedAt = utils.Now()
+	user.Medium = account
+	user.MediumVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Medium account %s", userId, account.Username)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "medium", "action": "connected", "account": account.Username})
+	writeMediumAccount(w, account)
+}
+
+func (h *Handlers) DeleteMediumAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Medium == nil {
+		http.Error(w, "No Medium account connected", http.StatusNotFound)
+		return
+	}
+	user.Medium = nil
+	user.MediumVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Medium account", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "medium", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const maxSubscriberListBytes = 1 << 20
+
+func writeNewsletter(w http.ResponseWriter, account *models.NewsletterAccount, subscribers int) {
+	response := map[string]interface{}{
+		"newsletter": account,
+	}
+	if account != nil && account.Provider == models.NewsletterBuiltin {
+		response["subscribers"] = subscribers
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetNewsletterHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	subscribers := 0
+	if user.Newsletter != nil && user.Newsletter.Provider == models.NewsletterBuiltin {
+		if subscribers, err = repo.CountNewsletterSubscribers(userId); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeNewsletter(w, user.Newsletter, subscribers)
+}
+
+// SetNewsletterHandler chooses how shared blogs are sent as newsletters:
+// by the built-in sender to the subscribers kept here, or through a
+// Buttondown or Mailchimp account, whose API key is checked first.
+func (h *Handlers) SetNewsletterHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Provider string `json:"provider"`
+		APIKey   string `json:"api_key"`
+		ListID   string `json:"list_id"`
+		FromName string `json:"from_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	account, err := services.NewNewsletterAccount(user, strings.TrimSpace(requestBody.Provider), requestBody.APIKey, requestBody.ListID, requestBody.FromName)
+	if err != nil {
+		log.Printf("[WARN] Failed to connect the newsletter of user %s: %v", userId, err)
+		writeError(w, err)
+		return
+	}
+	user.Newsletter = account
+	user.NewsletterVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	subscribers := 0
+	if account.Provider == models.NewsletterBuiltin {
+		if subscribers, err = repo.CountNewsletterSubscribers(userId); err != nil {
+			log.Printf("[WARN] Failed to count the newsletter subscribers of user %s: %v", userId, err)
+		}
+	}
+	log.Printf("[INFO] User with ID %s connected the %s newsletter sender", userId, account.Provider)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "newsletter", "action": "connected", "account": account.Provider})
+	writeNewsletter(w, account, subscribers)
+}
+
+// DeleteNewsletterHandler stops sending newsletters. The built-in sender's
+// subscribers are kept for when it is connected again.
+func (h *Handlers) DeleteNewsletterHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Newsletter == nil {
+		http.Error(w, "No newsletter connected", http.StatusNotFound)
+		return
+	}
+	user.Newsletter = nil
+	user.NewsletterVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their newsletter", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "newsletter", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// ListNewsletterSubscribersHandler lists the built-in sender's subscribers,
+// including those who unsubscribed.
+func (h *Handlers) ListNewsletterSubscribersHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	subscribers, err := repo.GetNewsletterSubscribers(userId, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"subscribers": subscribers,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// AddNewsletterSubscribersHandler adds the addresses of an uploaded list to
+// the built-in sender's subscribers. The list is sent as the "file" field
+// of a multipart form or as a text/csv or text/plain body, either a CSV
+// export with an "email" column or one address per line.
+func (h *Handlers) AddNewsletterSubscribersHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubscriberListBytes)
+	var list io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, `{"error": "Missing subscriber list"}`, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		list = file
+	}
+	emails, err := services.ParseNewsletterSubscribers(list)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	existing, err := repo.GetNewsletterSubscribers(userId, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	listed := map[string]bool{}
+	for _, subscriber := range existing {
+		listed[subscriber.Email] = true
+	}
+	count := len(existing)
+	for _, email := range emails {
+		if !listed[email] {
+			count++
+		}
+	}
+	if count > services.MaxNewsletterSubscribers {
+		writeError(w, fmt.Errorf("the built-in sender mails at most %d subscribers, connect Buttondown or Mailchimp for bigger lists: %w", services.MaxNewsletterSubscribers, apperrors.ErrInvalidInput))
+		return
+	}
+	subscribers, err := services.NewNewsletterSubscribers(emails)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	added, err := repo.AddNewsletterSubscribers(userId, subscribers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] User with ID %s added %d newsletter subscribers", userId, added)
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"added":     added,
+		"unchanged": len(emails) - added,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) DeleteNewsletterSubscriberHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(mux.Vars(r)["email"]))
+	if err := repo.DeleteNewsletterSubscriber(userId, email); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] User with ID %s removed a newsletter subscriber", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// unsubscribePage asks the reader to confirm. Link scanners and prefetchers
+// follow the link in the email too, so opening it must not unsubscribe.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<p>Stop getting this newsletter?</p>
+<form method="post" action="{{.}}"><button type="submit">Unsubscribe</button></form>
+</body>
+</html>
+`))
+
+// NewsletterUnsubscribePageHandler is the target of the unsubscribe links of
+// the built-in sender. It only shows a page that confirms with a POST.
+func (h *Handlers) NewsletterUnsubscribePageHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userId, token := query.Get("user"), query.Get("token")
+	if userId == "" || token == "" {
+		http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+	action := "unsubscribe?" + url.Values{"user": {userId}, "token": {token}}.Encode()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	unsubscribePage.Execute(w, action)
+}
+
+// NewsletterUnsubscribeHandler unsubscribes a reader who confirmed on the
+// unsubscribe page, or whose mail client sent an RFC 8058 one-click request.
+func (h *Handlers) NewsletterUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userId, token := query.Get("user"), query.Get("token")
+	if userId == "" || token == "" {
+		http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+	_, err := repo.UnsubscribeNewsletter(userId, token, utils.Now())
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrInvalidInput) {
+			http.Error(w, "Invalid unsubscribe link", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] A newsletter subscriber of user %s unsubscribed", userId)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("You are unsubscribed and won't get this newsletter anymore.\n"))
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeNostrAccount(w http.ResponseWriter, account *models.NostrAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetNostrAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeNostrAccount(w, user.Nostr)
+}
+
+// SetNostrAccountHandler connects the Nostr key blog notes are signed with
+// and the relays they are published to. The default relays are used when
+// none are given.
+func (h *Handlers) SetNostrAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		SecretKey string   `json:"secret_key"`
+		Relays    []string `json:"relays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	secretKey, err := services.DecodeNostrSecretKey(requestBody.SecretKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	relays, err := services.NormalizeNostrRelays(requestBody.Relays)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	publicKey, npub, err := services.NostrPublicKey(secretKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account := &models.NostrAccount{PublicKey: publicKey, Npub: npub, Relays: relays, ConnectedAt: utils.Now()}
+	account.SealedSecretKey, err = services.SealUserSecret(user, hex.EncodeToString(secretKey))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Nostr = account
+	user.NostrVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Nostr key %s", userId, npub)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "nostr", "action": "connected", "account": npub})
+	writeNostrAccount(w, account)
+}
+
+func (h *Handlers) DeleteNostrAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Nostr == nil {
+		http.Error(w, "No Nostr key connected", http.StatusNotFound)
+		return
+	}
+	user.Nostr = nil
+	user.NostrVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Nostr key", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "nostr", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
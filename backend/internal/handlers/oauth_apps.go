@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const maxAppCredentialLength = 256
+
+func (h *Handlers) writeOAuthApps(w http.ResponseWriter, user *models.User) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"available": services.AppCredentialsEnabled(),
+		"twitter":   user.TwitterApp,
+		"linkedin":  user.LinkedInApp,
+		// The user's apps must allow these, as the OAuth flows come back to
+		// them
+		"callback_urls": map[string]string{
+			"twitter":  h.twitterConfig.CallbackURL,
+			"linkedin": h.linkedinConfig.RedirectURL,
+		},
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// disconnectOAuthAppPlatform drops the user's connection to platform, whose
+// tokens belong to the app that was replaced.
+func disconnectOAuthAppPlatform(user *models.User, platform string) {
+	if connected, ok := services.LookupPlatform(platform); ok {
+		connected.Disconnect(user)
+	}
+}
+
+func (h *Handlers) GetOAuthAppsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	h.writeOAuthApps(w, user)
+}
+
+// SetOAuthAppHandler stores the user's own X or LinkedIn app, used for their
+// requests instead of the shared one, after checking the credentials with the
+// platform. Access tokens belong to the app that issued them, so the platform
+// is disconnected and has to be connected again through the new app.
+func (h *Handlers) SetOAuthAppHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	platform := mux.Vars(r)["platform"]
+	if platform != "twitter" && platform != "linkedin" {
+		http.Error(w, `platform must be "twitter" or "linkedin"`, http.StatusBadRequest)
+		return
+	}
+	if !services.AppCredentialsEnabled() {
+		http.Error(w, "Bringing your own app is not available", http.StatusServiceUnavailable)
+		return
+	}
+	var requestBody struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.ClientID = strings.TrimSpace(requestBody.ClientID)
+	requestBody.ClientSecret = strings.TrimSpace(requestBody.ClientSecret)
+	if requestBody.ClientID == "" || requestBody.ClientSecret == "" {
+		http.Error(w, "client_id and client_secret are required", http.StatusBadRequest)
+		return
+	}
+	if len(requestBody.ClientID) > maxAppCredentialLength || len(requestBody.ClientSecret) > maxAppCredentialLength {
+		http.Error(w, "client_id and client_secret must be at most 256 characters", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if platform == "twitter" {
+		err = services.ValidateTwitterApp(h.twitterConfig, requestBody.ClientID, requestBody.ClientSecret)
+	} else {
+		err = services.ValidateLinkedInApp(h.linkedinConfig, requestBody.ClientID, requestBody.ClientSecret)
+	}
+	if err != nil {
+		log.Printf("[WARN] The %s app of user %s failed its check: %v", platform, userId, err)
+		writeError(w, err)
+		return
+	}
+	sealed, err := services.SealUserSecret(user, requestBody.ClientSecret)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	app := &models.OAuthApp{ClientID: requestBody.ClientID, SealedSecret: sealed, UpdatedAt: utils.Now()}
+	if platform == "twitter" {
+		user.TwitterApp = app
+	} else {
+		user.LinkedInApp = app
+	}
+	disconnectOAuthAppPlatform(user, platform)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s set their own %s app", userId, platform)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": platform, "action": "app_changed"})
+	h.writeOAuthApps(w, user)
+}
+
+// DeleteOAuthAppHandler goes back to the shared app for the platform,
+// disconnecting it like SetOAuthAppHandler does.
+func (h *Handlers) DeleteOAuthAppHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	platform := mux.Vars(r)["platform"]
+	if platform != "twitter" && platform != "linkedin" {
+		http.Error(w, `platform must be "twitter" or "linkedin"`, http.StatusBadRequest)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if (platform == "twitter" && user.TwitterApp == nil) || (platform == "linkedin" && user.LinkedInApp == nil) {
+		http.Error(w, "No app of your own is set for "+platform, http.StatusNotFound)
+		return
+	}
+	if platform == "twitter" {
+		user.TwitterApp = nil
+	} else {
+		user.LinkedInApp = nil
+	}
+	disconnectOAuthAppPlatform(user, platform)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s went back to the shared %s app", userId, platform)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": platform, "action": "app_removed"})
+	h.writeOAuthApps(w, user)
+}
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeTTL        = 10 * time.Minute
+	oauthAccessTokenTTL = 30 * 24 * time.Hour
+	maxOAuthClients     = 10
+)
+
+func (h *Handlers) RegisterOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" || len(requestBody.Name) > 100 {
+		http.Error(w, "Client name must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+	if len(requestBody.RedirectURIs) == 0 {
+		http.Error(w, "At least one redirect uri is required", http.StatusBadRequest)
+		return
+	}
+	for _, redirectURI := range requestBody.RedirectURIs {
+		if err := services.ValidateRedirectURI(redirectURI); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	existing, err := repo.GetOAuthClientsByOwner(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(existing) >= maxOAuthClients {
+		http.Error(w, "Client limit reached", http.StatusConflict)
+		return
+	}
+
+	secret, secretHash, err := services.NewOAuthSecret("sscs_")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	client := models.OAuthClient{
+		ClientID:     "ssc_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		SecretHash:   secretHash,
+		Name:         requestBody.Name,
+		RedirectURIs: requestBody.RedirectURIs,
+		OwnerID:      userId,
+		CreatedAt:    utils.Now(),
+	}
+	if err := repo.CreateOAuthClient(client); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] OAuth client %s registered by user with ID %s", client.ClientID, userId)
+	// The secret is only ever shown here; it is stored hashed
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"client":        client,
+		"client_secret": secret,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clients, err := repo.GetOAuthClientsByOwner(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"clients": clients,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) DeleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		ClientId string `json:"client_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.ClientId == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := repo.DeleteOAuthClient(userId, requestBody.ClientId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] OAuth client %s deleted by user with ID %s", requestBody.ClientId, userId)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+type authorizeRequest struct {
+	ClientId            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	ResponseType        string `json:"response_type"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// validate checks the request against the registered client. Errors here
+// must be shown to the user rather than redirected, since the redirect uri
+// itself may be untrusted.
+func (ar *authorizeRequest) validate() (*models.OAuthClient, []string, error) {
+	client, err := repo.GetOAuthClient(ar.ClientId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, fmt.Errorf("unknown client: %w", apperrors.ErrInvalidInput)
+	}
+	if !slices.Contains(client.RedirectURIs, ar.RedirectURI) {
+		return nil, nil, fmt.Errorf("redirect uri is not registered for this client: %w", apperrors.ErrInvalidInput)
+	}
+	if ar.ResponseType != "code" {
+		return nil, nil, fmt.Errorf("only the code response type is supported: %w", apperrors.ErrInvalidInput)
+	}
+	if ar.CodeChallenge != "" && ar.CodeChallengeMethod != "S256" {
+		return nil, nil, fmt.Errorf("only the S256 code challenge method is supported: %w", apperrors.ErrInvalidInput)
+	}
+	scopes, err := services.ParseOAuthScopes(ar.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, scopes, nil
+}
+
+// OAuthConsentHandler describes an authorization request so the frontend can
+// render the consent screen.
+func (h *Handlers) OAuthConsentHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := ValidateLogin(r); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	request := authorizeRequest{
+		ClientId:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		ResponseType:        query.Get("response_type"),
+		Scope:               query.Get("scope"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		CodeChallenge:       query.Get("code_challenge"),
+	}
+	client, scopes, err := request.validate()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scopeDescriptions := []map[string]string{}
+	for _, scope := range scopes {
+		scopeDescriptions = append(scopeDescriptions, map[string]string{
+			"scope":       scope,
+			"description": services.OAuthScopes[scope],
+		})
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"client_id":    client.ClientID,
+		"client_name":  client.Name,
+		"redirect_uri": request.RedirectURI,
+		"scopes":       scopeDescriptions,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// OAuthAuthorizeHandler records the user's consent decision and returns the
+// client redirect carrying either an authorization code or access_denied.
+func (h *Handlers) OAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		authorizeRequest
+		Approve bool `json:"approve"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	client, scopes, err := requestBody.validate()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	redirect, _ := url.Parse(requestBody.RedirectURI)
+	params := redirect.Query()
+	if requestBody.State != "" {
+		params.Set("state", requestBody.State)
+	}
+	if !requestBody.Approve {
+		params.Set("error", "access_denied")
+	} else {
+		code, codeHash, err := services.NewOAuthSecret("ssa_")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		now := utils.Now()
+		err = repo.StoreOAuthGrant(models.OAuthGrant{
+			Kind:          models.OAuthGrantCode,
+			TokenHash:     codeHash,
+			ClientID:      client.ClientID,
+			UserID:        userId,
+			Scopes:        scopes,
+			RedirectURI:   requestBody.RedirectURI,
+			CodeChallenge: requestBody.CodeChallenge,
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(oauthCodeTTL),
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		params.Set("code", code)
+		log.Printf("[INFO] User with ID %s authorized OAuth client %s for %v", userId, client.ClientID, scopes)
+	}
+	redirect.RawQuery = params.Encode()
+
+	responseJson, err := json.Marshal(map[string]string{
+		"redirect_to": redirect.String(),
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	responseJson, _ := json.Marshal(map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(responseJson)
+}
+
+// authenticateOAuthClient reads client credentials from HTTP basic auth or
+// the form body, as allowed by RFC 6749 section 2.3.1.
+func authenticateOAuthClient(r *http.Request) (*models.OAuthClient, error) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+	client, err := repo.GetOAuthClient(clientId)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !services.CheckOAuthSecret(clientSecret, client.SecretHash) {
+		return nil, fmt.Errorf("invalid client credentials: %w", apperrors.ErrUnauthorized)
+	}
+	return client, nil
+}
+
+// OAuthTokenHandler exchanges an authorization code for an access token.
+func (h *Handlers) OAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+	client, err := authenticateOAuthClient(r)
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code is supported")
+		return
+	}
+
+	now := utils.Now()
+	grant, err := repo.TakeOAuthCode(services.HashOAuthSecret(r.PostFormValue("code")), now)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to look up the code")
+		return
+	}
+	if grant == nil || grant.ClientID != client.ClientID || grant.RedirectURI != r.PostFormValue("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid, expired or already used code")
+		return
+	}
+	if !services.VerifyPKCE(grant.CodeChallenge, r.PostFormValue("code_verifier")) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code verifier does not match")
+		return
+	}
+
+	accessToken, tokenHash, err := services.NewOAuthSecret("sst_")
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue token")
+		return
+	}
+	err = repo.StoreOAuthGrant(models.OAuthGrant{
+		Kind:      models.OAuthGrantAccessToken,
+		TokenHash: tokenHash,
+		ClientID:  client.ClientID,
+		UserID:    grant.UserID,
+		Scopes:    grant.Scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(oauthAccessTokenTTL),
+	})
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue token")
+		return
+	}
+
+	log.Printf("[INFO] Access token issued to OAuth client %s for user with ID %s", client.ClientID, grant.UserID)
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(oauthAccessTokenTTL.Seconds()),
+		"scope":        strings.Join(grant.Scopes, " "),
+	})
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue token")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// OAuthRevokeHandler implements RFC 7009 token revocation for clients.
+// Unknown tokens are not an error, so the response never reveals validity.
+func (h *Handlers) OAuthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+	client, err := authenticateOAuthClient(r)
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+	token := r.PostFormValue("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Missing token")
+		return
+	}
+	if err := repo.RevokeOAuthAccessToken(client.ClientID, services.HashOAuthSecret(token)); err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to revoke token")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetAuthorizedAppsHandler lists the third-party clients holding live access
+// to the user's account.
+func (h *Handlers) GetAuthorizedAppsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	grants, err := repo.GetUserOAuthGrants(userId, utils.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type authorizedApp struct {
+		ClientId   string    `json:"client_id"`
+		ClientName string    `json:"client_name"`
+		Scopes     []string  `json:"scopes"`
+		GrantedAt  time.Time `json:"granted_at"`
+	}
+	apps := []authorizedApp{}
+	index := map[string]int{}
+	for _, grant := range grants {
+		if i, ok := index[grant.ClientID]; ok {
+			for _, scope := range grant.Scopes {
+				if !slices.Contains(apps[i].Scopes, scope) {
+					apps[i].Scopes = append(apps[i].Scopes, scope)
+				}
+			}
+			if grant.CreatedAt.Before(apps[i].GrantedAt) {
+				apps[i].GrantedAt = grant.CreatedAt
+			}
+			continue
+		}
+		client, err := repo.GetOAuthClient(grant.ClientID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if client == nil {
+			continue
+		}
+		index[grant.ClientID] = len(apps)
+		apps = append(apps, authorizedApp{
+			ClientId:   client.ClientID,
+			ClientName: client.Name,
+			Scopes:     append([]string{}, grant.Scopes...),
+			GrantedAt:  grant.CreatedAt,
+		})
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"apps": apps,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) RevokeAuthorizedAppHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		ClientId string `json:"client_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.ClientId == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := repo.RevokeUserOAuthGrants(userId, requestBody.ClientId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] User with ID %s revoked access for OAuth client %s", userId, requestBody.ClientId)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
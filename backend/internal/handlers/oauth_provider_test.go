@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/middlewares"
+)
+
+const oauthRedirectURI = "https://app.example.com/callback"
+
+// registerOAuthClient registers a client owned by a new user and returns the
+// owner, the client id and its secret.
+func registerOAuthClient(t *testing.T) (ownerID, clientID, secret string) {
+	t.Helper()
+	ownerID = newUser(t, nil)
+	rec := serve(h.RegisterOAuthClientHandler, ownerID, `{"name": "Publisher", "redirect_uris": ["`+oauthRedirectURI+`"]}`, nil)
+	var registered struct {
+		Client struct {
+			ClientID string `json:"client_id"`
+		} `json:"client"`
+		ClientSecret string `json:"client_secret"`
+	}
+	decodeJSON(t, rec, http.StatusCreated, &registered)
+	return ownerID, registered.Client.ClientID, registered.ClientSecret
+}
+
+// authorizeOAuth records the user's decision on an authorization request and
+// returns the query of the redirect back to the client.
+func authorizeOAuth(t *testing.T, userID, body string) url.Values {
+	t.Helper()
+	var response struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	decodeJSON(t, serve(h.OAuthAuthorizeHandler, userID, body, nil), http.StatusOK, &response)
+	redirect, err := url.Parse(response.RedirectTo)
+	if err != nil || !strings.HasPrefix(response.RedirectTo, oauthRedirectURI+"?") {
+		t.Fatalf("redirect_to %q, want the registered redirect uri", response.RedirectTo)
+	}
+	return redirect.Query()
+}
+
+// exchangeOAuthCode posts a token request authenticated as the client.
+func exchangeOAuthCode(clientID, secret string, form url.Values) *httptest.ResponseRecorder {
+	form.Set("grant_type", "authorization_code")
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, secret)
+	return serveRequest(h.OAuthTokenHandler, req, "", nil)
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestRegisterOAuthClientHandler(t *testing.T) {
+	register := func() http.HandlerFunc { return h.RegisterOAuthClientHandler }
+	runCases(t, []handlerCase{
+		{name: "no session", handler: register, body: `{}`, status: http.StatusUnauthorized},
+		{name: "no name", handler: register, setup: anyUser, body: `{"redirect_uris": ["` + oauthRedirectURI + `"]}`, status: http.StatusBadRequest, text: "Client name"},
+		{name: "no redirect uri", handler: register, setup: anyUser, body: `{"name": "Publisher"}`, status: http.StatusBadRequest, text: "redirect uri"},
+		{name: "plain http redirect uri", handler: register, setup: anyUser, body: `{"name": "Publisher", "redirect_uris": ["http://app.example.com/callback"]}`, status: http.StatusBadRequest, text: `"reason":"Invalid input"`},
+		{name: "registered", handler: register, setup: userWith(nil), body: `{"name": "Publisher", "redirect_uris": ["` + oauthRedirectURI + `"]}`, status: http.StatusCreated},
+	})
+}
+
+// TestRegisterOAuthClientErrorsQuoteRequest checks that a rejected request is
+// answered with the request id rather than the error, which is only logged.
+func TestRegisterOAuthClientErrorsQuoteRequest(t *testing.T) {
+	requireMongo(t)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "Publisher", "redirect_uris": ["http://internal.example.com/callback"]}`))
+	req.Header.Set(middlewares.RequestIDHeader, "req-1")
+	register := middlewares.RequestIDMiddleware(http.HandlerFunc(h.RegisterOAuthClientHandler))
+	var response struct {
+		Reason    string `json:"reason"`
+		RequestID string `json:"request_id"`
+	}
+	rec := serveRequest(register.ServeHTTP, req, newUser(t, nil), nil)
+	decodeJSON(t, rec, http.StatusBadRequest, &response)
+	if response.Reason != "Invalid input" || response.RequestID != "req-1" || strings.Contains(rec.Body.String(), "internal.example.com") {
+		t.Errorf("body %q, want a generic reason and the request id", rec.Body.String())
+	}
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	_, clientID, secret := registerOAuthClient(t)
+	userID := newUser(t, nil)
+	verifier := "a-code-verifier-long-enough-for-pkce-0123456789"
+	request := `"client_id": "` + clientID + `", "redirect_uri": "` + oauthRedirectURI + `", "response_type": "code", "scope": "shares:read", "state": "xyz", "code_challenge": "` + pkceChallenge(verifier) + `", "code_challenge_method": "S256"`
+
+	consent := httptest.NewRequest(http.MethodGet, "/?"+url.Values{
+		"client_id":     {clientID},
+		"redirect_uri":  {oauthRedirectURI},
+		"response_type": {"code"},
+		"scope":         {"shares:read"},
+	}.Encode(), nil)
+	var described struct {
+		ClientName string `json:"client_name"`
+	}
+	decodeJSON(t, serveRequest(h.OAuthConsentHandler, consent, userID, nil), http.StatusOK, &described)
+	if described.ClientName != "Publisher" {
+		t.Errorf("consent screen names the client %q", described.ClientName)
+	}
+
+	denied := authorizeOAuth(t, userID, `{`+request+`, "approve": false}`)
+	if denied.Get("error") != "access_denied" || denied.Get("code") != "" || denied.Get("state") != "xyz" {
+		t.Errorf("denied redirect query = %v", denied)
+	}
+
+	approved := authorizeOAuth(t, userID, `{`+request+`, "approve": true}`)
+	code := approved.Get("code")
+	if code == "" || approved.Get("state") != "xyz" {
+		t.Fatalf("approved redirect query = %v", approved)
+	}
+
+	form := url.Values{"code": {code}, "redirect_uri": {oauthRedirectURI}, "code_verifier": {verifier}}
+	if rec := exchangeOAuthCode(clientID, "sscs_wrong", form); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "invalid_client") {
+		t.Errorf("wrong client secret: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	wrongVerifier := url.Values{"code": {code}, "redirect_uri": {oauthRedirectURI}, "code_verifier": {verifier + "x"}}
+	if rec := exchangeOAuthCode(clientID, secret, wrongVerifier); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_grant") {
+		t.Errorf("wrong code verifier: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	// The failed exchange used the code up
+	approved = authorizeOAuth(t, userID, `{`+request+`, "approve": true}`)
+	form.Set("code", approved.Get("code"))
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		Scope       string `json:"scope"`
+	}
+	decodeJSON(t, exchangeOAuthCode(clientID, secret, form), http.StatusOK, &token)
+	if token.AccessToken == "" || token.TokenType != "Bearer" || token.Scope != "shares:read" {
+		t.Errorf("token response = %+v", token)
+	}
+	if rec := exchangeOAuthCode(clientID, secret, form); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_grant") {
+		t.Errorf("reused code: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	var apps struct {
+		Apps []struct {
+			ClientID string `json:"client_id"`
+		} `json:"apps"`
+	}
+	decodeJSON(t, serve(h.GetAuthorizedAppsHandler, userID, "", nil), http.StatusOK, &apps)
+	if len(apps.Apps) != 1 || apps.Apps[0].ClientID != clientID {
+		t.Fatalf("authorized apps = %+v", apps.Apps)
+	}
+	if rec := serve(h.RevokeAuthorizedAppHandler, userID, `{"client_id": "`+clientID+`"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("revoking the app: status %d", rec.Code)
+	}
+	decodeJSON(t, serve(h.GetAuthorizedAppsHandler, userID, "", nil), http.StatusOK, &apps)
+	if len(apps.Apps) != 0 {
+		t.Errorf("authorized apps after revoking = %+v", apps.Apps)
+	}
+}
+
+func TestOAuthAuthorizeRejectsUnregisteredRedirect(t *testing.T) {
+	_, clientID, _ := registerOAuthClient(t)
+	rec := serve(h.OAuthAuthorizeHandler, newUser(t, nil), `{"client_id": "`+clientID+`", "redirect_uri": "https://attacker.example.com/", "response_type": "code", "scope": "shares:read", "approve": true}`, nil)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Location") != "" {
+		t.Errorf("status %d, location %q; want the error shown rather than redirected", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+// TestOAuthTokenRejectsVerifierWithoutChallenge checks that a code issued
+// without PKCE can't be exchanged with a verifier, which would mean the
+// challenge was stripped from the authorization request.
+func TestOAuthTokenRejectsVerifierWithoutChallenge(t *testing.T) {
+	_, clientID, secret := registerOAuthClient(t)
+	userID := newUser(t, nil)
+	request := `"client_id": "` + clientID + `", "redirect_uri": "` + oauthRedirectURI + `", "response_type": "code", "scope": "shares:read", "approve": true`
+
+	code := authorizeOAuth(t, userID, `{`+request+`}`).Get("code")
+	form := url.Values{"code": {code}, "redirect_uri": {oauthRedirectURI}, "code_verifier": {"a-code-verifier-long-enough-for-pkce-0123456789"}}
+	if rec := exchangeOAuthCode(clientID, secret, form); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_grant") {
+		t.Errorf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	code = authorizeOAuth(t, userID, `{`+request+`}`).Get("code")
+	if rec := exchangeOAuthCode(clientID, secret, url.Values{"code": {code}, "redirect_uri": {oauthRedirectURI}}); rec.Code != http.StatusOK {
+		t.Errorf("without PKCE: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
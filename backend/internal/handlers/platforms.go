@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/services"
+)
+
+// GetPlatformsHandler lists the platforms blogs are shared to with what
+// their posts can hold, in the order they are shown to users, so clients
+// needn't hard-code them. Platforms the operators turned off are flagged
+// with the reason why. It describes the service rather than a user, so no
+// session is needed.
+func (h *Handlers) GetPlatformsHandler(w http.ResponseWriter, r *http.Request) {
+	type platformInfo struct {
+		Name  string `json:"name"`
+		Title string `json:"title"`
+		services.PlatformCapabilities
+		Disabled       bool   `json:"disabled"`
+		DisabledReason string `json:"disabled_reason,omitempty"`
+	}
+	platforms := []platformInfo{}
+	for _, platform := range services.Platforms() {
+		reason, disabled := config.Get().PlatformDisabled(platform.Name())
+		platforms = append(platforms, platformInfo{platform.Name(), platform.Title(), platform.Capabilities(), disabled, reason})
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"platforms": platforms,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// Short, so platforms turned off or back on show up soon
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// userDTO is the user's view with the platforms turned off flagged.
+func userDTO(user *models.User) models.UserDTO {
+	dto := user.ToDTO()
+	dto.DisabledPlatforms = services.DisabledPlatforms()
+	return dto
+}
+
+// userProfileDTO is the user's profile with the platforms turned off
+// flagged.
+func userProfileDTO(user *models.User) models.UserProfileDTO {
+	dto := user.ToProfileDTO()
+	dto.DisabledPlatforms = services.DisabledPlatforms()
+	return dto
+}
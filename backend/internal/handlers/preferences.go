@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func (h *Handlers) GetUserPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		log.Printf("[ERROR] User with id: %s not found", userId)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	responseJson, err := json.Marshal(user.Preferences)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) UpdateUserPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		log.Printf("[ERROR] User with id: %s not found", userId)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Channels left out of the request keep their setting
+	var preferences struct {
+		models.Preferences
+		ScheduledShareEmail *bool                   `json:"scheduled_share_email"`
+		LoginAlertEmail     *bool                   `json:"login_alert_email"`
+		Retention           *models.RetentionPolicy `json:"retention"`
+		AutoShare           *string                 `json:"auto_share"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	seen := map[string]bool{}
+	defaultPlatforms := []string{}
+	for _, platform := range preferences.DefaultPlatforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if !services.IsValidPlatform(platform) {
+			http.Error(w, "Invalid platform: "+platform, http.StatusBadRequest)
+			return
+		}
+		if seen[platform] {
+			continue
+		}
+		seen[platform] = true
+		defaultPlatforms = append(defaultPlatforms, platform)
+	}
+	user.Preferences.DefaultPlatforms = defaultPlatforms
+	if preferences.ScheduledShareEmail != nil {
+		if *preferences.ScheduledShareEmail && (user.Email == "" || !user.EmailVerified) {
+			http.Error(w, `{"error": "A verified email address is required for email notifications"}`, http.StatusBadRequest)
+			return
+		}
+		user.Preferences.ScheduledShareEmail = *preferences.ScheduledShareEmail
+	}
+	if preferences.LoginAlertEmail != nil {
+		if *preferences.LoginAlertEmail && (user.Email == "" || !user.EmailVerified) {
+			http.Error(w, `{"error": "A verified email address is required for email notifications"}`, http.StatusBadRequest)
+			return
+		}
+		user.Preferences.LoginAlertEmail = *preferences.LoginAlertEmail
+	}
+	if preferences.Retention != nil {
+		if err := preferences.Retention.Validate(); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		if *preferences.Retention != user.Preferences.Retention {
+			user.Preferences.Retention = *preferences.Retention
+			// Enforce the new policy on the retention worker's next pass
+			user.RetentionDueAt = utils.Now()
+		}
+	}
+
+	if preferences.AutoShare != nil {
+		switch *preferences.AutoShare {
+		case models.AutoShareOff, models.AutoSharePublish, models.AutoShareApprove:
+			user.Preferences.AutoShare = *preferences.AutoShare
+		default:
+			http.Error(w, `{"error": "auto_share must be empty, \"share\" or \"approve\""}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = repo.UpdateUser(userId, user)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[INFO] Preferences updated for the user with ID %s", userId)
+	responseJson, err := json.Marshal(user.Preferences)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
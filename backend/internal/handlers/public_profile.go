@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+// GetPublicProfileHandler serves the public page of a user who turned theirs
+// on. Users who didn't look the same as users who don't exist.
+func (h *Handlers) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimSpace(mux.Vars(r)["handle"])
+	user, err := repo.GetUserByName(handle)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get the user with name %q for their public profile: %v", handle, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.Disabled || !user.PublicProfile.Enabled {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	responseJson, err := json.Marshal(user.ToPublicProfileDTO())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func writePublicProfileSettings(w http.ResponseWriter, profile models.PublicProfile) {
+	if profile.HiddenBlogs == nil {
+		profile.HiddenBlogs = []string{}
+	}
+	responseJson, err := json.Marshal(profile)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetPublicProfileSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writePublicProfileSettings(w, user.PublicProfile)
+}
+
+// UpdatePublicProfileSettingsHandler replaces the settings of the user's
+// public page: whether it is on, the bio, and which shares appear.
+func (h *Handlers) UpdatePublicProfileSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var profile models.PublicProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	profile.Bio = strings.TrimSpace(profile.Bio)
+	hidden := []string{}
+	for _, blogId := range profile.HiddenBlogs {
+		blogId = strings.TrimSpace(blogId)
+		if blogId != "" && !containsString(hidden, blogId) {
+			hidden = append(hidden, blogId)
+		}
+	}
+	profile.HiddenBlogs = hidden
+	if err := profile.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	wasEnabled := user.PublicProfile.Enabled
+	user.PublicProfile = profile
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if profile.Enabled && !wasEnabled {
+		log.Printf("[INFO] User with ID %s made their profile public", userId)
+	} else if !profile.Enabled && wasEnabled {
+		log.Printf("[INFO] User with ID %s made their profile private", userId)
+	}
+	writePublicProfileSettings(w, profile)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/metrics"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const (
+	maxQueueImportBytes = 1 << 20
+	maxQueueImportRows  = 500
+)
+
+// ImportQueueHandler schedules the posts of a Buffer or Hootsuite queue export.
+// The CSV is sent as the "file" field of a multipart form or as a text/csv
+// body. Links are matched against the user's Hashnode posts, and rows that
+// can't be scheduled are listed in the response instead of failing the import,
+// including times that the clocks skip or repeat in the import's timezone.
+// With dry_run=true nothing is scheduled.
+func (h *Handlers) ImportQueueHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.Verified || user.HashnodeBlog == "" {
+		http.Error(w, "User is not verified", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	// Without a timezone, times are read in the one of the user's posting
+	// goal
+	loc := time.UTC
+	zone := query.Get("timezone")
+	if zone == "" && user.PostingGoal != nil {
+		zone = user.PostingGoal.Timezone
+	}
+	if zone != "" {
+		if loc, err = time.LoadLocation(zone); err != nil {
+			http.Error(w, `{"error": "Invalid timezone"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	defaultPlatforms := []string{}
+	if platforms := query.Get("platforms"); platforms != "" {
+		defaultPlatforms = strings.Split(platforms, ",")
+		for _, platform := range defaultPlatforms {
+			if !services.IsValidPlatform(platform) {
+				http.Error(w, fmt.Sprintf(`{"error": "Unsupported platform %q"}`, platform), http.StatusBadRequest)
+				return
+			}
+		}
+	} else {
+		if user.LinkedinVerified {
+			defaultPlatforms = append(defaultPlatforms, "linkedin")
+		}
+		if user.XVerified {
+			defaultPlatforms = append(defaultPlatforms, "twitter")
+		}
+		if user.MastodonVerified {
+			defaultPlatforms = append(defaultPlatforms, "mastodon")
+		}
+		if user.RedditVerified && user.Reddit.Subreddit != "" {
+			defaultPlatforms = append(defaultPlatforms, "reddit")
+		}
+		if user.DiscordVerified {
+			defaultPlatforms = append(defaultPlatforms, "discord")
+		}
+		if user.NostrVerified {
+			defaultPlatforms = append(defaultPlatforms, "nostr")
+		}
+		if user.LemmyVerified && len(user.Lemmy.Communities) > 0 {
+			defaultPlatforms = append(defaultPlatforms, "lemmy")
+		}
+		if user.MatrixVerified {
+			defaultPlatforms = append(defaultPlatforms, "matrix")
+		}
+	}
+	dryRun := query.Get("dry_run") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxQueueImportBytes)
+	var export io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, `{"error": "Missing CSV file"}`, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		export = file
+	}
+	rows, rowErrors, err := services.ParseQueueExport(export, loc)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if len(rows)+len(rowErrors) > maxQueueImportRows {
+		http.Error(w, fmt.Sprintf(`{"error": "At most %d rows can be imported at once"}`, maxQueueImportRows), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	postsByURL := map[string]models.PostNode{}
+	for _, host := range services.PublicationHosts(user) {
+		posts, err := services.FetchPublicationPosts(host)
+		if err != nil {
+			log.Printf("[ERROR] Failed to fetch posts of %s: %v", host, err)
+			http.Error(w, `{"error": "Failed to load Hashnode posts"}`, http.StatusBadGateway)
+			return
+		}
+		for _, post := range posts {
+			postsByURL[services.NormalizePostURL(post.URL)] = post
+		}
+	}
+	alreadyScheduled := map[string]bool{}
+	for _, blog := range user.ScheduledBlogs {
+		alreadyScheduled[blog.Id] = true
+	}
+
+	// Buffer exports a row per profile, so rows sharing a post and time are
+	// merged into one scheduled share
+	var planned []*models.ScheduledBlog
+	plannedRows := map[string]int{}
+	byPost := map[string]*models.ScheduledBlog{}
+	reject := func(line int, reason string) {
+		rowErrors = append(rowErrors, services.QueueRowError{Line: line, Reason: reason})
+	}
+	for _, row := range rows {
+		if row.Link == "" {
+			reject(row.Line, "no link to a Hashnode post")
+			continue
+		}
+		post, ok := postsByURL[services.NormalizePostURL(row.Link)]
+		if !ok {
+			reject(row.Line, fmt.Sprintf("%s is not a post of your Hashnode publications", row.Link))
+			continue
+		}
+		if alreadyScheduled[post.ID] {
+			reject(row.Line, fmt.Sprintf("%q is already scheduled", post.Title))
+			continue
+		}
+		platforms := row.Platforms
+		if len(platforms) == 0 {
+			platforms = defaultPlatforms
+		}
+		if blog, ok := byPost[post.ID]; ok {
+			if !blog.ScheduledTime.Equal(row.ScheduledTime) {
+				reject(row.Line, fmt.Sprintf("%q is already imported from row %d at another time", post.Title, plannedRows[post.ID]))
+				continue
+			}
+			for _, platform := range platforms {
+				if !containsString(blog.Platforms, platform) {
+					blog.Platforms = append(blog.Platforms, platform)
+				}
+			}
+			continue
+		}
+
+		blog := &models.ScheduledBlog{
+			Blog: models.Blog{
+				Id:                post.ID,
+				Title:             post.Title,
+				Url:               post.URL,
+				CoverImage:        models.Image{URL: post.CoverImage.URL},
+				Author:            post.Author,
+				ReadTimeInMinutes: post.ReadTimeInMinutes,
+			},
+			Platforms:     append([]string{}, platforms...),
+			ScheduledTime: row.ScheduledTime,
+			Timezone:      loc.String(),
+		}
+		if err := blog.Validate(); err != nil {
+			reject(row.Line, err.Error())
+			continue
+		}
+		blog.AnchorLocalTime()
+		if !config.Get().PostingWindow.Allows(blog.ScheduledTime) {
+			reject(row.Line, "scheduled time is outside the allowed posting window")
+			continue
+		}
+		byPost[post.ID] = blog
+		plannedRows[post.ID] = row.Line
+		planned = append(planned, blog)
+	}
+
+	scheduled := []models.ScheduledBlog{}
+	for _, blog := range planned {
+		if !dryRun {
+			err := h.taskScheduler.AddTask(models.ScheduledBlogData{UserID: userId, ScheduledBlog: *blog})
+			if err != nil {
+				log.Printf("[WARN] Failed to schedule imported blog %s for user %s: %v", blog.Id, userId, err)
+				reject(plannedRows[blog.Id], "failed to schedule the post")
+				continue
+			}
+			user.ScheduledBlogs = append(user.ScheduledBlogs, *blog)
+			if schedules, err := metrics.Schedules.GetMetricWithLabelValues(metrics.Cohort(user)); err != nil {
+				log.Printf("[WARN] Failed to count the schedule: %v", err)
+			} else {
+				schedules.Inc()
+			}
+		}
+		scheduled = append(scheduled, *blog)
+	}
+	if !dryRun && len(scheduled) > 0 {
+		if err := repo.UpdateUser(userId, user); err != nil {
+			log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[INFO] User with ID %s imported %d scheduled blogs", userId, len(scheduled))
+	}
+
+	if rowErrors == nil {
+		rowErrors = []services.QueueRowError{}
+	}
+	sort.Slice(rowErrors, func(i, j int) bool { return rowErrors[i].Line < rowErrors[j].Line })
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"success":   true,
+		"dry_run":   dryRun,
+		"scheduled": scheduled,
+		"errors":    rowErrors,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	redditStateCookie  = "reddit_oauth_state"
+	maxRedditFlairText = 64
+)
+
+// ConnectRedditHandler starts the Reddit OAuth flow. Reddit access tokens
+// last an hour, so a permanent grant is asked for to get a refresh token.
+func (h *Handlers) ConnectRedditHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	state := uuid.New().String()
+	if err := repo.SetCache(state, userId, 10*time.Minute); err != nil {
+		log.Printf("[ERROR] Failed to store state in cache: %v", err)
+		http.Error(w, "Failed to store state in cache", http.StatusInternalServerError)
+		return
+	}
+	setStateCookie(w, redditStateCookie, state, 10*time.Minute)
+
+	authURL := h.redditConfig.AuthCodeURL(state, oauth2.SetAuthURLParam("duration", "permanent"))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handlers) RedditCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	queryState := r.URL.Query().Get("state")
+	stateCookie, err := r.Cookie(redditStateCookie)
+	if err != nil || queryState == "" || stateCookie.Value != queryState {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	stateUser, exists := repo.GetCache(queryState)
+	if !exists || stateUser != userId {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	if err := repo.DeleteCache(queryState); err != nil {
+		log.Printf("[WARN] Failed to delete state from cache for the user id: %s and error is %s", userId, err)
+	}
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		log.Printf("[INFO] User %s didn't connect Reddit: %s", userId, reason)
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?reddit_error="+url.QueryEscape(reason), http.StatusSeeOther)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		log.Printf("[ERROR] Missing authorization code")
+		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	token, err := h.redditConfig.Exchange(services.RedditContext(context.Background()), code)
+	if err != nil {
+		log.Printf("[ERROR] Failed to exchange the Reddit code of user %s: %v", userId, err)
+		http.Error(w, "Failed to exchange token", http.StatusBadGateway)
+		return
+	}
+	username, err := services.RedditUsername(userId, token.AccessToken)
+	if err != nil {
+		log.Printf("[ERROR] Failed to connect the Reddit account of user %s: %v", userId, err)
+		http.Error(w, "Failed to reach Reddit", http.StatusBadGateway)
+		return
+	}
+
+	// The subreddit and flair outlive reconnecting
+	user.Reddit.Username = username
+	if err := services.SetRedditTokens(user, token.AccessToken, token.RefreshToken, token.Expiry); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.RedditVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected to Reddit as u/%s", userId, username)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "reddit", "action": "connected", "account": "u/" + username})
+
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
+}
+
+// UpdateRedditSettingsHandler chooses the subreddit shares are posted to and
+// the flair they get. A flair is checked against the subreddit's flairs.
+func (h *Handlers) UpdateRedditSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Subreddit string `json:"subreddit"`
+		FlairID   string `json:"flair_id"`
+		FlairText string `json:"flair_text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	subreddit, err := services.NormalizeSubreddit(requestBody.Subreddit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	requestBody.FlairID = strings.TrimSpace(requestBody.FlairID)
+	requestBody.FlairText = strings.TrimSpace(requestBody.FlairText)
+	if len([]rune(requestBody.FlairText)) > maxRedditFlairText {
+		http.Error(w, "flair_text must be at most 64 characters", http.StatusBadRequest)
+		return
+	}
+	if requestBody.FlairText != "" && requestBody.FlairID == "" {
+		http.Error(w, "flair_text needs a flair_id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.RedditVerified {
+		http.Error(w, "Connect Reddit first", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.FlairID != "" {
+		flairs, err := services.RedditFlairs(user, subreddit)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var flair *services.RedditFlair
+		for i := range flairs {
+			if flairs[i].ID == requestBody.FlairID {
+				flair = &flairs[i]
+				break
+			}
+		}
+		if flair == nil {
+			http.Error(w, "r/"+subreddit+" has no flair "+requestBody.FlairID, http.StatusBadRequest)
+			return
+		}
+		if requestBody.FlairText != "" && !flair.Editable {
+			http.Error(w, "The text of this flair can't be changed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user.Reddit.Subreddit = subreddit
+	user.Reddit.FlairID = requestBody.FlairID
+	user.Reddit.FlairText = requestBody.FlairText
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s now shares to r/%s", userId, subreddit)
+
+	responseJson, err := json.Marshal(user.Reddit)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetRedditFlairsHandler lists the link flairs of the subreddit in the query,
+// or of the chosen one.
+func (h *Handlers) GetRedditFlairsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.RedditVerified {
+		http.Error(w, "Connect Reddit first", http.StatusBadRequest)
+		return
+	}
+	raw := r.URL.Query().Get("subreddit")
+	if raw == "" {
+		raw = user.Reddit.Subreddit
+	}
+	subreddit, err := services.NormalizeSubreddit(raw)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flairs, err := services.RedditFlairs(user, subreddit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"subreddit": subreddit,
+		"flairs":    flairs,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimContentType  = "application/scim+json"
+	scimMaxPageCount = 100
+)
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	Location     string    `json:"location"`
+}
+
+// scimUser is the SCIM 2.0 (RFC 7643) view of a team member. The email is
+// the userName, as that is what IdPs key members on.
+type scimUser struct {
+	Schemas    []string    `json:"schemas"`
+	Id         string      `json:"id,omitempty"`
+	ExternalId string      `json:"externalId,omitempty"`
+	UserName   string      `json:"userName"`
+	Active     *bool       `json:"active,omitempty"`
+	Emails     []scimEmail `json:"emails,omitempty"`
+	Meta       *scimMeta   `json:"meta,omitempty"`
+}
+
+func (u scimUser) email() string {
+	if strings.Contains(u.UserName, "@") {
+		return strings.ToLower(strings.TrimSpace(u.UserName))
+	}
+	for _, email := range u.Emails {
+		if email.Primary {
+			return strings.ToLower(strings.TrimSpace(email.Value))
+		}
+	}
+	if len(u.Emails) > 0 {
+		return strings.ToLower(strings.TrimSpace(u.Emails[0].Value))
+	}
+	return ""
+}
+
+func scimBaseURL() string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/scim/v2"
+}
+
+func toSCIMUser(user *models.User) scimUser {
+	active := !user.Disabled
+	return scimUser{
+		Schemas:    []string{scimUserSchema},
+		Id:         user.Id.Hex(),
+		ExternalId: user.SCIMExternalID,
+		UserName:   user.Email,
+		Active:     &active,
+		Emails:     []scimEmail{{Value: user.Email, Primary: true}},
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			Location:     scimBaseURL() + "/Users/" + user.Id.Hex(),
+		},
+	}
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	responseJson, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	w.Write(responseJson)
+}
+
+// writeSCIMError writes the error response defined by RFC 7644 section 3.12.
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+func writeSCIMAppError(w http.ResponseWriter, err error) {
+	status := apperrors.HTTPStatus(err)
+	detail := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[ERROR] %v", err)
+		detail = "Internal server error"
+	}
+	writeSCIMError(w, status, "", detail)
+}
+
+// authenticateSCIM resolves the team from the SCIM bearer token.
+func authenticateSCIM(r *http.Request) (*models.Team, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, fmt.Errorf("missing SCIM bearer token: %w", apperrors.ErrUnauthorized)
+	}
+	team, err := repo.GetTeamBySCIMTokenHash(services.HashOAuthSecret(token))
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, fmt.Errorf("invalid SCIM bearer token: %w", apperrors.ErrUnauthorized)
+	}
+	return team, nil
+}
+
+// loadSCIMMember authenticates the IdP and loads the team member named in
+// the path.
+func loadSCIMMember(r *http.Request) (*models.Team, *models.User, error) {
+	team, err := authenticateSCIM(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	userId := mux.Vars(r)["id"]
+	user, err := repo.GetUserById(userId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user %s: %w", userId, apperrors.ErrNotFound)
+	}
+	if user == nil || user.TeamID != team.Id.Hex() {
+		return nil, nil, fmt.Errorf("user %s: %w", userId, apperrors.ErrNotFound)
+	}
+	return team, user, nil
+}
+
+// teamOwnsEmailDomain reports whether the email is in one of the team's
+// verified domains; IdPs may only provision identities the team owns.
+func teamOwnsEmailDomain(team *models.Team, email string) bool {
+	domain := services.EmailDomain(email)
+	for _, teamDomain := range team.Domains {
+		if teamDomain.Domain == domain && teamDomain.Verified {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSCIMTokenHandler issues the team's SCIM bearer token, replacing any
+// previous one. The token is only shown once.
+func (h *Handlers) CreateSCIMTokenHandler(w http.ResponseWriter, r *http.Request) {
+	_, team, err := loadTeamAdmin(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	token, tokenHash, err := services.NewOAuthSecret("scim_")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	team.SCIMTokenHash = tokenHash
+	if err := repo.UpdateTeam(team); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] SCIM token rotated for team %s", team.Id.Hex())
+
+	responseJson, err := json.Marshal(map[string]string{
+		"token":    token,
+		"base_url": scimBaseURL(),
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+// ListSCIMUsersHandler lists team members, supporting the `userName eq` and
+// `externalId eq` filters IdPs use to look up existing accounts.
+func (h *Handlers) ListSCIMUsersHandler(w http.ResponseWriter, r *http.Request) {
+	team, err := authenticateSCIM(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	query := r.URL.Query()
+
+	var filter repo.TeamMemberFilter
+	if raw := strings.TrimSpace(query.Get("filter")); raw != "" {
+		parts := strings.SplitN(raw, " ", 3)
+		if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+			writeSCIMError(w, http.StatusBadRequest, "invalidFilter", "Only 'userName eq' and 'externalId eq' filters are supported")
+			return
+		}
+		value := strings.Trim(parts[2], `"`)
+		switch parts[0] {
+		case "userName":
+			filter.Email = strings.ToLower(value)
+		case "externalId":
+			filter.ExternalID = value
+		default:
+			writeSCIMError(w, http.StatusBadRequest, "invalidFilter", "Only 'userName eq' and 'externalId eq' filters are supported")
+			return
+		}
+	}
+
+	// startIndex is 1-based per RFC 7644 section 3.4.2.4
+	startIndex, _ := strconv.Atoi(query.Get("startIndex"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count > scimMaxPageCount {
+		count = scimMaxPageCount
+	}
+	if count < 0 {
+		count = 0
+	}
+	members, total, err := repo.ListTeamMembers(team.Id.Hex(), filter, int64(startIndex-1), int64(count))
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	page := make([]scimUser, len(members))
+	for i := range members {
+		page[i] = toSCIMUser(&members[i])
+	}
+
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(page),
+		"Resources":    page,
+	})
+}
+
+func (h *Handlers) GetSCIMUserHandler(w http.ResponseWriter, r *http.Request) {
+	_, user, err := loadSCIMMember(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(user))
+}
+
+// CreateSCIMUserHandler provisions a team member ahead of their first SSO
+// login, which then links to this account by email.
+func (h *Handlers) CreateSCIMUserHandler(w http.ResponseWriter, r *http.Request) {
+	team, err := authenticateSCIM(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	var requestBody scimUser
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	email := requestBody.email()
+	if !teamOwnsEmailDomain(team, email) {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName must be an email in a verified team domain")
+		return
+	}
+	existing, err := repo.GetTeamMemberByEmail(team.Id.Hex(), email)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	if existing != nil {
+		writeSCIMError(w, http.StatusConflict, "uniqueness", "A member with this userName already exists")
+		return
+	}
+
+	user := &models.User{
+		Email:          email,
+		TeamID:         team.Id.Hex(),
+		TeamRole:       services.MapTeamRole(team.SSO, nil),
+		SCIMExternalID: requestBody.ExternalId,
+		Disabled:       requestBody.Active != nil && !*requestBody.Active,
+		Region:         team.Region,
+	}
+	if err := createExternalUser(user); err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	log.Printf("[INFO] SCIM provisioned user %s into team %s", user.Id.Hex(), team.Id.Hex())
+	writeSCIM(w, http.StatusCreated, toSCIMUser(user))
+}
+
+// ReplaceSCIMUserHandler applies a full SCIM PUT. Only the attributes we map
+// are replaced; setting active to false suspends the member.
+func (h *Handlers) ReplaceSCIMUserHandler(w http.ResponseWriter, r *http.Request) {
+	team, user, err := loadSCIMMember(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	var requestBody scimUser
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	email := requestBody.email()
+	if !teamOwnsEmailDomain(team, email) {
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName must be an email in a verified team domain")
+		return
+	}
+	user.Email = email
+	user.SCIMExternalID = requestBody.ExternalId
+	active := requestBody.Active == nil || *requestBody.Active
+
+	if err := h.applySCIMActive(user, active); err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(user))
+}
+
+// PatchSCIMUserHandler applies the replace operations IdPs send to update or
+// deactivate a member. Both the path form and the path-less value object
+// form of RFC 7644 section 3.5.2.3 are accepted.
+func (h *Handlers) PatchSCIMUserHandler(w http.ResponseWriter, r *http.Request) {
+	team, user, err := loadSCIMMember(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	var requestBody struct {
+		Schemas    []string `json:"schemas"`
+		Operations []struct {
+			Op    string          `json:"op"`
+			Path  string          `json:"path"`
+			Value json.RawMessage `json:"value"`
+		} `json:"Operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	active := !user.Disabled
+	for _, operation := range requestBody.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "replace" && op != "add" {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", fmt.Sprintf("Unsupported operation %q", operation.Op))
+			return
+		}
+		values := map[string]json.RawMessage{}
+		if operation.Path != "" {
+			values[operation.Path] = operation.Value
+		} else if err := json.Unmarshal(operation.Value, &values); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Operation value must be an object when path is omitted")
+			return
+		}
+		for path, raw := range values {
+			switch path {
+			case "active":
+				parsed, ok := parseSCIMBool(raw)
+				if !ok {
+					writeSCIMError(w, http.StatusBadRequest, "invalidValue", "active must be a boolean")
+					return
+				}
+				active = parsed
+			case "externalId":
+				var externalId string
+				if err := json.Unmarshal(raw, &externalId); err != nil {
+					writeSCIMError(w, http.StatusBadRequest, "invalidValue", "externalId must be a string")
+					return
+				}
+				user.SCIMExternalID = externalId
+			case "userName":
+				var email string
+				if err := json.Unmarshal(raw, &email); err != nil || !teamOwnsEmailDomain(team, strings.ToLower(email)) {
+					writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName must be an email in a verified team domain")
+					return
+				}
+				user.Email = strings.ToLower(email)
+			default:
+				// Attributes we don't store, such as name or displayName, are ignored
+			}
+		}
+	}
+
+	if err := h.applySCIMActive(user, active); err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(user))
+}
+
+// DeleteSCIMUserHandler deprovisions the member and removes them from the team.
+func (h *Handlers) DeleteSCIMUserHandler(w http.ResponseWriter, r *http.Request) {
+	team, user, err := loadSCIMMember(r)
+	if err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	if user.TeamRole == models.TeamRoleOwner {
+		writeSCIMError(w, http.StatusForbidden, "mutability", "The team owner cannot be deprovisioned")
+		return
+	}
+	if err := h.deprovisionUser(user); err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	if err := repo.RemoveTeamMember(user.Id.Hex()); err != nil {
+		writeSCIMAppError(w, err)
+		return
+	}
+	log.Printf("[INFO] SCIM removed user %s from team %s", user.Id.Hex(), team.Id.Hex())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseSCIMBool accepts JSON booleans as well as the "True"/"False" strings
+// some IdPs send.
+func parseSCIMBool(raw json.RawMessage) (bool, bool) {
+	var value bool
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, true
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return false, false
+	}
+	value, err := strconv.ParseBool(strings.ToLower(text))
+	return value, err == nil
+}
+
+// applySCIMActive stores the member. Deactivating only suspends them, since
+// an IdP may reactivate them; deleting the member is what removes their data.
+func (h *Handlers) applySCIMActive(user *models.User, active bool) error {
+	switch {
+	case !active && !user.Disabled:
+		if user.TeamRole == models.TeamRoleOwner {
+			return fmt.Errorf("the team owner cannot be deactivated: %w", apperrors.ErrForbidden)
+		}
+		return h.suspendUser(user)
+	case active && user.Disabled:
+		h.requeueSchedules(user)
+	}
+	user.Disabled = !active
+	return repo.UpdateUser(user.Id.Hex(), user)
+}
+
+// suspendUser disables the account, dequeues its schedules and revokes what
+// would let anyone act as the user: sessions, OAuth grants and API keys. The
+// schedules stay on the user, to be queued again on reactivation.
+func (h *Handlers) suspendUser(user *models.User) error {
+	userId := user.Id.Hex()
+	for _, blog := range user.ScheduledBlogs {
+		if err := h.taskScheduler.RemoveTask(userId, blog.Id); err != nil {
+			log.Printf("[WARN] Failed to remove scheduled task %s of deactivated user %s: %v", blog.Id, userId, err)
+		}
+	}
+	user.Disabled = true
+	if err := repo.UpdateUser(userId, user); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserSessions(user.Id); err != nil {
+		return err
+	}
+	if err := repo.RevokeAllUserOAuthGrants(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserAPIKeys(userId); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Deactivated user %s", userId)
+	return nil
+}
+
+// requeueSchedules queues the schedules of a reactivated member again. Those
+// that fell due while they were deactivated are dropped rather than shared
+// late, and the member is told.
+func (h *Handlers) requeueSchedules(user *models.User) {
+	userId := user.Id.Hex()
+	now := utils.Now()
+	kept := []models.ScheduledBlog{}
+	for _, blog := range user.ScheduledBlogs {
+		data := models.ScheduledBlogData{UserID: userId, ScheduledBlog: blog}
+		missed := false
+		for i, task := range data.Tasks() {
+			if len(blog.Children) > 0 && blog.Children[i].Status != models.SchedulePending {
+				continue
+			}
+			if !task.ScheduledBlog.ScheduledTime.After(now) {
+				missed = true
+				if len(blog.Children) > 0 {
+					blog.Children[i].Status = models.ScheduleFailed
+					blog.Children[i].Error = "missed while the account was deactivated"
+				}
+				continue
+			}
+			if err := h.taskScheduler.AddTask(task); err != nil && !errors.Is(err, apperrors.ErrConflict) {
+				log.Printf("[WARN] Failed to queue scheduled task %s of reactivated user %s: %v", blog.Id, userId, err)
+			}
+		}
+		if missed {
+			user.AddNotification(fmt.Sprintf("The scheduled share of %q fell due while your account was deactivated and was not posted", blog.Title), now)
+		}
+		status := blog.RollupStatus()
+		if (len(blog.Children) == 0 && missed) || (status != models.SchedulePending && status != models.ScheduleInProgress) {
+			continue
+		}
+		kept = append(kept, blog)
+	}
+	user.ScheduledBlogs = kept
+}
+
+// deprovisionUser disables the account and tears down everything that would
+// keep acting on the user's behalf: queued schedules, deferred shares,
+// sessions and OAuth grants.
+func (h *Handlers) deprovisionUser(user *models.User) error {
+	userId := user.Id.Hex()
+	for _, blog := range user.ScheduledBlogs {
+		if err := h.taskScheduler.RemoveTask(userId, blog.Id); err != nil {
+			log.Printf("[WARN] Failed to remove scheduled task %s of deprovisioned user %s: %v", blog.Id, userId, err)
+		}
+	}
+	user.ScheduledBlogs = []models.ScheduledBlog{}
+	user.Disabled = true
+	if err := repo.UpdateUser(userId, user); err != nil {
+		return err
+	}
+
+	if err := repo.DeleteUserSessions(user.Id); err != nil {
+		return err
+	}
+	if err := repo.RevokeAllUserOAuthGrants(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserDeferredShares(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserManualTasks(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserPosts(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserAPIKeys(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserCampaigns(userId); err != nil {
+		return err
+	}
+	if err := repo.DeleteUserNewsletterSubscribers(userId); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Deprovisioned user %s", userId)
+	return nil
+}
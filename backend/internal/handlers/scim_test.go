@@ -0,0 +1,196 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+// scimTeam is a team with a verified domain and a SCIM token.
+type scimTeam struct {
+	id      string
+	ownerID string
+	domain  string
+	token   string
+}
+
+// newSCIMTeam creates a team owned by a new user and issues its SCIM token
+// the way a team admin would.
+func newSCIMTeam(t *testing.T) scimTeam {
+	t.Helper()
+	team := scimTeam{domain: fmt.Sprintf("team%d.example.com", userSeq.Add(1))}
+	team.ownerID = newUser(t, nil)
+	var err error
+	team.id, err = repo.CreateTeam(models.Team{
+		Name:    "Team",
+		OwnerID: team.ownerID,
+		Domains: []models.TeamDomain{{Domain: team.domain, Verified: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := repo.GetUserById(team.ownerID)
+	if err != nil || owner == nil {
+		t.Fatalf("loading the owner: %v", err)
+	}
+	owner.TeamID, owner.TeamRole, owner.Email = team.id, models.TeamRoleOwner, "owner@"+team.domain
+	if err := repo.UpdateUser(team.ownerID, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	var issued struct {
+		Token string `json:"token"`
+	}
+	decodeJSON(t, serve(h.CreateSCIMTokenHandler, team.ownerID, "", nil), http.StatusCreated, &issued)
+	team.token = issued.Token
+	return team
+}
+
+// scim makes a SCIM request with the team's token.
+func (team scimTeam) scim(handler http.HandlerFunc, method, target, body, memberID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+team.token)
+	var vars map[string]string
+	if memberID != "" {
+		vars = map[string]string{"id": memberID}
+	}
+	return serveRequest(handler, req, "", vars)
+}
+
+type scimUserResponse struct {
+	Id       string `json:"id"`
+	UserName string `json:"userName"`
+	Active   bool   `json:"active"`
+}
+
+func TestCreateSCIMTokenNeedsTeamAdmin(t *testing.T) {
+	team := newSCIMTeam(t)
+	memberID := newUser(t, func(user *models.User) {
+		user.TeamID, user.TeamRole = team.id, models.TeamRoleMember
+	})
+	if rec := serve(h.CreateSCIMTokenHandler, memberID, "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("a member issuing the token: status %d", rec.Code)
+	}
+}
+
+func TestSCIMAuthentication(t *testing.T) {
+	team := newSCIMTeam(t)
+	for name, authorization := range map[string]string{
+		"no token":      "",
+		"unknown token": "Bearer scim_unknown",
+		"session-style": "Basic " + team.token,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		if rec := serveRequest(h.ListSCIMUsersHandler, req, "", nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d", name, rec.Code)
+		}
+	}
+
+	// A token only reaches its own team's members
+	other := newSCIMTeam(t)
+	if rec := team.scim(h.GetSCIMUserHandler, http.MethodGet, "/", "", other.ownerID); rec.Code != http.StatusNotFound {
+		t.Errorf("another team's member: status %d", rec.Code)
+	}
+}
+
+func TestSCIMProvisioningLifecycle(t *testing.T) {
+	team := newSCIMTeam(t)
+	email := "new.member@" + team.domain
+
+	if rec := team.scim(h.CreateSCIMUserHandler, http.MethodPost, "/", `{"userName": "someone@elsewhere.example.org"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("an email outside the team's domains: status %d", rec.Code)
+	}
+	var created scimUserResponse
+	decodeJSON(t, team.scim(h.CreateSCIMUserHandler, http.MethodPost, "/", `{"userName": "`+email+`", "externalId": "idp-1"}`, ""), http.StatusCreated, &created)
+	if created.UserName != email || !created.Active {
+		t.Errorf("created %+v", created)
+	}
+	if rec := team.scim(h.CreateSCIMUserHandler, http.MethodPost, "/", `{"userName": "`+email+`"}`, ""); rec.Code != http.StatusConflict {
+		t.Errorf("provisioning twice: status %d", rec.Code)
+	}
+
+	var listed struct {
+		TotalResults int                `json:"totalResults"`
+		Resources    []scimUserResponse `json:"Resources"`
+	}
+	decodeJSON(t, team.scim(h.ListSCIMUsersHandler, http.MethodGet, `/?filter=userName+eq+"`+email+`"`, "", ""), http.StatusOK, &listed)
+	if listed.TotalResults != 1 || len(listed.Resources) != 1 || listed.Resources[0].Id != created.Id {
+		t.Errorf("filtered list = %+v", listed)
+	}
+	if rec := team.scim(h.ListSCIMUsersHandler, http.MethodGet, "/?filter=name+co+x", "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("an unsupported filter: status %d", rec.Code)
+	}
+
+	var patched scimUserResponse
+	decodeJSON(t, team.scim(h.PatchSCIMUserHandler, http.MethodPatch, "/", `{"Operations": [{"op": "replace", "path": "active", "value": "False"}]}`, created.Id), http.StatusOK, &patched)
+	if patched.Active {
+		t.Error("the member is still active after deactivation")
+	}
+	if user, err := repo.GetUserById(created.Id); err != nil || user == nil || !user.Disabled {
+		t.Errorf("deactivated member = %+v, %v", user, err)
+	}
+	decodeJSON(t, team.scim(h.PatchSCIMUserHandler, http.MethodPatch, "/", `{"Operations": [{"op": "replace", "value": {"active": true}}]}`, created.Id), http.StatusOK, &patched)
+	if !patched.Active {
+		t.Error("the member is still inactive after reactivation")
+	}
+
+	if rec := team.scim(h.DeleteSCIMUserHandler, http.MethodDelete, "/", "", team.ownerID); rec.Code != http.StatusForbidden {
+		t.Errorf("deprovisioning the owner: status %d", rec.Code)
+	}
+	if rec := team.scim(h.DeleteSCIMUserHandler, http.MethodDelete, "/", "", created.Id); rec.Code != http.StatusNoContent {
+		t.Fatalf("deprovisioning: status %d", rec.Code)
+	}
+	if user, err := repo.GetUserById(created.Id); err != nil || user == nil || !user.Disabled || user.TeamID != "" {
+		t.Errorf("deprovisioned member = %+v, %v", user, err)
+	}
+	if rec := team.scim(h.GetSCIMUserHandler, http.MethodGet, "/", "", created.Id); rec.Code != http.StatusNotFound {
+		t.Errorf("a deprovisioned member is still listed: status %d", rec.Code)
+	}
+}
+
+func TestListSCIMUsersPages(t *testing.T) {
+	team := newSCIMTeam(t)
+	var ids []string
+	for i := 1; i <= 3; i++ {
+		var created scimUserResponse
+		body := fmt.Sprintf(`{"userName": "Member%d@%s", "externalId": "idp-%d"}`, i, team.domain, i)
+		decodeJSON(t, team.scim(h.CreateSCIMUserHandler, http.MethodPost, "/", body, ""), http.StatusCreated, &created)
+		ids = append(ids, created.Id)
+	}
+
+	type list struct {
+		TotalResults int                `json:"totalResults"`
+		StartIndex   int                `json:"startIndex"`
+		ItemsPerPage int                `json:"itemsPerPage"`
+		Resources    []scimUserResponse `json:"Resources"`
+	}
+	for target, want := range map[string]list{
+		// The owner is listed first, being the oldest member
+		"/?startIndex=2&count=2":         {TotalResults: 4, StartIndex: 2, ItemsPerPage: 2, Resources: []scimUserResponse{{Id: ids[0]}, {Id: ids[1]}}},
+		"/?startIndex=4&count=10":        {TotalResults: 4, StartIndex: 4, ItemsPerPage: 1, Resources: []scimUserResponse{{Id: ids[2]}}},
+		"/?startIndex=9":                 {TotalResults: 4, StartIndex: 9},
+		"/?count=0":                      {TotalResults: 4, StartIndex: 1},
+		`/?filter=externalId+eq+"idp-2"`: {TotalResults: 1, StartIndex: 1, ItemsPerPage: 1, Resources: []scimUserResponse{{Id: ids[1]}}},
+		`/?filter=userName+eq+"MEMBER3@` + team.domain + `"`: {TotalResults: 1, StartIndex: 1, ItemsPerPage: 1, Resources: []scimUserResponse{{Id: ids[2]}}},
+	} {
+		var got list
+		decodeJSON(t, team.scim(h.ListSCIMUsersHandler, http.MethodGet, target, "", ""), http.StatusOK, &got)
+		if got.TotalResults != want.TotalResults || got.StartIndex != want.StartIndex || got.ItemsPerPage != want.ItemsPerPage || len(got.Resources) != len(want.Resources) {
+			t.Errorf("%s: listed %+v, want %+v", target, got, want)
+			continue
+		}
+		for i := range want.Resources {
+			if got.Resources[i].Id != want.Resources[i].Id {
+				t.Errorf("%s: resource %d is %s, want %s", target, i, got.Resources[i].Id, want.Resources[i].Id)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const (
+	maxSearchQueryLength = 200
+	maxSearchResults     = 20
+)
+
+// SearchHandler finds the caller's synced posts, past captions and scheduled
+// shares matching the q query parameter, so earlier work can be reused.
+// Posts are matched by the text index on the posts collection; captions and
+// schedules live on the user and are matched by word prefix.
+func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	terms := services.SearchTerms(query)
+	if len(terms) == 0 {
+		http.Error(w, `{"error": "Search query is required"}`, http.StatusBadRequest)
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		http.Error(w, `{"error": "Search query is too long"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Search is read-only, so a replica may serve it
+	user, err := repo.GetUserByIdStale(userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for id: %s - %v", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	found, err := repo.SearchUserPosts(userId, query, maxSearchResults)
+	if err != nil {
+		log.Printf("[ERROR] Failed to search posts of user %s: %v", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	posts := make([]models.PostNode, 0, len(found))
+	for _, post := range found {
+		posts = append(posts, post.Node())
+	}
+
+	captions := []models.SharedBlog{}
+	for _, blog := range user.SharedBlogs {
+		if blog.Caption != "" && services.MatchesSearch(terms, blog.Title, blog.Caption) {
+			captions = append(captions, blog)
+		}
+	}
+	sort.SliceStable(captions, func(i, j int) bool {
+		return captions[i].SharedTime > captions[j].SharedTime
+	})
+	if len(captions) > maxSearchResults {
+		captions = captions[:maxSearchResults]
+	}
+
+	scheduled := []models.ScheduledBlog{}
+	for _, blog := range user.ScheduledBlogs {
+		if services.MatchesSearch(terms, blog.Title) {
+			scheduled = append(scheduled, blog)
+		}
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		return scheduled[i].ScheduledTime.Before(scheduled[j].ScheduledTime)
+	})
+	if len(scheduled) > maxSearchResults {
+		scheduled = scheduled[:maxSearchResults]
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"success":   true,
+		"posts":     posts,
+		"captions":  captions,
+		"scheduled": scheduled,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+// notifySecurityEvent reports an event of the user, made by the request's
+// client, to the user's security webhook.
+func notifySecurityEvent(r *http.Request, user *models.User, eventType string, details map[string]string) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxSessionUserAgent {
+		userAgent = userAgent[:maxSessionUserAgent]
+	}
+	services.NotifySecurityEvent(user, eventType, utils.GetClientIP(r), userAgent, details)
+}
+
+// notifySecurityEventOf is notifySecurityEvent for handlers that haven't
+// loaded the user.
+func notifySecurityEventOf(r *http.Request, userId string, eventType string, details map[string]string) {
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil || user == nil {
+		log.Printf("[WARN] Not sending the %s security event of user %s: %v", eventType, userId, err)
+		return
+	}
+	notifySecurityEvent(r, user, eventType, details)
+}
+
+func writeSecurityWebhook(w http.ResponseWriter, webhook *models.SecurityWebhook, secret string) {
+	response := map[string]interface{}{
+		"webhook":     webhook,
+		"event_types": models.SecurityEventTypes,
+	}
+	if secret != "" {
+		response["secret"] = secret
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetSecurityWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeSecurityWebhook(w, user.SecurityWebhook, "")
+}
+
+// SetSecurityWebhookHandler registers the endpoint that receives the caller's
+// login, session revocation and connection change events. The signing secret
+// is generated on first registration or when rotate_secret is set, and only
+// shown in that response.
+func (h *Handlers) SetSecurityWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		URL          string   `json:"url"`
+		Events       []string `json:"events"`
+		RotateSecret bool     `json:"rotate_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.URL = strings.TrimSpace(requestBody.URL)
+	if len(requestBody.URL) > 2048 {
+		http.Error(w, "Webhook URL is too long", http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateSecurityWebhookURL(requestBody.URL); err != nil {
+		writeError(w, err)
+		return
+	}
+	events := []string{}
+	for _, event := range requestBody.Events {
+		if !slices.Contains(models.SecurityEventTypes, event) {
+			http.Error(w, fmt.Sprintf("Unknown event type %q, available types: %s", event, strings.Join(models.SecurityEventTypes, ", ")), http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	webhook := user.SecurityWebhook
+	if webhook == nil {
+		webhook = &models.SecurityWebhook{CreatedAt: utils.Now()}
+	}
+	var secret string
+	if (webhook.SealedSecret == "" && webhook.PlaintextSecret == "") || requestBody.RotateSecret {
+		secret, _, err = services.NewOAuthSecret(services.SecurityWebhookSecretPrefix)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := services.SealCredential(user, &webhook.SealedSecret, &webhook.PlaintextSecret, secret); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	webhook.URL = requestBody.URL
+	webhook.Events = events
+	user.SecurityWebhook = webhook
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Security webhook set by user with ID %s", userId)
+	writeSecurityWebhook(w, webhook, secret)
+}
+
+func (h *Handlers) DeleteSecurityWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.SecurityWebhook == nil {
+		http.Error(w, "No security webhook registered", http.StatusNotFound)
+		return
+	}
+	user.SecurityWebhook = nil
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Security webhook removed by user with ID %s", userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// TestSecurityWebhookHandler sends a ping event to the caller's webhook and
+// reports how the endpoint responded.
+func (h *Handlers) TestSecurityWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.SecurityWebhook == nil {
+		http.Error(w, "No security webhook registered", http.StatusNotFound)
+		return
+	}
+
+	secret, err := services.OpenCredential(user, user.SecurityWebhook.SealedSecret, user.SecurityWebhook.PlaintextSecret)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	event := services.NewSecurityEvent(user, models.SecurityEventPing, utils.GetClientIP(r), "", nil)
+	status, err := services.DeliverSecurityEvent(user.SecurityWebhook, secret, event)
+	response := map[string]interface{}{
+		"success":     err == nil,
+		"event_id":    event.Id,
+		"status_code": status,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
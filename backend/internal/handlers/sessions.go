@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// setCookie sets a cookie with the configured cookie policy applied.
+func setCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	config.Get().ApplyCookiePolicy(cookie)
+	http.SetCookie(w, cookie)
+}
+
+// setStateCookie binds the state of a flow through another site to the
+// browser starting it. The site redirects back cross-site, which the cookie
+// policy allows for since it is never strict.
+func setStateCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	setCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+	})
+}
+
+// takeStateCookie returns the value of a state cookie, or "" without one, and
+// clears it so the flow can't be completed twice.
+func takeStateCookie(w http.ResponseWriter, r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	setCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+	return cookie.Value
+}
+
+// sessionID identifies a session to its user without exposing the token the
+// session is stored under.
+func sessionID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// currentSessionKey returns the key the session making the request is stored
+// under, or "" when the request isn't authenticated by a session cookie.
+func currentSessionKey(r *http.Request) string {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		return ""
+	}
+	if services.IsSessionJWT(cookie.Value) {
+		claims, err := services.ParseSessionJWT(cookie.Value)
+		if err != nil {
+			return ""
+		}
+		return repo.SessionJWTRecordKey(claims.ID)
+	}
+	return cookie.Value
+}
+
+// currentSessionRemembered reports whether the session making the request was
+// started with "remember me", so replacing it keeps its lifetime.
+func currentSessionRemembered(r *http.Request) bool {
+	key := currentSessionKey(r)
+	if key == "" {
+		return false
+	}
+	cached, ok := repo.GetCache(key)
+	if !ok {
+		return false
+	}
+	item, ok := cached.(models.CacheItem)
+	return ok && item.Session != nil && item.Session.RememberMe
+}
+
+// signOutOtherSessions ends every session of the user except the one making
+// the request. Signing out everywhere revokes the caller's session JWT as
+// well, so it is reissued.
+func signOutOtherSessions(w http.ResponseWriter, r *http.Request, userId primitive.ObjectID) error {
+	currentToken := ""
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		currentToken = cookie.Value
+	}
+	if err := repo.DeleteOtherUserSessions(userId, currentToken); err != nil {
+		return err
+	}
+	if services.IsSessionJWT(currentToken) {
+		return startSession(w, r, userId, currentSessionRemembered(r))
+	}
+	return nil
+}
+
+// sessionUser returns the authenticated user's id, writing the error response
+// when there is none.
+func sessionUser(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return primitive.NilObjectID, false
+	}
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return primitive.NilObjectID, false
+	}
+	return oid, true
+}
+
+// ListSessionsHandler lists the caller's active sessions.
+func (h *Handlers) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	entries, err := repo.ListUserSessions(userId)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentKey := currentSessionKey(r)
+	sessions := make([]models.ActiveSession, 0, len(entries))
+	for _, entry := range entries {
+		session := models.ActiveSession{
+			Id:        sessionID(entry.Key),
+			ExpiresAt: entry.ExpiresAt,
+			Current:   entry.Key == currentKey,
+		}
+		if entry.Session != nil {
+			session.CreatedAt = entry.Session.CreatedAt
+			session.IP = entry.Session.IP
+			session.UserAgent = entry.Session.UserAgent
+		}
+		sessions = append(sessions, session)
+	}
+
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"sessions": sessions,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// RevokeSessionHandler ends one of the caller's sessions. Revoking the current
+// session is the same as logging out.
+func (h *Handlers) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	entries, err := repo.ListUserSessions(userId)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, entry := range entries {
+		if sessionID(entry.Key) != id {
+			continue
+		}
+		if err := repo.RevokeSession(entry); err != nil {
+			http.Error(w, `{"error": "Failed to revoke session"}`, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[INFO] User %s revoked the session %s", userId.Hex(), id)
+		notifySecurityEventOf(r, userId.Hex(), models.SecurityEventSessionRevoked, map[string]string{"scope": "session", "session_id": id})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success": true}`))
+		return
+	}
+	http.Error(w, `{"error": "Session not found"}`, http.StatusNotFound)
+}
+
+// RevokeOtherSessionsHandler signs the caller out everywhere else.
+func (h *Handlers) RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := signOutOtherSessions(w, r, userId); err != nil {
+		log.Printf("[ERROR] Failed to sign out other sessions of the user %s: %v", userId.Hex(), err)
+		http.Error(w, `{"error": "Failed to revoke sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User %s signed out their other sessions", userId.Hex())
+	notifySecurityEventOf(r, userId.Hex(), models.SecurityEventSessionRevoked, map[string]string{"scope": "others"})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+const shareHistoryImportTTL = 10 * time.Minute
+
+func shareHistoryImportKey(userId, platform string) string {
+	return "share_history_import_" + platform + "_" + userId
+}
+
+// requestShareHistoryImport remembers, for the callback, that the user asked
+// to import their earlier posts when connecting platform.
+func requestShareHistoryImport(r *http.Request, userId, platform string) {
+	if r.URL.Query().Get("import_history") != "true" {
+		return
+	}
+	if err := repo.SetCache(shareHistoryImportKey(userId, platform), true, shareHistoryImportTTL); err != nil {
+		log.Printf("[WARN] Failed to remember the %s history import of the user %s: %v", platform, userId, err)
+	}
+}
+
+// startShareHistoryImport backfills the shared-blog history from platform in
+// the background, when the user asked for it and platform wasn't connected
+// before.
+func startShareHistoryImport(userId, platform string, firstConnection bool) {
+	key := shareHistoryImportKey(userId, platform)
+	if _, requested := repo.GetCache(key); !requested {
+		return
+	}
+	repo.DeleteCache(key)
+	if !firstConnection {
+		return
+	}
+
+	go func() {
+		imported, err := services.BackfillShareHistory(userId, platform)
+		if err != nil {
+			log.Printf("[WARN] Failed to import the %s history of the user %s: %v", platform, userId, err)
+			return
+		}
+		log.Printf("[INFO] Imported %d shared blogs from the %s history of the user %s", imported, platform, userId)
+	}()
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeShareWebhook(w http.ResponseWriter, webhook *models.ShareWebhook, secret string) {
+	response := map[string]interface{}{
+		"webhook": webhook,
+	}
+	if secret != "" {
+		response["secret"] = secret
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetShareWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeShareWebhook(w, user.ShareWebhook, "")
+}
+
+// SetShareWebhookHandler registers the endpoint shares are posted to as the
+// "webhook" platform. Like the security webhook, the signing secret is
+// generated on first registration or when rotate_secret is set, and only
+// shown in that response.
+func (h *Handlers) SetShareWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		URL          string `json:"url"`
+		RotateSecret bool   `json:"rotate_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.URL = strings.TrimSpace(requestBody.URL)
+	if len(requestBody.URL) > 2048 {
+		http.Error(w, "Webhook URL is too long", http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateSecurityWebhookURL(requestBody.URL); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	webhook := user.ShareWebhook
+	if webhook == nil {
+		webhook = &models.ShareWebhook{CreatedAt: utils.Now()}
+	}
+	var secret string
+	if webhook.SealedSecret == "" || requestBody.RotateSecret {
+		secret, _, err = services.NewOAuthSecret(services.ShareWebhookSecretPrefix)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		webhook.SealedSecret, err = services.SealUserSecret(user, secret)
+		if err != nil {
+			log.Printf("[ERROR] Failed to seal the share webhook secret of user %s: %v", userId, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	webhook.URL = requestBody.URL
+	user.ShareWebhook = webhook
+	user.ShareWebhookVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Share webhook set by user with ID %s", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "webhook", "action": "connected", "account": webhook.URL})
+	writeShareWebhook(w, webhook, secret)
+}
+
+func (h *Handlers) DeleteShareWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.ShareWebhook == nil {
+		http.Error(w, "No share webhook registered", http.StatusNotFound)
+		return
+	}
+	user.ShareWebhook = nil
+	user.ShareWebhookVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Share webhook removed by user with ID %s", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "webhook", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// TestShareWebhookHandler sends a ping to the caller's share webhook and
+// reports how the endpoint responded.
+func (h *Handlers) TestShareWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.ShareWebhook == nil {
+		http.Error(w, "No share webhook registered", http.StatusNotFound)
+		return
+	}
+
+	event := services.NewShareWebhookPing()
+	status, _, err := services.DeliverShareWebhook(user, event)
+	response := map[string]interface{}{
+		"success":     err == nil,
+		"event_id":    event.Id,
+		"status_code": status,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ssoStateTTL    = 10 * time.Minute
+	ssoStateCookie = "sso_state"
+)
+
+var usernameUnsafeChars = regexp.MustCompile(`[^a-z0-9._-]`)
+
+// ssoLoginState is kept server side between the redirect to the IdP and the
+// callback, keyed by the state parameter.
+type ssoLoginState struct {
+	TeamID   string `json:"team_id"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// setLoginStateCookie binds a sign-in's state and nonce to the browser that
+// starts it, so a callback URL handed to another browser can't sign that
+// browser in.
+func setLoginStateCookie(w http.ResponseWriter, name, state, nonce string) {
+	setStateCookie(w, name, state+"."+nonce, ssoStateTTL)
+}
+
+// takeLoginStateCookie reports whether a callback came back to the browser
+// that started the sign-in for state, and returns the nonce bound with it.
+// The cookie is cleared either way.
+func takeLoginStateCookie(w http.ResponseWriter, r *http.Request, name, state string) (string, bool) {
+	cookieState, nonce, found := strings.Cut(takeStateCookie(w, r, name), ".")
+	if !found || state == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		return "", false
+	}
+	return nonce, true
+}
+
+func ssoRedirectURI() string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/sso/callback"
+}
+
+// SSOLoginHandler starts an OIDC login for the team owning the email domain.
+func (h *Handlers) SSOLoginHandler(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	domain := services.EmailDomain(email)
+	if domain == "" {
+		http.Error(w, "A work email address is required", http.StatusBadRequest)
+		return
+	}
+	team, err := repo.GetTeamByVerifiedDomain(domain)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if team == nil || !team.SSO.Enabled {
+		http.Error(w, "Single sign-on is not configured for this domain", http.StatusNotFound)
+		return
+	}
+
+	discovery, err := services.DiscoverOIDC(team.SSO.Issuer)
+	if err != nil {
+		log.Printf("[ERROR] OIDC discovery failed for team %s: %v", team.Id.Hex(), err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	state, nonce, verifier, err := services.NewOIDCRequestSecrets()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	stateJson, err := json.Marshal(ssoLoginState{TeamID: team.Id.Hex(), Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := repo.SetCache("sso_state_"+state, string(stateJson), ssoStateTTL); err != nil {
+		writeError(w, err)
+		return
+	}
+	setLoginStateCookie(w, ssoStateCookie, state, nonce)
+
+	http.Redirect(w, r, services.OIDCAuthURL(discovery, team.SSO, ssoRedirectURI(), state, nonce, verifier, email), http.StatusFound)
+}
+
+// SSOCallbackHandler completes an OIDC login, provisioning the member into
+// the team on first sign-in and refreshing their role from IdP groups.
+func (h *Handlers) SSOCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	failureURL := config.Get().FrontendURL + "/login?sso_error="
+	if idpError := query.Get("error"); idpError != "" {
+		http.Redirect(w, r, failureURL+"denied", http.StatusSeeOther)
+		return
+	}
+
+	nonce, bound := takeLoginStateCookie(w, r, ssoStateCookie, query.Get("state"))
+	if !bound {
+		log.Printf("[WARN] SSO login rejected: the state doesn't match the browser's")
+		http.Redirect(w, r, failureURL+"state", http.StatusSeeOther)
+		return
+	}
+	stateKey := "sso_state_" + query.Get("state")
+	cached, exists := repo.GetCache(stateKey)
+	if !exists {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+	repo.DeleteCache(stateKey)
+	var state ssoLoginState
+	stateValue, _ := cached.(models.CacheItem).Value.(string)
+	if err := json.Unmarshal([]byte(stateValue), &state); err != nil || subtle.ConstantTimeCompare([]byte(nonce), []byte(state.Nonce)) != 1 {
+		http.Redirect(w, r, failureURL+"expired", http.StatusSeeOther)
+		return
+	}
+
+	team, err := repo.GetTeamById(state.TeamID)
+	if err != nil || team == nil || !team.SSO.Enabled {
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	discovery, err := services.DiscoverOIDC(team.SSO.Issuer)
+	if err != nil {
+		log.Printf("[ERROR] OIDC discovery failed for team %s: %v", state.TeamID, err)
+		http.Redirect(w, r, failureURL+"unavailable", http.StatusSeeOther)
+		return
+	}
+	identity, err := services.ExchangeOIDCCode(discovery, team.SSO, ssoRedirectURI(), query.Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("[WARN] SSO login for team %s rejected: %v", state.TeamID, err)
+		http.Redirect(w, r, failureURL+"rejected", http.StatusSeeOther)
+		return
+	}
+
+	// The IdP may only assert identities in domains the team has proven it owns
+	domain := services.EmailDomain(identity.Email)
+	domainVerified := false
+	for _, teamDomain := range team.Domains {
+		if teamDomain.Domain == domain && teamDomain.Verified {
+			domainVerified = true
+			break
+		}
+	}
+	if !domainVerified || !identity.EmailVerified {
+		log.Printf("[WARN] SSO login for team %s rejected: unverified email %s", state.TeamID, identity.Email)
+		http.Redirect(w, r, failureURL+"domain", http.StatusSeeOther)
+		return
+	}
+
+	user, err := provisionSSOUser(team, identity)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrForbidden) {
+			log.Printf("[WARN] SSO login for team %s rejected: %v", state.TeamID, err)
+			http.Redirect(w, r, failureURL+"disabled", http.StatusSeeOther)
+			return
+		}
+		log.Printf("[ERROR] Failed to provision SSO user for team %s: %v", state.TeamID, err)
+		http.Redirect(w, r, failureURL+"provisioning", http.StatusSeeOther)
+		return
+	}
+
+	if err := startSession(w, r, user.Id, false); err != nil {
+		http.Redirect(w, r, failureURL+"session", http.StatusSeeOther)
+		return
+	}
+	log.Printf("[INFO] User with ID %s signed in through SSO for team %s", user.Id.Hex(), state.TeamID)
+	notifySecurityEvent(r, user, models.SecurityEventLogin, map[string]string{"method": "sso", "team_id": state.TeamID})
+	recordLogin(r, user, "sso")
+	http.Redirect(w, r, config.Get().FrontendURL+"/", http.StatusSeeOther)
+}
+
+// provisionSSOUser finds the team member for an IdP identity, creating the
+// account just in time on first login. The role is re-derived from IdP
+// groups on every login so IdP changes take effect; the owner keeps their role.
+func provisionSSOUser(team *models.Team, identity *services.OIDCIdentity) (*models.User, error) {
+	teamId := team.Id.Hex()
+	role := services.MapTeamRole(team.SSO, identity.Groups)
+
+	user, err := repo.GetUserBySSOSubject(teamId, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// Members provisioned through SCIM are linked on their first login
+		user, err = repo.GetTeamMemberByEmail(teamId, strings.ToLower(identity.Email))
+		if err != nil {
+			return nil, err
+		}
+		if user != nil && user.SSOSubject != "" {
+			return nil, fmt.Errorf("member %s is linked to another identity: %w", user.Id.Hex(), apperrors.ErrConflict)
+		}
+	}
+	if user != nil {
+		if user.Disabled {
+			return nil, fmt.Errorf("member %s is deprovisioned: %w", user.Id.Hex(), apperrors.ErrForbidden)
+		}
+		user.SSOSubject = identity.Subject
+		if user.TeamRole != models.TeamRoleOwner {
+			user.TeamRole = role
+		}
+		user.Email = strings.ToLower(identity.Email)
+		if err := repo.UpdateUser(user.Id.Hex(), user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	user = &models.User{
+		Email:      strings.ToLower(identity.Email),
+		TeamID:     teamId,
+		TeamRole:   role,
+		SSOSubject: identity.Subject,
+		Region:     team.Region,
+	}
+	if err := createExternalUser(user); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Provisioned SSO user %s into team %s", user.Id.Hex(), teamId)
+	return user, nil
+}
+
+// createExternalUser stores a new account managed by an identity provider (team
+// SSO, SCIM or Google), deriving a free username from the email. Such accounts never log in with a password, so an
+// unguessable one is stored.
+func createExternalUser(user *models.User) error {
+	unusablePassword, err := services.HashPassword(uuid.New().String() + uuid.New().String())
+	if err != nil {
+		return err
+	}
+	localPart := strings.ToLower(user.Email[:strings.LastIndex(user.Email, "@")])
+	baseName := usernameUnsafeChars.ReplaceAllString(localPart, "")
+	if len(baseName) < 4 {
+		baseName = "member-" + baseName
+	}
+	if len(baseName) > 48 {
+		baseName = baseName[:48]
+	}
+
+	now := utils.Now()
+	user.UserName = baseName
+	user.PassWord = unusablePassword
+	user.EmailVerified = true
+	user.Plan = models.PlanFree
+	user.CreatedAt = now
+	user.LastActiveAt = now
+	for attempt := 0; ; attempt++ {
+		userId, err := repo.CreateUser(*user)
+		if err == nil {
+			user.Id, _ = primitive.ObjectIDFromHex(userId)
+			return nil
+		}
+		if !errors.Is(err, repo.ErrUsernameTaken) || attempt >= 5 {
+			return err
+		}
+		user.UserName = fmt.Sprintf("%s-%s", baseName, uuid.New().String()[:6])
+	}
+}
@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+)
+
+// newSSOTeam creates a team that verified a new domain, with the SSO config,
+// and returns the domain.
+func newSSOTeam(t *testing.T, sso models.TeamSSOConfig) string {
+	t.Helper()
+	domain := fmt.Sprintf("sso%d.example.com", userSeq.Add(1))
+	_, err := repo.CreateTeam(models.Team{
+		Name:    "Team",
+		OwnerID: newUser(t, nil),
+		Domains: []models.TeamDomain{{Domain: domain, Verified: true}},
+		SSO:     sso,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return domain
+}
+
+func TestSSOLoginHandler(t *testing.T) {
+	requireMongo(t)
+	disabled := newSSOTeam(t, models.TeamSSOConfig{Issuer: "https://idp.example.com"})
+	// The issuer must be https, so discovery fails without a request
+	unreachable := newSSOTeam(t, models.TeamSSOConfig{Enabled: true, Issuer: "http://idp.example.com"})
+
+	cases := []struct {
+		name   string
+		email  string
+		status int
+	}{
+		{name: "no email", status: http.StatusBadRequest},
+		{name: "not an email", email: "someone", status: http.StatusBadRequest},
+		{name: "unclaimed domain", email: "someone@unclaimed.example.com", status: http.StatusNotFound},
+		{name: "sso disabled", email: "someone@" + disabled, status: http.StatusNotFound},
+		{name: "issuer unavailable", email: "someone@" + unreachable, status: http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+url.Values{"email": {tc.email}}.Encode(), nil)
+			rec := serveRequest(h.SSOLoginHandler, req, "", nil)
+			if rec.Code != tc.status {
+				t.Errorf("status %d, want %d; body %q", rec.Code, tc.status, rec.Body.String())
+			}
+			if rec.Header().Get("Location") != "" {
+				t.Errorf("redirected to %q", rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestSSOCallbackDenied(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?error=access_denied&state=state", nil)
+	rec := serveRequest(h.SSOCallbackHandler, req, "", nil)
+	if location := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || !strings.HasSuffix(location, "sso_error=denied") {
+		t.Errorf("status %d, location %q; want a redirect with sso_error=denied", rec.Code, location)
+	}
+}
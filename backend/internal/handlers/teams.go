@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const teamDomainTXTPrefix = "socialscribe-verification="
+
+const (
+	defaultCalendarConflictWindow = 30 * time.Minute
+	maxCalendarRange              = 31 * 24 * time.Hour
+)
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// loadTeamAdmin returns the caller and their team, failing unless the caller
+// is an owner or admin of it.
+func loadTeamAdmin(r *http.Request) (*models.User, *models.Team, error) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, fmt.Errorf("user %s: %w", userId, apperrors.ErrNotFound)
+	}
+	if user.TeamID == "" {
+		return nil, nil, fmt.Errorf("user is not in a team: %w", apperrors.ErrNotFound)
+	}
+	if user.TeamRole != models.TeamRoleOwner && user.TeamRole != models.TeamRoleAdmin {
+		return nil, nil, fmt.Errorf("team admin role required: %w", apperrors.ErrForbidden)
+	}
+	team, err := repo.GetTeamById(user.TeamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if team == nil {
+		return nil, nil, fmt.Errorf("team %s: %w", user.TeamID, apperrors.ErrNotFound)
+	}
+	return user, team, nil
+}
+
+func writeTeam(w http.ResponseWriter, status int, team *models.Team) {
+	responseJson, err := json.Marshal(team)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) CreateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.TeamID != "" {
+		http.Error(w, "User already belongs to a team", http.StatusConflict)
+		return
+	}
+
+	var requestBody struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" || len(requestBody.Name) > 100 {
+		http.Error(w, "Team name must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Region == "" {
+		requestBody.Region = models.RegionDefault
+	}
+	if !repo.IsRegion(requestBody.Region) {
+		http.Error(w, fmt.Sprintf("Unknown region %q, available regions: %s", requestBody.Region, strings.Join(repo.Regions(), ", ")), http.StatusBadRequest)
+		return
+	}
+
+	team := models.Team{
+		Name:      requestBody.Name,
+		OwnerID:   userId,
+		Domains:   []models.TeamDomain{},
+		SSO:       models.TeamSSOConfig{DefaultRole: models.TeamRoleMember},
+		CreatedAt: utils.Now(),
+		Region:    requestBody.Region,
+	}
+	teamId, err := repo.CreateTeam(team)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	team.Id, _ = primitive.ObjectIDFromHex(teamId)
+
+	// The owner's data follows the team into its region
+	if err := repo.MoveUserToRegion(userId, team.Region); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.TeamID = teamId
+	user.TeamRole = models.TeamRoleOwner
+	if err := repo.UpdateUser(userId, user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("[INFO] Team %s created by user with ID %s", teamId, userId)
+	writeTeam(w, http.StatusCreated, &team)
+}
+
+// GetRegionsHandler lists the storage regions a new team can pick.
+func (h *Handlers) GetRegionsHandler(w http.ResponseWriter, r *http.Request) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"regions": repo.Regions(),
+		"default": models.RegionDefault,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetTeamHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil || user.TeamID == "" {
+		http.Error(w, "Not a team member", http.StatusNotFound)
+		return
+	}
+	team, err := repo.GetTeamById(user.TeamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if team == nil {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	members, err := repo.GetTeamMembers(user.TeamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type teamMember struct {
+		models.UserDTO
+		Role string `json:"role"`
+	}
+	memberDTOs := make([]teamMember, 0, len(members))
+	for i := range members {
+		memberDTOs = append(memberDTOs, teamMember{UserDTO: members[i].ToDTO(), Role: members[i].TeamRole})
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"team":    team,
+		"role":    user.TeamRole,
+		"members": memberDTOs,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetTeamCalendarHandler shows every member's pending scheduled shares,
+// flagging members who post to the same platform close together. The range
+// is set with the from and to RFC 3339 query parameters, defaulting to the
+// next 7 days, and the conflict window with window, in minutes.
+func (h *Handlers) GetTeamCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := loadTeamMember(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	from := utils.Now()
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "from must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	to := from.Add(7 * 24 * time.Hour)
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "to must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	if !to.After(from) || to.Sub(from) > maxCalendarRange {
+		http.Error(w, "to must be after from and at most 31 days later", http.StatusBadRequest)
+		return
+	}
+	window := defaultCalendarConflictWindow
+	if value := query.Get("window"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 || minutes > 24*60 {
+			http.Error(w, "window must be between 0 and 1440 minutes", http.StatusBadRequest)
+			return
+		}
+		window = time.Duration(minutes) * time.Minute
+	}
+
+	members, err := repo.GetTeamMembers(user.TeamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	responseJson, err := json.Marshal(services.BuildTeamCalendar(members, from, to, window))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// AddTeamDomainHandler claims an email domain for the team and returns the
+// DNS TXT record that proves ownership.
+func (h *Handlers) AddTeamDomainHandler(w http.ResponseWriter, r *http.Request) {
+	_, team, err := loadTeamAdmin(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var requestBody struct {
+		Domain string `json:"domain"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	domain := strings.ToLower(strings.TrimSpace(requestBody.Domain))
+	if !domainPattern.MatchString(domain) {
+		http.Error(w, "Invalid domain", http.StatusBadRequest)
+		return
+	}
+	for _, existing := range team.Domains {
+		if existing.Domain == domain {
+			http.Error(w, "Domain already added", http.StatusConflict)
+			return
+		}
+	}
+
+	team.Domains = append(team.Domains, models.TeamDomain{
+		Domain:            domain,
+		VerificationToken: uuid.New().String(),
+	})
+	if err := repo.UpdateTeam(team); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeTeam(w, http.StatusOK, team)
+}
+
+// VerifyTeamDomainHandler looks up the domain's TXT records and marks it as
+// verified when the team's token is published.
+func (h *Handlers) VerifyTeamDomainHandler(w http.ResponseWriter, r *http.Request) {
+	_, team, err := loadTeamAdmin(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var requestBody struct {
+		Domain string `json:"domain"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	domain := strings.ToLower(strings.TrimSpace(requestBody.Domain))
+
+	index := -1
+	for i := range team.Domains {
+		if team.Domains[i].Domain == domain {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		http.Error(w, "Domain not found", http.StatusNotFound)
+		return
+	}
+
+	other, err := repo.GetTeamByVerifiedDomain(domain)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if other != nil && other.Id != team.Id {
+		http.Error(w, "Domain is verified by another team", http.StatusConflict)
+		return
+	}
+
+	records, err := net.LookupTXT(domain)
+	if err != nil {
+		log.Printf("[WARN] TXT lookup for %s failed: %v", domain, err)
+	}
+	expected := teamDomainTXTPrefix + team.Domains[index].VerificationToken
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("TXT record %q not found on %s", expected, domain), http.StatusPreconditionFailed)
+		return
+	}
+
+	// The check above races another team verifying the domain meanwhile;
+	// the unique index on verified domains settles it with a conflict
+	team.Domains[index].Verified = true
+	if err := repo.UpdateTeam(team); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] Domain %s verified for team %s", domain, team.Id.Hex())
+	writeTeam(w, http.StatusOK, team)
+}
+
+// UpdateTeamSSOHandler stores the team's OIDC configuration after checking
+// that the issuer publishes a usable discovery document.
+func (h *Handlers) UpdateTeamSSOHandler(w http.ResponseWriter, r *http.Request) {
+	_, team, err := loadTeamAdmin(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var requestBody struct {
+		models.TeamSSOConfig
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	sso := requestBody.TeamSSOConfig
+	sso.Issuer = strings.TrimRight(strings.TrimSpace(sso.Issuer), "/")
+	sso.ClientSecret = requestBody.ClientSecret
+	if sso.ClientSecret == "" {
+		// Allow edits without resending the stored secret
+		sso.ClientSecret = team.SSO.ClientSecret
+	}
+	if sso.DefaultRole == "" {
+		sso.DefaultRole = models.TeamRoleMember
+	}
+	if sso.DefaultRole != models.TeamRoleMember && sso.DefaultRole != models.TeamRoleAdmin {
+		http.Error(w, "default_role must be member or admin", http.StatusBadRequest)
+		return
+	}
+	for group, role := range sso.RoleMapping {
+		if role != models.TeamRoleMember && role != models.TeamRoleAdmin {
+			http.Error(w, fmt.Sprintf("Invalid role %q for group %q", role, group), http.StatusBadRequest)
+			return
+		}
+	}
+	if !strings.HasPrefix(sso.Issuer, "https://") || sso.ClientID == "" || sso.ClientSecret == "" {
+		http.Error(w, "issuer (https), client_id and client_secret are required", http.StatusBadRequest)
+		return
+	}
+	if _, err := services.DiscoverOIDC(sso.Issuer); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	team.SSO = sso
+	if err := repo.UpdateTeam(team); err != nil {
+		writeError(w, err)
+		return
+	}
+	log.Printf("[INFO] SSO configuration updated for team %s", team.Id.Hex())
+	writeTeam(w, http.StatusOK, team)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeTeamsWebhook(w http.ResponseWriter, webhook *models.TeamsWebhook) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"webhook": webhook,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetTeamsWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeTeamsWebhook(w, user.TeamsWebhook)
+}
+
+// SetTeamsWebhookHandler connects the Microsoft Teams incoming webhook that
+// blogs are announced through. Teams can't be asked about a webhook without
+// posting through it, so a confirmation card is posted first.
+func (h *Handlers) SetTeamsWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	webhookURL, host, err := services.ParseTeamsWebhookURL(requestBody.URL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	webhook := &models.TeamsWebhook{Host: host, CreatedAt: utils.Now()}
+	if webhook.SealedURL, err = services.SealUserSecret(user, webhookURL); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.TeamsWebhook = webhook
+	if err := services.PingTeamsWebhook(user); err != nil {
+		log.Printf("[WARN] The Teams webhook of user %s failed its check: %v", userId, err)
+		http.Error(w, "Teams didn't accept a message through this webhook", http.StatusBadRequest)
+		return
+	}
+	user.TeamsVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected a Teams webhook on %s", userId, host)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "teams", "action": "connected", "account": host})
+	writeTeamsWebhook(w, webhook)
+}
+
+func (h *Handlers) DeleteTeamsWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.TeamsWebhook == nil {
+		http.Error(w, "No Teams webhook connected", http.StatusNotFound)
+		return
+	}
+	user.TeamsWebhook = nil
+	user.TeamsVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Teams webhook", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "teams", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// TestTeamsWebhookHandler posts a test card through the caller's webhook and
+// reports how Teams responded.
+func (h *Handlers) TestTeamsWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.TeamsWebhook == nil {
+		http.Error(w, "No Teams webhook connected", http.StatusNotFound)
+		return
+	}
+
+	err = services.PingTeamsWebhook(user)
+	response := map[string]interface{}{
+		"success": err == nil,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+// tumblrRequestTTL is how long a user has to authorize the app on Tumblr.
+const tumblrRequestTTL = 10 * time.Minute
+
+// tumblrRequest is kept server side between the redirect to Tumblr and the
+// callback, keyed by the request token.
+type tumblrRequest struct {
+	UserID string `json:"user_id"`
+	Secret string `json:"secret"`
+}
+
+func writeTumblrAccount(w http.ResponseWriter, account *models.TumblrAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetTumblrAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeTumblrAccount(w, user.Tumblr)
+}
+
+// ConnectTumblrHandler starts the Tumblr OAuth1 flow. The request token's
+// secret stays server side until Tumblr redirects back.
+func (h *Handlers) ConnectTumblrHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	app := services.TumblrOAuth()
+	requestToken, requestSecret, err := app.RequestToken()
+	if err != nil {
+		log.Printf("[ERROR] Failed to get a Tumblr request token for user %s: %v", userId, err)
+		http.Error(w, "Failed to get request token", http.StatusBadGateway)
+		return
+	}
+	requestJson, err := json.Marshal(tumblrRequest{UserID: userId, Secret: requestSecret})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := repo.SetCache("tumblr_request_"+requestToken, string(requestJson), tumblrRequestTTL); err != nil {
+		writeError(w, err)
+		return
+	}
+	authorizationURL, err := app.AuthorizationURL(requestToken)
+	if err != nil {
+		http.Error(w, "Failed to get authorization URL", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, authorizationURL.String(), http.StatusFound)
+}
+
+// TumblrCallbackHandler exchanges the authorized request token for an
+// access token and connects the account's primary blog.
+func (h *Handlers) TumblrCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	requestToken := r.URL.Query().Get("oauth_token")
+	requestKey := "tumblr_request_" + requestToken
+	cached, exists := repo.GetCache(requestKey)
+	if requestToken == "" || !exists {
+		http.Error(w, "Invalid or expired request token", http.StatusForbidden)
+		return
+	}
+	var request tumblrRequest
+	requestValue, _ := cached.(models.CacheItem).Value.(string)
+	if err := json.Unmarshal([]byte(requestValue), &request); err != nil || request.UserID != userId {
+		http.Error(w, "Invalid or expired request token", http.StatusForbidden)
+		return
+	}
+	if err := repo.DeleteCache(requestKey); err != nil {
+		log.Printf("[WARN] Failed to delete the Tumblr request token from cache for the user id: %s and error is %s", userId, err)
+	}
+	verifier := r.URL.Query().Get("oauth_verifier")
+	if verifier == "" {
+		// Tumblr sends the user back without a verifier when they deny access
+		log.Printf("[INFO] User %s didn't connect Tumblr", userId)
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?tumblr_error="+url.QueryEscape("access_denied"), http.StatusSeeOther)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	accessToken, accessSecret, err := services.TumblrOAuth().AccessToken(requestToken, request.Secret, verifier)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get the Tumblr access token of user %s: %v", userId, err)
+		http.Error(w, "Failed to get access token", http.StatusBadGateway)
+		return
+	}
+	account, err := services.LookupTumblrBlog(userId, accessToken, accessSecret)
+	if err != nil {
+		log.Printf("[ERROR] Failed to connect the Tumblr account of user %s: %v", userId, err)
+		writeError(w, err)
+		return
+	}
+	account.ConnectedAt = utils.Now()
+	if account.SealedToken, err = services.SealUserSecret(user, accessToken); err != nil {
+		writeError(w, err)
+		return
+	}
+	if account.SealedSecret, err = services.SealUserSecret(user, accessSecret); err != nil {
+		writeError(w, err)
+		return
+	}
+	user.Tumblr = account
+	user.TumblrVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the Tumblr blog %s", userId, account.Blog)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "tumblr", "action": "connected", "account": account.Blog})
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
+}
+
+func (h *Handlers) DeleteTumblrAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.Tumblr == nil {
+		http.Error(w, "No Tumblr account connected", http.StatusNotFound)
+		return
+	}
+	user.Tumblr = nil
+	user.TumblrVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their Tumblr account", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "tumblr", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
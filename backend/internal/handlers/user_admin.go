@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+const maxAdminUserList = 1000
+
+// adminUserView is a user as listed to admins, without credentials or
+// content.
+type adminUserView struct {
+	Id           string    `json:"id"`
+	UserName     string    `json:"username"`
+	Email        string    `json:"email,omitempty"`
+	Role         string    `json:"role,omitempty"`
+	Plan         string    `json:"plan"`
+	Region       string    `json:"region"`
+	TeamID       string    `json:"team_id,omitempty"`
+	Verified     bool      `json:"verified"`
+	Disabled     bool      `json:"disabled"`
+	Scheduled    int       `json:"scheduled"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+func newAdminUserView(user *models.User) adminUserView {
+	return adminUserView{
+		Id:           user.Id.Hex(),
+		UserName:     user.UserName,
+		Email:        user.Email,
+		Role:         user.Role,
+		Plan:         user.Plan,
+		Region:       user.Region,
+		TeamID:       user.TeamID,
+		Verified:     user.Verified,
+		Disabled:     user.Disabled,
+		Scheduled:    len(user.ScheduledBlogs),
+		CreatedAt:    user.CreatedAt,
+		LastActiveAt: user.LastActiveAt,
+	}
+}
+
+// ListUsersHandler lists users across every region, oldest first.
+func (h *Handlers) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit := int64(100)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxAdminUserList {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	users, err := repo.ListUsers(limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	views := make([]adminUserView, 0, len(users))
+	for i := range users {
+		views = append(views, newAdminUserView(&users[i]))
+	}
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"users": views,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// UpdateUserRoleHandler grants or takes away the admin role. Admins can't
+// change their own role, so there is always an admin left to undo a change.
+func (h *Handlers) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	adminId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Role != models.RoleUser && requestBody.Role != models.RoleAdmin {
+		http.Error(w, `role must be "admin" or empty`, http.StatusBadRequest)
+		return
+	}
+	userId := mux.Vars(r)["id"]
+	if userId == adminId {
+		http.Error(w, "You can't change your own role", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetUserById(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user.Role = requestBody.Role
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Admin %s set the role of user %s to %q", adminId, userId, requestBody.Role)
+
+	responseJson, err := json.Marshal(newAdminUserView(user))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// UpdateUserStatusHandler disables or re-enables an account. Disabling signs
+// the user out everywhere, revokes their API keys and app grants and pauses
+// their scheduled shares; re-enabling queues the shares again, but restores
+// none of the others.
+func (h *Handlers) UpdateUserStatusHandler(w http.ResponseWriter, r *http.Request) {
+	adminId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Disabled == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	userId := mux.Vars(r)["id"]
+	if userId == adminId {
+		http.Error(w, "You can't disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetUserById(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Disabling pauses the user's schedules, which are queued again when
+	// they are enabled
+	switch {
+	case *requestBody.Disabled && !user.Disabled:
+		if err := h.suspendUser(user); err != nil {
+			log.Printf("[ERROR] Failed to disable user %s: %v", userId, err)
+			writeError(w, err)
+			return
+		}
+		notifySecurityEvent(r, user, models.SecurityEventSessionRevoked, map[string]string{"scope": "all", "reason": "disabled_by_admin"})
+	case !*requestBody.Disabled && user.Disabled:
+		h.requeueSchedules(user)
+		user.Disabled = false
+		if err := repo.UpdateUser(userId, user); err != nil {
+			log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	log.Printf("[INFO] Admin %s set disabled=%t on user %s", adminId, user.Disabled, userId)
+
+	responseJson, err := json.Marshal(newAdminUserView(user))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+// GetSystemStatsHandler reports the size of the user base and the state of the
+// scheduler queue.
+func (h *Handlers) GetSystemStatsHandler(w http.ResponseWriter, r *http.Request) {
+	counts, err := repo.GetUserCounts()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := map[string]interface{}{
+		"users":   counts,
+		"regions": repo.Regions(),
+	}
+	if h.taskScheduler != nil {
+		response["scheduler"] = h.taskScheduler.Stats()
+	}
+
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+func writeWordPressAccount(w http.ResponseWriter, account *models.WordPressAccount) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"account": account,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetWordPressAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeWordPressAccount(w, user.WordPress)
+}
+
+// SetWordPressAccountHandler connects the WordPress site that articles are
+// republished to, after checking the application password with the site.
+func (h *Handlers) SetWordPressAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var requestBody struct {
+		Site     string `json:"site"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if requestBody.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+	siteURL, err := services.NormalizeWordPressSite(requestBody.Site)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	password, err := services.NormalizeWordPressPassword(requestBody.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	account, err := services.LookupWordPressAccount(userId, siteURL, requestBody.Username, password)
+	if errors.Is(err, apperrors.ErrProviderAuth) || errors.Is(err, apperrors.ErrNotFound) {
+		log.Printf("[WARN] The WordPress application password of user %s failed its check: %v", userId, err)
+		http.Error(w, "The site rejected the application password", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account.SealedPassword, err = services.SealUserSecret(user, password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account.ConnectedAt = utils.Now()
+	user.WordPress = account
+	user.WordPressVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the WordPress site %s", userId, account.SiteURL)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "wordpress", "action": "connected", "account": account.SiteURL})
+	writeWordPressAccount(w, account)
+}
+
+func (h *Handlers) DeleteWordPressAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.WordPress == nil {
+		http.Error(w, "No WordPress site connected", http.StatusNotFound)
+		return
+	}
+	user.WordPress = nil
+	user.WordPressVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected their WordPress site", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "wordpress", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const youtubeStateCookie = "youtube_oauth_state"
+
+func writeYouTubeChannel(w http.ResponseWriter, channel *models.YouTubeChannel) {
+	responseJson, err := json.Marshal(map[string]interface{}{
+		"channel": channel,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (h *Handlers) GetYouTubeChannelHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	writeYouTubeChannel(w, user.YouTube)
+}
+
+// ConnectYouTubeHandler starts the Google OAuth flow for the user's YouTube
+// channel. Only read access is asked for, and only once: the token is used
+// to look up the channel and then dropped.
+func (h *Handlers) ConnectYouTubeHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	state := uuid.New().String()
+	if err := repo.SetCache(state, userId, 10*time.Minute); err != nil {
+		log.Printf("[ERROR] Failed to store state in cache: %v", err)
+		http.Error(w, "Failed to store state in cache", http.StatusInternalServerError)
+		return
+	}
+	setStateCookie(w, youtubeStateCookie, state, 10*time.Minute)
+
+	http.Redirect(w, r, h.youtubeConfig.AuthCodeURL(state), http.StatusFound)
+}
+
+// YouTubeCallbackHandler connects the channel of the Google account the user
+// signed in with.
+func (h *Handlers) YouTubeCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	queryState := r.URL.Query().Get("state")
+	stateCookie, err := r.Cookie(youtubeStateCookie)
+	if err != nil || queryState == "" || stateCookie.Value != queryState {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	stateUser, exists := repo.GetCache(queryState)
+	if !exists || stateUser != userId {
+		log.Printf("[ERROR] Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusForbidden)
+		return
+	}
+	if err := repo.DeleteCache(queryState); err != nil {
+		log.Printf("[WARN] Failed to delete state from cache for the user id: %s and error is %s", userId, err)
+	}
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		log.Printf("[INFO] User %s didn't connect YouTube: %s", userId, reason)
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?youtube_error="+url.QueryEscape(reason), http.StatusSeeOther)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		log.Printf("[ERROR] Missing authorization code")
+		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user for the id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	token, err := h.youtubeConfig.Exchange(services.YouTubeContext(context.Background()), code)
+	if err != nil {
+		log.Printf("[ERROR] Failed to exchange the Google code of user %s: %v", userId, err)
+		http.Error(w, "Failed to exchange token", http.StatusBadGateway)
+		return
+	}
+	channel, err := services.LookupYouTubeChannel(userId, token.AccessToken)
+	if err != nil {
+		log.Printf("[ERROR] Failed to look up the YouTube channel of user %s: %v", userId, err)
+		http.Redirect(w, r, config.Get().FrontendURL+"/verification?youtube_error=no_channel", http.StatusSeeOther)
+		return
+	}
+
+	user.YouTube = channel
+	user.YouTubeVerified = true
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s connected the YouTube channel %s", userId, channel.ChannelID)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "youtube", "action": "connected", "account": channel.Title})
+	http.Redirect(w, r, config.Get().FrontendURL+"/verification", http.StatusSeeOther)
+}
+
+func (h *Handlers) DeleteYouTubeChannelHandler(w http.ResponseWriter, r *http.Request) {
+	userId, err := ValidateLogin(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := repo.GetRequestUser(r.Context(), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.YouTube == nil {
+		http.Error(w, "No YouTube channel connected", http.StatusNotFound)
+		return
+	}
+	user.YouTube = nil
+	user.YouTubeVerified = false
+	services.RefreshVerified(user)
+	if err := repo.UpdateUser(userId, user); err != nil {
+		log.Printf("[ERROR] Failed to update user with id: %s and error is %s", userId, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] User with ID %s disconnected YouTube", userId)
+	notifySecurityEvent(r, user, models.SecurityEventConnection, map[string]string{"platform": "youtube", "action": "disconnected"})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
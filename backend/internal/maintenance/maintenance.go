@@ -0,0 +1,135 @@
+// Package maintenance switches the server in and out of maintenance mode.
+// While it is on, reads keep being served, writes are refused with 503 and
+// the scheduler holds due shares until it ends. The state is kept in the
+// cache collection so every instance follows it; each instance polls it with
+// Watch and keeps the last state it saw if the poll fails.
+package maintenance
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// DefaultRetryAfter is suggested to clients when maintenance has no expected
+// end, or has overrun it.
+const DefaultRetryAfter = time.Minute
+
+var (
+	current   atomic.Pointer[models.MaintenanceState]
+	mu        sync.Mutex
+	listeners []func(models.MaintenanceState)
+)
+
+// Current returns the maintenance state this instance follows.
+func Current() models.MaintenanceState {
+	if state := current.Load(); state != nil {
+		return *state
+	}
+	return models.MaintenanceState{}
+}
+
+// Active reports whether maintenance mode is on.
+func Active() bool {
+	return Current().Enabled
+}
+
+// RetryAfter is how long clients refused during maintenance should wait
+// before trying again.
+func RetryAfter(state models.MaintenanceState, now time.Time) time.Duration {
+	if wait := state.EndsAt.Sub(now); wait > 0 {
+		return wait
+	}
+	return DefaultRetryAfter
+}
+
+// OnChange registers fn to be called whenever maintenance mode is turned on
+// or off on this instance.
+func OnChange(fn func(models.MaintenanceState)) {
+	mu.Lock()
+	defer mu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Start turns maintenance mode on for every instance. A zero endsAt leaves
+// the expected end open.
+func Start(message string, endsAt time.Time) (models.MaintenanceState, error) {
+	state := models.MaintenanceState{
+		Enabled:   true,
+		Message:   message,
+		StartedAt: utils.Now(),
+		EndsAt:    endsAt,
+	}
+	if previous := Current(); previous.Enabled {
+		// Updating the message or estimate doesn't restart maintenance
+		state.StartedAt = previous.StartedAt
+	}
+	if err := repo.StoreMaintenance(state); err != nil {
+		return models.MaintenanceState{}, err
+	}
+	apply(state)
+	return state, nil
+}
+
+// End turns maintenance mode off for every instance.
+func End() error {
+	if err := repo.ClearMaintenance(); err != nil {
+		return err
+	}
+	apply(models.MaintenanceState{})
+	return nil
+}
+
+// Refresh loads the state other instances may have changed.
+func Refresh() error {
+	state, err := repo.GetMaintenance()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &models.MaintenanceState{}
+	}
+	apply(*state)
+	return nil
+}
+
+// Watch refreshes the state every interval until stop is closed.
+func Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := Refresh(); err != nil {
+				log.Printf("[WARN] Failed to refresh the maintenance state, keeping the last one: %v", err)
+			}
+		}
+	}
+}
+
+// apply makes state current and tells the listeners when maintenance was
+// turned on or off.
+func apply(state models.MaintenanceState) {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := Current()
+	current.Store(&state)
+	if previous.Enabled == state.Enabled {
+		return
+	}
+	if state.Enabled {
+		log.Printf("[WARN] Maintenance mode is on: %s", state.Message)
+	} else {
+		log.Println("[INFO] Maintenance mode is off")
+	}
+	for _, fn := range listeners {
+		fn(state)
+	}
+}
@@ -0,0 +1,46 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if got := RetryAfter(models.MaintenanceState{Enabled: true}, now); got != DefaultRetryAfter {
+		t.Errorf("open-ended maintenance: retry after %s, want %s", got, DefaultRetryAfter)
+	}
+	state := models.MaintenanceState{Enabled: true, EndsAt: now.Add(20 * time.Minute)}
+	if got := RetryAfter(state, now); got != 20*time.Minute {
+		t.Errorf("retry after %s, want 20m", got)
+	}
+	if got := RetryAfter(state, now.Add(time.Hour)); got != DefaultRetryAfter {
+		t.Errorf("overrun maintenance: retry after %s, want %s", got, DefaultRetryAfter)
+	}
+}
+
+func TestApplyNotifiesOnToggle(t *testing.T) {
+	var seen []bool
+	OnChange(func(state models.MaintenanceState) {
+		seen = append(seen, state.Enabled)
+	})
+	defer func() {
+		listeners = nil
+		apply(models.MaintenanceState{})
+	}()
+
+	apply(models.MaintenanceState{Enabled: true, Message: "Upgrading the database"})
+	apply(models.MaintenanceState{Enabled: true, Message: "Almost done"})
+	if !Active() || Current().Message != "Almost done" {
+		t.Errorf("current = %+v", Current())
+	}
+	apply(models.MaintenanceState{})
+	if Active() {
+		t.Error("still active after turning maintenance off")
+	}
+	if len(seen) != 2 || !seen[0] || seen[1] {
+		t.Errorf("listeners saw %v, want on then off", seen)
+	}
+}
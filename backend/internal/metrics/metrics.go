@@ -0,0 +1,127 @@
+// Package metrics keeps a small set of in-process counters and gauges and
+// renders them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+// Counter is one series of a CounterVec.
+type Counter struct {
+	vec    *CounterVec
+	series string
+}
+
+// GetMetricWithLabelValues returns the series identified by the label values,
+// which must be given in the order the labels were declared.
+func (c *CounterVec) GetMetricWithLabelValues(labelValues ...string) (Counter, error) {
+	if len(labelValues) != len(c.labels) {
+		return Counter{}, fmt.Errorf("metrics: %s expects %d label values, got %d", c.name, len(c.labels), len(labelValues))
+	}
+	return Counter{vec: c, series: formatLabels(c.labels, labelValues)}, nil
+}
+
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+func (c Counter) Add(delta float64) {
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	c.vec.values[c.series] += delta
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	series := make([]string, 0, len(c.values))
+	for labels := range c.values {
+		series = append(series, labels)
+	}
+	sort.Strings(series)
+	for _, labels := range series {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, labels, c.values[labels])
+	}
+}
+
+// GaugeFunc is a gauge whose value is computed at scrape time.
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.fn())
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(values[i])
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// WriteText renders every registered metric in the Prometheus text format.
+func WriteText(w io.Writer) {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	WriteText(w)
+}
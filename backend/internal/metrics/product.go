@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"fmt"
+
+	"social-scribe/backend/internal/models"
+)
+
+// Product metrics carry cohort labels so adoption can be compared by plan and
+// by the ISO week a user signed up in.
+var (
+	Signups = NewCounterVec("socialscribe_signups_total",
+		"Accounts created.", "plan")
+	Logins = NewCounterVec("socialscribe_logins_total",
+		"Successful logins.", "plan", "signup_week")
+	Shares = NewCounterVec("socialscribe_shares_total",
+		"Posts published to a platform.", "platform", "outcome", "plan", "signup_week")
+	Schedules = NewCounterVec("socialscribe_schedules_total",
+		"Blogs scheduled for sharing.", "plan", "signup_week")
+	SchedulerRuns = NewCounterVec("socialscribe_scheduler_runs_total",
+		"Scheduled tasks executed by the scheduler.", "outcome")
+)
+
+// Cohort returns the plan and signup week labels for a user. Accounts created
+// before signup dates were recorded fall into the "unknown" week.
+func Cohort(user *models.User) (plan, signupWeek string) {
+	plan = user.Plan
+	if plan == "" {
+		plan = models.PlanFree
+	}
+	if user.CreatedAt.IsZero() {
+		return plan, "unknown"
+	}
+	year, week := user.CreatedAt.UTC().ISOWeek()
+	return plan, fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Outcome maps an error to the outcome label used by the counters.
+func Outcome(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// AdminMiddleware restricts operator endpoints to requests presenting the
+// ADMIN_API_TOKEN in the X-Admin-Token header. Admin routes are disabled
+// entirely when no token is configured.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminToken := os.Getenv("ADMIN_API_TOKEN")
+		if adminToken == "" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		provided := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,21 +1,88 @@
 package middlewares
 
 import (
-	"context"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	repo "social-scribe/backend/internal/repositories"
 	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type contextKey string
+// lastActivity remembers when the activity of each user and API key was last
+// written so their documents are touched at most once per hour.
+var lastActivity sync.Map
 
-const userIDKey contextKey = "userID"
+const (
+	// activityWriters is how many activity writes run at once.
+	activityWriters = 4
+	// maxQueuedActivity bounds the activity writes waiting for a writer.
+	maxQueuedActivity = 256
+)
+
+var (
+	activityQueue        = make(chan func(), maxQueuedActivity)
+	startActivityWriters sync.Once
+)
+
+// touchActivity writes the activity of key off the request path, at most once
+// per hour. A write that fails, or finds the queue full, is retried on a
+// later request.
+func touchActivity(key string, write func(now time.Time) error) {
+	now := utils.Now()
+	if last, ok := lastActivity.Load(key); ok && now.Sub(last.(time.Time)) < time.Hour {
+		return
+	}
+	lastActivity.Store(key, now)
+	startActivityWriters.Do(func() {
+		for i := 0; i < activityWriters; i++ {
+			go func() {
+				for write := range activityQueue {
+					write()
+				}
+			}()
+		}
+	})
+	select {
+	case activityQueue <- func() {
+		if err := write(now); err != nil {
+			lastActivity.CompareAndDelete(key, now)
+		}
+	}:
+	default:
+		lastActivity.CompareAndDelete(key, now)
+	}
+}
+
+func recordActivity(userID string) {
+	touchActivity(userID, func(now time.Time) error {
+		return repo.TouchUserActivity(userID, now)
+	})
+}
+
+// AuthMiddleware handles authentication and rate limiting. The limit is
+// evaluated per request so it follows configuration reloads.
+func AuthMiddleware(limit func() int, duration time.Duration, next http.Handler) http.Handler {
+	serveUser := func(w http.ResponseWriter, r *http.Request, userID string) {
+		// Apply rate limiting per user
+		if repo.IsRateLimited(userID, limit(), duration) {
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		recordActivity(userID)
+
+		// Store userID in context and share one user load across the request
+		ctx := repo.WithRequestUserCache(utils.WithUserID(r.Context(), userID))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
 
-// AuthMiddleware handles authentication and rate limiting
-func AuthMiddleware(limit int, duration time.Duration, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Validate session
 		cookie, err := r.Cookie("session_token")
@@ -24,6 +91,16 @@ func AuthMiddleware(limit int, duration time.Duration, next http.Handler) http.H
 			return
 		}
 
+		if services.IsSessionJWT(cookie.Value) {
+			userID, err := services.VerifySessionJWT(cookie.Value)
+			if err != nil {
+				http.Error(w, "Unauthorized: Invalid or expired session", http.StatusUnauthorized)
+				return
+			}
+			serveUser(w, r, userID)
+			return
+		}
+
 		sessionData, exists := repo.GetCache(cookie.Value)
 		if !exists {
 			http.Error(w, "Unauthorized: Invalid or expired session", http.StatusUnauthorized)
@@ -36,7 +113,7 @@ func AuthMiddleware(limit int, duration time.Duration, next http.Handler) http.H
 			return
 		}
 
-		if session.ExpiresAt.Before(time.Now()) {
+		if session.ExpiresAt.Before(utils.Now()) {
 			http.Error(w, "Unauthorized: Session expired", http.StatusUnauthorized)
 			return
 		}
@@ -47,16 +124,93 @@ func AuthMiddleware(limit int, duration time.Duration, next http.Handler) http.H
 			return
 		}
 
-		userID := oid.Hex()
+		serveUser(w, r, oid.Hex())
+	})
+}
 
-		// Apply rate limiting per user
-		if repo.IsRateLimited(userID, limit, duration) {
+// ScopedAuthMiddleware is AuthMiddleware for routes that third-party OAuth
+// clients and scripts may call: a bearer access token or personal API key
+// granting scope is accepted in place of the session cookie. Token requests
+// are rate limited per client and user, key requests per key.
+func ScopedAuthMiddleware(scope string, limit func() int, duration time.Duration, next http.Handler) http.Handler {
+	sessionAuth := AuthMiddleware(limit, duration, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			sessionAuth.ServeHTTP(w, r)
+			return
+		}
+		if token := strings.TrimPrefix(authorization, "Bearer "); services.IsAPIKey(token) {
+			serveAPIKey(w, r, token, scope, limit, duration, next)
+			return
+		}
+
+		grant, err := repo.GetOAuthAccessToken(services.HashOAuthSecret(strings.TrimPrefix(authorization, "Bearer ")), utils.Now())
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if grant == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "Unauthorized: Invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+		if !slices.Contains(grant.Scopes, scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			http.Error(w, "Forbidden: Access token lacks the required scope", http.StatusForbidden)
+			return
+		}
+
+		if repo.IsRateLimited("oauth:"+grant.ClientID+":"+grant.UserID, limit(), duration) {
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
 
-		// Store userID in context and call next handler
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		ctx := repo.WithRequestUserCache(utils.WithUserID(r.Context(), grant.UserID))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// serveAPIKey authenticates a request made with a personal API key.
+func serveAPIKey(w http.ResponseWriter, r *http.Request, token, scope string, limit func() int, duration time.Duration, next http.Handler) {
+	key, err := repo.GetAPIKeyByHash(services.HashOAuthSecret(token), utils.Now())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Unauthorized: Invalid, revoked or expired API key", http.StatusUnauthorized)
+		return
+	}
+	// Disabling an account revokes its keys; this covers a key created while
+	// that was under way
+	ctx := repo.WithRequestUserCache(utils.WithUserID(r.Context(), key.UserID))
+	user, err := repo.GetRequestUser(ctx, key.UserID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.Disabled {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Unauthorized: The API key's account is disabled", http.StatusUnauthorized)
+		return
+	}
+	if !slices.Contains(key.Scopes, scope) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+		http.Error(w, "Forbidden: API key lacks the required scope", http.StatusForbidden)
+		return
+	}
+
+	if repo.IsRateLimited("apikey:"+key.Id, limit(), duration) {
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return
+	}
+
+	touchActivity("apikey:"+key.Id, func(now time.Time) error {
+		return repo.TouchAPIKey(key.Id, now)
+	})
+	recordActivity(key.UserID)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
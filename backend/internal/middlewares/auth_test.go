@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+// useUnreachableRedis points the rate limiter at a Redis that refuses
+// connections. Limits fail open, so requests go through unlimited.
+func useUnreachableRedis(t *testing.T) {
+	t.Helper()
+	previous := repo.RedisClient
+	repo.RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() {
+		repo.RedisClient.Close()
+		repo.RedisClient = previous
+	})
+}
+
+// newAPIKey stores a key with the scopes for the user and returns its id and
+// token.
+func newAPIKey(t *testing.T, userID string, expiresAt time.Time, scopes ...string) (string, string) {
+	t.Helper()
+	token, tokenHash, err := services.NewOAuthSecret(services.APIKeyPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := models.APIKey{
+		Id:        fmt.Sprintf("key%d", time.Now().UnixNano()),
+		UserID:    userID,
+		Name:      "CI",
+		Prefix:    token[:len(services.APIKeyPrefix)+6],
+		TokenHash: tokenHash,
+		Scopes:    scopes,
+		CreatedAt: utils.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := repo.CreateAPIKey(key); err != nil {
+		t.Fatal(err)
+	}
+	return key.Id, token
+}
+
+func TestScopedAuthMiddlewareAPIKeys(t *testing.T) {
+	useTestDB(t)
+	useUnreachableRedis(t)
+	var servedAs string
+	handler := ScopedAuthMiddleware("shares:read", func() int { return 100 }, time.Minute, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servedAs, _ = utils.GetUserID(r.Context())
+	}))
+	newUser := func(disabled bool) string {
+		t.Helper()
+		userID, err := repo.CreateUser(models.User{UserName: fmt.Sprintf("key-owner-%d", time.Now().UnixNano()), Region: models.RegionDefault, Disabled: disabled})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return userID
+	}
+
+	owner := newUser(false)
+	_, valid := newAPIKey(t, owner, time.Time{}, "shares:read")
+	_, otherScope := newAPIKey(t, owner, time.Time{}, "posts:read")
+	_, expired := newAPIKey(t, owner, utils.Now().Add(-time.Minute), "shares:read")
+	revokedID, revoked := newAPIKey(t, owner, time.Time{}, "shares:read")
+	if err := repo.RevokeAPIKey(owner, revokedID); err != nil {
+		t.Fatal(err)
+	}
+	_, ofDisabled := newAPIKey(t, newUser(true), time.Time{}, "shares:read")
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid", token: valid, status: http.StatusOK},
+		{name: "unknown", token: services.APIKeyPrefix + "unknown", status: http.StatusUnauthorized},
+		{name: "revoked", token: revoked, status: http.StatusUnauthorized},
+		{name: "expired", token: expired, status: http.StatusUnauthorized},
+		{name: "disabled account", token: ofDisabled, status: http.StatusUnauthorized},
+		{name: "without the scope", token: otherScope, status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			servedAs = ""
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/shared-blogs", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, want %d; body %q", rec.Code, tc.status, rec.Body.String())
+			}
+			want := ""
+			if tc.status == http.StatusOK {
+				want = owner
+			}
+			if servedAs != want {
+				t.Errorf("served as %q, want %q", servedAs, want)
+			}
+		})
+	}
+}
+
+func TestTouchActivityOncePerHour(t *testing.T) {
+	clock := utils.NewFakeClock(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	defer utils.SetClock(utils.SetClock(clock))
+	key := fmt.Sprintf("hourly:%d", time.Now().UnixNano())
+	writes := make(chan time.Time, 1)
+	var failure error
+	write := func(now time.Time) error {
+		err := failure
+		writes <- now
+		return err
+	}
+	wantWrite := func(what string, at time.Time) {
+		t.Helper()
+		select {
+		case written := <-writes:
+			if !written.Equal(at) {
+				t.Errorf("%s: wrote %v, want %v", what, written, at)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: nothing was written", what)
+		}
+	}
+
+	touchActivity(key, write)
+	wantWrite("first request", clock.Now())
+	clock.Advance(59 * time.Minute)
+	touchActivity(key, write)
+	select {
+	case <-writes:
+		t.Error("wrote again within the hour")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A failed write is retried by the next request
+	clock.Advance(time.Minute)
+	failure = errors.New("database unavailable")
+	touchActivity(key, write)
+	wantWrite("an hour later", clock.Now())
+	for deadline := time.Now().Add(time.Second); ; {
+		if _, ok := lastActivity.Load(key); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the failed write is still recorded as done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	failure = nil
+	touchActivity(key, write)
+	wantWrite("after the failed write", clock.Now())
+}
+
+func TestTouchActivityDropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	blocked := func(time.Time) error {
+		<-release
+		return nil
+	}
+
+	// The writers and the queue can't hold them all
+	keys := make([]string, activityWriters+maxQueuedActivity+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("full:%d:%d", time.Now().UnixNano(), i)
+		touchActivity(keys[i], blocked)
+	}
+	dropped := 0
+	for _, key := range keys {
+		if _, ok := lastActivity.Load(key); !ok {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Error("every write was queued, or dropped writes were recorded as done")
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"social-scribe/backend/internal/config"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// ConsentMiddleware refuses requests that could write from users who haven't
+// accepted the current terms of service and privacy policy, naming the
+// versions to accept. Reads pass through so the user can still look around.
+// It must run inside AuthMiddleware, which puts the signed in user on the
+// context.
+func ConsentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		terms := config.Get().Terms
+		if (terms.TermsOfService == "" && terms.PrivacyPolicy == "") || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := utils.GetUserID(r.Context())
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := repo.GetRequestUser(r.Context(), userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load user %s to check their consent: %v", userID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if user.HasConsented(terms.TermsOfService, terms.PrivacyPolicy) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"success":          false,
+			"reason":           "consent_required",
+			"terms_of_service": terms.TermsOfService,
+			"privacy_policy":   terms.PrivacyPolicy,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+	})
+}
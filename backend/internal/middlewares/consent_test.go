@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"social-scribe/backend/internal/config"
+)
+
+func TestConsentMiddleware(t *testing.T) {
+	called := false
+	handler := ConsentMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	serve := func(method string) int {
+		called = false
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/blogs/schedule", nil))
+		return rec.Code
+	}
+
+	if status := serve(http.MethodPost); status != http.StatusOK || !called {
+		t.Errorf("without terms: status = %d, called = %t", status, called)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"terms": {"terms_of_service": "2026-01", "privacy_policy": "2026-02"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	if err := config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Setenv("CONFIG_FILE", "")
+		config.Reload()
+	}()
+
+	if status := serve(http.MethodGet); status != http.StatusOK || !called {
+		t.Errorf("read with terms: status = %d, called = %t", status, called)
+	}
+	if status := serve(http.MethodPost); status != http.StatusUnauthorized || called {
+		t.Errorf("write without a user: status = %d, called = %t", status, called)
+	}
+}
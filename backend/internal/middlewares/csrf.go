@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/services"
+)
+
+// CSRFMiddleware rejects state-changing requests authenticated by the session
+// cookie unless they carry the session's CSRF token in the X-CSRF-Token
+// header. Requests without a session cookie are left to the auth middleware.
+// On routes accepting OAuth access tokens, bearer requests are exempt: a
+// browser never attaches those on its own.
+func CSRFMiddleware(bearerAllowed bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if bearerAllowed && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie("session_token")
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !services.ValidCSRFToken(cookie.Value, r.Header.Get(services.CSRFHeader)) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"success": false, "reason": "missing or invalid CSRF token"}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
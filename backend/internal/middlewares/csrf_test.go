@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-scribe/backend/internal/services"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "test-csrf-secret")
+	session := "5f0c8a4e-7d3b-4b8e-9a61-2c4d7e9f1a3b"
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	cases := []struct {
+		name          string
+		method        string
+		bearerAllowed bool
+		cookie        bool
+		token         string
+		bearer        bool
+		status        int
+	}{
+		{name: "safe method", method: http.MethodGet, cookie: true, status: http.StatusOK},
+		{name: "missing token", method: http.MethodPost, cookie: true, status: http.StatusForbidden},
+		{name: "token of another session", method: http.MethodDelete, cookie: true, token: services.CSRFToken("other-session"), status: http.StatusForbidden},
+		{name: "valid token", method: http.MethodPost, cookie: true, token: services.CSRFToken(session), status: http.StatusOK},
+		{name: "no session left to auth", method: http.MethodPost, status: http.StatusOK},
+		{name: "bearer on scoped route", method: http.MethodPost, bearerAllowed: true, cookie: true, bearer: true, status: http.StatusOK},
+		{name: "bearer on session-only route", method: http.MethodPost, cookie: true, bearer: true, status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/v1/blogs/schedule", nil)
+			if tc.cookie {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: session})
+			}
+			if tc.token != "" {
+				req.Header.Set(services.CSRFHeader, tc.token)
+			}
+			if tc.bearer {
+				req.Header.Set("Authorization", "Bearer some-access-token")
+			}
+			rec := httptest.NewRecorder()
+			CSRFMiddleware(tc.bearerAllowed, ok).ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("status %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
+
+func TestCSRFWithoutSecret(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "")
+	if services.CheckCSRFSecret() == nil {
+		t.Error("started without CSRF_SECRET")
+	}
+	session := "5f0c8a4e-7d3b-4b8e-9a61-2c4d7e9f1a3b"
+	if services.ValidCSRFToken(session, services.CSRFToken(session)) {
+		t.Error("accepted a token signed with no key")
+	}
+}
@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// DebugTokenHeader carries an admin-issued debug token. Requests presenting a
+// live token are captured even when the user has not been flagged.
+const DebugTokenHeader = "X-Debug-Token"
+
+const maxDebugCaptureBodySize = 16 * 1024
+
+// maxDebugCaptureReadSize bounds the bodies held for redaction. They are
+// redacted whole, before truncating to maxDebugCaptureBodySize, and dropped
+// when larger.
+const maxDebugCaptureReadSize = 1 << 20
+
+const droppedLargeBody = "[body dropped: too large to redact]"
+
+// Debug capture is configured through the environment:
+//
+//	DEBUG_CAPTURE_SAMPLE_RATE  fraction of flagged requests to capture (default 1)
+//	DEBUG_CAPTURE_RETENTION    how long captures are kept (default 24h)
+
+func debugCaptureSampleRate() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("DEBUG_CAPTURE_SAMPLE_RATE"), 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+func debugCaptureRetention() time.Duration {
+	retention, err := time.ParseDuration(os.Getenv("DEBUG_CAPTURE_RETENTION"))
+	if err != nil || retention <= 0 {
+		return 24 * time.Hour
+	}
+	return retention
+}
+
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (cw *captureResponseWriter) WriteHeader(status int) {
+	cw.status = status
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *captureResponseWriter) Write(b []byte) (int, error) {
+	if remaining := maxDebugCaptureReadSize + 1 - cw.body.Len(); remaining > 0 {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		cw.body.Write(b[:remaining])
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// DebugCaptureMiddleware records redacted request/response pairs for users an
+// admin has flagged, or for requests carrying a live debug token. It must run
+// after AuthMiddleware to see the user ID of authenticated requests.
+func DebugCaptureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !config.Get().FeatureEnabled("debug_capture") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, _ := utils.GetUserID(r.Context())
+		debugToken := r.Header.Get(DebugTokenHeader)
+
+		enabled := debugToken != "" && repo.IsDebugCaptureEnabled("token:"+debugToken)
+		if !enabled {
+			debugToken = ""
+			enabled = userID != "" && repo.IsDebugCaptureEnabled("user:"+userID)
+		}
+		if !enabled || rand.Float64() >= debugCaptureSampleRate() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Keep the handler's view of the body intact while holding a copy
+		requestBody, _ := io.ReadAll(io.LimitReader(r.Body, maxDebugCaptureReadSize+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+
+		recorder := &captureResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		start := utils.Now()
+		next.ServeHTTP(recorder, r)
+		now := utils.Now()
+
+		capture := models.DebugCapture{
+			UserID:          userID,
+			DebugToken:      debugToken,
+			RequestID:       utils.GetRequestID(r.Context()),
+			Method:          r.Method,
+			Path:            redactPath(r),
+			Query:           utils.RedactQuery(r.URL.RawQuery),
+			RequestHeaders:  redactHeaders(r.Header),
+			RequestBody:     redactCapturedBody(requestBody, r.Header.Get("Content-Type")),
+			StatusCode:      recorder.status,
+			ResponseHeaders: redactHeaders(recorder.Header()),
+			ResponseBody:    redactCapturedBody(recorder.body.Bytes(), recorder.Header().Get("Content-Type")),
+			DurationMs:      now.Sub(start).Milliseconds(),
+			CreatedAt:       now,
+			ExpiresAt:       now.Add(debugCaptureRetention()),
+		}
+		go func() {
+			if err := repo.StoreDebugCapture(capture); err != nil {
+				log.Printf("[WARN] Failed to store debug capture for request %s: %v", capture.RequestID, err)
+			}
+		}()
+	})
+}
+
+// redactPath is the path of the request with the route variables holding
+// credentials or personal data masked.
+func redactPath(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.URL.Path
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return r.URL.Path
+	}
+	return utils.RedactPath(template, mux.Vars(r))
+}
+
+// redactCapturedBody redacts a captured body, which is dropped when it was cut
+// short, since a partial body doesn't parse.
+func redactCapturedBody(body []byte, contentType string) string {
+	if len(body) > maxDebugCaptureReadSize {
+		return droppedLargeBody
+	}
+	return utils.RedactCapturedBody(body, contentType, maxDebugCaptureBodySize)
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name := range header {
+		if utils.IsSensitiveKey(name) {
+			redacted[name] = "[REDACTED]"
+			continue
+		}
+		redacted[name] = header.Get(name)
+	}
+	return redacted
+}
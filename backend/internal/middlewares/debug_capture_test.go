@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDebugCaptureRedaction(t *testing.T) {
+	query := utils.RedactQuery("code=abc&state=xyz&token=t0k&platform=linkedin&code_verifier=v")
+	if query != "code=[REDACTED]&state=[REDACTED]&token=[REDACTED]&platform=linkedin&code_verifier=[REDACTED]" {
+		t.Errorf("query = %q", query)
+	}
+	if query := utils.RedactQuery("token=a;b"); strings.Contains(query, "a;b") {
+		t.Errorf("kept an unparseable query: %q", query)
+	}
+
+	form := redactCapturedBody([]byte("grant_type=authorization_code&code=abc&client_secret=s3cret"), "application/x-www-form-urlencoded")
+	if strings.Contains(form, "abc") || strings.Contains(form, "s3cret") || !strings.Contains(form, "grant_type=authorization_code") {
+		t.Errorf("form = %q", form)
+	}
+
+	// A secret past the stored size is still masked before truncating
+	large := `{"padding": "` + strings.Repeat("x", maxDebugCaptureBodySize) + `", "password": "hunter2"}`
+	body := redactCapturedBody([]byte(large), "application/json")
+	if strings.Contains(body, "hunter2") || !strings.HasSuffix(body, "...[truncated]") {
+		t.Errorf("large JSON body kept its secret or wasn't truncated: %q", body[len(body)-40:])
+	}
+	if body := redactCapturedBody([]byte("<html>token hunter2</html>"), "text/html"); strings.Contains(body, "hunter2") {
+		t.Errorf("stored an unparsed body: %q", body)
+	}
+	if body := redactCapturedBody(make([]byte, maxDebugCaptureReadSize+1), "application/json"); body != droppedLargeBody {
+		t.Errorf("stored a body cut short: %q", body[:40])
+	}
+}
+
+func TestDebugCapturePathRedaction(t *testing.T) {
+	var captured string
+	router := mux.NewRouter()
+	router.HandleFunc("/subscribers/{email}/reset/{token}/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		captured = redactPath(r)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/subscribers/a%40example.com/reset/t0k/42", nil))
+	if captured != "/subscribers/[REDACTED]/reset/[REDACTED]/42" {
+		t.Errorf("path = %q", captured)
+	}
+
+	if path := redactPath(httptest.NewRequest(http.MethodGet, "/unrouted", nil)); path != "/unrouted" {
+		t.Errorf("path without a route = %q", path)
+	}
+}
@@ -7,10 +7,10 @@ import (
 )
 
 // IPRateLimitMiddleware applies rate limiting per IP for public routes
-func IPRateLimitMiddleware(limit int, duration time.Duration) func(http.Handler) http.Handler {
+func IPRateLimitMiddleware(limit func() int, duration time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if services.IsIPRateLimited(r, limit, duration) {
+			if services.IsIPRateLimited(r, limit(), duration) {
 				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 				return
 			}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"social-scribe/backend/internal/maintenance"
+	"social-scribe/backend/internal/utils"
+)
+
+// MaintenanceMiddleware refuses requests that could write while maintenance
+// mode is on, telling clients when to retry. Reads pass through, unless
+// writes says the route's GETs change state too, as OAuth callbacks do.
+func MaintenanceMiddleware(writes bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := maintenance.Current()
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if !state.Enabled || r.Method == http.MethodOptions || (read && !writes) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		retryAfter := maintenance.RetryAfter(state, utils.Now())
+		body, _ := json.Marshal(map[string]interface{}{
+			"success": false,
+			"reason":  "maintenance",
+			"message": state.Message,
+		})
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(body)
+	})
+}
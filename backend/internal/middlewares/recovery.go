@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"social-scribe/backend/internal/reporting"
+	"social-scribe/backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID, which RequestIDMiddleware also sets
+// on the response so clients can quote it.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware tags every request with an ID, reusing a sane incoming
+// X-Request-ID so traces can be correlated with an upstream proxy.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(utils.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// RecoveryMiddleware converts panics in handlers into a 500 JSON response,
+// logging the stack trace and forwarding the panic to the error reporter.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			requestID := utils.GetRequestID(r.Context())
+			log.Printf("[ERROR] Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID, rec, debug.Stack())
+			reporting.Report(r.Context(), fmt.Errorf("panic: %v", rec), map[string]string{
+				"request_id": requestID,
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			})
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"success":    false,
+				"reason":     "Internal server error",
+				"request_id": requestID,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(body)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
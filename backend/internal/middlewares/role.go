@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// AdminRoleMiddleware restricts a route to users with the admin role. It must
+// run inside AuthMiddleware, which puts the signed in user on the context.
+func AdminRoleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := utils.GetUserID(r.Context())
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := repo.GetRequestUser(r.Context(), userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load user %s to check their role: %v", userID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || !user.IsAdmin() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// useTestDB points the repositories at a throwaway database on the MongoDB
+// named by MONGO_TEST_URI, skipping the test when it isn't set.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	dbName := fmt.Sprintf("social-scribe-test-%d", time.Now().UnixNano())
+	if err := repo.Connect(uri, dbName); err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { repo.Disconnect(dbName) })
+}
+
+func TestAdminRoleMiddlewareNeedsAuthenticatedUser(t *testing.T) {
+	called := false
+	handler := AdminRoleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if called {
+		t.Error("admin handler ran without an authenticated user")
+	}
+}
+
+func TestAdminRoleMiddlewareChecksRole(t *testing.T) {
+	useTestDB(t)
+	called := false
+	handler := AdminRoleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	serveAs := func(role string) int {
+		t.Helper()
+		userID, err := repo.CreateUser(models.User{UserName: "role-" + role, Role: role, Region: models.RegionDefault})
+		if err != nil {
+			t.Fatal(err)
+		}
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+		req = req.WithContext(repo.WithRequestUserCache(utils.WithUserID(req.Context(), userID)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if status := serveAs(""); status != http.StatusForbidden || called {
+		t.Errorf("non-admin: status = %d, called = %t", status, called)
+	}
+	if status := serveAs(models.RoleAdmin); status != http.StatusOK || !called {
+		t.Errorf("admin: status = %d, called = %t", status, called)
+	}
+}
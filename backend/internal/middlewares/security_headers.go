@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SecurityHeadersConfig controls the headers added by SecurityHeadersMiddleware.
+// An empty value disables the corresponding header.
+type SecurityHeadersConfig struct {
+	StrictTransportSecurity string
+	ContentTypeOptions      string
+	ReferrerPolicy          string
+	FrameOptions            string
+	ContentSecurityPolicy   string
+}
+
+// SecurityHeadersConfigFromEnv builds the header configuration for APP_ENV.
+// HSTS is only sent in production, where the API is served over TLS; sending
+// it from a plain-HTTP development host would pin browsers to HTTPS for it.
+//
+//	APP_ENV          "production" enables HSTS (default "development")
+//	HSTS_MAX_AGE     HSTS max-age in seconds (default one year)
+//	REFERRER_POLICY  overrides the default "no-referrer"
+func SecurityHeadersConfigFromEnv() SecurityHeadersConfig {
+	config := SecurityHeadersConfig{
+		ContentTypeOptions:    "nosniff",
+		ReferrerPolicy:        "no-referrer",
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	}
+
+	if strings.EqualFold(os.Getenv("APP_ENV"), "production") {
+		maxAge := 31536000
+		if v, err := strconv.Atoi(os.Getenv("HSTS_MAX_AGE")); err == nil && v >= 0 {
+			maxAge = v
+		}
+		config.StrictTransportSecurity = fmt.Sprintf("max-age=%d; includeSubDomains", maxAge)
+	}
+	if policy := os.Getenv("REFERRER_POLICY"); policy != "" {
+		config.ReferrerPolicy = policy
+	}
+	return config
+}
+
+// SecurityHeadersMiddleware sets the configured security headers on every
+// response before the wrapped handler runs.
+func SecurityHeadersMiddleware(config SecurityHeadersConfig) func(http.Handler) http.Handler {
+	headers := map[string]string{
+		"Strict-Transport-Security": config.StrictTransportSecurity,
+		"X-Content-Type-Options":    config.ContentTypeOptions,
+		"Referrer-Policy":           config.ReferrerPolicy,
+		"X-Frame-Options":           config.FrameOptions,
+		"Content-Security-Policy":   config.ContentSecurityPolicy,
+	}
+	for name, value := range headers {
+		if value == "" {
+			delete(headers, name)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
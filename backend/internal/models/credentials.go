@@ -0,0 +1,132 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Credential fields of a User, and of the structs it holds, are tagged
+// seal:"user" when they are sealed with the user's data key. Credentials
+// stored before they were sealed are kept, until they are, in a field tagged
+// seal:"plaintext" beside the sealed one, named Plaintext instead of Sealed.
+const (
+	SealTag       = "seal"
+	SealUser      = "user"
+	SealPlaintext = "plaintext"
+)
+
+// CredentialField is a credential field of a user.
+type CredentialField struct {
+	// Root is the bson name of the User field the credential is in.
+	Root  string
+	Value *string
+	// Sealed is the field a plaintext credential is sealed into; nil for
+	// the sealed ones.
+	Sealed *string
+}
+
+// CredentialFields returns the user's credential fields tagged seal:kind that
+// are set.
+func (u *User) CredentialFields(kind string) []CredentialField {
+	var fields []CredentialField
+	userValue := reflect.ValueOf(u).Elem()
+	userType := userValue.Type()
+	for i := 0; i < userType.NumField(); i++ {
+		root := bsonName(userType.Field(i))
+		walkCredentials(userValue, i, func(parent reflect.Value, field reflect.StructField, value reflect.Value) {
+			if field.Tag.Get(SealTag) != kind || value.String() == "" {
+				return
+			}
+			credential := CredentialField{Root: root, Value: value.Addr().Interface().(*string)}
+			if kind == SealPlaintext {
+				sealed := parent.FieldByName("Sealed" + strings.TrimPrefix(field.Name, "Plaintext"))
+				credential.Sealed = sealed.Addr().Interface().(*string)
+			}
+			fields = append(fields, credential)
+		})
+	}
+	return fields
+}
+
+// walkCredentials calls visit with every string field of parent's field i,
+// or of the structs it holds directly or through a pointer.
+func walkCredentials(parent reflect.Value, i int, visit func(parent reflect.Value, field reflect.StructField, value reflect.Value)) {
+	field := parent.Type().Field(i)
+	value := parent.Field(i)
+	if !field.IsExported() {
+		return
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		visit(parent, field, value)
+	case reflect.Struct:
+		for j := 0; j < value.NumField(); j++ {
+			walkCredentials(value, j, visit)
+		}
+	}
+}
+
+// CredentialRoots returns the bson names of the User fields that hold
+// credentials.
+func CredentialRoots() []string {
+	var roots []string
+	userType := reflect.TypeOf(User{})
+	for i := 0; i < userType.NumField(); i++ {
+		if len(credentialPaths(userType.Field(i), "", "")) > 0 {
+			roots = append(roots, bsonName(userType.Field(i)))
+		}
+	}
+	return roots
+}
+
+// CredentialPaths returns the bson paths of the User's credential fields
+// tagged seal:kind.
+func CredentialPaths(kind string) []string {
+	var paths []string
+	userType := reflect.TypeOf(User{})
+	for i := 0; i < userType.NumField(); i++ {
+		paths = append(paths, credentialPaths(userType.Field(i), "", kind)...)
+	}
+	return paths
+}
+
+// credentialPaths returns the bson paths of the credential fields tagged
+// seal:kind, or of any kind when kind is empty, in field, whose parent is at
+// prefix.
+func credentialPaths(field reflect.StructField, prefix, kind string) []string {
+	if !field.IsExported() {
+		return nil
+	}
+	path := prefix + bsonName(field)
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		if tag := field.Tag.Get(SealTag); tag != "" && (kind == "" || tag == kind) {
+			return []string{path}
+		}
+	case reflect.Struct:
+		var paths []string
+		for i := 0; i < fieldType.NumField(); i++ {
+			paths = append(paths, credentialPaths(fieldType.Field(i), path+".", kind)...)
+		}
+		return paths
+	}
+	return nil
+}
+
+func bsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("bson"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
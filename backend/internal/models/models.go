@@ -3,10 +3,16 @@ package models
 import (
 	"fmt"
 	"net/url"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/utils"
 )
 
 type User struct {
@@ -20,13 +26,857 @@ type User struct {
 	XVerified        bool               `json:"x_verified" bson:"x_verified"`
 	WebHookUrl       string             `json:"webhook_url" bson:"webhook_url"`
 	HashnodeBlog     string             `json:"hashnode_blog" bson:"hashnode_blog"`
-	XOAuthToken      string             `json:"x_oauth_token" bson:"x_oauth_token"`
-	XOAuthSecret     string             `json:"x_oauth_secret" bson:"x_oauth_secret"`
-	LinkedInOauthKey string             `json:"linkedin_oauth_key" bson:"linkedin_oauth_key"`
-	HashnodePAT      string             `json:"hashnode_pat" bson:"hashnode_pat"`
-	SharedBlogs      []SharedBlog       `json:"shared_posts" bson:"shared_posts"`
-	ScheduledBlogs   []ScheduledBlog    `json:"scheduled_posts" bson:"scheduled_posts"`
-	Notifications    []string           `json:"notifications" bson:"notifications"`
+	// The X, LinkedIn and Hashnode credentials are sealed with the user's
+	// data key. The Plaintext fields hold those stored before they were,
+	// until RewrapDataKeys seals them; they are not omitempty, so clearing
+	// them is saved.
+	SealedXOAuthToken              string          `json:"-" bson:"sealed_x_oauth_token" seal:"user"`
+	SealedXOAuthSecret             string          `json:"-" bson:"sealed_x_oauth_secret" seal:"user"`
+	SealedLinkedInOauthKey         string          `json:"-" bson:"sealed_linkedin_oauth_key" seal:"user"`
+	SealedHashnodePAT              string          `json:"-" bson:"sealed_hashnode_pat" seal:"user"`
+	SealedHashnodeWebhookSecret    string          `json:"-" bson:"sealed_hashnode_webhook_secret" seal:"user"`
+	PlaintextXOAuthToken           string          `json:"-" bson:"x_oauth_token" seal:"plaintext"`
+	PlaintextXOAuthSecret          string          `json:"-" bson:"x_oauth_secret" seal:"plaintext"`
+	PlaintextLinkedInOauthKey      string          `json:"-" bson:"linkedin_oauth_key" seal:"plaintext"`
+	PlaintextHashnodePAT           string          `json:"-" bson:"hashnode_pat" seal:"plaintext"`
+	PlaintextHashnodeWebhookSecret string          `json:"-" bson:"hashnode_webhook_secret" seal:"plaintext"`
+	SharedBlogs                    []SharedBlog    `json:"shared_posts" bson:"shared_posts"`
+	ScheduledBlogs                 []ScheduledBlog `json:"scheduled_posts" bson:"scheduled_posts"`
+	Notifications                  []string        `json:"notifications" bson:"notifications"`
+	Preferences                    Preferences     `json:"preferences" bson:"preferences"`
+	Plan                           string          `json:"plan" bson:"plan"`
+	CreatedAt                      time.Time       `json:"created_at" bson:"created_at"`
+	LastActiveAt                   time.Time       `json:"last_active_at" bson:"last_active_at"`
+	Email                          string          `json:"email,omitempty" bson:"email,omitempty"`
+	TeamID                         string          `json:"team_id,omitempty" bson:"team_id,omitempty"`
+	TeamRole                       string          `json:"team_role,omitempty" bson:"team_role,omitempty"`
+	SSOSubject                     string          `json:"-" bson:"sso_subject,omitempty"`
+	GoogleId                       string          `json:"-" bson:"google_id,omitempty"`
+	SCIMExternalID                 string          `json:"-" bson:"scim_external_id,omitempty"`
+	Region                         string          `json:"region" bson:"region"`
+	// Disabled accounts were deprovisioned by their team's IdP or disabled
+	// by an admin and cannot sign in. Not omitempty, so enabling them again
+	// is saved.
+	Disabled bool `json:"disabled,omitempty" bson:"disabled"`
+	// Role is RoleAdmin for users who may manage other users; empty for
+	// everyone else. Not omitempty, so taking the role away is saved.
+	Role string `json:"role,omitempty" bson:"role"`
+	// PostsSyncDueAt is when the posts of the user's Hashnode publication are
+	// next copied into the local posts collection; unset means now.
+	PostsSyncDueAt time.Time `json:"-" bson:"posts_sync_due_at,omitempty"`
+	// Feed is the RSS or Atom feed the user's blogs are listed from, for
+	// writers who don't blog on Hashnode. Not omitempty, so removing it is
+	// saved.
+	Feed *BlogFeed `json:"-" bson:"feed"`
+	// FeedSyncDueAt is when Feed is next polled for new items; unset means
+	// now.
+	FeedSyncDueAt time.Time `json:"-" bson:"feed_sync_due_at,omitempty"`
+	// Ghost is the Ghost site the user's blogs are listed from. Not
+	// omitempty, so removing it is saved.
+	Ghost *GhostSource `json:"-" bson:"ghost"`
+	// MediumSource is the Medium profile whose stories are listed as blogs,
+	// apart from Medium, the account blogs are republished to. Not
+	// omitempty, so removing it is saved.
+	MediumSource *MediumSource `json:"-" bson:"medium_source"`
+	// SecurityWebhook receives the account's security events; nil when the
+	// user has not registered one.
+	SecurityWebhook *SecurityWebhook `json:"-" bson:"security_webhook,omitempty"`
+	// RetentionDueAt is when the user's retention policy is next enforced;
+	// unset means now.
+	RetentionDueAt time.Time `json:"-" bson:"retention_due_at,omitempty"`
+	// PostingGoal is the user's weekly sharing goal. Not omitempty, so
+	// removing it is saved.
+	PostingGoal *PostingGoal `json:"-" bson:"posting_goal"`
+	// GoalReminderDueAt is when PostingGoal's reminder is next checked;
+	// unset means now.
+	GoalReminderDueAt time.Time `json:"-" bson:"goal_reminder_due_at,omitempty"`
+	// NotificationTimes holds when each notification was added, matched to
+	// the tail of Notifications; older notifications have no time.
+	NotificationTimes []time.Time `json:"-" bson:"notification_times"`
+	// LoginHistory holds the most recent sign-ins, oldest first.
+	LoginHistory []LoginRecord `json:"-" bson:"login_history,omitempty"`
+	// ConsentHistory holds every acceptance of the terms, oldest first. It
+	// is the record of what the user agreed to, so it is never trimmed.
+	ConsentHistory []ConsentRecord `json:"-" bson:"consent_history,omitempty"`
+	// MastodonInstance is the host of the Mastodon server the user
+	// connected, such as mastodon.social; MastodonAccount is their handle
+	// there.
+	MastodonInstance          string `json:"mastodon_instance,omitempty" bson:"mastodon_instance,omitempty"`
+	MastodonAccount           string `json:"mastodon_account,omitempty" bson:"mastodon_account,omitempty"`
+	SealedMastodonAccessToken string `json:"-" bson:"sealed_mastodon_access_token,omitempty" seal:"user"`
+	// PlaintextMastodonAccessToken is a token stored before tokens were
+	// sealed. Not omitempty, so clearing it is saved.
+	PlaintextMastodonAccessToken string `json:"-" bson:"mastodon_access_token" seal:"plaintext"`
+	MastodonVerified             bool   `json:"mastodon_verified" bson:"mastodon_verified,omitempty"`
+	// TwitterApp and LinkedInApp are the user's own apps, used for their
+	// requests instead of the shared ones. Not omitempty, so removing one
+	// is saved.
+	TwitterApp  *OAuthApp `json:"-" bson:"twitter_app"`
+	LinkedInApp *OAuthApp `json:"-" bson:"linkedin_app"`
+	// LinkedInPages are the LinkedIn Pages the member may post for, found
+	// when they granted the organization scopes, and LinkedInPageID is the
+	// one shares go to unless they say otherwise; empty means the member's
+	// own profile. Not omitempty, so removing them is saved.
+	LinkedInPages  []LinkedInPage `json:"-" bson:"linkedin_pages"`
+	LinkedInPageID string         `json:"-" bson:"linkedin_page_id"`
+	// HashnodePublications are the publications of the connected Hashnode
+	// account; HashnodeBlog is the host of the default one, whose posts are
+	// listed unless another is asked for. Not omitempty, so removing them
+	// is saved.
+	HashnodePublications []HashnodePublication `json:"-" bson:"hashnode_publications"`
+	// Reddit is the connected Reddit account and where shares go.
+	Reddit         RedditAccount `json:"reddit" bson:"reddit"`
+	RedditVerified bool          `json:"reddit_verified" bson:"reddit_verified,omitempty"`
+	// DiscordWebhook is where blogs are announced on Discord. Not
+	// omitempty, so removing it is saved.
+	DiscordWebhook  *DiscordWebhook `json:"-" bson:"discord_webhook"`
+	DiscordVerified bool            `json:"discord_verified" bson:"discord_verified,omitempty"`
+	// Devto is the Dev.to account full articles are republished to. Not
+	// omitempty, so removing it is saved.
+	Devto         *DevtoAccount `json:"-" bson:"devto"`
+	DevtoVerified bool          `json:"devto_verified" bson:"devto_verified,omitempty"`
+	// Medium is the Medium account full articles are republished to. Not
+	// omitempty, so removing it is saved.
+	Medium         *MediumAccount `json:"-" bson:"medium"`
+	MediumVerified bool           `json:"medium_verified" bson:"medium_verified,omitempty"`
+	// Nostr is the Nostr key blog notes are signed with and the relays they
+	// go to. Not omitempty, so removing it is saved.
+	Nostr         *NostrAccount `json:"-" bson:"nostr"`
+	NostrVerified bool          `json:"nostr_verified" bson:"nostr_verified,omitempty"`
+	// Lemmy is the Lemmy account and the communities blog links are
+	// submitted to. Not omitempty, so removing it is saved.
+	Lemmy         *LemmyAccount `json:"-" bson:"lemmy"`
+	LemmyVerified bool          `json:"lemmy_verified" bson:"lemmy_verified,omitempty"`
+	// WordPress is the WordPress site full articles are republished to. Not
+	// omitempty, so removing it is saved.
+	WordPress         *WordPressAccount `json:"-" bson:"wordpress"`
+	WordPressVerified bool              `json:"wordpress_verified" bson:"wordpress_verified,omitempty"`
+	// Matrix is the Matrix room blogs are announced in. Not omitempty, so
+	// removing it is saved.
+	Matrix         *MatrixRoom `json:"-" bson:"matrix"`
+	MatrixVerified bool        `json:"matrix_verified" bson:"matrix_verified,omitempty"`
+	// Tumblr is the Tumblr blog links are posted to. Not omitempty, so
+	// removing it is saved.
+	Tumblr         *TumblrAccount `json:"-" bson:"tumblr"`
+	TumblrVerified bool           `json:"tumblr_verified" bson:"tumblr_verified,omitempty"`
+	// TeamsWebhook is where blogs are announced on Microsoft Teams. Not
+	// omitempty, so removing it is saved.
+	TeamsWebhook  *TeamsWebhook `json:"-" bson:"teams_webhook"`
+	TeamsVerified bool          `json:"teams_verified" bson:"teams_verified,omitempty"`
+	// Newsletter is how blogs are sent to the user's email subscribers. Not
+	// omitempty, so removing it is saved.
+	Newsletter         *NewsletterAccount `json:"-" bson:"newsletter"`
+	NewsletterVerified bool               `json:"newsletter_verified" bson:"newsletter_verified,omitempty"`
+	// Substack is the Substack account blog summaries are posted to as
+	// Notes. Not omitempty, so removing it is saved.
+	Substack         *SubstackAccount `json:"-" bson:"substack"`
+	SubstackVerified bool             `json:"substack_verified" bson:"substack_verified,omitempty"`
+	// GoogleBusiness is the Google Business Profile "What's New" posts are
+	// published to. Not omitempty, so removing it is saved.
+	GoogleBusiness         *GoogleBusinessAccount `json:"-" bson:"google_business"`
+	GoogleBusinessVerified bool                   `json:"google_business_verified" bson:"google_business_verified,omitempty"`
+	// Instagram turns on caption kits for the user's Instagram account,
+	// which they post by hand. Not omitempty, so removing it is saved.
+	Instagram         *InstagramAccount `json:"-" bson:"instagram"`
+	InstagramVerified bool              `json:"instagram_verified" bson:"instagram_verified,omitempty"`
+	// ShareWebhook receives the user's shares as signed JSON. Not
+	// omitempty, so removing it is saved.
+	ShareWebhook         *ShareWebhook `json:"-" bson:"share_webhook"`
+	ShareWebhookVerified bool          `json:"share_webhook_verified" bson:"share_webhook_verified,omitempty"`
+	// YouTube is the channel community post kits are made for. Not
+	// omitempty, so removing it is saved.
+	YouTube         *YouTubeChannel `json:"-" bson:"youtube"`
+	YouTubeVerified bool            `json:"youtube_verified" bson:"youtube_verified,omitempty"`
+	// PublicProfile controls the user's public page; it is off unless the
+	// user turns it on.
+	PublicProfile PublicProfile `json:"-" bson:"public_profile"`
+	// DataKey encrypts the user's stored credentials, so a leaked data key
+	// exposes only this user's.
+	DataKey *DataKey `json:"-" bson:"data_key,omitempty"`
+}
+
+// DataKey is a user's data key, wrapped with a master key. Version counts
+// the user's data keys, going up each time the key is replaced.
+type DataKey struct {
+	Wrapped     string    `bson:"wrapped"`
+	MasterKeyID string    `bson:"master_key_id"`
+	Version     int       `bson:"version"`
+	CreatedAt   time.Time `bson:"created_at"`
+	RewrappedAt time.Time `bson:"rewrapped_at,omitempty"`
+}
+
+// DevtoAccount is a user's Dev.to connection. The API key is sealed with the
+// user's data key.
+type DevtoAccount struct {
+	Username     string    `json:"username" bson:"username"`
+	Name         string    `json:"name" bson:"name"`
+	SealedAPIKey string    `json:"-" bson:"sealed_api_key" seal:"user"`
+	ConnectedAt  time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// MediumAccount is a user's Medium connection through an integration token,
+// which is sealed with the user's data key. AuthorID is the id stories are
+// published under.
+type MediumAccount struct {
+	AuthorID    string    `json:"author_id" bson:"author_id"`
+	Username    string    `json:"username" bson:"username"`
+	Name        string    `json:"name" bson:"name"`
+	SealedToken string    `json:"-" bson:"sealed_token" seal:"user"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// NostrAccount is a user's Nostr identity. The secret key is sealed with the
+// user's data key; PublicKey is its hex x-only public key and Npub the same
+// key as users see it.
+type NostrAccount struct {
+	PublicKey       string    `json:"public_key" bson:"public_key"`
+	Npub            string    `json:"npub" bson:"npub"`
+	SealedSecretKey string    `json:"-" bson:"sealed_secret_key" seal:"user"`
+	Relays          []string  `json:"relays" bson:"relays"`
+	ConnectedAt     time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// WordPressAccount is a user's WordPress site, reached through an
+// application password which is sealed with the user's data key. SiteURL
+// is the address of the site, which may live in a subdirectory.
+type WordPressAccount struct {
+	SiteURL        string    `json:"site_url" bson:"site_url"`
+	SiteName       string    `json:"site_name" bson:"site_name"`
+	Username       string    `json:"username" bson:"username"`
+	SealedPassword string    `json:"-" bson:"sealed_password" seal:"user"`
+	ConnectedAt    time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// LemmyAccount is a user's Lemmy connection. The password is only used to
+// log in; the session token Lemmy returns is sealed with the user's data
+// key.
+type LemmyAccount struct {
+	// Instance is the host of the user's Lemmy server.
+	Instance    string           `json:"instance" bson:"instance"`
+	Username    string           `json:"username" bson:"username"`
+	SealedJWT   string           `json:"-" bson:"sealed_jwt" seal:"user"`
+	Communities []LemmyCommunity `json:"communities" bson:"communities"`
+	ConnectedAt time.Time        `json:"connected_at" bson:"connected_at"`
+}
+
+// LemmyCommunity is a community blog links are submitted to. Name is how
+// the user's instance knows it, such as "golang" or "golang@lemmy.ml"; ID
+// is its id on the user's instance.
+type LemmyCommunity struct {
+	ID    int    `json:"id" bson:"id"`
+	Name  string `json:"name" bson:"name"`
+	Title string `json:"title" bson:"title"`
+}
+
+// MatrixRoom is a Matrix room blogs are announced in, by the account the
+// access token belongs to. The token is sealed with the user's data key.
+type MatrixRoom struct {
+	// Homeserver is the client API address of the account's homeserver.
+	Homeserver  string    `json:"homeserver" bson:"homeserver"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	RoomID      string    `json:"room_id" bson:"room_id"`
+	RoomName    string    `json:"room_name,omitempty" bson:"room_name,omitempty"`
+	SealedToken string    `json:"-" bson:"sealed_token" seal:"user"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// TumblrAccount is the primary blog of a user's Tumblr account, reached
+// through an OAuth1 access token which is sealed with the user's data key.
+type TumblrAccount struct {
+	// Blog is the blog's name, such as "ada" for ada.tumblr.com.
+	Blog         string    `json:"blog" bson:"blog"`
+	Title        string    `json:"title" bson:"title"`
+	URL          string    `json:"url" bson:"url"`
+	SealedToken  string    `json:"-" bson:"sealed_token" seal:"user"`
+	SealedSecret string    `json:"-" bson:"sealed_secret" seal:"user"`
+	ConnectedAt  time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// DiscordWebhook is a Discord channel webhook blogs are announced through.
+// The token is what lets anyone post with it.
+type DiscordWebhook struct {
+	ID          string    `json:"id" bson:"id"`
+	SealedToken string    `json:"-" bson:"sealed_token,omitempty" seal:"user"`
+	Name        string    `json:"name" bson:"name"`
+	ChannelID   string    `json:"channel_id" bson:"channel_id"`
+	GuildID     string    `json:"guild_id" bson:"guild_id"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	// PlaintextToken is a token stored before tokens were sealed.
+	PlaintextToken string `json:"-" bson:"token,omitempty" seal:"plaintext"`
+}
+
+// TeamsWebhook is a Microsoft Teams incoming webhook blogs are announced
+// through. The URL is what lets anyone post with it, so it is sealed with
+// the user's data key.
+type TeamsWebhook struct {
+	Host      string    `json:"host" bson:"host"`
+	SealedURL string    `json:"-" bson:"sealed_url" seal:"user"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Newsletter senders: the built-in one mails the subscribers the user keeps
+// here, the others send through the user's account with the service.
+const (
+	NewsletterBuiltin    = "builtin"
+	NewsletterButtondown = "buttondown"
+	NewsletterMailchimp  = "mailchimp"
+)
+
+// NewsletterAccount is where shared blogs go out as newsletters. API keys
+// of Buttondown and Mailchimp are sealed with the user's data key.
+type NewsletterAccount struct {
+	Provider string `json:"provider" bson:"provider"`
+	// FromName is the sender name subscribers see.
+	FromName     string `json:"from_name" bson:"from_name"`
+	SealedAPIKey string `json:"-" bson:"sealed_api_key,omitempty" seal:"user"`
+	// ListID and ListName are the Mailchimp audience campaigns go to, and
+	// ReplyTo the address its campaigns are answered at.
+	ListID      string    `json:"list_id,omitempty" bson:"list_id,omitempty"`
+	ListName    string    `json:"list_name,omitempty" bson:"list_name,omitempty"`
+	ReplyTo     string    `json:"reply_to,omitempty" bson:"reply_to,omitempty"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// SubstackAccount is a user's Substack connection. Substack has no API keys
+// for Notes, so the account is reached with the session cookie of a
+// signed-in browser, which is sealed with the user's data key.
+type SubstackAccount struct {
+	UserID        int64     `json:"user_id" bson:"user_id"`
+	Handle        string    `json:"handle" bson:"handle"`
+	Name          string    `json:"name" bson:"name"`
+	SealedSession string    `json:"-" bson:"sealed_session" seal:"user"`
+	ConnectedAt   time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// GoogleBusinessAccount is a user's Google Business Profile connection. The
+// refresh token is sealed with the user's data key; access tokens are
+// fetched with it for each post.
+type GoogleBusinessAccount struct {
+	// Locations are the business locations the user manages.
+	Locations []GoogleBusinessLocation `json:"locations" bson:"locations"`
+	// Location is the name of the one posts are published to, empty until
+	// the user chooses.
+	Location           string    `json:"location" bson:"location"`
+	SealedRefreshToken string    `json:"-" bson:"sealed_refresh_token" seal:"user"`
+	ConnectedAt        time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// GoogleBusinessLocation is a business location. Name is its resource name
+// in the Business Profile API, such as "accounts/1/locations/2".
+type GoogleBusinessLocation struct {
+	Name    string `json:"name" bson:"name"`
+	Title   string `json:"title" bson:"title"`
+	Address string `json:"address,omitempty" bson:"address,omitempty"`
+}
+
+// InstagramAccount is the Instagram account caption kits are made for.
+// Instagram has no API to post links from personal accounts, so nothing is
+// posted with it; the handle only names the account in reminders.
+type InstagramAccount struct {
+	Handle      string    `json:"handle" bson:"handle"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// ShareWebhook is an endpoint of the user that gets each share of a blog,
+// with the text written for it, to feed their own automations. Deliveries
+// are signed with the secret, which is sealed with the user's data key.
+type ShareWebhook struct {
+	URL          string    `json:"url" bson:"url"`
+	SealedSecret string    `json:"-" bson:"sealed_secret" seal:"user"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// YouTubeChannel is the YouTube channel community post kits are made for.
+// The YouTube Data API can't create community posts, so the channel is
+// only looked up when the user connects it, to name it in reminders; no
+// token is kept.
+type YouTubeChannel struct {
+	ChannelID   string    `json:"channel_id" bson:"channel_id"`
+	Title       string    `json:"title" bson:"title"`
+	Handle      string    `json:"handle,omitempty" bson:"handle,omitempty"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// BlogFeed is an RSS or Atom feed the user registered as a blog source. Its
+// items are polled into the posts collection. LastError is why the last
+// poll failed, cleared once one succeeds.
+type BlogFeed struct {
+	URL          string    `json:"url" bson:"url"`
+	Title        string    `json:"title" bson:"title"`
+	AddedAt      time.Time `json:"added_at" bson:"added_at"`
+	LastPolledAt time.Time `json:"last_polled_at,omitempty" bson:"last_polled_at,omitempty"`
+	LastError    string    `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// GhostSource is a Ghost site the user registered as a blog source, read
+// through the Content API of a custom integration. The Content API key is
+// sealed with the user's data key.
+type GhostSource struct {
+	APIURL           string    `json:"api_url" bson:"api_url"`
+	SiteTitle        string    `json:"site_title" bson:"site_title"`
+	SealedContentKey string    `json:"-" bson:"sealed_content_key" seal:"user"`
+	ConnectedAt      time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// PostingGoal is how many blogs the user aims to share each week. Weeks
+// start on Monday at midnight in Timezone.
+type PostingGoal struct {
+	SharesPerWeek int    `json:"shares_per_week" bson:"shares_per_week"`
+	Timezone      string `json:"timezone" bson:"timezone"`
+	// Reminder asks for a notification when a week is about to end under
+	// target.
+	Reminder  bool      `json:"reminder" bson:"reminder"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	// RemindedWeek is the start of the last week a reminder was sent for.
+	RemindedWeek time.Time `json:"-" bson:"reminded_week,omitempty"`
+}
+
+// GoalProgress is how the user is doing against their posting goal. Each
+// blog counts in the week it was last shared.
+type GoalProgress struct {
+	WeekStart time.Time `json:"week_start"`
+	WeekEnd   time.Time `json:"week_end"`
+	Shares    int       `json:"shares"`
+	Remaining int       `json:"remaining"`
+	Met       bool      `json:"met"`
+	// CurrentStreak counts the weeks in a row the goal was met, up to last
+	// week, and this week once it is met.
+	CurrentStreak int `json:"current_streak"`
+	LongestStreak int `json:"longest_streak"`
+	// Weeks are the latest weeks, this one first.
+	Weeks []GoalWeek `json:"weeks"`
+}
+
+// GoalWeek is the shares of a week.
+type GoalWeek struct {
+	Start  time.Time `json:"start"`
+	Shares int       `json:"shares"`
+	Met    bool      `json:"met"`
+}
+
+// MediumSource is a Medium profile the user registered as a blog source,
+// read through its public RSS feed.
+type MediumSource struct {
+	Username    string    `json:"username" bson:"username"`
+	Title       string    `json:"title" bson:"title"`
+	ConnectedAt time.Time `json:"connected_at" bson:"connected_at"`
+}
+
+// NewsletterSubscriber is an address the built-in sender mails. Subscribers
+// who unsubscribed are kept, so uploading the list again doesn't bring them
+// back.
+type NewsletterSubscriber struct {
+	Id     primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID string             `json:"-" bson:"user_id"`
+	Email  string             `json:"email" bson:"email"`
+	// Token identifies the subscriber in unsubscribe links.
+	Token          string     `json:"-" bson:"token"`
+	SubscribedAt   time.Time  `json:"subscribed_at" bson:"subscribed_at"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty" bson:"unsubscribed_at,omitempty"`
+	Region         string     `json:"-" bson:"region"`
+}
+
+// Newsletter delivery statuses.
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	// DeliveryUnsubscribed is a delivery dropped because the subscriber
+	// unsubscribed before it went out.
+	DeliveryUnsubscribed = "unsubscribed"
+)
+
+// NewsletterDelivery is the built-in sender's email of one issue to one
+// subscriber. Deliveries are queued when a blog is shared and mailed in the
+// background, and a subscriber gets each blog's issue once however often the
+// share is retried.
+type NewsletterDelivery struct {
+	Id     primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	UserID string             `json:"-" bson:"user_id"`
+	// IssueID is the blog the issue is about.
+	IssueID  string            `json:"issue_id" bson:"issue_id"`
+	Email    string            `json:"email" bson:"email"`
+	Subject  string            `json:"-" bson:"subject"`
+	Body     string            `json:"-" bson:"body"`
+	FromName string            `json:"-" bson:"from_name"`
+	Headers  map[string]string `json:"-" bson:"headers,omitempty"`
+	Status   string            `json:"status" bson:"status"`
+	Attempts int               `json:"attempts" bson:"attempts"`
+	// NextAttemptAt is when a pending delivery is tried next. Claiming it
+	// pushes it back, so a worker that dies mid-send hands it on.
+	NextAttemptAt time.Time  `json:"-" bson:"next_attempt_at"`
+	Error         string     `json:"error,omitempty" bson:"error,omitempty"`
+	QueuedAt      time.Time  `json:"queued_at" bson:"queued_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	Region        string     `json:"-" bson:"region"`
+}
+
+// RedditAccount is a user's Reddit connection. Access tokens last an hour
+// and are refreshed with the refresh token when they run out.
+type RedditAccount struct {
+	Username           string    `json:"username,omitempty" bson:"username,omitempty"`
+	SealedAccessToken  string    `json:"-" bson:"sealed_access_token,omitempty" seal:"user"`
+	SealedRefreshToken string    `json:"-" bson:"sealed_refresh_token,omitempty" seal:"user"`
+	TokenExpiry        time.Time `json:"-" bson:"token_expiry,omitempty"`
+	// The Plaintext tokens were stored before tokens were sealed.
+	PlaintextAccessToken  string `json:"-" bson:"access_token,omitempty" seal:"plaintext"`
+	PlaintextRefreshToken string `json:"-" bson:"refresh_token,omitempty" seal:"plaintext"`
+	// Subreddit receives the link posts, without the r/ prefix.
+	Subreddit string `json:"subreddit,omitempty" bson:"subreddit,omitempty"`
+	// FlairID and FlairText flair the posts in subreddits that require it.
+	FlairID   string `json:"flair_id,omitempty" bson:"flair_id,omitempty"`
+	FlairText string `json:"flair_text,omitempty" bson:"flair_text,omitempty"`
+}
+
+// LinkedInMemberProfile targets the member's own profile in share
+// requests, whatever Page they chose for shares.
+const LinkedInMemberProfile = "member"
+
+// LinkedInPage is a LinkedIn organization page a member administers.
+type LinkedInPage struct {
+	// ID is the number of the organization's URN, urn:li:organization:<id>.
+	ID         string `json:"id" bson:"id"`
+	Name       string `json:"name" bson:"name"`
+	VanityName string `json:"vanity_name,omitempty" bson:"vanity_name,omitempty"`
+}
+
+// HashnodePublication is a publication of the user's Hashnode account.
+type HashnodePublication struct {
+	ID string `json:"id" bson:"id"`
+	// Host is the publication's URL without its scheme, e.g.
+	// ada.hashnode.dev, which is how Hashnode looks publications up.
+	Host  string `json:"host" bson:"host"`
+	Title string `json:"title,omitempty" bson:"title,omitempty"`
+	// WebhookID is the webhook registered on the publication to tell
+	// SocialScribe about newly published posts.
+	WebhookID string `json:"-" bson:"webhook_id,omitempty"`
+}
+
+// OAuthApp is an X or LinkedIn app registered by the user. The secret is
+// sealed with APP_CREDENTIALS_SECRETS and never leaves the backend.
+type OAuthApp struct {
+	ClientID     string    `json:"client_id" bson:"client_id"`
+	SealedSecret string    `json:"-" bson:"sealed_secret" seal:"user"`
+	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// AddNotification appends a notification and records when it was added.
+func (u *User) AddNotification(message string, at time.Time) {
+	// Drop times left over from notifications cleared elsewhere, so the
+	// rest stay lined up with the tail
+	for len(u.NotificationTimes) > len(u.Notifications) {
+		u.NotificationTimes = u.NotificationTimes[1:]
+	}
+	u.Notifications = append(u.Notifications, message)
+	u.NotificationTimes = append(u.NotificationTimes, at)
+}
+
+// Security event types delivered to security webhooks.
+const (
+	SecurityEventLogin          = "login.succeeded"
+	SecurityEventLoginFailed    = "login.failed"
+	SecurityEventSessionRevoked = "session.revoked"
+	SecurityEventConnection     = "connection.changed"
+	// SecurityEventPing is only sent when the user tests their webhook.
+	SecurityEventPing = "ping"
+)
+
+// SecurityEventTypes are the events a security webhook can subscribe to.
+var SecurityEventTypes = []string{SecurityEventLogin, SecurityEventLoginFailed, SecurityEventSessionRevoked, SecurityEventConnection}
+
+// SecurityWebhook is an endpoint of the user that receives account activity
+// events, signed with Secret.
+type SecurityWebhook struct {
+	URL          string `json:"url" bson:"url"`
+	SealedSecret string `json:"-" bson:"sealed_secret,omitempty" seal:"user"`
+	// PlaintextSecret is a secret stored before secrets were sealed.
+	PlaintextSecret string `json:"-" bson:"secret,omitempty" seal:"plaintext"`
+	// Events limits the deliveries to these types; empty means every type.
+	Events    []string  `json:"events" bson:"events"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Wants reports whether the webhook subscribed to the event type.
+func (w *SecurityWebhook) Wants(eventType string) bool {
+	return eventType == SecurityEventPing || len(w.Events) == 0 || slices.Contains(w.Events, eventType)
+}
+
+// SecurityEvent is the payload of a security webhook delivery.
+type SecurityEvent struct {
+	Id         string            `json:"id"`
+	Type       string            `json:"type"`
+	UserID     string            `json:"user_id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// PlanFree is the plan every account starts on.
+const PlanFree = "free"
+
+// RegionDefault is the storage region of users outside a team and of teams
+// that didn't pick one.
+const RegionDefault = "default"
+
+// UserDirectoryEntry is the global record of which storage region holds a
+// user. It also keeps usernames unique across regions.
+type UserDirectoryEntry struct {
+	Id       primitive.ObjectID `bson:"_id"`
+	UserName string             `bson:"username"`
+	Region   string             `bson:"region"`
+	// MovingTo is set while MoveUserToRegion copies the user's data to
+	// another region. Their writes are refused until the move switches
+	// the region.
+	MovingTo string `bson:"moving_to,omitempty"`
+	// MovingSince is when the move blocked the user's writes.
+	MovingSince *time.Time `bson:"moving_since,omitempty"`
+	// MovedFrom is the region a move switched away from, until its copies
+	// of the user's data are removed.
+	MovedFrom string `bson:"moved_from,omitempty"`
+}
+
+type Preferences struct {
+	DefaultPlatforms []string `json:"default_platforms" bson:"default_platforms"`
+	// ScheduledShareEmail opts in to an email with the live post links
+	// whenever a scheduled share goes out.
+	ScheduledShareEmail bool `json:"scheduled_share_email" bson:"scheduled_share_email,omitempty"`
+	// LoginAlertEmail also emails the alert raised when the account is
+	// signed in to from a new device or country.
+	LoginAlertEmail bool `json:"login_alert_email" bson:"login_alert_email,omitempty"`
+	// Retention auto-deletes the user's old data.
+	Retention RetentionPolicy `json:"retention" bson:"retention,omitempty"`
+	// AutoShare is what happens when a post is published on the user's
+	// Hashnode publications without a share-on-publish plan: nothing, a
+	// share to DefaultPlatforms, or a share waiting for their approval.
+	AutoShare string `json:"auto_share" bson:"auto_share,omitempty"`
+}
+
+// Modes of Preferences.AutoShare.
+const (
+	AutoShareOff     = ""
+	AutoSharePublish = "share"
+	AutoShareApprove = "approve"
+)
+
+// Limits of a retention policy.
+const (
+	MaxNotificationRetentionDays   = 3650
+	MaxShareHistoryRetentionMonths = 120
+	MaxRawDataRetentionDays        = 365
+)
+
+// RetentionPolicy says how long the user's data is kept. A zero field keeps
+// that data forever.
+type RetentionPolicy struct {
+	NotificationDays   int `json:"notification_days" bson:"notification_days,omitempty"`
+	ShareHistoryMonths int `json:"share_history_months" bson:"share_history_months,omitempty"`
+	// RawDataDays applies to the raw provider responses kept for analytics
+	// and debugging.
+	RawDataDays int `json:"raw_data_days" bson:"raw_data_days,omitempty"`
+}
+
+// IsZero reports whether the policy keeps everything.
+func (p RetentionPolicy) IsZero() bool {
+	return p == RetentionPolicy{}
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.NotificationDays < 0 || p.NotificationDays > MaxNotificationRetentionDays {
+		return fmt.Errorf("notification_days must be between 0 and %d", MaxNotificationRetentionDays)
+	}
+	if p.ShareHistoryMonths < 0 || p.ShareHistoryMonths > MaxShareHistoryRetentionMonths {
+		return fmt.Errorf("share_history_months must be between 0 and %d", MaxShareHistoryRetentionMonths)
+	}
+	if p.RawDataDays < 0 || p.RawDataDays > MaxRawDataRetentionDays {
+		return fmt.Errorf("raw_data_days must be between 0 and %d", MaxRawDataRetentionDays)
+	}
+	return nil
+}
+
+// UserDTO is the minimal view of a user returned by the auth endpoints.
+type UserDTO struct {
+	Id                     string `json:"_id"`
+	UserName               string `json:"username"`
+	Verified               bool   `json:"verified"`
+	EmailVerified          bool   `json:"email_verified"`
+	HashnodeVerified       bool   `json:"hashnode_verified"`
+	LinkedinVerified       bool   `json:"linkedin_verified"`
+	XVerified              bool   `json:"x_verified"`
+	MastodonVerified       bool   `json:"mastodon_verified"`
+	RedditVerified         bool   `json:"reddit_verified"`
+	DiscordVerified        bool   `json:"discord_verified"`
+	DevtoVerified          bool   `json:"devto_verified"`
+	MediumVerified         bool   `json:"medium_verified"`
+	NostrVerified          bool   `json:"nostr_verified"`
+	LemmyVerified          bool   `json:"lemmy_verified"`
+	WordPressVerified      bool   `json:"wordpress_verified"`
+	MatrixVerified         bool   `json:"matrix_verified"`
+	TumblrVerified         bool   `json:"tumblr_verified"`
+	TeamsVerified          bool   `json:"teams_verified"`
+	NewsletterVerified     bool   `json:"newsletter_verified"`
+	SubstackVerified       bool   `json:"substack_verified"`
+	GoogleBusinessVerified bool   `json:"google_business_verified"`
+	InstagramVerified      bool   `json:"instagram_verified"`
+	ShareWebhookVerified   bool   `json:"share_webhook_verified"`
+	YouTubeVerified        bool   `json:"youtube_verified"`
+	FeedVerified           bool   `json:"feed_verified"`
+	GhostVerified          bool   `json:"ghost_verified"`
+	MediumSourceVerified   bool   `json:"medium_source_verified"`
+	HashnodeBlog           string `json:"hashnode_blog"`
+	Role                   string `json:"role,omitempty"`
+	// DisabledPlatforms maps platforms turned off by the operators to the
+	// reason why. Their connections are kept but can't be shared to.
+	DisabledPlatforms map[string]string `json:"disabled_platforms,omitempty"`
+}
+
+// UserProfileDTO is the detailed view served by the profile endpoint.
+type UserProfileDTO struct {
+	UserDTO
+	WebHookUrl     string          `json:"webhook_url"`
+	SharedBlogs    []SharedBlog    `json:"shared_posts"`
+	ScheduledBlogs []ScheduledBlog `json:"scheduled_posts"`
+	Notifications  []string        `json:"notifications"`
+	Preferences    Preferences     `json:"preferences"`
+}
+
+const (
+	// MaxPublicBioLength caps the bio on a public profile, in characters.
+	MaxPublicBioLength = 500
+	// MaxPublicShares is how many recent shares a public profile lists.
+	MaxPublicShares = 20
+	// maxHiddenBlogs caps the shares a user can hide from their profile.
+	maxHiddenBlogs = 1000
+)
+
+// PublicProfile is what the user's public page, served by username, shows.
+type PublicProfile struct {
+	Enabled bool   `json:"enabled" bson:"enabled"`
+	Bio     string `json:"bio" bson:"bio"`
+	// ShowShares lists the user's recent shares; ShowPlatforms adds where
+	// each of them went.
+	ShowShares    bool `json:"show_shares" bson:"show_shares"`
+	ShowPlatforms bool `json:"show_platforms" bson:"show_platforms"`
+	// HiddenBlogs are the ids of blogs whose shares are left off the page.
+	HiddenBlogs []string `json:"hidden_blogs" bson:"hidden_blogs"`
+}
+
+func (p *PublicProfile) Validate() error {
+	var v ValidationError
+	if utf8.RuneCountInString(p.Bio) > MaxPublicBioLength {
+		v.add("bio", "must be at most %d characters", MaxPublicBioLength)
+	}
+	if len(p.HiddenBlogs) > maxHiddenBlogs {
+		v.add("hidden_blogs", "must list at most %d blogs", maxHiddenBlogs)
+	}
+	return v.err()
+}
+
+// PublicShare is a share as listed on a public profile.
+type PublicShare struct {
+	Id         string   `json:"id"`
+	Title      string   `json:"title"`
+	Url        string   `json:"url"`
+	CoverImage string   `json:"cover_image,omitempty"`
+	SharedTime string   `json:"shared_time"`
+	Platforms  []string `json:"platforms,omitempty"`
+}
+
+// PublicProfileDTO is the public view of a user. Shares is left out unless
+// the user chose to show them.
+type PublicProfileDTO struct {
+	UserName     string        `json:"username"`
+	Bio          string        `json:"bio,omitempty"`
+	HashnodeBlog string        `json:"hashnode_blog,omitempty"`
+	Shares       []PublicShare `json:"shares,omitempty"`
+}
+
+// ToPublicProfileDTO returns what the user's public page shows: their most
+// recent shares, newest first, unless the user hid them.
+func (u *User) ToPublicProfileDTO() PublicProfileDTO {
+	profile := PublicProfileDTO{
+		UserName:     u.UserName,
+		Bio:          u.PublicProfile.Bio,
+		HashnodeBlog: u.HashnodeBlog,
+	}
+	if !u.PublicProfile.ShowShares {
+		return profile
+	}
+	shares := make([]SharedBlog, 0, len(u.SharedBlogs))
+	for _, shared := range u.SharedBlogs {
+		if !slices.Contains(u.PublicProfile.HiddenBlogs, shared.Id) {
+			shares = append(shares, shared)
+		}
+	}
+	sharedAt := func(shared SharedBlog) time.Time {
+		at, _ := time.Parse(time.RFC3339, shared.SharedTime)
+		return at
+	}
+	sort.SliceStable(shares, func(i, j int) bool {
+		return sharedAt(shares[i]).After(sharedAt(shares[j]))
+	})
+	if len(shares) > MaxPublicShares {
+		shares = shares[:MaxPublicShares]
+	}
+	profile.Shares = make([]PublicShare, len(shares))
+	for i, shared := range shares {
+		profile.Shares[i] = PublicShare{
+			Id:         shared.Id,
+			Title:      shared.Title,
+			Url:        shared.Url,
+			CoverImage: shared.CoverImage.URL,
+			SharedTime: shared.SharedTime,
+		}
+		if u.PublicProfile.ShowPlatforms {
+			profile.Shares[i].Platforms = shared.Platforms
+		}
+	}
+	return profile
+}
+
+func (u *User) ToDTO() UserDTO {
+	return UserDTO{
+		Id:                     u.Id.Hex(),
+		UserName:               u.UserName,
+		Verified:               u.Verified,
+		EmailVerified:          u.EmailVerified,
+		HashnodeVerified:       u.HashnodeVerified,
+		LinkedinVerified:       u.LinkedinVerified,
+		XVerified:              u.XVerified,
+		MastodonVerified:       u.MastodonVerified,
+		RedditVerified:         u.RedditVerified,
+		DiscordVerified:        u.DiscordVerified,
+		DevtoVerified:          u.DevtoVerified,
+		MediumVerified:         u.MediumVerified,
+		NostrVerified:          u.NostrVerified,
+		LemmyVerified:          u.LemmyVerified,
+		WordPressVerified:      u.WordPressVerified,
+		MatrixVerified:         u.MatrixVerified,
+		TumblrVerified:         u.TumblrVerified,
+		TeamsVerified:          u.TeamsVerified,
+		NewsletterVerified:     u.NewsletterVerified,
+		SubstackVerified:       u.SubstackVerified,
+		GoogleBusinessVerified: u.GoogleBusinessVerified,
+		InstagramVerified:      u.InstagramVerified,
+		ShareWebhookVerified:   u.ShareWebhookVerified,
+		YouTubeVerified:        u.YouTubeVerified,
+		FeedVerified:           u.Feed != nil,
+		GhostVerified:          u.Ghost != nil,
+		MediumSourceVerified:   u.MediumSource != nil,
+		HashnodeBlog:           u.HashnodeBlog,
+		Role:                   u.Role,
+	}
+}
+
+func (u *User) ToProfileDTO() UserProfileDTO {
+	return UserProfileDTO{
+		UserDTO:        u.ToDTO(),
+		WebHookUrl:     u.WebHookUrl,
+		SharedBlogs:    u.SharedBlogs,
+		ScheduledBlogs: u.ScheduledBlogs,
+		Notifications:  u.Notifications,
+		Preferences:    u.Preferences,
+	}
 }
 
 type Session struct {
@@ -37,14 +887,28 @@ type Session struct {
 	ExpiresAt    time.Time `json:"expires_at" bson:"expires_at"`
 }
 
+const (
+	RoleUser  = ""
+	RoleAdmin = "admin"
+)
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin && !u.Disabled
+}
+
 type LoginStruct struct {
-	Username string `json:"username" bson:"username"`
-	Password string `json:"password" bson:"password"`
+	Username   string `json:"username" bson:"username"`
+	Password   string `json:"password" bson:"password"`
+	RememberMe bool   `json:"remember_me" bson:"-"`
 }
 
 type ScheduledBlogData struct {
 	UserID        string        `json:"user_id" bson:"user_id"`
 	ScheduledBlog ScheduledBlog `json:"blog" bson:"blog"`
+	Region        string        `json:"region" bson:"region"`
+	// Platform is set on the child tasks of a blog scheduled with platform
+	// offsets; each shares the blog to that platform only.
+	Platform string `json:"platform,omitempty" bson:"platform,omitempty"`
 }
 
 type Blog struct {
@@ -68,12 +932,449 @@ type SharedBlog struct {
 	Blog
 	Platforms  []string `json:"platforms" bson:"platforms"`
 	SharedTime string   `json:"shared_time" bson:"shared_time"`
+	// Imported shares were posted before the platform was connected and
+	// backfilled from its history.
+	Imported   bool             `json:"imported,omitempty" bson:"imported,omitempty"`
+	Engagement *ShareEngagement `json:"engagement,omitempty" bson:"engagement,omitempty"`
+	// PlatformEngagement splits Engagement by platform, for the platforms
+	// whose engagement is known.
+	PlatformEngagement map[string]ShareEngagement `json:"platform_engagement,omitempty" bson:"platform_engagement,omitempty"`
+	// Topics are what the blog is about: its tags and the topics the AI
+	// found in it when first shared, unless the user assigned others.
+	Topics []string `json:"topics,omitempty" bson:"topics,omitempty"`
+	// Caption is the text last posted for the blog.
+	Caption string `json:"caption,omitempty" bson:"caption,omitempty"`
+	// DevtoArticleID is the Dev.to copy of the blog, updated rather than
+	// published again when the blog is shared again.
+	DevtoArticleID int `json:"devto_article_id,omitempty" bson:"devto_article_id,omitempty"`
+	// MediumPostURL is the Medium copy of the blog. Medium stories can't be
+	// updated through the API, so the blog isn't published there again.
+	MediumPostURL string `json:"medium_post_url,omitempty" bson:"medium_post_url,omitempty"`
+	// WordPressPostID is the WordPress copy of the blog, updated rather than
+	// published again when the blog is shared again.
+	WordPressPostID int `json:"wordpress_post_id,omitempty" bson:"wordpress_post_id,omitempty"`
+}
+
+// Campaign groups the shares of several blogs under a name. Shares of its
+// blogs link back with utm_campaign set to UTMCampaign.
+type Campaign struct {
+	Id          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID      string             `json:"-" bson:"user_id"`
+	Name        string             `json:"name" bson:"name"`
+	UTMCampaign string             `json:"utm_campaign" bson:"utm_campaign"`
+	BlogIDs     []string           `json:"blog_ids" bson:"blog_ids"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	Region      string             `json:"-" bson:"region"`
+}
+
+// CampaignAnalytics sums up the shares of a campaign's blogs.
+type CampaignAnalytics struct {
+	Blogs       int `json:"blogs"`
+	SharedBlogs int `json:"shared_blogs"`
+	Scheduled   int `json:"scheduled"`
+	// Platforms counts the campaign's blogs shared to each platform
+	Platforms    map[string]int  `json:"platforms"`
+	Engagement   ShareEngagement `json:"engagement"`
+	LastSharedAt string          `json:"last_shared_at,omitempty"`
+}
+
+// PlatformAnalytics compares how the blogs shared between From and To did
+// on each platform.
+type PlatformAnalytics struct {
+	From      time.Time                  `json:"from"`
+	To        time.Time                  `json:"to"`
+	Platforms map[string]PlatformSummary `json:"platforms"`
+	// Articles are the blogs shared to more than one platform with known
+	// engagement, newest first.
+	Articles []ArticleComparison `json:"articles"`
+}
+
+// PlatformSummary describes the interactions (likes, reposts and comments)
+// with the shares of a platform.
+type PlatformSummary struct {
+	Shares     int             `json:"shares"`
+	Engagement ShareEngagement `json:"engagement"`
+	Mean       float64         `json:"mean"`
+	Median     float64         `json:"median"`
+	StdDev     float64         `json:"std_dev"`
+	Min        int             `json:"min"`
+	Max        int             `json:"max"`
+}
+
+// ArticleComparison is how one blog did on each platform it was shared to.
+type ArticleComparison struct {
+	BlogID     string                     `json:"blog_id"`
+	Title      string                     `json:"title"`
+	URL        string                     `json:"url"`
+	SharedTime string                     `json:"shared_time"`
+	Platforms  map[string]ArticlePlatform `json:"platforms"`
+	// BestPlatform has the highest engagement rate, if any platform engaged.
+	BestPlatform string `json:"best_platform,omitempty"`
+}
+
+// ArticlePlatform is a blog's engagement on a platform. Rate normalizes its
+// interactions by the platform's mean over the range, so 1 is a typical
+// share there whatever the size of the audience.
+type ArticlePlatform struct {
+	Engagement   ShareEngagement `json:"engagement"`
+	Interactions int             `json:"interactions"`
+	Rate         float64         `json:"rate"`
+}
+
+// TopicAnalytics compares how the topics of the blogs shared between From
+// and To did on each platform.
+type TopicAnalytics struct {
+	From   time.Time               `json:"from"`
+	To     time.Time               `json:"to"`
+	Topics map[string]TopicSummary `json:"topics"`
+	// Insights point out the topics doing notably better or worse on a
+	// platform, the strongest first.
+	Insights []TopicInsight `json:"insights"`
+}
+
+// TopicSummary is how the shares of a topic did on each platform.
+type TopicSummary struct {
+	Shares    int                      `json:"shares"`
+	Platforms map[string]TopicPlatform `json:"platforms"`
+}
+
+// TopicPlatform is the mean interactions with a topic's shares on a
+// platform. Lift compares it with the mean of all the platform's shares, so
+// 2 means the topic does twice as well there as the user's other blogs.
+type TopicPlatform struct {
+	Shares int     `json:"shares"`
+	Mean   float64 `json:"mean"`
+	Lift   float64 `json:"lift"`
+}
+
+// TopicInsight is a topic doing notably better or worse on a platform.
+type TopicInsight struct {
+	Topic    string  `json:"topic"`
+	Platform string  `json:"platform"`
+	Shares   int     `json:"shares"`
+	Lift     float64 `json:"lift"`
+	Summary  string  `json:"summary"`
+}
+
+// ShareEngagement is the reaction to a share as reported by the platforms it
+// was posted to.
+type ShareEngagement struct {
+	Likes    int `json:"likes" bson:"likes"`
+	Reposts  int `json:"reposts" bson:"reposts"`
+	Comments int `json:"comments" bson:"comments"`
 }
 
 type ScheduledBlog struct {
 	Blog
 	Platforms     []string  `json:"platforms" bson:"platforms"`
 	ScheduledTime time.Time `json:"scheduled_time" bson:"scheduled_time"`
+	// AssetIDs are team library assets applied to the share when it runs.
+	AssetIDs []string `json:"asset_ids,omitempty" bson:"asset_ids,omitempty"`
+	// LinkedInPage is the LinkedIn Page the share goes to: a Page id,
+	// LinkedInMemberProfile, or empty for the Page chosen when it runs.
+	LinkedInPage string `json:"linkedin_page,omitempty" bson:"linkedin_page,omitempty"`
+	// XThread shares the blog on X as a thread of tweets, its caption and
+	// brief split between them, rather than one tweet.
+	XThread bool `json:"x_thread,omitempty" bson:"x_thread,omitempty"`
+	// Publication is the host of the Hashnode publication the blog was
+	// picked from, checked when it is scheduled.
+	Publication string `json:"publication,omitempty" bson:"publication,omitempty"`
+	// Timezone is the IANA zone the blog was scheduled in, and LocalTime
+	// the wall-clock time it was scheduled for there. The clock change
+	// reconciler keeps ScheduledTime at that local time when the zone's
+	// rules change.
+	Timezone  string `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	LocalTime string `json:"local_time,omitempty" bson:"local_time,omitempty"`
+	// PlatformOffsets delays the share on some platforms, in minutes after
+	// ScheduledTime. A blog scheduled with offsets runs as one child task
+	// per platform, tracked in Children.
+	PlatformOffsets map[string]int   `json:"platform_offsets,omitempty" bson:"platform_offsets,omitempty"`
+	Children        []ScheduledChild `json:"children,omitempty" bson:"children,omitempty"`
+	// Status rolls up the children's statuses when listing schedules.
+	Status string `json:"status,omitempty" bson:"-"`
+}
+
+// ScheduledChild is the share of a scheduled blog to one platform.
+type ScheduledChild struct {
+	Platform      string    `json:"platform" bson:"platform"`
+	ScheduledTime time.Time `json:"scheduled_time" bson:"scheduled_time"`
+	Status        string    `json:"status" bson:"status"`
+	PostURL       string    `json:"post_url,omitempty" bson:"post_url,omitempty"`
+	Error         string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+const (
+	SchedulePending         = "pending"
+	ScheduleInProgress      = "in_progress"
+	ScheduleShared          = "shared"
+	ScheduleFailed          = "failed"
+	SchedulePartiallyShared = "partially_shared"
+)
+
+// maxPlatformOffset bounds how far one platform's share may trail the
+// scheduled time.
+const maxPlatformOffset = 24 * time.Hour
+
+// DeliveryReceipt records where a share of a blog went live.
+type DeliveryReceipt struct {
+	BlogID      string             `json:"blog_id"`
+	BlogTitle   string             `json:"blog_title"`
+	BlogURL     string             `json:"blog_url"`
+	Caption     string             `json:"caption"`
+	Deliveries  []PlatformDelivery `json:"deliveries"`
+	DeliveredAt time.Time          `json:"delivered_at"`
+}
+
+// PlatformDelivery is the post a share created on one platform. PostURL is
+// empty when the platform didn't say where the post lives.
+type PlatformDelivery struct {
+	Platform string `json:"platform"`
+	PostURL  string `json:"post_url,omitempty"`
+}
+
+// DeferredShare is a share plan bound to a Hashnode post that has not been
+// published yet; it fires when the post_published webhook arrives.
+type DeferredShare struct {
+	UserID    string   `json:"user_id" bson:"user_id"`
+	PostID    string   `json:"post_id" bson:"post_id"`
+	Platforms []string `json:"platforms" bson:"platforms"`
+	AssetIDs  []string `json:"asset_ids,omitempty" bson:"asset_ids,omitempty"`
+	// LinkedInPage and XThread are as in ScheduledBlog.
+	LinkedInPage string    `json:"linkedin_page,omitempty" bson:"linkedin_page,omitempty"`
+	XThread      bool      `json:"x_thread,omitempty" bson:"x_thread,omitempty"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	Region       string    `json:"region" bson:"region"`
+	// AwaitingApproval marks the share of a post already published,
+	// planned by Preferences.AutoShare, which runs once the user approves
+	// it rather than on the post_published webhook.
+	AwaitingApproval bool `json:"awaiting_approval,omitempty" bson:"awaiting_approval,omitempty"`
+}
+
+// ManualTask is a share the user posts by hand, such as an Instagram caption
+// kit: the caption to copy and the image to upload, made when the share was
+// due. The image is served on its own, so it is left out of JSON.
+type ManualTask struct {
+	Id        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    string             `json:"-" bson:"user_id"`
+	Platform  string             `json:"platform" bson:"platform"`
+	BlogID    string             `json:"blog_id" bson:"blog_id"`
+	BlogTitle string             `json:"blog_title" bson:"blog_title"`
+	// BlogURL is the link to put in the account's bio, as posts can't
+	// link out.
+	BlogURL   string     `json:"blog_url" bson:"blog_url"`
+	Caption   string     `json:"caption" bson:"caption"`
+	Image     []byte     `json:"-" bson:"image,omitempty"`
+	ImageType string     `json:"image_type,omitempty" bson:"image_type,omitempty"`
+	Status    string     `json:"status" bson:"status"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty" bson:"done_at,omitempty"`
+	Region    string     `json:"-" bson:"region"`
+}
+
+const (
+	ManualTaskPending = "pending"
+	ManualTaskDone    = "done"
+)
+
+// OAuthClient is a third-party application registered by a developer to act
+// on behalf of users who grant it access.
+type OAuthClient struct {
+	ClientID     string    `json:"client_id" bson:"client_id"`
+	SecretHash   string    `json:"-" bson:"secret_hash"`
+	Name         string    `json:"name" bson:"name"`
+	RedirectURIs []string  `json:"redirect_uris" bson:"redirect_uris"`
+	OwnerID      string    `json:"owner_id" bson:"owner_id"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// OAuthGrant is an authorization code or access token issued to a client. Only
+// the SHA-256 hash of the secret value is stored.
+type OAuthGrant struct {
+	Kind          string    `json:"kind" bson:"kind"`
+	TokenHash     string    `json:"-" bson:"token_hash"`
+	ClientID      string    `json:"client_id" bson:"client_id"`
+	UserID        string    `json:"user_id" bson:"user_id"`
+	Scopes        []string  `json:"scopes" bson:"scopes"`
+	RedirectURI   string    `json:"-" bson:"redirect_uri,omitempty"`
+	CodeChallenge string    `json:"-" bson:"code_challenge,omitempty"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// APIKey is a personal access token a user created to call the API from
+// scripts. Only a hash of the token is stored; Prefix identifies it in lists.
+type APIKey struct {
+	Id         string    `json:"id" bson:"key_id"`
+	UserID     string    `json:"-" bson:"user_id"`
+	Name       string    `json:"name" bson:"name"`
+	Prefix     string    `json:"prefix" bson:"prefix"`
+	TokenHash  string    `json:"-" bson:"token_hash"`
+	Scopes     []string  `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	// ExpiresAt is unset for keys that don't expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+const (
+	OAuthGrantCode        = "code"
+	OAuthGrantAccessToken = "access_token"
+)
+
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleAdmin  = "admin"
+	TeamRoleMember = "member"
+)
+
+// Team is an organization account whose members can sign in through the
+// organization's identity provider.
+type Team struct {
+	Id        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	OwnerID   string             `json:"owner_id" bson:"owner_id"`
+	Domains   []TeamDomain       `json:"domains" bson:"domains"`
+	SSO       TeamSSOConfig      `json:"sso" bson:"sso"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	// Region is the storage region chosen at creation; members' documents
+	// live there.
+	Region string `json:"region" bson:"region"`
+	// SCIMTokenHash is the SHA-256 of the bearer token the IdP uses for SCIM
+	// provisioning; empty until a team admin generates one.
+	SCIMTokenHash string `json:"-" bson:"scim_token_hash,omitempty"`
+	// VerifiedDomains repeats the verified entries of Domains under a unique
+	// index, so two teams can't verify the same domain at once. The
+	// repository keeps it in step with Domains.
+	VerifiedDomains []string `json:"-" bson:"verified_domains,omitempty"`
+}
+
+// SyncVerifiedDomains sets VerifiedDomains from Domains.
+func (t *Team) SyncVerifiedDomains() {
+	t.VerifiedDomains = nil
+	for _, domain := range t.Domains {
+		if domain.Verified {
+			t.VerifiedDomains = append(t.VerifiedDomains, domain.Domain)
+		}
+	}
+}
+
+const (
+	AssetImage    = "image"
+	AssetHashtags = "hashtags"
+	AssetCaption  = "caption"
+)
+
+// LibraryAsset is a reusable image, hashtag set or caption snippet shared by
+// the members of a team. Only the field matching Kind is set.
+type LibraryAsset struct {
+	Id        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	TeamID    string             `json:"team_id" bson:"team_id"`
+	Kind      string             `json:"kind" bson:"kind"`
+	Name      string             `json:"name" bson:"name"`
+	ImageURL  string             `json:"image_url,omitempty" bson:"image_url,omitempty"`
+	Hashtags  []string           `json:"hashtags,omitempty" bson:"hashtags,omitempty"`
+	Text      string             `json:"text,omitempty" bson:"text,omitempty"`
+	CreatedBy string             `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	Region    string             `json:"-" bson:"region"`
+}
+
+// TeamCalendarEntry is a share to one platform scheduled by a team member.
+type TeamCalendarEntry struct {
+	UserID        string    `json:"user_id"`
+	Username      string    `json:"username"`
+	BlogID        string    `json:"blog_id"`
+	BlogTitle     string    `json:"blog_title"`
+	Platform      string    `json:"platform"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	Conflict      bool      `json:"conflict"`
+}
+
+// TeamCalendarConflict groups the entries of several members that post to
+// the same platform within the conflict window of each other.
+type TeamCalendarConflict struct {
+	Platform string              `json:"platform"`
+	Start    time.Time           `json:"start"`
+	End      time.Time           `json:"end"`
+	UserIDs  []string            `json:"user_ids"`
+	Entries  []TeamCalendarEntry `json:"entries"`
+}
+
+// TeamCalendar is the team's scheduled shares between From and To, ordered
+// by time.
+type TeamCalendar struct {
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Entries   []TeamCalendarEntry    `json:"entries"`
+	Conflicts []TeamCalendarConflict `json:"conflicts"`
+}
+
+// TeamDomain is an email domain claimed by a team. SSO only applies to a
+// domain once ownership has been proven through a DNS TXT record.
+type TeamDomain struct {
+	Domain            string `json:"domain" bson:"domain"`
+	VerificationToken string `json:"verification_token" bson:"verification_token"`
+	Verified          bool   `json:"verified" bson:"verified"`
+}
+
+// TeamSSOConfig is the OIDC relying party configuration for a team.
+type TeamSSOConfig struct {
+	Enabled      bool   `json:"enabled" bson:"enabled"`
+	Issuer       string `json:"issuer" bson:"issuer"`
+	ClientID     string `json:"client_id" bson:"client_id"`
+	ClientSecret string `json:"-" bson:"client_secret"`
+	// GroupsClaim names the ID token claim listing the user's IdP groups,
+	// which RoleMapping translates into team roles.
+	GroupsClaim string            `json:"groups_claim" bson:"groups_claim"`
+	RoleMapping map[string]string `json:"role_mapping" bson:"role_mapping"`
+	DefaultRole string            `json:"default_role" bson:"default_role"`
+}
+
+type HashnodeWebhookEvent struct {
+	Metadata struct {
+		UUID string `json:"uuid"`
+	} `json:"metadata"`
+	Data struct {
+		Publication struct {
+			Id string `json:"id"`
+		} `json:"publication"`
+		Post struct {
+			Id string `json:"id"`
+		} `json:"post"`
+		EventType string `json:"eventType"`
+	} `json:"data"`
+}
+
+// ProviderResponse is a redacted copy of a raw response returned by a social
+// platform, kept so support can debug failed posts without a reproduction.
+type ProviderResponse struct {
+	UserID     string    `json:"user_id" bson:"user_id"`
+	Platform   string    `json:"platform" bson:"platform"`
+	Endpoint   string    `json:"endpoint" bson:"endpoint"`
+	StatusCode int       `json:"status_code" bson:"status_code"`
+	Body       string    `json:"body" bson:"body"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	Region     string    `json:"region" bson:"region"`
+}
+
+// DebugCapture is a redacted request/response pair recorded while an admin
+// has enabled debug capture for a user or issued a debug token.
+type DebugCapture struct {
+	UserID          string            `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	DebugToken      string            `json:"debug_token,omitempty" bson:"debug_token,omitempty"`
+	RequestID       string            `json:"request_id" bson:"request_id"`
+	Method          string            `json:"method" bson:"method"`
+	Path            string            `json:"path" bson:"path"`
+	Query           string            `json:"query,omitempty" bson:"query,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers" bson:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty" bson:"request_body,omitempty"`
+	StatusCode      int               `json:"status_code" bson:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers" bson:"response_headers"`
+	ResponseBody    string            `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	DurationMs      int64             `json:"duration_ms" bson:"duration_ms"`
+	CreatedAt       time.Time         `json:"created_at" bson:"created_at"`
+	ExpiresAt       time.Time         `json:"expires_at" bson:"expires_at"`
 }
 
 type GraphQLQuery struct {
@@ -92,14 +1393,75 @@ type PostNode struct {
 	CoverImage        CoverImage `json:"coverImage"`
 	Author            Author     `json:"author"`
 	ReadTimeInMinutes int        `json:"readTimeInMinutes"`
+	PublishedAt       string     `json:"publishedAt,omitempty"`
+	Brief             string     `json:"brief,omitempty"`
+	// Source is "devto" for Dev.to articles, "ghost" for Ghost posts,
+	// "medium" for Medium stories, PostSourceFeed for feed items and empty
+	// for Hashnode posts.
+	Source string `json:"source,omitempty"`
+	// Publication is the host of the Hashnode publication of a synced
+	// Hashnode post.
+	Publication string `json:"publication,omitempty"`
+}
+
+// PostSourceFeed is the source of posts polled from the user's feed.
+const PostSourceFeed = "feed"
+
+// Post is the local copy of a post of a user's Hashnode publication, kept in
+// step by the post sync worker so listing blogs doesn't call Hashnode, or of
+// an item of their feed, polled by the feed sync worker.
+type Post struct {
+	UserID            string    `bson:"user_id"`
+	Id                string    `bson:"id"`
+	Title             string    `bson:"title"`
+	Url               string    `bson:"url"`
+	CoverImageURL     string    `bson:"cover_image_url"`
+	AuthorName        string    `bson:"author_name"`
+	ReadTimeInMinutes int       `bson:"read_time_in_minutes"`
+	Brief             string    `bson:"brief,omitempty"`
+	PublishedAt       time.Time `bson:"published_at"`
+	SyncedAt          time.Time `bson:"synced_at"`
+	Region            string    `bson:"region"`
+	// Source is PostSourceFeed for items of the user's feed and unset for
+	// Hashnode posts.
+	Source string `bson:"source,omitempty"`
+	// Publication is the host of the Hashnode publication of the post.
+	// Posts synced before publications were told apart leave it unset and
+	// belong to the default one.
+	Publication string `bson:"publication,omitempty"`
+}
+
+// Node returns the post as Hashnode lists it.
+func (p Post) Node() PostNode {
+	node := PostNode{
+		Title:             p.Title,
+		URL:               p.Url,
+		ID:                p.Id,
+		CoverImage:        CoverImage{URL: p.CoverImageURL},
+		Author:            Author{Name: p.AuthorName},
+		ReadTimeInMinutes: p.ReadTimeInMinutes,
+		Brief:             p.Brief,
+		Source:            p.Source,
+		Publication:       p.Publication,
+	}
+	if !p.PublishedAt.IsZero() {
+		node.PublishedAt = p.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	return node
 }
 
 type Edge struct {
 	Node PostNode `json:"node"`
 }
 
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
 type Posts struct {
-	Edges []Edge `json:"edges"`
+	Edges    []Edge   `json:"edges"`
+	PageInfo PageInfo `json:"pageInfo"`
 }
 
 type Publication struct {
@@ -126,71 +1488,460 @@ type CacheItem struct {
 	Key       string      `bson:"key"`
 	Value     interface{} `bson:"value"`
 	ExpiresAt time.Time   `bson:"expiresAt,omitempty"`
+	// Session is set on session entries, whose Value is the user's id.
+	Session *SessionInfo `bson:"session,omitempty"`
+	// Attempts is set on login throttling entries.
+	Attempts *LoginAttempts `bson:"attempts,omitempty"`
+	// EmailChange is set on pending email change entries.
+	EmailChange *PendingEmailChange `bson:"email_change,omitempty"`
+	// MagicLink is set on unused magic login link entries.
+	MagicLink *MagicLink `bson:"magic_link,omitempty"`
+	// Maintenance is set on the maintenance mode entry while it is on.
+	Maintenance *MaintenanceState `bson:"maintenance,omitempty"`
+	// MastodonApp is set on the entry of each Mastodon server the app is
+	// registered with.
+	MastodonApp *MastodonApp `bson:"mastodon_app,omitempty"`
+	// MastodonAuth is set on the entry of a Mastodon authorization in
+	// progress, keyed by its OAuth state.
+	MastodonAuth *MastodonAuth `bson:"mastodon_auth,omitempty"`
+}
+
+// MastodonApp is the OAuth client SocialScribe registered on a Mastodon
+// server. Apps are bound to their redirect URI.
+type MastodonApp struct {
+	ClientID     string `bson:"client_id"`
+	ClientSecret string `bson:"client_secret"`
+	RedirectURI  string `bson:"redirect_uri"`
+}
+
+// MastodonAuth links the OAuth state of a Mastodon authorization to the user
+// and server it is for.
+type MastodonAuth struct {
+	UserID   string `bson:"user_id"`
+	Instance string `bson:"instance"`
+}
+
+// MaintenanceState describes maintenance mode. EndsAt is the operator's
+// estimate of when it ends; maintenance lasts until it is turned off.
+type MaintenanceState struct {
+	Enabled   bool      `json:"enabled" bson:"enabled"`
+	Message   string    `json:"message,omitempty" bson:"message,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	EndsAt    time.Time `json:"ends_at,omitempty" bson:"ends_at,omitempty"`
+}
+
+// MagicLink is a one-time login link emailed to a user. It is stored under a
+// hash of its token.
+type MagicLink struct {
+	UserID     string `bson:"user_id"`
+	RememberMe bool   `bson:"remember_me"`
+}
+
+// PendingEmailChange is a new account email waiting for its OTP. Only a hash
+// of the OTP is kept.
+type PendingEmailChange struct {
+	Email    string `bson:"email"`
+	OTPHash  string `bson:"otp_hash"`
+	Failures int    `bson:"failures"`
+}
+
+// LoginAttempts counts the failed logins of a username or client IP.
+// Failures reset whenever a lockout starts; Lockouts keeps growing so each
+// lockout lasts longer than the last.
+type LoginAttempts struct {
+	Failures    int       `bson:"failures"`
+	Lockouts    int       `bson:"lockouts"`
+	LockedUntil time.Time `bson:"locked_until"`
+}
+
+// SessionInfo records where a session was started.
+type SessionInfo struct {
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	IP        string    `json:"ip" bson:"ip"`
+	UserAgent string    `json:"user_agent" bson:"user_agent"`
+	// RememberMe sessions last remember_me_lifetime_hours, and so do the
+	// sessions they are refreshed into.
+	RememberMe bool `json:"remember_me,omitempty" bson:"remember_me,omitempty"`
+}
+
+// MaxLoginHistory is how many sign-ins are kept per user.
+const MaxLoginHistory = 50
+
+// LoginRecord is a sign-in to the account. NewDevice and NewCountry flag
+// sign-ins that raised an alert.
+type LoginRecord struct {
+	At        time.Time `json:"at" bson:"at"`
+	Method    string    `json:"method" bson:"method"`
+	IP        string    `json:"ip" bson:"ip"`
+	UserAgent string    `json:"user_agent" bson:"user_agent"`
+	// Device is the browser and operating system named by the user agent,
+	// such as "Firefox on Windows".
+	Device string `json:"device" bson:"device"`
+	// Country is the ISO code reported by the proxy in front of the server;
+	// empty when it doesn't report one.
+	Country    string `json:"country,omitempty" bson:"country,omitempty"`
+	NewDevice  bool   `json:"new_device,omitempty" bson:"new_device,omitempty"`
+	NewCountry bool   `json:"new_country,omitempty" bson:"new_country,omitempty"`
+}
+
+// ConsentRecord is an acceptance of the terms of service and privacy policy
+// versions it names.
+type ConsentRecord struct {
+	TermsOfService string    `json:"terms_of_service" bson:"terms_of_service"`
+	PrivacyPolicy  string    `json:"privacy_policy" bson:"privacy_policy"`
+	At             time.Time `json:"at" bson:"at"`
+	IP             string    `json:"ip" bson:"ip"`
+	UserAgent      string    `json:"user_agent" bson:"user_agent"`
+}
+
+// AuditConsentAccepted is the audit log action of a user accepting the terms.
+const AuditConsentAccepted = "consent.accepted"
+
+// AuditEvent is an entry of the audit log. Entries are only ever added, and
+// are kept in the user's storage region.
+type AuditEvent struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Action    string             `json:"action" bson:"action"`
+	At        time.Time          `json:"at" bson:"at"`
+	IP        string             `json:"ip" bson:"ip"`
+	UserAgent string             `json:"user_agent" bson:"user_agent"`
+	Details   map[string]string  `json:"details,omitempty" bson:"details,omitempty"`
+	Region    string             `json:"-" bson:"region"`
+}
+
+// HasConsented reports whether the user's latest acceptance covers the given
+// terms of service and privacy policy versions. Empty versions need no
+// acceptance.
+func (u *User) HasConsented(termsOfService, privacyPolicy string) bool {
+	if termsOfService == "" && privacyPolicy == "" {
+		return true
+	}
+	if len(u.ConsentHistory) == 0 {
+		return false
+	}
+	latest := u.ConsentHistory[len(u.ConsentHistory)-1]
+	return (termsOfService == "" || latest.TermsOfService == termsOfService) &&
+		(privacyPolicy == "" || latest.PrivacyPolicy == privacyPolicy)
+}
+
+// ActiveSession is a session as listed to its user. Id identifies it for
+// revocation without revealing the token.
+type ActiveSession struct {
+	Id        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// FieldError is what is wrong with one field of a request. Field is the
+// field's JSON path.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field of a request that failed validation,
+// so clients can point at all of them at once. It is invalid input.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return apperrors.ErrInvalidInput
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns e if any field failed, and nil otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// sharePlatforms are the platforms blogs can be shared to.
+var sharePlatforms = map[string]bool{
+	"twitter":         true,
+	"linkedin":        true,
+	"mastodon":        true,
+	"reddit":          true,
+	"discord":         true,
+	"devto":           true,
+	"medium":          true,
+	"nostr":           true,
+	"lemmy":           true,
+	"wordpress":       true,
+	"matrix":          true,
+	"tumblr":          true,
+	"teams":           true,
+	"newsletter":      true,
+	"substack":        true,
+	"google_business": true,
+	"instagram":       true,
+	"webhook":         true,
+	"youtube":         true,
+}
+
+// IsSharePlatform reports whether blogs can be shared to platform.
+func IsSharePlatform(platform string) bool {
+	return sharePlatforms[platform]
 }
 
+const (
+	// maxBlogTitleLength is the longest title any platform takes, Reddit's.
+	maxBlogTitleLength = 300
+	// maxScheduleAhead is how far ahead a blog may be scheduled, counting
+	// its platform offsets.
+	maxScheduleAhead = 7 * 24 * time.Hour
+)
+
 func (b *Blog) ValidateBase() error {
-	if strings.TrimSpace(b.Title) == "" {
-		return fmt.Errorf("title is required")
+	var v ValidationError
+	b.validateBase(&v)
+	return v.err()
+}
+
+func (b *Blog) validateBase(v *ValidationError) {
+	title := strings.TrimSpace(b.Title)
+	if title == "" {
+		v.add("title", "is required")
+	} else if utf8.RuneCountInString(title) > maxBlogTitleLength {
+		v.add("title", "must be at most %d characters", maxBlogTitleLength)
 	}
 
-	if strings.TrimSpace(b.Url) == "" || !isValidURL(b.Url) {
-		return fmt.Errorf("a valid URL is required")
+	if strings.TrimSpace(b.Url) == "" {
+		v.add("url", "is required")
+	} else if !isWebURL(b.Url) {
+		v.add("url", "must be an http or https URL")
 	}
 
 	if strings.TrimSpace(b.Id) == "" {
-		return fmt.Errorf("ID is required")
+		v.add("id", "is required")
 	}
 
 	if strings.TrimSpace(b.Author.Name) == "" {
-		return fmt.Errorf("author name is required")
+		v.add("author.name", "is required")
 	}
 
-	if strings.TrimSpace(b.CoverImage.URL) == "" || !isValidURL(b.CoverImage.URL) {
-		return fmt.Errorf("a valid cover image URL is required")
+	if strings.TrimSpace(b.CoverImage.URL) == "" || !isWebURL(b.CoverImage.URL) {
+		v.add("coverImage.url", "must be an http or https URL")
 	}
+}
 
-	return nil
+func validatePlatforms(v *ValidationError, platforms []string) {
+	if len(platforms) == 0 {
+		v.add("platforms", "at least one platform is required")
+		return
+	}
+	for i, platform := range platforms {
+		if !IsSharePlatform(platform) {
+			v.add(fmt.Sprintf("platforms[%d]", i), "unknown platform %q", platform)
+		} else if slices.Index(platforms, platform) != i {
+			v.add(fmt.Sprintf("platforms[%d]", i), "%s is listed twice", platform)
+		}
+	}
 }
 
+// Validate checks the whole schedule and reports every field that is wrong.
+// The blog must be shared in the future, and within maxScheduleAhead even on
+// its most delayed platform.
 func (sb *ScheduledBlog) Validate() error {
+	var v ValidationError
+	sb.Blog.validateBase(&v)
+	validatePlatforms(&v, sb.Platforms)
 
-	if err := sb.Blog.ValidateBase(); err != nil {
-		return err
+	var lastOffset time.Duration
+	for platform, minutes := range sb.PlatformOffsets {
+		field := "platform_offsets." + platform
+		if !slices.Contains(sb.Platforms, platform) {
+			v.add(field, "%s is not scheduled", platform)
+			continue
+		}
+		offset := time.Duration(minutes) * time.Minute
+		if offset < 0 || offset > maxPlatformOffset {
+			v.add(field, "must be between 0 and %d minutes", int(maxPlatformOffset.Minutes()))
+			continue
+		}
+		lastOffset = max(lastOffset, offset)
+	}
+	if len(sb.AssetIDs) > MaxShareAssets {
+		v.add("asset_ids", "at most %d library assets can be applied to a share", MaxShareAssets)
+	}
+	if sb.Timezone != "" {
+		if _, err := time.LoadLocation(sb.Timezone); err != nil {
+			v.add("timezone", "must be an IANA time zone such as Europe/Berlin")
+		}
 	}
 
-	if len(sb.Platforms) == 0 {
-		return fmt.Errorf("at least one platform is required")
+	switch diff := sb.ScheduledTime.Sub(utils.Now()); {
+	case sb.ScheduledTime.IsZero():
+		v.add("scheduled_time", "is required")
+	case diff <= 0:
+		v.add("scheduled_time", "is in the past")
+	case diff+lastOffset > maxScheduleAhead:
+		v.add("scheduled_time", "is more than %d days from now", int(maxScheduleAhead.Hours()/24))
 	}
 
-	scheduledTime, err := time.Parse(time.RFC3339, sb.ScheduledTime.Format(time.RFC3339))
+	return v.err()
+}
+
+// PlatformTime is when the blog is shared to platform.
+func (sb *ScheduledBlog) PlatformTime(platform string) time.Time {
+	return sb.ScheduledTime.Add(time.Duration(sb.PlatformOffsets[platform]) * time.Minute)
+}
+
+// LocalTimeLayout is the layout of ScheduledBlog.LocalTime.
+const LocalTimeLayout = "2006-01-02T15:04:05"
+
+// AnchorLocalTime records the wall-clock time the blog is scheduled for in
+// its timezone. A blog without a valid timezone keeps none.
+func (sb *ScheduledBlog) AnchorLocalTime() {
+	sb.LocalTime = ""
+	if sb.Timezone == "" {
+		return
+	}
+	loc, err := time.LoadLocation(sb.Timezone)
 	if err != nil {
-		return fmt.Errorf("invalid scheduled_time format, expected YYYY-MM-DD HH:mm")
+		return
 	}
-	currentTime := time.Now()
-	diff := scheduledTime.Sub(currentTime)
+	sb.LocalTime = sb.ScheduledTime.In(loc).Format(LocalTimeLayout)
+}
 
-	if diff > (7 * 24 * time.Hour) {
-		return fmt.Errorf("scheduled time is more than 7 days from now")
-	} else if diff < 0 {
-		return fmt.Errorf("scheduled time is in the past")
+// PlanChildren records a pending child per platform for a blog scheduled
+// with platform offsets.
+func (sb *ScheduledBlog) PlanChildren() {
+	sb.Children = nil
+	if len(sb.PlatformOffsets) == 0 {
+		return
+	}
+	for _, platform := range sb.Platforms {
+		sb.Children = append(sb.Children, ScheduledChild{
+			Platform:      platform,
+			ScheduledTime: sb.PlatformTime(platform),
+			Status:        SchedulePending,
+		})
 	}
-
-	return nil
 }
 
-func (shb *SharedBlog) Validate() error {
-	if err := shb.Blog.ValidateBase(); err != nil {
-		return err
+// Tasks returns the scheduler tasks of the blog: one per child when it was
+// scheduled with platform offsets, else just the blog itself.
+func (d ScheduledBlogData) Tasks() []ScheduledBlogData {
+	if len(d.ScheduledBlog.Children) == 0 {
+		return []ScheduledBlogData{d}
+	}
+	tasks := make([]ScheduledBlogData, 0, len(d.ScheduledBlog.Children))
+	for _, child := range d.ScheduledBlog.Children {
+		task := d
+		task.Platform = child.Platform
+		task.ScheduledBlog.Platforms = []string{child.Platform}
+		task.ScheduledBlog.ScheduledTime = child.ScheduledTime
+		task.ScheduledBlog.Children = nil
+		tasks = append(tasks, task)
 	}
+	return tasks
+}
 
-	if len(shb.Platforms) == 0 {
-		return fmt.Errorf("at least one platform is required")
+// RollupStatus sums up the statuses of the children.
+func (sb *ScheduledBlog) RollupStatus() string {
+	pending, shared := 0, 0
+	for _, child := range sb.Children {
+		switch child.Status {
+		case SchedulePending:
+			pending++
+		case ScheduleShared:
+			shared++
+		}
+	}
+	switch {
+	case len(sb.Children) == 0 || pending == len(sb.Children):
+		return SchedulePending
+	case pending > 0:
+		return ScheduleInProgress
+	case shared == len(sb.Children):
+		return ScheduleShared
+	case shared == 0:
+		return ScheduleFailed
+	default:
+		return SchedulePartiallyShared
 	}
+}
 
-	return nil
+func (shb *SharedBlog) Validate() error {
+	var v ValidationError
+	shb.Blog.validateBase(&v)
+	validatePlatforms(&v, shb.Platforms)
+	return v.err()
 }
 
 func isValidURL(str string) bool {
 	u, err := url.Parse(str)
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
+
+// isWebURL is isValidURL limited to pages a browser opens.
+func isWebURL(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+const (
+	// MaxShareAssets caps the library assets applied to a single share.
+	MaxShareAssets        = 10
+	maxAssetHashtags      = 30
+	maxAssetHashtagLength = 100
+	maxAssetCaptionLength = 1000
+)
+
+// Validate checks the asset and normalizes its hashtags to bare tags without
+// the leading #.
+func (a *LibraryAsset) Validate() error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" || len(a.Name) > 100 {
+		return fmt.Errorf("asset name must be between 1 and 100 characters")
+	}
+	switch a.Kind {
+	case AssetImage:
+		a.ImageURL = strings.TrimSpace(a.ImageURL)
+		if !strings.HasPrefix(a.ImageURL, "https://") || !isValidURL(a.ImageURL) {
+			return fmt.Errorf("a valid https image URL is required")
+		}
+		a.Hashtags, a.Text = nil, ""
+	case AssetHashtags:
+		if len(a.Hashtags) == 0 || len(a.Hashtags) > maxAssetHashtags {
+			return fmt.Errorf("a hashtag set needs between 1 and %d hashtags", maxAssetHashtags)
+		}
+		for i, tag := range a.Hashtags {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+			if tag == "" || len(tag) > maxAssetHashtagLength || strings.IndexFunc(tag, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+			}) >= 0 {
+				return fmt.Errorf("invalid hashtag %q", a.Hashtags[i])
+			}
+			a.Hashtags[i] = tag
+		}
+		a.ImageURL, a.Text = "", ""
+	case AssetCaption:
+		a.Text = strings.TrimSpace(a.Text)
+		if a.Text == "" || len(a.Text) > maxAssetCaptionLength {
+			return fmt.Errorf("a caption snippet must be between 1 and %d characters", maxAssetCaptionLength)
+		}
+		a.ImageURL, a.Hashtags = "", nil
+	default:
+		return fmt.Errorf("asset kind must be image, hashtags or caption")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestToPublicProfileDTO(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	user := &User{UserName: "ada", HashnodeBlog: "ada.hashnode.dev"}
+	for i := 0; i < MaxPublicShares+5; i++ {
+		user.SharedBlogs = append(user.SharedBlogs, SharedBlog{
+			Blog:       Blog{Id: fmt.Sprintf("blog-%d", i), Title: "Post"},
+			Platforms:  []string{"twitter"},
+			SharedTime: start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+		})
+	}
+	user.PublicProfile = PublicProfile{Enabled: true, Bio: "Writes about Go"}
+
+	if profile := user.ToPublicProfileDTO(); profile.Shares != nil || profile.Bio != "Writes about Go" {
+		t.Errorf("shares shown without show_shares: %+v", profile)
+	}
+
+	last := fmt.Sprintf("blog-%d", MaxPublicShares+4)
+	user.PublicProfile.ShowShares = true
+	user.PublicProfile.HiddenBlogs = []string{last}
+	profile := user.ToPublicProfileDTO()
+	if len(profile.Shares) != MaxPublicShares {
+		t.Fatalf("got %d shares, want %d", len(profile.Shares), MaxPublicShares)
+	}
+	if first := profile.Shares[0]; first.Id != fmt.Sprintf("blog-%d", MaxPublicShares+3) || first.Platforms != nil {
+		t.Errorf("first share = %+v, want the newest visible one without platforms", first)
+	}
+
+	user.PublicProfile.ShowPlatforms = true
+	if shares := user.ToPublicProfileDTO().Shares; len(shares[0].Platforms) != 1 {
+		t.Errorf("platforms not shown: %+v", shares[0])
+	}
+}
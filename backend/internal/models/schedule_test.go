@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+)
+
+func offsetSchedule(at time.Time, offsets map[string]int) ScheduledBlog {
+	return ScheduledBlog{
+		Blog: Blog{
+			Id:         "blog-1",
+			Title:      "Title",
+			Url:        "https://blog.example.com/post",
+			CoverImage: Image{URL: "https://cdn.example.com/cover.png"},
+			Author:     Author{Name: "Author"},
+		},
+		Platforms:       []string{"twitter", "linkedin"},
+		ScheduledTime:   at,
+		PlatformOffsets: offsets,
+	}
+}
+
+func TestScheduledBlogTasksFanOutByOffset(t *testing.T) {
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Minute)
+	data := ScheduledBlogData{UserID: "user-1", ScheduledBlog: offsetSchedule(at, map[string]int{"linkedin": 30})}
+	data.ScheduledBlog.PlanChildren()
+
+	tasks := data.Tasks()
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	want := map[string]time.Time{"twitter": at, "linkedin": at.Add(30 * time.Minute)}
+	for _, task := range tasks {
+		if len(task.ScheduledBlog.Platforms) != 1 || task.ScheduledBlog.Platforms[0] != task.Platform {
+			t.Errorf("child %s shares to %v", task.Platform, task.ScheduledBlog.Platforms)
+		}
+		if !task.ScheduledBlog.ScheduledTime.Equal(want[task.Platform]) {
+			t.Errorf("child %s runs at %v, want %v", task.Platform, task.ScheduledBlog.ScheduledTime, want[task.Platform])
+		}
+	}
+
+	plain := ScheduledBlogData{ScheduledBlog: offsetSchedule(at, nil)}
+	plain.ScheduledBlog.PlanChildren()
+	if tasks := plain.Tasks(); len(tasks) != 1 || tasks[0].Platform != "" {
+		t.Errorf("a schedule without offsets should stay one task, got %+v", tasks)
+	}
+}
+
+func TestScheduledBlogValidateOffsets(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	lastDay := time.Now().Add(7*24*time.Hour - time.Hour)
+	cases := map[string]struct {
+		at      time.Time
+		offsets map[string]int
+		ok      bool
+	}{
+		"valid":             {soon, map[string]int{"linkedin": 30}, true},
+		"unscheduled":       {soon, map[string]int{"mastodon": 30}, false},
+		"negative":          {soon, map[string]int{"linkedin": -5}, false},
+		"too long":          {soon, map[string]int{"linkedin": 25 * 60}, false},
+		"past the 7 days":   {lastDay, map[string]int{"linkedin": 24 * 60}, false},
+		"within the 7 days": {lastDay, map[string]int{"linkedin": 30}, true},
+	}
+	for name, tc := range cases {
+		blog := offsetSchedule(tc.at, tc.offsets)
+		if err := blog.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v, want ok %t", name, err, tc.ok)
+		}
+	}
+}
+
+func TestScheduledBlogValidateReportsEveryField(t *testing.T) {
+	blog := offsetSchedule(time.Now().Add(-time.Minute), nil)
+	blog.Title = " "
+	blog.Url = "javascript://blog.example.com/post"
+	blog.Platforms = []string{"linkedin", "myspace", "linkedin"}
+
+	err := blog.Validate()
+	if !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Fatalf("Validate() = %v, want invalid input", err)
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() = %T, want a ValidationError", err)
+	}
+	got := map[string]bool{}
+	for _, field := range validationErr.Fields {
+		got[field.Field] = true
+	}
+	for _, field := range []string{"title", "url", "platforms[1]", "platforms[2]", "scheduled_time"} {
+		if !got[field] {
+			t.Errorf("no error for %s in %v", field, validationErr.Fields)
+		}
+	}
+	if len(validationErr.Fields) != 5 {
+		t.Errorf("got %d field errors, want 5: %v", len(validationErr.Fields), validationErr.Fields)
+	}
+
+	blog = offsetSchedule(time.Time{}, nil)
+	blog.Platforms = nil
+	if err := blog.Validate(); err == nil || err.Error() != "platforms: at least one platform is required; scheduled_time: is required" {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestScheduledBlogRollupStatus(t *testing.T) {
+	cases := []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{SchedulePending, SchedulePending}, SchedulePending},
+		{[]string{ScheduleShared, SchedulePending}, ScheduleInProgress},
+		{[]string{ScheduleShared, ScheduleShared}, ScheduleShared},
+		{[]string{ScheduleFailed, ScheduleFailed}, ScheduleFailed},
+		{[]string{ScheduleShared, ScheduleFailed}, SchedulePartiallyShared},
+	}
+	for _, tc := range cases {
+		var blog ScheduledBlog
+		for _, status := range tc.statuses {
+			blog.Children = append(blog.Children, ScheduledChild{Status: status})
+		}
+		if got := blog.RollupStatus(); got != tc.want {
+			t.Errorf("RollupStatus(%v) = %s, want %s", tc.statuses, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package newsletter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// A delivery whose attempt was cut short is retried once the lease runs
+	// out
+	lease        = 10 * time.Minute
+	pollInterval = 30 * time.Second
+	maxAttempts  = 3
+)
+
+// retryDelays is how long to wait before each retry of a failed delivery.
+var retryDelays = []time.Duration{5 * time.Minute, 30 * time.Minute}
+
+// Worker mails the queued deliveries of built-in newsletters, one subscriber
+// at a time. Failed deliveries are retried, and the user is told once an
+// issue is done if some subscribers couldn't be mailed.
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	clock  utils.Clock
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{ctx: ctx, cancel: cancel, clock: utils.GetClock()}
+	go w.run()
+	return w
+}
+
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
+func (w *Worker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Newsletter worker panicked: %v", r)
+			reporting.Report(w.ctx, fmt.Errorf("newsletter worker panicked: %v", r), map[string]string{
+				"component": "newsletter",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Newsletter worker started")
+	for {
+		w.deliverDue()
+		if !w.sleep(pollInterval) {
+			log.Println("[INFO] Newsletter worker stopped")
+			return
+		}
+	}
+}
+
+// deliverDue mails deliveries until none is due.
+func (w *Worker) deliverDue() {
+	for w.ctx.Err() == nil {
+		delivery, err := repo.ClaimNewsletterDelivery(w.clock.Now(), lease)
+		if err != nil || delivery == nil {
+			return
+		}
+		if err := w.deliver(delivery); err != nil {
+			log.Printf("[ERROR] Failed to store a newsletter delivery of user %s: %v", delivery.UserID, err)
+		}
+	}
+}
+
+func (w *Worker) deliver(delivery *models.NewsletterDelivery) error {
+	subscribed, err := repo.NewsletterSubscribed(delivery.UserID, delivery.Email)
+	if err != nil {
+		return err
+	}
+	if subscribed {
+		w.send(delivery)
+	} else {
+		// Unsubscribed after the issue was queued
+		delivery.Status = models.DeliveryUnsubscribed
+	}
+	if err := repo.UpdateNewsletterDelivery(delivery); err != nil {
+		return err
+	}
+	if delivery.Status != models.DeliveryPending {
+		return w.reportIssue(delivery)
+	}
+	return nil
+}
+
+// send mails the delivery and records the outcome on it: sent, pending with
+// its next attempt, or failed for good once maxAttempts is reached.
+func (w *Worker) send(delivery *models.NewsletterDelivery) {
+	now := w.clock.Now()
+	err := services.DeliverNewsletter(delivery)
+	if err == nil {
+		delivery.Status = models.DeliverySent
+		delivery.Error = ""
+		delivery.SentAt = &now
+		return
+	}
+	delivery.Error = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		log.Printf("[WARN] Gave up mailing the newsletter of user %s to a subscriber after %d attempts: %v", delivery.UserID, delivery.Attempts, err)
+		delivery.Status = models.DeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(retryDelays[min(delivery.Attempts, len(retryDelays))-1])
+}
+
+// reportIssue tells the user, once every delivery of the issue is done, how
+// many subscribers it couldn't be mailed to. Only the delivery that finished
+// the issue sees none pending.
+func (w *Worker) reportIssue(delivery *models.NewsletterDelivery) error {
+	counts, err := repo.CountNewsletterDeliveries(delivery.UserID, delivery.IssueID)
+	if err != nil {
+		return err
+	}
+	if counts[models.DeliveryPending] > 0 || counts[models.DeliveryFailed] == 0 {
+		return nil
+	}
+	user, err := repo.GetUserById(delivery.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+	total := counts[models.DeliverySent] + counts[models.DeliveryFailed]
+	user.AddNotification(fmt.Sprintf("The newsletter %q could not be mailed to %d of %d subscribers", delivery.Subject, counts[models.DeliveryFailed], total), w.clock.Now().UTC())
+	return repo.UpdateUser(delivery.UserID, user)
+}
+
+// sleep waits for d and reports false if the worker was stopped meanwhile.
+func (w *Worker) sleep(d time.Duration) bool {
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
@@ -0,0 +1,183 @@
+package postsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	pageSize = 20
+	// Hashnode rate limits per client, so pages are fetched one at a time
+	// with a pause in between
+	pageDelay = time.Second
+	maxPages  = 500
+	// lease must outlast a sync of maxPages pages, or another instance
+	// starts syncing the same user
+	lease          = 15 * time.Minute
+	pollInterval   = 30 * time.Second
+	resyncInterval = 30 * time.Minute
+	retryInterval  = 5 * time.Minute
+	// After a 429 the worker stops calling Hashnode for a while and the
+	// throttled user goes to the back of the queue
+	rateLimitPause   = 2 * time.Minute
+	rateLimitBackoff = 15 * time.Minute
+)
+
+// Worker keeps the local posts collection in step with the users' Hashnode
+// publications, so listing blogs doesn't page through Hashnode on request.
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	clock  utils.Clock
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{ctx: ctx, cancel: cancel, clock: utils.GetClock()}
+	go w.run()
+	return w
+}
+
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
+func (w *Worker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Post sync worker panicked: %v", r)
+			reporting.Report(w.ctx, fmt.Errorf("post sync worker panicked: %v", r), map[string]string{
+				"component": "postsync",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Post sync worker started")
+	for {
+		pause := w.syncDue()
+		if !w.sleep(pause) {
+			log.Println("[INFO] Post sync worker stopped")
+			return
+		}
+	}
+}
+
+// syncDue syncs users until none is due and returns how long to wait before
+// looking again.
+func (w *Worker) syncDue() time.Duration {
+	for w.ctx.Err() == nil {
+		user, err := repo.ClaimPostSync(w.clock.Now(), lease)
+		if err != nil || user == nil {
+			return pollInterval
+		}
+		userID := user.Id.Hex()
+
+		err = w.syncUser(userID, services.PublicationHosts(user))
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, context.Canceled):
+			return 0
+		case errors.Is(err, apperrors.ErrProviderRateLimited):
+			log.Printf("[WARN] Hashnode throttled the post sync of user %s, pausing for %s", userID, rateLimitPause)
+			repo.SchedulePostSync(userID, w.clock.Now().Add(rateLimitBackoff))
+			return rateLimitPause
+		default:
+			log.Printf("[ERROR] Failed to sync posts of user %s: %v", userID, err)
+			repo.SchedulePostSync(userID, w.clock.Now().Add(retryInterval))
+		}
+	}
+	return 0
+}
+
+// syncUser copies every post of the publications at hosts and then drops
+// the local posts the sync didn't see.
+func (w *Worker) syncUser(userID string, hosts []string) error {
+	// Mongo keeps milliseconds, so the cut-off must not be finer
+	syncStart := w.clock.Now().UTC().Truncate(time.Millisecond)
+	for i, host := range hosts {
+		if i > 0 && !w.sleep(pageDelay) {
+			return context.Canceled
+		}
+		if err := w.syncPublication(userID, host, syncStart); err != nil {
+			return err
+		}
+	}
+
+	if err := repo.DeletePostsSyncedBefore(userID, syncStart); err != nil {
+		return err
+	}
+	return repo.SchedulePostSync(userID, w.clock.Now().Add(resyncInterval))
+}
+
+// syncPublication copies every post of the publication at host.
+func (w *Worker) syncPublication(userID, host string, syncStart time.Time) error {
+	after := ""
+	for page := 0; ; page++ {
+		if page == maxPages {
+			return fmt.Errorf("publication %s has more than %d pages of posts", host, maxPages)
+		}
+		if page > 0 && !w.sleep(pageDelay) {
+			return context.Canceled
+		}
+		nodes, pageInfo, err := services.FetchPublicationPostsPage(host, pageSize, after)
+		if err != nil {
+			return err
+		}
+		posts := make([]models.Post, 0, len(nodes))
+		for _, node := range nodes {
+			post := postFromNode(node, syncStart)
+			post.Publication = host
+			posts = append(posts, post)
+		}
+		if err := repo.UpsertPosts(userID, posts); err != nil {
+			return err
+		}
+		if !pageInfo.HasNextPage || pageInfo.EndCursor == "" {
+			return nil
+		}
+		after = pageInfo.EndCursor
+	}
+}
+
+func postFromNode(node models.PostNode, syncedAt time.Time) models.Post {
+	post := models.Post{
+		Id:                node.ID,
+		Title:             node.Title,
+		Url:               node.URL,
+		CoverImageURL:     node.CoverImage.URL,
+		AuthorName:        node.Author.Name,
+		ReadTimeInMinutes: node.ReadTimeInMinutes,
+		Brief:             node.Brief,
+		SyncedAt:          syncedAt,
+	}
+	if publishedAt, err := time.Parse(time.RFC3339, node.PublishedAt); err == nil {
+		post.PublishedAt = publishedAt.UTC()
+	}
+	return post
+}
+
+// sleep waits for d and reports false if the worker was stopped meanwhile.
+func (w *Worker) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return w.ctx.Err() == nil
+	}
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
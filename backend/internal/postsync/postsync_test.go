@@ -0,0 +1,40 @@
+package postsync
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestPostFromNode(t *testing.T) {
+	syncedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	node := models.PostNode{
+		Title:             "Hello",
+		URL:               "https://blog.example.com/hello",
+		ID:                "post-1",
+		CoverImage:        models.CoverImage{URL: "https://cdn.example.com/cover.png"},
+		Author:            models.Author{Name: "Ada"},
+		ReadTimeInMinutes: 4,
+		PublishedAt:       "2024-02-10T08:30:00+02:00",
+	}
+
+	post := postFromNode(node, syncedAt)
+	if post.Id != "post-1" || post.Url != node.URL || post.CoverImageURL != node.CoverImage.URL || post.AuthorName != "Ada" {
+		t.Fatalf("unexpected post: %+v", post)
+	}
+	if want := time.Date(2024, 2, 10, 6, 30, 0, 0, time.UTC); !post.PublishedAt.Equal(want) {
+		t.Fatalf("published at = %s, want %s", post.PublishedAt, want)
+	}
+	if !post.SyncedAt.Equal(syncedAt) {
+		t.Fatalf("synced at = %s, want %s", post.SyncedAt, syncedAt)
+	}
+	if got := post.Node().PublishedAt; got != "2024-02-10T06:30:00Z" {
+		t.Fatalf("node published at = %q", got)
+	}
+
+	node.PublishedAt = ""
+	if post := postFromNode(node, syncedAt); !post.PublishedAt.IsZero() {
+		t.Fatalf("expected no published date, got %s", post.PublishedAt)
+	}
+}
@@ -0,0 +1,95 @@
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DemoPost is a Hashnode post the fakes publish for demo accounts.
+type DemoPost struct {
+	ID                string
+	Title             string
+	Brief             string
+	ReadTimeInMinutes int
+}
+
+// URL is where the post is published.
+func (p DemoPost) URL() string {
+	return "https://demo.example.com/" + p.ID
+}
+
+// CoverImage is the post's cover image URL.
+func (p DemoPost) CoverImage() string {
+	return "https://cdn.example.com/" + p.ID + ".png"
+}
+
+// DemoAuthor writes every demo post.
+const DemoAuthor = "Demo Author"
+
+// DemoPosts make up the publication every Hashnode account has. Post queries
+// for their ids are answered with them; any other id gets the post PostID.
+var DemoPosts = []DemoPost{
+	{ID: "demo-post-1", Title: "Shipping a Go service to production", Brief: "Everything we learned taking a small Go API live.", ReadTimeInMinutes: 7},
+	{ID: "demo-post-2", Title: "A gentle introduction to MongoDB indexes", Brief: "Why your queries are slow and how indexes fix them.", ReadTimeInMinutes: 5},
+	{ID: "demo-post-3", Title: "Writing for developers", Brief: "How to keep technical posts short and useful.", ReadTimeInMinutes: 4},
+	{ID: "demo-post-4", Title: "OAuth flows explained with diagrams", Brief: "Authorization code, PKCE and friends, one picture at a time.", ReadTimeInMinutes: 9},
+	{ID: "demo-post-5", Title: "Scheduling work without cron", Brief: "A heap, a timer and a goroutine go a long way.", ReadTimeInMinutes: 6},
+	{ID: "demo-post-6", Title: "What I wish I knew about rate limits", Brief: "Backoff, budgets and being a good API citizen.", ReadTimeInMinutes: 5},
+}
+
+func demoPost(id string) (DemoPost, bool) {
+	for _, post := range DemoPosts {
+		if post.ID == id {
+			return post, true
+		}
+	}
+	return DemoPost{}, false
+}
+
+func (p DemoPost) node() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                p.ID,
+		"url":               p.URL(),
+		"title":             p.Title,
+		"subtitle":          "",
+		"brief":             p.Brief,
+		"readTimeInMinutes": p.ReadTimeInMinutes,
+		"coverImage":        map[string]string{"url": p.CoverImage()},
+		"author":            map[string]string{"name": DemoAuthor},
+		"content":           map[string]string{"text": p.Brief},
+	}
+}
+
+// postResponse answers a Hashnode post query.
+func postResponse(body []byte) string {
+	var query struct {
+		Variables struct {
+			ID string `json:"id"`
+		} `json:"variables"`
+	}
+	json.Unmarshal(body, &query)
+	post, ok := demoPost(query.Variables.ID)
+	if !ok {
+		return hashnodePostResponse
+	}
+	return mustJSON(map[string]interface{}{"data": map[string]interface{}{"post": post.node()}})
+}
+
+// publicationResponse lists the demo posts as a Hashnode publication.
+func publicationResponse() string {
+	edges := make([]interface{}, 0, len(DemoPosts))
+	for _, post := range DemoPosts {
+		edges = append(edges, map[string]interface{}{"node": post.node()})
+	}
+	return mustJSON(map[string]interface{}{"data": map[string]interface{}{
+		"publication": map[string]interface{}{"posts": map[string]interface{}{"edges": edges}},
+	}})
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("providertest: encoding response: %v", err))
+	}
+	return string(data)
+}
@@ -0,0 +1,88 @@
+// Package providertest fakes Hashnode, the AI provider, LinkedIn and X for
+// tests and benchmarks. Install Transport in-process with
+// services.SetProviderTransport, or serve it over HTTP with Stub.
+package providertest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// PostID is the Hashnode post every post query is answered with.
+	PostID = "perf-post-1"
+
+	hashnodeMeResponse   = `{"data":{"me":{"publications":{"edges":[{"node":{"url":"https://blog.example.com","id":"perf-publication-1","title":"Perf blog"}}]}}}}`
+	hashnodePostResponse = `{"data":{"post":{"id":"perf-post-1","url":"https://blog.example.com/perf","title":"Benchmarking the share pipeline","subtitle":"Fake connectors","brief":"A post used by the share benchmarks.","readTimeInMinutes":4,"coverImage":{"url":"https://cdn.example.com/cover.png"},"author":{"name":"Perf Author"},"content":{"text":"The share pipeline fetches the post, asks the AI provider for copy and posts it to every selected platform."}}}}`
+	aiResponse           = `{"candidates":[{"content":{"parts":[{"text":"New post: benchmarking the share pipeline. Read it at https://blog.example.com/perf"}]}}]}`
+)
+
+// Transport answers every provider call in-process with canned responses,
+// optionally after a fixed delay standing in for network latency, so callers
+// exercise our own code rather than Hashnode, the AI provider, LinkedIn or X.
+type Transport struct {
+	Latency time.Duration
+	calls   atomic.Int64
+}
+
+// Calls returns how many provider requests were answered.
+func (f *Transport) Calls() int64 {
+	return f.calls.Load()
+}
+
+func (f *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	if f.Latency > 0 {
+		time.Sleep(f.Latency)
+	}
+	f.calls.Add(1)
+
+	switch {
+	case req.URL.Host == "gql.hashnode.com" && strings.Contains(string(body), "query Me"):
+		return response(req, http.StatusOK, hashnodeMeResponse), nil
+	case req.URL.Host == "gql.hashnode.com" && strings.Contains(string(body), "query Publication"):
+		return response(req, http.StatusOK, publicationResponse()), nil
+	case req.URL.Host == "gql.hashnode.com":
+		return response(req, http.StatusOK, postResponse(body)), nil
+	case strings.HasSuffix(req.URL.Host, "googleapis.com"):
+		return response(req, http.StatusOK, aiResponse), nil
+	case req.URL.Host == "api.linkedin.com" && req.URL.Path == "/v2/userinfo":
+		return response(req, http.StatusOK, `{"sub":"perf-member"}`), nil
+	case req.URL.Host == "www.linkedin.com" && req.URL.Path == "/oauth/v2/accessToken":
+		return response(req, http.StatusOK, `{"access_token":"fake-linkedin-token","token_type":"Bearer","expires_in":3600}`), nil
+	case req.URL.Host == "api.linkedin.com":
+		return response(req, http.StatusCreated, `{"id":"urn:li:share:1"}`), nil
+	case req.URL.Host == "api.twitter.com" && req.URL.Path == "/oauth/request_token":
+		return formResponse(req, "oauth_token=fake-request-token&oauth_token_secret=fake-request-secret&oauth_callback_confirmed=true"), nil
+	case req.URL.Host == "api.twitter.com" && req.URL.Path == "/oauth/access_token":
+		return formResponse(req, "oauth_token=fake-x-token&oauth_token_secret=fake-x-secret"), nil
+	case req.URL.Host == "api.twitter.com" && req.URL.Path == "/2/tweets":
+		return response(req, http.StatusCreated, `{"data":{"id":"1","text":"fake"}}`), nil
+	case req.URL.Host == "api.twitter.com":
+		return response(req, http.StatusOK, `{"id_str":"1"}`), nil
+	}
+	return response(req, http.StatusNotFound, `{}`), nil
+}
+
+func response(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+func formResponse(req *http.Request, body string) *http.Response {
+	resp := response(req, http.StatusOK, body)
+	resp.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return resp
+}
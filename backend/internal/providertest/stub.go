@@ -0,0 +1,85 @@
+package providertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RecordedRequest is a provider call received by a Stub.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+}
+
+// Stub serves the Transport's canned responses over HTTP, for a server binary
+// pointed at it with services.UseProviderStub. Like WireMock, it records every
+// call and lists them at GET /__admin/requests; DELETE there clears the log.
+type Stub struct {
+	Transport Transport
+
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// Requests returns the calls received so far, oldest first.
+func (s *Stub) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/__admin/requests" {
+		s.serveAdmin(w, r)
+		return
+	}
+
+	// The first path segment is the provider host the call was meant for
+	host, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, RecordedRequest{Method: r.Method, Host: host, Path: "/" + path, Body: string(body)})
+	s.mu.Unlock()
+
+	target := r.Clone(r.Context())
+	target.URL.Scheme = "https"
+	target.URL.Host = host
+	target.URL.Path = "/" + path
+	target.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := s.Transport.RoundTrip(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (s *Stub) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"requests": s.Requests()})
+	case http.MethodDelete:
+		s.mu.Lock()
+		s.requests = nil
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,78 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reporter forwards errors to an external tracker such as Sentry or Rollbar.
+// Implementations must be safe for concurrent use.
+type Reporter interface {
+	Report(ctx context.Context, err error, tags map[string]string)
+}
+
+// LogReporter is the default reporter; it only writes to the process log.
+type LogReporter struct{}
+
+func (LogReporter) Report(ctx context.Context, err error, tags map[string]string) {
+	log.Printf("[ERROR] Reported error: %v %s", err, formatTags(tags))
+}
+
+var (
+	mu       sync.RWMutex
+	reporter Reporter = LogReporter{}
+)
+
+// SetReporter installs the process-wide reporter. Passing nil restores the
+// default LogReporter.
+func SetReporter(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	if r == nil {
+		r = LogReporter{}
+	}
+	reporter = r
+}
+
+// Report sends err to the configured reporter.
+func Report(ctx context.Context, err error, tags map[string]string) {
+	if err == nil {
+		return
+	}
+	mu.RLock()
+	r := reporter
+	mu.RUnlock()
+	r.Report(ctx, err, tags)
+}
+
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+tags[k])
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Recover reports a panic in a background goroutine instead of crashing the
+// process. It must be called directly via defer.
+func Recover(ctx context.Context, tags map[string]string) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	log.Printf("[ERROR] Background worker panicked: %v\n%s", rec, debug.Stack())
+	Report(ctx, fmt.Errorf("panic: %v", rec), tags)
+}
@@ -0,0 +1,40 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// SentryReporter sends reported errors to Sentry, attaching the tags so
+// events can be filtered by task, user and platform.
+type SentryReporter struct{}
+
+func (SentryReporter) Report(ctx context.Context, err error, tags map[string]string) {
+	hub := sentry.CurrentHub().Clone()
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		hub.CaptureException(err)
+	})
+}
+
+// InitSentry configures the Sentry client and installs SentryReporter as the
+// process-wide reporter.
+func InitSentry(dsn, environment string) error {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         dsn,
+		Environment: environment,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize sentry: %v", err)
+	}
+	SetReporter(SentryReporter{})
+	return nil
+}
+
+// Flush waits for buffered events to be delivered, used during shutdown.
+func Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func CreateAPIKey(key models.APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := apiKeysCollection.InsertOne(ctx, key)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create api key: %v", err)
+	}
+	return err
+}
+
+// GetAPIKeyByHash returns nil, nil for unknown, revoked or expired keys.
+func GetAPIKeyByHash(tokenHash string, now time.Time) (*models.APIKey, error) {
+	ctx := context.TODO()
+
+	key := &models.APIKey{}
+	err := apiKeysCollection.FindOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting api key: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetUserAPIKeys lists the user's live keys, oldest first.
+func GetUserAPIKeys(userID string, now time.Time) ([]models.APIKey, error) {
+	ctx := context.TODO()
+
+	keys := []models.APIKey{}
+	cursor, err := apiKeysCollection.Find(ctx, bson.M{
+		"user_id": userID,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting api keys: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &keys); err != nil {
+		log.Printf("[ERROR] Error decoding api keys: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// TouchAPIKey records when a key was last used.
+func TouchAPIKey(keyID string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := apiKeysCollection.UpdateOne(ctx, bson.M{"key_id": keyID}, bson.M{"$set": bson.M{"last_used_at": now}})
+	if err != nil {
+		log.Printf("[WARN] Failed to record use of api key %s: %v", keyID, err)
+	}
+	return err
+}
+
+// RevokeAPIKey deletes a key, but only when it belongs to the user.
+func RevokeAPIKey(userID, keyID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := apiKeysCollection.DeleteOne(ctx, bson.M{"user_id": userID, "key_id": keyID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke api key: %v", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("api key %s: %w", keyID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteUserAPIKeys(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := apiKeysCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete api keys of user %s: %v", userID, err)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+// RecordAuditEvent adds an event to the audit log of its user.
+func RecordAuditEvent(event models.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(event.UserID)
+	if err != nil {
+		return err
+	}
+	event.Region = store.name
+	if _, err := store.auditEvents.InsertOne(ctx, event); err != nil {
+		log.Printf("[ERROR] Failed to record the %s audit event of user %s: %v", event.Action, event.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetAuditEvents returns up to limit of the user's audit events, newest
+// first.
+func GetAuditEvents(userID string, limit int64) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	events := []models.AuditEvent{}
+	cursor, err := store.auditEvents.Find(ctx, store.filter(bson.M{"user_id": userID}),
+		options.Find().SetSort(bson.D{{Key: "at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		log.Printf("[ERROR] Error getting the audit events of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &events); err != nil {
+		log.Printf("[ERROR] Error decoding the audit events of user %s: %v", userID, err)
+		return nil, err
+	}
+	return events, nil
+}
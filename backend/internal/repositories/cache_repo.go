@@ -2,15 +2,24 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/config"
 	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
 )
 
+// SetCache stores a short-lived value such as a session, OAuth state or OTP.
+// The cache lives only in MongoDB, with no in-process copy, so instance
+// memory doesn't grow with the number of keys; the expiresAt TTL index
+// sweeps expired entries.
 func SetCache(key string, value interface{}, expiration time.Duration) error {
 	ctx := context.TODO()
 
@@ -20,7 +29,7 @@ func SetCache(key string, value interface{}, expiration time.Duration) error {
 	}
 
 	if expiration > 0 {
-		item.ExpiresAt = time.Now().Add(expiration)
+		item.ExpiresAt = utils.Now().Add(expiration)
 	}
 
 	_, err := cacheCollection.UpdateOne(
@@ -39,6 +48,17 @@ func SetCache(key string, value interface{}, expiration time.Duration) error {
 }
 
 func GetCache(key string) (interface{}, bool) {
+	item, err := lookupCache(key)
+	if err != nil || item == nil {
+		return nil, false
+	}
+	return *item, true
+}
+
+// lookupCache returns the unexpired entry under key, or nil when there is
+// none. Unlike GetCache it reports lookup failures, for callers that must not
+// mistake an unreachable cache for a missing entry.
+func lookupCache(key string) (*models.CacheItem, error) {
 	ctx := context.TODO()
 
 	var result models.CacheItem
@@ -46,19 +66,19 @@ func GetCache(key string) (interface{}, bool) {
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, false
+			return nil, nil
 		}
 		log.Printf("[ERROR] Error getting cache for key %s: %v", key, err)
-		return nil, false
+		return nil, err
 	}
 
 	// Double-check expiration in case TTL cleanup hasn't happened yet
-	if !result.ExpiresAt.IsZero() && time.Now().After(result.ExpiresAt) {
+	if !result.ExpiresAt.IsZero() && utils.Now().After(result.ExpiresAt) {
 		DeleteCache(key)
-		return nil, false
+		return nil, nil
 	}
 
-	return result, true
+	return &result, nil
 }
 
 func DeleteCache(key string) error {
@@ -70,3 +90,471 @@ func DeleteCache(key string) error {
 	}
 	return err
 }
+
+// StoreSession records a session for the user under key: the opaque token
+// itself, or SessionJWTRecordKey for a session JWT, which only needs the
+// record to be listed.
+func StoreSession(key string, userID primitive.ObjectID, info models.SessionInfo, expiration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item := models.CacheItem{
+		Key:       key,
+		Value:     userID,
+		ExpiresAt: utils.Now().Add(expiration),
+		Session:   &info,
+	}
+	_, err := cacheCollection.UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": item}, options.Update().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing session for user %s: %v", userID.Hex(), err)
+	}
+	return err
+}
+
+// SessionJWTRecordKey is the cache key of the record listing a session JWT.
+func SessionJWTRecordKey(jti string) string {
+	return sessionJWTRecordPrefix + jti
+}
+
+const sessionJWTRecordPrefix = "session_jwt_"
+
+// ListUserSessions returns the user's unexpired sessions, oldest first.
+// Sessions started before session metadata was recorded have no Session.
+func ListUserSessions(userID primitive.ObjectID) ([]models.CacheItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"value": userID, "expiresAt": bson.M{"$gt": utils.Now()}}
+	cursor, err := cacheCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"session.created_at": 1}))
+	if err != nil {
+		log.Printf("[ERROR] Error listing sessions for user %s: %v", userID.Hex(), err)
+		return nil, err
+	}
+	var sessions []models.CacheItem
+	if err := cursor.All(ctx, &sessions); err != nil {
+		log.Printf("[ERROR] Error decoding sessions for user %s: %v", userID.Hex(), err)
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends a session returned by ListUserSessions.
+func RevokeSession(session models.CacheItem) error {
+	if jti, ok := strings.CutPrefix(session.Key, sessionJWTRecordPrefix); ok {
+		return RevokeSessionJWT(jti, session.ExpiresAt)
+	}
+	return DeleteCache(session.Key)
+}
+
+// DeleteUserSessions signs the user out everywhere by dropping every session
+// token that points at them.
+func DeleteUserSessions(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := cacheCollection.DeleteMany(ctx, bson.M{"value": userID})
+	if err != nil {
+		log.Printf("[ERROR] Error deleting sessions for user %s: %v", userID.Hex(), err)
+		return err
+	}
+	log.Printf("[INFO] Deleted %d sessions for user %s", result.DeletedCount, userID.Hex())
+	return revokeUserSessionJWTs(userID)
+}
+
+// DeleteOtherUserSessions signs the user out everywhere except the session
+// identified by keepToken.
+func DeleteOtherUserSessions(userID primitive.ObjectID, keepToken string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := cacheCollection.DeleteMany(ctx, bson.M{"value": userID, "key": bson.M{"$ne": keepToken}})
+	if err != nil {
+		log.Printf("[ERROR] Error deleting other sessions for user %s: %v", userID.Hex(), err)
+		return err
+	}
+	log.Printf("[INFO] Deleted %d other sessions for user %s", result.DeletedCount, userID.Hex())
+	// Session JWTs can't be told apart by token here; the caller reissues
+	// its own if it had one
+	return revokeUserSessionJWTs(userID)
+}
+
+func sessionsRevokedKey(userID string) string {
+	return "sessions_revoked_" + userID
+}
+
+func revokedSessionJWTKey(jti string) string {
+	return "revoked_session_" + jti
+}
+
+// revokeUserSessionJWTs rejects every session JWT issued to the user before
+// now. The time is kept to the millisecond, so a token the caller is issued
+// straight afterwards, as when the other sessions are signed out, survives.
+func revokeUserSessionJWTs(userID primitive.ObjectID) error {
+	// Only tokens still within their lifetime can be affected
+	return SetCache(sessionsRevokedKey(userID.Hex()), utils.Now(), config.MaxSessionLifetime)
+}
+
+// RevokeSessionJWT ends a single session JWT, remembering it until it would
+// have expired anyway.
+func RevokeSessionJWT(jti string, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(utils.Now())
+	if ttl <= 0 {
+		return nil
+	}
+	if err := SetCache(revokedSessionJWTKey(jti), true, ttl); err != nil {
+		return err
+	}
+	return DeleteCache(SessionJWTRecordKey(jti))
+}
+
+// IsSessionJWTRevoked reports whether the session JWT with the given id,
+// issued to userID at issuedAt, was logged out or signed out everywhere. An
+// error means revocations couldn't be checked, and the token must not be
+// trusted.
+func IsSessionJWTRevoked(userID, jti string, issuedAt time.Time) (bool, error) {
+	item, err := lookupCache(revokedSessionJWTKey(jti))
+	if err != nil {
+		return false, err
+	}
+	if item != nil {
+		return true, nil
+	}
+	item, err = lookupCache(sessionsRevokedKey(userID))
+	if err != nil || item == nil {
+		return false, err
+	}
+	switch revokedAt := item.Value.(type) {
+	case primitive.DateTime:
+		return issuedAt.Before(revokedAt.Time()), nil
+	case int64:
+		// Revocations from before they were kept to the millisecond hold
+		// until the end of their second
+		return issuedAt.Before(time.Unix(revokedAt+1, 0)), nil
+	}
+	return false, fmt.Errorf("unexpected session revocation for user %s: %T", userID, item.Value)
+}
+
+const (
+	// LoginScopeUser and LoginScopeIP throttle logins per username and per
+	// client IP.
+	LoginScopeUser = "user"
+	LoginScopeIP   = "ip"
+
+	// Failures and lockouts are forgotten a day after the last failure
+	loginAttemptsTTL = 24 * time.Hour
+	minLoginLockout  = time.Minute
+	maxLoginLockout  = time.Hour
+)
+
+func loginAttemptsKey(scope, subject string) string {
+	return "login_attempts_" + scope + "_" + subject
+}
+
+// LoginLockedUntil returns when the lockout of the username or IP ends, or the
+// zero time when it isn't locked out.
+func LoginLockedUntil(scope, subject string) (time.Time, error) {
+	ctx := context.TODO()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOne(ctx, bson.M{"key": loginAttemptsKey(scope, subject)}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return time.Time{}, nil
+		}
+		log.Printf("[ERROR] Error getting login attempts of %s %s: %v", scope, subject, err)
+		return time.Time{}, err
+	}
+	if item.Attempts == nil || !utils.Now().Before(item.Attempts.LockedUntil) {
+		return time.Time{}, nil
+	}
+	return item.Attempts.LockedUntil, nil
+}
+
+// RecordLoginFailure counts a failed login of the username or IP. Once
+// threshold failures have accumulated it is locked out, for a minute the first
+// time and twice as long as the previous lockout after that, up to an hour.
+// It returns when the lockout ends, or the zero time when none started.
+//
+// Counting and locking out are one update, so parallel failures can't push
+// the count past the threshold without a lockout or start two lockouts.
+func RecordLoginFailure(scope, subject string, threshold int) (time.Time, error) {
+	ctx := context.TODO()
+	key := loginAttemptsKey(scope, subject)
+	now := utils.Now()
+
+	failures := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$attempts.failures", 0}}, 1}}
+	lockouts := bson.M{"$ifNull": bson.A{"$attempts.lockouts", 0}}
+	lockout := bson.M{"$min": bson.A{
+		bson.M{"$multiply": bson.A{minLoginLockout.Milliseconds(), bson.M{"$pow": bson.A{2, lockouts}}}},
+		maxLoginLockout.Milliseconds(),
+	}}
+	reached := bson.M{"$gte": bson.A{failures, threshold}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"expiresAt":             now.Add(loginAttemptsTTL),
+		"attempts.failures":     bson.M{"$cond": bson.A{reached, 0, failures}},
+		"attempts.lockouts":     bson.M{"$cond": bson.A{reached, bson.M{"$add": bson.A{lockouts, 1}}, lockouts}},
+		"attempts.locked_until": bson.M{"$cond": bson.A{reached, bson.M{"$add": bson.A{now, lockout}}, "$attempts.locked_until"}},
+	}}}}
+
+	var item models.CacheItem
+	err := cacheCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"key": key},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&item)
+	if err != nil {
+		log.Printf("[ERROR] Error recording login failure of %s %s: %v", scope, subject, err)
+		return time.Time{}, err
+	}
+	// Failures only drop back to zero when this failure started a lockout
+	if item.Attempts == nil || item.Attempts.Failures > 0 {
+		return time.Time{}, nil
+	}
+	return item.Attempts.LockedUntil, nil
+}
+
+// ClearLoginFailures forgets the failed logins of the username or IP.
+func ClearLoginFailures(scope, subject string) error {
+	return DeleteCache(loginAttemptsKey(scope, subject))
+}
+
+func emailChangeKey(userID string) string {
+	return "email_change_" + userID
+}
+
+// StoreEmailChange records the user's pending email change, replacing any
+// earlier one.
+func StoreEmailChange(userID string, change models.PendingEmailChange, expiration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := emailChangeKey(userID)
+	item := models.CacheItem{
+		Key:         key,
+		ExpiresAt:   utils.Now().Add(expiration),
+		EmailChange: &change,
+	}
+	_, err := cacheCollection.ReplaceOne(ctx, bson.M{"key": key}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing email change for user %s: %v", userID, err)
+	}
+	return err
+}
+
+// GetEmailChange returns the user's pending email change, or nil when there is
+// none or it expired.
+func GetEmailChange(userID string) (*models.PendingEmailChange, error) {
+	ctx := context.TODO()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOne(ctx, bson.M{"key": emailChangeKey(userID)}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting email change for user %s: %v", userID, err)
+		return nil, err
+	}
+	if item.EmailChange == nil || (!item.ExpiresAt.IsZero() && utils.Now().After(item.ExpiresAt)) {
+		return nil, nil
+	}
+	return item.EmailChange, nil
+}
+
+// RecordEmailChangeFailure counts a wrong OTP for the user's pending email
+// change and returns the failures so far.
+func RecordEmailChangeFailure(userID string) (int, error) {
+	ctx := context.TODO()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"key": emailChangeKey(userID), "email_change": bson.M{"$exists": true}},
+		bson.M{"$inc": bson.M{"email_change.failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		log.Printf("[ERROR] Error recording email change failure for user %s: %v", userID, err)
+		return 0, err
+	}
+	return item.EmailChange.Failures, nil
+}
+
+func DeleteEmailChange(userID string) error {
+	return DeleteCache(emailChangeKey(userID))
+}
+
+func magicLinkKey(tokenHash string) string {
+	return "magic_link_" + tokenHash
+}
+
+// StoreMagicLink records an emailed login link under the hash of its token.
+func StoreMagicLink(tokenHash string, link models.MagicLink, expiration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := magicLinkKey(tokenHash)
+	item := models.CacheItem{
+		Key:       key,
+		ExpiresAt: utils.Now().Add(expiration),
+		MagicLink: &link,
+	}
+	_, err := cacheCollection.ReplaceOne(ctx, bson.M{"key": key}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing magic link for user %s: %v", link.UserID, err)
+	}
+	return err
+}
+
+// TakeMagicLink removes and returns the login link with the token hash, so
+// it can only be used once. It returns nil when there is none or it expired.
+func TakeMagicLink(tokenHash string) (*models.MagicLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOneAndDelete(ctx, bson.M{"key": magicLinkKey(tokenHash)}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error taking magic link: %v", err)
+		return nil, err
+	}
+	if item.MagicLink == nil || (!item.ExpiresAt.IsZero() && utils.Now().After(item.ExpiresAt)) {
+		return nil, nil
+	}
+	return item.MagicLink, nil
+}
+
+const maintenanceKey = "maintenance"
+
+// StoreMaintenance turns maintenance mode on for every instance. The entry
+// doesn't expire.
+func StoreMaintenance(state models.MaintenanceState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item := models.CacheItem{Key: maintenanceKey, Maintenance: &state}
+	_, err := cacheCollection.ReplaceOne(ctx, bson.M{"key": maintenanceKey}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing the maintenance state: %v", err)
+	}
+	return err
+}
+
+// GetMaintenance returns the maintenance state, or nil when maintenance mode
+// is off.
+func GetMaintenance() (*models.MaintenanceState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOne(ctx, bson.M{"key": maintenanceKey}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting the maintenance state: %v", err)
+		return nil, err
+	}
+	return item.Maintenance, nil
+}
+
+// ClearMaintenance turns maintenance mode off.
+func ClearMaintenance() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cacheCollection.DeleteOne(ctx, bson.M{"key": maintenanceKey})
+	if err != nil {
+		log.Printf("[ERROR] Error clearing the maintenance state: %v", err)
+	}
+	return err
+}
+
+func mastodonAppKey(instance string) string {
+	return "mastodon_app_" + instance
+}
+
+// StoreMastodonApp records the app registered on a Mastodon server. The
+// entry doesn't expire.
+func StoreMastodonApp(instance string, app models.MastodonApp) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := mastodonAppKey(instance)
+	item := models.CacheItem{Key: key, MastodonApp: &app}
+	_, err := cacheCollection.ReplaceOne(ctx, bson.M{"key": key}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing the Mastodon app of %s: %v", instance, err)
+	}
+	return err
+}
+
+// GetMastodonApp returns the app registered on a Mastodon server, or nil
+// when there is none yet.
+func GetMastodonApp(instance string) (*models.MastodonApp, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOne(ctx, bson.M{"key": mastodonAppKey(instance)}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting the Mastodon app of %s: %v", instance, err)
+		return nil, err
+	}
+	return item.MastodonApp, nil
+}
+
+func mastodonAuthKey(state string) string {
+	return "mastodon_auth_" + state
+}
+
+// StoreMastodonAuth records a Mastodon authorization in progress under its
+// OAuth state.
+func StoreMastodonAuth(state string, auth models.MastodonAuth, expiration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := mastodonAuthKey(state)
+	item := models.CacheItem{
+		Key:          key,
+		ExpiresAt:    utils.Now().Add(expiration),
+		MastodonAuth: &auth,
+	}
+	_, err := cacheCollection.ReplaceOne(ctx, bson.M{"key": key}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[ERROR] Error storing the Mastodon authorization of user %s: %v", auth.UserID, err)
+	}
+	return err
+}
+
+// TakeMastodonAuth removes and returns the Mastodon authorization with the
+// OAuth state, so a callback can only be used once. It returns nil when there
+// is none or it expired.
+func TakeMastodonAuth(state string) (*models.MastodonAuth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var item models.CacheItem
+	err := cacheCollection.FindOneAndDelete(ctx, bson.M{"key": mastodonAuthKey(state)}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error taking the Mastodon authorization: %v", err)
+		return nil, err
+	}
+	if item.MastodonAuth == nil || (!item.ExpiresAt.IsZero() && utils.Now().After(item.ExpiresAt)) {
+		return nil, nil
+	}
+	return item.MastodonAuth, nil
+}
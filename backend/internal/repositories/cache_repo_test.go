@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/utils"
+)
+
+// useTestCache points the cache repository at a throwaway database on the
+// MongoDB named by MONGO_TEST_URI, skipping the test when it isn't set.
+func useTestCache(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	testClient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	db := testClient.Database(fmt.Sprintf("social-scribe-test-%d", time.Now().UnixNano()))
+
+	previous := cacheCollection
+	cacheCollection = db.Collection("cache")
+	t.Cleanup(func() {
+		cacheCollection = previous
+		db.Drop(context.Background())
+		testClient.Disconnect(context.Background())
+	})
+}
+
+func useFakeClock(t *testing.T) *utils.FakeClock {
+	t.Helper()
+	clock := utils.NewFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	previous := utils.SetClock(clock)
+	t.Cleanup(func() { utils.SetClock(previous) })
+	return clock
+}
+
+func countCacheKey(t *testing.T, key string) int64 {
+	t.Helper()
+	count, err := cacheCollection.CountDocuments(context.Background(), bson.M{"key": key})
+	if err != nil {
+		t.Fatalf("counting cache entries: %v", err)
+	}
+	return count
+}
+
+func TestDeleteCacheRemovesExpiredSession(t *testing.T) {
+	useTestCache(t)
+	clock := useFakeClock(t)
+
+	token := "2d9c5bb4-5d3a-4a53-9b4e-1f9e8f0c2a77"
+	if err := SetCache(token, primitive.NewObjectID(), 24*time.Hour); err != nil {
+		t.Fatalf("SetCache: %v", err)
+	}
+	// Past its expiry but still stored, as before the TTL monitor runs
+	clock.Advance(25 * time.Hour)
+
+	if err := DeleteCache(token); err != nil {
+		t.Fatalf("DeleteCache on expired session: %v", err)
+	}
+	if count := countCacheKey(t, token); count != 0 {
+		t.Fatalf("expired session still stored, %d entries", count)
+	}
+}
+
+func TestDeleteCacheAfterExpiredLookup(t *testing.T) {
+	useTestCache(t)
+	clock := useFakeClock(t)
+
+	token := "7a0f6c1e-2b44-4c59-8d7e-3c1b2a9f0e55"
+	if err := SetCache(token, primitive.NewObjectID(), time.Hour); err != nil {
+		t.Fatalf("SetCache: %v", err)
+	}
+	clock.Advance(2 * time.Hour)
+
+	// GetCache already drops the expired entry; logging out afterwards must
+	// still succeed
+	if _, exists := GetCache(token); exists {
+		t.Fatal("GetCache returned an expired session")
+	}
+	if err := DeleteCache(token); err != nil {
+		t.Fatalf("DeleteCache on already removed session: %v", err)
+	}
+}
+
+func TestDeleteCacheLeavesOtherSessions(t *testing.T) {
+	useTestCache(t)
+	useFakeClock(t)
+
+	kept := "0b6f1d2e-9c3a-4e8b-a1f0-5d4c3b2a1e0f"
+	removed := "c3e2d1f0-8b7a-4c6d-9e5f-4a3b2c1d0e9f"
+	for _, token := range []string{kept, removed} {
+		if err := SetCache(token, primitive.NewObjectID(), time.Hour); err != nil {
+			t.Fatalf("SetCache: %v", err)
+		}
+	}
+
+	if err := DeleteCache(removed); err != nil {
+		t.Fatalf("DeleteCache: %v", err)
+	}
+	if count := countCacheKey(t, removed); count != 0 {
+		t.Fatalf("session still stored after DeleteCache")
+	}
+	if _, exists := GetCache(kept); !exists {
+		t.Fatal("DeleteCache removed another session")
+	}
+}
+
+func TestLoginLockoutBackoff(t *testing.T) {
+	useTestCache(t)
+	clock := useFakeClock(t)
+
+	fail := func(times int) time.Time {
+		t.Helper()
+		var lockedUntil time.Time
+		for i := 0; i < times; i++ {
+			until, err := RecordLoginFailure(LoginScopeUser, "bruteforced", 3)
+			if err != nil {
+				t.Fatalf("RecordLoginFailure: %v", err)
+			}
+			if !until.IsZero() && i != times-1 {
+				t.Fatalf("locked out after %d failures, want %d", i+1, times)
+			}
+			lockedUntil = until
+		}
+		return lockedUntil
+	}
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		until := fail(3)
+		if got := until.Sub(clock.Now()); got != want {
+			t.Fatalf("lockout of %s, want %s", got, want)
+		}
+		if locked, err := LoginLockedUntil(LoginScopeUser, "bruteforced"); err != nil || !locked.Equal(until) {
+			t.Fatalf("LoginLockedUntil = %s, %v; want %s", locked, err, until)
+		}
+		clock.Advance(want)
+		if locked, _ := LoginLockedUntil(LoginScopeUser, "bruteforced"); !locked.IsZero() {
+			t.Fatalf("still locked out after the lockout ended")
+		}
+	}
+
+	if err := ClearLoginFailures(LoginScopeUser, "bruteforced"); err != nil {
+		t.Fatalf("ClearLoginFailures: %v", err)
+	}
+	if until := fail(3); until.Sub(clock.Now()) != time.Minute {
+		t.Fatalf("backoff not reset after clearing failures")
+	}
+}
+
+func TestLoginFailuresInParallel(t *testing.T) {
+	useTestCache(t)
+	useFakeClock(t)
+
+	// 9 failures at once with a threshold of 3 start exactly 3 lockouts
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	lockouts := 0
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			until, err := RecordLoginFailure(LoginScopeIP, "203.0.113.7", 3)
+			if err != nil {
+				t.Errorf("RecordLoginFailure: %v", err)
+			}
+			if !until.IsZero() {
+				mu.Lock()
+				lockouts++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if lockouts != 3 {
+		t.Errorf("%d lockouts started, want 3", lockouts)
+	}
+}
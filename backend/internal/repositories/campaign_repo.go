@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// CreateCampaign stores a new campaign of the user and returns its id. UTM
+// tags are unique per user.
+func CreateCampaign(campaign models.Campaign) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(campaign.UserID)
+	if err != nil {
+		return "", err
+	}
+	campaign.Region = store.name
+	if campaign.BlogIDs == nil {
+		campaign.BlogIDs = []string{}
+	}
+	result, err := store.campaigns.InsertOne(ctx, campaign)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("campaign %q: %w", campaign.UTMCampaign, apperrors.ErrConflict)
+		}
+		log.Printf("[ERROR] Error creating campaign: %v", err)
+		return "", err
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetUserCampaigns lists the user's campaigns, newest first.
+func GetUserCampaigns(userID string) ([]models.Campaign, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	campaigns := []models.Campaign{}
+	cursor, err := store.campaigns.Find(ctx,
+		store.filter(bson.M{"user_id": userID}),
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error getting campaigns of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &campaigns); err != nil {
+		log.Printf("[ERROR] Error decoding campaigns: %v", err)
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// GetCampaign returns nil, nil when the user has no such campaign.
+func GetCampaign(userID, campaignID string) (*models.Campaign, error) {
+	ctx := context.TODO()
+
+	objectId, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid campaign id %q: %w", campaignID, apperrors.ErrInvalidInput)
+	}
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	campaign := &models.Campaign{}
+	err = store.campaigns.FindOne(ctx, store.filter(bson.M{"_id": objectId, "user_id": userID})).Decode(campaign)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting campaign %s: %v", campaignID, err)
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// GetCampaignForBlog returns the campaign the user attached the blog to, or
+// nil, nil when it is in none.
+func GetCampaignForBlog(userID, blogID string) (*models.Campaign, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	campaign := &models.Campaign{}
+	err = store.campaigns.FindOne(ctx, store.filter(bson.M{"user_id": userID, "blog_ids": blogID})).Decode(campaign)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting the campaign of blog %s: %v", blogID, err)
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// AttachCampaignBlog adds the blog to the campaign. A blog is in at most one
+// campaign, so it leaves any other campaign of the user.
+func AttachCampaignBlog(userID, campaignID, blogID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectId, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return fmt.Errorf("invalid campaign id %q: %w", campaignID, apperrors.ErrInvalidInput)
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.campaigns.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objectId, "user_id": userID}),
+		bson.M{"$addToSet": bson.M{"blog_ids": blogID}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error attaching blog %s to campaign %s: %v", blogID, campaignID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("campaign %s: %w", campaignID, apperrors.ErrNotFound)
+	}
+	_, err = store.campaigns.UpdateMany(ctx,
+		store.filter(bson.M{"_id": bson.M{"$ne": objectId}, "user_id": userID, "blog_ids": blogID}),
+		bson.M{"$pull": bson.M{"blog_ids": blogID}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error detaching blog %s from other campaigns: %v", blogID, err)
+		return err
+	}
+	return nil
+}
+
+func DetachCampaignBlog(userID, campaignID, blogID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectId, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return fmt.Errorf("invalid campaign id %q: %w", campaignID, apperrors.ErrInvalidInput)
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.campaigns.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objectId, "user_id": userID}),
+		bson.M{"$pull": bson.M{"blog_ids": blogID}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error detaching blog %s from campaign %s: %v", blogID, campaignID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("campaign %s: %w", campaignID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteUserCampaigns(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.campaigns.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete campaigns of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
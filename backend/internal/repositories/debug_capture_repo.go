@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	debugCaptureCachePrefix  = "debug_capture:"
+	maxDebugCapturesReturned = 100
+)
+
+// EnableDebugCapture marks a subject ("user:<id>" or "token:<token>") for
+// request capture until the TTL runs out.
+func EnableDebugCapture(subject string, ttl time.Duration) error {
+	return SetCache(debugCaptureCachePrefix+subject, true, ttl)
+}
+
+func DisableDebugCapture(subject string) error {
+	return DeleteCache(debugCaptureCachePrefix + subject)
+}
+
+func IsDebugCaptureEnabled(subject string) bool {
+	_, exists := GetCache(debugCaptureCachePrefix + subject)
+	return exists
+}
+
+func StoreDebugCapture(capture models.DebugCapture) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := debugCapturesCollection.InsertOne(ctx, capture)
+	if err != nil {
+		log.Printf("[ERROR] Failed to store debug capture: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetDebugCaptures returns the newest captures matching every non-empty
+// filter argument.
+func GetDebugCaptures(userID, debugToken, requestID string) ([]models.DebugCapture, error) {
+	ctx := context.TODO()
+
+	filter := bson.M{}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	if debugToken != "" {
+		filter["debug_token"] = debugToken
+	}
+	if requestID != "" {
+		filter["request_id"] = requestID
+	}
+
+	captures := []models.DebugCapture{}
+	cursor, err := debugCapturesCollection.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetLimit(maxDebugCapturesReturned),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error getting debug captures: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &captures); err != nil {
+		log.Printf("[ERROR] Error decoding debug captures: %v", err)
+		return nil, err
+	}
+	return captures, nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// StoreDeferredShare creates or replaces the share plan for a user's post.
+func StoreDeferredShare(share models.DeferredShare) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(share.UserID)
+	if err != nil {
+		return err
+	}
+	share.Region = store.name
+	_, err = store.deferredShares.ReplaceOne(ctx,
+		store.filter(bson.M{"user_id": share.UserID, "post_id": share.PostID}),
+		share,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to store deferred share: %v", err)
+		return err
+	}
+	return nil
+}
+
+func GetDeferredShares(userID string) ([]models.DeferredShare, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	shares := []models.DeferredShare{}
+	cursor, err := store.deferredShares.Find(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting deferred shares: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &shares); err != nil {
+		log.Printf("[ERROR] Error decoding deferred shares: %v", err)
+		return nil, err
+	}
+	return shares, nil
+}
+
+// TakeDeferredShare atomically removes and returns the share plan for a post so
+// that duplicate webhook deliveries can't trigger the same share twice. Shares
+// awaiting approval are only taken with awaitingApproval set, and others only
+// without.
+func TakeDeferredShare(userID, postID string, awaitingApproval bool) (*models.DeferredShare, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	share := &models.DeferredShare{}
+	filter := bson.M{"user_id": userID, "post_id": postID, "awaiting_approval": bson.M{"$ne": true}}
+	if awaitingApproval {
+		filter["awaiting_approval"] = true
+	}
+	err = store.deferredShares.FindOneAndDelete(ctx, store.filter(filter)).Decode(share)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Failed to take deferred share: %v", err)
+		return nil, err
+	}
+	return share, nil
+}
+
+func DeleteDeferredShare(userID, postID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.deferredShares.DeleteOne(ctx, store.filter(bson.M{"user_id": userID, "post_id": postID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete deferred share: %v", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("deferred share for post %s: %w", postID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteUserDeferredShares(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.deferredShares.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete deferred shares of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+// ClaimGoalReminder picks a user whose posting goal reminder is due to be
+// checked and pushes the check back by lease, so concurrent workers don't
+// remind them twice. It returns nil when no check is due.
+func ClaimGoalReminder(now time.Time, lease time.Duration) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		user := &models.User{}
+		err := store.users.FindOneAndUpdate(ctx,
+			store.filter(bson.M{
+				"posting_goal.reminder": true,
+				"disabled":              bson.M{"$ne": true},
+				"$or": bson.A{
+					bson.M{"goal_reminder_due_at": bson.M{"$exists": false}},
+					bson.M{"goal_reminder_due_at": bson.M{"$lte": now}},
+				},
+			}),
+			bson.M{"$set": bson.M{"goal_reminder_due_at": now.Add(lease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"goal_reminder_due_at": 1}).SetReturnDocument(options.After),
+		).Decode(user)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim a goal reminder in region %s: %v", name, err)
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, nil
+}
+
+// SetGoalReminderDue sets when the user's goal reminder is next checked.
+func SetGoalReminderDue(userID string, dueAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.users.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objID}),
+		bson.M{"$set": bson.M{"goal_reminder_due_at": dueAt}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to schedule the goal reminder of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// CreateLibraryAsset stores a new asset in the team's library and returns its
+// id. Asset names are unique per team.
+func CreateLibraryAsset(asset models.LibraryAsset) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := regionForTeam(asset.TeamID)
+	if err != nil {
+		return "", err
+	}
+	asset.Region = store.name
+	result, err := store.libraryAssets.InsertOne(ctx, asset)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("asset %q: %w", asset.Name, apperrors.ErrConflict)
+		}
+		log.Printf("[ERROR] Error creating library asset: %v", err)
+		return "", err
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetTeamLibraryAssets lists the team's assets by name, optionally only those
+// of one kind.
+func GetTeamLibraryAssets(teamID, kind string) ([]models.LibraryAsset, error) {
+	ctx := context.TODO()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"team_id": teamID}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	assets := []models.LibraryAsset{}
+	cursor, err := store.libraryAssets.Find(ctx, store.filter(filter), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting library assets of team %s: %v", teamID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &assets); err != nil {
+		log.Printf("[ERROR] Error decoding library assets: %v", err)
+		return nil, err
+	}
+	return assets, nil
+}
+
+// GetLibraryAssets returns the team's assets with the given ids. Ids that are
+// not in the library are left out.
+func GetLibraryAssets(teamID string, assetIDs []string) ([]models.LibraryAsset, error) {
+	ctx := context.TODO()
+
+	objectIds := make([]primitive.ObjectID, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		objectId, err := primitive.ObjectIDFromHex(assetID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset id %q: %w", assetID, apperrors.ErrInvalidInput)
+		}
+		objectIds = append(objectIds, objectId)
+	}
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	assets := []models.LibraryAsset{}
+	cursor, err := store.libraryAssets.Find(ctx, store.filter(bson.M{"_id": bson.M{"$in": objectIds}, "team_id": teamID}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting library assets of team %s: %v", teamID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &assets); err != nil {
+		log.Printf("[ERROR] Error decoding library assets: %v", err)
+		return nil, err
+	}
+	return assets, nil
+}
+
+// GetLibraryAsset returns nil, nil when the team has no such asset.
+func GetLibraryAsset(teamID, assetID string) (*models.LibraryAsset, error) {
+	ctx := context.TODO()
+
+	objectId, err := primitive.ObjectIDFromHex(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset id %q: %w", assetID, apperrors.ErrInvalidInput)
+	}
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	asset := &models.LibraryAsset{}
+	err = store.libraryAssets.FindOne(ctx, store.filter(bson.M{"_id": objectId, "team_id": teamID})).Decode(asset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting library asset %s: %v", assetID, err)
+		return nil, err
+	}
+	return asset, nil
+}
+
+func UpdateLibraryAsset(asset *models.LibraryAsset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := regionForTeam(asset.TeamID)
+	if err != nil {
+		return err
+	}
+	asset.Region = store.name
+	result, err := store.libraryAssets.ReplaceOne(ctx, store.filter(bson.M{"_id": asset.Id, "team_id": asset.TeamID}), asset)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("asset %q: %w", asset.Name, apperrors.ErrConflict)
+		}
+		log.Printf("[ERROR] Error updating library asset %s: %v", asset.Id.Hex(), err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("asset %s: %w", asset.Id.Hex(), apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteLibraryAsset(teamID, assetID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectId, err := primitive.ObjectIDFromHex(assetID)
+	if err != nil {
+		return fmt.Errorf("invalid asset id %q: %w", assetID, apperrors.ErrInvalidInput)
+	}
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return err
+	}
+	result, err := store.libraryAssets.DeleteOne(ctx, store.filter(bson.M{"_id": objectId, "team_id": teamID}))
+	if err != nil {
+		log.Printf("[ERROR] Error deleting library asset %s: %v", assetID, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("asset %s: %w", assetID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func CountTeamLibraryAssets(teamID string) (int64, error) {
+	ctx := context.TODO()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := store.libraryAssets.CountDocuments(ctx, store.filter(bson.M{"team_id": teamID}))
+	if err != nil {
+		log.Printf("[ERROR] Error counting library assets of team %s: %v", teamID, err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// CreateManualTask stores a new manual task of the user and returns its id.
+func CreateManualTask(task models.ManualTask) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(task.UserID)
+	if err != nil {
+		return "", err
+	}
+	task.Region = store.name
+	result, err := store.manualTasks.InsertOne(ctx, task)
+	if err != nil {
+		log.Printf("[ERROR] Error creating manual task: %v", err)
+		return "", err
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetManualTasks lists the user's manual tasks, newest first, without their
+// images.
+func GetManualTasks(userID string) ([]models.ManualTask, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []models.ManualTask{}
+	cursor, err := store.manualTasks.Find(ctx,
+		store.filter(bson.M{"user_id": userID}),
+		options.Find().SetSort(bson.M{"created_at": -1}).SetProjection(bson.M{"image": 0}),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error getting manual tasks of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &tasks); err != nil {
+		log.Printf("[ERROR] Error decoding manual tasks: %v", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetManualTask returns nil, nil when the user has no such task.
+func GetManualTask(userID, taskID string) (*models.ManualTask, error) {
+	ctx := context.TODO()
+
+	objectId, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manual task id %q: %w", taskID, apperrors.ErrInvalidInput)
+	}
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	task := &models.ManualTask{}
+	err = store.manualTasks.FindOne(ctx, store.filter(bson.M{"_id": objectId, "user_id": userID})).Decode(task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting manual task %s: %v", taskID, err)
+		return nil, err
+	}
+	return task, nil
+}
+
+// CompleteManualTask marks the task done, after which it expires.
+func CompleteManualTask(userID, taskID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectId, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return fmt.Errorf("invalid manual task id %q: %w", taskID, apperrors.ErrInvalidInput)
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.manualTasks.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objectId, "user_id": userID}),
+		bson.M{"$set": bson.M{"status": models.ManualTaskDone, "done_at": at}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error completing manual task %s: %v", taskID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("manual task %s: %w", taskID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteManualTask(userID, taskID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectId, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return fmt.Errorf("invalid manual task id %q: %w", taskID, apperrors.ErrInvalidInput)
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.manualTasks.DeleteOne(ctx, store.filter(bson.M{"_id": objectId, "user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Error deleting manual task %s: %v", taskID, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("manual task %s: %w", taskID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func DeleteUserManualTasks(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.manualTasks.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete manual tasks of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
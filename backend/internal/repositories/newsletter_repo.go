@@ -0,0 +1,307 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// AddNewsletterSubscribers adds the subscribers to the user's list and
+// returns how many were new. Addresses already on the list keep their
+// token and, if they unsubscribed, stay unsubscribed.
+func AddNewsletterSubscribers(userID string, subscribers []models.NewsletterSubscriber) (int, error) {
+	if len(subscribers) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	writes := make([]mongo.WriteModel, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		subscriber.UserID = userID
+		subscriber.Region = store.name
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(store.filter(bson.M{"user_id": userID, "email": subscriber.Email})).
+			SetUpdate(bson.M{"$setOnInsert": subscriber}).
+			SetUpsert(true))
+	}
+	result, err := store.newsletterSubscribers.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Printf("[ERROR] Error adding newsletter subscribers of user %s: %v", userID, err)
+		return 0, err
+	}
+	return int(result.UpsertedCount), nil
+}
+
+// GetNewsletterSubscribers lists the user's subscribers by address. Unless
+// all is set, those who unsubscribed are left out.
+func GetNewsletterSubscribers(userID string, all bool) ([]models.NewsletterSubscriber, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"user_id": userID}
+	if !all {
+		filter["unsubscribed_at"] = bson.M{"$exists": false}
+	}
+	subscribers := []models.NewsletterSubscriber{}
+	cursor, err := store.newsletterSubscribers.Find(ctx, store.filter(filter), options.Find().SetSort(bson.M{"email": 1}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting newsletter subscribers of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &subscribers); err != nil {
+		log.Printf("[ERROR] Error decoding newsletter subscribers: %v", err)
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+// CountNewsletterSubscribers counts the user's subscribers, including those
+// who unsubscribed.
+func CountNewsletterSubscribers(userID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := store.newsletterSubscribers.CountDocuments(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Error counting newsletter subscribers of user %s: %v", userID, err)
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func DeleteNewsletterSubscriber(userID, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.newsletterSubscribers.DeleteOne(ctx, store.filter(bson.M{"user_id": userID, "email": email}))
+	if err != nil {
+		log.Printf("[ERROR] Error deleting a newsletter subscriber of user %s: %v", userID, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("subscriber %s: %w", email, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+// UnsubscribeNewsletter marks the subscriber with the token unsubscribed
+// from the user's newsletter and returns their address. Unsubscribing twice
+// is not an error.
+func UnsubscribeNewsletter(userID, token string, at time.Time) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return "", err
+	}
+	subscriber := &models.NewsletterSubscriber{}
+	err = store.newsletterSubscribers.FindOne(ctx, store.filter(bson.M{"user_id": userID, "token": token})).Decode(subscriber)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", fmt.Errorf("unsubscribe token: %w", apperrors.ErrNotFound)
+		}
+		log.Printf("[ERROR] Error getting a newsletter subscriber of user %s: %v", userID, err)
+		return "", err
+	}
+	if subscriber.UnsubscribedAt != nil {
+		return subscriber.Email, nil
+	}
+	_, err = store.newsletterSubscribers.UpdateOne(ctx,
+		store.filter(bson.M{"_id": subscriber.Id}),
+		bson.M{"$set": bson.M{"unsubscribed_at": at}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error unsubscribing a newsletter subscriber of user %s: %v", userID, err)
+		return "", err
+	}
+	return subscriber.Email, nil
+}
+
+// DeleteUserNewsletterSubscribers deletes the user's subscribers and the
+// deliveries queued for them.
+func DeleteUserNewsletterSubscribers(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.newsletterSubscribers.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete newsletter subscribers of user %s: %v", userID, err)
+		return err
+	}
+	_, err = store.newsletterDeliveries.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete newsletter deliveries of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// NewsletterSubscribed reports whether the address is subscribed to the
+// user's newsletter.
+func NewsletterSubscribed(userID, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return false, err
+	}
+	count, err := store.newsletterSubscribers.CountDocuments(ctx, store.filter(bson.M{
+		"user_id":         userID,
+		"email":           email,
+		"unsubscribed_at": bson.M{"$exists": false},
+	}))
+	if err != nil {
+		log.Printf("[ERROR] Error checking a newsletter subscriber of user %s: %v", userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// QueueNewsletterDeliveries queues the deliveries of an issue and returns how
+// many were new. Subscribers the issue was already queued for are left as
+// they are, so queueing it again mails no one twice.
+func QueueNewsletterDeliveries(userID string, deliveries []models.NewsletterDelivery) (int, error) {
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	writes := make([]mongo.WriteModel, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		delivery.UserID = userID
+		delivery.Region = store.name
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(store.filter(bson.M{"user_id": userID, "issue_id": delivery.IssueID, "email": delivery.Email})).
+			SetUpdate(bson.M{"$setOnInsert": delivery}).
+			SetUpsert(true))
+	}
+	result, err := store.newsletterDeliveries.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Printf("[ERROR] Error queueing newsletter deliveries of user %s: %v", userID, err)
+		return 0, err
+	}
+	return int(result.UpsertedCount), nil
+}
+
+// ClaimNewsletterDelivery takes the pending delivery, in any region, that has
+// been due the longest. It counts as an attempt and is handed to no one else
+// until lease has passed.
+func ClaimNewsletterDelivery(now time.Time, lease time.Duration) (*models.NewsletterDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		delivery := &models.NewsletterDelivery{}
+		err := store.newsletterDeliveries.FindOneAndUpdate(ctx,
+			store.filter(bson.M{"status": models.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}}),
+			bson.M{
+				"$set": bson.M{"next_attempt_at": now.Add(lease)},
+				"$inc": bson.M{"attempts": 1},
+			},
+			options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+		).Decode(delivery)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim a newsletter delivery in region %s: %v", name, err)
+			return nil, err
+		}
+		return delivery, nil
+	}
+	return nil, nil
+}
+
+// UpdateNewsletterDelivery stores the outcome of an attempt: the status,
+// error, next attempt and sending time.
+func UpdateNewsletterDelivery(delivery *models.NewsletterDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(delivery.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = store.newsletterDeliveries.UpdateOne(ctx,
+		store.filter(bson.M{"_id": delivery.Id}),
+		bson.M{"$set": bson.M{
+			"status":          delivery.Status,
+			"error":           delivery.Error,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"sent_at":         delivery.SentAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error updating a newsletter delivery of user %s: %v", delivery.UserID, err)
+	}
+	return err
+}
+
+// CountNewsletterDeliveries counts the deliveries of an issue by status.
+func CountNewsletterDeliveries(userID, issueID string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := store.newsletterDeliveries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: store.filter(bson.M{"user_id": userID, "issue_id": issueID})}},
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error counting newsletter deliveries of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, group := range groups {
+		counts[group.Status] = group.Count
+	}
+	return counts, nil
+}
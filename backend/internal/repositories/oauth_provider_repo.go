@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func CreateOAuthClient(client models.OAuthClient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := oauthClientsCollection.InsertOne(ctx, client)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create oauth client: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetOAuthClient returns nil, nil when no client has the given ID.
+func GetOAuthClient(clientID string) (*models.OAuthClient, error) {
+	ctx := context.TODO()
+
+	client := &models.OAuthClient{}
+	err := oauthClientsCollection.FindOne(ctx, bson.M{"client_id": clientID}).Decode(client)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting oauth client %s: %v", clientID, err)
+		return nil, err
+	}
+	return client, nil
+}
+
+func GetOAuthClientsByOwner(ownerID string) ([]models.OAuthClient, error) {
+	ctx := context.TODO()
+
+	clients := []models.OAuthClient{}
+	cursor, err := oauthClientsCollection.Find(ctx, bson.M{"owner_id": ownerID})
+	if err != nil {
+		log.Printf("[ERROR] Error getting oauth clients: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &clients); err != nil {
+		log.Printf("[ERROR] Error decoding oauth clients: %v", err)
+		return nil, err
+	}
+	return clients, nil
+}
+
+// DeleteOAuthClient removes a client owned by ownerID together with every
+// code and token issued to it.
+func DeleteOAuthClient(ownerID, clientID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := oauthClientsCollection.DeleteOne(ctx, bson.M{"owner_id": ownerID, "client_id": clientID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete oauth client %s: %v", clientID, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("oauth client %s: %w", clientID, apperrors.ErrNotFound)
+	}
+	_, err = oauthGrantsCollection.DeleteMany(ctx, bson.M{"client_id": clientID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete grants of oauth client %s: %v", clientID, err)
+		return err
+	}
+	return nil
+}
+
+func StoreOAuthGrant(grant models.OAuthGrant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := oauthGrantsCollection.InsertOne(ctx, grant)
+	if err != nil {
+		log.Printf("[ERROR] Failed to store oauth %s: %v", grant.Kind, err)
+		return err
+	}
+	return nil
+}
+
+// TakeOAuthCode atomically consumes an authorization code so it can only be
+// exchanged once. It returns nil, nil for unknown or expired codes.
+func TakeOAuthCode(codeHash string, now time.Time) (*models.OAuthGrant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	grant := &models.OAuthGrant{}
+	err := oauthGrantsCollection.FindOneAndDelete(ctx, bson.M{
+		"kind":       models.OAuthGrantCode,
+		"token_hash": codeHash,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(grant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Failed to take oauth code: %v", err)
+		return nil, err
+	}
+	return grant, nil
+}
+
+// GetOAuthAccessToken returns nil, nil for unknown, revoked or expired tokens.
+func GetOAuthAccessToken(tokenHash string, now time.Time) (*models.OAuthGrant, error) {
+	ctx := context.TODO()
+
+	grant := &models.OAuthGrant{}
+	err := oauthGrantsCollection.FindOne(ctx, bson.M{
+		"kind":       models.OAuthGrantAccessToken,
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(grant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting oauth access token: %v", err)
+		return nil, err
+	}
+	return grant, nil
+}
+
+// RevokeOAuthAccessToken deletes a token, but only when it belongs to the
+// given client so one client can't revoke another's tokens.
+func RevokeOAuthAccessToken(clientID, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := oauthGrantsCollection.DeleteOne(ctx, bson.M{
+		"kind":       models.OAuthGrantAccessToken,
+		"client_id":  clientID,
+		"token_hash": tokenHash,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke oauth access token: %v", err)
+	}
+	return err
+}
+
+// GetUserOAuthGrants returns the live access tokens a user has granted.
+func GetUserOAuthGrants(userID string, now time.Time) ([]models.OAuthGrant, error) {
+	ctx := context.TODO()
+
+	grants := []models.OAuthGrant{}
+	cursor, err := oauthGrantsCollection.Find(ctx, bson.M{
+		"kind":       models.OAuthGrantAccessToken,
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error getting oauth grants: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &grants); err != nil {
+		log.Printf("[ERROR] Error decoding oauth grants: %v", err)
+		return nil, err
+	}
+	return grants, nil
+}
+
+// RevokeUserOAuthGrants removes every code and token a user granted to a
+// client.
+func RevokeUserOAuthGrants(userID, clientID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := oauthGrantsCollection.DeleteMany(ctx, bson.M{"user_id": userID, "client_id": clientID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke oauth grants: %v", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("no access granted to client %s: %w", clientID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeAllUserOAuthGrants removes every code and token the user granted to
+// any client.
+func RevokeAllUserOAuthGrants(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := oauthGrantsCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke oauth grants of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
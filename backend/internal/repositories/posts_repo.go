@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+// ClaimPostSync picks a user whose posts are due to be synced and pushes their
+// next sync back by lease, so concurrent workers don't sync the same user. It
+// returns nil when no sync is due.
+func ClaimPostSync(now time.Time, lease time.Duration) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		user := &models.User{}
+		err := store.users.FindOneAndUpdate(ctx,
+			store.filter(bson.M{
+				"hashnode_verified": true,
+				"hashnode_blog":     bson.M{"$ne": ""},
+				"disabled":          bson.M{"$ne": true},
+				"$or": bson.A{
+					bson.M{"posts_sync_due_at": bson.M{"$exists": false}},
+					bson.M{"posts_sync_due_at": bson.M{"$lte": now}},
+				},
+			}),
+			bson.M{"$set": bson.M{"posts_sync_due_at": now.Add(lease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"posts_sync_due_at": 1}).SetReturnDocument(options.After),
+		).Decode(user)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim a post sync in region %s: %v", name, err)
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, nil
+}
+
+// SchedulePostSync sets when the user's posts are next synced.
+func SchedulePostSync(userID string, dueAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.users.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objID}),
+		bson.M{"$set": bson.M{"posts_sync_due_at": dueAt}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to schedule post sync of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// UpsertPosts stores a page of the user's posts, replacing earlier copies.
+func UpsertPosts(userID string, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	writes := make([]mongo.WriteModel, 0, len(posts))
+	for _, post := range posts {
+		post.UserID = userID
+		post.Region = store.name
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(store.filter(bson.M{"user_id": userID, "id": post.Id})).
+			SetReplacement(post).
+			SetUpsert(true))
+	}
+	_, err = store.posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Printf("[ERROR] Failed to store posts of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// DeletePostsSyncedBefore removes the user's Hashnode posts a completed sync
+// didn't see, which were deleted or unpublished on Hashnode.
+func DeletePostsSyncedBefore(userID string, syncStart time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.posts.DeleteMany(ctx, store.filter(bson.M{
+		"user_id":   userID,
+		"source":    nil,
+		"synced_at": bson.M{"$lt": syncStart},
+	}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete stale posts of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// GetUserPosts lists the synced posts of the user's publication, newest
+// first. It reads from secondaries, so a sync that just finished may not be
+// visible yet.
+func GetUserPosts(userID string) ([]models.Post, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	posts := []models.Post{}
+	cursor, err := store.stalePosts.Find(ctx,
+		store.filter(bson.M{"user_id": userID}),
+		options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}}),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error getting posts: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &posts); err != nil {
+		log.Printf("[ERROR] Error decoding posts: %v", err)
+		return nil, err
+	}
+	return posts, nil
+}
+
+func DeleteUserPosts(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.posts.DeleteMany(ctx, store.filter(bson.M{"user_id": userID}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete posts of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteSourcePosts deletes the user's posts from one source: their Hashnode
+// posts for an empty source, or their feed items for models.PostSourceFeed.
+func DeleteSourcePosts(userID, source string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	// Hashnode posts have no source, which a null matches
+	var sourceFilter interface{}
+	if source != "" {
+		sourceFilter = source
+	}
+	_, err = store.posts.DeleteMany(ctx, store.filter(bson.M{"user_id": userID, "source": sourceFilter}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete %q posts of user %s: %v", source, userID, err)
+		return err
+	}
+	return nil
+}
+
+// ClaimFeedSync picks a user whose feed is due to be polled and pushes their
+// next poll back by lease, so concurrent workers don't poll the same feed.
+// It returns nil when no poll is due.
+func ClaimFeedSync(now time.Time, lease time.Duration) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		user := &models.User{}
+		err := store.users.FindOneAndUpdate(ctx,
+			store.filter(bson.M{
+				"feed.url": bson.M{"$exists": true},
+				"disabled": bson.M{"$ne": true},
+				"$or": bson.A{
+					bson.M{"feed_sync_due_at": bson.M{"$exists": false}},
+					bson.M{"feed_sync_due_at": bson.M{"$lte": now}},
+				},
+			}),
+			bson.M{"$set": bson.M{"feed_sync_due_at": now.Add(lease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"feed_sync_due_at": 1}).SetReturnDocument(options.After),
+		).Decode(user)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim a feed sync in region %s: %v", name, err)
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, nil
+}
+
+// RecordFeedSync records a poll of the user's feed at feedURL, with why it
+// failed or "" when it didn't, and sets when the feed is next polled. A feed
+// the user replaced or removed meanwhile is left alone.
+func RecordFeedSync(userID, feedURL string, polledAt time.Time, lastError string, dueAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	_, err = store.users.UpdateOne(ctx,
+		store.filter(bson.M{"_id": objID, "feed.url": feedURL}),
+		bson.M{"$set": bson.M{
+			"feed.last_polled_at": polledAt,
+			"feed.last_error":     lastError,
+			"feed_sync_due_at":    dueAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record the feed sync of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// SearchUserPosts returns up to limit of the user's synced posts matching the
+// text query, best match first.
+func SearchUserPosts(userID, query string, limit int64) ([]models.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	score := bson.M{"$meta": "textScore"}
+	posts := []models.Post{}
+	cursor, err := store.stalePosts.Find(ctx,
+		store.filter(bson.M{"user_id": userID, "$text": bson.M{"$search": query}}),
+		options.Find().
+			SetProjection(bson.M{"score": score}).
+			SetSort(bson.D{{Key: "score", Value: score}}).
+			SetLimit(limit),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error searching posts of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &posts); err != nil {
+		log.Printf("[ERROR] Error decoding posts: %v", err)
+		return nil, err
+	}
+	return posts, nil
+}
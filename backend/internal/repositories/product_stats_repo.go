@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+// ProductStats are the adoption numbers reported to the product team.
+type ProductStats struct {
+	DailyActiveUsers  int64            `json:"dau"`
+	WeeklyActiveUsers int64            `json:"wau"`
+	SignupsLastWeek   int64            `json:"signups_last_7d"`
+	SharesLastDay     int64            `json:"shares_last_24h"`
+	SignupsByWeek     map[string]int64 `json:"signups_by_week"`
+}
+
+// UserCounts sizes the user base for the admin system stats.
+type UserCounts struct {
+	Total    int64            `json:"total"`
+	Verified int64            `json:"verified"`
+	Disabled int64            `json:"disabled"`
+	Admins   int64            `json:"admins"`
+	ByRegion map[string]int64 `json:"by_region"`
+}
+
+// GetUserCounts counts users over every storage region.
+func GetUserCounts() (*UserCounts, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	counts := &UserCounts{ByRegion: map[string]int64{}}
+	for _, store := range regionStores {
+		users := store.staleUsers
+		for _, count := range []struct {
+			total  *int64
+			filter bson.M
+		}{
+			{&counts.Total, bson.M{}},
+			{&counts.Verified, bson.M{"verified": true}},
+			{&counts.Disabled, bson.M{"disabled": true}},
+			{&counts.Admins, bson.M{"role": models.RoleAdmin}},
+		} {
+			n, err := users.CountDocuments(ctx, store.filter(count.filter))
+			if err != nil {
+				log.Printf("[ERROR] Error counting users in region %s: %v", store.name, err)
+				return nil, err
+			}
+			*count.total += n
+			if len(count.filter) == 0 {
+				counts.ByRegion[store.name] = n
+			}
+		}
+	}
+	return counts, nil
+}
+
+// TouchUserActivity records that a user was active at the given time. Writes
+// are skipped when the stored timestamp is already within the last hour.
+func TouchUserActivity(userID string, at time.Time) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = store.users.UpdateOne(ctx,
+		bson.M{"_id": objectId, "region": store.name, "$or": bson.A{
+			bson.M{"last_active_at": bson.M{"$lt": at.Add(-time.Hour)}},
+			bson.M{"last_active_at": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"last_active_at": at}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error recording activity for user %s: %v", userID, err)
+	}
+	return err
+}
+
+// GetProductStats sums the adoption numbers over every storage region.
+func GetProductStats(now time.Time) (*ProductStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats := &ProductStats{SignupsByWeek: map[string]int64{}}
+	for _, store := range regionStores {
+		if err := addRegionProductStats(ctx, store, stats, now); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func addRegionProductStats(ctx context.Context, store *regionStore, stats *ProductStats, now time.Time) error {
+	// Analytics tolerate replication lag
+	users := store.staleUsers
+
+	count, err := users.CountDocuments(ctx, store.filter(bson.M{"last_active_at": bson.M{"$gte": now.Add(-24 * time.Hour)}}))
+	if err != nil {
+		log.Printf("[ERROR] Error counting daily active users: %v", err)
+		return err
+	}
+	stats.DailyActiveUsers += count
+	count, err = users.CountDocuments(ctx, store.filter(bson.M{"last_active_at": bson.M{"$gte": now.Add(-7 * 24 * time.Hour)}}))
+	if err != nil {
+		log.Printf("[ERROR] Error counting weekly active users: %v", err)
+		return err
+	}
+	stats.WeeklyActiveUsers += count
+	count, err = users.CountDocuments(ctx, store.filter(bson.M{"created_at": bson.M{"$gte": now.Add(-7 * 24 * time.Hour)}}))
+	if err != nil {
+		log.Printf("[ERROR] Error counting signups: %v", err)
+		return err
+	}
+	stats.SignupsLastWeek += count
+
+	// Shared times are RFC3339 strings whose offset depends on where they
+	// were written, so they are compared as dates. Offsets stay within 14
+	// hours of UTC, so any share since then has a local time, and string,
+	// no earlier than 14 hours before it in UTC; that bound can use an
+	// index before the exact comparison
+	since := now.Add(-24 * time.Hour)
+	bound := since.Add(-14 * time.Hour).UTC().Format("2006-01-02T15:04:05")
+	sharedAt := bson.M{"$dateFromString": bson.M{"dateString": "$shared_posts.shared_time", "onError": nil, "onNull": nil}}
+	cursor, err := users.Aggregate(ctx, bson.A{
+		bson.M{"$match": store.filter(bson.M{"shared_posts.shared_time": bson.M{"$gte": bound}})},
+		bson.M{"$unwind": "$shared_posts"},
+		bson.M{"$match": bson.M{"$expr": bson.M{"$gte": bson.A{sharedAt, since}}}},
+		bson.M{"$count": "shares"},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error counting shares: %v", err)
+		return err
+	}
+	var shareCounts []struct {
+		Shares int64 `bson:"shares"`
+	}
+	if err := cursor.All(ctx, &shareCounts); err != nil {
+		log.Printf("[ERROR] Error decoding share counts: %v", err)
+		return err
+	}
+	if len(shareCounts) > 0 {
+		stats.SharesLastDay += shareCounts[0].Shares
+	}
+
+	cursor, err = users.Aggregate(ctx, bson.A{
+		bson.M{"$match": store.filter(bson.M{"created_at": bson.M{"$gte": now.Add(-12 * 7 * 24 * time.Hour)}})},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%G-W%V", "date": "$created_at"}},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error grouping signups by week: %v", err)
+		return err
+	}
+	var weeks []struct {
+		Week  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &weeks); err != nil {
+		log.Printf("[ERROR] Error decoding signup weeks: %v", err)
+		return err
+	}
+	for _, week := range weeks {
+		stats.SignupsByWeek[week.Week] += week.Count
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+// useTestDB points the repositories at a throwaway database on the MongoDB
+// named by MONGO_TEST_URI, skipping the test when it isn't set.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	dbName := fmt.Sprintf("social-scribe-test-%d", time.Now().UnixNano())
+	if err := Connect(uri, dbName); err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { Disconnect(dbName) })
+}
+
+func TestProductStatsCountSharesByDate(t *testing.T) {
+	useTestDB(t)
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	shared := func(at string) models.SharedBlog {
+		return models.SharedBlog{Blog: models.Blog{Id: at}, SharedTime: at}
+	}
+	_, err := CreateUser(models.User{
+		UserName:  "sharer",
+		Region:    models.RegionDefault,
+		CreatedAt: now,
+		SharedBlogs: []models.SharedBlog{
+			shared("2026-03-02T11:00:00Z"),
+			// 02:00 UTC on 2 March, within the day, though earlier as a string
+			shared("2026-03-01T16:00:00-10:00"),
+			// 23:00 UTC on 28 February, outside the day, though later as a
+			// string than its start
+			shared("2026-03-01T13:00:00+14:00"),
+			shared("2026-02-28T09:00:00Z"),
+			shared("not a date"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetProductStats(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.SharesLastDay != 2 {
+		t.Errorf("shares in the last day = %d, want 2", stats.SharesLastDay)
+	}
+}
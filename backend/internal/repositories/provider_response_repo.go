@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+// ArchiveProviderResponse stores a response and trims the archive so only the
+// newest keep entries remain for that user and platform.
+func ArchiveProviderResponse(response models.ProviderResponse, keep int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(response.UserID)
+	if err != nil {
+		return err
+	}
+	response.Region = store.name
+	_, err = store.providerResponses.InsertOne(ctx, response)
+	if err != nil {
+		log.Printf("[ERROR] Failed to archive provider response: %v", err)
+		return err
+	}
+
+	filter := store.filter(bson.M{"user_id": response.UserID, "platform": response.Platform})
+	cursor, err := store.providerResponses.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetSkip(int64(keep)).
+			SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to find stale provider responses: %v", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []bson.M
+	if err := cursor.All(ctx, &stale); err != nil {
+		log.Printf("[ERROR] Failed to decode stale provider responses: %v", err)
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, 0, len(stale))
+	for _, doc := range stale {
+		ids = append(ids, doc["_id"])
+	}
+	_, err = store.providerResponses.DeleteMany(ctx, store.filter(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to trim provider responses: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetProviderResponses returns archived responses newest first. An empty
+// platform returns responses for every platform.
+func GetProviderResponses(userID, platform string) ([]models.ProviderResponse, error) {
+	ctx := context.TODO()
+
+	store, err := mustRegionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := store.filter(bson.M{"user_id": userID})
+	if platform != "" {
+		filter["platform"] = platform
+	}
+
+	// Archived responses are history; a lagging secondary is fine
+	responses := []models.ProviderResponse{}
+	cursor, err := store.staleProviderResponses.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error getting provider responses: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &responses); err != nil {
+		log.Printf("[ERROR] Error decoding provider responses: %v", err)
+		return nil, err
+	}
+	return responses, nil
+}
+
+// DeleteProviderResponsesBefore removes the user's archived responses older
+// than before and returns how many went.
+func DeleteProviderResponsesBefore(userID string, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := store.providerResponses.DeleteMany(ctx, store.filter(bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$lt": before},
+	}))
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete provider responses of user %s: %v", userID, err)
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// staleReadPreference is used by the read paths that tolerate data lagging
+// behind the primary: blog lists, share history, provider response history
+// and product analytics. Everything else, including every write, session
+// check and directory lookup, stays on the primary so a user always sees
+// their own writes where it matters.
+//
+// It is configured with MONGO_STALE_READ_PREFERENCE (primary,
+// primaryPreferred, secondary, secondaryPreferred or nearest) and
+// MONGO_MAX_STALENESS, e.g. "2m"; MongoDB requires at least 90s. Unset, stale
+// reads also go to the primary.
+var staleReadPreference = readpref.Primary()
+
+func initReadPreference() {
+	mode := os.Getenv("MONGO_STALE_READ_PREFERENCE")
+	if mode == "" {
+		return
+	}
+	parsedMode, err := readpref.ModeFromString(mode)
+	if err != nil {
+		log.Fatalf("[ERROR] Invalid MONGO_STALE_READ_PREFERENCE %q: %v", mode, err)
+	}
+
+	var opts []readpref.Option
+	if raw := os.Getenv("MONGO_MAX_STALENESS"); raw != "" {
+		maxStaleness, err := time.ParseDuration(raw)
+		if err != nil || maxStaleness < 90*time.Second {
+			log.Fatalf("[ERROR] Invalid MONGO_MAX_STALENESS %q: must be a duration of at least 90s", raw)
+		}
+		opts = append(opts, readpref.WithMaxStaleness(maxStaleness))
+	}
+	staleReadPreference, err = readpref.New(parsedMode, opts...)
+	if err != nil {
+		log.Fatalf("[ERROR] Invalid stale read preference: %v", err)
+	}
+	log.Printf("[INFO] Staleness-tolerant reads use read preference %s", staleReadPreference)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"os"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,10 +13,15 @@ import (
 
 var RedisClient *redis.Client
 
-// InitRedis initializes a persistent connection to Redis.
+// InitRedis initializes a persistent connection to Redis at REDIS_ADDR,
+// localhost:6379 by default.
 func InitRedis() {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", 
+		Addr:     addr,
 		Password: "",              
 		DB:       0,           
 	})
@@ -0,0 +1,802 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// regionStore holds the collections whose documents must stay in a team's
+// storage region. Every document written through it carries the region and
+// every query filters on it, so a misrouted read finds nothing rather than
+// another region's data.
+type regionStore struct {
+	name                  string
+	users                 *mongo.Collection
+	scheduledItems        *mongo.Collection
+	deferredShares        *mongo.Collection
+	providerResponses     *mongo.Collection
+	posts                 *mongo.Collection
+	campaigns             *mongo.Collection
+	libraryAssets         *mongo.Collection
+	newsletterSubscribers *mongo.Collection
+	newsletterDeliveries  *mongo.Collection
+	manualTasks           *mongo.Collection
+	auditEvents           *mongo.Collection
+
+	// The stale* handles read with the staleness-tolerant read preference
+	// and must only back read-only paths; see staleReadPreference.
+	staleUsers             *mongo.Collection
+	staleProviderResponses *mongo.Collection
+	stalePosts             *mongo.Collection
+}
+
+func newRegionStore(name string, db *mongo.Database) *regionStore {
+	staleReads := options.Collection().SetReadPreference(staleReadPreference)
+	return &regionStore{
+		name:                   name,
+		users:                  db.Collection("users"),
+		scheduledItems:         db.Collection("scheduled_items"),
+		deferredShares:         db.Collection("deferred_shares"),
+		providerResponses:      db.Collection("provider_responses"),
+		posts:                  db.Collection("posts"),
+		campaigns:              db.Collection("campaigns"),
+		libraryAssets:          db.Collection("library_assets"),
+		newsletterSubscribers:  db.Collection("newsletter_subscribers"),
+		newsletterDeliveries:   db.Collection("newsletter_deliveries"),
+		manualTasks:            db.Collection("manual_tasks"),
+		auditEvents:            db.Collection("audit_events"),
+		staleUsers:             db.Collection("users", staleReads),
+		staleProviderResponses: db.Collection("provider_responses", staleReads),
+		stalePosts:             db.Collection("posts", staleReads),
+	}
+}
+
+// filter scopes a query to the region.
+func (s *regionStore) filter(filter bson.M) bson.M {
+	scoped := bson.M{"region": s.name}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	return scoped
+}
+
+var (
+	regionStores = map[string]*regionStore{}
+	// userRegions caches user id -> cachedRegion from the directory, and
+	// teamRegions team id -> cachedRegion.
+	userRegions sync.Map
+	teamRegions sync.Map
+)
+
+// regionCacheTTL is how long a region looked up is trusted. A user's region
+// only changes through MoveUserToRegion, which waits at least this long after
+// blocking their writes, so every server has dropped the old region by the
+// time the move switches.
+const regionCacheTTL = 5 * time.Second
+
+type cachedRegion struct {
+	name    string
+	expires time.Time
+}
+
+// loadRegion returns the region cached for id, unless it has expired.
+func loadRegion(cache *sync.Map, id string) (string, bool) {
+	cached, ok := cache.Load(id)
+	if !ok {
+		return "", false
+	}
+	entry := cached.(cachedRegion)
+	if !time.Now().Before(entry.expires) {
+		cache.CompareAndDelete(id, cached)
+		return "", false
+	}
+	return entry.name, true
+}
+
+// storeRegion caches the region of id as read at readAt. Taking the time
+// before the read keeps the entry from outliving a move that started while
+// it was made.
+func storeRegion(cache *sync.Map, id, name string, readAt time.Time) {
+	cache.Store(id, cachedRegion{name: name, expires: readAt.Add(regionCacheTTL)})
+}
+
+// initRegions connects to the extra regional clusters listed in MONGO_REGIONS
+// as comma separated name=uri pairs, e.g.
+// "eu=mongodb://eu-db:27017/social-scribe-eu". The database is taken from the
+// URI path and defaults to social-scribe-<name>.
+func initRegions(ctx context.Context) {
+	for _, entry := range strings.Split(os.Getenv("MONGO_REGIONS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, uri, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" || name == models.RegionDefault {
+			log.Fatalf("[ERROR] Invalid MONGO_REGIONS entry %q", entry)
+		}
+		parsed, err := connstring.ParseAndValidate(uri)
+		if err != nil {
+			log.Fatalf("[ERROR] Invalid MongoDB URI for region %s: %v", name, err)
+		}
+		dbName := parsed.Database
+		if dbName == "" {
+			dbName = "social-scribe-" + name
+		}
+
+		regionClient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			log.Fatalf("[ERROR] Failed connecting to MongoDB for region %s: %v", name, err)
+		}
+		if err := regionClient.Ping(ctx, nil); err != nil {
+			log.Fatalf("[ERROR] Could not ping MongoDB for region %s: %v", name, err)
+		}
+		regionStores[name] = newRegionStore(name, regionClient.Database(dbName))
+		log.Printf("[INFO] Connected storage region %s (database %s)", name, dbName)
+	}
+}
+
+// Regions lists the storage regions teams can choose from.
+func Regions() []string {
+	names := make([]string, 0, len(regionStores))
+	for name := range regionStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func IsRegion(name string) bool {
+	_, ok := regionStores[name]
+	return ok
+}
+
+func regionByName(name string) (*regionStore, error) {
+	if name == "" {
+		name = models.RegionDefault
+	}
+	store, ok := regionStores[name]
+	if !ok {
+		return nil, fmt.Errorf("storage region %q is not configured", name)
+	}
+	return store, nil
+}
+
+// regionForUser resolves the user's region through the directory. It returns
+// nil, nil for unknown users.
+func regionForUser(userID string) (*regionStore, error) {
+	if cached, ok := loadRegion(&userRegions, userID); ok {
+		return regionByName(cached)
+	}
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id %q: %w", userID, apperrors.ErrInvalidInput)
+	}
+
+	readAt := time.Now()
+	var entry models.UserDirectoryEntry
+	err = userDirectoryCollection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error resolving region of user %s: %v", userID, err)
+		return nil, err
+	}
+	// The region of a user being moved is about to change
+	if entry.MovingTo == "" {
+		storeRegion(&userRegions, userID, entry.Region, readAt)
+	}
+	return regionByName(entry.Region)
+}
+
+// mustRegionForUser is regionForUser for callers that treat an unknown user
+// as an error.
+func mustRegionForUser(userID string) (*regionStore, error) {
+	store, err := regionForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
+	}
+	return store, nil
+}
+
+// writableRegionForUser is mustRegionForUser for writes. It reads the
+// directory rather than the cache, so a write never lands in a region the
+// user is leaving: it is refused while their data is copied, and follows the
+// move once it switched, even when another server made it.
+func writableRegionForUser(userID string) (*regionStore, error) {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id %q: %w", userID, apperrors.ErrInvalidInput)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	readAt := time.Now()
+	var entry models.UserDirectoryEntry
+	err = userDirectoryCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
+		}
+		log.Printf("[ERROR] Error resolving region of user %s: %v", userID, err)
+		return nil, err
+	}
+	if entry.MovingTo != "" {
+		return nil, fmt.Errorf("user %s is being moved to region %s, try again shortly: %w", userID, entry.MovingTo, apperrors.ErrConflict)
+	}
+	storeRegion(&userRegions, userID, entry.Region, readAt)
+	return regionByName(entry.Region)
+}
+
+// regionForTeam returns the region all of the team's members are stored in.
+func regionForTeam(teamID string) (*regionStore, error) {
+	if cached, ok := loadRegion(&teamRegions, teamID); ok {
+		return regionByName(cached)
+	}
+	readAt := time.Now()
+	team, err := GetTeamById(teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, fmt.Errorf("team %s: %w", teamID, apperrors.ErrNotFound)
+	}
+	storeRegion(&teamRegions, teamID, team.Region, readAt)
+	return regionByName(team.Region)
+}
+
+// backfillDefaultRegion records the default region on documents written
+// before regions existed, and registers those users in the directory so
+// usernames stay unique across regions. It only touches unmarked documents,
+// so after the first run it is cheap.
+func backfillDefaultRegion() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	store := regionStores[models.RegionDefault]
+	unmarked := bson.M{"region": bson.M{"$exists": false}}
+
+	cursor, err := store.users.Find(ctx, unmarked, options.Find().SetProjection(bson.M{"_id": 1, "username": 1}))
+	if err != nil {
+		log.Printf("[ERROR] Error finding users without a region: %v", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+	count := 0
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			log.Printf("[ERROR] Error decoding user during region backfill: %v", err)
+			return err
+		}
+		_, err := userDirectoryCollection.UpdateOne(ctx,
+			bson.M{"_id": user.Id},
+			bson.M{"$setOnInsert": bson.M{"username": user.UserName, "region": models.RegionDefault}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			log.Printf("[ERROR] Error registering user %s in the directory: %v", user.Id.Hex(), err)
+			return err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	for _, collection := range []*mongo.Collection{store.users, store.scheduledItems, store.deferredShares, store.providerResponses} {
+		if _, err := collection.UpdateMany(ctx, unmarked, bson.M{"$set": bson.M{"region": models.RegionDefault}}); err != nil {
+			log.Printf("[ERROR] Error backfilling region on %s: %v", collection.Name(), err)
+			return err
+		}
+	}
+	if count > 0 {
+		log.Printf("[INFO] Backfilled the default region for %d users", count)
+	}
+	return nil
+}
+
+const (
+	// moveWriteGrace is how long a move waits after blocking the user's
+	// writes before it copies their data, so writes already past the check
+	// have finished. Repository writes mostly time out within 5 seconds. It
+	// must not be shorter than regionCacheTTL.
+	moveWriteGrace = 5 * time.Second
+	// moveCleanupAttempts is how often a move tries to remove the user's
+	// data from the old region before it reports the move unfinished.
+	moveCleanupAttempts = 3
+)
+
+// regionMove is one collection a user's documents are moved through.
+type regionMove struct {
+	from, to *mongo.Collection
+	filter   bson.M
+}
+
+func regionMoves(from, to *regionStore, userID string) []regionMove {
+	objectId, _ := primitive.ObjectIDFromHex(userID)
+	return []regionMove{
+		{from.users, to.users, bson.M{"_id": objectId}},
+		{from.scheduledItems, to.scheduledItems, bson.M{"user_id": userID}},
+		{from.deferredShares, to.deferredShares, bson.M{"user_id": userID}},
+		{from.providerResponses, to.providerResponses, bson.M{"user_id": userID}},
+		{from.posts, to.posts, bson.M{"user_id": userID}},
+		{from.campaigns, to.campaigns, bson.M{"user_id": userID}},
+		{from.newsletterSubscribers, to.newsletterSubscribers, bson.M{"user_id": userID}},
+		{from.newsletterDeliveries, to.newsletterDeliveries, bson.M{"user_id": userID}},
+		{from.manualTasks, to.manualTasks, bson.M{"user_id": userID}},
+		{from.auditEvents, to.auditEvents, bson.M{"user_id": userID}},
+	}
+}
+
+// MoveUserToRegion relocates a user and their pending work to another region,
+// as happens when they create a team there. The user's writes are refused
+// while their documents are copied, then the directory is switched and the
+// documents are removed from the old region. Should removing them fail, the
+// move returns an error and the old region is recorded in the directory;
+// calling it again finishes the removal.
+func MoveUserToRegion(userID, region string) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q: %w", userID, apperrors.ErrInvalidInput)
+	}
+	to, err := regionByName(region)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, apperrors.ErrInvalidInput)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var entry models.UserDirectoryEntry
+	err = userDirectoryCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Error resolving region of user %s: %v", userID, err)
+		return err
+	}
+	if entry.MovedFrom != "" {
+		// An earlier move left copies behind in the region it left
+		if err := finishRegionMove(ctx, userID, entry.MovedFrom, entry.Region); err != nil {
+			return err
+		}
+	}
+	from, err := regionByName(entry.Region)
+	if err != nil {
+		return err
+	}
+	if from.name == to.name {
+		return nil
+	}
+
+	// A move interrupted while copying left the flag set; resuming it is safe
+	// since copies overwrite by id
+	result, err := userDirectoryCollection.UpdateOne(ctx,
+		bson.M{"_id": objectId, "region": from.name, "moving_to": bson.M{"$in": bson.A{nil, to.name}}},
+		bson.M{"$set": bson.M{"moving_to": to.name, "moving_since": time.Now().UTC()}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error blocking writes of user %s for region move: %v", userID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user %s is already being moved to another region: %w", userID, apperrors.ErrConflict)
+	}
+	unblock := func() {
+		_, err := userDirectoryCollection.UpdateOne(context.Background(), bson.M{"_id": objectId}, bson.M{"$unset": bson.M{"moving_to": "", "moving_since": ""}})
+		if err != nil {
+			log.Printf("[ERROR] Error unblocking writes of user %s after a failed region move: %v", userID, err)
+		}
+	}
+	time.Sleep(moveWriteGrace)
+
+	for _, move := range regionMoves(from, to, userID) {
+		cursor, err := move.from.Find(ctx, from.filter(move.filter))
+		if err != nil {
+			log.Printf("[ERROR] Error reading %s of user %s for region move: %v", move.from.Name(), userID, err)
+			unblock()
+			return err
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			unblock()
+			return err
+		}
+		for _, doc := range docs {
+			doc["region"] = to.name
+			_, err := move.to.ReplaceOne(ctx, bson.M{"_id": doc["_id"]}, doc, options.Replace().SetUpsert(true))
+			if err != nil {
+				log.Printf("[ERROR] Error copying %s of user %s to region %s: %v", move.to.Name(), userID, to.name, err)
+				unblock()
+				return err
+			}
+		}
+	}
+
+	_, err = userDirectoryCollection.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{
+		"$set":   bson.M{"region": to.name, "moved_from": from.name},
+		"$unset": bson.M{"moving_to": "", "moving_since": ""},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error switching region of user %s: %v", userID, err)
+		unblock()
+		return err
+	}
+	storeRegion(&userRegions, userID, to.name, time.Now())
+
+	if err := finishRegionMove(ctx, userID, from.name, to.name); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Moved user %s from region %s to %s", userID, from.name, to.name)
+	return nil
+}
+
+// finishRegionMove removes the user's documents from the region a move left,
+// retrying failed deletes, and then clears the region from the directory.
+func finishRegionMove(ctx context.Context, userID, fromName, toName string) error {
+	from, err := regionByName(fromName)
+	if err != nil {
+		return err
+	}
+	to, err := regionByName(toName)
+	if err != nil {
+		return err
+	}
+	remaining := regionMoves(from, to, userID)
+	for attempt := 1; len(remaining) > 0; attempt++ {
+		var failed []regionMove
+		for _, move := range remaining {
+			if _, err := move.from.DeleteMany(ctx, from.filter(move.filter)); err != nil {
+				log.Printf("[WARN] Failed to remove %s of user %s from region %s (attempt %d): %v", move.from.Name(), userID, from.name, attempt, err)
+				failed = append(failed, move)
+			}
+		}
+		remaining = failed
+		if len(remaining) > 0 && attempt == moveCleanupAttempts {
+			return fmt.Errorf("user %s was moved to region %s but %d collections still hold their data in region %s", userID, to.name, len(remaining), from.name)
+		}
+		if len(remaining) > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+
+	objectId, _ := primitive.ObjectIDFromHex(userID)
+	_, err = userDirectoryCollection.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$unset": bson.M{"moved_from": ""}})
+	if err != nil {
+		log.Printf("[ERROR] Error clearing the finished region move of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// stuckMoveAge is how long a move may block a user's writes before it is
+// taken to have been interrupted.
+const stuckMoveAge = 5 * time.Minute
+
+// FinishRegionMoves completes the region moves that were interrupted: it
+// resumes copying for moves that stopped while writes were blocked, and
+// removes the copies that moves failed to delete from the old region.
+func FinishRegionMoves(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := userDirectoryCollection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"moved_from": bson.M{"$exists": true}},
+		bson.M{"moving_to": bson.M{"$exists": true}, "moving_since": bson.M{"$lt": now.Add(-stuckMoveAge)}},
+	}})
+	if err != nil {
+		log.Printf("[ERROR] Error finding unfinished region moves: %v", err)
+		return err
+	}
+	var entries []models.UserDirectoryEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+	var failed int
+	for _, entry := range entries {
+		target := entry.MovingTo
+		if target == "" {
+			target = entry.Region
+		}
+		if err := MoveUserToRegion(entry.Id.Hex(), target); err != nil {
+			log.Printf("[ERROR] Failed to finish the region move of user %s: %v", entry.Id.Hex(), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d unfinished region moves failed again", failed, len(entries))
+	}
+	return nil
+}
+
+func createRegionIndexes(ctx context.Context, store *regionStore) error {
+	userIndexes := []mongo.IndexModel{
+		// Unique index so concurrent signups can't create the same username twice
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// SSO logins find members by the IdP subject within their team
+		{
+			Keys:    bson.D{{Key: "team_id", Value: 1}, {Key: "sso_subject", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"sso_subject": bson.M{"$exists": true}}),
+		},
+		// Sign in with Google finds users by their Google subject
+		{
+			Keys:    bson.D{{Key: "google_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"google_id": bson.M{"$exists": true}}),
+		},
+		// The post sync worker claims users whose sync is due
+		{
+			Keys:    bson.D{{Key: "posts_sync_due_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"hashnode_verified": true}),
+		},
+		// The feed sync worker claims users whose feed is due
+		{
+			Keys:    bson.D{{Key: "feed_sync_due_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"feed.url": bson.M{"$exists": true}}),
+		},
+		// The retention worker claims users whose policy is due
+		{
+			Keys:    bson.D{{Key: "retention_due_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"preferences.retention": bson.M{"$exists": true}}),
+		},
+		// The goal worker claims users whose reminder is due
+		{
+			Keys:    bson.D{{Key: "goal_reminder_due_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"posting_goal.reminder": true}),
+		},
+		// Product stats count users by activity and signup date
+		{
+			Keys: bson.D{{Key: "last_active_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}},
+		},
+	}
+	_, err := store.users.Indexes().CreateMany(ctx, userIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating user indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	_, err = store.deferredShares.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating deferred share indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	providerResponseIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "platform", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		// Archived responses are debugging aids only, never keep them for long
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((30 * 24 * time.Hour).Seconds())),
+		},
+	}
+	_, err = store.providerResponses.Indexes().CreateMany(ctx, providerResponseIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating provider response indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	postIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Blog lists show the newest posts first
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "published_at", Value: -1}},
+		},
+		// Search matches titles first, then briefs
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "brief", Value: "text"}},
+			Options: options.Index().
+				SetName("posts_text").
+				SetWeights(bson.D{{Key: "title", Value: 3}, {Key: "brief", Value: 1}}),
+		},
+	}
+	_, err = store.posts.Indexes().CreateMany(ctx, postIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating post indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	campaignIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "utm_campaign", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "blog_ids", Value: 1}},
+		},
+	}
+	_, err = store.campaigns.Indexes().CreateMany(ctx, campaignIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating campaign indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	newsletterSubscriberIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Unsubscribe links find the subscriber by token
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "token", Value: 1}},
+		},
+	}
+	_, err = store.newsletterSubscribers.Indexes().CreateMany(ctx, newsletterSubscriberIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating newsletter subscriber indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	newsletterDeliveryIndexes := []mongo.IndexModel{
+		// A subscriber gets each issue once
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "issue_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// The newsletter worker claims pending deliveries that are due
+		{
+			Keys:    bson.D{{Key: "next_attempt_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"status": models.DeliveryPending}),
+		},
+		// Deliveries are kept long enough to stop a retried share mailing
+		// subscribers twice
+		{
+			Keys:    bson.D{{Key: "queued_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((90 * 24 * time.Hour).Seconds())),
+		},
+	}
+	_, err = store.newsletterDeliveries.Indexes().CreateMany(ctx, newsletterDeliveryIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating newsletter delivery indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	manualTaskIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		// Done tasks are kept a month; pending ones have no done_at and stay
+		{
+			Keys:    bson.D{{Key: "done_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((30 * 24 * time.Hour).Seconds())),
+		},
+	}
+	_, err = store.manualTasks.Indexes().CreateMany(ctx, manualTaskIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating manual task indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	libraryAssetIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "team_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "team_id", Value: 1}, {Key: "kind", Value: 1}},
+		},
+	}
+	_, err = store.libraryAssets.Indexes().CreateMany(ctx, libraryAssetIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating library asset indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	_, err = store.auditEvents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "at", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating audit event indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	// Tasks are deleted once they ran, so each one is pending; the index
+	// keeps concurrent submissions from queueing a blog twice. Duplicates
+	// queued before it existed are removed with socialscribe-admin tasks
+	// dedupe.
+	_, err = store.scheduledItems.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "blog.blog.id", Value: 1}, {Key: "platform", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("scheduled_items_pending_unique"),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		log.Printf("[ERROR] Region %s has blogs queued twice; run socialscribe-admin tasks dedupe: %v", store.name, err)
+		return err
+	}
+	if err != nil {
+		log.Printf("[ERROR] Error creating scheduled task indexes in region %s: %v", store.name, err)
+		return err
+	}
+
+	log.Printf("[INFO] Successfully created indexes for region %s", store.name)
+	return nil
+}
+
+// DropDuplicateScheduledTasks keeps the first of the tasks queued twice for
+// the same blog and platform, in every region, and returns how many it
+// removed. The unique index on pending tasks can't be built over them; it is
+// a one-off repair, run through socialscribe-admin rather than on every start.
+func DropDuplicateScheduledTasks() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var removed int64
+	for _, name := range Regions() {
+		dropped, err := dropDuplicateScheduledTasks(ctx, regionStores[name])
+		removed += dropped
+		if err != nil {
+			log.Printf("[ERROR] Error removing duplicate scheduled tasks in region %s: %v", name, err)
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+func dropDuplicateScheduledTasks(ctx context.Context, store *regionStore) (int64, error) {
+	cursor, err := store.scheduledItems.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "user_id", Value: "$user_id"},
+				{Key: "blog_id", Value: "$blog.blog.id"},
+				{Key: "platform", Value: "$platform"},
+			}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var groups []struct {
+		IDs []interface{} `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+	var duplicates []interface{}
+	for _, group := range groups {
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if len(duplicates) == 0 {
+		return 0, nil
+	}
+	result, err := store.scheduledItems.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("[WARN] Removed %d duplicate scheduled tasks in region %s", result.DeletedCount, store.name)
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func TestRegionCacheExpires(t *testing.T) {
+	var cache sync.Map
+	storeRegion(&cache, "fresh", "eu", time.Now())
+	storeRegion(&cache, "stale", "eu", time.Now().Add(-regionCacheTTL))
+
+	if name, ok := loadRegion(&cache, "fresh"); !ok || name != "eu" {
+		t.Errorf("fresh entry = %q, %t", name, ok)
+	}
+	if name, ok := loadRegion(&cache, "stale"); ok {
+		t.Errorf("expired entry = %q, want a miss", name)
+	}
+	if _, ok := cache.Load("stale"); ok {
+		t.Error("the expired entry was kept")
+	}
+}
+
+func TestRegionOfMovingUserIsNotCached(t *testing.T) {
+	useTestDB(t)
+	userID, err := CreateUser(models.User{UserName: "mover", Region: models.RegionDefault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectId, _ := primitive.ObjectIDFromHex(userID)
+	userRegions.Delete(userID)
+	if _, err := userDirectoryCollection.UpdateOne(context.Background(), bson.M{"_id": objectId}, bson.M{"$set": bson.M{"moving_to": "eu"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := regionForUser(userID)
+	if err != nil || store == nil || store.name != models.RegionDefault {
+		t.Fatalf("region of a moving user = %+v, %v", store, err)
+	}
+	if name, ok := loadRegion(&userRegions, userID); ok {
+		t.Errorf("cached %q for a user being moved", name)
+	}
+}
@@ -2,46 +2,110 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"social-scribe/backend/internal/models"
 )
 
 var client *mongo.Client
-var userCollection *mongo.Collection
 var cacheCollection *mongo.Collection
-var scheduledItemsCollection *mongo.Collection
+var userDirectoryCollection *mongo.Collection
+var debugCapturesCollection *mongo.Collection
+var oauthClientsCollection *mongo.Collection
+var teamsCollection *mongo.Collection
+var oauthGrantsCollection *mongo.Collection
+var apiKeysCollection *mongo.Collection
 
+// InitMongoDb connects to MONGO_URI and MONGO_DB, defaulting to the local
+// social-scribe database.
 func InitMongoDb() {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	dbName := os.Getenv("MONGO_DB")
+	if dbName == "" {
+		dbName = "social-scribe"
+	}
+	if err := Connect(uri, dbName); err != nil {
+		log.Fatal("[ERROR] Failed connecting to MongoDB:", err)
+	}
+}
+
+// Connect points the repositories at the given MongoDB database. InitMongoDb
+// uses it for the server; test and benchmark harnesses call it with a
+// throwaway database.
+func Connect(uri, dbName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	dbName := "social-scribe"
-
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
+	clientOptions := options.Client().ApplyURI(uri)
 	var err error
 	client, err = mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatal("[ERROR] Failed connecting to MongoDB:", err)
+		return err
 	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal("Could not ping MongoDB:", err)
+		return fmt.Errorf("could not ping MongoDB: %v", err)
 	}
 
-	userCollection = client.Database(dbName).Collection("users")
 	cacheCollection = client.Database(dbName).Collection("cache")
-	scheduledItemsCollection = client.Database(dbName).Collection("scheduled_items")
+	userDirectoryCollection = client.Database(dbName).Collection("user_directory")
+	debugCapturesCollection = client.Database(dbName).Collection("debug_captures")
+	oauthClientsCollection = client.Database(dbName).Collection("oauth_clients")
+	teamsCollection = client.Database(dbName).Collection("teams")
+	oauthGrantsCollection = client.Database(dbName).Collection("oauth_grants")
+	apiKeysCollection = client.Database(dbName).Collection("api_keys")
 
-	err = CreateIndexes()
-	if err != nil {
-		log.Println("[ERROR] Failed creating indexes:", err)
+	// Users, their schedules and provider data live in their team's storage
+	// region; the default region shares the main database
+	initReadPreference()
+	regionStores[models.RegionDefault] = newRegionStore(models.RegionDefault, client.Database(dbName))
+	initRegions(ctx)
+
+	if err := Migrate(); err != nil {
+		log.Println("[ERROR] Failed migrating the database:", err)
 	}
 	log.Println("[INFO] Successfully connected to MongoDB")
+	return nil
+}
+
+// Migrate brings the schema up to date: it creates indexes and backfills
+// fields added since documents were written. Every step is idempotent and
+// runs even if an earlier one failed; Connect runs them on each start.
+func Migrate() error {
+	var errs []error
+	if err := CreateIndexes(); err != nil {
+		errs = append(errs, fmt.Errorf("creating indexes: %w", err))
+	}
+	if err := backfillDefaultRegion(); err != nil {
+		errs = append(errs, fmt.Errorf("backfilling the default region: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Disconnect drops the database when asked to and closes the connection.
+// Harnesses use it to clean up after Connect.
+func Disconnect(dropDatabase string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if dropDatabase != "" {
+		if err := client.Database(dropDatabase).Drop(ctx); err != nil {
+			return err
+		}
+	}
+	return client.Disconnect(ctx)
 }
 
 func CreateIndexes() error {
@@ -67,5 +131,135 @@ func CreateIndexes() error {
 	}
 
 	log.Println("[INFO] Successfully created indexes for cache collection")
+
+	// The directory is global so usernames stay unique across regions
+	_, err = userDirectoryCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// The retention worker finds region moves left unfinished
+		{
+			Keys:    bson.D{{Key: "moved_from", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"moved_from": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "moving_since", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"moving_to": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating user directory indexes: %v", err)
+		return err
+	}
+
+	for _, store := range regionStores {
+		if err := createRegionIndexes(ctx, store); err != nil {
+			return err
+		}
+	}
+
+	debugCaptureIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "request_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = debugCapturesCollection.Indexes().CreateMany(ctx, debugCaptureIndexes)
+	if err != nil {
+		log.Printf("[ERROR] Error creating debug capture indexes: %v", err)
+		return err
+	}
+
+	_, err = oauthClientsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "client_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "owner_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating oauth client indexes: %v", err)
+		return err
+	}
+
+	_, err = oauthGrantsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}},
+		},
+		// Codes and tokens disappear on their own once expired
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating oauth grant indexes: %v", err)
+		return err
+	}
+
+	_, err = apiKeysCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "key_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Keys with an expiry disappear on their own once expired
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating api key indexes: %v", err)
+		return err
+	}
+
+	// Teams that verified domains before verified_domains existed get it
+	// filled in, or the unique index wouldn't cover them
+	_, err = teamsCollection.UpdateMany(ctx,
+		bson.M{"verified_domains": bson.M{"$exists": false}, "domains.verified": true},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"verified_domains": bson.M{"$map": bson.M{
+			"input": bson.M{"$filter": bson.M{"input": "$domains", "cond": "$$this.verified"}},
+			"in":    "$$this.domain",
+		}}}}}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error backfilling verified team domains: %v", err)
+		return err
+	}
+	_, err = teamsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "domains.domain", Value: 1}},
+		},
+		// One team per verified domain, even when two verify it at once
+		{
+			Keys:    bson.D{{Key: "verified_domains", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"verified_domains": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "scim_token_hash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR] Error creating team indexes: %v", err)
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"social-scribe/backend/internal/models"
+)
+
+type requestUsersKey struct{}
+
+// requestUsers memoizes user loads for the lifetime of one request. Users
+// are kept encoded, so each caller decodes a copy of its own; nil is a user
+// that wasn't found.
+type requestUsers struct {
+	mu    sync.Mutex
+	users map[string][]byte
+}
+
+// WithRequestUserCache returns a context in which GetRequestUser loads each
+// user at most once. The auth middleware installs it for every request.
+func WithRequestUserCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestUsersKey{}).(*requestUsers); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestUsersKey{}, &requestUsers{users: map[string][]byte{}})
+}
+
+// GetRequestUser is GetUserById memoized on the request context, so the
+// middleware, handler and services share one load. Each caller gets a copy of
+// the user as first loaded, so changes one makes, saved or not, aren't seen by
+// the others. Without a request cache it simply loads the user.
+func GetRequestUser(ctx context.Context, userID string) (*models.User, error) {
+	cache, ok := ctx.Value(requestUsersKey{}).(*requestUsers)
+	if !ok {
+		return GetUserById(userID)
+	}
+
+	// Held across the load so concurrent callers don't both hit the database
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if raw, ok := cache.users[userID]; ok {
+		if raw == nil {
+			return nil, nil
+		}
+		var user models.User
+		if err := bson.Unmarshal(raw, &user); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	user, err := GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+	var raw []byte
+	if user != nil {
+		if raw, err = bson.Marshal(user); err != nil {
+			return nil, err
+		}
+	}
+	cache.users[userID] = raw
+	return user, nil
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/models"
+)
+
+// ClaimRetentionRun picks a user with a retention policy that is due to be
+// enforced and pushes their next run back by interval, so concurrent workers
+// don't enforce it twice. It returns nil when no run is due.
+func ClaimRetentionRun(now time.Time, interval time.Duration) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		user := &models.User{}
+		err := store.users.FindOneAndUpdate(ctx,
+			store.filter(bson.M{
+				"preferences.retention": bson.M{"$exists": true},
+				"$or": bson.A{
+					bson.M{"retention_due_at": bson.M{"$exists": false}},
+					bson.M{"retention_due_at": bson.M{"$lte": now}},
+				},
+			}),
+			bson.M{"$set": bson.M{"retention_due_at": now.Add(interval)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"retention_due_at": 1}).SetReturnDocument(options.After),
+		).Decode(user)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to claim a retention run in region %s: %v", name, err)
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, nil
+}
@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"social-scribe/backend/internal/apperrors"
 	"social-scribe/backend/internal/models"
 )
 
@@ -14,27 +17,43 @@ func GetScheduledTasks() ([]models.ScheduledBlogData, error) {
 
 	var scheduledTasks []models.ScheduledBlogData
 
-	cursor, err := scheduledItemsCollection.Find(ctx, bson.M{})
-	if err != nil {
-		log.Printf("[ERROR] Error getting scheduled tasks: %v", err)
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+	// The scheduler runs every region's queue
+	for _, store := range regionStores {
+		var regionTasks []models.ScheduledBlogData
+		cursor, err := store.scheduledItems.Find(ctx, store.filter(bson.M{}))
+		if err != nil {
+			log.Printf("[ERROR] Error getting scheduled tasks in region %s: %v", store.name, err)
+			return nil, err
+		}
 
-	if err = cursor.All(ctx, &scheduledTasks); err != nil {
-		log.Printf("[ERROR] Error decoding scheduled tasks: %v", err)
-		return nil, err
+		err = cursor.All(ctx, &regionTasks)
+		cursor.Close(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Error decoding scheduled tasks in region %s: %v", store.name, err)
+			return nil, err
+		}
+		scheduledTasks = append(scheduledTasks, regionTasks...)
 	}
 
 	return scheduledTasks, nil
 }
 
+// StoreScheduledTask queues the task. A blog is queued once per platform, so
+// storing a task that is already pending is a conflict.
 func StoreScheduledTask(task models.ScheduledBlogData) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := scheduledItemsCollection.InsertOne(ctx, task)
+	store, err := writableRegionForUser(task.UserID)
+	if err != nil {
+		return err
+	}
+	task.Region = store.name
+	_, err = store.scheduledItems.InsertOne(ctx, task)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("blog %s is already scheduled: %w", task.ScheduledBlog.Id, apperrors.ErrConflict)
+		}
 		log.Printf("[ERROR] Failed to store scheduled task: %v", err)
 		return err
 	}
@@ -45,10 +64,19 @@ func DeleteScheduledTask(task models.ScheduledBlogData) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := scheduledItemsCollection.DeleteOne(ctx, bson.M{
+	store, err := writableRegionForUser(task.UserID)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{
 		"user_id":      task.UserID,
 		"blog.blog.id": task.ScheduledBlog.Id,
-	})
+	}
+	// Without a platform every child task of the blog goes too
+	if task.Platform != "" {
+		filter["platform"] = task.Platform
+	}
+	result, err := store.scheduledItems.DeleteMany(ctx, store.filter(filter))
 	if err != nil {
 		log.Printf("[ERROR] Failed to delete scheduled task: %v", err)
 		return err
@@ -57,3 +85,29 @@ func DeleteScheduledTask(task models.ScheduledBlogData) error {
 
 	return nil
 }
+
+// GetUsersWithScheduledBlogs returns the users, across every region, with
+// blogs scheduled.
+func GetUsersWithScheduledBlogs() ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var users []models.User
+	for _, name := range Regions() {
+		store := regionStores[name]
+		cursor, err := store.users.Find(ctx, store.filter(bson.M{"scheduled_posts.0": bson.M{"$exists": true}}))
+		if err != nil {
+			log.Printf("[ERROR] Error finding users with scheduled blogs in region %s: %v", name, err)
+			return nil, err
+		}
+		var regionUsers []models.User
+		err = cursor.All(ctx, &regionUsers)
+		cursor.Close(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Error decoding users with scheduled blogs in region %s: %v", name, err)
+			return nil, err
+		}
+		users = append(users, regionUsers...)
+	}
+	return users, nil
+}
@@ -0,0 +1,252 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func CreateTeam(team models.Team) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if team.Region == "" {
+		team.Region = models.RegionDefault
+	}
+	if !IsRegion(team.Region) {
+		return "", fmt.Errorf("storage region %q is not configured: %w", team.Region, apperrors.ErrInvalidInput)
+	}
+	team.SyncVerifiedDomains()
+	result, err := teamsCollection.InsertOne(ctx, team)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("a domain is verified by another team: %w", apperrors.ErrConflict)
+		}
+		log.Printf("[ERROR] Error creating team: %v", err)
+		return "", err
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetTeamById returns nil, nil when the team does not exist.
+func GetTeamById(teamID string) (*models.Team, error) {
+	ctx := context.TODO()
+
+	objectId, err := primitive.ObjectIDFromHex(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid team id %q: %w", teamID, apperrors.ErrInvalidInput)
+	}
+	team := &models.Team{}
+	err = teamsCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(team)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting team %s: %v", teamID, err)
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetTeamByVerifiedDomain returns the team that proved ownership of an email
+// domain, or nil, nil when no team has.
+func GetTeamByVerifiedDomain(domain string) (*models.Team, error) {
+	ctx := context.TODO()
+
+	team := &models.Team{}
+	err := teamsCollection.FindOne(ctx, bson.M{
+		"domains": bson.M{"$elemMatch": bson.M{"domain": domain, "verified": true}},
+	}).Decode(team)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting team for domain %s: %v", domain, err)
+		return nil, err
+	}
+	return team, nil
+}
+
+// UpdateTeam replaces the team. It fails with ErrConflict when the team
+// verified a domain another team verified first.
+func UpdateTeam(team *models.Team) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	team.SyncVerifiedDomains()
+	result, err := teamsCollection.ReplaceOne(ctx, bson.M{"_id": team.Id}, team)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("a domain is verified by another team: %w", apperrors.ErrConflict)
+		}
+		log.Printf("[ERROR] Error updating team %s: %v", team.Id.Hex(), err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("team %s: %w", team.Id.Hex(), apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+func GetTeamMembers(teamID string) ([]models.User, error) {
+	ctx := context.TODO()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	members := []models.User{}
+	cursor, err := store.users.Find(ctx, store.filter(bson.M{"team_id": teamID}))
+	if err != nil {
+		log.Printf("[ERROR] Error getting members of team %s: %v", teamID, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &members); err != nil {
+		log.Printf("[ERROR] Error decoding members of team %s: %v", teamID, err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// TeamMemberFilter narrows ListTeamMembers to the members with an email or a
+// SCIM external id. Empty fields don't filter.
+type TeamMemberFilter struct {
+	Email      string
+	ExternalID string
+}
+
+// ListTeamMembers returns up to limit of the team's members matching the
+// filter, oldest first after skipping skip of them, along with how many match
+// in all.
+func ListTeamMembers(teamID string, filter TeamMemberFilter, skip, limit int64) ([]models.User, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"team_id": teamID}
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+	if filter.ExternalID != "" {
+		query["scim_external_id"] = filter.ExternalID
+	}
+	query = store.filter(query)
+
+	total, err := store.users.CountDocuments(ctx, query)
+	if err != nil {
+		log.Printf("[ERROR] Error counting members of team %s: %v", teamID, err)
+		return nil, 0, err
+	}
+	members := []models.User{}
+	// A limit of 0 would mean no limit to MongoDB
+	if limit <= 0 || skip >= total {
+		return members, total, nil
+	}
+	cursor, err := store.users.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetSkip(skip).SetLimit(limit))
+	if err != nil {
+		log.Printf("[ERROR] Error listing members of team %s: %v", teamID, err)
+		return nil, 0, err
+	}
+	if err = cursor.All(ctx, &members); err != nil {
+		log.Printf("[ERROR] Error decoding members of team %s: %v", teamID, err)
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// RemoveTeamMember takes the user out of their team along with the SSO and
+// SCIM identities that tied them to it. UpdateUser can't do this since the
+// fields are left out of the stored document when empty.
+func RemoveTeamMember(userID string) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.users.UpdateOne(context.TODO(),
+		store.filter(bson.M{"_id": objectId}),
+		bson.M{"$unset": bson.M{"team_id": "", "team_role": "", "sso_subject": "", "scim_external_id": ""}},
+	)
+	if err != nil {
+		log.Printf("[ERROR] Error removing user %s from their team: %v", userID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+// GetUserBySSOSubject returns nil, nil when no member of the team has signed
+// in with that IdP subject yet.
+func GetUserBySSOSubject(teamID, subject string) (*models.User, error) {
+	ctx := context.TODO()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{}
+	err = store.users.FindOne(ctx, store.filter(bson.M{"team_id": teamID, "sso_subject": subject})).Decode(user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting SSO user of team %s: %v", teamID, err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetTeamBySCIMTokenHash returns nil, nil when no team uses the token.
+func GetTeamBySCIMTokenHash(tokenHash string) (*models.Team, error) {
+	ctx := context.TODO()
+
+	team := &models.Team{}
+	err := teamsCollection.FindOne(ctx, bson.M{"scim_token_hash": tokenHash}).Decode(team)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting team by SCIM token: %v", err)
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetTeamMemberByEmail returns nil, nil when the team has no member with
+// that email.
+func GetTeamMemberByEmail(teamID, email string) (*models.User, error) {
+	ctx := context.TODO()
+
+	store, err := regionForTeam(teamID)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{}
+	err = store.users.FindOne(ctx, store.filter(bson.M{"team_id": teamID, "email": email})).Decode(user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		log.Printf("[ERROR] Error getting member %s of team %s: %v", email, teamID, err)
+		return nil, err
+	}
+	return user, nil
+}
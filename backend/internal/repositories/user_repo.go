@@ -2,23 +2,58 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"slices"
+	"social-scribe/backend/internal/apperrors"
 	"social-scribe/backend/internal/models"
+	"sort"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func InsertUser(user models.User) (string, error) {
+var ErrUsernameTaken = fmt.Errorf("username already taken: %w", apperrors.ErrConflict)
+
+// CreateUser inserts a new user into their region (user.Region, or the
+// default region). The user is registered in the directory first, whose
+// unique username index detects concurrent signups across all regions,
+// returning ErrUsernameTaken when the race is lost.
+func CreateUser(user models.User) (string, error) {
 	ctx := context.TODO()
 
-	result, err := userCollection.InsertOne(ctx, user)
+	store, err := regionByName(user.Region)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", err, apperrors.ErrInvalidInput)
+	}
+	user.Region = store.name
+	if user.Id.IsZero() {
+		user.Id = primitive.NewObjectID()
+	}
+
+	_, err = userDirectoryCollection.InsertOne(ctx, models.UserDirectoryEntry{Id: user.Id, UserName: user.UserName, Region: store.name})
 	if err != nil {
-		log.Printf("[ERROR] Error inserting user: %v", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrUsernameTaken
+		}
+		log.Printf("[ERROR] Error registering user in the directory: %v", err)
+		return "", err
+	}
+
+	result, err := store.users.InsertOne(ctx, user)
+	if err != nil {
+		log.Printf("[ERROR] Error creating user: %v", err)
+		userDirectoryCollection.DeleteOne(ctx, bson.M{"_id": user.Id})
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrUsernameTaken
+		}
 		return "", err
 	}
 	id := result.InsertedID.(primitive.ObjectID).Hex()
+	storeRegion(&userRegions, id, store.name, time.Now())
 	return id, nil
 }
 
@@ -30,20 +65,53 @@ func UpdateUser(userID string, updatedUser *models.User) error {
 		return err
 	}
 
-	filter := bson.M{"_id": objID}
-	update := bson.M{"$set": updatedUser}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	// A stale copy must not move the user out of their region
+	updatedUser.Region = store.name
+
+	fields, err := userFields(updatedUser)
+	if err != nil {
+		return err
+	}
+
+	filter := store.filter(bson.M{"_id": objID})
+	update := bson.M{"$set": fields}
 
-	result, err := userCollection.UpdateOne(ctx, filter, update)
+	result, err := store.users.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 
 	if result.MatchedCount == 0 {
-		return mongo.ErrNoDocuments
+		return fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
 	}
 	return nil
 }
 
+// ownWriteFields are written by their own updates alone, such as
+// TouchUserActivity while requests are being handled and RecordConsent
+// appending to the consent history, so a full update from a copy loaded
+// earlier must not move them back.
+var ownWriteFields = []string{"last_active_at", "consent_history"}
+
+// userFields are the fields UpdateUser sets.
+func userFields(user *models.User) (bson.D, error) {
+	raw, err := bson.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(fields, func(field bson.E) bool {
+		return slices.Contains(ownWriteFields, field.Key)
+	}), nil
+}
+
 func GetUserById(userID string) (*models.User, error) {
 	ctx := context.TODO()
 
@@ -51,8 +119,37 @@ func GetUserById(userID string) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	store, err := regionForUser(userID)
+	if err != nil || store == nil {
+		return nil, err
+	}
 	user := &models.User{}
-	err = userCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(user)
+	err = store.users.FindOne(ctx, store.filter(bson.M{"_id": objID})).Decode(user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByIdStale is GetUserById for read-only display paths that tolerate
+// data a few seconds behind the primary. Never use it to load a user that is
+// then written back, as that would overwrite newer data.
+func GetUserByIdStale(userID string) (*models.User, error) {
+	ctx := context.TODO()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	store, err := regionForUser(userID)
+	if err != nil || store == nil {
+		return nil, err
+	}
+	user := &models.User{}
+	err = store.staleUsers.FindOne(ctx, store.filter(bson.M{"_id": objID})).Decode(user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -64,8 +161,21 @@ func GetUserById(userID string) (*models.User, error) {
 
 func GetUserByName(userName string) (*models.User, error) {
 	ctx := context.TODO()
+
+	var entry models.UserDirectoryEntry
+	err := userDirectoryCollection.FindOne(ctx, bson.M{"username": userName}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	store, err := regionByName(entry.Region)
+	if err != nil {
+		return nil, err
+	}
 	user := &models.User{}
-	err := userCollection.FindOne(ctx, bson.M{"username": userName}).Decode(user)
+	err = store.users.FindOne(ctx, store.filter(bson.M{"_id": entry.Id})).Decode(user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -74,3 +184,230 @@ func GetUserByName(userName string) (*models.User, error) {
 	}
 	return user, nil
 }
+
+// ListUsers returns up to limit users across every region, oldest first, for
+// operator tooling. A limit of 0 returns everyone.
+func ListUsers(limit int64) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var users []models.User
+	for _, name := range Regions() {
+		store := regionStores[name]
+		findOptions := options.Find().SetSort(bson.M{"created_at": 1})
+		if limit > 0 {
+			findOptions.SetLimit(limit)
+		}
+		cursor, err := store.staleUsers.Find(ctx, store.filter(bson.M{}), findOptions)
+		if err != nil {
+			log.Printf("[ERROR] Error listing users in region %s: %v", name, err)
+			return nil, err
+		}
+		var regionUsers []models.User
+		err = cursor.All(ctx, &regionUsers)
+		cursor.Close(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Error decoding users in region %s: %v", name, err)
+			return nil, err
+		}
+		users = append(users, regionUsers...)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt) })
+	if limit > 0 && int64(len(users)) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// GetUserByGoogleId returns nil, nil when no account is linked to the Google
+// subject.
+func GetUserByGoogleId(googleID string) (*models.User, error) {
+	ctx := context.TODO()
+
+	for _, name := range Regions() {
+		store := regionStores[name]
+		user := &models.User{}
+		err := store.users.FindOne(ctx, store.filter(bson.M{"google_id": googleID})).Decode(user)
+		if err == nil {
+			return user, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			log.Printf("[ERROR] Error getting Google user in region %s: %v", name, err)
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// GetUsersByEmail returns every account recorded with the email, across all
+// regions.
+func GetUsersByEmail(email string) ([]models.User, error) {
+	ctx := context.TODO()
+
+	var users []models.User
+	for _, name := range Regions() {
+		store := regionStores[name]
+		cursor, err := store.users.Find(ctx, store.filter(bson.M{"email": email}))
+		if err != nil {
+			log.Printf("[ERROR] Error getting users by email in region %s: %v", name, err)
+			return nil, err
+		}
+		var regionUsers []models.User
+		err = cursor.All(ctx, &regionUsers)
+		cursor.Close(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Error decoding users by email in region %s: %v", name, err)
+			return nil, err
+		}
+		users = append(users, regionUsers...)
+	}
+	return users, nil
+}
+
+// RecordConsent adds an acceptance of the terms to the user's consent
+// history.
+func RecordConsent(userID string, record models.ConsentRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	result, err := store.users.UpdateOne(ctx, store.filter(bson.M{"_id": objID}), bson.M{"$push": bson.M{"consent_history": record}})
+	if err != nil {
+		log.Printf("[ERROR] Failed to record the consent of user %s: %v", userID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user %s: %w", userID, apperrors.ErrNotFound)
+	}
+	return nil
+}
+
+// GetUsersWithStaleKeys returns up to limit users, across every region,
+// whose data key is wrapped with a master key other than masterKeyID or who
+// have credentials not yet sealed with their data key, whose sealed form
+// starts with dataKeyPrefix.
+func GetUsersWithStaleKeys(masterKeyID, dataKeyPrefix string, limit int64) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	legacySecret := bson.M{"$exists": true, "$ne": "", "$not": primitive.Regex{Pattern: "^" + dataKeyPrefix}}
+	stale := bson.A{
+		bson.M{"data_key": bson.M{"$exists": true}, "data_key.master_key_id": bson.M{"$ne": masterKeyID}},
+	}
+	for _, path := range models.CredentialPaths(models.SealUser) {
+		stale = append(stale, bson.M{path: legacySecret})
+	}
+	for _, path := range models.CredentialPaths(models.SealPlaintext) {
+		stale = append(stale, bson.M{path: bson.M{"$exists": true, "$ne": ""}})
+	}
+	filter := bson.M{"$or": stale}
+	var users []models.User
+	for _, name := range Regions() {
+		store := regionStores[name]
+		cursor, err := store.users.Find(ctx, store.filter(filter), options.Find().SetLimit(limit))
+		if err != nil {
+			log.Printf("[ERROR] Error finding users with stale keys in region %s: %v", name, err)
+			return nil, err
+		}
+		var regionUsers []models.User
+		err = cursor.All(ctx, &regionUsers)
+		cursor.Close(ctx)
+		if err != nil {
+			log.Printf("[ERROR] Error decoding users with stale keys in region %s: %v", name, err)
+			return nil, err
+		}
+		users = append(users, regionUsers...)
+	}
+	if int64(len(users)) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// UpdateUserKeys saves the user's data key and the credentials sealed with
+// it, unless the data key changed since it was read as previousWrapped
+// (empty for a user who had none).
+func UpdateUserKeys(userID, previousWrapped string, user *models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objID, "data_key": bson.M{"$exists": false}}
+	if previousWrapped != "" {
+		filter = bson.M{"_id": objID, "data_key.wrapped": previousWrapped}
+	}
+	fields, err := userFields(user)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"data_key": user.DataKey}
+	roots := models.CredentialRoots()
+	for _, field := range fields {
+		if slices.Contains(roots, field.Key) {
+			update[field.Key] = field.Value
+		}
+	}
+	result, err := store.users.UpdateOne(ctx, store.filter(filter), bson.M{"$set": update})
+	if err != nil {
+		log.Printf("[ERROR] Failed to update the keys of user %s: %v", userID, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("keys of user %s changed meanwhile: %w", userID, apperrors.ErrConflict)
+	}
+	return nil
+}
+
+// RecordLogin adds a sign-in to the user's login history, keeping the newest
+// models.MaxLoginHistory. A non-empty alert is added to their notifications
+// as well.
+func RecordLogin(userID string, record models.LoginRecord, alert string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	store, err := writableRegionForUser(userID)
+	if err != nil {
+		return err
+	}
+	// A pipeline update, since the arrays may be null on older users, which
+	// $push refuses; $literal keeps user-controlled strings from being read
+	// as field paths
+	appendTo := func(field string, value interface{}) bson.M {
+		return bson.M{"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+			bson.A{bson.M{"$literal": value}},
+		}}
+	}
+	set := bson.M{
+		"login_history": bson.M{"$slice": bson.A{appendTo("login_history", record), -models.MaxLoginHistory}},
+	}
+	if alert != "" {
+		set["notifications"] = appendTo("notifications", alert)
+		set["notification_times"] = appendTo("notification_times", record.At)
+	}
+	_, err = store.users.UpdateOne(ctx, store.filter(bson.M{"_id": objID}), mongo.Pipeline{{{Key: "$set", Value: set}}})
+	if err != nil {
+		log.Printf("[ERROR] Failed to record a login of user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
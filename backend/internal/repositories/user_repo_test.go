@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestUpdateUserKeepsActivity(t *testing.T) {
+	useTestDB(t)
+	created := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	userID, err := CreateUser(models.User{UserName: "active", Region: models.RegionDefault, CreatedAt: created, LastActiveAt: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := GetUserById(userID)
+	if err != nil || stale == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+
+	active := created.Add(2 * time.Hour)
+	if err := TouchUserActivity(userID, active); err != nil {
+		t.Fatal(err)
+	}
+	stale.Verified = true
+	if err := UpdateUser(userID, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if !user.Verified {
+		t.Error("the update was lost")
+	}
+	if !user.LastActiveAt.Equal(active) {
+		t.Errorf("last active at %v, want %v", user.LastActiveAt, active)
+	}
+}
+
+func TestGetRequestUserReturnsCopies(t *testing.T) {
+	useTestDB(t)
+	userID, err := CreateUser(models.User{UserName: "shared", Region: models.RegionDefault, Notifications: []string{"welcome"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRequestUserCache(context.Background())
+	first, err := GetRequestUser(ctx, userID)
+	if err != nil || first == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	first.Verified = true
+	first.Notifications[0] = "changed"
+
+	second, err := GetRequestUser(ctx, userID)
+	if err != nil || second == nil {
+		t.Fatalf("loading the user again: %v", err)
+	}
+	if second == first || second.Verified || second.Notifications[0] != "welcome" {
+		t.Errorf("a change to one caller's user reached another: %+v", second)
+	}
+}
+
+func TestUpdateUserKeepsConsent(t *testing.T) {
+	useTestDB(t)
+	created := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	first := models.ConsentRecord{TermsOfService: "2026-01", PrivacyPolicy: "2026-01", At: created}
+	userID, err := CreateUser(models.User{UserName: "consenting", Region: models.RegionDefault, CreatedAt: created, ConsentHistory: []models.ConsentRecord{first}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := GetUserById(userID)
+	if err != nil || stale == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+
+	record := models.ConsentRecord{TermsOfService: "2026-03", PrivacyPolicy: "2026-01", At: created.Add(time.Minute)}
+	if err := RecordConsent(userID, record); err != nil {
+		t.Fatal(err)
+	}
+	stale.Verified = true
+	if err := UpdateUser(userID, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := GetUserById(userID)
+	if err != nil || user == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if !user.Verified {
+		t.Error("the update was lost")
+	}
+	if len(user.ConsentHistory) != 2 || !user.HasConsented("2026-03", "2026-01") {
+		t.Errorf("consent history %+v, want the recorded consent", user.ConsentHistory)
+	}
+}
+
+func TestUsersWithPlaintextCredentialsAreStale(t *testing.T) {
+	useTestDB(t)
+	userID, err := CreateUser(models.User{
+		UserName:             "legacy",
+		Region:               models.RegionDefault,
+		PlaintextXOAuthToken: "x-token",
+		Reddit:               models.RedditAccount{Username: "ada", PlaintextRefreshToken: "reddit-refresh"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := GetUsersWithStaleKeys("master", "dk", 10)
+	if err != nil || len(stale) != 1 || stale[0].Id.Hex() != userID {
+		t.Fatalf("stale users = %+v, %v", stale, err)
+	}
+
+	user := &stale[0]
+	user.DataKey = &models.DataKey{Wrapped: "wrapped", MasterKeyID: "master", Version: 1}
+	user.SealedXOAuthToken, user.PlaintextXOAuthToken = "dk1.x", ""
+	user.Reddit.SealedRefreshToken, user.Reddit.PlaintextRefreshToken = "dk1.reddit", ""
+	if err := UpdateUserKeys(userID, "", user); err != nil {
+		t.Fatal(err)
+	}
+	if stale, err := GetUsersWithStaleKeys("master", "dk", 10); err != nil || len(stale) != 0 {
+		t.Errorf("stale users after sealing = %+v, %v", stale, err)
+	}
+	saved, err := GetUserById(userID)
+	if err != nil || saved == nil {
+		t.Fatalf("loading the user: %v", err)
+	}
+	if saved.SealedXOAuthToken != "dk1.x" || saved.Reddit.SealedRefreshToken != "dk1.reddit" || saved.Reddit.Username != "ada" {
+		t.Errorf("saved %+v", saved)
+	}
+}
@@ -0,0 +1,116 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	pollInterval = time.Minute
+	// A policy counts in days, so enforcing it a few times a day keeps data
+	// from outliving it by more than a few hours
+	runInterval = 6 * time.Hour
+)
+
+// Worker deletes the data users asked not to keep, following the retention
+// policy in their preferences.
+type Worker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	clock  utils.Clock
+}
+
+func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{ctx: ctx, cancel: cancel, clock: utils.GetClock()}
+	go w.run()
+	return w
+}
+
+func (w *Worker) Stop() {
+	w.cancel()
+}
+
+func (w *Worker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Retention worker panicked: %v", r)
+			reporting.Report(w.ctx, fmt.Errorf("retention worker panicked: %v", r), map[string]string{
+				"component": "retention",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Retention worker started")
+	for {
+		// Copies a region move left behind would be claimed twice by the
+		// workers that scan every region
+		if err := repo.FinishRegionMoves(w.clock.Now()); err != nil {
+			log.Printf("[ERROR] Failed to finish region moves: %v", err)
+		}
+		w.enforceDue()
+		if !w.sleep(pollInterval) {
+			log.Println("[INFO] Retention worker stopped")
+			return
+		}
+	}
+}
+
+// enforceDue enforces the policies of users until none is due. A failed run
+// is retried once the claim's interval has passed.
+func (w *Worker) enforceDue() {
+	for w.ctx.Err() == nil {
+		user, err := repo.ClaimRetentionRun(w.clock.Now(), runInterval)
+		if err != nil || user == nil {
+			return
+		}
+		if err := w.enforce(user); err != nil {
+			log.Printf("[ERROR] Failed to enforce the retention policy of user %s: %v", user.Id.Hex(), err)
+		}
+	}
+}
+
+func (w *Worker) enforce(user *models.User) error {
+	userID := user.Id.Hex()
+	now := w.clock.Now().UTC()
+
+	result, changed := services.ApplyRetention(user, now)
+	if changed {
+		if err := repo.UpdateUser(userID, user); err != nil {
+			return err
+		}
+	}
+	var rawData int64
+	if days := user.Preferences.Retention.RawDataDays; days > 0 {
+		var err error
+		rawData, err = repo.DeleteProviderResponsesBefore(userID, now.AddDate(0, 0, -days))
+		if err != nil {
+			return err
+		}
+	}
+	if result.Notifications > 0 || result.SharedBlogs > 0 || rawData > 0 {
+		log.Printf("[INFO] Retention removed %d notifications, %d shared blogs and %d provider responses of user %s",
+			result.Notifications, result.SharedBlogs, rawData, userID)
+	}
+	return nil
+}
+
+// sleep waits for d and reports false if the worker was stopped meanwhile.
+func (w *Worker) sleep(d time.Duration) bool {
+	timer := w.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
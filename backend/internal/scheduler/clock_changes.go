@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/services"
+)
+
+// ClockShift is a schedule kept at a local time that its instant no longer
+// matches, because the rules of its timezone changed since it was scheduled.
+type ClockShift struct {
+	UserID    string    `json:"user_id"`
+	BlogID    string    `json:"blog_id"`
+	Timezone  string    `json:"timezone"`
+	LocalTime string    `json:"local_time"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	// Change is set when the clocks now skip or repeat the local time, so
+	// the schedule can't keep it exactly.
+	Change   string `json:"change,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ClockReport lists the schedules moved back to their local time.
+type ClockReport struct {
+	CheckedAt time.Time    `json:"checked_at"`
+	Shifted   []ClockShift `json:"shifted"`
+	Repaired  int          `json:"repaired"`
+	Failed    int          `json:"failed,omitempty"`
+}
+
+// clockShift reports whether a pending schedule drifted from its local time,
+// and where it moves to. Schedules already due are left to run.
+func clockShift(userID string, blog models.ScheduledBlog, now time.Time) (ClockShift, bool) {
+	if blog.Timezone == "" || blog.LocalTime == "" || !blog.ScheduledTime.After(now) {
+		return ClockShift{}, false
+	}
+	if len(blog.Children) > 0 && blog.RollupStatus() != models.SchedulePending {
+		return ClockShift{}, false
+	}
+	loc, err := time.LoadLocation(blog.Timezone)
+	if err != nil {
+		log.Printf("[WARN] Scheduled blog %s of user %s has an unknown timezone %q", blog.Id, userID, blog.Timezone)
+		return ClockShift{}, false
+	}
+	wall, err := time.Parse(models.LocalTimeLayout, blog.LocalTime)
+	if err != nil {
+		log.Printf("[WARN] Scheduled blog %s of user %s has an invalid local time %q", blog.Id, userID, blog.LocalTime)
+		return ClockShift{}, false
+	}
+	// A blog scheduled in the repeated hour keeps whichever occurrence the
+	// user picked
+	for _, instant := range services.LocalInstants(wall, loc) {
+		if instant.Equal(blog.ScheduledTime) {
+			return ClockShift{}, false
+		}
+	}
+	to, change := services.ResolveLocalTime(wall, loc)
+	return ClockShift{
+		UserID:    userID,
+		BlogID:    blog.Id,
+		Timezone:  blog.Timezone,
+		LocalTime: blog.LocalTime,
+		From:      blog.ScheduledTime,
+		To:        to,
+		Change:    change,
+	}, true
+}
+
+// CheckClockChanges moves the pending schedules kept at a local time back to
+// it when their timezone's rules changed, such as a country dropping daylight
+// saving time, and tells their users. Times the clocks now skip run just after
+// the change, and times they repeat at their first occurrence.
+func (s *Scheduler) CheckClockChanges() (ClockReport, error) {
+	report := ClockReport{CheckedAt: s.clock.Now().UTC(), Shifted: []ClockShift{}}
+	users, err := repo.GetUsersWithScheduledBlogs()
+	if err != nil {
+		return report, err
+	}
+	for i := range users {
+		// A deactivated user's schedules aren't queued and run at no time
+		if users[i].Disabled {
+			continue
+		}
+		for _, blog := range users[i].ScheduledBlogs {
+			shift, ok := clockShift(users[i].Id.Hex(), blog, report.CheckedAt)
+			if !ok {
+				continue
+			}
+			repaired, err := s.reanchor(shift)
+			switch {
+			case err != nil:
+				log.Printf("[ERROR] Failed to move scheduled blog %s of user %s to %v: %v", shift.BlogID, shift.UserID, shift.To, err)
+				shift.Error = err.Error()
+				report.Failed++
+			case repaired:
+				log.Printf("[INFO] Clock change: moved scheduled blog %s of user %s from %v to %v", shift.BlogID, shift.UserID, shift.From, shift.To)
+				shift.Repaired = true
+				report.Repaired++
+			}
+			report.Shifted = append(report.Shifted, shift)
+		}
+	}
+	return report, nil
+}
+
+// reanchor moves a schedule and its tasks to shift.To. It reports false when
+// the schedule was changed, cancelled or run meanwhile. Tasks that fail to
+// queue are requeued by the drift check.
+func (s *Scheduler) reanchor(shift ClockShift) (bool, error) {
+	unlock := s.lockParent(taskKey(shift.UserID, shift.BlogID))
+	defer unlock()
+
+	user, err := repo.GetUserById(shift.UserID)
+	if err != nil {
+		return false, err
+	}
+	if user == nil || user.Disabled {
+		return false, nil
+	}
+	var blog *models.ScheduledBlog
+	for i := range user.ScheduledBlogs {
+		if user.ScheduledBlogs[i].Id == shift.BlogID {
+			blog = &user.ScheduledBlogs[i]
+			break
+		}
+	}
+	if blog == nil || !blog.ScheduledTime.Equal(shift.From) || blog.LocalTime != shift.LocalTime || blog.Timezone != shift.Timezone {
+		return false, nil
+	}
+	if !shift.From.After(s.clock.Now()) {
+		return false, nil
+	}
+
+	if err := s.RemoveTask(shift.UserID, shift.BlogID); err != nil {
+		return false, err
+	}
+	blog.ScheduledTime = shift.To
+	for i := range blog.Children {
+		blog.Children[i].ScheduledTime = blog.PlatformTime(blog.Children[i].Platform)
+	}
+	user.AddNotification(clockShiftMessage(shift, blog.Title), s.clock.Now().UTC())
+	if err := repo.UpdateUser(shift.UserID, user); err != nil {
+		return false, err
+	}
+	for _, task := range (models.ScheduledBlogData{UserID: shift.UserID, ScheduledBlog: *blog}).Tasks() {
+		if err := s.AddTask(task); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func clockShiftMessage(shift ClockShift, title string) string {
+	local := shift.LocalTime
+	if wall, err := time.Parse(models.LocalTimeLayout, shift.LocalTime); err == nil {
+		local = wall.Format("Mon 2 Jan 2006 15:04")
+	}
+	to := shift.To.Format(time.RFC1123)
+	if loc, err := time.LoadLocation(shift.Timezone); err == nil {
+		to = shift.To.In(loc).Format("Mon 2 Jan 2006 15:04 MST")
+	}
+	switch shift.Change {
+	case services.ClockSkipped:
+		return fmt.Sprintf("The clocks in %s now skip %s, so the scheduled share of %q runs at %s instead", shift.Timezone, local, title, to)
+	case services.ClockRepeated:
+		return fmt.Sprintf("The clocks in %s now pass %s twice, so the scheduled share of %q runs at the first, %s", shift.Timezone, local, title, to)
+	}
+	return fmt.Sprintf("The clocks in %s changed, so the scheduled share of %q moved to %s to keep its local time", shift.Timezone, title, to)
+}
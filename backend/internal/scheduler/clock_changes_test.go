@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/services"
+)
+
+func TestClockShift(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	scheduled := func(local string, at time.Time) models.ScheduledBlog {
+		return models.ScheduledBlog{Blog: models.Blog{Id: local}, Timezone: "Europe/Berlin", LocalTime: local, ScheduledTime: at}
+	}
+	cases := []struct {
+		name   string
+		blog   models.ScheduledBlog
+		to     time.Time
+		change string
+	}{
+		{name: "at its local time", blog: scheduled("2026-04-01T10:00:00", time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC))},
+		// Scheduled when the rules still had Berlin on winter time in April
+		{
+			name: "drifted",
+			blog: scheduled("2026-04-01T10:00:00", time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)),
+			to:   time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC),
+		},
+		// Berlin falls back from 03:00 to 02:00 on 25 October 2026
+		{name: "second of a repeated time", blog: scheduled("2026-10-25T02:30:00", time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC))},
+		{
+			name:   "drifted into a repeated time",
+			blog:   scheduled("2026-10-25T02:30:00", time.Date(2026, 10, 25, 3, 30, 0, 0, time.UTC)),
+			to:     time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC),
+			change: services.ClockRepeated,
+		},
+		// Berlin springs forward from 02:00 to 03:00 on 29 March 2026
+		{
+			name:   "drifted into a skipped time",
+			blog:   scheduled("2026-03-29T02:30:00", time.Date(2026, 3, 29, 2, 30, 0, 0, time.UTC)),
+			to:     time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+			change: services.ClockSkipped,
+		},
+		{name: "already due", blog: scheduled("2026-03-01T08:00:00", now.Add(-time.Hour))},
+		{name: "without a timezone", blog: models.ScheduledBlog{Blog: models.Blog{Id: "plain"}, ScheduledTime: now.Add(time.Hour)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			shift, ok := clockShift("user", tc.blog, now)
+			if ok != !tc.to.IsZero() {
+				t.Fatalf("shifted = %v (%+v), want %v", ok, shift, !tc.to.IsZero())
+			}
+			if !ok {
+				return
+			}
+			if !shift.To.Equal(tc.to) || shift.Change != tc.change || !shift.From.Equal(tc.blog.ScheduledTime) {
+				t.Errorf("shift = %+v, want to %v with change %q", shift, tc.to, tc.change)
+			}
+		})
+	}
+}
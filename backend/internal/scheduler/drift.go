@@ -0,0 +1,344 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// driftGrace skips schedules that fell due this recently: a running
+	// task is deleted before its schedule is removed from the user.
+	driftGrace = 30 * time.Minute
+	// reconcileInterval is how often the reconciler checks for drift.
+	reconcileInterval = time.Hour
+)
+
+const (
+	// DriftRequeue queues the task of a schedule that has none.
+	DriftRequeue = "requeue"
+	// DriftRemove deletes a task whose schedule is gone or already ran.
+	DriftRemove = "remove"
+)
+
+// DriftEntry is a task missing for a user's schedule, or queued without one.
+type DriftEntry struct {
+	UserID        string    `json:"user_id"`
+	BlogID        string    `json:"blog_id"`
+	Platform      string    `json:"platform,omitempty"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	// Action is what repairing the drift does.
+	Action   string `json:"action"`
+	Repaired bool   `json:"repaired,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DriftReport compares the schedules stored on users with the scheduler's
+// tasks, which are updated one after the other rather than atomically.
+type DriftReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Users     int       `json:"users"`
+	Tasks     int       `json:"tasks"`
+	// MissingTasks are pending schedules with no task to run them.
+	MissingTasks []DriftEntry `json:"missing_tasks"`
+	// OrphanTasks are tasks with no pending schedule, which would share a
+	// blog the user cancelled or already shared.
+	OrphanTasks []DriftEntry `json:"orphan_tasks"`
+	Repaired    int          `json:"repaired"`
+	Failed      int          `json:"failed,omitempty"`
+}
+
+// Drifted reports whether any task is missing or orphaned.
+func (r DriftReport) Drifted() bool {
+	return len(r.MissingTasks) > 0 || len(r.OrphanTasks) > 0
+}
+
+// pendingTasks are the tasks the user's pending schedules should have queued,
+// by task key. A blog scheduled with platform offsets has one per child still
+// pending. A deactivated user keeps their schedules but has none queued.
+func pendingTasks(user *models.User) map[string]models.ScheduledBlogData {
+	tasks := make(map[string]models.ScheduledBlogData)
+	if user.Disabled {
+		return tasks
+	}
+	for _, blog := range user.ScheduledBlogs {
+		data := models.ScheduledBlogData{UserID: user.Id.Hex(), ScheduledBlog: blog}
+		for i, task := range data.Tasks() {
+			if len(blog.Children) > 0 && blog.Children[i].Status != models.SchedulePending {
+				continue
+			}
+			tasks[taskKeyOf(task)] = task
+		}
+	}
+	return tasks
+}
+
+func driftEntry(task models.ScheduledBlogData, action string) DriftEntry {
+	return DriftEntry{
+		UserID:        task.UserID,
+		BlogID:        task.ScheduledBlog.Blog.Id,
+		Platform:      task.Platform,
+		ScheduledTime: task.ScheduledBlog.ScheduledTime,
+		Action:        action,
+	}
+}
+
+// diffTasks compares the users' pending schedules with the queued tasks at
+// now. Schedules due within driftGrace may be running and aren't missing.
+func diffTasks(users []models.User, tasks []models.ScheduledBlogData, now time.Time) (missing, orphans []models.ScheduledBlogData) {
+	expected := make(map[string]models.ScheduledBlogData)
+	for i := range users {
+		for key, task := range pendingTasks(&users[i]) {
+			expected[key] = task
+		}
+	}
+	queued := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		key := taskKeyOf(task)
+		queued[key] = true
+		if _, ok := expected[key]; !ok {
+			orphans = append(orphans, task)
+		}
+	}
+	for key, task := range expected {
+		due := task.ScheduledBlog.ScheduledTime
+		if queued[key] || (!due.After(now) && now.Sub(due) < driftGrace) {
+			continue
+		}
+		missing = append(missing, task)
+	}
+	sortTasks(missing)
+	sortTasks(orphans)
+	return missing, orphans
+}
+
+// sortTasks orders tasks by their scheduled time, then by key.
+func sortTasks(tasks []models.ScheduledBlogData) {
+	sort.Slice(tasks, func(i, j int) bool {
+		ti, tj := tasks[i].ScheduledBlog.ScheduledTime, tasks[j].ScheduledBlog.ScheduledTime
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return taskKeyOf(tasks[i]) < taskKeyOf(tasks[j])
+	})
+}
+
+// CheckDrift compares the users' schedules with the stored tasks and, with
+// repair, queues the missing tasks and removes the orphaned ones. Each is
+// checked against the user again before it is repaired, since schedules keep
+// changing while the check runs.
+func (s *Scheduler) CheckDrift(repair bool) (DriftReport, error) {
+	report := DriftReport{CheckedAt: s.clock.Now().UTC(), MissingTasks: []DriftEntry{}, OrphanTasks: []DriftEntry{}}
+	// Tasks are read first. A schedule being added may then show up on the
+	// user without its tasks, which requeueing finds already queued, or
+	// its tasks without the user's schedule, which is checked again before
+	// they are removed
+	tasks, err := repo.GetScheduledTasks()
+	if err != nil {
+		return report, err
+	}
+	users, err := repo.GetUsersWithScheduledBlogs()
+	if err != nil {
+		return report, err
+	}
+	report.Users, report.Tasks = len(users), len(tasks)
+
+	missing, orphans := diffTasks(users, tasks, report.CheckedAt)
+	for _, task := range missing {
+		entry := driftEntry(task, DriftRequeue)
+		if repair {
+			s.repairDrift(&entry, task, s.requeue)
+			report.count(entry)
+		}
+		report.MissingTasks = append(report.MissingTasks, entry)
+	}
+	for _, task := range orphans {
+		entry := driftEntry(task, DriftRemove)
+		if repair {
+			s.repairDrift(&entry, task, s.removeOrphan)
+			report.count(entry)
+		}
+		report.OrphanTasks = append(report.OrphanTasks, entry)
+	}
+	return report, nil
+}
+
+func (r *DriftReport) count(entry DriftEntry) {
+	if entry.Repaired {
+		r.Repaired++
+	} else if entry.Error != "" {
+		r.Failed++
+	}
+}
+
+func (s *Scheduler) repairDrift(entry *DriftEntry, task models.ScheduledBlogData, fix func(models.ScheduledBlogData) (bool, error)) {
+	repaired, err := fix(task)
+	if err != nil {
+		log.Printf("[ERROR] Failed to %s task %s: %v", entry.Action, taskKeyOf(task), err)
+		entry.Error = err.Error()
+		return
+	}
+	entry.Repaired = repaired
+	if repaired {
+		log.Printf("[INFO] Drift repair: %s task %s due at %v", entry.Action, taskKeyOf(task), task.ScheduledBlog.ScheduledTime)
+	}
+}
+
+// requeue queues the task of a schedule still pending without one. It
+// reports false when the schedule was cancelled or queued meanwhile.
+func (s *Scheduler) requeue(task models.ScheduledBlogData) (bool, error) {
+	user, err := repo.GetUserById(task.UserID)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+	current, ok := pendingTasks(user)[taskKeyOf(task)]
+	if !ok {
+		return false, nil
+	}
+	if err := s.AddTask(current); err != nil {
+		if errors.Is(err, apperrors.ErrConflict) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// removeOrphan dequeues and deletes a task without a pending schedule. It
+// reports false when the user scheduled the blog again meanwhile.
+func (s *Scheduler) removeOrphan(task models.ScheduledBlogData) (bool, error) {
+	user, err := repo.GetUserById(task.UserID)
+	if err != nil {
+		return false, err
+	}
+	if user != nil {
+		if _, ok := pendingTasks(user)[taskKeyOf(task)]; ok {
+			return false, nil
+		}
+	}
+
+	key := taskKeyOf(task)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if delayed, ok := s.delayed[key]; ok {
+		close(delayed.cancel)
+		delete(s.delayed, key)
+	}
+	if i, ok := s.heap.indexMap[key]; ok {
+		s.heap.RemoveAt(i)
+		s.notify()
+	}
+	if err := repo.DeleteScheduledTask(task); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reconciler checks the scheduler for drift every reconcileInterval and
+// reports it. It repairs the drift too while the scheduler_drift_repair flag
+// is on. It also moves schedules kept at a local time back to it after their
+// timezone's rules change.
+type Reconciler struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	clock     utils.Clock
+	scheduler *Scheduler
+}
+
+func NewReconciler(s *Scheduler) *Reconciler {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Reconciler{ctx: ctx, cancel: cancel, clock: utils.GetClock(), scheduler: s}
+	go r.run()
+	return r
+}
+
+func (r *Reconciler) Stop() {
+	r.cancel()
+}
+
+func (r *Reconciler) run() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[ERROR] Scheduler reconciler panicked: %v", rec)
+			reporting.Report(r.ctx, fmt.Errorf("scheduler reconciler panicked: %v", rec), map[string]string{
+				"component": "scheduler",
+			})
+		}
+	}()
+
+	log.Println("[INFO] Scheduler reconciler started")
+	for r.sleep(reconcileInterval) {
+		r.reconcile()
+	}
+	log.Println("[INFO] Scheduler reconciler stopped")
+}
+
+func (r *Reconciler) reconcile() {
+	// Tasks stay queued during maintenance, and data may be mid-migration
+	if r.scheduler.Stats().Held {
+		return
+	}
+	r.checkDrift()
+	r.checkClockChanges()
+}
+
+func (r *Reconciler) checkDrift() {
+	report, err := r.scheduler.CheckDrift(config.Get().FeatureEnabled("scheduler_drift_repair"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to check the scheduler for drift: %v", err)
+		return
+	}
+	if !report.Drifted() {
+		return
+	}
+	log.Printf("[WARN] Scheduler drift: %d schedules without a task and %d tasks without a schedule, %d repaired, %d failed",
+		len(report.MissingTasks), len(report.OrphanTasks), report.Repaired, report.Failed)
+	if report.Failed > 0 {
+		reporting.Report(r.ctx, fmt.Errorf("failed to repair %d drifted scheduler tasks", report.Failed), map[string]string{
+			"component": "scheduler",
+		})
+	}
+}
+
+func (r *Reconciler) checkClockChanges() {
+	report, err := r.scheduler.CheckClockChanges()
+	if err != nil {
+		log.Printf("[ERROR] Failed to check schedules for clock changes: %v", err)
+		return
+	}
+	if len(report.Shifted) == 0 {
+		return
+	}
+	log.Printf("[WARN] Clock changes: %d schedules drifted from their local time, %d moved back, %d failed",
+		len(report.Shifted), report.Repaired, report.Failed)
+	if report.Failed > 0 {
+		reporting.Report(r.ctx, fmt.Errorf("failed to move %d schedules after clock changes", report.Failed), map[string]string{
+			"component": "scheduler",
+		})
+	}
+}
+
+// sleep waits for d and reports false if the reconciler was stopped meanwhile.
+func (r *Reconciler) sleep(d time.Duration) bool {
+	timer := r.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
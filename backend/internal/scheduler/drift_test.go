@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func TestDiffTasks(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	scheduled := func(id string, at time.Time) models.ScheduledBlog {
+		return models.ScheduledBlog{Blog: models.Blog{Id: id}, Platforms: []string{"twitter", "linkedin"}, ScheduledTime: at}
+	}
+	user := models.User{Id: primitive.NewObjectID()}
+	userID := user.Id.Hex()
+
+	fanned := scheduled("fanned", now.Add(time.Hour))
+	fanned.Children = []models.ScheduledChild{
+		{Platform: "twitter", ScheduledTime: now.Add(-2 * time.Hour), Status: models.ScheduleShared},
+		{Platform: "linkedin", ScheduledTime: now.Add(time.Hour), Status: models.SchedulePending},
+	}
+	user.ScheduledBlogs = []models.ScheduledBlog{
+		scheduled("queued", now.Add(time.Hour)),
+		scheduled("lost", now.Add(2*time.Hour)),
+		// May be running: its task is deleted before the schedule is
+		scheduled("running", now.Add(-time.Minute)),
+		scheduled("stuck", now.Add(-time.Hour)),
+		fanned,
+	}
+	task := func(userID, blogID, platform string, at time.Time) models.ScheduledBlogData {
+		return models.ScheduledBlogData{UserID: userID, Platform: platform, ScheduledBlog: models.ScheduledBlog{Blog: models.Blog{Id: blogID}, ScheduledTime: at}}
+	}
+	tasks := []models.ScheduledBlogData{
+		task(userID, "queued", "", now.Add(time.Hour)),
+		task(userID, "fanned", "linkedin", now.Add(time.Hour)),
+		// Already shared, so running it again would share it twice
+		task(userID, "fanned", "twitter", now.Add(-2*time.Hour)),
+		task(userID, "cancelled", "", now.Add(3*time.Hour)),
+		task(primitive.NewObjectID().Hex(), "queued", "", now.Add(time.Hour)),
+	}
+
+	missing, orphans := diffTasks([]models.User{user}, tasks, now)
+	if len(missing) != 2 || missing[0].ScheduledBlog.Id != "stuck" || missing[1].ScheduledBlog.Id != "lost" {
+		t.Errorf("missing %+v", missing)
+	}
+	if missing[1].UserID != userID || len(missing[1].ScheduledBlog.Platforms) != 2 {
+		t.Errorf("requeues %+v", missing[1])
+	}
+	want := []string{userID + ":fanned:twitter", tasks[4].UserID + ":queued", userID + ":cancelled"}
+	if len(orphans) != len(want) {
+		t.Fatalf("orphans %+v", orphans)
+	}
+	for i, orphan := range orphans {
+		if taskKeyOf(orphan) != want[i] {
+			t.Errorf("orphan %d = %s, want %s", i, taskKeyOf(orphan), want[i])
+		}
+	}
+}
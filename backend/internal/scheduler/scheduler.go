@@ -3,14 +3,38 @@ package scheduler
 import (
 	"container/heap"
 	"context"
+	"fmt"
 	"log"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/metrics"
 	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/reporting"
 	repo "social-scribe/backend/internal/repositories"
 	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// taskKey identifies a task in the heap. Blog ids are only unique per user, so
+// the owner is part of the key.
+func taskKey(userID, blogID string) string {
+	return userID + ":" + blogID
+}
+
+// taskKeyOf is the heap key of a task. The child tasks of a blog scheduled
+// with platform offsets share the blog id, so they are told apart by
+// platform.
+func taskKeyOf(task models.ScheduledBlogData) string {
+	key := taskKey(task.UserID, task.ScheduledBlog.Blog.Id)
+	if task.Platform != "" {
+		key += ":" + task.Platform
+	}
+	return key
+}
+
 type TaskHeap struct {
 	tasks    []models.ScheduledBlogData
 	indexMap map[string]int
@@ -24,21 +48,21 @@ func (h TaskHeap) Less(i, j int) bool {
 
 func (h TaskHeap) Swap(i, j int) {
 	h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i]
-	h.indexMap[h.tasks[i].ScheduledBlog.Blog.Id] = i
-	h.indexMap[h.tasks[j].ScheduledBlog.Blog.Id] = j
+	h.indexMap[taskKeyOf(h.tasks[i])] = i
+	h.indexMap[taskKeyOf(h.tasks[j])] = j
 }
 
 func (h *TaskHeap) Push(x interface{}) {
 	task := x.(models.ScheduledBlogData)
 	h.tasks = append(h.tasks, task)
-	h.indexMap[task.ScheduledBlog.Blog.Id] = len(h.tasks) - 1
+	h.indexMap[taskKeyOf(task)] = len(h.tasks) - 1
 }
 
 func (h *TaskHeap) Pop() interface{} {
 	n := len(h.tasks)
 	task := h.tasks[n-1]
 	h.tasks = h.tasks[0 : n-1]
-	delete(h.indexMap, task.ScheduledBlog.Blog.Id)
+	delete(h.indexMap, taskKeyOf(task))
 	return task
 }
 
@@ -47,27 +71,62 @@ func (h *TaskHeap) RemoveAt(index int) models.ScheduledBlogData {
 	h.Swap(index, n-1)
 	removed := h.tasks[n-1]
 	h.tasks = h.tasks[:n-1]
-	delete(h.indexMap, removed.ScheduledBlog.Blog.Id)
+	delete(h.indexMap, taskKeyOf(removed))
 	if index < len(h.tasks) {
 		heap.Fix(h, index)
 	}
 	return removed
 }
 
+// Scheduler runs scheduled shares at their due time. All exported methods are
+// safe for concurrent use: the heap is guarded by mu, and the agent goroutine
+// is woken through newTaskCh whenever the heap changes.
 type Scheduler struct {
 	heap      *TaskHeap
 	mu        sync.Mutex
 	ctx       context.Context
 	cancel    context.CancelFunc
 	newTaskCh chan struct{}
+	clock     utils.Clock
+	// parentLocks serializes the child tasks of one schedule, which all
+	// update the same entry of the user. Guarded by mu.
+	parentLocks map[string]*parentLock
+	// held keeps due tasks queued during maintenance. Guarded by mu.
+	held bool
+	// pacer and delayed spread bursts of due tasks, see config.Smoothing.
+	// delayed holds the due tasks waiting for their slot by task key.
+	// Guarded by mu.
+	pacer   *pacer
+	delayed map[string]*delayedTask
+}
+
+// delayedTask is a due task waiting for its smoothing slot.
+type delayedTask struct {
+	task   models.ScheduledBlogData
+	cancel chan struct{}
+}
+
+type parentLock struct {
+	sync.Mutex
+	refs int
 }
 
 func NewScheduler() *Scheduler {
+	return NewSchedulerWithClock(utils.GetClock())
+}
+
+// NewSchedulerWithClock creates a scheduler driven by the given clock, letting
+// tests fast-forward time instead of waiting for real timers.
+func NewSchedulerWithClock(clock utils.Clock) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &Scheduler{
-		ctx:       ctx,
-		cancel:    cancel,
-		newTaskCh: make(chan struct{}, 1),
+		clock:       clock,
+		ctx:         ctx,
+		cancel:      cancel,
+		newTaskCh:   make(chan struct{}, 1),
+		parentLocks: make(map[string]*parentLock),
+		pacer:       newPacer(),
+		delayed:     make(map[string]*delayedTask),
 		heap: &TaskHeap{
 			tasks:    []models.ScheduledBlogData{},
 			indexMap: make(map[string]int),
@@ -85,23 +144,30 @@ func (s *Scheduler) runAgent() {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("[ERROR] Agent panicked: %v", r)
+			reporting.Report(s.ctx, fmt.Errorf("scheduler agent panicked: %v", r), map[string]string{
+				"component": "scheduler",
+			})
 			s.Stop()
 		}
 	}()
 
 	log.Println("[INFO] Scheduler agent started")
 
-	var timer *time.Timer
+	var timer utils.Timer
 
 	for {
 		s.mu.Lock()
-		if s.heap.Len() == 0 {
-			log.Println("[INFO] No tasks in the heap, waiting for new tasks")
+		if s.heap.Len() == 0 || s.held {
+			if s.held {
+				log.Println("[INFO] Scheduler is held, waiting for it to be released")
+			} else {
+				log.Println("[INFO] No tasks in the heap, waiting for new tasks")
+			}
 			s.mu.Unlock()
 
 			select {
 			case <-s.newTaskCh:
-				log.Println("[INFO] New task added, rechecking heap")
+				log.Println("[INFO] Queue changed, rechecking heap")
 				continue
 			case <-s.ctx.Done():
 				log.Println("[INFO] Scheduler stopped")
@@ -111,7 +177,7 @@ func (s *Scheduler) runAgent() {
 
 		nextTask := s.heap.tasks[0]
 		scheduledTimeUTC := nextTask.ScheduledBlog.ScheduledTime.UTC()
-		timeUntil := time.Until(scheduledTimeUTC)
+		timeUntil := scheduledTimeUTC.Sub(s.clock.Now())
 		if timeUntil <= 0 {
 			timeUntil = 1 * time.Millisecond
 		}
@@ -119,10 +185,10 @@ func (s *Scheduler) runAgent() {
 
 		if timeUntil == 1*time.Millisecond {
 			s.mu.Lock()
-			if s.heap.Len() > 0 {
+			if s.heap.Len() > 0 && !s.held {
 				task := heap.Pop(s.heap).(models.ScheduledBlogData)
+				s.dispatch(task)
 				s.mu.Unlock()
-				go s.worker(task)
 			} else {
 				s.mu.Unlock()
 			}
@@ -130,11 +196,11 @@ func (s *Scheduler) runAgent() {
 		}
 
 		if timer == nil {
-			timer = time.NewTimer(timeUntil)
+			timer = s.clock.NewTimer(timeUntil)
 		} else {
 			if !timer.Stop() {
 				select {
-				case <-timer.C:
+				case <-timer.C():
 				default:
 				}
 			}
@@ -143,12 +209,12 @@ func (s *Scheduler) runAgent() {
 		}
 
 		select {
-		case <-timer.C:
+		case <-timer.C():
 			s.mu.Lock()
-			if s.heap.Len() > 0 {
+			if s.heap.Len() > 0 && !s.held {
 				task := heap.Pop(s.heap).(models.ScheduledBlogData)
+				s.dispatch(task)
 				s.mu.Unlock()
-				go s.worker(task)
 			} else {
 				s.mu.Unlock()
 			}
@@ -158,7 +224,7 @@ func (s *Scheduler) runAgent() {
 			if timer != nil {
 				if !timer.Stop() {
 					select {
-					case <-timer.C:
+					case <-timer.C():
 					default:
 					}
 				}
@@ -168,24 +234,92 @@ func (s *Scheduler) runAgent() {
 	}
 }
 
+// dispatch starts a due task, or delays it to its smoothing slot when it is
+// part of a burst. Callers hold mu.
+func (s *Scheduler) dispatch(task models.ScheduledBlogData) {
+	now := s.clock.Now()
+	due := task.ScheduledBlog.ScheduledTime
+	s.pacer.forget(now)
+	start := s.pacer.slot(config.Get().Smoothing, taskPlatforms(task.Platform, task.ScheduledBlog.Platforms), due, now)
+	delay := start.Sub(now)
+	if delay <= 0 {
+		go s.worker(task)
+		return
+	}
+
+	key := taskKeyOf(task)
+	delayed := &delayedTask{task: task, cancel: make(chan struct{})}
+	s.delayed[key] = delayed
+	log.Printf("[INFO] Smoothing delays task %s by %v, to %v after its due time", key, delay, start.Sub(due))
+	timer := s.clock.NewTimer(delay)
+	go func() {
+		select {
+		case <-timer.C():
+		case <-delayed.cancel:
+			timer.Stop()
+			return
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+		s.mu.Lock()
+		if s.delayed[key] != delayed {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.delayed, key)
+		// Maintenance began while the task waited; it queues again
+		if s.held {
+			heap.Push(s.heap, task)
+			s.mu.Unlock()
+			s.notify()
+			return
+		}
+		s.mu.Unlock()
+		s.worker(task)
+	}()
+}
+
 func (s *Scheduler) worker(task models.ScheduledBlogData) {
+	tags := map[string]string{
+		"component": "scheduler",
+		"task_id":   taskKeyOf(task),
+		"user_id":   task.UserID,
+		"platform":  strings.Join(task.ScheduledBlog.Platforms, ","),
+	}
+	defer reporting.Recover(s.ctx, tags)
+
 	log.Printf("[INFO] Worker executing task for user %v with blog %v, for platforms %v", task.UserID, task.ScheduledBlog.Blog.Id, task.ScheduledBlog.Platforms)
 
 	user, err := repo.GetUserById(task.UserID)
 	if err != nil || user == nil {
 		log.Printf("[ERROR] Error getting user or user not found: %v", task.UserID)
+		if err != nil {
+			reporting.Report(s.ctx, fmt.Errorf("failed to load user for scheduled task: %w", err), tags)
+		}
 		if delErr := repo.DeleteScheduledTask(task); delErr != nil {
 			log.Printf("[ERROR] Error deleting scheduled task: %v", delErr)
 		}
 		return
 	}
 
+	if task.Platform != "" {
+		s.runChild(task, user, tags)
+		return
+	}
+
 	blogId := task.ScheduledBlog.Blog.Id
 	platforms := task.ScheduledBlog.Platforms
 
-	processErr := services.ProcessSharedBlog(user, blogId, platforms)
+	receipt, processErr := services.ShareBlog(user, blogId, platforms, task.ScheduledBlog.AssetIDs, task.ScheduledBlog.LinkedInPage, task.ScheduledBlog.XThread)
+	if runs, err := metrics.SchedulerRuns.GetMetricWithLabelValues(metrics.Outcome(processErr)); err != nil {
+		log.Printf("[WARN] Failed to count the scheduler run: %v", err)
+	} else {
+		runs.Inc()
+	}
 	if processErr != nil {
 		log.Printf("[ERROR] Error processing shared blog for blog id %s and user id %s: %v", blogId, task.UserID, processErr)
+		reporting.Report(s.ctx, processErr, tags)
 	}
 
 	delErr := repo.DeleteScheduledTask(task)
@@ -208,12 +342,102 @@ func (s *Scheduler) worker(task models.ScheduledBlogData) {
 	updErr := repo.UpdateUser(task.UserID, user)
 	if updErr != nil {
 		log.Printf("[ERROR] Error updating user: %v", updErr)
+		reporting.Report(s.ctx, fmt.Errorf("failed to update user after scheduled task: %w", updErr), tags)
 	}
 
 	if processErr != nil {
 		log.Printf("[INFO] Task executed with errors for blog with ID %s and user ID %s, error: %v", blogId, task.UserID, processErr)
 	} else {
 		log.Printf("[INFO] Task executed successfully for blog with ID %s and user ID %s at %v", blogId, task.UserID, task.ScheduledBlog.ScheduledTime)
+		services.SendShareReceiptEmail(user, receipt)
+	}
+}
+
+// runChild shares a blog to the platform of one child task and records the
+// outcome on the parent schedule, which is removed once every child ran.
+func (s *Scheduler) runChild(task models.ScheduledBlogData, user *models.User, tags map[string]string) {
+	unlock := s.lockParent(taskKey(task.UserID, task.ScheduledBlog.Blog.Id))
+	defer unlock()
+
+	blogId := task.ScheduledBlog.Blog.Id
+	// An earlier child may have changed the user while this one waited
+	if reloaded, err := repo.GetUserById(task.UserID); err == nil && reloaded != nil {
+		user = reloaded
+	}
+
+	receipt, processErr := services.ShareBlog(user, blogId, []string{task.Platform}, task.ScheduledBlog.AssetIDs, task.ScheduledBlog.LinkedInPage, task.ScheduledBlog.XThread)
+	if runs, err := metrics.SchedulerRuns.GetMetricWithLabelValues(metrics.Outcome(processErr)); err != nil {
+		log.Printf("[WARN] Failed to count the scheduler run: %v", err)
+	} else {
+		runs.Inc()
+	}
+	child := models.ScheduledChild{
+		Platform:      task.Platform,
+		ScheduledTime: task.ScheduledBlog.ScheduledTime,
+		Status:        models.ScheduleShared,
+	}
+	if processErr != nil {
+		log.Printf("[ERROR] Error sharing blog %s of user %s to %s: %v", blogId, task.UserID, task.Platform, processErr)
+		reporting.Report(s.ctx, processErr, tags)
+		child.Status = models.ScheduleFailed
+		child.Error = processErr.Error()
+	} else if receipt != nil && len(receipt.Deliveries) > 0 {
+		child.PostURL = receipt.Deliveries[0].PostURL
+	}
+
+	if delErr := repo.DeleteScheduledTask(task); delErr != nil {
+		log.Printf("[ERROR] Error deleting scheduled task: %v", delErr)
+	}
+
+	for i := range user.ScheduledBlogs {
+		parent := &user.ScheduledBlogs[i]
+		if parent.Id != blogId {
+			continue
+		}
+		for j := range parent.Children {
+			if parent.Children[j].Platform == task.Platform {
+				parent.Children[j] = child
+			}
+		}
+		status := parent.RollupStatus()
+		if status != models.SchedulePending && status != models.ScheduleInProgress {
+			log.Printf("[INFO] All platforms of scheduled blog %s of user %s ran, status %s", blogId, task.UserID, status)
+			user.ScheduledBlogs = append(user.ScheduledBlogs[:i], user.ScheduledBlogs[i+1:]...)
+		}
+		break
+	}
+	if updErr := repo.UpdateUser(task.UserID, user); updErr != nil {
+		log.Printf("[ERROR] Error updating user: %v", updErr)
+		reporting.Report(s.ctx, fmt.Errorf("failed to update user after scheduled task: %w", updErr), tags)
+	}
+
+	if processErr == nil {
+		log.Printf("[INFO] Task executed successfully for blog with ID %s on %s and user ID %s", blogId, task.Platform, task.UserID)
+		services.SendShareReceiptEmail(user, receipt)
+	}
+}
+
+// lockParent locks the schedule with the given key and returns the func
+// releasing it.
+func (s *Scheduler) lockParent(key string) func() {
+	s.mu.Lock()
+	lock, ok := s.parentLocks[key]
+	if !ok {
+		lock = &parentLock{}
+		s.parentLocks[key] = lock
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.parentLocks, key)
+		}
+		s.mu.Unlock()
 	}
 }
 
@@ -224,20 +448,94 @@ func (s *Scheduler) loadTasks() error {
 		return err
 	}
 
+	s.LoadTasks(tasks)
+	return nil
+}
+
+// LoadTasks replaces the in-memory queue with the given tasks, typically the
+// persisted tasks recovered at startup. Tasks are not written back to the
+// store. Duplicate tasks for the same user and blog keep the last occurrence.
+func (s *Scheduler) LoadTasks(tasks []models.ScheduledBlogData) {
+	h := &TaskHeap{
+		tasks:    make([]models.ScheduledBlogData, 0, len(tasks)),
+		indexMap: make(map[string]int),
+	}
+	for _, task := range tasks {
+		key := taskKeyOf(task)
+		if i, ok := h.indexMap[key]; ok {
+			h.tasks[i] = task
+			continue
+		}
+		h.indexMap[key] = len(h.tasks)
+		h.tasks = append(h.tasks, task)
+	}
+	heap.Init(h)
+
+	s.mu.Lock()
+	s.heap = h
+	s.mu.Unlock()
+
+	s.notify()
+	log.Printf("[INFO] Loaded %d tasks successfully into heap", h.Len())
+}
+
+// SchedulerStats summarises the queue for capacity and utilization reports.
+type SchedulerStats struct {
+	Queued     int `json:"queued"`
+	DueNextDay int `json:"due_next_24h"`
+	// Delayed counts the due tasks waiting for their smoothing slot.
+	Delayed int  `json:"delayed,omitempty"`
+	Held    bool `json:"held,omitempty"`
+}
+
+func (s *Scheduler) Stats() SchedulerStats {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.heap = &TaskHeap{
-		tasks:    tasks,
-		indexMap: make(map[string]int),
+
+	stats := SchedulerStats{Queued: s.heap.Len(), Delayed: len(s.delayed), Held: s.held}
+	horizon := s.clock.Now().Add(24 * time.Hour)
+	for _, task := range s.heap.tasks {
+		if task.ScheduledBlog.ScheduledTime.Before(horizon) {
+			stats.DueNextDay++
+		}
+	}
+	return stats
+}
+
+// ListTasks returns a snapshot of the queued tasks for a user ordered by their
+// scheduled time, including those delayed by smoothing. Tasks that are
+// already executing are not included.
+func (s *Scheduler) ListTasks(userID string) []models.ScheduledBlogData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := []models.ScheduledBlogData{}
+	for _, task := range s.heap.tasks {
+		if task.UserID == userID {
+			tasks = append(tasks, task)
+		}
 	}
-	for i, task := range s.heap.tasks {
-		s.heap.indexMap[task.ScheduledBlog.Blog.Id] = i
+	for _, delayed := range s.delayed {
+		if delayed.task.UserID == userID {
+			tasks = append(tasks, delayed.task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ScheduledBlog.ScheduledTime.Before(tasks[j].ScheduledBlog.ScheduledTime)
+	})
+	return tasks
+}
+
+// notify wakes the agent so it re-evaluates the head of the heap.
+func (s *Scheduler) notify() {
+	select {
+	case s.newTaskCh <- struct{}{}:
+	default:
 	}
-	heap.Init(s.heap)
-	log.Printf("[INFO] Loaded %d tasks successfully into heap", len(tasks))
-	return nil
 }
 
+// AddTask persists the task and queues it. The lock is held across the store
+// write so a concurrent RemoveTask can't observe a half-added task.
 func (s *Scheduler) AddTask(task models.ScheduledBlogData) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -248,34 +546,68 @@ func (s *Scheduler) AddTask(task models.ScheduledBlogData) error {
 	}
 	heap.Push(s.heap, task)
 
-	select {
-	case s.newTaskCh <- struct{}{}:
-	default:
-	}
+	s.notify()
 	return nil
 }
 
-func (s *Scheduler) RemoveTask(blogId string) error {
+// RemoveTask dequeues and deletes the user's tasks for the given blog,
+// including every child task of a blog scheduled with platform offsets and
+// tasks delayed by smoothing. It is a no-op for tasks that are not queued,
+// e.g. because they are executing.
+func (s *Scheduler) RemoveTask(userId, blogId string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	index, ok := s.heap.indexMap[blogId]
-	if !ok {
-		return nil
+	for key, delayed := range s.delayed {
+		if delayed.task.UserID != userId || delayed.task.ScheduledBlog.Blog.Id != blogId {
+			continue
+		}
+		close(delayed.cancel)
+		delete(s.delayed, key)
+		if err := repo.DeleteScheduledTask(delayed.task); err != nil {
+			log.Printf("[ERROR] Error deleting task: %v", err)
+			return err
+		}
 	}
-	task := s.heap.RemoveAt(index)
-	err := repo.DeleteScheduledTask(task)
-	if err != nil {
-		log.Printf("[ERROR] Error deleting task: %v", err)
-		return err
+
+	var matched []models.ScheduledBlogData
+	for _, task := range s.heap.tasks {
+		if task.UserID == userId && task.ScheduledBlog.Blog.Id == blogId {
+			matched = append(matched, task)
+		}
 	}
-	select {
-	case s.newTaskCh <- struct{}{}:  // so we have to notify the agent to recheck the heap
-	default:
+	if len(matched) == 0 {
+		return nil
 	}
+	for _, task := range matched {
+		s.heap.RemoveAt(s.heap.indexMap[taskKeyOf(task)])
+		err := repo.DeleteScheduledTask(task)
+		if err != nil {
+			log.Printf("[ERROR] Error deleting task: %v", err)
+			return err
+		}
+	}
+	s.notify() // so we have to notify the agent to recheck the heap
 	return nil
 }
 
+// Hold keeps due tasks in the queue until Release, so nothing is shared
+// during maintenance. Tasks already running finish.
+func (s *Scheduler) Hold() {
+	s.mu.Lock()
+	s.held = true
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Release resumes running tasks; those that fell due while held run at once.
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	s.held = false
+	s.mu.Unlock()
+	s.notify()
+}
+
 func (s *Scheduler) Stop() {
 	s.cancel()
 }
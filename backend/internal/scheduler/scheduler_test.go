@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// useTestDB points the repositories at a throwaway database on the MongoDB
+// named by MONGO_TEST_URI, skipping the test when it isn't set.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	dbName := fmt.Sprintf("social-scribe-test-%d", time.Now().UnixNano())
+	if err := repo.Connect(uri, dbName); err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { repo.Disconnect(dbName) })
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsDueTasks(t *testing.T) {
+	useTestDB(t)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	clock := utils.NewFakeClock(start)
+	previous := utils.SetClock(clock)
+	t.Cleanup(func() { utils.SetClock(previous) })
+
+	scheduled := func(id string, at time.Time) models.ScheduledBlog {
+		return models.ScheduledBlog{Blog: models.Blog{Id: id, Title: id}, Platforms: []string{"linkedin"}, ScheduledTime: at}
+	}
+	soon, later := scheduled("soon", start.Add(time.Hour)), scheduled("later", start.Add(48*time.Hour))
+	// The user isn't verified, so each share fails at once without calling
+	// out; the task still runs and leaves the queue
+	userID, err := repo.CreateUser(models.User{
+		UserName:       "scheduled",
+		Region:         models.RegionDefault,
+		CreatedAt:      start,
+		ScheduledBlogs: []models.ScheduledBlog{soon, later},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, blog := range []models.ScheduledBlog{soon, later} {
+		if err := repo.StoreScheduledTask(models.ScheduledBlogData{UserID: userID, ScheduledBlog: blog}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewSchedulerWithClock(clock)
+	defer s.Stop()
+	queued := func() []string {
+		var ids []string
+		for _, task := range s.ListTasks(userID) {
+			ids = append(ids, task.ScheduledBlog.Id)
+		}
+		return ids
+	}
+	stored := func() int {
+		tasks, err := repo.GetScheduledTasks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tasks)
+	}
+	pending := func() int {
+		user, err := repo.GetUserById(userID)
+		if err != nil || user == nil {
+			t.Fatalf("loading the user: %v", err)
+		}
+		return len(user.ScheduledBlogs)
+	}
+	armed := func() bool { return clock.ActiveTimers() == 1 }
+
+	waitFor(t, "the agent to arm its timer", armed)
+	clock.Advance(59 * time.Minute)
+	// Give the agent a chance to run a task that isn't due
+	time.Sleep(20 * time.Millisecond)
+	if ids := queued(); len(ids) != 2 {
+		t.Fatalf("queued %v a minute before the first task is due", ids)
+	}
+
+	clock.Advance(time.Minute)
+	waitFor(t, "the due task to run", func() bool { return pending() == 1 && stored() == 1 })
+	if ids := queued(); len(ids) != 1 || ids[0] != "later" {
+		t.Fatalf("queued %v after the first task ran, want [later]", ids)
+	}
+
+	waitFor(t, "the agent to arm its timer for the next task", armed)
+	clock.Advance(47 * time.Hour)
+	waitFor(t, "the last task to run", func() bool { return pending() == 0 && stored() == 0 })
+	if ids := queued(); len(ids) != 0 {
+		t.Fatalf("queued %v after every task ran", ids)
+	}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"time"
+
+	"social-scribe/backend/internal/config"
+)
+
+// taskPlatforms are the platforms a task shares to.
+func taskPlatforms(platform string, platforms []string) []string {
+	if platform != "" {
+		return []string{platform}
+	}
+	return platforms
+}
+
+// pacer hands out start times for due tasks, keeping the smoothing interval
+// between shares on each platform. Tasks are given slots in the order they
+// fall due, so each platform keeps that order.
+type pacer struct {
+	// next is the earliest free start per platform.
+	next map[string]time.Time
+}
+
+func newPacer() *pacer {
+	return &pacer{next: make(map[string]time.Time)}
+}
+
+// slot returns when a task due at due, sharing to platforms, starts. It is
+// never later than the smoothing window after due, nor earlier than now.
+func (p *pacer) slot(smoothing config.Smoothing, platforms []string, due, now time.Time) time.Time {
+	start := due
+	if start.Before(now) {
+		start = now
+	}
+	paced := false
+	for _, platform := range platforms {
+		if smoothing.Interval(platform) == 0 {
+			continue
+		}
+		paced = true
+		if next := p.next[platform]; next.After(start) {
+			start = next
+		}
+	}
+	if !paced {
+		return start
+	}
+	// Past the window the task runs anyway; the burst is only bunched up
+	// at its end
+	if latest := due.Add(smoothing.Window()); start.After(latest) {
+		start = latest
+		if start.Before(now) {
+			start = now
+		}
+	}
+	for _, platform := range platforms {
+		interval := smoothing.Interval(platform)
+		if interval == 0 {
+			continue
+		}
+		next := p.next[platform]
+		if next.Before(start) {
+			next = start
+		}
+		p.next[platform] = next.Add(interval)
+	}
+	return start
+}
+
+// forget drops slots that have passed.
+func (p *pacer) forget(now time.Time) {
+	for platform, next := range p.next {
+		if !next.After(now) {
+			delete(p.next, platform)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/config"
+)
+
+func TestPacerSpreadsBursts(t *testing.T) {
+	smoothing := config.Smoothing{WindowSeconds: 60, PerMinute: map[string]int{"twitter": 30}}
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	p := newPacer()
+
+	// 40 tweets due in the same minute start 2s apart until the window is
+	// used up, then the rest start at its end
+	for i := 0; i < 40; i++ {
+		start := p.slot(smoothing, []string{"twitter"}, due, due)
+		want := due.Add(time.Duration(i) * 2 * time.Second)
+		if want.After(due.Add(time.Minute)) {
+			want = due.Add(time.Minute)
+		}
+		if !start.Equal(want) {
+			t.Fatalf("tweet %d starts at %v, want %v", i, start.Sub(due), want.Sub(due))
+		}
+	}
+
+	// Platforms are paced on their own, and unpaced ones aren't delayed
+	if start := p.slot(smoothing, []string{"linkedin"}, due, due); !start.Equal(due) {
+		t.Errorf("LinkedIn share delayed by %v", start.Sub(due))
+	}
+	// A share to both waits for the busier platform
+	if start := p.slot(smoothing, []string{"linkedin", "twitter"}, due, due); !start.Equal(due.Add(time.Minute)) {
+		t.Errorf("share to both starts at %v", start.Sub(due))
+	}
+}
+
+func TestPacerKeepsTimesWithinTheWindow(t *testing.T) {
+	smoothing := config.Smoothing{WindowSeconds: 10, PerMinute: map[string]int{"linkedin": 6}}
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	p := newPacer()
+
+	if start := p.slot(smoothing, []string{"linkedin"}, due, due); !start.Equal(due) {
+		t.Fatalf("first share starts at %v", start.Sub(due))
+	}
+	if start := p.slot(smoothing, []string{"linkedin"}, due, due); !start.Equal(due.Add(10 * time.Second)) {
+		t.Fatalf("second share starts at %v, want the window's end", start.Sub(due))
+	}
+	// A task that is already late, say after maintenance, starts now
+	now := due.Add(time.Hour)
+	if start := p.slot(smoothing, []string{"linkedin"}, due, now); !start.Equal(now) {
+		t.Errorf("late share starts at %v, want now", start.Sub(now))
+	}
+
+	p.forget(now.Add(time.Hour))
+	if len(p.next) != 0 {
+		t.Errorf("forget kept %v", p.next)
+	}
+}
+
+func TestPacerOffWithoutWindow(t *testing.T) {
+	smoothing := config.Smoothing{PerMinute: map[string]int{"twitter": 1}}
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	p := newPacer()
+	for i := 0; i < 3; i++ {
+		if start := p.slot(smoothing, []string{"twitter"}, due, due); !start.Equal(due) {
+			t.Fatalf("share %d delayed by %v without a window", i, start.Sub(due))
+		}
+	}
+}
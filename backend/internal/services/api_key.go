@@ -0,0 +1,11 @@
+package services
+
+import "strings"
+
+// APIKeyPrefix starts every personal access token, telling them apart from
+// OAuth access tokens in the Authorization header.
+const APIKeyPrefix = "sspat_"
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
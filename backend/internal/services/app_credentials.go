@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dghubble/oauth1"
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// linkedInCheckCode is exchanged to check LinkedIn app credentials. LinkedIn
+// tells a code it doesn't know apart from a client it doesn't know.
+const linkedInCheckCode = "socialscribe-credential-check"
+
+type appCredentialKey struct {
+	id  string
+	aes cipher.AEAD
+}
+
+// appCredentialKeys reads APP_CREDENTIALS_SECRETS, the master keys: a comma
+// separated list whose first secret wraps new data keys. Older secrets stay
+// listed after a rotation until RewrapDataKeys has moved every data key off
+// them.
+func appCredentialKeys() ([]appCredentialKey, error) {
+	var keys []appCredentialKey
+	for _, secret := range strings.Split(os.Getenv("APP_CREDENTIALS_SECRETS"), ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte("app-credentials:" + secret))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := sha256.Sum256(sum[:])
+		keys = append(keys, appCredentialKey{id: hex.EncodeToString(id[:4]), aes: aead})
+	}
+	return keys, nil
+}
+
+// AppCredentialsEnabled reports whether users can bring their own X and
+// LinkedIn apps, which needs APP_CREDENTIALS_SECRETS to seal their secrets.
+func AppCredentialsEnabled() bool {
+	keys, err := appCredentialKeys()
+	return err == nil && len(keys) > 0
+}
+
+// sealWithMasterKey encrypts plaintext with the first master key. binding is
+// authenticated along with it, so the result only opens for the same binding.
+func sealWithMasterKey(plaintext []byte, binding string) (string, error) {
+	keys, err := appCredentialKeys()
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("APP_CREDENTIALS_SECRETS is not set")
+	}
+	nonce := make([]byte, keys[0].aes.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := keys[0].aes.Seal(nonce, nonce, plaintext, []byte(keys[0].id+binding))
+	return keys[0].id + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openWithMasterKey decrypts what sealWithMasterKey sealed and returns it
+// with the id of the master key that sealed it.
+func openWithMasterKey(sealed, binding string) ([]byte, string, error) {
+	id, encoded, ok := strings.Cut(sealed, ".")
+	if !ok {
+		return nil, "", errors.New("malformed sealed secret")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("malformed sealed secret: %v", err)
+	}
+	keys, err := appCredentialKeys()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, key := range keys {
+		if key.id != id {
+			continue
+		}
+		if len(raw) < key.aes.NonceSize() {
+			return nil, "", errors.New("malformed sealed secret")
+		}
+		nonce, ciphertext := raw[:key.aes.NonceSize()], raw[key.aes.NonceSize():]
+		plaintext, err := key.aes.Open(nil, nonce, ciphertext, []byte(id+binding))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open secret: %v", err)
+		}
+		return plaintext, id, nil
+	}
+	return nil, "", fmt.Errorf("secret was sealed with the unknown key %s", id)
+}
+
+// openLegacyAppSecret decrypts an app secret sealed with a master key
+// directly, as they were before users had data keys.
+func openLegacyAppSecret(sealed string) (string, error) {
+	secret, _, err := openWithMasterKey(sealed, "")
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// TwitterConfigFor returns the X app configuration for the user's requests:
+// their own app if they have one, the shared app otherwise.
+func TwitterConfigFor(user *models.User) (*oauth1.Config, error) {
+	config := *twitterConfig
+	config.HTTPClient = getProviderClient(ProviderTwitter)
+	if user == nil || user.TwitterApp == nil {
+		return &config, nil
+	}
+	secret, err := OpenUserSecret(user, user.TwitterApp.SealedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the X app secret of user %s: %w", user.Id.Hex(), err)
+	}
+	config.ConsumerKey = user.TwitterApp.ClientID
+	config.ConsumerSecret = secret
+	return &config, nil
+}
+
+// statusRecorder remembers the status of the last response it carried.
+type statusRecorder struct {
+	base   http.RoundTripper
+	status int
+}
+
+func (s *statusRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := s.base.RoundTrip(req)
+	if resp != nil {
+		s.status = resp.StatusCode
+	}
+	return resp, err
+}
+
+// ValidateTwitterApp checks an X API key and secret by requesting a request
+// token with them, which also fails unless the app allows the shared
+// callback URL.
+func ValidateTwitterApp(base *oauth1.Config, consumerKey, consumerSecret string) error {
+	recorder := &statusRecorder{base: getProviderTransport()}
+	config := *base
+	config.ConsumerKey = consumerKey
+	config.ConsumerSecret = consumerSecret
+	config.HTTPClient = &http.Client{Transport: recorder, Timeout: providerTimeouts[ProviderTwitter]}
+	if _, _, err := config.RequestToken(); err != nil {
+		switch recorder.status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("X rejected the app, check the API key and secret and that %s is an allowed callback URL: %w", base.CallbackURL, apperrors.ErrInvalidInput)
+		case http.StatusTooManyRequests:
+			return fmt.Errorf("failed to check the X app: %w", apperrors.ErrProviderRateLimited)
+		}
+		return fmt.Errorf("failed to check the X app: %v", err)
+	}
+	return nil
+}
+
+// ValidateLinkedInApp checks a LinkedIn client id and secret by exchanging a
+// made-up code with them: LinkedIn refuses an unknown client before it looks
+// at the code.
+func ValidateLinkedInApp(base *oauth2.Config, clientID, clientSecret string) error {
+	config := *base
+	config.ClientID = clientID
+	config.ClientSecret = clientSecret
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, getProviderClient(ProviderLinkedIn))
+	_, err := config.Exchange(ctx, linkedInCheckCode)
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to check the LinkedIn app: %v", err)
+	}
+	switch {
+	case retrieveErr.ErrorCode == "invalid_client" || retrieveErr.Response.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("LinkedIn rejected the client id and secret: %w", apperrors.ErrInvalidInput)
+	case retrieveErr.Response.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("failed to check the LinkedIn app: %w", apperrors.ErrProviderRateLimited)
+	case retrieveErr.Response.StatusCode == http.StatusBadRequest:
+		// The client was accepted and only the code refused
+		return nil
+	}
+	return fmt.Errorf("failed to check the LinkedIn app: %v", err)
+}
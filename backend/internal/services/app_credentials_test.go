@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dghubble/oauth1"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestSealUserSecret(t *testing.T) {
+	user := &models.User{Id: primitive.NewObjectID()}
+	t.Setenv("APP_CREDENTIALS_SECRETS", "")
+	if AppCredentialsEnabled() {
+		t.Fatal("enabled without APP_CREDENTIALS_SECRETS")
+	}
+	if _, err := SealUserSecret(user, "s3cret"); err == nil {
+		t.Fatal("sealed without APP_CREDENTIALS_SECRETS")
+	}
+
+	t.Setenv("APP_CREDENTIALS_SECRETS", "old-key")
+	sealed, err := SealUserSecret(user, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "s3cret") {
+		t.Fatalf("sealed secret is readable: %q", sealed)
+	}
+	if user.DataKey == nil || user.DataKey.Version != 1 {
+		t.Fatalf("data key = %+v, want version 1", user.DataKey)
+	}
+
+	// Rotating keeps the old key for opening
+	t.Setenv("APP_CREDENTIALS_SECRETS", "new-key, old-key")
+	if secret, err := OpenUserSecret(user, sealed); err != nil || secret != "s3cret" {
+		t.Errorf("OpenUserSecret after rotation = %q, %v", secret, err)
+	}
+
+	t.Setenv("APP_CREDENTIALS_SECRETS", "new-key")
+	if _, err := OpenUserSecret(user, sealed); err == nil {
+		t.Error("opened a secret whose data key is wrapped with a dropped key")
+	}
+
+	t.Setenv("APP_CREDENTIALS_SECRETS", "new-key, old-key")
+	flipped := byte('A')
+	if sealed[20] == 'A' {
+		flipped = 'B'
+	}
+	tampered := sealed[:20] + string(flipped) + sealed[21:]
+	if _, err := OpenUserSecret(user, tampered); err == nil {
+		t.Error("opened a tampered secret")
+	}
+	other := &models.User{Id: primitive.NewObjectID(), DataKey: user.DataKey}
+	if _, err := OpenUserSecret(other, sealed); err == nil {
+		t.Error("opened a secret with another user's data key")
+	}
+}
+
+func TestTwitterConfigFor(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	previous := twitterConfig
+	twitterConfig = &oauth1.Config{ConsumerKey: "shared", ConsumerSecret: "shared-secret"}
+	defer func() { twitterConfig = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	config, err := TwitterConfigFor(user)
+	if err != nil || config.ConsumerKey != "shared" {
+		t.Fatalf("without an app: %+v, %v", config, err)
+	}
+	sealed, err := SealUserSecret(user, "own-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TwitterApp = &models.OAuthApp{ClientID: "own", SealedSecret: sealed}
+	config, err = TwitterConfigFor(user)
+	if err != nil || config.ConsumerKey != "own" || config.ConsumerSecret != "own-secret" {
+		t.Fatalf("with an app: %+v, %v", config, err)
+	}
+	if twitterConfig.ConsumerKey != "shared" {
+		t.Error("the shared config was changed")
+	}
+}
+
+func TestValidateTwitterApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), `oauth_consumer_key="good"`) {
+			http.Error(w, `{"errors":[{"code":32,"message":"Could not authenticate you."}]}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("oauth_token=t&oauth_token_secret=s&oauth_callback_confirmed=true"))
+	}))
+	defer server.Close()
+	base := &oauth1.Config{CallbackURL: "https://api.example/callback", Endpoint: oauth1.Endpoint{RequestTokenURL: server.URL}}
+
+	if err := ValidateTwitterApp(base, "good", "secret"); err != nil {
+		t.Errorf("good credentials: %v", err)
+	}
+	if err := ValidateTwitterApp(base, "bad", "secret"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("bad credentials: %v, want invalid input", err)
+	}
+}
+
+func TestValidateLinkedInApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		clientID, _, _ := r.BasicAuth()
+		if clientID == "" {
+			clientID = r.FormValue("client_id")
+		}
+		if clientID != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"Client authentication failed"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_request","error_description":"Unable to retrieve access token"}`))
+	}))
+	defer server.Close()
+	base := &oauth2.Config{RedirectURL: "https://api.example/callback", Endpoint: oauth2.Endpoint{TokenURL: server.URL, AuthStyle: oauth2.AuthStyleInParams}}
+
+	if err := ValidateLinkedInApp(base, "good", "secret"); err != nil {
+		t.Errorf("good credentials: %v", err)
+	}
+	if err := ValidateLinkedInApp(base, "bad", "secret"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("bad credentials: %v, want invalid input", err)
+	}
+}
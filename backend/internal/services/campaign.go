@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const maxUTMCampaignLength = 64
+
+var (
+	utmCampaignPattern   = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	utmCampaignSeparator = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// UTMCampaignTag checks the utm_campaign tag of a campaign. Without a tag one
+// is derived from the campaign name, e.g. "Spring Launch!" -> "spring-launch".
+func UTMCampaignTag(tag, name string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		tag = strings.Trim(utmCampaignSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	}
+	if tag == "" || len(tag) > maxUTMCampaignLength || !utmCampaignPattern.MatchString(tag) {
+		return "", fmt.Errorf("utm_campaign must be 1 to %d lowercase letters, digits, - or _: %w", maxUTMCampaignLength, apperrors.ErrInvalidInput)
+	}
+	return tag, nil
+}
+
+// CampaignURL tags a blog link shared to platform so analytics attribute the
+// visits to the campaign. Other query parameters are kept.
+func CampaignURL(rawURL, campaignTag, platform string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || campaignTag == "" {
+		return rawURL
+	}
+	query := parsed.Query()
+	query.Set("utm_source", platform)
+	query.Set("utm_medium", "social")
+	query.Set("utm_campaign", campaignTag)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// SummarizeCampaign sums up the shares and scheduled shares of the campaign's
+// blogs.
+func SummarizeCampaign(campaign models.Campaign, user *models.User) models.CampaignAnalytics {
+	inCampaign := map[string]bool{}
+	for _, blogID := range campaign.BlogIDs {
+		inCampaign[blogID] = true
+	}
+	analytics := models.CampaignAnalytics{
+		Blogs:     len(inCampaign),
+		Platforms: map[string]int{},
+	}
+	var lastShared time.Time
+	for _, blog := range user.SharedBlogs {
+		if !inCampaign[blog.Id] {
+			continue
+		}
+		analytics.SharedBlogs++
+		for _, platform := range blog.Platforms {
+			analytics.Platforms[platform]++
+		}
+		if blog.Engagement != nil {
+			addEngagement(&analytics.Engagement, *blog.Engagement)
+		}
+		if sharedAt, err := time.Parse(time.RFC3339, blog.SharedTime); err == nil && sharedAt.After(lastShared) {
+			lastShared = sharedAt
+			analytics.LastSharedAt = blog.SharedTime
+		}
+	}
+	for _, blog := range user.ScheduledBlogs {
+		if inCampaign[blog.Id] {
+			analytics.Scheduled++
+		}
+	}
+	return analytics
+}
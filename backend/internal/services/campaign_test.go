@@ -0,0 +1,75 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestUTMCampaignTag(t *testing.T) {
+	cases := []struct {
+		tag, name, want string
+		ok              bool
+	}{
+		{"", "Spring Launch!", "spring-launch", true},
+		{"", "  Q3 / dev_rel  ", "q3-dev_rel", true},
+		{"launch-2026", "Anything", "launch-2026", true},
+		{"Launch", "Anything", "", false},
+		{"", "!!!", "", false},
+	}
+	for _, tc := range cases {
+		got, err := UTMCampaignTag(tc.tag, tc.name)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("UTMCampaignTag(%q, %q) = %q, %v; want %q, ok %t", tc.tag, tc.name, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCampaignURL(t *testing.T) {
+	tagged := CampaignURL("https://blog.example.com/post?ref=home&utm_source=old", "spring", "linkedin")
+	parsed, err := url.Parse(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	want := map[string]string{"ref": "home", "utm_source": "linkedin", "utm_medium": "social", "utm_campaign": "spring"}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q in %s", key, query.Get(key), value, tagged)
+		}
+	}
+
+	if got := CampaignURL("https://blog.example.com/post", "", "linkedin"); got != "https://blog.example.com/post" {
+		t.Errorf("untagged share changed the link to %s", got)
+	}
+}
+
+func TestSummarizeCampaign(t *testing.T) {
+	user := &models.User{
+		SharedBlogs: []models.SharedBlog{
+			{Blog: models.Blog{Id: "a"}, Platforms: []string{"twitter", "linkedin"}, SharedTime: "2026-10-01T09:00:00Z",
+				Engagement: &models.ShareEngagement{Likes: 3, Reposts: 1}},
+			{Blog: models.Blog{Id: "b"}, Platforms: []string{"linkedin"}, SharedTime: "2026-10-02T11:00:00+02:00",
+				Engagement: &models.ShareEngagement{Likes: 2, Comments: 4}},
+			{Blog: models.Blog{Id: "other"}, Platforms: []string{"twitter"}, SharedTime: "2026-10-05T09:00:00Z",
+				Engagement: &models.ShareEngagement{Likes: 50}},
+		},
+		ScheduledBlogs: []models.ScheduledBlog{{Blog: models.Blog{Id: "c"}}},
+	}
+	campaign := models.Campaign{BlogIDs: []string{"a", "b", "c"}}
+
+	got := SummarizeCampaign(campaign, user)
+	if got.Blogs != 3 || got.SharedBlogs != 2 || got.Scheduled != 1 {
+		t.Errorf("counts = %d blogs, %d shared, %d scheduled; want 3, 2, 1", got.Blogs, got.SharedBlogs, got.Scheduled)
+	}
+	if got.Platforms["linkedin"] != 2 || got.Platforms["twitter"] != 1 {
+		t.Errorf("platforms = %v", got.Platforms)
+	}
+	if got.Engagement != (models.ShareEngagement{Likes: 5, Reposts: 1, Comments: 4}) {
+		t.Errorf("engagement = %+v", got.Engagement)
+	}
+	if got.LastSharedAt != "2026-10-02T11:00:00+02:00" {
+		t.Errorf("last shared at %s", got.LastSharedAt)
+	}
+}
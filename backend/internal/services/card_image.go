@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Platforms crop card images to about 1.91:1 and upscale small ones
+	// badly, so inline images outside these bounds aren't used
+	minCardImageWidth  = 400
+	minCardImageHeight = 200
+	minCardImageAspect = 1.0
+	maxCardImageAspect = 2.5
+	// Only this many inline images are fetched, in document order
+	maxCardImageCandidates = 5
+	// Image headers hold the dimensions, so a small prefix is enough
+	maxCardImageHeaderBytes = 64 << 10
+)
+
+var (
+	htmlImageTag     = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+	htmlImageAttr    = regexp.MustCompile(`(?is)\b(src|width|height)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	markdownImageTag = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^\s)>]+)>?(?:\s+"[^"]*")?\s*\)`)
+)
+
+// inlineImage is an image referenced by an article. Width and height are the
+// declared dimensions, 0 when not given.
+type inlineImage struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// SelectCardImage picks the image shown on the platform card of a blog
+// post: its cover image, else the first inline image of the article that is
+// large enough and roughly landscape, else the post's generated OG image.
+// html and markdown are the article body; either may be empty.
+func SelectCardImage(coverURL, ogImageURL, html, markdown string) string {
+	if coverURL != "" {
+		return coverURL
+	}
+	candidates := inlineImages(html, markdown)
+	if len(candidates) > maxCardImageCandidates {
+		candidates = candidates[:maxCardImageCandidates]
+	}
+	for _, candidate := range candidates {
+		width, height := candidate.Width, candidate.Height
+		if width == 0 || height == 0 {
+			var err error
+			width, height, err = fetchImageSize(candidate.URL)
+			if err != nil {
+				continue
+			}
+		}
+		if suitableCardImage(width, height) {
+			return candidate.URL
+		}
+	}
+	return ogImageURL
+}
+
+func suitableCardImage(width, height int) bool {
+	if width < minCardImageWidth || height < minCardImageHeight {
+		return false
+	}
+	aspect := float64(width) / float64(height)
+	return aspect >= minCardImageAspect && aspect <= maxCardImageAspect
+}
+
+// inlineImages lists the absolute http(s) image URLs of an article in order,
+// from its HTML when there is any and its markdown otherwise.
+func inlineImages(html, markdown string) []inlineImage {
+	var images []inlineImage
+	seen := map[string]bool{}
+	add := func(image inlineImage) {
+		parsed, err := url.Parse(image.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return
+		}
+		// Vector images can't be used on cards
+		if strings.HasSuffix(strings.ToLower(parsed.Path), ".svg") || seen[image.URL] {
+			return
+		}
+		seen[image.URL] = true
+		images = append(images, image)
+	}
+
+	if html != "" {
+		for _, tag := range htmlImageTag.FindAllString(html, -1) {
+			var image inlineImage
+			for _, attr := range htmlImageAttr.FindAllStringSubmatch(tag, -1) {
+				value := attr[2] + attr[3] + attr[4]
+				switch strings.ToLower(attr[1]) {
+				case "src":
+					image.URL = strings.ReplaceAll(value, "&amp;", "&")
+				case "width":
+					image.Width, _ = strconv.Atoi(value)
+				case "height":
+					image.Height, _ = strconv.Atoi(value)
+				}
+			}
+			add(image)
+		}
+		return images
+	}
+	for _, match := range markdownImageTag.FindAllStringSubmatch(markdown, -1) {
+		add(inlineImage{URL: match[1]})
+	}
+	return images
+}
+
+// checkImageHost guards fetching inline images, whose URLs come from article
+// content: only hosts resolving to public addresses are fetched. Tests
+// replace it to reach local servers.
+var checkImageHost = checkPublicHost
+
+// checkPublicHost fails unless every address host resolves to is public.
+func checkPublicHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		ip := addr.IP
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+			return fmt.Errorf("%s resolves to the non-public address %s", host, ip)
+		}
+	}
+	return nil
+}
+
+// maxImageRedirects bounds the redirects followed when fetching an image.
+const maxImageRedirects = 5
+
+// imageTransport checks the address of every connection made for an image
+// as well, or the host could resolve to a public address for checkImageHost
+// and to an internal one when it is dialled.
+var imageTransport = newGuardedTransport(func(host string) error { return checkImageHost(host) })
+
+// imageClient fetches images whose URLs come from content. The host of every
+// redirect is checked too, or a public URL could redirect to an internal one.
+func imageClient() *http.Client {
+	client := *getProviderClient(ProviderWeb)
+	// Fake transports of tests and benchmarks are kept
+	if client.Transport == outboundTransport {
+		client.Transport = imageTransport
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxImageRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxImageRedirects)
+		}
+		return checkImageHost(req.URL.Hostname())
+	}
+	return &client
+}
+
+// fetchImageSize reads the dimensions of a GIF, JPEG or PNG image from the
+// start of the file.
+func fetchImageSize(imageURL string) (int, int, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := checkImageHost(parsed.Hostname()); err != nil {
+		return 0, 0, err
+	}
+	resp, err := imageClient().Get(imageURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	config, _, err := image.DecodeConfig(io.LimitReader(resp.Body, maxCardImageHeaderBytes))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
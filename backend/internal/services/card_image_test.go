@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelectCardImage(t *testing.T) {
+	pngOf := func(width, height int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	images := map[string][]byte{
+		"/banner.png": pngOf(1200, 630),
+		"/tall.png":   pngOf(400, 1200),
+		"/icon.png":   pngOf(64, 64),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+	previous := checkImageHost
+	checkImageHost = func(string) error { return nil }
+	defer func() { checkImageHost = previous }()
+
+	const og = "https://hashnode.com/og/post.png"
+	cases := []struct {
+		name     string
+		cover    string
+		html     string
+		markdown string
+		want     string
+	}{
+		{name: "cover image wins", cover: "https://cdn.example.com/cover.png", html: `<img src="` + server.URL + `/banner.png">`, want: "https://cdn.example.com/cover.png"},
+		{name: "declared size", html: `<p><img width="32" height="32" src="https://cdn.example.com/a.png"><img src='https://cdn.example.com/b.png' width=1600 height=900></p>`, want: "https://cdn.example.com/b.png"},
+		{name: "fetched size skips small and tall images", html: `<img src="` + server.URL + `/icon.png"><img src="` + server.URL + `/tall.png"><img src="` + server.URL + `/banner.png">`, want: server.URL + "/banner.png"},
+		{name: "markdown", markdown: "Intro\n\n![diagram](" + server.URL + "/missing.png)\n![banner](" + server.URL + `/banner.png "Banner")`, want: server.URL + "/banner.png"},
+		{name: "svg and relative images are skipped", html: `<img src="https://cdn.example.com/logo.svg" width="1200" height="630"><img src="/local.png" width="1200" height="630">`, want: og},
+		{name: "no inline images", html: "<p>Just text</p>", want: og},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SelectCardImage(tc.cover, og, tc.html, tc.markdown); got != tc.want {
+				t.Errorf("SelectCardImage = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckImageHostRejectsPrivateAddresses(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "10.1.2.3", "169.254.169.254", "::1"} {
+		if err := checkImageHost(host); err == nil {
+			t.Errorf("checkImageHost(%q) allowed a non-public address", host)
+		}
+	}
+}
+
+func TestFetchImageSizeChecksRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://metadata.internal/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+	var checked []string
+	previous := checkImageHost
+	checkImageHost = func(host string) error {
+		checked = append(checked, host)
+		if host != "127.0.0.1" {
+			return fmt.Errorf("%s is internal", host)
+		}
+		return nil
+	}
+	defer func() { checkImageHost = previous }()
+
+	if _, _, err := fetchImageSize(server.URL + "/cover.png"); err == nil {
+		t.Fatal("followed a redirect to an internal host")
+	}
+	// The host, the address dialled for it and the redirect's host
+	if len(checked) != 3 || checked[2] != "metadata.internal" {
+		t.Errorf("checked %v", checked)
+	}
+}
+
+// TestFetchImageSizeChecksDialledAddress checks that a host passing the check
+// by name is still refused when it resolves to an internal address.
+func TestFetchImageSizeChecksDialledAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached the server")
+	}))
+	defer server.Close()
+	previous := checkImageHost
+	checkImageHost = func(host string) error {
+		if host != "localhost" {
+			return fmt.Errorf("%s is internal", host)
+		}
+		return nil
+	}
+	defer func() { checkImageHost = previous }()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	if _, _, err := fetchImageSize("http://localhost:" + port + "/cover.png"); err == nil || !strings.Contains(err.Error(), "is internal") {
+		t.Errorf("error = %v, want the dialled address refused", err)
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// CSRFHeader carries the CSRF token on state-changing requests authenticated
+// by the session cookie.
+const CSRFHeader = "X-CSRF-Token"
+
+// CheckCSRFSecret fails unless CSRF_SECRET is set. Every instance must share
+// the key and keep it across restarts, or tokens stop verifying, so the
+// server refuses to start without it rather than make one up.
+func CheckCSRFSecret() error {
+	if os.Getenv("CSRF_SECRET") == "" {
+		return fmt.Errorf("CSRF_SECRET is not set")
+	}
+	return nil
+}
+
+func csrfKey() []byte {
+	return []byte(os.Getenv("CSRF_SECRET"))
+}
+
+// CSRFToken derives the CSRF token of a session from its session token, so no
+// state is kept. The token changes whenever the session does.
+func CSRFToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, csrfKey())
+	mac.Write([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidCSRFToken reports whether token is the CSRF token of the session.
+// Without a key it fails closed.
+func ValidCSRFToken(sessionToken, token string) bool {
+	return token != "" && len(csrfKey()) > 0 && hmac.Equal([]byte(token), []byte(CSRFToken(sessionToken)))
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// dataKeySealPrefix starts secrets sealed with a user's data key, followed by
+// the key's version. Secrets sealed with a master key directly start with
+// the master key's id instead.
+const dataKeySealPrefix = "dk"
+
+// rewrapBatchSize is how many users the rewrap job loads at a time.
+const rewrapBatchSize = 100
+
+// wrapBinding ties a wrapped data key to its user and version, so it can't
+// be moved to another user or replayed as an older key.
+func wrapBinding(userId string, version int) string {
+	return ":data-key:" + userId + ":" + strconv.Itoa(version)
+}
+
+func dataKeyCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newDataKey generates a data key of the given version for the user, wrapped
+// with the current master key.
+func newDataKey(userId string, version int) (*models.DataKey, []byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := sealWithMasterKey(key, wrapBinding(userId, version))
+	if err != nil {
+		return nil, nil, err
+	}
+	masterKeyID, _, _ := strings.Cut(wrapped, ".")
+	return &models.DataKey{
+		Wrapped:     wrapped,
+		MasterKeyID: masterKeyID,
+		Version:     version,
+		CreatedAt:   utils.Now(),
+	}, key, nil
+}
+
+func unwrapDataKey(userId string, dataKey *models.DataKey) ([]byte, error) {
+	key, _, err := openWithMasterKey(dataKey.Wrapped, wrapBinding(userId, dataKey.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %d of user %s: %v", dataKey.Version, userId, err)
+	}
+	return key, nil
+}
+
+func sealWithDataKey(userId string, dataKey *models.DataKey, key []byte, secret string) (string, error) {
+	aead, err := dataKeyCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(userId))
+	return dataKeySealPrefix + strconv.Itoa(dataKey.Version) + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func isDataKeySealed(sealed string) bool {
+	return strings.HasPrefix(sealed, dataKeySealPrefix)
+}
+
+// SealUserSecret encrypts a credential of the user for storage with their
+// data key, creating the key on first use. The caller saves the user.
+func SealUserSecret(user *models.User, secret string) (string, error) {
+	userId := user.Id.Hex()
+	var key []byte
+	var err error
+	if user.DataKey == nil {
+		user.DataKey, key, err = newDataKey(userId, 1)
+	} else {
+		key, err = unwrapDataKey(userId, user.DataKey)
+	}
+	if err != nil {
+		return "", err
+	}
+	return sealWithDataKey(userId, user.DataKey, key, secret)
+}
+
+// OpenUserSecret decrypts a credential sealed by SealUserSecret, or one
+// sealed with a master key before the user had a data key.
+func OpenUserSecret(user *models.User, sealed string) (string, error) {
+	if !isDataKeySealed(sealed) {
+		return openLegacyAppSecret(sealed)
+	}
+	userId := user.Id.Hex()
+	version, encoded, ok := strings.Cut(strings.TrimPrefix(sealed, dataKeySealPrefix), ".")
+	if !ok {
+		return "", errors.New("malformed sealed secret")
+	}
+	if user.DataKey == nil || version != strconv.Itoa(user.DataKey.Version) {
+		return "", fmt.Errorf("secret was sealed with data key %s, which user %s no longer has", version, userId)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed secret: %v", err)
+	}
+	key, err := unwrapDataKey(userId, user.DataKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := dataKeyCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", errors.New("malformed sealed secret")
+	}
+	secret, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(userId))
+	if err != nil {
+		return "", fmt.Errorf("failed to open secret: %v", err)
+	}
+	return string(secret), nil
+}
+
+// SealCredential seals secret with the user's data key into sealed and
+// clears plaintext, the field the credential was stored in before it was
+// sealed; an empty secret clears both. The caller saves the user.
+func SealCredential(user *models.User, sealed, plaintext *string, secret string) error {
+	*plaintext = ""
+	if secret == "" {
+		*sealed = ""
+		return nil
+	}
+	value, err := SealUserSecret(user, secret)
+	if err != nil {
+		return err
+	}
+	*sealed = value
+	return nil
+}
+
+// OpenCredential opens a credential sealed by SealCredential, or returns
+// plaintext when it was stored before it was sealed.
+func OpenCredential(user *models.User, sealed, plaintext string) (string, error) {
+	if sealed == "" {
+		return plaintext, nil
+	}
+	return OpenUserSecret(user, sealed)
+}
+
+// userSealedSecrets are the user's stored credentials sealed with their data
+// key or a master key.
+func userSealedSecrets(user *models.User) []*string {
+	var secrets []*string
+	for _, field := range user.CredentialFields(models.SealUser) {
+		secrets = append(secrets, field.Value)
+	}
+	return secrets
+}
+
+// RotateUserDataKey replaces the user's data key with a new version and
+// reseals their credentials with it, so the old key opens nothing anymore.
+// The caller saves the user.
+func RotateUserDataKey(user *models.User) error {
+	userId := user.Id.Hex()
+	secrets := userSealedSecrets(user)
+	opened := make([]string, len(secrets))
+	for i, sealed := range secrets {
+		secret, err := OpenUserSecret(user, *sealed)
+		if err != nil {
+			return err
+		}
+		opened[i] = secret
+	}
+	version := 1
+	if user.DataKey != nil {
+		version = user.DataKey.Version + 1
+	}
+	dataKey, key, err := newDataKey(userId, version)
+	if err != nil {
+		return err
+	}
+	for i, sealed := range secrets {
+		if *sealed, err = sealWithDataKey(userId, dataKey, key, opened[i]); err != nil {
+			return err
+		}
+	}
+	user.DataKey = dataKey
+	return nil
+}
+
+// rewrapUserKeys brings the user's keys up to date: a data key wrapped with
+// an older master key is wrapped again with the current one, and credentials
+// still sealed with a master key or stored in plain text are sealed with the
+// data key. It reports whether anything changed.
+func rewrapUserKeys(user *models.User, currentKeyID string) (bool, error) {
+	userId := user.Id.Hex()
+	changed := false
+	if user.DataKey != nil && user.DataKey.MasterKeyID != currentKeyID {
+		key, err := unwrapDataKey(userId, user.DataKey)
+		if err != nil {
+			return false, err
+		}
+		wrapped, err := sealWithMasterKey(key, wrapBinding(userId, user.DataKey.Version))
+		if err != nil {
+			return false, err
+		}
+		user.DataKey.Wrapped = wrapped
+		user.DataKey.MasterKeyID = currentKeyID
+		user.DataKey.RewrappedAt = utils.Now()
+		changed = true
+	}
+	for _, sealed := range userSealedSecrets(user) {
+		if isDataKeySealed(*sealed) {
+			continue
+		}
+		secret, err := openLegacyAppSecret(*sealed)
+		if err != nil {
+			return false, err
+		}
+		if *sealed, err = SealUserSecret(user, secret); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	for _, field := range user.CredentialFields(models.SealPlaintext) {
+		// A sealed value was stored after the plaintext one
+		if *field.Sealed == "" {
+			sealed, err := SealUserSecret(user, *field.Value)
+			if err != nil {
+				return false, err
+			}
+			*field.Sealed = sealed
+		}
+		*field.Value = ""
+		changed = true
+	}
+	return changed, nil
+}
+
+// RewrapReport is the outcome of a RewrapDataKeys run.
+type RewrapReport struct {
+	MasterKeyID string `json:"master_key_id"`
+	Updated     int    `json:"updated"`
+	Failed      int    `json:"failed"`
+}
+
+// RewrapDataKeys is the job that follows a master key rotation: every data
+// key wrapped with an older master key is wrapped with the current one, and
+// credentials sealed before data keys existed, or stored before credentials
+// were sealed, are sealed with them. Data keys themselves don't change, so
+// credentials don't need resealing. Users whose keys fail to update are
+// logged and counted, and left for the next run.
+func RewrapDataKeys() (RewrapReport, error) {
+	keys, err := appCredentialKeys()
+	if err != nil {
+		return RewrapReport{}, err
+	}
+	if len(keys) == 0 {
+		return RewrapReport{}, fmt.Errorf("APP_CREDENTIALS_SECRETS is not set: %w", apperrors.ErrInvalidInput)
+	}
+	report := RewrapReport{MasterKeyID: keys[0].id}
+	failed := map[string]bool{}
+	for {
+		users, err := repositories.GetUsersWithStaleKeys(report.MasterKeyID, dataKeySealPrefix, rewrapBatchSize)
+		if err != nil {
+			return report, err
+		}
+		progress := false
+		for i := range users {
+			user := &users[i]
+			userId := user.Id.Hex()
+			if failed[userId] {
+				continue
+			}
+			var previous string
+			if user.DataKey != nil {
+				previous = user.DataKey.Wrapped
+			}
+			changed, err := rewrapUserKeys(user, report.MasterKeyID)
+			if err == nil && !changed {
+				continue
+			}
+			if err == nil {
+				err = repositories.UpdateUserKeys(userId, previous, user)
+			}
+			if err != nil {
+				log.Printf("[WARN] Failed to rewrap the keys of user %s: %v", userId, err)
+				failed[userId] = true
+				report.Failed++
+				continue
+			}
+			report.Updated++
+			progress = true
+		}
+		// Users that failed come back in every batch
+		if len(users) < rewrapBatchSize || !progress {
+			return report, nil
+		}
+	}
+}
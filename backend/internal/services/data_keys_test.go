@@ -0,0 +1,192 @@
+package services
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func TestRotateUserDataKey(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	user := &models.User{Id: primitive.NewObjectID()}
+	sealed, err := SealUserSecret(user, "own-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TwitterApp = &models.OAuthApp{ClientID: "own", SealedSecret: sealed}
+	before := *user.DataKey
+
+	if err := RotateUserDataKey(user); err != nil {
+		t.Fatal(err)
+	}
+	if user.DataKey.Version != 2 || user.DataKey.Wrapped == before.Wrapped {
+		t.Fatalf("data key after rotation = %+v", user.DataKey)
+	}
+	if secret, err := OpenUserSecret(user, user.TwitterApp.SealedSecret); err != nil || secret != "own-secret" {
+		t.Errorf("resealed secret = %q, %v", secret, err)
+	}
+	if _, err := OpenUserSecret(user, sealed); err == nil {
+		t.Error("opened a secret sealed with the replaced data key")
+	}
+}
+
+func TestRotateUserDataKeyReseals(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	user := &models.User{Id: primitive.NewObjectID(), DiscordWebhook: &models.DiscordWebhook{ID: "1"}}
+	if err := SetXToken(user, "x-token", "x-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRedditTokens(user, "reddit-access", "reddit-refresh", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SealCredential(user, &user.DiscordWebhook.SealedToken, &user.DiscordWebhook.PlaintextToken, "discord-token"); err != nil {
+		t.Fatal(err)
+	}
+	before := user.DiscordWebhook.SealedToken
+
+	if err := RotateUserDataKey(user); err != nil {
+		t.Fatal(err)
+	}
+	if user.DiscordWebhook.SealedToken == before {
+		t.Error("a credential in a nested struct was not resealed")
+	}
+	for _, want := range []struct{ sealed, secret string }{
+		{user.SealedXOAuthToken, "x-token"},
+		{user.SealedXOAuthSecret, "x-secret"},
+		{user.Reddit.SealedAccessToken, "reddit-access"},
+		{user.Reddit.SealedRefreshToken, "reddit-refresh"},
+		{user.DiscordWebhook.SealedToken, "discord-token"},
+	} {
+		if secret, err := OpenUserSecret(user, want.sealed); err != nil || secret != want.secret {
+			t.Errorf("OpenUserSecret = %q, %v, want %q", secret, err, want.secret)
+		}
+	}
+}
+
+// credentialName matches the names of fields that hold credentials.
+var credentialName = regexp.MustCompile(`(?i)(token|secret|password|apikey|key$|pat$|jwt|session|credential)`)
+
+// notCredentials are fields whose names look like credentials but whose
+// values aren't ones.
+var notCredentials = map[string]bool{
+	// an argon2id hash, which is what is compared at sign in
+	"User.PassWord":          true,
+	"NostrAccount.PublicKey": true,
+}
+
+// TestCredentialFieldsAreSealed fails when a field of a user that holds a
+// credential is not sealed, so it is stored in plain text.
+func TestCredentialFieldsAreSealed(t *testing.T) {
+	seen := map[reflect.Type]bool{}
+	var check func(structType reflect.Type, inSlice bool)
+	check = func(structType reflect.Type, inSlice bool) {
+		if seen[structType] {
+			return
+		}
+		seen[structType] = true
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			name := structType.Name() + "." + field.Name
+			fieldType := field.Type
+			nested := inSlice
+			for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Map {
+				nested = nested || fieldType.Kind() != reflect.Pointer
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				check(fieldType, nested)
+				continue
+			}
+			tag := field.Tag.Get(models.SealTag)
+			if fieldType.Kind() != reflect.String || notCredentials[name] {
+				continue
+			}
+			switch {
+			case tag == "" && credentialName.MatchString(field.Name):
+				t.Errorf("%s holds a credential but is not sealed; tag it seal:%q", name, models.SealUser)
+			case tag != "" && nested:
+				// The walk that seals credentials doesn't go into slices
+				t.Errorf("%s is in a slice or map, where it isn't sealed", name)
+			case tag == models.SealPlaintext:
+				sealed, ok := structType.FieldByName("Sealed" + strings.TrimPrefix(field.Name, "Plaintext"))
+				if !strings.HasPrefix(field.Name, "Plaintext") || !ok || sealed.Tag.Get(models.SealTag) != models.SealUser {
+					t.Errorf("%s has no sealed field beside it", name)
+				}
+			case tag != "" && tag != models.SealUser:
+				t.Errorf("%s has an unknown seal tag %q", name, tag)
+			}
+		}
+	}
+	check(reflect.TypeOf(models.User{}), false)
+}
+
+func TestRewrapUserKeys(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "old-key")
+	user := &models.User{Id: primitive.NewObjectID()}
+	sealed, err := SealUserSecret(user, "twitter-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TwitterApp = &models.OAuthApp{ClientID: "own", SealedSecret: sealed}
+	// Sealed with the master key, as before data keys existed
+	legacy, err := sealWithMasterKey([]byte("linkedin-secret"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.LinkedInApp = &models.OAuthApp{ClientID: "own", SealedSecret: legacy}
+	// Stored before credentials were sealed
+	user.PlaintextXOAuthToken = "x-token"
+	user.Reddit.PlaintextRefreshToken = "reddit-refresh"
+	// Sealed since, so the plaintext one is out of date
+	user.PlaintextLinkedInOauthKey = "old-linkedin-token"
+	if err := SealCredential(user, &user.SealedLinkedInOauthKey, new(string), "linkedin-token"); err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := OpenUserSecret(user, legacy); err != nil || secret != "linkedin-secret" {
+		t.Fatalf("legacy secret = %q, %v", secret, err)
+	}
+
+	t.Setenv("APP_CREDENTIALS_SECRETS", "new-key, old-key")
+	keys, err := appCredentialKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := rewrapUserKeys(user, keys[0].id)
+	if err != nil || !changed {
+		t.Fatalf("rewrapUserKeys = %v, %v", changed, err)
+	}
+	if user.DataKey.MasterKeyID != keys[0].id || user.DataKey.Version != 1 {
+		t.Errorf("data key after rewrap = %+v", user.DataKey)
+	}
+	if user.TwitterApp.SealedSecret != sealed {
+		t.Error("a secret sealed with the data key was resealed")
+	}
+	if !isDataKeySealed(user.LinkedInApp.SealedSecret) {
+		t.Errorf("legacy secret was not resealed: %q", user.LinkedInApp.SealedSecret)
+	}
+	if len(user.CredentialFields(models.SealPlaintext)) != 0 {
+		t.Errorf("plaintext credentials were left: %+v", user.CredentialFields(models.SealPlaintext))
+	}
+	if changed, err := rewrapUserKeys(user, keys[0].id); err != nil || changed {
+		t.Errorf("second rewrapUserKeys = %v, %v", changed, err)
+	}
+
+	// The old master key is no longer needed
+	t.Setenv("APP_CREDENTIALS_SECRETS", "new-key")
+	for _, want := range []struct{ sealed, secret string }{
+		{user.TwitterApp.SealedSecret, "twitter-secret"},
+		{user.LinkedInApp.SealedSecret, "linkedin-secret"},
+		{user.SealedXOAuthToken, "x-token"},
+		{user.Reddit.SealedRefreshToken, "reddit-refresh"},
+		{user.SealedLinkedInOauthKey, "linkedin-token"},
+	} {
+		if secret, err := OpenUserSecret(user, want.sealed); err != nil || secret != want.secret {
+			t.Errorf("OpenUserSecret = %q, %v, want %q", secret, err, want.secret)
+		}
+	}
+}
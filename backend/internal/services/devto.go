@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// maxDevtoTags is how many tags Dev.to takes on an article.
+const maxDevtoTags = 4
+
+// devtoAPI is replaced by tests.
+var devtoAPI = "https://dev.to/api"
+
+var devtoAPIKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,128}$`)
+
+// hashnodeImageAlign matches the alignment Hashnode writes into image links,
+// such as ![](https://cdn.hashnode.com/a.png align="center"), which breaks
+// the links anywhere else.
+var hashnodeImageAlign = regexp.MustCompile(`\s+align="(?:left|center|right)"\)`)
+
+// NormalizeDevtoAPIKey trims the API key a user pasted and checks that it
+// looks like one.
+func NormalizeDevtoAPIKey(raw string) (string, error) {
+	apiKey := strings.TrimSpace(raw)
+	if !devtoAPIKeyPattern.MatchString(apiKey) {
+		return "", fmt.Errorf("api_key must be a Dev.to API key from https://dev.to/settings/extensions: %w", apperrors.ErrInvalidInput)
+	}
+	return apiKey, nil
+}
+
+// devtoCall sends a request to the Dev.to API with the API key and decodes
+// its JSON response into out.
+func devtoCall(userId, apiKey string, req *http.Request, out interface{}) error {
+	req.Header.Set("api-key", apiKey)
+	req.Header.Set("Accept", "application/vnd.forem.api-v1+json")
+	resp, err := getProviderClient(ProviderDevto).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Dev.to: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "devto", req.URL.String(), resp.StatusCode, body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Dev.to throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Dev.to rejected the API key: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("Dev.to doesn't know %s: %w", req.URL.Path, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		var refused struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &refused)
+		return fmt.Errorf("Dev.to refused the article, %s: %w", refused.Error, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Dev.to answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Dev.to: %v", err)
+	}
+	return nil
+}
+
+// LookupDevtoAccount returns the Dev.to account the API key belongs to.
+func LookupDevtoAccount(userId, apiKey string) (*models.DevtoAccount, error) {
+	req, err := http.NewRequest(http.MethodGet, devtoAPI+"/users/me", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var account struct {
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	}
+	if err := devtoCall(userId, apiKey, req, &account); err != nil {
+		return nil, fmt.Errorf("failed to look up the Dev.to account: %w", err)
+	}
+	return &models.DevtoAccount{Username: account.Username, Name: account.Name}, nil
+}
+
+// devtoArticle is the part of a Dev.to article republished blogs set.
+type devtoArticle struct {
+	Title        string   `json:"title"`
+	BodyMarkdown string   `json:"body_markdown"`
+	Published    bool     `json:"published"`
+	CanonicalURL string   `json:"canonical_url"`
+	MainImage    string   `json:"main_image,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// portableMarkdown adapts Hashnode markdown for the sites articles are
+// republished to.
+func portableMarkdown(markdown string) string {
+	return hashnodeImageAlign.ReplaceAllString(markdown, ")")
+}
+
+// devtoTags turns Hashnode tag slugs into Dev.to tags, which are lowercase
+// letters and digits only, keeping the first four.
+func devtoTags(slugs []string) []string {
+	var tags []string
+	for _, slug := range slugs {
+		tag := strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, strings.ToLower(slug))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+		if len(tags) == maxDevtoTags {
+			break
+		}
+	}
+	return tags
+}
+
+// publishDevtoArticle republishes a blog on the user's Dev.to account, or
+// updates the copy with articleID when there is one, and returns the
+// article's id and URL. A copy deleted on Dev.to is published again.
+func publishDevtoArticle(user *models.User, articleID int, article devtoArticle) (int, string, error) {
+	if user.Devto == nil {
+		return 0, "", fmt.Errorf("Dev.to is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	apiKey, err := OpenUserSecret(user, user.Devto.SealedAPIKey)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open the Dev.to API key: %v", err)
+	}
+	payload, err := json.Marshal(map[string]interface{}{"article": article})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to marshal article: %v", err)
+	}
+	userId := user.Id.Hex()
+	send := func(method, endpoint string) (int, string, error) {
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		var published struct {
+			ID  int    `json:"id"`
+			URL string `json:"url"`
+		}
+		if err := devtoCall(userId, apiKey, req, &published); err != nil {
+			return 0, "", err
+		}
+		return published.ID, published.URL, nil
+	}
+	if articleID != 0 {
+		id, articleURL, err := send(http.MethodPut, devtoAPI+"/articles/"+strconv.Itoa(articleID))
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return id, articleURL, err
+		}
+	}
+	return send(http.MethodPost, devtoAPI+"/articles")
+}
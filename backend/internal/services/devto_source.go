@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// DevtoBlogPrefix starts the blog id of a Dev.to article, keeping it apart
+// from Hashnode post ids wherever blogs are shared, scheduled or recorded.
+const DevtoBlogPrefix = "devto:"
+
+// maxDevtoSourceArticles caps the articles listed from Dev.to.
+const maxDevtoSourceArticles = 100
+
+// DevtoBlogID is the blog id of the Dev.to article.
+func DevtoBlogID(articleID int) string {
+	return DevtoBlogPrefix + strconv.Itoa(articleID)
+}
+
+// DevtoArticleID returns the article id of a Dev.to blog id, and whether
+// blogId is one.
+func DevtoArticleID(blogId string) (int, bool) {
+	id, found := strings.CutPrefix(blogId, DevtoBlogPrefix)
+	if !found {
+		return 0, false
+	}
+	articleID, err := strconv.Atoi(id)
+	return articleID, err == nil && articleID > 0
+}
+
+// devtoSourceArticle is a Dev.to article as the API returns it.
+type devtoSourceArticle struct {
+	ID                 int    `json:"id"`
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	URL                string `json:"url"`
+	CanonicalURL       string `json:"canonical_url"`
+	CoverImage         string `json:"cover_image"`
+	SocialImage        string `json:"social_image"`
+	PublishedAt        string `json:"published_at"`
+	ReadingTimeMinutes int    `json:"reading_time_minutes"`
+	BodyHTML           string `json:"body_html"`
+	BodyMarkdown       string `json:"body_markdown"`
+	// Tags is only set on single articles. Their tag_list is a string,
+	// but a list in article lists, so it isn't read.
+	Tags []string `json:"tags"`
+	User struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+func devtoAPIKey(user *models.User) (string, error) {
+	if user.Devto == nil {
+		return "", fmt.Errorf("Dev.to is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	apiKey, err := OpenUserSecret(user, user.Devto.SealedAPIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Dev.to API key: %v", err)
+	}
+	return apiKey, nil
+}
+
+// FetchDevtoArticles lists the user's published Dev.to articles, newest
+// first, as blogs to share. Articles republished from a blog whose posts
+// are listed under skipCanonical are left out, so a blog SocialScribe
+// republishes to Dev.to isn't listed twice.
+func FetchDevtoArticles(user *models.User, skipCanonical map[string]bool) ([]models.PostNode, error) {
+	apiKey, err := devtoAPIKey(user)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, devtoAPI+"/articles/me/published?per_page="+strconv.Itoa(maxDevtoSourceArticles), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var articles []devtoSourceArticle
+	if err := devtoCall(user.Id.Hex(), apiKey, req, &articles); err != nil {
+		return nil, fmt.Errorf("failed to list the Dev.to articles: %w", err)
+	}
+	posts := []models.PostNode{}
+	for _, article := range articles {
+		if article.CanonicalURL != "" && article.CanonicalURL != article.URL && skipCanonical[article.CanonicalURL] {
+			continue
+		}
+		posts = append(posts, models.PostNode{
+			Title:             article.Title,
+			URL:               article.URL,
+			ID:                DevtoBlogID(article.ID),
+			CoverImage:        models.CoverImage{URL: article.CoverImage},
+			Author:            models.Author{Name: article.User.Name},
+			ReadTimeInMinutes: article.ReadingTimeMinutes,
+			PublishedAt:       article.PublishedAt,
+			Brief:             article.Description,
+			Source:            "devto",
+		})
+	}
+	return posts, nil
+}
+
+// fetchDevtoPost fetches a Dev.to article of the user's to share it.
+// Articles of other Dev.to accounts are refused.
+func fetchDevtoPost(user *models.User, articleID int) (*sourcePost, error) {
+	apiKey, err := devtoAPIKey(user)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, devtoAPI+"/articles/"+strconv.Itoa(articleID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var article devtoSourceArticle
+	if err := devtoCall(user.Id.Hex(), apiKey, req, &article); err != nil {
+		return nil, fmt.Errorf("failed to fetch the Dev.to article: %w", err)
+	}
+	if !strings.EqualFold(article.User.Username, user.Devto.Username) {
+		return nil, fmt.Errorf("the Dev.to article isn't yours: %w", apperrors.ErrForbidden)
+	}
+	return &sourcePost{
+		Id:          DevtoBlogID(article.ID),
+		Title:       article.Title,
+		Url:         article.URL,
+		CoverImage:  article.CoverImage,
+		SocialImage: article.SocialImage,
+		Author:      article.User.Name,
+		ReadTime:    article.ReadingTimeMinutes,
+		Brief:       article.Description,
+		// Dev.to has no plain text of an article
+		Text:     article.BodyMarkdown,
+		HTML:     article.BodyHTML,
+		Markdown: article.BodyMarkdown,
+		Tags:     article.Tags,
+	}, nil
+}
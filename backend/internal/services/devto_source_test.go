@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestDevtoSource(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("api-key") != "devtoapikey123":
+			http.Error(w, `{"error": "unauthorized", "status": 401}`, http.StatusUnauthorized)
+		case r.URL.Path == "/articles/me/published":
+			w.Write([]byte(`[
+				{"id": 11, "title": "Queues", "url": "https://dev.to/ada/queues", "canonical_url": "https://dev.to/ada/queues", "description": "On queues", "cover_image": "https://cdn.example.com/q.png", "reading_time_minutes": 4, "published_at": "2026-10-01T09:00:00Z", "tag_list": ["go"], "user": {"name": "Ada", "username": "ada"}},
+				{"id": 12, "title": "Scheduling", "url": "https://dev.to/ada/scheduling", "canonical_url": "https://blog.example.com/scheduling", "user": {"name": "Ada", "username": "ada"}}
+			]`))
+		case r.URL.Path == "/articles/11":
+			w.Write([]byte(`{"id": 11, "title": "Queues", "url": "https://dev.to/ada/queues", "description": "On queues", "social_image": "https://cdn.example.com/social.png", "reading_time_minutes": 4, "body_html": "<p>Queues</p>", "body_markdown": "Queues", "tag_list": "go, backend", "tags": ["go", "backend"], "user": {"name": "Ada", "username": "ada"}}`))
+		case r.URL.Path == "/articles/13":
+			w.Write([]byte(`{"id": 13, "title": "Someone else's", "url": "https://dev.to/grace/other", "user": {"name": "Grace", "username": "grace"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := devtoAPI
+	devtoAPI = server.URL
+	defer func() { devtoAPI = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	sealed, err := SealUserSecret(user, "devtoapikey123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Devto = &models.DevtoAccount{Username: "ada", Name: "Ada", SealedAPIKey: sealed}
+
+	// The copy republished from the Hashnode blog isn't listed again
+	posts, err := FetchDevtoArticles(user, map[string]bool{"https://blog.example.com/scheduling": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != "devto:11" || posts[0].Source != "devto" || posts[0].Brief != "On queues" || posts[0].ReadTimeInMinutes != 4 {
+		t.Fatalf("listed %+v", posts)
+	}
+
+	articleID, ok := DevtoArticleID(posts[0].ID)
+	if !ok || articleID != 11 {
+		t.Fatalf("DevtoArticleID(%q) = %d, %t", posts[0].ID, articleID, ok)
+	}
+	if _, ok := DevtoArticleID("65f1c2a9e4b0a1b2c3d4e5f6"); ok {
+		t.Error("a Hashnode post id was taken for a Dev.to article")
+	}
+	post, err := fetchSourcePost(user, posts[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Id != "devto:11" || post.Author != "Ada" || post.SocialImage != "https://cdn.example.com/social.png" || !reflect.DeepEqual(post.Tags, []string{"go", "backend"}) {
+		t.Errorf("fetched %+v", post)
+	}
+	if _, err := fetchSourcePost(user, DevtoBlogID(13)); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("shared another account's article: %v", err)
+	}
+	if err := devtoPlatform.ValidateContent(&Share{BlogID: post.Id, Markdown: post.Markdown}); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a Dev.to article was republished to Dev.to: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestDevtoTagsAndMarkdown(t *testing.T) {
+	tags := devtoTags([]string{"Go-Lang", "web-dev", "golang", "c++", "ai", "testing"})
+	if want := []string{"golang", "webdev", "c", "ai"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("devtoTags = %v, want %v", tags, want)
+	}
+	markdown := portableMarkdown(`![Cover](https://cdn.hashnode.com/a.png align="center") and [a link](https://example.com)`)
+	if want := `![Cover](https://cdn.hashnode.com/a.png) and [a link](https://example.com)`; markdown != want {
+		t.Errorf("portableMarkdown = %q, want %q", markdown, want)
+	}
+	if _, err := NormalizeDevtoAPIKey(" key with spaces "); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted a malformed API key: %v", err)
+	}
+}
+
+func TestPublishDevtoArticle(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var requests []string
+	var posted map[string]devtoArticle
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Header.Get("api-key") != "devtoapikey123":
+			http.Error(w, `{"error": "unauthorized", "status": 401}`, http.StatusUnauthorized)
+		case r.URL.Path == "/users/me":
+			w.Write([]byte(`{"username": "ada", "name": "Ada"}`))
+		case r.URL.Path == "/articles/7":
+			http.Error(w, `{"error": "not found", "status": 404}`, http.StatusNotFound)
+		default:
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"id": 42, "url": "https://dev.to/ada/scheduling-posts"}`))
+		}
+	}))
+	defer server.Close()
+	previous := devtoAPI
+	devtoAPI = server.URL
+	defer func() { devtoAPI = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	if _, _, err := publishDevtoArticle(user, 0, devtoArticle{}); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Fatalf("published without an account: %v", err)
+	}
+	account, err := LookupDevtoAccount("", "devtoapikey123")
+	if err != nil || account.Username != "ada" {
+		t.Fatalf("looked up %+v, %v", account, err)
+	}
+	if account.SealedAPIKey, err = SealUserSecret(user, "devtoapikey123"); err != nil {
+		t.Fatal(err)
+	}
+	user.Devto = account
+
+	article := devtoArticle{Title: "Scheduling posts", BodyMarkdown: "# Hello", Published: true, CanonicalURL: "https://blog.example.com/scheduling"}
+	id, articleURL, err := publishDevtoArticle(user, 0, article)
+	if err != nil || id != 42 || articleURL != "https://dev.to/ada/scheduling-posts" {
+		t.Fatalf("published %d at %q, %v", id, articleURL, err)
+	}
+	if posted["article"].CanonicalURL != article.CanonicalURL {
+		t.Errorf("sent article %+v", posted["article"])
+	}
+
+	// A copy deleted on Dev.to is published again
+	requests = nil
+	if id, _, err := publishDevtoArticle(user, 7, article); err != nil || id != 42 {
+		t.Fatalf("republished %d, %v", id, err)
+	}
+	if want := []string{"PUT /articles/7", "POST /articles"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	if _, err := LookupDevtoAccount("", "wrongapikey123"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up with a wrong key: %v", err)
+	}
+}
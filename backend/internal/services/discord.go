@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	// discordEmbedColor is the accent bar of announcement embeds.
+	discordEmbedColor          = 0x2962FF
+	maxDiscordEmbedTitle       = 256
+	maxDiscordEmbedDescription = 4096
+)
+
+// discordAPI is replaced by tests.
+var discordAPI = "https://discord.com/api"
+
+// discordWebhookPattern matches the webhook URLs Discord hands out, on any
+// of its hosts and with or without an API version.
+var discordWebhookPattern = regexp.MustCompile(`^https://(?:(?:canary|ptb)\.)?discord(?:app)?\.com/api/(?:v\d+/)?webhooks/(\d{17,20})/([A-Za-z0-9_-]{60,100})/?$`)
+
+// ParseDiscordWebhookURL splits a Discord webhook URL into the webhook's id
+// and token. Only Discord's own hosts are accepted, so announcements can't
+// be pointed anywhere else.
+func ParseDiscordWebhookURL(raw string) (string, string, error) {
+	match := discordWebhookPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if match == nil {
+		return "", "", fmt.Errorf("webhook URL must be a Discord webhook URL such as https://discord.com/api/webhooks/<id>/<token>: %w", apperrors.ErrInvalidInput)
+	}
+	return match[1], match[2], nil
+}
+
+func discordWebhookURL(webhook *models.DiscordWebhook, token string) string {
+	return discordAPI + "/webhooks/" + webhook.ID + "/" + token
+}
+
+// discordCall sends a request to a Discord webhook, whose token is in the
+// request's path, and decodes its JSON response into out, if given.
+func discordCall(userId, token string, req *http.Request, out interface{}) error {
+	resp, err := getProviderClient(ProviderDiscord).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Discord: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	// The token is part of the path, so it stays out of the archive
+	endpoint := strings.Replace(req.URL.String(), "/"+token, "/[REDACTED]", 1)
+	archiveProviderResponse(userId, "discord", endpoint, resp.StatusCode, body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.Unmarshal(body, &limited)
+		return fmt.Errorf("Discord asks to retry in %.1fs: %w", limited.RetryAfter, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("Discord doesn't know the webhook anymore: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Discord answered %s", resp.Status)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Discord: %v", err)
+	}
+	return nil
+}
+
+// LookupDiscordWebhook checks with Discord that the webhook with the token
+// exists and fills in its name and channel.
+func LookupDiscordWebhook(userId string, webhook *models.DiscordWebhook, token string) error {
+	req, err := http.NewRequest(http.MethodGet, discordWebhookURL(webhook, token), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	var found struct {
+		Name      string `json:"name"`
+		ChannelID string `json:"channel_id"`
+		GuildID   string `json:"guild_id"`
+	}
+	if err := discordCall(userId, token, req, &found); err != nil {
+		return fmt.Errorf("failed to look up the Discord webhook: %w", err)
+	}
+	webhook.Name = found.Name
+	webhook.ChannelID = found.ChannelID
+	webhook.GuildID = found.GuildID
+	return nil
+}
+
+// discordEmbed is the part of Discord's embed object announcements use.
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Author      *discordEmbedAuthor `json:"author,omitempty"`
+	Image       *discordEmbedImage  `json:"image,omitempty"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+type discordEmbedAuthor struct {
+	Name string `json:"name"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// discordAnnouncement is the embed announcing a blog.
+func discordAnnouncement(title, link, caption, author, imageURL string, readTime int) discordEmbed {
+	embed := discordEmbed{
+		Title:       truncateRunes(title, maxDiscordEmbedTitle),
+		URL:         link,
+		Description: truncateRunes(strings.TrimSpace(caption), maxDiscordEmbedDescription),
+		Color:       discordEmbedColor,
+	}
+	if author != "" {
+		embed.Author = &discordEmbedAuthor{Name: truncateRunes(author, maxDiscordEmbedTitle)}
+	}
+	if imageURL != "" {
+		embed.Image = &discordEmbedImage{URL: imageURL}
+	}
+	if readTime > 0 {
+		embed.Fields = []discordEmbedField{{Name: "Read time", Value: fmt.Sprintf("%d min", readTime), Inline: true}}
+	}
+	return embed
+}
+
+// postDiscordMessage posts to the user's webhook and returns the message's
+// URL. Mentions in the text are never pinged.
+func postDiscordMessage(user *models.User, content string, embeds []discordEmbed) (string, error) {
+	webhook := user.DiscordWebhook
+	if webhook == nil {
+		return "", fmt.Errorf("Discord is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	token, err := OpenCredential(user, webhook.SealedToken, webhook.PlaintextToken)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"content":          content,
+		"embeds":           embeds,
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message: %v", err)
+	}
+	// wait makes Discord return the message, which tells where it went
+	req, err := http.NewRequest(http.MethodPost, discordWebhookURL(webhook, token)+"?wait=true", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var message struct {
+		ID        string `json:"id"`
+		ChannelID string `json:"channel_id"`
+	}
+	if err := discordCall(user.Id.Hex(), token, req, &message); err != nil {
+		return "", fmt.Errorf("failed to post to Discord: %w", err)
+	}
+	if webhook.GuildID == "" || message.ID == "" {
+		return "", nil
+	}
+	return "https://discord.com/channels/" + webhook.GuildID + "/" + message.ChannelID + "/" + message.ID, nil
+}
+
+// PingDiscordWebhook posts a short message confirming the user's webhook
+// works.
+func PingDiscordWebhook(user *models.User) (string, error) {
+	return postDiscordMessage(user, "SocialScribe is connected. New posts will be announced here.", nil)
+}
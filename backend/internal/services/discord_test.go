@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const testDiscordToken = "aBcDeFgHiJkLmNoPqRsTuVwXyZ0123456789_-aBcDeFgHiJkLmNoPqRsTuVwXyZ01"
+
+func TestParseDiscordWebhookURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://discord.com/api/webhooks/123456789012345678/" + testDiscordToken:              true,
+		" https://discordapp.com/api/v10/webhooks/123456789012345678/" + testDiscordToken:      true,
+		"https://ptb.discord.com/api/webhooks/123456789012345678/" + testDiscordToken + "/":    true,
+		"http://discord.com/api/webhooks/123456789012345678/" + testDiscordToken:               false,
+		"https://discord.com.evil.example/api/webhooks/123456789012345678/" + testDiscordToken: false,
+		"https://discord.com/api/webhooks/123456789012345678":                                  false,
+		"https://discord.com/api/webhooks/abc/" + testDiscordToken:                             false,
+		"": false,
+	} {
+		id, token, err := ParseDiscordWebhookURL(raw)
+		if !ok {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("ParseDiscordWebhookURL(%q) = %q, %v; want invalid input", raw, id, err)
+			}
+			continue
+		}
+		if err != nil || id != "123456789012345678" || token != testDiscordToken {
+			t.Errorf("ParseDiscordWebhookURL(%q) = %q, %q, %v", raw, id, token, err)
+		}
+	}
+}
+
+func TestDiscordAnnouncement(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/webhooks/123456789012345678/"+testDiscordToken:
+			http.Error(w, `{"message": "Unknown Webhook", "code": 10015}`, http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"id": "123456789012345678", "name": "Blog", "channel_id": "22", "guild_id": "11", "token": "` + testDiscordToken + `"}`))
+		case r.URL.Query().Get("wait") != "true":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"id": "33", "channel_id": "22"}`))
+		}
+	}))
+	defer server.Close()
+	previous := discordAPI
+	discordAPI = server.URL
+	defer func() { discordAPI = previous }()
+
+	webhook := &models.DiscordWebhook{ID: "123456789012345678"}
+	if err := LookupDiscordWebhook("", webhook, testDiscordToken); err != nil || webhook.Name != "Blog" || webhook.GuildID != "11" {
+		t.Fatalf("looked up %+v, %v", webhook, err)
+	}
+	user := &models.User{Id: primitive.NewObjectID(), DiscordWebhook: webhook}
+	if err := SealCredential(user, &webhook.SealedToken, &webhook.PlaintextToken, testDiscordToken); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := discordAnnouncement("Scheduling posts", "https://blog.example.com/scheduling", "Read @everyone", "Ada", "https://cdn.example.com/cover.png", 7)
+	messageURL, err := postDiscordMessage(user, "", []discordEmbed{embed})
+	if err != nil || messageURL != "https://discord.com/channels/11/22/33" {
+		t.Fatalf("posted to %q, %v", messageURL, err)
+	}
+	embeds := posted["embeds"].([]interface{})
+	sent := embeds[0].(map[string]interface{})
+	if sent["title"] != "Scheduling posts" || sent["image"].(map[string]interface{})["url"] != "https://cdn.example.com/cover.png" {
+		t.Errorf("sent embed %v", sent)
+	}
+	if fields := sent["fields"].([]interface{}); fields[0].(map[string]interface{})["value"] != "7 min" {
+		t.Errorf("sent fields %v", fields)
+	}
+	if mentions := posted["allowed_mentions"].(map[string]interface{}); len(mentions["parse"].([]interface{})) != 0 {
+		t.Errorf("mentions are pinged: %v", mentions)
+	}
+
+	long := discordAnnouncement(strings.Repeat("t", 300), "https://blog.example.com", "", "", "", 0)
+	if len([]rune(long.Title)) != maxDiscordEmbedTitle || long.Fields != nil || long.Image != nil {
+		t.Errorf("long title embed %+v", long)
+	}
+
+	// A token stored before tokens were sealed is still used
+	deleted := &models.User{DiscordWebhook: &models.DiscordWebhook{ID: "999999999999999999", PlaintextToken: testDiscordToken}}
+	if _, err := PingDiscordWebhook(deleted); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("deleted webhook: %v, want unauthorized", err)
+	}
+	if _, err := postDiscordMessage(&models.User{}, "hi", nil); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("not connected: %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+// EmailConfigured reports whether outgoing email is set up. SMTP_HOST and
+// SMTP_FROM are required; SMTP_USERNAME and SMTP_PASSWORD enable auth and
+// SMTP_PORT defaults to 587.
+func EmailConfigured() bool {
+	return os.Getenv("SMTP_HOST") != "" && os.Getenv("SMTP_FROM") != ""
+}
+
+// smtpSendMail is replaced by tests.
+var smtpSendMail = smtp.SendMail
+
+// SendEmail sends a plain-text email.
+func SendEmail(to, subject, body string) error {
+	return sendEmailMessage(emailMessage{To: to, Subject: subject, Body: body})
+}
+
+// emailMessage is a plain-text email with optional extra headers.
+type emailMessage struct {
+	To      string
+	Subject string
+	Body    string
+	// FromName replaces the display name of SMTP_FROM.
+	FromName string
+	Headers  map[string]string
+}
+
+func sendEmailMessage(message emailMessage) error {
+	if !EmailConfigured() {
+		return fmt.Errorf("email is not configured")
+	}
+	headerValues := message.To + message.Subject + message.FromName
+	for name, value := range message.Headers {
+		headerValues += name + value
+	}
+	if strings.ContainsAny(headerValues, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	fromHeader := from
+	if message.FromName != "" {
+		if address, err := mail.ParseAddress(from); err == nil {
+			fromHeader = (&mail.Address{Name: message.FromName, Address: address.Address}).String()
+		}
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	names := make([]string, 0, len(message.Headers))
+	for name := range message.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var extra strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&extra, "%s: %s\r\n", name, message.Headers[name])
+	}
+	raw := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n%sMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		fromHeader, message.To, message.Subject, time.Now().UTC().Format(time.RFC1123Z), extra.String(), strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return smtpSendMail(net.JoinHostPort(host, port), auth, from, []string{message.To}, []byte(raw))
+}
+
+var shareReceiptTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"platformName": PlatformTitle,
+}).Parse(`Your scheduled share of "{{.BlogTitle}}" went out at {{.DeliveredAt.UTC.Format "Jan 2, 2006 15:04 MST"}}.
+
+{{range .Deliveries}}{{platformName .Platform}}: {{if .PostURL}}{{.PostURL}}{{else}}posted, no link was returned{{end}}
+{{end}}
+Blog post: {{.BlogURL}}
+
+What was posted:
+{{.Caption}}
+
+You get this email because scheduled share emails are on in your notification preferences.
+`))
+
+// RenderShareReceiptEmail renders the confirmation email of a scheduled share.
+func RenderShareReceiptEmail(receipt *models.DeliveryReceipt) (string, string, error) {
+	var body bytes.Buffer
+	if err := shareReceiptTemplate.Execute(&body, receipt); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("Shared: %s", receipt.BlogTitle), body.String(), nil
+}
+
+// SendShareReceiptEmail emails the receipt of a scheduled share to the user
+// if they opted in and have a verified address. Without email configured it
+// does nothing.
+func SendShareReceiptEmail(user *models.User, receipt *models.DeliveryReceipt) {
+	if !user.Preferences.ScheduledShareEmail || user.Email == "" || !user.EmailVerified || receipt == nil {
+		return
+	}
+	if !EmailConfigured() {
+		log.Printf("[WARN] Not sending the share receipt to user %s: email is not configured", user.Id.Hex())
+		return
+	}
+	subject, body, err := RenderShareReceiptEmail(receipt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to render the share receipt for user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	if err := SendEmail(user.Email, strings.ReplaceAll(subject, "\n", " "), body); err != nil {
+		log.Printf("[ERROR] Failed to email the share receipt to user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	log.Printf("[INFO] Emailed the share receipt of blog %s to user %s", receipt.BlogID, user.Id.Hex())
+}
+
+var manualTaskTemplate = template.Must(template.New("manual").Parse(`It's time to post "{{.Task.BlogTitle}}" on {{.PlatformName}}. {{.PlatformName}} can't be posted to for you, so here is what to post.
+
+Caption, to copy:
+
+{{.Task.Caption}}
+
+{{if .Task.ImageType}}Link for your bio: {{.Task.BlogURL}}
+
+The card image is in your manual tasks, where you can mark the post done once it is up.
+{{else}}Mark the post done in your manual tasks once it is up.
+{{end}}`))
+
+// RenderManualTaskEmail renders the reminder of a manual task. The
+// platform's name is passed in, as the platforms making manual tasks can't
+// look themselves up while the registry is built.
+func RenderManualTaskEmail(task *models.ManualTask, platformName string) (string, string, error) {
+	data := struct {
+		Task         *models.ManualTask
+		PlatformName string
+	}{task, platformName}
+	var body bytes.Buffer
+	if err := manualTaskTemplate.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("Ready to post: %s", task.BlogTitle), body.String(), nil
+}
+
+// SendManualTaskEmail emails the reminder of a manual task to the user if
+// they have a verified address. Without email configured it does nothing.
+func SendManualTaskEmail(user *models.User, task *models.ManualTask, platformName string) {
+	if user.Email == "" || !user.EmailVerified {
+		return
+	}
+	if !EmailConfigured() {
+		log.Printf("[WARN] Not sending the manual task reminder to user %s: email is not configured", user.Id.Hex())
+		return
+	}
+	subject, body, err := RenderManualTaskEmail(task, platformName)
+	if err != nil {
+		log.Printf("[ERROR] Failed to render the manual task reminder for user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	if err := SendEmail(user.Email, strings.ReplaceAll(subject, "\n", " "), body); err != nil {
+		log.Printf("[ERROR] Failed to email the manual task reminder to user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	log.Printf("[INFO] Emailed the %s reminder of blog %s to user %s", task.Platform, task.BlogID, user.Id.Hex())
+}
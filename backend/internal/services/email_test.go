@@ -0,0 +1,42 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestRenderShareReceiptEmail(t *testing.T) {
+	receipt := &models.DeliveryReceipt{
+		BlogID:    "post-1",
+		BlogTitle: "Scheduling posts with Go",
+		BlogURL:   "https://blog.example.com/scheduling",
+		Caption:   "New post: how we schedule shares",
+		Deliveries: []models.PlatformDelivery{
+			{Platform: "twitter", PostURL: "https://twitter.com/ada/status/1"},
+			{Platform: "linkedin"},
+		},
+		DeliveredAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	subject, body, err := RenderShareReceiptEmail(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Shared: Scheduling posts with Go" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{
+		"went out at May 1, 2024 09:30 UTC",
+		"X (Twitter): https://twitter.com/ada/status/1",
+		"LinkedIn: posted, no link was returned",
+		"Blog post: https://blog.example.com/scheduling",
+		"New post: how we schedule shares",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+}
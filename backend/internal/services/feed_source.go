@@ -0,0 +1,429 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// FeedBlogPrefix starts the blog id of a feed item, keeping it apart from
+// Hashnode post ids and Dev.to blog ids.
+const FeedBlogPrefix = "feed:"
+
+// FeedPollInterval is how often feeds are polled for new items.
+const FeedPollInterval = 30 * time.Minute
+
+const (
+	// maxFeedSize caps how much of a feed is read.
+	maxFeedSize = 5 << 20
+	// maxFeedItems caps the items taken from a feed, newest first as feeds
+	// list them.
+	maxFeedItems = 100
+	// maxFeedRedirects caps the redirects followed to a feed.
+	maxFeedRedirects = 5
+	// maxFeedBrief is how many characters of an item's text make its brief.
+	maxFeedBrief = 250
+	// feedWordsPerMinute estimates read times, which feeds don't carry.
+	feedWordsPerMinute = 200
+)
+
+var (
+	// checkFeedHost keeps feed fetches off internal addresses, redirects
+	// included. Tests replace it to reach local servers.
+	checkFeedHost = checkPublicHost
+	feedClient    = &http.Client{
+		Transport: outboundTransport,
+		Timeout:   20 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFeedRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFeedRedirects)
+			}
+			return checkFeedHost(req.URL.Hostname())
+		},
+	}
+	feedHTMLTag = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// FeedBlogID is the blog id of the feed item with the guid, or link for
+// items without one. Guids can be long URLs, so they are hashed.
+func FeedBlogID(guid string) string {
+	sum := sha256.Sum256([]byte(guid))
+	return FeedBlogPrefix + hex.EncodeToString(sum[:12])
+}
+
+// IsFeedBlogID reports whether blogId is the id of a feed item.
+func IsFeedBlogID(blogId string) bool {
+	return strings.HasPrefix(blogId, FeedBlogPrefix)
+}
+
+// ValidateFeedURL checks that a feed URL is http(s) and points at a public
+// host, and returns it trimmed.
+func ValidateFeedURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" || parsed.User != nil {
+		return "", fmt.Errorf("feed URL must be an http(s) URL: %w", apperrors.ErrInvalidInput)
+	}
+	if err := checkFeedHost(parsed.Hostname()); err != nil {
+		return "", fmt.Errorf("feed URL must point at a public host: %w", apperrors.ErrInvalidInput)
+	}
+	return rawURL, nil
+}
+
+// feedDocument is an RSS 2.0 or Atom feed; only the fields of its format
+// are set.
+type feedDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string        `xml:"title"`
+		Items []feedRSSItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string          `xml:"title"`
+	Entries []feedAtomEntry `xml:"entry"`
+}
+
+type feedMedia struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+type feedRSSItem struct {
+	Title       string      `xml:"title"`
+	Link        string      `xml:"link"`
+	GUID        string      `xml:"guid"`
+	PubDate     string      `xml:"pubDate"`
+	Description string      `xml:"description"`
+	Content     string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string      `xml:"author"`
+	Creator     string      `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string    `xml:"category"`
+	Enclosure   feedMedia   `xml:"enclosure"`
+	Media       []feedMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnail   feedMedia   `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type feedAtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// HTML is the text as HTML: xhtml content is markup already, and text and
+// html content is its character data.
+func (t feedAtomText) HTML() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	if t.Type == "html" {
+		return strings.TrimSpace(t.Text)
+	}
+	return html.EscapeString(strings.TrimSpace(t.Text))
+}
+
+type feedAtomEntry struct {
+	Title string `xml:"title"`
+	ID    string `xml:"id"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Summary   feedAtomText `xml:"summary"`
+	Content   feedAtomText `xml:"content"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	Thumbnail feedMedia `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// feedItem is an item of an RSS or Atom feed.
+type feedItem struct {
+	Id          string
+	GUID        string
+	Title       string
+	URL         string
+	Author      string
+	PublishedAt time.Time
+	Summary     string
+	HTML        string
+	Image       string
+	Tags        []string
+}
+
+// Text is the item's text without markup.
+func (i feedItem) Text() string {
+	body := i.HTML
+	if body == "" {
+		body = i.Summary
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(feedHTMLTag.ReplaceAllString(body, " "))), " ")
+}
+
+// Brief is the item's summary, or the start of its text, without markup.
+func (i feedItem) Brief() string {
+	brief := strings.Join(strings.Fields(html.UnescapeString(feedHTMLTag.ReplaceAllString(i.Summary, " "))), " ")
+	if brief == "" {
+		brief = i.Text()
+	}
+	if utf8.RuneCountInString(brief) > maxFeedBrief {
+		brief = string([]rune(brief)[:maxFeedBrief-1]) + "…"
+	}
+	return brief
+}
+
+// ReadTime estimates the minutes the item takes to read.
+func (i feedItem) ReadTime() int {
+	words := len(strings.Fields(i.Text()))
+	return max(1, (words+feedWordsPerMinute-1)/feedWordsPerMinute)
+}
+
+// feedTimeLayouts are the date formats found in feeds: RFC 822 dates in
+// RSS, with and without the weekday and seconds, and RFC 3339 in Atom.
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	time.RFC3339,
+}
+
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// feedCharsetReader reads feeds declared in Latin-1 as UTF-8; UTF-8 feeds
+// need no reader. Windows-1252 is read as Latin-1, which it only differs
+// from in rarely used punctuation.
+func feedCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "us-ascii", "windows-1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported feed encoding %q", charset)
+}
+
+// parseFeed reads the title and items of an RSS 2.0 or Atom feed. Items
+// without a link are left out, as there would be nothing to share.
+func parseFeed(data []byte) (string, []feedItem, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = feedCharsetReader
+	// Feeds use HTML entities such as &nbsp; in their markup
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	var doc feedDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return "", nil, fmt.Errorf("not an RSS or Atom feed: %v: %w", err, apperrors.ErrInvalidInput)
+	}
+
+	var title string
+	var items []feedItem
+	switch doc.XMLName.Local {
+	case "rss":
+		title = doc.Channel.Title
+		for _, entry := range doc.Channel.Items {
+			item := feedItem{
+				Title:       strings.TrimSpace(entry.Title),
+				URL:         strings.TrimSpace(entry.Link),
+				Author:      strings.TrimSpace(entry.Creator),
+				PublishedAt: parseFeedTime(entry.PubDate),
+				Summary:     strings.TrimSpace(entry.Description),
+				HTML:        strings.TrimSpace(entry.Content),
+				Tags:        entry.Categories,
+			}
+			if item.Author == "" {
+				item.Author = strings.TrimSpace(entry.Author)
+			}
+			guid := strings.TrimSpace(entry.GUID)
+			if guid == "" {
+				guid = item.URL
+			}
+			item.GUID = guid
+			item.Id = FeedBlogID(guid)
+			for _, media := range append([]feedMedia{entry.Enclosure}, entry.Media...) {
+				if media.URL != "" && (strings.HasPrefix(media.Type, "image/") || media.Medium == "image") {
+					item.Image = media.URL
+					break
+				}
+			}
+			if item.Image == "" {
+				item.Image = entry.Thumbnail.URL
+			}
+			items = append(items, item)
+		}
+	case "feed":
+		title = doc.Title
+		for _, entry := range doc.Entries {
+			item := feedItem{
+				Title:   strings.TrimSpace(entry.Title),
+				Author:  strings.TrimSpace(entry.Author.Name),
+				Summary: entry.Summary.HTML(),
+				HTML:    entry.Content.HTML(),
+				Image:   entry.Thumbnail.URL,
+			}
+			for _, link := range entry.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					item.URL = strings.TrimSpace(link.Href)
+					break
+				}
+			}
+			item.PublishedAt = parseFeedTime(entry.Published)
+			if item.PublishedAt.IsZero() {
+				item.PublishedAt = parseFeedTime(entry.Updated)
+			}
+			for _, category := range entry.Categories {
+				item.Tags = append(item.Tags, category.Term)
+			}
+			guid := strings.TrimSpace(entry.ID)
+			if guid == "" {
+				guid = item.URL
+			}
+			item.GUID = guid
+			item.Id = FeedBlogID(guid)
+			items = append(items, item)
+		}
+	default:
+		return "", nil, fmt.Errorf("not an RSS or Atom feed: %w", apperrors.ErrInvalidInput)
+	}
+
+	kept := items[:0]
+	for _, item := range items {
+		if item.URL == "" || len(kept) == maxFeedItems {
+			continue
+		}
+		if item.Title == "" {
+			item.Title = item.URL
+		}
+		kept = append(kept, item)
+	}
+	return strings.TrimSpace(title), kept, nil
+}
+
+// fetchFeed downloads and parses the feed at feedURL.
+func fetchFeed(feedURL string) (string, []feedItem, error) {
+	if _, err := ValidateFeedURL(feedURL); err != nil {
+		return "", nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, feedURL, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8")
+	req.Header.Set("User-Agent", "SocialScribe feed reader")
+	resp, err := feedClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch the feed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", nil, fmt.Errorf("the feed's host throttled the fetch: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return "", nil, fmt.Errorf("there is no feed at %s: %w", feedURL, apperrors.ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetching the feed returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the feed: %v", err)
+	}
+	if len(data) > maxFeedSize {
+		return "", nil, fmt.Errorf("the feed is larger than %d MB: %w", maxFeedSize>>20, apperrors.ErrInvalidInput)
+	}
+	return parseFeed(data)
+}
+
+// FetchFeed downloads the feed at feedURL and returns its title and its
+// items as posts to list, stamped syncedAt.
+func FetchFeed(feedURL string, syncedAt time.Time) (string, []models.Post, error) {
+	title, items, err := fetchFeed(feedURL)
+	if err != nil {
+		return "", nil, err
+	}
+	posts := make([]models.Post, 0, len(items))
+	for _, item := range items {
+		posts = append(posts, models.Post{
+			Id:                item.Id,
+			Title:             item.Title,
+			Url:               item.URL,
+			CoverImageURL:     item.Image,
+			AuthorName:        item.Author,
+			ReadTimeInMinutes: item.ReadTime(),
+			Brief:             item.Brief(),
+			PublishedAt:       item.PublishedAt,
+			SyncedAt:          syncedAt,
+			Source:            models.PostSourceFeed,
+		})
+	}
+	return title, posts, nil
+}
+
+// fetchFeedPost fetches the item of the user's feed to share it. Feeds hold
+// their latest items only, so an item that has dropped off can't be shared
+// any more.
+func fetchFeedPost(user *models.User, blogId string) (*sourcePost, error) {
+	if user.Feed == nil {
+		return nil, fmt.Errorf("no feed is registered: %w", apperrors.ErrInvalidInput)
+	}
+	_, items, err := fetchFeed(user.Feed.URL)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Id != blogId {
+			continue
+		}
+		body := item.HTML
+		if body == "" {
+			body = item.Summary
+		}
+		return &sourcePost{
+			Id:         item.Id,
+			Title:      item.Title,
+			Url:        item.URL,
+			CoverImage: item.Image,
+			Author:     item.Author,
+			ReadTime:   item.ReadTime(),
+			Brief:      item.Brief(),
+			Text:       item.Text(),
+			HTML:       body,
+			Tags:       item.Tags,
+		}, nil
+	}
+	return nil, fmt.Errorf("the item is no longer in the feed: %w", apperrors.ErrNotFound)
+}
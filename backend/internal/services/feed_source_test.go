@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+	<title>Ada's blog</title>
+	<item>
+		<title>Queues</title>
+		<link>https://blog.example.com/queues</link>
+		<guid isPermaLink="false">post-11</guid>
+		<pubDate>Thu, 01 Oct 2026 09:00:00 +0200</pubDate>
+		<dc:creator>Ada</dc:creator>
+		<description><![CDATA[<p>On queues&nbsp;and backpressure</p>]]></description>
+		<content:encoded><![CDATA[<p>Queues hold work.</p><img src="https://cdn.example.com/inline.png">]]></content:encoded>
+		<category>go</category>
+		<media:content url="https://cdn.example.com/q.png" medium="image"/>
+	</item>
+	<item>
+		<title>Draft without a link</title>
+	</item>
+</channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Grace writes</title>
+	<entry>
+		<title>Scheduling</title>
+		<id>tag:grace.example.com,2026:scheduling</id>
+		<link rel="alternate" href="https://grace.example.com/scheduling"/>
+		<link rel="edit" href="https://grace.example.com/edit/1"/>
+		<updated>2026-10-02T10:00:00Z</updated>
+		<author><name>Grace</name></author>
+		<summary type="html">&lt;b&gt;Cron&lt;/b&gt; and beyond</summary>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Timers</p></div></content>
+		<category term="ops"/>
+	</entry>
+</feed>`
+
+func TestParseFeed(t *testing.T) {
+	title, items, err := parseFeed([]byte(testRSSFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Ada's blog" || len(items) != 1 {
+		t.Fatalf("parsed %q with %+v", title, items)
+	}
+	item := items[0]
+	if item.Id != FeedBlogID("post-11") || item.URL != "https://blog.example.com/queues" || item.Author != "Ada" || item.Image != "https://cdn.example.com/q.png" || !reflect.DeepEqual(item.Tags, []string{"go"}) {
+		t.Errorf("RSS item = %+v", item)
+	}
+	if want := time.Date(2026, 10, 1, 7, 0, 0, 0, time.UTC); !item.PublishedAt.Equal(want) {
+		t.Errorf("published at %s, want %s", item.PublishedAt, want)
+	}
+	if item.Brief() != "On queues and backpressure" || item.Text() != "Queues hold work." || item.ReadTime() != 1 {
+		t.Errorf("brief %q, text %q, read time %d", item.Brief(), item.Text(), item.ReadTime())
+	}
+
+	title, items, err = parseFeed([]byte(testAtomFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Grace writes" || len(items) != 1 {
+		t.Fatalf("parsed %q with %+v", title, items)
+	}
+	item = items[0]
+	if item.URL != "https://grace.example.com/scheduling" || item.Author != "Grace" || !item.PublishedAt.Equal(time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC)) || item.Brief() != "Cron and beyond" || item.Text() != "Timers" {
+		t.Errorf("Atom item = %+v", item)
+	}
+
+	if _, _, err := parseFeed([]byte(`<html><body>Not a feed</body></html>`)); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a web page gave %v", err)
+	}
+}
+
+func TestFeedSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			http.Redirect(w, r, "/rss.xml", http.StatusMovedPermanently)
+		case "/rss.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(testRSSFeed))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := checkFeedHost
+	checkFeedHost = func(string) error { return nil }
+	defer func() { checkFeedHost = previous }()
+
+	syncedAt := time.Date(2026, 10, 3, 8, 0, 0, 0, time.UTC)
+	title, posts, err := FetchFeed(server.URL+"/feed", syncedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Ada's blog" || len(posts) != 1 || posts[0].Source != models.PostSourceFeed || !posts[0].SyncedAt.Equal(syncedAt) {
+		t.Fatalf("fetched %q with %+v", title, posts)
+	}
+	if node := posts[0].Node(); node.ID != FeedBlogID("post-11") || node.Source != models.PostSourceFeed {
+		t.Errorf("listed as %+v", node)
+	}
+
+	user := &models.User{Feed: &models.BlogFeed{URL: server.URL + "/feed"}}
+	post, err := fetchSourcePost(user, posts[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Queues" || post.HTML == "" || post.CoverImage != "https://cdn.example.com/q.png" {
+		t.Errorf("fetched %+v", post)
+	}
+	if _, err := fetchSourcePost(user, FeedBlogID("gone")); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("an item no longer in the feed gave %v", err)
+	}
+	if _, _, err := FetchFeed(server.URL+"/missing", syncedAt); err == nil {
+		t.Error("a missing feed was fetched")
+	}
+
+	checkFeedHost = func(string) error { return errors.New("private") }
+	if _, err := ValidateFeedURL(server.URL + "/feed"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a private host gave %v", err)
+	}
+	if _, err := ValidateFeedURL("ftp://blog.example.com/feed"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("an ftp URL gave %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// GhostBlogPrefix starts the blog id of a Ghost post, keeping it apart from
+// the ids of the other blog sources.
+const GhostBlogPrefix = "ghost:"
+
+// maxGhostSourcePosts caps the posts listed from Ghost.
+const maxGhostSourcePosts = 100
+
+// ghostContentKeyPattern matches Ghost Content API keys, which are 26 hex
+// characters.
+var ghostContentKeyPattern = regexp.MustCompile(`^[0-9a-f]{26}$`)
+
+// GhostBlogID is the blog id of the Ghost post.
+func GhostBlogID(postID string) string {
+	return GhostBlogPrefix + postID
+}
+
+// GhostPostID returns the post id of a Ghost blog id, and whether blogId is
+// one.
+func GhostPostID(blogId string) (string, bool) {
+	id, found := strings.CutPrefix(blogId, GhostBlogPrefix)
+	return id, found && id != "" && !strings.ContainsAny(id, "/?#")
+}
+
+// NormalizeGhostAPIURL reduces the API URL shown on a Ghost custom
+// integration, such as "https://example.ghost.io/", to the address of the
+// site. A pasted Content API path is dropped. Only hosts resolving to public
+// addresses are accepted.
+func NormalizeGhostAPIURL(raw string) (string, error) {
+	return publicServers.normalizeGhostAPIURL(raw)
+}
+
+func (s userServers) normalizeGhostAPIURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" ||
+		parsed.RawQuery != "" || parsed.Fragment != "" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("API URL must be the https address of a Ghost site: %w", apperrors.ErrInvalidInput)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if err := s.checkHost(host); err != nil {
+		return "", fmt.Errorf("API URL must be a public Ghost site: %w", apperrors.ErrInvalidInput)
+	}
+	path := strings.TrimRight(parsed.EscapedPath(), "/")
+	path = strings.TrimSuffix(path, "/ghost/api/content")
+	path = strings.TrimSuffix(path, "/ghost")
+	return s.scheme + "://" + host + path, nil
+}
+
+// NormalizeGhostContentKey checks a Ghost Content API key.
+func NormalizeGhostContentKey(raw string) (string, error) {
+	key := strings.TrimSpace(raw)
+	if !ghostContentKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("key must be the Content API key of a Ghost custom integration: %w", apperrors.ErrInvalidInput)
+	}
+	return key, nil
+}
+
+// ghostCall sends a request to the Content API of a Ghost site with the key
+// and decodes its JSON response into out.
+func ghostCall(userId, apiURL, contentKey, path string, query url.Values, out interface{}) error {
+	query.Set("key", contentKey)
+	endpoint := apiURL + "/ghost/api/content" + path
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Version", "v5.0")
+	resp, err := getProviderClient(ProviderGhost).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	// The key travels in the query, which isn't archived
+	archiveProviderResponse(userId, "ghost", endpoint, resp.StatusCode, body)
+	var failure struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(body, &failure)
+	reason := resp.Status
+	if len(failure.Errors) > 0 {
+		reason = failure.Errors[0].Message
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%s throttled the request: %w", req.URL.Host, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the Content API key, %s: %w", req.URL.Host, reason, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s doesn't know %s: %w", req.URL.Host, path, apperrors.ErrNotFound)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%s answered %s", req.URL.Host, reason)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of %s: %v", req.URL.Host, err)
+	}
+	return nil
+}
+
+// LookupGhostSite checks the Content API key with the site and returns the
+// site's title along with its API URL.
+func LookupGhostSite(userId, apiURL, contentKey string) (*models.GhostSource, error) {
+	var response struct {
+		Settings struct {
+			Title string `json:"title"`
+		} `json:"settings"`
+	}
+	if err := ghostCall(userId, apiURL, contentKey, "/settings/", url.Values{}, &response); err != nil {
+		return nil, fmt.Errorf("failed to look up the Ghost site: %w", err)
+	}
+	return &models.GhostSource{APIURL: apiURL, SiteTitle: response.Settings.Title}, nil
+}
+
+// ghostSourcePost is a Ghost post as the Content API returns it.
+type ghostSourcePost struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	CanonicalURL  string `json:"canonical_url"`
+	FeatureImage  string `json:"feature_image"`
+	OGImage       string `json:"og_image"`
+	Excerpt       string `json:"excerpt"`
+	CustomExcerpt string `json:"custom_excerpt"`
+	ReadingTime   int    `json:"reading_time"`
+	PublishedAt   string `json:"published_at"`
+	HTML          string `json:"html"`
+	Plaintext     string `json:"plaintext"`
+	PrimaryAuthor struct {
+		Name string `json:"name"`
+	} `json:"primary_author"`
+	Tags []struct {
+		Slug string `json:"slug"`
+	} `json:"tags"`
+}
+
+// Brief is the post's own excerpt, or the one Ghost makes from its start.
+func (p ghostSourcePost) Brief() string {
+	if p.CustomExcerpt != "" {
+		return p.CustomExcerpt
+	}
+	return p.Excerpt
+}
+
+func ghostContentKey(user *models.User) (string, error) {
+	if user.Ghost == nil {
+		return "", fmt.Errorf("Ghost is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	contentKey, err := OpenUserSecret(user, user.Ghost.SealedContentKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Ghost Content API key: %v", err)
+	}
+	return contentKey, nil
+}
+
+// FetchGhostPosts lists the published posts of the user's Ghost site,
+// newest first, as blogs to share. Posts whose canonical URL is listed
+// under skipCanonical are left out, as another source lists them.
+func FetchGhostPosts(user *models.User, skipCanonical map[string]bool) ([]models.PostNode, error) {
+	contentKey, err := ghostContentKey(user)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("include", "authors")
+	query.Set("limit", strconv.Itoa(maxGhostSourcePosts))
+	var response struct {
+		Posts []ghostSourcePost `json:"posts"`
+	}
+	if err := ghostCall(user.Id.Hex(), user.Ghost.APIURL, contentKey, "/posts/", query, &response); err != nil {
+		return nil, fmt.Errorf("failed to list the Ghost posts: %w", err)
+	}
+	posts := []models.PostNode{}
+	for _, post := range response.Posts {
+		if post.CanonicalURL != "" && post.CanonicalURL != post.URL && skipCanonical[post.CanonicalURL] {
+			continue
+		}
+		posts = append(posts, models.PostNode{
+			Title:             post.Title,
+			URL:               post.URL,
+			ID:                GhostBlogID(post.ID),
+			CoverImage:        models.CoverImage{URL: post.FeatureImage},
+			Author:            models.Author{Name: post.PrimaryAuthor.Name},
+			ReadTimeInMinutes: post.ReadingTime,
+			PublishedAt:       post.PublishedAt,
+			Brief:             post.Brief(),
+			Source:            "ghost",
+		})
+	}
+	return posts, nil
+}
+
+// fetchGhostPost fetches a post of the user's Ghost site to share it.
+func fetchGhostPost(user *models.User, postID string) (*sourcePost, error) {
+	contentKey, err := ghostContentKey(user)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("include", "authors,tags")
+	query.Set("formats", "html,plaintext")
+	var response struct {
+		Posts []ghostSourcePost `json:"posts"`
+	}
+	if err := ghostCall(user.Id.Hex(), user.Ghost.APIURL, contentKey, "/posts/"+url.PathEscape(postID)+"/", query, &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch the Ghost post: %w", err)
+	}
+	if len(response.Posts) == 0 {
+		return nil, fmt.Errorf("the Ghost post is gone: %w", apperrors.ErrNotFound)
+	}
+	post := response.Posts[0]
+	var tags []string
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Slug)
+	}
+	return &sourcePost{
+		Id:          GhostBlogID(post.ID),
+		Title:       post.Title,
+		Url:         post.URL,
+		CoverImage:  post.FeatureImage,
+		SocialImage: post.OGImage,
+		Author:      post.PrimaryAuthor.Name,
+		ReadTime:    post.ReadingTime,
+		Brief:       post.Brief(),
+		Text:        post.Plaintext,
+		HTML:        post.HTML,
+		Tags:        tags,
+	}, nil
+}
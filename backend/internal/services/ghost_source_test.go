@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestGhostSource(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	const contentKey = "22444f78447824223cefc48062"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != contentKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"message": "Unknown Content API Key", "type": "UnauthorizedError"}]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/blog/ghost/api/content/settings/":
+			w.Write([]byte(`{"settings": {"title": "Ada's notes"}}`))
+		case "/blog/ghost/api/content/posts/":
+			w.Write([]byte(`{"posts": [
+				{"id": "65f1", "title": "Queues", "url": "https://ada.example.com/blog/queues/", "feature_image": "https://cdn.example.com/q.png", "custom_excerpt": "On queues", "excerpt": "Queues hold work", "reading_time": 4, "published_at": "2026-10-01T09:00:00.000+00:00", "primary_author": {"name": "Ada"}},
+				{"id": "65f2", "title": "Scheduling", "url": "https://ada.example.com/blog/scheduling/", "canonical_url": "https://ada.hashnode.dev/scheduling", "primary_author": {"name": "Ada"}}
+			], "meta": {"pagination": {"page": 1, "pages": 1}}}`))
+		case "/blog/ghost/api/content/posts/65f1/":
+			if r.URL.Query().Get("formats") != "html,plaintext" {
+				t.Errorf("fetched formats %q", r.URL.Query().Get("formats"))
+			}
+			w.Write([]byte(`{"posts": [{"id": "65f1", "title": "Queues", "url": "https://ada.example.com/blog/queues/", "feature_image": "https://cdn.example.com/q.png", "og_image": "https://cdn.example.com/og.png", "excerpt": "Queues hold work", "reading_time": 4, "html": "<p>Queues hold work</p>", "plaintext": "Queues hold work", "primary_author": {"name": "Ada"}, "tags": [{"slug": "go"}, {"slug": "backend"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": [{"message": "Resource not found", "type": "NotFoundError"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	if apiURL, err := localServers().normalizeGhostAPIURL("Ada.example.com/blog/ghost/api/content/"); err != nil || apiURL != "http://ada.example.com/blog" {
+		t.Errorf("NormalizeGhostAPIURL = %q, %v", apiURL, err)
+	}
+	// httptest listens on a port, which API URLs may not have
+	apiURL := server.URL + "/blog"
+	if _, err := NormalizeGhostContentKey("not-a-key"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a malformed key gave %v", err)
+	}
+	if _, err := LookupGhostSite("user-1", apiURL, "00000000000000000000000000"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("a wrong key gave %v", err)
+	}
+	source, err := LookupGhostSite("user-1", apiURL, contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.SiteTitle != "Ada's notes" || source.APIURL != apiURL {
+		t.Errorf("site = %+v", source)
+	}
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	source.SealedContentKey, err = SealUserSecret(user, contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Ghost = source
+
+	// The copy of the Hashnode post isn't listed again
+	posts, err := FetchGhostPosts(user, map[string]bool{"https://ada.hashnode.dev/scheduling": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != "ghost:65f1" || posts[0].Source != "ghost" || posts[0].Brief != "On queues" || posts[0].ReadTimeInMinutes != 4 || posts[0].CoverImage.URL != "https://cdn.example.com/q.png" {
+		t.Fatalf("listed %+v", posts)
+	}
+
+	post, err := fetchSourcePost(user, posts[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Id != "ghost:65f1" || post.Text != "Queues hold work" || post.SocialImage != "https://cdn.example.com/og.png" || !reflect.DeepEqual(post.Tags, []string{"go", "backend"}) {
+		t.Errorf("fetched %+v", post)
+	}
+	if _, err := fetchSourcePost(user, GhostBlogID("gone")); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("a deleted post gave %v", err)
+	}
+	if _, ok := GhostPostID("ghost:../settings"); ok {
+		t.Error("a path was taken for a Ghost post id")
+	}
+}
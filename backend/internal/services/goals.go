@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	// MaxGoalSharesPerWeek caps a posting goal.
+	MaxGoalSharesPerWeek = 50
+	// GoalReminderLead is how long before a week ends a reminder is sent
+	// if the goal isn't met yet.
+	GoalReminderLead = 24 * time.Hour
+	// goalHistoryWeeks is how many weeks progress lists.
+	goalHistoryWeeks = 12
+)
+
+// NewPostingGoal checks a posting goal. An empty timezone is UTC.
+func NewPostingGoal(sharesPerWeek int, timezone string, reminder bool) (*models.PostingGoal, error) {
+	if sharesPerWeek < 1 || sharesPerWeek > MaxGoalSharesPerWeek {
+		return nil, fmt.Errorf("shares_per_week must be between 1 and %d: %w", MaxGoalSharesPerWeek, apperrors.ErrInvalidInput)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("timezone must be an IANA time zone such as Europe/Berlin: %w", apperrors.ErrInvalidInput)
+	}
+	return &models.PostingGoal{SharesPerWeek: sharesPerWeek, Timezone: timezone, Reminder: reminder}, nil
+}
+
+func goalLocation(goal *models.PostingGoal) *time.Location {
+	loc, err := time.LoadLocation(goal.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// goalWeekStart is the start of the week t falls in: Monday at midnight in
+// loc.
+func goalWeekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, loc)
+}
+
+// GoalProgressAt reports how the user is doing against their posting goal
+// at now.
+func GoalProgressAt(user *models.User, now time.Time) models.GoalProgress {
+	goal := user.PostingGoal
+	loc := goalLocation(goal)
+	weekStart := goalWeekStart(now, loc)
+
+	shares := map[int64]int{}
+	earliest := weekStart
+	for _, blog := range user.SharedBlogs {
+		sharedAt, err := time.Parse(time.RFC3339, blog.SharedTime)
+		if err != nil || sharedAt.After(now) {
+			continue
+		}
+		start := goalWeekStart(sharedAt, loc)
+		shares[start.Unix()]++
+		if start.Before(earliest) {
+			earliest = start
+		}
+	}
+
+	progress := models.GoalProgress{
+		WeekStart: weekStart,
+		WeekEnd:   weekStart.AddDate(0, 0, 7),
+		Shares:    shares[weekStart.Unix()],
+		Weeks:     []models.GoalWeek{},
+	}
+	progress.Met = progress.Shares >= goal.SharesPerWeek
+	progress.Remaining = max(0, goal.SharesPerWeek-progress.Shares)
+
+	// Walk the weeks from the first share on, this week only counting
+	// towards a streak once it is met
+	streak := 0
+	for start := earliest; !start.After(weekStart); start = start.AddDate(0, 0, 7) {
+		if shares[start.Unix()] >= goal.SharesPerWeek {
+			streak++
+			progress.LongestStreak = max(progress.LongestStreak, streak)
+		} else if !start.Equal(weekStart) {
+			streak = 0
+		}
+	}
+	progress.CurrentStreak = streak
+
+	for i := 0; i < goalHistoryWeeks; i++ {
+		start := weekStart.AddDate(0, 0, -7*i)
+		if start.Before(earliest) {
+			break
+		}
+		count := shares[start.Unix()]
+		progress.Weeks = append(progress.Weeks, models.GoalWeek{Start: start, Shares: count, Met: count >= goal.SharesPerWeek})
+	}
+	return progress
+}
+
+// NextGoalReminder is when the reminder of the goal is next checked: a
+// GoalReminderLead before the end of this week, or of next week once that
+// time has passed.
+func NextGoalReminder(goal *models.PostingGoal, now time.Time) time.Time {
+	weekStart := goalWeekStart(now, goalLocation(goal))
+	remindAt := weekStart.AddDate(0, 0, 7).Add(-GoalReminderLead)
+	if now.Before(remindAt) {
+		return remindAt
+	}
+	return weekStart.AddDate(0, 0, 14).Add(-GoalReminderLead)
+}
+
+// GoalReminder returns the reminder to send the user at now, if their week
+// ends within GoalReminderLead under target and they weren't reminded this
+// week yet.
+func GoalReminder(user *models.User, now time.Time) (string, bool) {
+	goal := user.PostingGoal
+	if goal == nil || !goal.Reminder {
+		return "", false
+	}
+	progress := GoalProgressAt(user, now)
+	left := progress.WeekEnd.Sub(now)
+	if progress.Met || left > GoalReminderLead || goal.RemindedWeek.Equal(progress.WeekStart) {
+		return "", false
+	}
+	blogs := "blogs"
+	if progress.Remaining == 1 {
+		blogs = "blog"
+	}
+	hours := int(math.Ceil(left.Hours()))
+	return fmt.Sprintf("Your week ends in %d hours: share %d more %s to reach your goal of %d a week", hours, progress.Remaining, blogs, goal.SharesPerWeek), true
+}
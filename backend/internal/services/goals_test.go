@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestNewPostingGoal(t *testing.T) {
+	if goal, err := NewPostingGoal(3, "", true); err != nil || goal.Timezone != "UTC" {
+		t.Errorf("NewPostingGoal = %+v, %v", goal, err)
+	}
+	if _, err := NewPostingGoal(0, "UTC", false); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a goal of 0 gave %v", err)
+	}
+	if _, err := NewPostingGoal(3, "Mars/Olympus", false); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("an unknown timezone gave %v", err)
+	}
+}
+
+func TestGoalProgress(t *testing.T) {
+	var shared []models.SharedBlog
+	for _, at := range []string{
+		"2026-08-31T09:00:00Z", "2026-09-02T09:00:00Z",
+		"2026-09-07T09:00:00Z", "2026-09-08T09:00:00Z",
+		"2026-09-14T09:00:00Z", "2026-09-15T09:00:00Z", "2026-09-16T09:00:00Z",
+		// Nothing the week of September 21
+		"2026-09-28T09:00:00Z", "2026-09-30T09:00:00Z",
+		"2026-10-06T09:00:00Z", "2026-10-08T09:00:00Z",
+		// Monday 00:30 in Berlin, so this week
+		"2026-10-11T22:30:00Z",
+	} {
+		shared = append(shared, models.SharedBlog{SharedTime: at})
+	}
+	goal := &models.PostingGoal{SharesPerWeek: 2, Timezone: "Europe/Berlin", Reminder: true}
+	user := &models.User{PostingGoal: goal, SharedBlogs: shared}
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+	progress := GoalProgressAt(user, now)
+	if !progress.WeekStart.Equal(time.Date(2026, 10, 11, 22, 0, 0, 0, time.UTC)) || !progress.WeekEnd.Equal(time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("week = %s to %s", progress.WeekStart, progress.WeekEnd)
+	}
+	if progress.Shares != 1 || progress.Remaining != 1 || progress.Met || progress.CurrentStreak != 2 || progress.LongestStreak != 3 {
+		t.Errorf("progress = %+v", progress)
+	}
+	if len(progress.Weeks) != 7 || progress.Weeks[1].Shares != 2 || !progress.Weeks[1].Met || progress.Weeks[3].Shares != 0 || progress.Weeks[4].Shares != 3 {
+		t.Errorf("weeks = %+v", progress.Weeks)
+	}
+
+	if _, ok := GoalReminder(user, now.Add(-24*time.Hour)); ok {
+		t.Error("reminded with more than a day left")
+	}
+	message, ok := GoalReminder(user, now)
+	if want := "Your week ends in 14 hours: share 1 more blog to reach your goal of 2 a week"; !ok || message != want {
+		t.Errorf("reminder = %q, %t; want %q", message, ok, want)
+	}
+	goal.RemindedWeek = progress.WeekStart
+	if _, ok := GoalReminder(user, now); ok {
+		t.Error("reminded twice in a week")
+	}
+
+	if next := NextGoalReminder(goal, now.Add(-24*time.Hour)); !next.Equal(time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("next reminder this week at %s", next)
+	}
+	// Berlin leaves summer time on October 25
+	if next := NextGoalReminder(goal, now); !next.Equal(time.Date(2026, 10, 24, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("next reminder next week at %s", next)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	// maxGoogleBusinessSummary is the longest text a "What's New" post
+	// takes.
+	maxGoogleBusinessSummary = 1500
+	// maxGoogleBusinessLocations caps the locations listed for a user, so
+	// an agency account doesn't page through thousands.
+	maxGoogleBusinessLocations = 100
+)
+
+// GoogleBusinessScopes let SocialScribe list the user's locations and post
+// to them.
+var GoogleBusinessScopes = []string{"https://www.googleapis.com/auth/business.manage"}
+
+// GoogleBusinessEndpoint is Google's OAuth endpoint.
+var GoogleBusinessEndpoint = oauth2.Endpoint{
+	AuthURL:   "https://accounts.google.com/o/oauth2/auth",
+	TokenURL:  "https://oauth2.googleapis.com/token",
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
+// The Business Profile API is split across services; they are replaced by
+// tests.
+var (
+	googleAccountsAPI  = "https://mybusinessaccountmanagement.googleapis.com/v1"
+	googleLocationsAPI = "https://mybusinessbusinessinformation.googleapis.com/v1"
+	googlePostsAPI     = "https://mybusiness.googleapis.com/v4"
+)
+
+var googleBusinessConfig = &oauth2.Config{}
+
+// InitGoogleBusinessConfig sets the Google app access tokens are refreshed
+// with.
+func InitGoogleBusinessConfig(config *oauth2.Config) {
+	googleBusinessConfig = config
+}
+
+// GoogleBusinessContext carries the client to use for Google's token
+// endpoint.
+func GoogleBusinessContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, getProviderClient(ProviderGoogleBusiness))
+}
+
+// googleCall sends a request to one of Google's APIs with the access token
+// and decodes the JSON response into out. The provider names the API's
+// client and archive.
+func googleCall(provider, userId, accessToken string, req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(provider).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, provider, req.URL.String(), resp.StatusCode, body)
+	var failure struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &failure)
+	reason := failure.Error.Message
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Google throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("Google rejected the access token, %s: %w", reason, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Google refused, %s: %w", reason, apperrors.ErrForbidden)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("Google doesn't know it, %s: %w", reason, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("Google refused the request, %s: %w", reason, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Google answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Google: %v", err)
+	}
+	return nil
+}
+
+// googleBusinessAccessToken gets a fresh access token with the user's
+// refresh token.
+func googleBusinessAccessToken(user *models.User) (string, error) {
+	account := user.GoogleBusiness
+	if account == nil {
+		return "", fmt.Errorf("Google Business Profile is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	refreshToken, err := OpenUserSecret(user, account.SealedRefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Google refresh token: %v", err)
+	}
+	token, err := googleBusinessConfig.TokenSource(GoogleBusinessContext(context.Background()), &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh the Google access token: %w", apperrors.ErrProviderAuth)
+	}
+	return token.AccessToken, nil
+}
+
+// LookupGoogleBusinessLocations lists the locations of every business
+// account the access token's user manages.
+func LookupGoogleBusinessLocations(userId, accessToken string) ([]models.GoogleBusinessLocation, error) {
+	var accounts struct {
+		Accounts []struct {
+			Name string `json:"name"`
+		} `json:"accounts"`
+	}
+	req, err := http.NewRequest(http.MethodGet, googleAccountsAPI+"/accounts?pageSize=20", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := googleCall(ProviderGoogleBusiness, userId, accessToken, req, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to list the business accounts: %w", err)
+	}
+
+	locations := []models.GoogleBusinessLocation{}
+	for _, account := range accounts.Accounts {
+		pageToken := ""
+		for len(locations) < maxGoogleBusinessLocations {
+			query := url.Values{"readMask": {"name,title,storefrontAddress"}, "pageSize": {"100"}}
+			if pageToken != "" {
+				query.Set("pageToken", pageToken)
+			}
+			req, err := http.NewRequest(http.MethodGet, googleLocationsAPI+"/"+account.Name+"/locations?"+query.Encode(), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %v", err)
+			}
+			var page struct {
+				Locations []struct {
+					Name              string `json:"name"`
+					Title             string `json:"title"`
+					StorefrontAddress struct {
+						AddressLines []string `json:"addressLines"`
+						Locality     string   `json:"locality"`
+					} `json:"storefrontAddress"`
+				} `json:"locations"`
+				NextPageToken string `json:"nextPageToken"`
+			}
+			if err := googleCall(ProviderGoogleBusiness, userId, accessToken, req, &page); err != nil {
+				return nil, fmt.Errorf("failed to list the locations of %s: %w", account.Name, err)
+			}
+			for _, location := range page.Locations {
+				address := append(location.StorefrontAddress.AddressLines, location.StorefrontAddress.Locality)
+				locations = append(locations, models.GoogleBusinessLocation{
+					// Posts still live in the v4 API, which names locations
+					// under their account
+					Name:    account.Name + "/" + location.Name,
+					Title:   location.Title,
+					Address: strings.Trim(strings.Join(address, ", "), ", "),
+				})
+			}
+			if pageToken = page.NextPageToken; pageToken == "" {
+				break
+			}
+		}
+	}
+	if len(locations) > maxGoogleBusinessLocations {
+		locations = locations[:maxGoogleBusinessLocations]
+	}
+	return locations, nil
+}
+
+// ValidateGoogleBusinessLocation checks that posts may go to the location.
+func ValidateGoogleBusinessLocation(account *models.GoogleBusinessAccount, location string) error {
+	if account == nil {
+		return fmt.Errorf("Google Business Profile is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	for _, known := range account.Locations {
+		if known.Name == location {
+			return nil
+		}
+	}
+	return fmt.Errorf("location %q is not one of your business locations: %w", location, apperrors.ErrInvalidInput)
+}
+
+// postGoogleBusinessUpdate publishes the caption as a "What's New" post on
+// the chosen location, with a Learn more button linking to the blog. It
+// returns the post's URL on Google Search.
+func postGoogleBusinessUpdate(user *models.User, caption, link string) (string, error) {
+	account := user.GoogleBusiness
+	if account == nil || account.Location == "" {
+		return "", fmt.Errorf("no Google Business Profile location is chosen: %w", apperrors.ErrInvalidInput)
+	}
+	accessToken, err := googleBusinessAccessToken(user)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"languageCode": "en",
+		"summary":      truncateRunes(strings.TrimSpace(caption), maxGoogleBusinessSummary),
+		"topicType":    "STANDARD",
+		"callToAction": map[string]string{"actionType": "LEARN_MORE", "url": link},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal post: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, googlePostsAPI+"/"+account.Location+"/localPosts", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var post struct {
+		SearchURL string `json:"searchUrl"`
+	}
+	if err := googleCall(ProviderGoogleBusiness, user.Id.Hex(), accessToken, req, &post); err != nil {
+		return "", err
+	}
+	return post.SearchURL, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestGoogleBusinessPost(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var post map[string]interface{}
+	var postedTo string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.Form.Get("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "access", "token_type": "Bearer", "expires_in": 3599}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`))
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1/accounts":
+			w.Write([]byte(`{"accounts": [{"name": "accounts/111", "accountName": "Ada Consulting"}]}`))
+		case r.URL.Path == "/v1/accounts/111/locations" && r.URL.Query().Get("pageToken") == "":
+			w.Write([]byte(`{"locations": [{"name": "locations/222", "title": "Ada Consulting", "storefrontAddress": {"addressLines": ["1 Main St"], "locality": "Springfield"}}], "nextPageToken": "next"}`))
+		case r.URL.Path == "/v1/accounts/111/locations":
+			w.Write([]byte(`{"locations": [{"name": "locations/333", "title": "Ada Consulting Online"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/localPosts"):
+			postedTo = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&post)
+			w.Write([]byte(`{"name": "accounts/111/locations/222/localPosts/444", "searchUrl": "https://local.google.com/place?id=1&use=posts&lpsid=444"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := []string{googleAccountsAPI, googleLocationsAPI, googlePostsAPI}
+	googleAccountsAPI, googleLocationsAPI, googlePostsAPI = server.URL+"/v1", server.URL+"/v1", server.URL+"/v4"
+	defer func() { googleAccountsAPI, googleLocationsAPI, googlePostsAPI = previous[0], previous[1], previous[2] }()
+	previousConfig := googleBusinessConfig
+	googleBusinessConfig = &oauth2.Config{ClientID: "client", ClientSecret: "secret", Endpoint: oauth2.Endpoint{TokenURL: server.URL + "/token", AuthStyle: oauth2.AuthStyleInParams}}
+	defer func() { googleBusinessConfig = previousConfig }()
+
+	if _, err := LookupGoogleBusinessLocations("", "expired"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("listed locations with an expired token: %v", err)
+	}
+	locations, err := LookupGoogleBusinessLocations("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.GoogleBusinessLocation{
+		{Name: "accounts/111/locations/222", Title: "Ada Consulting", Address: "1 Main St, Springfield"},
+		{Name: "accounts/111/locations/333", Title: "Ada Consulting Online"},
+	}
+	if len(locations) != 2 || locations[0] != want[0] || locations[1] != want[1] {
+		t.Fatalf("locations = %+v, want %+v", locations, want)
+	}
+
+	user := &models.User{Id: primitive.NewObjectID(), GoogleBusiness: &models.GoogleBusinessAccount{Locations: locations}}
+	if user.GoogleBusiness.SealedRefreshToken, err = SealUserSecret(user, "refresh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postGoogleBusinessUpdate(user, "New post", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("posted without a chosen location: %v", err)
+	}
+	if err := ValidateGoogleBusinessLocation(user.GoogleBusiness, "accounts/111/locations/999"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted someone else's location: %v", err)
+	}
+	user.GoogleBusiness.Location = "accounts/111/locations/222"
+
+	postURL, err := postGoogleBusinessUpdate(user, strings.Repeat("a", 2000), "https://blog.example.com/scheduling")
+	if err != nil || postURL != "https://local.google.com/place?id=1&use=posts&lpsid=444" {
+		t.Fatalf("posted at %q, %v", postURL, err)
+	}
+	if postedTo != "/v4/accounts/111/locations/222/localPosts" {
+		t.Errorf("posted to %s", postedTo)
+	}
+	summary, _ := post["summary"].(string)
+	action, _ := post["callToAction"].(map[string]interface{})
+	if len([]rune(summary)) != maxGoogleBusinessSummary || action["actionType"] != "LEARN_MORE" || action["url"] != "https://blog.example.com/scheduling" {
+		t.Errorf("posted %v", post)
+	}
+}
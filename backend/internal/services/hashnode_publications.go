@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// AllPublications lists the posts of every publication of the user.
+const AllPublications = "all"
+
+// maxHashnodePublications is how many publications of an account are
+// looked up.
+const maxHashnodePublications = 20
+
+// hashnodeHost is how Hashnode looks up the publication at url.
+func hashnodeHost(url string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"), "/")
+}
+
+// LookupHashnodePublications lists the publications of the Hashnode account
+// the personal access token belongs to.
+func LookupHashnodePublications(token string) ([]models.HashnodePublication, error) {
+	query, err := json.Marshal(models.GraphQLQuery{
+		Query: fmt.Sprintf(`query Me { me { publications(first: %d) { edges { node { url id title } } } } }`, maxHashnodePublications),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://gql.hashnode.com", bytes.NewBuffer(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err := getProviderClient(ProviderHashnode).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("Hashnode rejected the publication lookup: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("Hashnode refused the API key: %w", apperrors.ErrProviderAuth)
+	}
+
+	var response struct {
+		Data struct {
+			Me struct {
+				Publications struct {
+					Edges []struct {
+						Node struct {
+							URL   string `json:"url"`
+							ID    string `json:"id"`
+							Title string `json:"title"`
+						} `json:"node"`
+					} `json:"edges"`
+				} `json:"publications"`
+			} `json:"me"`
+		} `json:"data"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	publications := []models.HashnodePublication{}
+	for _, edge := range response.Data.Me.Publications.Edges {
+		node := edge.Node
+		if node.URL == "" {
+			continue
+		}
+		publications = append(publications, models.HashnodePublication{ID: node.ID, Host: hashnodeHost(node.URL), Title: node.Title})
+	}
+	return publications, nil
+}
+
+// DefaultPublication picks the publication listed by default among those
+// found: the current default while the account still has it, and its first
+// publication otherwise.
+func DefaultPublication(user *models.User, publications []models.HashnodePublication) string {
+	for _, publication := range publications {
+		if publication.Host == user.HashnodeBlog {
+			return publication.Host
+		}
+	}
+	if len(publications) == 0 {
+		return ""
+	}
+	return publications[0].Host
+}
+
+// PublicationHosts are the hosts of the user's Hashnode publications, the
+// default one first. Accounts connected before publications were stored
+// only have the default one.
+func PublicationHosts(user *models.User) []string {
+	hosts := []string{}
+	if user.HashnodeBlog != "" {
+		hosts = append(hosts, user.HashnodeBlog)
+	}
+	for _, publication := range user.HashnodePublications {
+		if publication.Host != user.HashnodeBlog {
+			hosts = append(hosts, publication.Host)
+		}
+	}
+	return hosts
+}
+
+// ValidateHashnodePublication checks that host is one of the user's
+// Hashnode publications.
+func ValidateHashnodePublication(user *models.User, host string) error {
+	for _, known := range PublicationHosts(user) {
+		if known == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("publication %q is not one of your Hashnode publications: %w", host, apperrors.ErrInvalidInput)
+}
+
+// CheckPostPublication checks that the blog to share is a post of the
+// user's Hashnode publication at host. An empty host checks nothing.
+func CheckPostPublication(user *models.User, blogId, host string) error {
+	if host == "" {
+		return nil
+	}
+	if err := ValidateHashnodePublication(user, host); err != nil {
+		return err
+	}
+	for _, prefix := range []string{DevtoBlogPrefix, GhostBlogPrefix, MediumBlogPrefix, FeedBlogPrefix} {
+		if strings.HasPrefix(blogId, prefix) {
+			return fmt.Errorf("publication only applies to Hashnode posts: %w", apperrors.ErrInvalidInput)
+		}
+	}
+
+	query, err := json.Marshal(models.GraphQLQuery{
+		Query:     `query PostPublication($id: ID!) { post(id: $id) { publication { url } } }`,
+		Variables: map[string]interface{}{"id": blogId},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %v", err)
+	}
+	gqlResponse, err := MakePostRequest("https://gql.hashnode.com", query, map[string]string{"Content-Type": "application/json"})
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	var response struct {
+		Data struct {
+			Post *struct {
+				Publication struct {
+					URL string `json:"url"`
+				} `json:"publication"`
+			} `json:"post"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(gqlResponse, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+	if response.Data.Post == nil {
+		return fmt.Errorf("blog %s was not found on Hashnode: %w", blogId, apperrors.ErrNotFound)
+	}
+	if hashnodeHost(response.Data.Post.Publication.URL) != host {
+		return fmt.Errorf("blog %s is not a post of %s: %w", blogId, host, apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// SelectPublications resolves the publication a blog listing asks for to
+// the hosts it lists: the default one for "", every one for
+// AllPublications, and otherwise the named one.
+func SelectPublications(user *models.User, publication string) ([]string, error) {
+	switch publication {
+	case "":
+		if user.HashnodeBlog == "" {
+			return []string{}, nil
+		}
+		return []string{user.HashnodeBlog}, nil
+	case AllPublications:
+		return PublicationHosts(user), nil
+	}
+	if err := ValidateHashnodePublication(user, publication); err != nil {
+		return nil, err
+	}
+	return []string{publication}, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestHashnodePublications(t *testing.T) {
+	fake := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		status, response := http.StatusOK, ""
+		switch {
+		case strings.Contains(string(body), "query Me") && r.Header.Get("Authorization") != "pat":
+			status = http.StatusUnauthorized
+		case strings.Contains(string(body), "query Me"):
+			response = `{"data": {"me": {"publications": {"edges": [
+				{"node": {"url": "https://ada.hashnode.dev", "id": "p1", "title": "Ada's blog"}},
+				{"node": {"url": "https://notes.ada.dev/", "id": "p2", "title": "Notes"}}
+			]}}}}`
+		case strings.Contains(string(body), `"post-on-notes"`):
+			response = `{"data": {"post": {"publication": {"url": "https://notes.ada.dev"}}}}`
+		default:
+			response = `{"data": {"post": null}}`
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(response)), Request: r}, nil
+	})
+	previous := SetProviderTransport(fake)
+	defer SetProviderTransport(previous)
+
+	if _, err := LookupHashnodePublications("revoked"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up publications with a revoked key: %v", err)
+	}
+	publications, err := LookupHashnodePublications("pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.HashnodePublication{{ID: "p1", Host: "ada.hashnode.dev", Title: "Ada's blog"}, {ID: "p2", Host: "notes.ada.dev", Title: "Notes"}}
+	if !reflect.DeepEqual(publications, want) {
+		t.Fatalf("publications = %+v, want %+v", publications, want)
+	}
+
+	// The default survives a reconnect while the account still has it
+	user := &models.User{HashnodeBlog: "notes.ada.dev"}
+	if host := DefaultPublication(user, publications); host != "notes.ada.dev" {
+		t.Errorf("default = %q", host)
+	}
+	user.HashnodeBlog = "gone.ada.dev"
+	if host := DefaultPublication(user, publications); host != "ada.hashnode.dev" {
+		t.Errorf("default of a removed publication = %q", host)
+	}
+
+	user.HashnodeBlog, user.HashnodePublications = "notes.ada.dev", publications
+	if hosts := PublicationHosts(user); !reflect.DeepEqual(hosts, []string{"notes.ada.dev", "ada.hashnode.dev"}) {
+		t.Errorf("hosts = %v", hosts)
+	}
+	if hosts, err := SelectPublications(user, ""); err != nil || !reflect.DeepEqual(hosts, []string{"notes.ada.dev"}) {
+		t.Errorf("default selection = %v, %v", hosts, err)
+	}
+	if hosts, err := SelectPublications(user, AllPublications); err != nil || len(hosts) != 2 {
+		t.Errorf("all = %v, %v", hosts, err)
+	}
+	if _, err := SelectPublications(user, "someone.hashnode.dev"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("selected another account's publication: %v", err)
+	}
+
+	if err := CheckPostPublication(user, "post-on-notes", "notes.ada.dev"); err != nil {
+		t.Errorf("rejected a post of its publication: %v", err)
+	}
+	if err := CheckPostPublication(user, "post-on-notes", "ada.hashnode.dev"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted a post of another publication: %v", err)
+	}
+	if err := CheckPostPublication(user, "missing", "notes.ada.dev"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("a missing post gave %v", err)
+	}
+	if err := CheckPostPublication(user, GhostBlogID("65f1"), "notes.ada.dev"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("checked the publication of a Ghost post: %v", err)
+	}
+	if err := CheckPostPublication(user, "anything", ""); err != nil {
+		t.Errorf("checked a share without a publication: %v", err)
+	}
+}
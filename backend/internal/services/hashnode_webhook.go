@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+const hashnodeSignatureTolerance = 5 * time.Minute
+
+// VerifyHashnodeSignature validates the x-hashnode-signature header, which has
+// the form "t=<unix millis>,v1=<hex hmac-sha256 of "<t>.<payload>">".
+func VerifyHashnodeSignature(payload []byte, header, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("no webhook secret configured")
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("malformed signature header")
+	}
+
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp: %v", err)
+	}
+	age := utils.Now().Sub(time.UnixMilli(millis))
+	if age > hashnodeSignatureTolerance || age < -hashnodeSignatureTolerance {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// NewHashnodeWebhookSecret generates the secret Hashnode signs the webhook
+// deliveries of a user's publications with.
+func NewHashnodeWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashnodeMutation runs a GraphQL mutation with the user's personal access
+// token and decodes its data into out.
+func hashnodeMutation(token, query string, variables map[string]interface{}, out interface{}) error {
+	queryBytes, err := json.Marshal(models.GraphQLQuery{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %v", err)
+	}
+	headers := map[string]string{"Content-Type": "application/json", "Authorization": token}
+	gqlResponse, err := MakePostRequest("https://gql.hashnode.com", queryBytes, headers)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(gqlResponse, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("Hashnode rejected the mutation: %s", response.Errors[0].Message)
+	}
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+	return nil
+}
+
+// RegisterHashnodeWebhook registers a webhook on the publication that posts
+// post_published events to url, signed with secret, and returns its id.
+func RegisterHashnodeWebhook(token, publicationID, url, secret string) (string, error) {
+	var data struct {
+		CreateWebhook struct {
+			Webhook struct {
+				ID string `json:"id"`
+			} `json:"webhook"`
+		} `json:"createWebhook"`
+	}
+	err := hashnodeMutation(token,
+		`mutation CreateWebhook($input: CreateWebhookInput!) { createWebhook(input: $input) { webhook { id } } }`,
+		map[string]interface{}{"input": map[string]interface{}{
+			"publicationId": publicationID,
+			"url":           url,
+			"events":        []string{"POST_PUBLISHED"},
+			"secret":        secret,
+		}},
+		&data,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to register the webhook of publication %s: %w", publicationID, err)
+	}
+	if data.CreateWebhook.Webhook.ID == "" {
+		return "", fmt.Errorf("Hashnode returned no webhook for publication %s", publicationID)
+	}
+	return data.CreateWebhook.Webhook.ID, nil
+}
+
+// DeleteHashnodeWebhook removes a webhook registered by
+// RegisterHashnodeWebhook.
+func DeleteHashnodeWebhook(token, webhookID string) error {
+	var data struct{}
+	err := hashnodeMutation(token,
+		`mutation DeleteWebhook($id: ID!) { deleteWebhook(id: $id) { webhook { id } } }`,
+		map[string]interface{}{"id": webhookID},
+		&data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %s: %w", webhookID, err)
+	}
+	return nil
+}
+
+// RegisterPublicationWebhooks makes sure each of the user's Hashnode
+// publications has a webhook posting to url, keeping the webhooks of
+// publications found before and removing those of publications the account
+// no longer has. A publication whose webhook can't be registered is left
+// without one, so publishing there shares nothing.
+func RegisterPublicationWebhooks(user *models.User, url string, previous []models.HashnodePublication) error {
+	pat, err := OpenCredential(user, user.SealedHashnodePAT, user.PlaintextHashnodePAT)
+	if err != nil {
+		return err
+	}
+	secret, err := OpenCredential(user, user.SealedHashnodeWebhookSecret, user.PlaintextHashnodeWebhookSecret)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		if secret, err = NewHashnodeWebhookSecret(); err != nil {
+			return err
+		}
+	}
+	if err := SealCredential(user, &user.SealedHashnodeWebhookSecret, &user.PlaintextHashnodeWebhookSecret, secret); err != nil {
+		return err
+	}
+	registered := map[string]string{}
+	for _, publication := range previous {
+		if publication.WebhookID != "" {
+			registered[publication.ID] = publication.WebhookID
+		}
+	}
+	userId := user.Id.Hex()
+	for i := range user.HashnodePublications {
+		publication := &user.HashnodePublications[i]
+		if webhookID, ok := registered[publication.ID]; ok {
+			publication.WebhookID = webhookID
+			delete(registered, publication.ID)
+			continue
+		}
+		webhookID, err := RegisterHashnodeWebhook(pat, publication.ID, url, secret)
+		if err != nil {
+			log.Printf("[WARN] Publication %s of user %s won't share on publish: %v", publication.Host, userId, err)
+			continue
+		}
+		publication.WebhookID = webhookID
+	}
+	for _, webhookID := range registered {
+		if err := DeleteHashnodeWebhook(pat, webhookID); err != nil {
+			log.Printf("[WARN] Failed to remove a webhook of user %s: %v", userId, err)
+		}
+	}
+	user.WebHookUrl = url
+	return nil
+}
+
+// DeletePublicationWebhooks removes the webhooks registered on the user's
+// Hashnode publications, as far as Hashnode lets it.
+func DeletePublicationWebhooks(user *models.User) {
+	pat, err := OpenCredential(user, user.SealedHashnodePAT, user.PlaintextHashnodePAT)
+	if err != nil {
+		log.Printf("[WARN] Failed to open the Hashnode token of user %s: %v", user.Id.Hex(), err)
+	}
+	for i := range user.HashnodePublications {
+		publication := &user.HashnodePublications[i]
+		if publication.WebhookID == "" {
+			continue
+		}
+		if err := DeleteHashnodeWebhook(pat, publication.WebhookID); err != nil {
+			log.Printf("[WARN] Failed to remove the webhook of publication %s of user %s: %v", publication.Host, user.Id.Hex(), err)
+		}
+		publication.WebhookID = ""
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func TestRegisterPublicationWebhooks(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var created []map[string]interface{}
+	var deleted []string
+	fake := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var query models.GraphQLQuery
+		json.NewDecoder(r.Body).Decode(&query)
+		response := ""
+		switch {
+		case r.Header.Get("Authorization") != "pat":
+			response = `{"errors": [{"message": "Invalid token"}]}`
+		case strings.Contains(query.Query, "createWebhook") && query.Variables["input"].(map[string]interface{})["publicationId"] == "p3":
+			response = `{"errors": [{"message": "Not the owner of the publication"}]}`
+		case strings.Contains(query.Query, "createWebhook"):
+			input := query.Variables["input"].(map[string]interface{})
+			created = append(created, input)
+			response = `{"data": {"createWebhook": {"webhook": {"id": "w-` + input["publicationId"].(string) + `"}}}}`
+		case strings.Contains(query.Query, "deleteWebhook"):
+			deleted = append(deleted, query.Variables["id"].(string))
+			response = `{"data": {"deleteWebhook": {"webhook": {"id": "` + query.Variables["id"].(string) + `"}}}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(response)), Request: r}, nil
+	})
+	previous := SetProviderTransport(fake)
+	defer SetProviderTransport(previous)
+
+	user := &models.User{
+		Id: primitive.NewObjectID(),
+		HashnodePublications: []models.HashnodePublication{
+			{ID: "p1", Host: "ada.hashnode.dev"},
+			{ID: "p2", Host: "notes.ada.dev"},
+			{ID: "p3", Host: "team.example.com"},
+		},
+	}
+	if err := SealCredential(user, &user.SealedHashnodePAT, &user.PlaintextHashnodePAT, "pat"); err != nil {
+		t.Fatal(err)
+	}
+	// p1 kept its webhook, and p0 is no longer the account's
+	found := []models.HashnodePublication{{ID: "p0", WebhookID: "w-p0"}, {ID: "p1", WebhookID: "w-p1"}}
+	if err := RegisterPublicationWebhooks(user, "https://api.example.com/api/v1/webhook/hashnode/1", found); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := OpenCredential(user, user.SealedHashnodeWebhookSecret, user.PlaintextHashnodeWebhookSecret)
+	if err != nil || len(secret) != 64 {
+		t.Errorf("secret = %q, %v", secret, err)
+	}
+	if len(created) != 1 || created[0]["publicationId"] != "p2" || created[0]["secret"] != secret || created[0]["url"] != user.WebHookUrl {
+		t.Errorf("created %+v", created)
+	}
+	webhooks := []string{}
+	for _, publication := range user.HashnodePublications {
+		webhooks = append(webhooks, publication.WebhookID)
+	}
+	if strings.Join(webhooks, ",") != "w-p1,w-p2," {
+		t.Errorf("webhooks = %v", webhooks)
+	}
+	if len(deleted) != 1 || deleted[0] != "w-p0" {
+		t.Errorf("deleted %v", deleted)
+	}
+
+	// A reconnect keeps the secret the webhooks were registered with
+	if err := RegisterPublicationWebhooks(user, user.WebHookUrl, user.HashnodePublications); err != nil {
+		t.Fatal(err)
+	}
+	if kept, err := OpenCredential(user, user.SealedHashnodeWebhookSecret, user.PlaintextHashnodeWebhookSecret); err != nil || kept != secret {
+		t.Errorf("secret changed to %q, %v", kept, err)
+	}
+
+	deleted = nil
+	DeletePublicationWebhooks(user)
+	if len(deleted) != 2 || user.HashnodePublications[0].WebhookID != "" || user.HashnodePublications[1].WebhookID != "" {
+		t.Errorf("deleted %v, left %+v", deleted, user.HashnodePublications)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// instagramCardSize is the side of the square card, Instagram's feed
+	// image size.
+	instagramCardSize = 1080
+	// maxInstagramCaption and maxInstagramHashtags are Instagram's limits
+	// on a caption.
+	maxInstagramCaption  = 2200
+	maxInstagramHashtags = 30
+	instagramBioNote     = "Read the full post via the link in bio."
+)
+
+var instagramHandlePattern = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+
+// NormalizeInstagramHandle checks an Instagram username, with or without
+// its leading @.
+func NormalizeInstagramHandle(handle string) (string, error) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if !instagramHandlePattern.MatchString(handle) {
+		return "", fmt.Errorf("an Instagram username has up to 30 letters, digits, periods and underscores: %w", apperrors.ErrInvalidInput)
+	}
+	return handle, nil
+}
+
+// InstagramCaption is the caption of an Instagram post for a blog. Links in
+// captions aren't clickable, so readers are pointed at the bio instead, and
+// the blog's tags become hashtags within Instagram's limit. The caption is
+// cut short to fit what Instagram takes.
+func InstagramCaption(caption string, tags []string) string {
+	caption = strings.TrimSpace(caption)
+	hashtags := []string{}
+	seen := map[string]bool{}
+	room := maxInstagramHashtags
+	for _, word := range strings.Fields(caption) {
+		if strings.HasPrefix(word, "#") {
+			seen[strings.ToLower(strings.TrimPrefix(word, "#"))] = true
+			room--
+		}
+	}
+	for _, tag := range tags {
+		// Hashtags end at the first character that isn't a letter,
+		// digit or underscore, so slugs lose their hyphens
+		hashtag := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, tag)
+		if hashtag == "" || seen[strings.ToLower(hashtag)] || len(hashtags) >= room {
+			continue
+		}
+		seen[strings.ToLower(hashtag)] = true
+		hashtags = append(hashtags, "#"+hashtag)
+	}
+
+	footer := "\n\n" + instagramBioNote
+	if len(hashtags) > 0 {
+		footer += "\n\n" + strings.Join(hashtags, " ")
+	}
+	if room := maxInstagramCaption - len([]rune(footer)); len([]rune(caption)) > room {
+		caption = strings.TrimSpace(string([]rune(caption)[:room-1])) + "…"
+	}
+	return caption + footer
+}
+
+// RenderInstagramCard makes the square card of an Instagram post from the
+// image at imageURL. The whole image is kept, fitted in the square on a
+// background of its average color, since cropping a landscape cover would
+// cut off its sides.
+func RenderInstagramCard(imageURL string) ([]byte, error) {
+	data, _, _, err := fetchCoverImage(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the cover image: %v", err)
+	}
+	bounds := src.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, fmt.Errorf("the cover image is empty")
+	}
+
+	average := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	scaleImage(average, src)
+	background := average.RGBAAt(0, 0)
+	background.A = 255
+	card := image.NewRGBA(image.Rect(0, 0, instagramCardSize, instagramCardSize))
+	draw.Draw(card, card.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	width, height := instagramCardSize, instagramCardSize
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, instagramCardSize*bounds.Dy()/bounds.Dx())
+	} else {
+		width = max(1, instagramCardSize*bounds.Dx()/bounds.Dy())
+	}
+	fitted := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleImage(fitted, src)
+	offset := image.Pt((instagramCardSize-width)/2, (instagramCardSize-height)/2)
+	draw.Draw(card, fitted.Bounds().Add(offset), fitted, image.Point{}, draw.Over)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, card, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode the card: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// scaleImage resizes src to fill dst, each pixel of dst averaging the
+// pixels of src it covers.
+func scaleImage(dst *image.RGBA, src image.Image) {
+	sb, db := src.Bounds(), dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/db.Dy()
+		y1 := max(sb.Min.Y+(y+1)*sb.Dy()/db.Dy(), y0+1)
+		for x := 0; x < db.Dx(); x++ {
+			x0 := sb.Min.X + x*sb.Dx()/db.Dx()
+			x1 := max(sb.Min.X+(x+1)*sb.Dx()/db.Dx(), x0+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(db.Min.X+x, db.Min.Y+y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+}
+
+// makeInstagramKit stores the caption and card of a blog as a manual task
+// for the user to post on Instagram, and reminds them with a notification
+// and, if they have a verified address, an email holding the caption.
+func makeInstagramKit(user *models.User, share *Share) (string, error) {
+	card, err := RenderInstagramCard(share.CardImage)
+	if err != nil {
+		return "", fmt.Errorf("failed to make the Instagram card: %w", err)
+	}
+	task := models.ManualTask{
+		UserID:    user.Id.Hex(),
+		Platform:  "instagram",
+		BlogID:    share.BlogID,
+		BlogTitle: share.Title,
+		BlogURL:   share.Link("instagram"),
+		Caption:   InstagramCaption(share.Caption, share.Tags),
+		Image:     card,
+		ImageType: "image/jpeg",
+		Status:    models.ManualTaskPending,
+		CreatedAt: utils.Now(),
+	}
+	if _, err := repositories.CreateManualTask(task); err != nil {
+		return "", fmt.Errorf("failed to store the Instagram kit: %w", err)
+	}
+	handle := ""
+	if user.Instagram != nil {
+		handle = " as @" + user.Instagram.Handle
+	}
+	user.AddNotification(fmt.Sprintf("Time to post %q on Instagram%s: its caption and card are ready in your manual tasks", share.Title, handle), task.CreatedAt)
+	SendManualTaskEmail(user, &task, "Instagram")
+	log.Printf("[INFO] Instagram kit of blog %s is ready for user %s", share.BlogID, task.UserID)
+	return "", nil
+}
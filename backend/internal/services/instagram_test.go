@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+)
+
+func TestInstagramCaption(t *testing.T) {
+	caption := InstagramCaption("Scheduling posts, explained. #golang", []string{"golang", "social-media", "web-dev"})
+	want := "Scheduling posts, explained. #golang\n\n" + instagramBioNote + "\n\n#socialmedia #webdev"
+	if caption != want {
+		t.Errorf("caption = %q, want %q", caption, want)
+	}
+
+	caption = InstagramCaption(strings.Repeat("a", 3000), []string{"go"})
+	if n := len([]rune(caption)); n != maxInstagramCaption || !strings.HasSuffix(caption, "…\n\n"+instagramBioNote+"\n\n#go") {
+		t.Errorf("a long caption became %d characters, ending %q", n, caption[len(caption)-60:])
+	}
+
+	if _, err := NormalizeInstagramHandle("ada lovelace"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted a username with a space: %v", err)
+	}
+	if handle, err := NormalizeInstagramHandle(" @ada.codes "); err != nil || handle != "ada.codes" {
+		t.Errorf("NormalizeInstagramHandle = %q, %v", handle, err)
+	}
+}
+
+func TestRenderInstagramCard(t *testing.T) {
+	// Red on the left and blue on the right, averaging to purple
+	cover := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			pixel := color.RGBA{R: 200, B: 40, A: 255}
+			if x >= 200 {
+				pixel = color.RGBA{R: 40, B: 200, A: 255}
+			}
+			cover.Set(x, y, pixel)
+		}
+	}
+	var encoded bytes.Buffer
+	png.Encode(&encoded, cover)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(encoded.Bytes())
+	}))
+	defer server.Close()
+	previous := checkImageHost
+	checkImageHost = func(string) error { return nil }
+	defer func() { checkImageHost = previous }()
+
+	card, err := RenderInstagramCard(server.URL + "/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := jpeg.Decode(bytes.NewReader(card))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := rendered.Bounds().Size(); size.X != instagramCardSize || size.Y != instagramCardSize {
+		t.Fatalf("the card is %v", size)
+	}
+	// The cover fills a band across the middle, on its average color
+	for point, want := range map[image.Point]color.RGBA{
+		{270, 540}:  {R: 200, B: 40},
+		{810, 540}:  {R: 40, B: 200},
+		{540, 10}:   {R: 120, B: 120},
+		{540, 1070}: {R: 120, B: 120},
+	} {
+		r, g, b, _ := rendered.At(point.X, point.Y).RGBA()
+		if channelDistance(r>>8, want.R) > 12 || channelDistance(g>>8, 0) > 12 || channelDistance(b>>8, want.B) > 12 {
+			t.Errorf("the card at %v is %d, %d, %d, want %v", point, r>>8, g>>8, b>>8, want)
+		}
+	}
+}
+
+func channelDistance(a uint32, b uint8) uint32 {
+	if a > uint32(b) {
+		return a - uint32(b)
+	}
+	return uint32(b) - a
+}
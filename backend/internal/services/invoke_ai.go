@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"social-scribe/backend/internal/apperrors"
 )
 
 func invokeAi(prompt string) (string, error) {
@@ -34,7 +35,7 @@ func invokeAi(prompt string) (string, error) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := getProviderClient(ProviderAI)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %v", err)
@@ -46,6 +47,9 @@ func invokeAi(prompt string) (string, error) {
 		return "", fmt.Errorf("failed to read response body: %v", err)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("AI provider throttled the request: %w", apperrors.ErrProviderRateLimited)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("API error: %s", body)
 	}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// maxLemmyCommunities is how many communities a blog is submitted to.
+	maxLemmyCommunities = 5
+	// lemmyCommunityCooldown is how long a user waits between two links to
+	// the same community, so a burst of shares doesn't read as spam.
+	lemmyCommunityCooldown = 10 * time.Minute
+	// lemmyRateLimitCooldown is how long a community is left alone after
+	// the instance throttled a submission to it. Lemmy doesn't say when
+	// its limit resets.
+	lemmyRateLimitCooldown = time.Minute
+	// maxLemmyTitleLength is the longest post title Lemmy takes.
+	maxLemmyTitleLength = 200
+)
+
+var (
+	lemmyCooldownMu sync.Mutex
+	// lemmyCooldownUntil is when a user can submit to a community again,
+	// keyed by user and community.
+	lemmyCooldownUntil = map[string]time.Time{}
+)
+
+var lemmyCommunityPattern = regexp.MustCompile(`^[a-z0-9_]{3,20}(@[a-z0-9.-]+\.[a-z]{2,})?$`)
+
+// NormalizeLemmyInstance reduces what a user typed for their Lemmy server,
+// such as "https://Lemmy.World/", to its host. Only hosts resolving to public
+// addresses are accepted.
+func NormalizeLemmyInstance(raw string) (string, error) {
+	return publicServers.normalizeLemmyInstance(raw)
+}
+
+func (s userServers) normalizeLemmyInstance(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" ||
+		strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("instance must be the host name of a Lemmy server: %w", apperrors.ErrInvalidInput)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if err := s.checkHost(host); err != nil {
+		return "", fmt.Errorf("instance must be a public Lemmy server: %w", apperrors.ErrInvalidInput)
+	}
+	return host, nil
+}
+
+// NormalizeLemmyCommunity reduces "!golang@lemmy.ml" or "/c/golang" to the
+// name Lemmy looks communities up by.
+func NormalizeLemmyCommunity(raw string) (string, error) {
+	name := strings.ToLower(strings.Trim(strings.TrimSpace(raw), "/"))
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "!"), "c/")
+	if !lemmyCommunityPattern.MatchString(name) {
+		return "", fmt.Errorf("%q is not a Lemmy community: %w", raw, apperrors.ErrInvalidInput)
+	}
+	return name, nil
+}
+
+func (s userServers) lemmyURL(instance, path string) string {
+	return s.scheme + "://" + instance + "/api/v3" + path
+}
+
+// lemmyCall sends a request to a Lemmy server and decodes its JSON response
+// into out. Lemmy reports most failures as a 400 with an error code.
+func lemmyCall(userId, jwt string, req *http.Request, out interface{}) error {
+	if jwt != "" {
+		req.Header.Set("Authorization", "Bearer "+jwt)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(ProviderLemmy).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "lemmy", req.URL.String(), resp.StatusCode, body)
+	var failure struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &failure)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || failure.Error == "rate_limit_error":
+		return fmt.Errorf("%s throttled the request: %w", req.URL.Host, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || failure.Error == "not_logged_in" || failure.Error == "incorrect_login":
+		return fmt.Errorf("%s rejected the login: %w", req.URL.Host, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusNotFound || failure.Error == "couldnt_find_community":
+		return fmt.Errorf("%s doesn't know it: %w", req.URL.Host, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%s refused the request, %s: %w", req.URL.Host, failure.Error, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of %s: %v", req.URL.Host, err)
+	}
+	return nil
+}
+
+func (s userServers) lemmyJSON(method, instance, path string, payload interface{}) (*http.Request, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	req, err := http.NewRequest(method, s.lemmyURL(instance, path), bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// LoginLemmy logs the user in to their Lemmy server and returns the session
+// token and the account's name. The password isn't kept.
+func LoginLemmy(userId, instance, usernameOrEmail, password, totp string) (string, string, error) {
+	return publicServers.loginLemmy(userId, instance, usernameOrEmail, password, totp)
+}
+
+func (s userServers) loginLemmy(userId, instance, usernameOrEmail, password, totp string) (string, string, error) {
+	login := map[string]string{"username_or_email": usernameOrEmail, "password": password}
+	if totp != "" {
+		login["totp_2fa_token"] = totp
+	}
+	req, err := s.lemmyJSON(http.MethodPost, instance, "/user/login", login)
+	if err != nil {
+		return "", "", err
+	}
+	var session struct {
+		JWT string `json:"jwt"`
+	}
+	if err := lemmyCall(userId, "", req, &session); err != nil {
+		return "", "", fmt.Errorf("failed to log in to %s: %w", instance, err)
+	}
+	if session.JWT == "" {
+		return "", "", fmt.Errorf("%s needs the email address verified or the registration approved first: %w", instance, apperrors.ErrInvalidInput)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, s.lemmyURL(instance, "/site"), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %v", err)
+	}
+	var site struct {
+		MyUser struct {
+			LocalUserView struct {
+				Person struct {
+					Name string `json:"name"`
+				} `json:"person"`
+			} `json:"local_user_view"`
+		} `json:"my_user"`
+	}
+	if err := lemmyCall(userId, session.JWT, req, &site); err != nil {
+		return "", "", fmt.Errorf("failed to look up the Lemmy account: %w", err)
+	}
+	return session.JWT, site.MyUser.LocalUserView.Person.Name, nil
+}
+
+// lemmyJWT opens the user's Lemmy session token.
+func lemmyJWT(user *models.User) (string, error) {
+	if user.Lemmy == nil {
+		return "", fmt.Errorf("Lemmy is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	jwt, err := OpenUserSecret(user, user.Lemmy.SealedJWT)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Lemmy session: %v", err)
+	}
+	return jwt, nil
+}
+
+// ResolveLemmyCommunities looks the communities up on the user's instance,
+// which learns of a remote community the first time it is asked for it.
+func ResolveLemmyCommunities(userId, instance, jwt string, names []string) ([]models.LemmyCommunity, error) {
+	return publicServers.resolveLemmyCommunities(userId, instance, jwt, names)
+}
+
+func (s userServers) resolveLemmyCommunities(userId, instance, jwt string, names []string) ([]models.LemmyCommunity, error) {
+	if len(names) > maxLemmyCommunities {
+		return nil, fmt.Errorf("at most %d communities can be chosen: %w", maxLemmyCommunities, apperrors.ErrInvalidInput)
+	}
+	communities := []models.LemmyCommunity{}
+	seen := map[string]bool{}
+	for _, raw := range names {
+		name, err := NormalizeLemmyCommunity(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		req, err := http.NewRequest(http.MethodGet, s.lemmyURL(instance, "/community?name="+url.QueryEscape(name)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		var found struct {
+			CommunityView struct {
+				Community struct {
+					ID    int    `json:"id"`
+					Title string `json:"title"`
+				} `json:"community"`
+			} `json:"community_view"`
+		}
+		if err := lemmyCall(userId, jwt, req, &found); err != nil {
+			return nil, fmt.Errorf("failed to look up the community %s: %w", name, err)
+		}
+		community := found.CommunityView.Community
+		communities = append(communities, models.LemmyCommunity{ID: community.ID, Name: name, Title: community.Title})
+	}
+	return communities, nil
+}
+
+func lemmyCooldownKey(userId, community string) string {
+	return userId + "/" + community
+}
+
+// lemmyCoolingDown returns when the user can submit to the community again,
+// or the zero time if they can now.
+func lemmyCoolingDown(userId, community string) time.Time {
+	lemmyCooldownMu.Lock()
+	defer lemmyCooldownMu.Unlock()
+	key := lemmyCooldownKey(userId, community)
+	until, ok := lemmyCooldownUntil[key]
+	if ok && !utils.Now().Before(until) {
+		delete(lemmyCooldownUntil, key)
+		return time.Time{}
+	}
+	return until
+}
+
+func lemmyCoolDown(userId, community string, d time.Duration) {
+	lemmyCooldownMu.Lock()
+	lemmyCooldownUntil[lemmyCooldownKey(userId, community)] = utils.Now().Add(d)
+	lemmyCooldownMu.Unlock()
+}
+
+// submitLemmyLink posts the blog link to each of the user's communities and
+// returns the URL of the first post. Communities the user submitted to
+// recently are skipped, so sharing again right after a partial failure
+// doesn't post twice where it already went through.
+func (s userServers) submitLemmyLink(user *models.User, title, link string) (string, error) {
+	jwt, err := lemmyJWT(user)
+	if err != nil {
+		return "", err
+	}
+	account := user.Lemmy
+	if len(account.Communities) == 0 {
+		return "", fmt.Errorf("no community is chosen for Lemmy: %w", apperrors.ErrInvalidInput)
+	}
+	userId := user.Id.Hex()
+	var postURL string
+	var cooling []string
+	for _, community := range account.Communities {
+		if until := lemmyCoolingDown(userId, community.Name); !until.IsZero() {
+			log.Printf("[INFO] Skipped the Lemmy community %s of user %s, cooling down for %v", community.Name, userId, until.Sub(utils.Now()).Round(time.Second))
+			cooling = append(cooling, community.Name)
+			continue
+		}
+		post := map[string]interface{}{
+			"name":         truncateRunes(title, maxLemmyTitleLength),
+			"community_id": community.ID,
+			"url":          link,
+		}
+		req, err := s.lemmyJSON(http.MethodPost, account.Instance, "/post", post)
+		if err != nil {
+			return "", err
+		}
+		var created struct {
+			PostView struct {
+				Post struct {
+					ID int `json:"id"`
+				} `json:"post"`
+			} `json:"post_view"`
+		}
+		if err := lemmyCall(userId, jwt, req, &created); err != nil {
+			if errors.Is(err, apperrors.ErrProviderRateLimited) {
+				lemmyCoolDown(userId, community.Name, lemmyRateLimitCooldown)
+			}
+			return "", fmt.Errorf("failed to submit to %s: %w", community.Name, err)
+		}
+		lemmyCoolDown(userId, community.Name, lemmyCommunityCooldown)
+		if postURL == "" {
+			postURL = s.scheme + "://" + account.Instance + "/post/" + strconv.Itoa(created.PostView.Post.ID)
+		}
+	}
+	if postURL == "" {
+		return "", fmt.Errorf("every community is cooling down (%s): %w", strings.Join(cooling, ", "), apperrors.ErrProviderRateLimited)
+	}
+	return postURL, nil
+}
+
+// ChangeLemmyCommunities looks up the communities the user chose with their
+// stored session.
+func ChangeLemmyCommunities(user *models.User, names []string) ([]models.LemmyCommunity, error) {
+	jwt, err := lemmyJWT(user)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveLemmyCommunities(user.Id.Hex(), user.Lemmy.Instance, jwt, names)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+func TestNormalizeLemmyCommunity(t *testing.T) {
+	for raw, want := range map[string]string{
+		"golang":                 "golang",
+		"!golang@lemmy.ml":       "golang@lemmy.ml",
+		"/c/Programming/":        "programming",
+		"c/rust@programming.dev": "rust@programming.dev",
+		"go":                     "",
+		"golang@":                "",
+		"golang@localhost":       "",
+		"r/golang":               "",
+	} {
+		got, err := NormalizeLemmyCommunity(raw)
+		if want == "" {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("NormalizeLemmyCommunity(%q) = %q, %v; want invalid input", raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizeLemmyCommunity(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+}
+
+func TestLemmyConnectAndSubmit(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	clock := utils.NewFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	defer utils.SetClock(utils.SetClock(clock))
+	submitted := map[int]int{}
+	throttled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/user/login" {
+			var login map[string]string
+			json.NewDecoder(r.Body).Decode(&login)
+			if login["username_or_email"] != "ada" || login["password"] != "hunter22" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "incorrect_login"}`))
+				return
+			}
+			w.Write([]byte(`{"jwt": "session-1"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer session-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "not_logged_in"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v3/site":
+			w.Write([]byte(`{"my_user": {"local_user_view": {"person": {"id": 7, "name": "ada"}}}}`))
+		case "/api/v3/community":
+			switch r.URL.Query().Get("name") {
+			case "golang":
+				w.Write([]byte(`{"community_view": {"community": {"id": 11, "name": "golang", "title": "Go"}}}`))
+			case "rust@programming.dev":
+				w.Write([]byte(`{"community_view": {"community": {"id": 12, "name": "rust", "title": "Rust"}}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": "couldnt_find_community"}`))
+			}
+		case "/api/v3/post":
+			var post struct {
+				Name        string `json:"name"`
+				CommunityID int    `json:"community_id"`
+				URL         string `json:"url"`
+			}
+			json.NewDecoder(r.Body).Decode(&post)
+			if throttled && post.CommunityID == 12 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "rate_limit_error"}`))
+				return
+			}
+			submitted[post.CommunityID]++
+			fmt.Fprintf(w, `{"post_view": {"post": {"id": %d}}}`, 90+post.CommunityID)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	servers := localServers()
+	instance := strings.TrimPrefix(server.URL, "http://")
+
+	if _, _, err := servers.loginLemmy("", instance, "ada", "wrong", ""); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("logged in with a wrong password: %v", err)
+	}
+	jwt, username, err := servers.loginLemmy("", instance, "ada", "hunter22", "")
+	if err != nil || jwt != "session-1" || username != "ada" {
+		t.Fatalf("logged in as %q with %q, %v", username, jwt, err)
+	}
+	if _, err := servers.resolveLemmyCommunities("", instance, jwt, []string{"golang", "nowhere"}); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("resolved a missing community: %v", err)
+	}
+	communities, err := servers.resolveLemmyCommunities("", instance, jwt, []string{"!golang", "golang", "rust@programming.dev"})
+	if err != nil || len(communities) != 2 || communities[1].ID != 12 {
+		t.Fatalf("resolved %+v, %v", communities, err)
+	}
+
+	user := &models.User{Id: primitive.NewObjectID(), Lemmy: &models.LemmyAccount{Instance: instance, Username: username, Communities: communities}}
+	if user.Lemmy.SealedJWT, err = SealUserSecret(user, jwt); err != nil {
+		t.Fatal(err)
+	}
+	postURL, err := servers.submitLemmyLink(user, "Scheduling posts", "https://blog.example.com/scheduling")
+	if err != nil || postURL != "http://"+instance+"/post/101" {
+		t.Fatalf("submitted at %q, %v", postURL, err)
+	}
+	if submitted[11] != 1 || submitted[12] != 1 {
+		t.Errorf("submissions %v, want one per community", submitted)
+	}
+
+	// Both communities are cooling down now
+	if _, err := servers.submitLemmyLink(user, "Scheduling posts", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrProviderRateLimited) {
+		t.Errorf("submitted during the cooldown: %v", err)
+	}
+	if submitted[11] != 1 || submitted[12] != 1 {
+		t.Errorf("submissions %v during the cooldown", submitted)
+	}
+
+	// A community the instance throttled cools down for a shorter while
+	clock.Advance(lemmyCommunityCooldown)
+	throttled = true
+	if _, err := servers.submitLemmyLink(user, "Scheduling posts", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrProviderRateLimited) {
+		t.Errorf("throttled submission: %v", err)
+	}
+	if until := lemmyCoolingDown(user.Id.Hex(), "rust@programming.dev"); until.IsZero() || until.Sub(utils.Now()) > lemmyRateLimitCooldown {
+		t.Errorf("throttled community cools down until %v", until)
+	}
+	if submitted[11] != 2 {
+		t.Errorf("submissions %v after the cooldown", submitted)
+	}
+}
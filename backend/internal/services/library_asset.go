@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+)
+
+// ResolveShareAssets loads the library assets referenced by a share of user,
+// in the order they were given. Every id must be an asset of the user's team.
+func ResolveShareAssets(user *models.User, assetIDs []string) ([]models.LibraryAsset, error) {
+	if len(assetIDs) == 0 {
+		return nil, nil
+	}
+	if user.TeamID == "" {
+		return nil, fmt.Errorf("library assets are only available to team members: %w", apperrors.ErrForbidden)
+	}
+	if len(assetIDs) > models.MaxShareAssets {
+		return nil, fmt.Errorf("at most %d library assets can be applied to a share: %w", models.MaxShareAssets, apperrors.ErrInvalidInput)
+	}
+	assets, err := repositories.GetLibraryAssets(user.TeamID, assetIDs)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]models.LibraryAsset, len(assets))
+	for _, asset := range assets {
+		byId[asset.Id.Hex()] = asset
+	}
+	ordered := make([]models.LibraryAsset, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		asset, ok := byId[assetID]
+		if !ok {
+			return nil, fmt.Errorf("library asset %s: %w", assetID, apperrors.ErrNotFound)
+		}
+		ordered = append(ordered, asset)
+	}
+	return ordered, nil
+}
+
+// ApplyLibraryAssets adds caption snippets and hashtag sets to the caption, in
+// the order given, and returns the image of the last image asset in place of
+// the card image.
+func ApplyLibraryAssets(caption, cardImage string, assets []models.LibraryAsset) (string, string) {
+	var hashtags []string
+	seen := map[string]bool{}
+	for _, asset := range assets {
+		switch asset.Kind {
+		case models.AssetImage:
+			cardImage = asset.ImageURL
+		case models.AssetCaption:
+			caption = strings.TrimSpace(caption) + "\n\n" + asset.Text
+		case models.AssetHashtags:
+			for _, tag := range asset.Hashtags {
+				if key := strings.ToLower(tag); !seen[key] {
+					seen[key] = true
+					hashtags = append(hashtags, "#"+tag)
+				}
+			}
+		}
+	}
+	if len(hashtags) > 0 {
+		caption = strings.TrimSpace(caption) + "\n\n" + strings.Join(hashtags, " ")
+	}
+	return caption, cardImage
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestApplyLibraryAssets(t *testing.T) {
+	assets := []models.LibraryAsset{
+		{Kind: models.AssetHashtags, Hashtags: []string{"golang", "DevRel"}},
+		{Kind: models.AssetCaption, Text: "Brought to you by Acme."},
+		{Kind: models.AssetImage, ImageURL: "https://cdn.example.com/brand.png"},
+		{Kind: models.AssetHashtags, Hashtags: []string{"devrel", "acme"}},
+	}
+	caption, image := ApplyLibraryAssets("New post is out! ", "https://cdn.example.com/cover.png", assets)
+
+	want := "New post is out!\n\nBrought to you by Acme.\n\n#golang #DevRel #acme"
+	if caption != want {
+		t.Errorf("caption = %q, want %q", caption, want)
+	}
+	if image != "https://cdn.example.com/brand.png" {
+		t.Errorf("card image = %q", image)
+	}
+
+	caption, image = ApplyLibraryAssets("Unchanged", "https://cdn.example.com/cover.png", nil)
+	if caption != "Unchanged" || image != "https://cdn.example.com/cover.png" {
+		t.Errorf("without assets got %q, %q", caption, image)
+	}
+}
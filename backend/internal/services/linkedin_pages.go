@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// LinkedInPageScopes are asked for on top of the member scopes when the
+// member wants to post for the LinkedIn Pages they administer.
+var LinkedInPageScopes = []string{"r_organization_social", "w_organization_social"}
+
+// linkedInPageRoles are the Page roles that may post for the Page.
+var linkedInPageRoles = []string{"ADMINISTRATOR", "CONTENT_ADMINISTRATOR"}
+
+// LinkedInGrantsPages reports whether the scopes LinkedIn granted a token,
+// space or comma separated, let it post for Pages.
+func LinkedInGrantsPages(granted string) bool {
+	scopes := strings.FieldsFunc(granted, func(r rune) bool { return r == ' ' || r == ',' })
+	return slices.Contains(scopes, "w_organization_social")
+}
+
+// LookupLinkedInPages lists the Pages the member may post for.
+func LookupLinkedInPages(userId, accessToken string) ([]models.LinkedInPage, error) {
+	endpoint := "https://api.linkedin.com/v2/organizationAcls?q=roleAssignee&state=APPROVED" +
+		"&projection=(elements*(role,organization~(id,localizedName,vanityName)))"
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("X-Restli-Protocol-Version", "2.0.0")
+
+	resp, err := getProviderClient(ProviderLinkedIn).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "linkedin", req.URL.String(), resp.StatusCode, body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("LinkedIn rejected the Page lookup: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("LinkedIn refused to list the member's Pages: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to list Pages, status code: %d, response: %s", resp.StatusCode, body)
+	}
+
+	var acls struct {
+		Elements []struct {
+			Role         string `json:"role"`
+			Organization struct {
+				ID            int64  `json:"id"`
+				LocalizedName string `json:"localizedName"`
+				VanityName    string `json:"vanityName"`
+			} `json:"organization~"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(body, &acls); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	pages := []models.LinkedInPage{}
+	for _, acl := range acls.Elements {
+		organization := acl.Organization
+		if organization.ID == 0 || !slices.Contains(linkedInPageRoles, acl.Role) {
+			continue
+		}
+		id := fmt.Sprint(organization.ID)
+		// A member holding both roles on a Page is listed twice
+		if slices.ContainsFunc(pages, func(page models.LinkedInPage) bool { return page.ID == id }) {
+			continue
+		}
+		pages = append(pages, models.LinkedInPage{ID: id, Name: organization.LocalizedName, VanityName: organization.VanityName})
+	}
+	return pages, nil
+}
+
+// ValidateLinkedInPage checks that a share may target the Page: one the
+// member administers, LinkedInMemberProfile or empty for their choice.
+func ValidateLinkedInPage(user *models.User, page string) error {
+	if page == "" || page == models.LinkedInMemberProfile {
+		return nil
+	}
+	if _, ok := findLinkedInPage(user, page); !ok {
+		return fmt.Errorf("linkedin_page %q is not a LinkedIn Page you can post for: %w", page, apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+func findLinkedInPage(user *models.User, id string) (models.LinkedInPage, bool) {
+	for _, page := range user.LinkedInPages {
+		if page.ID == id {
+			return page, true
+		}
+	}
+	return models.LinkedInPage{}, false
+}
+
+// LinkedInToken opens the user's LinkedIn access token.
+func LinkedInToken(user *models.User) (string, error) {
+	return OpenCredential(user, user.SealedLinkedInOauthKey, user.PlaintextLinkedInOauthKey)
+}
+
+// linkedInAuthor returns the URN a share targeting the Page is posted as.
+// Without a Page, shares go where the member chose, and to their profile
+// when they chose none.
+func linkedInAuthor(user *models.User, page string) (string, error) {
+	if page == "" {
+		page = user.LinkedInPageID
+	}
+	if page == "" || page == models.LinkedInMemberProfile {
+		token, err := LinkedInToken(user)
+		if err != nil {
+			return "", err
+		}
+		return getUserURN(user.Id.Hex(), token)
+	}
+	if err := ValidateLinkedInPage(user, page); err != nil {
+		return "", err
+	}
+	return "urn:li:organization:" + page, nil
+}
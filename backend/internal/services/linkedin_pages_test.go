@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestLookupLinkedInPages(t *testing.T) {
+	acls := `{"elements": [
+		{"role": "ADMINISTRATOR", "organization~": {"id": 101, "localizedName": "Scribe Labs", "vanityName": "scribe-labs"}},
+		{"role": "CONTENT_ADMINISTRATOR", "organization~": {"id": 101, "localizedName": "Scribe Labs", "vanityName": "scribe-labs"}},
+		{"role": "ANALYST", "organization~": {"id": 202, "localizedName": "Read only"}},
+		{"role": "CONTENT_ADMINISTRATOR", "organization~": {"id": 303, "localizedName": "Scribe Blog"}}
+	]}`
+	fake := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status := http.StatusOK
+		if r.Header.Get("Authorization") != "Bearer token" {
+			status = http.StatusUnauthorized
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(acls)), Request: r}, nil
+	})
+	previous := SetProviderTransport(fake)
+	defer SetProviderTransport(previous)
+
+	pages, err := LookupLinkedInPages("user-1", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.LinkedInPage{{ID: "101", Name: "Scribe Labs", VanityName: "scribe-labs"}, {ID: "303", Name: "Scribe Blog"}}
+	if len(pages) != len(want) || pages[0] != want[0] || pages[1] != want[1] {
+		t.Errorf("pages = %+v, want %+v", pages, want)
+	}
+	if _, err := LookupLinkedInPages("user-1", "revoked"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up Pages with a revoked token: %v", err)
+	}
+}
+
+func TestLinkedInAuthor(t *testing.T) {
+	if !LinkedInGrantsPages("openid profile w_member_social w_organization_social") || !LinkedInGrantsPages("r_organization_social,w_organization_social") {
+		t.Error("didn't see the Page scope among the granted ones")
+	}
+	if LinkedInGrantsPages("openid profile w_member_social") {
+		t.Error("saw the Page scope where none was granted")
+	}
+
+	user := &models.User{Id: primitive.NewObjectID(), LinkedInPages: []models.LinkedInPage{{ID: "101"}}, LinkedInPageID: "101"}
+	if author, err := linkedInAuthor(user, ""); err != nil || author != "urn:li:organization:101" {
+		t.Errorf("default author = %q, %v", author, err)
+	}
+	if _, err := linkedInAuthor(user, "202"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("posted as a Page the member doesn't administer: %v", err)
+	}
+	if err := ValidateLinkedInPage(user, models.LinkedInMemberProfile); err != nil {
+		t.Errorf("rejected the member profile: %v", err)
+	}
+}
@@ -6,24 +6,54 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
 )
 
-func linkedPostHandler(message, accessToken string) error {
-	userURN, err := getUserURN(accessToken)
-	if err != nil {
-		return fmt.Errorf("failed to fetch user ID: %v", err)
-	}
+// maxLinkedInCommentary is the longest text LinkedIn takes on a post.
+const maxLinkedInCommentary = 3000
+
+// linkedInRevokeURL is replaced by tests.
+var linkedInRevokeURL = "https://www.linkedin.com/oauth/v2/revoke"
+
+// linkedInArticle is the blog post a LinkedIn share links to, shown as a card.
+type linkedInArticle struct {
+	URL      string
+	Title    string
+	ImageURL string
+}
 
+// linkedPostHandler posts the message as author, the member or a Page they
+// administer, and returns the post's URL, which is empty if LinkedIn didn't
+// return the post's id. With an article the post carries a card linking to
+// it.
+func linkedPostHandler(userId, author, message, accessToken string, article *linkedInArticle) (string, error) {
+	shareContent := map[string]interface{}{
+		"shareCommentary": map[string]interface{}{
+			"text": message,
+		},
+		"shareMediaCategory": "NONE",
+	}
+	if article != nil && article.URL != "" {
+		media := map[string]interface{}{
+			"status":      "READY",
+			"originalUrl": article.URL,
+			"title":       map[string]interface{}{"text": article.Title},
+		}
+		if article.ImageURL != "" {
+			media["thumbnails"] = []map[string]interface{}{{"url": article.ImageURL}}
+		}
+		shareContent["shareMediaCategory"] = "ARTICLE"
+		shareContent["media"] = []map[string]interface{}{media}
+	}
 	postData := map[string]interface{}{
-		"author":         userURN,
+		"author":         author,
 		"lifecycleState": "PUBLISHED",
 		"specificContent": map[string]interface{}{
-			"com.linkedin.ugc.ShareContent": map[string]interface{}{
-				"shareCommentary": map[string]interface{}{
-					"text": message,
-				},
-				"shareMediaCategory": "NONE",
-			},
+			"com.linkedin.ugc.ShareContent": shareContent,
 		},
 		"visibility": map[string]interface{}{
 			"com.linkedin.ugc.MemberNetworkVisibility": "PUBLIC",
@@ -32,49 +62,79 @@ func linkedPostHandler(message, accessToken string) error {
 
 	postBody, err := json.Marshal(postData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal post data: %v", err)
+		return "", fmt.Errorf("failed to marshal post data: %v", err)
 	}
 
 	req, err := http.NewRequest("POST", "https://api.linkedin.com/v2/ugcPosts", bytes.NewBuffer(postBody))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Restli-Protocol-Version", "2.0.0")
 
-	client := &http.Client{}
+	client := getProviderClient(ProviderLinkedIn)
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send post request: %v", err)
+		return "", fmt.Errorf("failed to send post request: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(resp.Body)
+	archiveProviderResponse(userId, "linkedin", req.URL.String(), resp.StatusCode, body)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("LinkedIn rejected the post: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", fmt.Errorf("LinkedIn access token was rejected: %w", apperrors.ErrProviderAuth)
+	}
+	if resp.StatusCode == http.StatusForbidden && strings.HasPrefix(author, "urn:li:organization:") {
+		return "", fmt.Errorf("LinkedIn refused the post for the Page, reconnect with Page access: %w", apperrors.ErrProviderAuth)
+	}
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create post, status code: %d, response: %s", resp.StatusCode, body)
+		return "", fmt.Errorf("failed to create post, status code: %d, response: %s", resp.StatusCode, body)
 	}
 
-	return nil
+	postURN := resp.Header.Get("X-RestLi-Id")
+	if postURN == "" {
+		var created struct {
+			Id string `json:"id"`
+		}
+		json.Unmarshal(body, &created)
+		postURN = created.Id
+	}
+	if postURN == "" {
+		return "", nil
+	}
+	return "https://www.linkedin.com/feed/update/" + postURN + "/", nil
 }
 
-func getUserURN(accessToken string) (string, error) {
+func getUserURN(userId, accessToken string) (string, error) {
 	req, err := http.NewRequest("GET", "https://api.linkedin.com/v2/userinfo", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{}
+	client := getProviderClient(ProviderLinkedIn)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	archiveProviderResponse(userId, "linkedin", req.URL.String(), resp.StatusCode, body)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("LinkedIn rejected the user lookup: %w", apperrors.ErrProviderRateLimited)
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
 		return "", fmt.Errorf("failed to get user ID, status code: %d, response: %s", resp.StatusCode, body)
 	}
 
@@ -82,9 +142,43 @@ func getUserURN(accessToken string) (string, error) {
 		ID string `json:"sub"`
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&data)
+	err = json.Unmarshal(body, &data)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse response: %v", err)
 	}
 	return "urn:li:person:" + data.ID, nil
 }
+
+// RevokeLinkedInToken invalidates the access token with the app that issued
+// it.
+func RevokeLinkedInToken(userId string, app *oauth2.Config, accessToken string) error {
+	if accessToken == "" {
+		return nil
+	}
+	form := url.Values{
+		"client_id":     {app.ClientID},
+		"client_secret": {app.ClientSecret},
+		"token":         {accessToken},
+	}
+	req, err := http.NewRequest(http.MethodPost, linkedInRevokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := getProviderClient(ProviderLinkedIn).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach LinkedIn: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "linkedin", req.URL.String(), resp.StatusCode, body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("LinkedIn throttled the revocation: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to revoke the LinkedIn token, status code: %d, response: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestRevokeLinkedInToken(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = map[string]string{"client_id": r.Form.Get("client_id"), "client_secret": r.Form.Get("client_secret"), "token": r.Form.Get("token")}
+		if r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	previous := linkedInRevokeURL
+	linkedInRevokeURL = server.URL + "/oauth/v2/revoke"
+	defer func() { linkedInRevokeURL = previous }()
+
+	app := &oauth2.Config{ClientID: "client", ClientSecret: "secret"}
+	if err := RevokeLinkedInToken("", app, "access"); err != nil {
+		t.Fatal(err)
+	}
+	if form["client_id"] != "client" || form["token"] != "access" {
+		t.Errorf("revoked with %v", form)
+	}
+	app.ClientSecret = "wrong"
+	if err := RevokeLinkedInToken("", app, "access"); err == nil {
+		t.Error("revoked with the wrong app secret")
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+// How the clocks treat a local time that doesn't happen exactly once.
+const (
+	ClockSkipped  = "skipped"
+	ClockRepeated = "repeated"
+)
+
+// LocalInstants returns the instants at which the local date and time in
+// wall, read as UTC, happen in loc: none when the clocks spring forward over
+// it, two, in order, when they fall back over it.
+func LocalInstants(wall time.Time, loc *time.Location) []time.Time {
+	const layout = "2006-01-02 15:04:05"
+	var instants []time.Time
+	seen := map[int]bool{}
+	// Clocks change at most once a day, so the offsets half a day either
+	// side are those before and after any change around wall
+	for _, probe := range []time.Duration{-12 * time.Hour, 12 * time.Hour} {
+		_, offset := wall.Add(probe).In(loc).Zone()
+		if seen[offset] {
+			continue
+		}
+		seen[offset] = true
+		instant := wall.Add(-time.Duration(offset) * time.Second)
+		if instant.In(loc).Format(layout) == wall.Format(layout) {
+			instants = append(instants, instant.UTC())
+		}
+	}
+	sort.Slice(instants, func(i, j int) bool { return instants[i].Before(instants[j]) })
+	return instants
+}
+
+// ResolveLocalTime returns when the local date and time in wall, read as
+// UTC, happens in loc. A time the clocks skip resolves to the same time after
+// they spring forward, and a time they repeat to its first occurrence; change
+// tells which, and is "" for a time that happens once.
+func ResolveLocalTime(wall time.Time, loc *time.Location) (instant time.Time, change string) {
+	instants := LocalInstants(wall, loc)
+	switch len(instants) {
+	case 0:
+		_, before := wall.Add(-12 * time.Hour).In(loc).Zone()
+		return wall.Add(-time.Duration(before) * time.Second).UTC(), ClockSkipped
+	case 1:
+		return instants[0], ""
+	}
+	return instants[0], ClockRepeated
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"social-scribe/backend/internal/models"
+)
+
+// Browsers and systems are matched in order, since user agents name the
+// engines they are compatible with too: Edge claims to be Chrome and Safari,
+// and Android claims to be Linux.
+var (
+	userAgentBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	userAgentSystems = []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"Android", "Android"},
+		{"CrOS", "ChromeOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceLabel names the browser and operating system of a user agent, such
+// as "Firefox on Windows". Version numbers are left out, so updating a
+// browser doesn't make it a new device.
+func DeviceLabel(userAgent string) string {
+	browser, system := "Unknown browser", "unknown system"
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+	return browser + " on " + system
+}
+
+// AssessLogin flags a sign-in from a device or country that none of the
+// earlier sign-ins came from. The first sign-in on record is never flagged,
+// and neither is a country when no earlier sign-in was located.
+func AssessLogin(history []models.LoginRecord, record *models.LoginRecord) {
+	if len(history) == 0 {
+		return
+	}
+	knownDevice, knownCountry, located := false, false, false
+	for _, earlier := range history {
+		knownDevice = knownDevice || earlier.Device == record.Device
+		knownCountry = knownCountry || earlier.Country == record.Country
+		located = located || earlier.Country != ""
+	}
+	record.NewDevice = !knownDevice
+	record.NewCountry = record.Country != "" && located && !knownCountry
+}
+
+// LoginAlertMessage describes a flagged sign-in for the user's notifications.
+func LoginAlertMessage(record models.LoginRecord) string {
+	var what string
+	switch {
+	case record.NewDevice && record.NewCountry:
+		what = fmt.Sprintf("a new device (%s) in a new country (%s)", record.Device, record.Country)
+	case record.NewCountry:
+		what = fmt.Sprintf("a new country (%s)", record.Country)
+	default:
+		what = fmt.Sprintf("a new device (%s)", record.Device)
+	}
+	return fmt.Sprintf("New sign-in to your account from %s at %s, IP %s. If this wasn't you, change your password and sign out your other sessions.",
+		what, record.At.UTC().Format("Jan 2, 2006 15:04 MST"), record.IP)
+}
+
+// SendLoginAlertEmail emails the alert of a flagged sign-in to the user if
+// they opted in and have a verified address. Without email configured it
+// does nothing.
+func SendLoginAlertEmail(user *models.User, record models.LoginRecord) {
+	if !user.Preferences.LoginAlertEmail || user.Email == "" || !user.EmailVerified {
+		return
+	}
+	if !EmailConfigured() {
+		log.Printf("[WARN] Not sending the login alert to user %s: email is not configured", user.Id.Hex())
+		return
+	}
+	body := LoginAlertMessage(record) + "\n\nYou get this email because login alert emails are on in your notification preferences.\n"
+	if err := SendEmail(user.Email, "New sign-in to your SocialScribe account", body); err != nil {
+		log.Printf("[ERROR] Failed to email the login alert to user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	log.Printf("[INFO] Emailed a login alert to user %s", user.Id.Hex())
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestDeviceLabel(t *testing.T) {
+	for userAgent, want := range map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0":   "Edge on Windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15":           "Safari on macOS",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36":           "Chrome on Android",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0 Mobile Safari/604.1": "Chrome on iOS",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                                                          "Firefox on Linux",
+		"curl/8.4.0": "Unknown browser on unknown system",
+	} {
+		if got := DeviceLabel(userAgent); got != want {
+			t.Errorf("DeviceLabel(%q) = %q, want %q", userAgent, got, want)
+		}
+	}
+}
+
+func TestAssessLogin(t *testing.T) {
+	record := models.LoginRecord{Device: "Firefox on Linux", Country: "DE"}
+	AssessLogin(nil, &record)
+	if record.NewDevice || record.NewCountry {
+		t.Errorf("first sign-in flagged: %+v", record)
+	}
+
+	// Earlier sign-ins weren't located, so only the device counts
+	history := []models.LoginRecord{{Device: "Safari on macOS"}}
+	record = models.LoginRecord{Device: "Firefox on Linux", Country: "DE"}
+	AssessLogin(history, &record)
+	if !record.NewDevice || record.NewCountry {
+		t.Errorf("unlocated history: %+v, want only a new device", record)
+	}
+
+	history = append(history, models.LoginRecord{Device: "Firefox on Linux", Country: "DE"})
+	record = models.LoginRecord{Device: "Firefox on Linux", Country: "DE"}
+	AssessLogin(history, &record)
+	if record.NewDevice || record.NewCountry {
+		t.Errorf("familiar sign-in flagged: %+v", record)
+	}
+
+	record = models.LoginRecord{Device: "Safari on macOS", Country: "BR", IP: "203.0.113.7", At: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)}
+	AssessLogin(history, &record)
+	if record.NewDevice || !record.NewCountry {
+		t.Errorf("sign-in from abroad: %+v, want only a new country", record)
+	}
+	message := LoginAlertMessage(record)
+	for _, want := range []string{"a new country (BR)", "May 1, 2024 09:30 UTC", "203.0.113.7"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("alert %q does not contain %q", message, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+)
+
+const (
+	mastodonScopes = "read:accounts write:statuses"
+	// MaxTootLength is the default status limit of Mastodon servers.
+	MaxTootLength = 500
+)
+
+// NormalizeMastodonInstance reduces what a user typed for their Mastodon
+// server, such as "https://Mastodon.social/", to its host. Only hosts
+// resolving to public addresses are accepted.
+func NormalizeMastodonInstance(raw string) (string, error) {
+	return publicServers.normalizeMastodonInstance(raw)
+}
+
+func (s userServers) normalizeMastodonInstance(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" ||
+		strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("instance must be the host name of a Mastodon server: %w", apperrors.ErrInvalidInput)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if err := s.checkHost(host); err != nil {
+		return "", fmt.Errorf("instance must be a public Mastodon server: %w", apperrors.ErrInvalidInput)
+	}
+	return host, nil
+}
+
+func (s userServers) mastodonURL(instance, path string) string {
+	return s.scheme + "://" + instance + path
+}
+
+// mastodonCall sends a request to a Mastodon server and decodes its JSON
+// response into out.
+func mastodonCall(userId string, req *http.Request, out interface{}) error {
+	resp, err := getProviderClient(ProviderMastodon).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "mastodon", req.URL.String(), resp.StatusCode, body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%s throttled the request: %w", req.URL.Host, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the access token: %w", req.URL.Host, apperrors.ErrProviderAuth)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of %s: %v", req.URL.Host, err)
+	}
+	return nil
+}
+
+func (s userServers) mastodonForm(instance, path string, form url.Values) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, s.mastodonURL(instance, path), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// MastodonApp returns the app registered on the Mastodon server for
+// redirectURI, registering one on first use.
+func MastodonApp(instance, redirectURI string) (*models.MastodonApp, error) {
+	return publicServers.mastodonApp(instance, redirectURI)
+}
+
+func (s userServers) mastodonApp(instance, redirectURI string) (*models.MastodonApp, error) {
+	app, err := repositories.GetMastodonApp(instance)
+	if err != nil {
+		return nil, err
+	}
+	if app != nil && app.RedirectURI == redirectURI {
+		return app, nil
+	}
+
+	req, err := s.mastodonForm(instance, "/api/v1/apps", url.Values{
+		"client_name":   {"SocialScribe"},
+		"redirect_uris": {redirectURI},
+		"scopes":        {mastodonScopes},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := mastodonCall("", req, &registered); err != nil {
+		return nil, fmt.Errorf("failed to register with %s: %w", instance, err)
+	}
+	if registered.ClientID == "" || registered.ClientSecret == "" {
+		return nil, fmt.Errorf("%s returned no client credentials", instance)
+	}
+	app = &models.MastodonApp{ClientID: registered.ClientID, ClientSecret: registered.ClientSecret, RedirectURI: redirectURI}
+	if err := repositories.StoreMastodonApp(instance, *app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// MastodonAuthorizeURL is where the user approves SocialScribe on their
+// Mastodon server.
+func MastodonAuthorizeURL(instance string, app *models.MastodonApp, state string) string {
+	return publicServers.mastodonURL(instance, "/oauth/authorize") + "?" + url.Values{
+		"client_id":     {app.ClientID},
+		"redirect_uri":  {app.RedirectURI},
+		"response_type": {"code"},
+		"scope":         {mastodonScopes},
+		"state":         {state},
+	}.Encode()
+}
+
+// ConnectMastodon exchanges the authorization code for an access token and
+// returns it with the handle of the account it belongs to.
+func ConnectMastodon(userId, instance string, app *models.MastodonApp, code string) (string, string, error) {
+	return publicServers.connectMastodon(userId, instance, app, code)
+}
+
+func (s userServers) connectMastodon(userId, instance string, app *models.MastodonApp, code string) (string, string, error) {
+	req, err := s.mastodonForm(instance, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {app.ClientID},
+		"client_secret": {app.ClientSecret},
+		"redirect_uri":  {app.RedirectURI},
+		"scope":         {mastodonScopes},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := mastodonCall(userId, req, &token); err != nil {
+		return "", "", fmt.Errorf("failed to exchange the code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", "", fmt.Errorf("%s returned no access token", instance)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, s.mastodonURL(instance, "/api/v1/accounts/verify_credentials"), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	var account struct {
+		Username string `json:"username"`
+	}
+	if err := mastodonCall(userId, req, &account); err != nil {
+		return "", "", fmt.Errorf("failed to look up the account: %w", err)
+	}
+	return token.AccessToken, "@" + account.Username + "@" + instance, nil
+}
+
+// TootText joins the caption and the blog link into one status within
+// MaxTootLength characters, shortening the caption if need be.
+func TootText(caption, link string) string {
+	caption = strings.TrimSpace(caption)
+	if link == "" {
+		return truncateRunes(caption, MaxTootLength)
+	}
+	room := MaxTootLength - utf8.RuneCountInString(link) - 2
+	if room <= 0 {
+		return link
+	}
+	return truncateRunes(caption, room) + "\n\n" + link
+}
+
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// postTootHandler posts the status and returns the toot's URL.
+func (s userServers) postTootHandler(userId, status, instance, accessToken string) (string, error) {
+	if instance == "" || accessToken == "" {
+		return "", fmt.Errorf("Mastodon is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	req, err := s.mastodonForm(instance, "/api/v1/statuses", url.Values{
+		"status":     {status},
+		"visibility": {"public"},
+	})
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	var toot struct {
+		URL string `json:"url"`
+	}
+	if err := mastodonCall(userId, req, &toot); err != nil {
+		return "", fmt.Errorf("failed to post the toot: %w", err)
+	}
+	return toot.URL, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestNormalizeMastodonInstance(t *testing.T) {
+	servers := userServers{scheme: "https", checkHost: func(host string) error {
+		if host == "internal.example" {
+			return errors.New("private address")
+		}
+		return nil
+	}}
+
+	for raw, want := range map[string]string{
+		"mastodon.social":           "mastodon.social",
+		" https://Fosstodon.org/ ":  "fosstodon.org",
+		"http://mastodon.social":    "",
+		"mastodon.social:8443":      "",
+		"mastodon.social/@ada":      "",
+		"https://user@mastodon.soc": "",
+		"internal.example":          "",
+		"":                          "",
+	} {
+		got, err := servers.normalizeMastodonInstance(raw)
+		if want == "" {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("NormalizeMastodonInstance(%q) = %q, %v; want invalid input", raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizeMastodonInstance(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+}
+
+func TestTootText(t *testing.T) {
+	link := "https://blog.example.com/scheduling?utm_source=mastodon"
+	if got := TootText(" New post! ", link); got != "New post!\n\n"+link {
+		t.Errorf("TootText = %q", got)
+	}
+
+	long := TootText(strings.Repeat("é", 600), link)
+	if n := utf8.RuneCountInString(long); n != MaxTootLength {
+		t.Errorf("long toot has %d characters, want %d", n, MaxTootLength)
+	}
+	if !strings.HasSuffix(long, "…\n\n"+link) {
+		t.Errorf("long toot doesn't end with the shortened caption and link: %q", long[len(long)-80:])
+	}
+}
+
+func TestMastodonConnectAndPost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			if r.FormValue("code") != "the-code" || r.FormValue("client_secret") != "secret" {
+				http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token": "token-1", "token_type": "Bearer"}`))
+		case "/api/v1/accounts/verify_credentials":
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id": "1", "username": "ada"}`))
+		case "/api/v1/statuses":
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			if r.FormValue("status") != "Hello" || r.FormValue("visibility") != "public" {
+				http.Error(w, `{"error": "unexpected status"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			w.Write([]byte(`{"id": "42", "url": "https://mastodon.example/@ada/42"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	servers := localServers()
+	instance := strings.TrimPrefix(server.URL, "http://")
+
+	app := &models.MastodonApp{ClientID: "client", ClientSecret: "secret", RedirectURI: "https://api.example/callback"}
+	token, account, err := servers.connectMastodon("", instance, app, "the-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token-1" || account != "@ada@"+instance {
+		t.Errorf("connected %q as %q", token, account)
+	}
+	if _, _, err := servers.connectMastodon("", instance, app, "stale-code"); err == nil {
+		t.Error("expected a rejected code to fail")
+	}
+
+	postURL, err := servers.postTootHandler("", "Hello", instance, token)
+	if err != nil || postURL != "https://mastodon.example/@ada/42" {
+		t.Errorf("posted to %q, %v", postURL, err)
+	}
+	if _, err := servers.postTootHandler("", "Hello", instance, "revoked"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("revoked token: %v, want unauthorized", err)
+	}
+	if _, err := servers.postTootHandler("", "Hello", "", ""); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("not connected: %v, want invalid input", err)
+	}
+}
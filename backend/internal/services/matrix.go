@@ -0,0 +1,226 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// matrixPermalink is where an announcement can be viewed, by room and event.
+const matrixPermalink = "https://matrix.to/#/"
+
+// matrixBaseURL checks a homeserver address and reduces it to its scheme,
+// host and path.
+func (s userServers) matrixBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Scheme != s.scheme || parsed.User != nil || parsed.RawQuery != "" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("homeserver must be the address of a Matrix server: %w", apperrors.ErrInvalidInput)
+	}
+	if err := s.checkHost(parsed.Hostname()); err != nil {
+		return "", fmt.Errorf("homeserver must be a public Matrix server: %w", apperrors.ErrInvalidInput)
+	}
+	return s.scheme + "://" + strings.ToLower(parsed.Host) + strings.TrimRight(parsed.EscapedPath(), "/"), nil
+}
+
+// DiscoverMatrixHomeserver finds the client API of the homeserver a user
+// named, such as "matrix.org". A server that delegates its client API
+// elsewhere says so in its .well-known document; others serve it
+// themselves. Only homeservers at public addresses are accepted.
+func DiscoverMatrixHomeserver(raw string) (string, error) {
+	return publicServers.discoverMatrixHomeserver(raw)
+}
+
+func (s userServers) discoverMatrixHomeserver(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = s.scheme + "://" + raw
+	}
+	server, err := s.matrixBaseURL(raw)
+	if err != nil {
+		return "", err
+	}
+	resp, err := getProviderClient(ProviderMatrix).Get(server + "/.well-known/matrix/client")
+	if err != nil {
+		return server, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	var wellKnown struct {
+		Homeserver struct {
+			BaseURL string `json:"base_url"`
+		} `json:"m.homeserver"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(body, &wellKnown) != nil || wellKnown.Homeserver.BaseURL == "" {
+		return server, nil
+	}
+	return s.matrixBaseURL(wellKnown.Homeserver.BaseURL)
+}
+
+// matrixCall sends a request to the client API of a homeserver with the
+// access token and decodes its JSON response into out.
+func matrixCall(userId, token string, req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(ProviderMatrix).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "matrix", req.URL.String(), resp.StatusCode, body)
+	var failure struct {
+		ErrCode      string `json:"errcode"`
+		Error        string `json:"error"`
+		RetryAfterMs int    `json:"retry_after_ms"`
+	}
+	json.Unmarshal(body, &failure)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%s asks to retry in %dms: %w", req.URL.Host, failure.RetryAfterMs, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%s rejected the access token, %s: %w", req.URL.Host, failure.ErrCode, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s refused, %s: %w", req.URL.Host, failure.Error, apperrors.ErrForbidden)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s doesn't know it, %s: %w", req.URL.Host, failure.ErrCode, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%s refused the request, %s: %w", req.URL.Host, failure.Error, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of %s: %v", req.URL.Host, err)
+	}
+	return nil
+}
+
+func matrixRequest(method, homeserver, path string, payload interface{}) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %v", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, homeserver+"/_matrix/client/v3"+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// ConnectMatrixRoom checks the access token with the homeserver, resolves
+// the room, which may be given by alias, and joins it unless the account
+// already has.
+func ConnectMatrixRoom(userId, homeserver, token, room string) (*models.MatrixRoom, error) {
+	room = strings.TrimSpace(room)
+	if !strings.HasPrefix(room, "!") && !strings.HasPrefix(room, "#") || !strings.Contains(room, ":") {
+		return nil, fmt.Errorf("room must be a room id such as !abc:example.org or an alias such as #room:example.org: %w", apperrors.ErrInvalidInput)
+	}
+	req, err := matrixRequest(http.MethodGet, homeserver, "/account/whoami", nil)
+	if err != nil {
+		return nil, err
+	}
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := matrixCall(userId, token, req, &whoami); err != nil {
+		return nil, fmt.Errorf("failed to look up the Matrix account: %w", err)
+	}
+
+	if req, err = matrixRequest(http.MethodPost, homeserver, "/join/"+url.PathEscape(room), map[string]string{}); err != nil {
+		return nil, err
+	}
+	var joined struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := matrixCall(userId, token, req, &joined); err != nil {
+		return nil, fmt.Errorf("failed to join %s: %w", room, err)
+	}
+
+	connected := &models.MatrixRoom{Homeserver: homeserver, UserID: whoami.UserID, RoomID: joined.RoomID}
+	if req, err = matrixRequest(http.MethodGet, homeserver, "/rooms/"+url.PathEscape(joined.RoomID)+"/state/m.room.name", nil); err != nil {
+		return nil, err
+	}
+	var name struct {
+		Name string `json:"name"`
+	}
+	// Rooms don't need a name
+	if err := matrixCall(userId, token, req, &name); err == nil {
+		connected.RoomName = name.Name
+	}
+	return connected, nil
+}
+
+// matrixMessage is an m.room.message event, with an HTML body for clients
+// that render it.
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+// matrixAnnouncement is the message announcing a blog: the linked title,
+// the caption and a byline.
+func matrixAnnouncement(title, link, caption, author string, readTime int) matrixMessage {
+	caption = strings.TrimSpace(caption)
+	var byline []string
+	if author != "" {
+		byline = append(byline, "by "+author)
+	}
+	if readTime > 0 {
+		byline = append(byline, fmt.Sprintf("%d min read", readTime))
+	}
+
+	plain := title + "\n" + link
+	formatted := fmt.Sprintf(`<h4><a href="%s">%s</a></h4>`, html.EscapeString(link), html.EscapeString(title))
+	if caption != "" {
+		plain += "\n\n" + caption
+		formatted += "<p>" + strings.ReplaceAll(html.EscapeString(caption), "\n", "<br>") + "</p>"
+	}
+	if len(byline) > 0 {
+		plain += "\n\n" + strings.Join(byline, " · ")
+		formatted += "<p><em>" + html.EscapeString(strings.Join(byline, " · ")) + "</em></p>"
+	}
+	return matrixMessage{MsgType: "m.text", Body: plain, Format: "org.matrix.custom.html", FormattedBody: formatted}
+}
+
+// postMatrixMessage sends the message to the user's room and returns a link
+// to it.
+func postMatrixMessage(user *models.User, message matrixMessage) (string, error) {
+	room := user.Matrix
+	if room == nil {
+		return "", fmt.Errorf("Matrix is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	token, err := OpenUserSecret(user, room.SealedToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Matrix access token: %v", err)
+	}
+	path := "/rooms/" + url.PathEscape(room.RoomID) + "/send/m.room.message/" + uuid.NewString()
+	req, err := matrixRequest(http.MethodPut, room.Homeserver, path, message)
+	if err != nil {
+		return "", err
+	}
+	var sent struct {
+		EventID string `json:"event_id"`
+	}
+	if err := matrixCall(user.Id.Hex(), token, req, &sent); err != nil {
+		return "", err
+	}
+	return matrixPermalink + room.RoomID + "/" + sent.EventID, nil
+}
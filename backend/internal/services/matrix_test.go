@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestMatrixAnnouncement(t *testing.T) {
+	message := matrixAnnouncement("Tips & tricks", "https://blog.example.com/tips?utm_source=matrix", " Two lines\n<b>here</b> ", "Ada", 4)
+	if message.Body != "Tips & tricks\nhttps://blog.example.com/tips?utm_source=matrix\n\nTwo lines\n<b>here</b>\n\nby Ada · 4 min read" {
+		t.Errorf("body %q", message.Body)
+	}
+	want := `<h4><a href="https://blog.example.com/tips?utm_source=matrix">Tips &amp; tricks</a></h4><p>Two lines<br>&lt;b&gt;here&lt;/b&gt;</p><p><em>by Ada · 4 min read</em></p>`
+	if message.FormattedBody != want || message.Format != "org.matrix.custom.html" {
+		t.Errorf("formatted body %q", message.FormattedBody)
+	}
+}
+
+func TestMatrixConnectAndAnnounce(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var sent matrixMessage
+	var homeserver string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/matrix/client" {
+			w.Write([]byte(`{"m.homeserver": {"base_url": "` + homeserver + `/client/"}}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer syt_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errcode": "M_UNKNOWN_TOKEN", "error": "Unknown access token"}`))
+			return
+		}
+		switch {
+		case r.URL.Path == "/client/_matrix/client/v3/account/whoami":
+			w.Write([]byte(`{"user_id": "@ada:example.org"}`))
+		case r.URL.Path == "/client/_matrix/client/v3/join/#releases:example.org":
+			w.Write([]byte(`{"room_id": "!abc:example.org"}`))
+		case r.URL.Path == "/client/_matrix/client/v3/join/#private:example.org":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errcode": "M_FORBIDDEN", "error": "You are not invited to this room."}`))
+		case r.URL.Path == "/client/_matrix/client/v3/rooms/!abc:example.org/state/m.room.name":
+			w.Write([]byte(`{"name": "Releases"}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/client/_matrix/client/v3/rooms/!abc:example.org/send/m.room.message/"):
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"event_id": "$ev1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errcode": "M_NOT_FOUND"}`))
+		}
+	}))
+	defer server.Close()
+	homeserver = server.URL
+
+	base, err := localServers().discoverMatrixHomeserver(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil || base != server.URL+"/client" {
+		t.Fatalf("discovered %q, %v", base, err)
+	}
+	if _, err := ConnectMatrixRoom("", base, "revoked", "#releases:example.org"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("connected with a revoked token: %v", err)
+	}
+	if _, err := ConnectMatrixRoom("", base, "syt_token", "#private:example.org"); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("connected to a room without an invite: %v", err)
+	}
+	if _, err := ConnectMatrixRoom("", base, "syt_token", "releases"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("connected to a malformed room: %v", err)
+	}
+	room, err := ConnectMatrixRoom("", base, "syt_token", "#releases:example.org")
+	if err != nil || room.RoomID != "!abc:example.org" || room.UserID != "@ada:example.org" || room.RoomName != "Releases" {
+		t.Fatalf("connected %+v, %v", room, err)
+	}
+
+	user := &models.User{Id: primitive.NewObjectID(), Matrix: room}
+	if room.SealedToken, err = SealUserSecret(user, "syt_token"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := postMatrixMessage(user, matrixAnnouncement("Scheduling posts", "https://blog.example.com/scheduling", "New post", "", 0))
+	if err != nil || link != "https://matrix.to/#/!abc:example.org/$ev1" {
+		t.Errorf("announced at %q, %v", link, err)
+	}
+	if sent.MsgType != "m.text" || !strings.Contains(sent.FormattedBody, "Scheduling posts</a>") {
+		t.Errorf("sent %+v", sent)
+	}
+}
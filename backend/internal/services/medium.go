@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	// maxMediumTags is how many tags Medium takes on a story, each at most
+	// maxMediumTagLength characters.
+	maxMediumTags      = 3
+	maxMediumTagLength = 25
+)
+
+// mediumAPI is replaced by tests.
+var mediumAPI = "https://api.medium.com/v1"
+
+var mediumTokenPattern = regexp.MustCompile(`^[A-Za-z0-9]{20,128}$`)
+
+// NormalizeMediumToken trims the integration token a user pasted and checks
+// that it looks like one.
+func NormalizeMediumToken(raw string) (string, error) {
+	token := strings.TrimSpace(raw)
+	if !mediumTokenPattern.MatchString(token) {
+		return "", fmt.Errorf("token must be a Medium integration token from https://medium.com/me/settings/security: %w", apperrors.ErrInvalidInput)
+	}
+	return token, nil
+}
+
+// mediumCall sends a request to the Medium API with the integration token
+// and decodes the data of its JSON response into out.
+func mediumCall(userId, token string, req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(ProviderMedium).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Medium: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "medium", req.URL.String(), resp.StatusCode, body)
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(body, &envelope)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Medium throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("Medium rejected the integration token: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusForbidden:
+		var messages []string
+		for _, e := range envelope.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("Medium refused the request, %s: %w", strings.Join(messages, "; "), apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Medium answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Medium: %v", err)
+	}
+	return nil
+}
+
+// LookupMediumAccount returns the Medium account the integration token
+// belongs to.
+func LookupMediumAccount(userId, token string) (*models.MediumAccount, error) {
+	req, err := http.NewRequest(http.MethodGet, mediumAPI+"/me", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var account struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	}
+	if err := mediumCall(userId, token, req, &account); err != nil {
+		return nil, fmt.Errorf("failed to look up the Medium account: %w", err)
+	}
+	return &models.MediumAccount{AuthorID: account.ID, Username: account.Username, Name: account.Name}, nil
+}
+
+// mediumStory is a story as created through the Medium API.
+type mediumStory struct {
+	Title         string   `json:"title"`
+	ContentFormat string   `json:"contentFormat"`
+	Content       string   `json:"content"`
+	CanonicalURL  string   `json:"canonicalUrl"`
+	Tags          []string `json:"tags,omitempty"`
+	PublishStatus string   `json:"publishStatus"`
+}
+
+// newMediumStory republishes a blog's markdown as a public story. Medium
+// shows no title of its own, so the content starts with it.
+func newMediumStory(title, markdown, canonicalURL string, tagSlugs []string) mediumStory {
+	var tags []string
+	for _, slug := range tagSlugs {
+		tag := strings.TrimSpace(strings.ReplaceAll(slug, "-", " "))
+		if tag == "" || len(tag) > maxMediumTagLength {
+			continue
+		}
+		tags = append(tags, tag)
+		if len(tags) == maxMediumTags {
+			break
+		}
+	}
+	return mediumStory{
+		Title:         title,
+		ContentFormat: "markdown",
+		Content:       "# " + title + "\n\n" + portableMarkdown(markdown),
+		CanonicalURL:  canonicalURL,
+		Tags:          tags,
+		PublishStatus: "public",
+	}
+}
+
+// publishMediumStory creates the story on the user's Medium account and
+// returns its URL.
+func publishMediumStory(user *models.User, story mediumStory) (string, error) {
+	if user.Medium == nil {
+		return "", fmt.Errorf("Medium is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	token, err := OpenUserSecret(user, user.Medium.SealedToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Medium integration token: %v", err)
+	}
+	payload, err := json.Marshal(story)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal story: %v", err)
+	}
+	endpoint := mediumAPI + "/users/" + url.PathEscape(user.Medium.AuthorID) + "/posts"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var published struct {
+		URL string `json:"url"`
+	}
+	if err := mediumCall(user.Id.Hex(), token, req, &published); err != nil {
+		return "", err
+	}
+	return published.URL, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// MediumBlogPrefix starts the blog id of a Medium story listed from the
+// user's Medium profile, keeping it apart from the ids of the other blog
+// sources.
+const MediumBlogPrefix = "medium:"
+
+var (
+	// mediumFeedBase is where Medium serves the RSS feed of a profile's
+	// latest stories; tests replace it.
+	mediumFeedBase         = "https://medium.com/feed/@"
+	mediumUsernamePattern  = regexp.MustCompile(`^[A-Za-z0-9_.]{1,50}$`)
+	mediumStoryIDPattern   = regexp.MustCompile(`^[0-9a-f]{8,16}$`)
+	mediumStoryImage       = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+	mediumStoryURLSuffixID = regexp.MustCompile(`-([0-9a-f]{8,16})$`)
+)
+
+// MediumBlogID is the blog id of the Medium story.
+func MediumBlogID(storyID string) string {
+	return MediumBlogPrefix + storyID
+}
+
+// MediumStoryID returns the story id of a Medium blog id, and whether blogId
+// is one.
+func MediumStoryID(blogId string) (string, bool) {
+	id, found := strings.CutPrefix(blogId, MediumBlogPrefix)
+	return id, found && mediumStoryIDPattern.MatchString(id)
+}
+
+// NormalizeMediumUsername checks a Medium username, with or without its @.
+func NormalizeMediumUsername(raw string) (string, error) {
+	username := strings.TrimPrefix(strings.TrimSpace(raw), "@")
+	if !mediumUsernamePattern.MatchString(username) {
+		return "", fmt.Errorf("username must be a Medium username such as @ada: %w", apperrors.ErrInvalidInput)
+	}
+	return username, nil
+}
+
+// mediumFeedStory is a story of a Medium profile's feed, with the story id
+// taken from its guid, https://medium.com/p/<id>.
+type mediumFeedStory struct {
+	feedItem
+	StoryID string
+}
+
+// Image is the first picture of the story, which Medium shows as its cover.
+// The tracking pixel Medium appends is skipped.
+func (s mediumFeedStory) Image() string {
+	for _, match := range mediumStoryImage.FindAllStringSubmatch(s.HTML, -1) {
+		if !strings.Contains(match[1], "/_/stat") {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// fetchMediumStories fetches the feed of the Medium profile, which holds
+// the profile's 10 latest stories.
+func fetchMediumStories(username string) (string, []mediumFeedStory, error) {
+	title, items, err := fetchFeed(mediumFeedBase + url.PathEscape(username))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch the Medium stories of @%s: %w", username, err)
+	}
+	stories := make([]mediumFeedStory, 0, len(items))
+	for _, item := range items {
+		storyID := path.Base(item.GUID)
+		if !mediumStoryIDPattern.MatchString(storyID) {
+			continue
+		}
+		// The link carries an rss tracking source
+		if link, err := url.Parse(item.URL); err == nil {
+			link.RawQuery = ""
+			item.URL = link.String()
+		}
+		stories = append(stories, mediumFeedStory{feedItem: item, StoryID: storyID})
+	}
+	return title, stories, nil
+}
+
+// LookupMediumProfile checks that the Medium profile exists and returns it
+// with the title of its feed.
+func LookupMediumProfile(username string) (*models.MediumSource, error) {
+	title, _, err := fetchMediumStories(username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MediumSource{Username: username, Title: title}, nil
+}
+
+// republishedMediumStories are the ids of the stories SocialScribe
+// republished the user's blogs as.
+func republishedMediumStories(user *models.User) map[string]bool {
+	republished := map[string]bool{}
+	for _, blog := range user.SharedBlogs {
+		if blog.MediumPostURL == "" {
+			continue
+		}
+		if link, err := url.Parse(blog.MediumPostURL); err == nil {
+			if match := mediumStoryURLSuffixID.FindStringSubmatch(path.Base(link.Path)); match != nil {
+				republished[match[1]] = true
+			}
+		}
+	}
+	return republished
+}
+
+// FetchMediumStories lists the latest stories of the user's Medium profile
+// as blogs to share. Stories the user's blogs were republished as are left
+// out, as the blogs are listed already.
+func FetchMediumStories(user *models.User) ([]models.PostNode, error) {
+	if user.MediumSource == nil {
+		return nil, fmt.Errorf("no Medium profile is registered: %w", apperrors.ErrInvalidInput)
+	}
+	_, stories, err := fetchMediumStories(user.MediumSource.Username)
+	if err != nil {
+		return nil, err
+	}
+	republished := republishedMediumStories(user)
+	posts := []models.PostNode{}
+	for _, story := range stories {
+		if republished[story.StoryID] {
+			continue
+		}
+		publishedAt := ""
+		if !story.PublishedAt.IsZero() {
+			publishedAt = story.PublishedAt.Format(time.RFC3339)
+		}
+		posts = append(posts, models.PostNode{
+			Title:             story.Title,
+			URL:               story.URL,
+			ID:                MediumBlogID(story.StoryID),
+			CoverImage:        models.CoverImage{URL: story.Image()},
+			Author:            models.Author{Name: story.Author},
+			ReadTimeInMinutes: story.ReadTime(),
+			PublishedAt:       publishedAt,
+			Brief:             story.Brief(),
+			Source:            "medium",
+		})
+	}
+	return posts, nil
+}
+
+// fetchMediumPost fetches a story of the user's Medium profile to share it.
+// The feed only holds the latest stories, so older ones can't be shared.
+func fetchMediumPost(user *models.User, storyID string) (*sourcePost, error) {
+	if user.MediumSource == nil {
+		return nil, fmt.Errorf("no Medium profile is registered: %w", apperrors.ErrInvalidInput)
+	}
+	_, stories, err := fetchMediumStories(user.MediumSource.Username)
+	if err != nil {
+		return nil, err
+	}
+	for _, story := range stories {
+		if story.StoryID != storyID {
+			continue
+		}
+		return &sourcePost{
+			Id:         MediumBlogID(story.StoryID),
+			Title:      story.Title,
+			Url:        story.URL,
+			CoverImage: story.Image(),
+			Author:     story.Author,
+			ReadTime:   story.ReadTime(),
+			Brief:      story.Brief(),
+			Text:       story.Text(),
+			HTML:       story.HTML,
+			Tags:       story.Tags,
+		}, nil
+	}
+	return nil, fmt.Errorf("the story is no longer among the profile's latest: %w", apperrors.ErrNotFound)
+}
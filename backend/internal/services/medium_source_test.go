@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const testMediumFeed = `<?xml version="1.0" encoding="UTF-8"?><rss xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/" version="2.0">
+<channel>
+	<title><![CDATA[Stories by Ada on Medium]]></title>
+	<item>
+		<title><![CDATA[Queues]]></title>
+		<link>https://medium.com/@ada/queues-1a2b3c4d5e6f?source=rss-0f1e2d3c4b5a------2</link>
+		<guid isPermaLink="false">https://medium.com/p/1a2b3c4d5e6f</guid>
+		<category><![CDATA[go]]></category>
+		<dc:creator><![CDATA[Ada]]></dc:creator>
+		<pubDate>Thu, 01 Oct 2026 09:00:00 GMT</pubDate>
+		<content:encoded><![CDATA[<figure><img alt="" src="https://cdn-images-1.medium.com/max/1024/queues.png" /></figure><p>Queues hold work.</p><img src="https://medium.com/_/stat?event=post.clientViewed&referrerSource=full_rss&postId=1a2b3c4d5e6f" width="1" height="1" alt="">]]></content:encoded>
+	</item>
+	<item>
+		<title><![CDATA[Scheduling]]></title>
+		<link>https://medium.com/@ada/scheduling-6f5e4d3c2b1a?source=rss-0f1e2d3c4b5a------2</link>
+		<guid isPermaLink="false">https://medium.com/p/6f5e4d3c2b1a</guid>
+		<dc:creator><![CDATA[Ada]]></dc:creator>
+		<content:encoded><![CDATA[<p>Timers</p>]]></content:encoded>
+	</item>
+</channel>
+</rss>`
+
+func TestMediumSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed/@ada" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+		w.Write([]byte(testMediumFeed))
+	}))
+	defer server.Close()
+	previousHost, previousBase := checkFeedHost, mediumFeedBase
+	checkFeedHost = func(string) error { return nil }
+	mediumFeedBase = server.URL + "/feed/@"
+	defer func() { checkFeedHost, mediumFeedBase = previousHost, previousBase }()
+
+	if username, err := NormalizeMediumUsername(" @ada "); err != nil || username != "ada" {
+		t.Errorf("NormalizeMediumUsername = %q, %v", username, err)
+	}
+	if _, err := NormalizeMediumUsername("ada/../feed"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("a path gave %v", err)
+	}
+	if _, err := LookupMediumProfile("nobody"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("an unknown profile gave %v", err)
+	}
+	source, err := LookupMediumProfile("ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Title != "Stories by Ada on Medium" {
+		t.Errorf("profile = %+v", source)
+	}
+
+	// The blog republished to Medium isn't listed again
+	user := &models.User{
+		MediumSource: source,
+		SharedBlogs:  []models.SharedBlog{{Blog: models.Blog{Id: "hashnode-1"}, MediumPostURL: "https://medium.com/@ada/scheduling-6f5e4d3c2b1a"}},
+	}
+	posts, err := FetchMediumStories(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != "medium:1a2b3c4d5e6f" || posts[0].URL != "https://medium.com/@ada/queues-1a2b3c4d5e6f" ||
+		posts[0].Source != "medium" || posts[0].CoverImage.URL != "https://cdn-images-1.medium.com/max/1024/queues.png" || posts[0].PublishedAt != "2026-10-01T09:00:00Z" {
+		t.Fatalf("listed %+v", posts)
+	}
+
+	post, err := fetchSourcePost(user, posts[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Queues" || post.Text != "Queues hold work." || post.Author != "Ada" || !reflect.DeepEqual(post.Tags, []string{"go"}) {
+		t.Errorf("fetched %+v", post)
+	}
+	if _, err := fetchSourcePost(user, MediumBlogID("0000aaaa0000")); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("an older story gave %v", err)
+	}
+	if err := mediumPlatform.validate(&Share{BlogID: posts[0].ID, Title: "Queues", Markdown: "Queues hold work."}); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("republishing a Medium story to Medium gave %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const testMediumToken = "2a7f3c0e9b1d4f6a8c5e7b9d1f3a5c7e9"
+
+func TestPublishMediumStory(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var posted mediumStory
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer "+testMediumToken:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"message": "Token was invalid.", "code": 6003}]}`))
+		case r.URL.Path == "/me":
+			w.Write([]byte(`{"data": {"id": "5303d74c64f66366f00cb9b2a94f3251bf5", "username": "ada", "name": "Ada"}}`))
+		case r.URL.Path == "/users/5303d74c64f66366f00cb9b2a94f3251bf5/posts":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "e6f36a", "url": "https://medium.com/@ada/scheduling-posts-e6f36a"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := mediumAPI
+	mediumAPI = server.URL
+	defer func() { mediumAPI = previous }()
+
+	if _, err := NormalizeMediumToken("not a token"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted a malformed token: %v", err)
+	}
+	if _, err := LookupMediumAccount("", "0000000000000000000000000"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up with a wrong token: %v", err)
+	}
+	account, err := LookupMediumAccount("", testMediumToken)
+	if err != nil || account.AuthorID != "5303d74c64f66366f00cb9b2a94f3251bf5" {
+		t.Fatalf("looked up %+v, %v", account, err)
+	}
+	user := &models.User{Id: primitive.NewObjectID(), Medium: account}
+	if account.SealedToken, err = SealUserSecret(user, testMediumToken); err != nil {
+		t.Fatal(err)
+	}
+
+	story := newMediumStory("Scheduling posts", `![](https://cdn.hashnode.com/a.png align="left")`, "https://blog.example.com/scheduling", []string{"web-development", "go", "a-tag-too-long-for-medium-to-take", "testing", "ci"})
+	storyURL, err := publishMediumStory(user, story)
+	if err != nil || storyURL != "https://medium.com/@ada/scheduling-posts-e6f36a" {
+		t.Fatalf("published at %q, %v", storyURL, err)
+	}
+	if posted.CanonicalURL != "https://blog.example.com/scheduling" || posted.PublishStatus != "public" {
+		t.Errorf("sent story %+v", posted)
+	}
+	if want := []string{"web development", "go", "testing"}; !reflect.DeepEqual(posted.Tags, want) {
+		t.Errorf("sent tags %v, want %v", posted.Tags, want)
+	}
+	if !strings.HasPrefix(posted.Content, "# Scheduling posts\n\n![](https://cdn.hashnode.com/a.png)") {
+		t.Errorf("sent content %q", posted.Content)
+	}
+}
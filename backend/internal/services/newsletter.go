@@ -0,0 +1,535 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// MaxNewsletterSubscribers caps the built-in sender's list. It mails
+	// subscribers one at a time over SMTP, so bigger lists belong with
+	// Buttondown or Mailchimp.
+	MaxNewsletterSubscribers = 500
+	maxNewsletterFromName    = 100
+	maxNewsletterPreview     = 150
+)
+
+// buttondownAPI is replaced by tests.
+var buttondownAPI = "https://api.buttondown.email/v1"
+
+// mailchimpAPI is the API of the data center an account lives in. It is
+// replaced by tests.
+var mailchimpAPI = func(dataCenter string) string {
+	return "https://" + dataCenter + ".api.mailchimp.com/3.0"
+}
+
+var (
+	buttondownAPIKeyPattern = regexp.MustCompile(`^[A-Za-z0-9-]{16,64}$`)
+	// Mailchimp keys end with the account's data center, such as "-us21"
+	mailchimpAPIKeyPattern = regexp.MustCompile(`^[0-9a-f]{32}-([a-z]+[0-9]+)$`)
+)
+
+// ParseNewsletterSubscribers reads the addresses of an uploaded subscriber
+// list: one per line, or a CSV export with an "email" column. Duplicates are
+// dropped and one invalid address fails the whole list.
+func ParseNewsletterSubscribers(list io.Reader) ([]string, error) {
+	reader := csv.NewReader(list)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("subscribers must be a CSV file or one address per line: %w", apperrors.ErrInvalidInput)
+	}
+	column := 0
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), "email") || strings.EqualFold(strings.TrimSpace(name), "email address") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+	seen := map[string]bool{}
+	emails := []string{}
+	for i, record := range records {
+		if column >= len(record) {
+			continue
+		}
+		email := strings.ToLower(strings.TrimSpace(record[column]))
+		if email == "" || seen[email] {
+			continue
+		}
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email || len(email) > 254 {
+			return nil, fmt.Errorf("row %d: %q is not an email address: %w", i+1, email, apperrors.ErrInvalidInput)
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// NewNewsletterSubscribers gives each address the token of its unsubscribe
+// link.
+func NewNewsletterSubscribers(emails []string) ([]models.NewsletterSubscriber, error) {
+	subscribers := make([]models.NewsletterSubscriber, 0, len(emails))
+	for _, email := range emails {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate unsubscribe token: %v", err)
+		}
+		subscribers = append(subscribers, models.NewsletterSubscriber{
+			Email:        email,
+			Token:        base64.RawURLEncoding.EncodeToString(buf),
+			SubscribedAt: utils.Now(),
+		})
+	}
+	return subscribers, nil
+}
+
+func newsletterUnsubscribeURL(userId, token string) string {
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:9696"
+	}
+	query := url.Values{"user": {userId}, "token": {token}}
+	return strings.TrimRight(backendURL, "/") + "/api/v1/newsletter/unsubscribe?" + query.Encode()
+}
+
+// NewNewsletterAccount checks the sender a user chose and returns the
+// account to store. Buttondown and Mailchimp API keys are tried against the
+// service, then sealed.
+func NewNewsletterAccount(user *models.User, provider, apiKey, listID, fromName string) (*models.NewsletterAccount, error) {
+	fromName = strings.TrimSpace(fromName)
+	named := fromName != ""
+	if !named {
+		fromName = user.UserName
+	}
+	if strings.ContainsAny(fromName, "\r\n") || len([]rune(fromName)) > maxNewsletterFromName {
+		return nil, fmt.Errorf("from_name must be one line of at most %d characters: %w", maxNewsletterFromName, apperrors.ErrInvalidInput)
+	}
+	account := &models.NewsletterAccount{Provider: provider, FromName: fromName, ConnectedAt: utils.Now()}
+	userId := user.Id.Hex()
+	apiKey = strings.TrimSpace(apiKey)
+	switch provider {
+	case models.NewsletterBuiltin:
+		if !EmailConfigured() {
+			return nil, fmt.Errorf("this server can't send email, connect Buttondown or Mailchimp instead: %w", apperrors.ErrInvalidInput)
+		}
+		return account, nil
+	case models.NewsletterButtondown:
+		if !buttondownAPIKeyPattern.MatchString(apiKey) {
+			return nil, fmt.Errorf("api_key must be a Buttondown API key from https://buttondown.com/settings/api: %w", apperrors.ErrInvalidInput)
+		}
+		if err := checkButtondownAPIKey(userId, apiKey); err != nil {
+			return nil, err
+		}
+	case models.NewsletterMailchimp:
+		if !mailchimpAPIKeyPattern.MatchString(apiKey) {
+			return nil, fmt.Errorf("api_key must be a Mailchimp API key, such as 0123…cdef-us21: %w", apperrors.ErrInvalidInput)
+		}
+		audience, err := lookupMailchimpAudience(userId, apiKey, strings.TrimSpace(listID))
+		if err != nil {
+			return nil, err
+		}
+		account.ListID, account.ListName, account.ReplyTo = audience.ID, audience.Name, audience.Defaults.FromEmail
+		if !named && audience.Defaults.FromName != "" {
+			account.FromName = audience.Defaults.FromName
+		}
+	default:
+		return nil, fmt.Errorf("provider must be %s, %s or %s: %w", models.NewsletterBuiltin, models.NewsletterButtondown, models.NewsletterMailchimp, apperrors.ErrInvalidInput)
+	}
+	sealed, err := SealUserSecret(user, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	account.SealedAPIKey = sealed
+	return account, nil
+}
+
+// newsletterIssue is a blog as a newsletter tells subscribers about it.
+type newsletterIssue struct {
+	Title    string
+	Link     string
+	Summary  string
+	Byline   string
+	ImageURL string
+	FromName string
+	// UnsubscribeURL is set by the built-in sender for each subscriber.
+	// Services sending for the user add their own unsubscribe footers.
+	UnsubscribeURL string
+}
+
+// newNewsletterIssue summarizes the blog with the caption written for the
+// share, or the blog's brief without one.
+func newNewsletterIssue(share *Share, fromName string) newsletterIssue {
+	summary := strings.TrimSpace(share.Caption)
+	if summary == "" {
+		summary = strings.TrimSpace(share.Brief)
+	}
+	var byline []string
+	if share.Author != "" {
+		byline = append(byline, "by "+share.Author)
+	}
+	if share.ReadTime > 0 {
+		byline = append(byline, fmt.Sprintf("%d min read", share.ReadTime))
+	}
+	return newsletterIssue{
+		Title:    strings.TrimSpace(share.Title),
+		Link:     share.Link("newsletter"),
+		Summary:  summary,
+		Byline:   strings.Join(byline, " · "),
+		ImageURL: share.CardImage,
+		FromName: fromName,
+	}
+}
+
+var newsletterTextTemplate = template.Must(template.New("newsletter").Parse(`{{.Title}}
+{{if .Byline}}{{.Byline}}
+{{end}}
+{{if .Summary}}{{.Summary}}
+
+{{end}}Read it: {{.Link}}
+{{if .UnsubscribeURL}}
+--
+You get this email because you subscribed to {{.FromName}}'s newsletter.
+Unsubscribe: {{.UnsubscribeURL}}
+{{end}}`))
+
+var newsletterHTMLTemplate = htmltemplate.Must(htmltemplate.New("newsletter").Parse(`<h1>{{.Title}}</h1>
+{{if .Byline}}<p style="color:#666666">{{.Byline}}</p>
+{{end}}{{if .ImageURL}}<p><a href="{{.Link}}"><img src="{{.ImageURL}}" alt="{{.Title}}" style="max-width:100%"></a></p>
+{{end}}{{if .Summary}}<p>{{.Summary}}</p>
+{{end}}<p><a href="{{.Link}}">Read the post</a></p>
+<p style="font-size:12px;color:#999999"><a href="*|UNSUB|*">Unsubscribe</a> · *|LIST:ADDRESSLINE|*</p>
+`))
+
+// renderNewsletterText renders the plain-text issue, which Buttondown reads
+// as markdown.
+func renderNewsletterText(issue newsletterIssue) (string, error) {
+	var body bytes.Buffer
+	if err := newsletterTextTemplate.Execute(&body, issue); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
+
+// sendNewsletter sends the blog as an issue with the user's sender and
+// returns where it can be read online, if the sender says.
+func sendNewsletter(user *models.User, share *Share) (string, error) {
+	if user.Newsletter == nil {
+		return "", fmt.Errorf("the newsletter is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	issue := newNewsletterIssue(share, user.Newsletter.FromName)
+	switch user.Newsletter.Provider {
+	case models.NewsletterButtondown:
+		return sendButtondownEmail(user, issue)
+	case models.NewsletterMailchimp:
+		return sendMailchimpCampaign(user, issue)
+	default:
+		issueID := share.BlogID
+		if issueID == "" {
+			issueID = share.URL
+		}
+		return "", sendBuiltinNewsletter(user, issueID, issue)
+	}
+}
+
+// newsletterDelivery is the email of the issue to one subscriber, with their
+// own unsubscribe link.
+func newsletterDelivery(userId, issueID string, subscriber models.NewsletterSubscriber, issue newsletterIssue, now time.Time) (models.NewsletterDelivery, error) {
+	issue.UnsubscribeURL = newsletterUnsubscribeURL(userId, subscriber.Token)
+	body, err := renderNewsletterText(issue)
+	if err != nil {
+		return models.NewsletterDelivery{}, err
+	}
+	return models.NewsletterDelivery{
+		IssueID:  issueID,
+		Email:    subscriber.Email,
+		Subject:  strings.ReplaceAll(issue.Title, "\n", " "),
+		Body:     body,
+		FromName: issue.FromName,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + issue.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		QueuedAt:      now,
+	}, nil
+}
+
+// sendBuiltinNewsletter queues the issue for each subscriber; the newsletter
+// worker mails them. Subscribers the blog's issue was already queued for
+// aren't mailed again.
+func sendBuiltinNewsletter(user *models.User, issueID string, issue newsletterIssue) error {
+	if !EmailConfigured() {
+		return fmt.Errorf("email is not configured")
+	}
+	userId := user.Id.Hex()
+	subscribers, err := repo.GetNewsletterSubscribers(userId, false)
+	if err != nil {
+		return err
+	}
+	if len(subscribers) == 0 {
+		return fmt.Errorf("the newsletter has no subscribers: %w", apperrors.ErrInvalidInput)
+	}
+	now := utils.Now()
+	deliveries := make([]models.NewsletterDelivery, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		delivery, err := newsletterDelivery(userId, issueID, subscriber, issue, now)
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	queued, err := repo.QueueNewsletterDeliveries(userId, deliveries)
+	if err != nil {
+		return fmt.Errorf("failed to queue the newsletter: %w", err)
+	}
+	log.Printf("[INFO] Queued the newsletter of user %s for %d subscribers, %d already had it", userId, queued, len(subscribers)-queued)
+	return nil
+}
+
+// DeliverNewsletter mails a queued newsletter delivery.
+func DeliverNewsletter(delivery *models.NewsletterDelivery) error {
+	return sendEmailMessage(emailMessage{
+		To:       delivery.Email,
+		Subject:  delivery.Subject,
+		Body:     delivery.Body,
+		FromName: delivery.FromName,
+		Headers:  delivery.Headers,
+	})
+}
+
+// buttondownCall sends a request to the Buttondown API with the API key and
+// decodes its JSON response into out.
+func buttondownCall(userId, apiKey string, req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Token "+apiKey)
+	resp, err := getProviderClient(ProviderButtondown).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Buttondown: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "buttondown", req.URL.String(), resp.StatusCode, body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Buttondown throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Buttondown rejected the API key: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		var refused struct {
+			Detail string `json:"detail"`
+		}
+		json.Unmarshal(body, &refused)
+		return fmt.Errorf("Buttondown refused the email, %s: %w", truncateRunes(refused.Detail, 200), apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Buttondown answered %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Buttondown: %v", err)
+	}
+	return nil
+}
+
+func checkButtondownAPIKey(userId, apiKey string) error {
+	req, err := http.NewRequest(http.MethodGet, buttondownAPI+"/newsletters", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := buttondownCall(userId, apiKey, req, nil); err != nil {
+		return fmt.Errorf("failed to check the Buttondown API key: %w", err)
+	}
+	return nil
+}
+
+// sendButtondownEmail sends the issue to the Buttondown newsletter, which
+// adds its own unsubscribe footer, and returns the email's archive page.
+func sendButtondownEmail(user *models.User, issue newsletterIssue) (string, error) {
+	apiKey, err := OpenUserSecret(user, user.Newsletter.SealedAPIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Buttondown API key: %v", err)
+	}
+	issue.UnsubscribeURL = ""
+	body, err := renderNewsletterText(issue)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]string{
+		"subject": issue.Title,
+		"body":    body,
+		"status":  "about_to_send",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal email: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, buttondownAPI+"/emails", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var sent struct {
+		ID          string `json:"id"`
+		AbsoluteURL string `json:"absolute_url"`
+	}
+	if err := buttondownCall(user.Id.Hex(), apiKey, req, &sent); err != nil {
+		return "", err
+	}
+	return sent.AbsoluteURL, nil
+}
+
+// mailchimpCall sends a request to the Mailchimp Marketing API of the API
+// key's data center and decodes its JSON response into out.
+func mailchimpCall(userId, apiKey, method, path string, payload interface{}, out interface{}) error {
+	dataCenter := mailchimpAPIKeyPattern.FindStringSubmatch(apiKey)
+	if dataCenter == nil {
+		return fmt.Errorf("the Mailchimp API key has no data center: %w", apperrors.ErrInvalidInput)
+	}
+	var reqBody io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, mailchimpAPI(dataCenter[1])+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.SetBasicAuth("socialscribe", apiKey)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := getProviderClient(ProviderMailchimp).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Mailchimp: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "mailchimp", req.URL.String(), resp.StatusCode, body)
+	var problem struct {
+		Detail string `json:"detail"`
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Mailchimp throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Mailchimp rejected the API key: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("Mailchimp doesn't know %s: %w", path, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		json.Unmarshal(body, &problem)
+		return fmt.Errorf("Mailchimp refused the request, %s: %w", truncateRunes(problem.Detail, 200), apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Mailchimp answered %s", resp.Status)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Mailchimp: %v", err)
+	}
+	return nil
+}
+
+type mailchimpAudience struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Defaults struct {
+		FromName  string `json:"from_name"`
+		FromEmail string `json:"from_email"`
+	} `json:"campaign_defaults"`
+}
+
+// lookupMailchimpAudience returns the audience campaigns go to. Without a
+// list id, the account's only audience is taken.
+func lookupMailchimpAudience(userId, apiKey, listID string) (*mailchimpAudience, error) {
+	if listID != "" {
+		audience := &mailchimpAudience{}
+		if err := mailchimpCall(userId, apiKey, http.MethodGet, "/lists/"+url.PathEscape(listID), nil, audience); err != nil {
+			return nil, fmt.Errorf("failed to look up the Mailchimp audience: %w", err)
+		}
+		return audience, nil
+	}
+	var lists struct {
+		Lists []mailchimpAudience `json:"lists"`
+	}
+	if err := mailchimpCall(userId, apiKey, http.MethodGet, "/lists?count=2", nil, &lists); err != nil {
+		return nil, fmt.Errorf("failed to look up the Mailchimp audiences: %w", err)
+	}
+	if len(lists.Lists) != 1 {
+		return nil, fmt.Errorf("list_id is required, the Mailchimp account has %d audiences: %w", len(lists.Lists), apperrors.ErrInvalidInput)
+	}
+	return &lists.Lists[0], nil
+}
+
+// sendMailchimpCampaign creates a campaign of the issue for the user's
+// audience, sends it and returns its archive page. Mailchimp fills in the
+// unsubscribe link of each subscriber.
+func sendMailchimpCampaign(user *models.User, issue newsletterIssue) (string, error) {
+	account := user.Newsletter
+	apiKey, err := OpenUserSecret(user, account.SealedAPIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Mailchimp API key: %v", err)
+	}
+	var content bytes.Buffer
+	if err := newsletterHTMLTemplate.Execute(&content, issue); err != nil {
+		return "", err
+	}
+	userId := user.Id.Hex()
+	var campaign struct {
+		ID         string `json:"id"`
+		ArchiveURL string `json:"long_archive_url"`
+	}
+	err = mailchimpCall(userId, apiKey, http.MethodPost, "/campaigns", map[string]interface{}{
+		"type":       "regular",
+		"recipients": map[string]string{"list_id": account.ListID},
+		"settings": map[string]string{
+			"subject_line": issue.Title,
+			"preview_text": truncateRunes(issue.Summary, maxNewsletterPreview),
+			"title":        issue.Title,
+			"from_name":    account.FromName,
+			"reply_to":     account.ReplyTo,
+		},
+	}, &campaign)
+	if err != nil {
+		return "", err
+	}
+	campaignPath := "/campaigns/" + url.PathEscape(campaign.ID)
+	if err := mailchimpCall(userId, apiKey, http.MethodPut, campaignPath+"/content", map[string]string{"html": content.String()}, nil); err != nil {
+		return "", err
+	}
+	if err := mailchimpCall(userId, apiKey, http.MethodPost, campaignPath+"/actions/send", nil, nil); err != nil {
+		return "", err
+	}
+	return campaign.ArchiveURL, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestParseNewsletterSubscribers(t *testing.T) {
+	emails, err := ParseNewsletterSubscribers(strings.NewReader("Ada@Example.com\n\nbob@example.com\nada@example.com\n"))
+	if err != nil || strings.Join(emails, ",") != "ada@example.com,bob@example.com" {
+		t.Errorf("parsed lines as %v, %v", emails, err)
+	}
+	export := "First name,Email Address,Tags\nAda,ada@example.com,vip\nBob,bob@example.com,\n"
+	emails, err = ParseNewsletterSubscribers(strings.NewReader(export))
+	if err != nil || strings.Join(emails, ",") != "ada@example.com,bob@example.com" {
+		t.Errorf("parsed the export as %v, %v", emails, err)
+	}
+	if _, err := ParseNewsletterSubscribers(strings.NewReader("ada@example.com\nnot an address\n")); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("parsed an invalid address: %v", err)
+	}
+}
+
+func TestBuiltinNewsletterEmail(t *testing.T) {
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_FROM", "news@example.com")
+	t.Setenv("BACKEND_URL", "https://api.example.com/")
+	var sent string
+	previous := smtpSendMail
+	smtpSendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = string(msg)
+		return nil
+	}
+	defer func() { smtpSendMail = previous }()
+
+	share := &Share{Title: "Scheduling posts", URL: "https://blog.example.com/scheduling", Brief: "How shares get scheduled", Author: "Ada", ReadTime: 4}
+	issue := newNewsletterIssue(share, "Ada's notes")
+	if issue.Summary != "How shares get scheduled" || issue.Byline != "by Ada · 4 min read" {
+		t.Errorf("issue = %+v", issue)
+	}
+	subscriber := models.NewsletterSubscriber{Email: "bob@example.com", Token: "tok"}
+	delivery, err := newsletterDelivery("user-1", "blog-1", subscriber, issue, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != models.DeliveryPending || delivery.IssueID != "blog-1" {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if err := DeliverNewsletter(&delivery); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"From: \"Ada's notes\" <news@example.com>\r\n",
+		"List-Unsubscribe: <https://api.example.com/api/v1/newsletter/unsubscribe?token=tok&user=user-1>\r\n",
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n",
+		"Subject: Scheduling posts\r\n",
+		"How shares get scheduled\r\n\r\nRead it: https://blog.example.com/scheduling",
+		"Unsubscribe: https://api.example.com/api/v1/newsletter/unsubscribe?token=tok&user=user-1",
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("email does not contain %q:\n%s", want, sent)
+		}
+	}
+}
+
+func TestButtondownNewsletter(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var email map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token 0123456789abcdef-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"detail": "Invalid token."}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/newsletters":
+			w.Write([]byte(`{"results": [{"username": "ada", "name": "Ada's notes"}], "count": 1}`))
+		case "/v1/emails":
+			json.NewDecoder(r.Body).Decode(&email)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "9a1c", "absolute_url": "https://buttondown.com/ada/archive/scheduling-posts"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	previous := buttondownAPI
+	buttondownAPI = server.URL + "/v1"
+	defer func() { buttondownAPI = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID(), UserName: "ada"}
+	if _, err := NewNewsletterAccount(user, models.NewsletterButtondown, "0123456789abcdef-revoked", "", ""); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("connected with a revoked key: %v", err)
+	}
+	account, err := NewNewsletterAccount(user, models.NewsletterButtondown, " 0123456789abcdef-key ", "", "")
+	if err != nil || account.FromName != "ada" || account.SealedAPIKey == "" {
+		t.Fatalf("connected %+v, %v", account, err)
+	}
+	user.Newsletter = account
+
+	share := &Share{Title: "Scheduling posts", URL: "https://blog.example.com/scheduling", Caption: "New post"}
+	link, err := sendNewsletter(user, share)
+	if err != nil || link != "https://buttondown.com/ada/archive/scheduling-posts" {
+		t.Fatalf("sent at %q, %v", link, err)
+	}
+	if email["subject"] != "Scheduling posts" || email["status"] != "about_to_send" ||
+		!strings.Contains(email["body"], "Read it: https://blog.example.com/scheduling") || strings.Contains(email["body"], "Unsubscribe") {
+		t.Errorf("sent %v", email)
+	}
+}
+
+func TestMailchimpNewsletter(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	apiKey := strings.Repeat("ab", 16) + "-us21"
+	var calls []string
+	var campaign struct {
+		Recipients map[string]string `json:"recipients"`
+		Settings   map[string]string `json:"settings"`
+	}
+	var content map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, _ := r.BasicAuth(); password != apiKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /us21/3.0/lists":
+			w.Write([]byte(`{"lists": [{"id": "aud1", "name": "Readers", "campaign_defaults": {"from_name": "Ada Lovelace", "from_email": "ada@example.com"}}]}`))
+		case "POST /us21/3.0/campaigns":
+			json.NewDecoder(r.Body).Decode(&campaign)
+			w.Write([]byte(`{"id": "c42", "long_archive_url": "https://us21.campaign-archive.com/?u=1&id=c42"}`))
+		case "PUT /us21/3.0/campaigns/c42/content":
+			json.NewDecoder(r.Body).Decode(&content)
+			w.Write([]byte(`{}`))
+		case "POST /us21/3.0/campaigns/c42/actions/send":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	previous := mailchimpAPI
+	mailchimpAPI = func(dataCenter string) string { return server.URL + "/" + dataCenter + "/3.0" }
+	defer func() { mailchimpAPI = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID(), UserName: "ada"}
+	if _, err := NewNewsletterAccount(user, models.NewsletterMailchimp, "not-a-key", "", ""); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("connected with an invalid key: %v", err)
+	}
+	account, err := NewNewsletterAccount(user, models.NewsletterMailchimp, apiKey, "", "")
+	if err != nil || account.ListID != "aud1" || account.FromName != "Ada Lovelace" || account.ReplyTo != "ada@example.com" {
+		t.Fatalf("connected %+v, %v", account, err)
+	}
+	user.Newsletter = account
+
+	share := &Share{Title: "Scheduling <posts>", URL: "https://blog.example.com/scheduling", Brief: "How shares get scheduled"}
+	link, err := sendNewsletter(user, share)
+	if err != nil || link != "https://us21.campaign-archive.com/?u=1&id=c42" {
+		t.Fatalf("sent at %q, %v", link, err)
+	}
+	if strings.Join(calls, ", ") != "GET /us21/3.0/lists, POST /us21/3.0/campaigns, PUT /us21/3.0/campaigns/c42/content, POST /us21/3.0/campaigns/c42/actions/send" {
+		t.Errorf("calls = %v", calls)
+	}
+	if campaign.Recipients["list_id"] != "aud1" || campaign.Settings["subject_line"] != "Scheduling <posts>" || campaign.Settings["reply_to"] != "ada@example.com" {
+		t.Errorf("campaign = %+v", campaign)
+	}
+	if !strings.Contains(content["html"], "<h1>Scheduling &lt;posts&gt;</h1>") || !strings.Contains(content["html"], `<a href="*|UNSUB|*">`) {
+		t.Errorf("content = %s", content["html"])
+	}
+}
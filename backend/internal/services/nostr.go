@@ -0,0 +1,410 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/gorilla/websocket"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+const (
+	// maxNostrRelays is how many relays a user can publish to.
+	maxNostrRelays = 8
+	// nostrTextNote is the kind of short text note events (NIP-01).
+	nostrTextNote = 1
+)
+
+// defaultNostrRelays are used when a user names no relays of their own.
+var defaultNostrRelays = []string{"wss://relay.damus.io", "wss://nos.lol", "wss://relay.primal.net"}
+
+// checkNostrHost guards the connections to user-supplied relays: only hosts
+// resolving to public addresses are called. Tests replace it to reach local
+// relays.
+var checkNostrHost = checkPublicHost
+
+// nostrNoteURL is where a published note can be viewed, by its note id.
+var nostrNoteURL = "https://njump.me/"
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// convertBits regroups data from groups of fromBits bits to groups of toBits
+// bits, as bech32 needs between bytes and its 5-bit characters.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<toBits - 1
+	var out []byte
+	for _, value := range data {
+		if uint(value)>>fromBits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<fromBits | uint(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+// bech32Encode encodes data as Nostr shows keys and ids (NIP-19), such as
+// npub1... for a public key.
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	polymod := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+	return b.String(), nil
+}
+
+// bech32Decode returns the human-readable part and the data of a bech32
+// string.
+func bech32Decode(encoded string) (string, []byte, error) {
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, errors.New("mixed case")
+	}
+	encoded = strings.ToLower(encoded)
+	sep := strings.LastIndexByte(encoded, '1')
+	if sep < 1 || sep+7 > len(encoded) {
+		return "", nil, errors.New("missing separator or checksum")
+	}
+	hrp := encoded[:sep]
+	values := make([]byte, 0, len(encoded)-sep-1)
+	for i := sep + 1; i < len(encoded); i++ {
+		v := strings.IndexByte(bech32Charset, encoded[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", encoded[i])
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
+
+// DecodeNostrSecretKey reads the secret key a user pasted, either as an
+// nsec1... string or as 64 hex characters.
+func DecodeNostrSecretKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	var key []byte
+	if strings.HasPrefix(strings.ToLower(raw), "nsec1") {
+		hrp, data, err := bech32Decode(raw)
+		if err == nil && hrp == "nsec" {
+			key = data
+		}
+	} else if decoded, err := hex.DecodeString(raw); err == nil {
+		key = decoded
+	}
+	var scalar btcec.ModNScalar
+	if len(key) != 32 || scalar.SetByteSlice(key) || scalar.IsZero() {
+		return nil, fmt.Errorf("key must be a Nostr secret key, as nsec1... or 64 hex characters: %w", apperrors.ErrInvalidInput)
+	}
+	return key, nil
+}
+
+// NostrPublicKey returns the hex public key of a secret key, and its npub.
+func NostrPublicKey(secretKey []byte) (string, string, error) {
+	_, public := btcec.PrivKeyFromBytes(secretKey)
+	serialized := schnorr.SerializePubKey(public)
+	npub, err := bech32Encode("npub", serialized)
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(serialized), npub, nil
+}
+
+// NormalizeNostrRelays checks the relay URLs a user gave, dropping repeats.
+// With none given, the default relays are used.
+func NormalizeNostrRelays(raw []string) ([]string, error) {
+	var relays []string
+	for _, relay := range raw {
+		relay = strings.TrimSpace(relay)
+		if relay == "" {
+			continue
+		}
+		parsed, err := url.Parse(relay)
+		if err != nil || parsed.Scheme != "wss" || parsed.User != nil || parsed.Hostname() == "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+			return nil, fmt.Errorf("relay %q must be a wss:// URL: %w", relay, apperrors.ErrInvalidInput)
+		}
+		if err := checkNostrHost(parsed.Hostname()); err != nil {
+			return nil, fmt.Errorf("relay %q must be a public server: %w", relay, apperrors.ErrInvalidInput)
+		}
+		relay = "wss://" + strings.ToLower(parsed.Host) + strings.TrimRight(parsed.Path, "/")
+		if !slices.Contains(relays, relay) {
+			relays = append(relays, relay)
+		}
+	}
+	if len(relays) > maxNostrRelays {
+		return nil, fmt.Errorf("at most %d relays can be used: %w", maxNostrRelays, apperrors.ErrInvalidInput)
+	}
+	if len(relays) == 0 {
+		return append([]string(nil), defaultNostrRelays...), nil
+	}
+	return relays, nil
+}
+
+// nostrEvent is a signed Nostr event (NIP-01).
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// nostrString writes s as NIP-01 serializes strings: only the quote, the
+// backslash and the line break, carriage return, tab, backspace and form
+// feed characters are escaped, so the id is the same everywhere.
+func nostrString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// serialize returns the form of the event its id is the hash of.
+func (e *nostrEvent) serialize() string {
+	var b strings.Builder
+	b.WriteString(`[0,`)
+	nostrString(&b, e.PubKey)
+	b.WriteString("," + strconv.FormatInt(e.CreatedAt, 10) + "," + strconv.Itoa(e.Kind) + ",[")
+	for i, tag := range e.Tags {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('[')
+		for j, value := range tag {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			nostrString(&b, value)
+		}
+		b.WriteByte(']')
+	}
+	b.WriteString("],")
+	nostrString(&b, e.Content)
+	b.WriteByte(']')
+	return b.String()
+}
+
+// sign sets the public key, id and signature of the event.
+func (e *nostrEvent) sign(secretKey []byte) error {
+	private, public := btcec.PrivKeyFromBytes(secretKey)
+	e.PubKey = hex.EncodeToString(schnorr.SerializePubKey(public))
+	id := sha256.Sum256([]byte(e.serialize()))
+	sig, err := schnorr.Sign(private, id[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign the event: %v", err)
+	}
+	e.ID = hex.EncodeToString(id[:])
+	e.Sig = hex.EncodeToString(sig.Serialize())
+	return nil
+}
+
+// newNostrNote announces a blog as a text note: the caption and the link,
+// with the link and the blog's tags as tags so clients can find the note.
+func newNostrNote(caption, link string, tagSlugs []string) *nostrEvent {
+	content := strings.TrimSpace(caption)
+	tags := [][]string{}
+	if link != "" {
+		content += "\n\n" + link
+		tags = append(tags, []string{"r", link})
+	}
+	for _, slug := range tagSlugs {
+		if slug = strings.ToLower(strings.TrimSpace(slug)); slug != "" {
+			tags = append(tags, []string{"t", slug})
+		}
+	}
+	return &nostrEvent{CreatedAt: utils.Now().Unix(), Kind: nostrTextNote, Tags: tags, Content: strings.TrimSpace(content)}
+}
+
+// sendNostrEvent publishes the event to one relay and waits for the relay to
+// accept or refuse it.
+func sendNostrEvent(userId, relay string, event *nostrEvent) error {
+	parsed, err := url.Parse(relay)
+	if err != nil {
+		return err
+	}
+	if err := checkNostrHost(parsed.Hostname()); err != nil {
+		return err
+	}
+	timeout := providerTimeouts[ProviderNostr]
+	dialer := websocket.Dialer{HandshakeTimeout: timeout, Proxy: outboundTransport.Proxy}
+	conn, resp, err := dialer.Dial(relay, nil)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", parsed.Host, err)
+	}
+	defer conn.Close()
+	conn.SetReadLimit(1 << 16)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+
+	if err := conn.WriteJSON([]interface{}{"EVENT", event}); err != nil {
+		return fmt.Errorf("failed to send the event to %s: %v", parsed.Host, err)
+	}
+	// Relays may send notices or other subscriptions' messages first
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("%s didn't confirm the event: %v", parsed.Host, err)
+		}
+		var reply []json.RawMessage
+		if json.Unmarshal(message, &reply) != nil || len(reply) < 3 {
+			continue
+		}
+		var label, id string
+		var accepted bool
+		var reason string
+		json.Unmarshal(reply[0], &label)
+		json.Unmarshal(reply[1], &id)
+		if label != "OK" || id != event.ID {
+			continue
+		}
+		archiveProviderResponse(userId, "nostr", relay, resp.StatusCode, message)
+		json.Unmarshal(reply[2], &accepted)
+		if len(reply) > 3 {
+			json.Unmarshal(reply[3], &reason)
+		}
+		if !accepted {
+			if strings.HasPrefix(reason, "rate-limited:") {
+				return fmt.Errorf("%s throttled the event: %w", parsed.Host, apperrors.ErrProviderRateLimited)
+			}
+			return fmt.Errorf("%s refused the event: %s", parsed.Host, reason)
+		}
+		return nil
+	}
+}
+
+// publishNostrNote signs the note with the user's key and publishes it to
+// their relays at once. It succeeds if any relay accepts the note, and
+// returns where the note can be viewed.
+func publishNostrNote(user *models.User, event *nostrEvent) (string, error) {
+	if user.Nostr == nil {
+		return "", fmt.Errorf("Nostr is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	userId := user.Id.Hex()
+	hexKey, err := OpenUserSecret(user, user.Nostr.SealedSecretKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Nostr secret key: %v", err)
+	}
+	secretKey, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the Nostr secret key: %v", err)
+	}
+	if err := event.sign(secretKey); err != nil {
+		return "", err
+	}
+
+	errs := make([]error, len(user.Nostr.Relays))
+	var wg sync.WaitGroup
+	for i, relay := range user.Nostr.Relays {
+		wg.Add(1)
+		go func(i int, relay string) {
+			defer wg.Done()
+			errs[i] = sendNostrEvent(userId, relay, event)
+		}(i, relay)
+	}
+	wg.Wait()
+	var accepted int
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("[WARN] Relay %s didn't take the note of user %s: %v", user.Nostr.Relays[i], userId, err)
+			continue
+		}
+		accepted++
+	}
+	if accepted == 0 {
+		return "", fmt.Errorf("no relay accepted the note: %w", errors.Join(errs...))
+	}
+	id, _ := hex.DecodeString(event.ID)
+	note, err := bech32Encode("note", id)
+	if err != nil {
+		return "", err
+	}
+	return nostrNoteURL + note, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestNostrKeys(t *testing.T) {
+	// NIP-19 examples
+	key, err := DecodeNostrSecretKey("nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5")
+	if err != nil || hex.EncodeToString(key) != "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa" {
+		t.Errorf("decoded %x, %v", key, err)
+	}
+	pub, _ := hex.DecodeString("7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e")
+	if npub, err := bech32Encode("npub", pub); npub != "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg" {
+		t.Errorf("encoded %s, %v", npub, err)
+	}
+
+	for _, raw := range []string{
+		"nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe6",
+		"npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg",
+		strings.Repeat("0", 64),
+		strings.Repeat("f", 64),
+		"67dea2ed",
+	} {
+		if _, err := DecodeNostrSecretKey(raw); !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Errorf("accepted %s: %v", raw, err)
+		}
+	}
+
+	previous := checkNostrHost
+	checkNostrHost = func(string) error { return nil }
+	defer func() { checkNostrHost = previous }()
+	relays, err := NormalizeNostrRelays([]string{" wss://Relay.Example.com/ ", "wss://relay.example.com", "wss://nos.example/inbox"})
+	if err != nil || strings.Join(relays, " ") != "wss://relay.example.com wss://nos.example/inbox" {
+		t.Errorf("normalized to %v, %v", relays, err)
+	}
+	if relays, _ := NormalizeNostrRelays(nil); len(relays) != len(defaultNostrRelays) {
+		t.Errorf("got %v without relays", relays)
+	}
+	if _, err := NormalizeNostrRelays([]string{"https://relay.example.com"}); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted an https relay: %v", err)
+	}
+}
+
+func TestNostrEventSerialization(t *testing.T) {
+	event := &nostrEvent{PubKey: "ab", CreatedAt: 1700000000, Kind: nostrTextNote, Tags: [][]string{{"r", "https://a.example/?x=1&y=2"}, {"t", "go"}}, Content: "Tabs\tand \"quotes\" <b> \nhere"}
+	want := "[0,\"ab\",1700000000,1,[[\"r\",\"https://a.example/?x=1&y=2\"],[\"t\",\"go\"]],\"Tabs\\tand \\\"quotes\\\" <b> \\nhere\"]"
+	if got := event.serialize(); got != want {
+		t.Errorf("serialized\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPublishNostrNote(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	upgrader := websocket.Upgrader{}
+	relay := func(accept bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			var message []json.RawMessage
+			if err := conn.ReadJSON(&message); err != nil || len(message) != 2 {
+				return
+			}
+			var event nostrEvent
+			json.Unmarshal(message[1], &event)
+			id := sha256.Sum256([]byte(event.serialize()))
+			sigBytes, _ := hex.DecodeString(event.Sig)
+			pubBytes, _ := hex.DecodeString(event.PubKey)
+			sig, sigErr := schnorr.ParseSignature(sigBytes)
+			pub, pubErr := schnorr.ParsePubKey(pubBytes)
+			valid := hex.EncodeToString(id[:]) == event.ID && sigErr == nil && pubErr == nil && sig.Verify(id[:], pub)
+			conn.WriteJSON([]interface{}{"NOTICE", "welcome"})
+			if !valid {
+				conn.WriteJSON([]interface{}{"OK", event.ID, false, "invalid: bad signature"})
+			} else if !accept {
+				conn.WriteJSON([]interface{}{"OK", event.ID, false, "blocked: not on the allow list"})
+			} else {
+				conn.WriteJSON([]interface{}{"OK", event.ID, true, ""})
+			}
+		}))
+	}
+	accepting, refusing := relay(true), relay(false)
+	defer accepting.Close()
+	defer refusing.Close()
+	previous := checkNostrHost
+	checkNostrHost = func(string) error { return nil }
+	defer func() { checkNostrHost = previous }()
+
+	secretKey, _ := hex.DecodeString("67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa")
+	user := &models.User{Id: primitive.NewObjectID(), Nostr: &models.NostrAccount{
+		Relays: []string{"ws" + strings.TrimPrefix(refusing.URL, "http")},
+	}}
+	var err error
+	if user.Nostr.SealedSecretKey, err = SealUserSecret(user, hex.EncodeToString(secretKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	note := newNostrNote("New post on scheduling", "https://blog.example.com/scheduling", []string{"go"})
+	if _, err := publishNostrNote(user, note); err == nil || !strings.Contains(err.Error(), "not on the allow list") {
+		t.Errorf("published with only a refusing relay: %v", err)
+	}
+
+	user.Nostr.Relays = append(user.Nostr.Relays, "ws"+strings.TrimPrefix(accepting.URL, "http"))
+	note = newNostrNote("New post on scheduling", "https://blog.example.com/scheduling", []string{"go"})
+	noteURL, err := publishNostrNote(user, note)
+	if err != nil || !strings.HasPrefix(noteURL, nostrNoteURL+"note1") {
+		t.Fatalf("published at %q, %v", noteURL, err)
+	}
+	_, id, err := bech32Decode(strings.TrimPrefix(noteURL, nostrNoteURL))
+	if err != nil || hex.EncodeToString(id) != note.ID {
+		t.Errorf("note id %x, want %s, %v", id, note.ID, err)
+	}
+	if note.Content != "New post on scheduling\n\nhttps://blog.example.com/scheduling" {
+		t.Errorf("note content %q", note.Content)
+	}
+}
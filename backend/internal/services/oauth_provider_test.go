@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	// The example of RFC 7636 appendix B
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+	cases := []struct {
+		name                string
+		challenge, verifier string
+		want                bool
+	}{
+		{name: "S256", challenge: challenge, verifier: verifier, want: true},
+		{name: "wrong verifier", challenge: challenge, verifier: "eBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"},
+		{name: "missing verifier", challenge: challenge},
+		{name: "short verifier", challenge: challenge, verifier: "too-short"},
+		{name: "plain method", challenge: verifier, verifier: verifier},
+		{name: "no challenge recorded", verifier: verifier},
+		{name: "no pkce", want: true},
+	}
+	for _, tc := range cases {
+		if got := VerifyPKCE(tc.challenge, tc.verifier); got != tc.want {
+			t.Errorf("%s: VerifyPKCE = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
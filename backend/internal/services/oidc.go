@@ -0,0 +1,281 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+// OIDCDiscovery is the subset of the provider metadata document we rely on.
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// OIDCIdentity is the identity asserted by a validated ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+type cachedDiscovery struct {
+	discovery OIDCDiscovery
+	fetchedAt time.Time
+}
+
+// checkOIDCHost guards the calls to the identity providers team admins
+// configure: only hosts resolving to public addresses are called. Tests
+// replace it to reach local servers.
+var checkOIDCHost = checkPublicHost
+
+var (
+	discoveryMu    sync.Mutex
+	discoveryCache = map[string]cachedDiscovery{}
+	oidcHTTPClient = &http.Client{Transport: outboundTransport, Timeout: 10 * time.Second, CheckRedirect: checkOIDCRedirect}
+)
+
+const (
+	discoveryCacheTTL = time.Hour
+	maxOIDCRedirects  = 5
+)
+
+// checkOIDCRedirect checks the host of every redirect, or a public issuer
+// could redirect the server to an internal address.
+func checkOIDCRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxOIDCRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxOIDCRedirects)
+	}
+	return checkOIDCHost(req.URL.Hostname())
+}
+
+// checkOIDCEndpoint fails unless the endpoint is an https URL on a public
+// host.
+func checkOIDCEndpoint(name, endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return fmt.Errorf("OIDC %s must be an https URL: %w", name, apperrors.ErrInvalidInput)
+	}
+	if err := checkOIDCHost(parsed.Hostname()); err != nil {
+		return fmt.Errorf("OIDC %s must be on a public host: %w", name, apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// DiscoverOIDC fetches and caches the provider metadata for an issuer.
+func DiscoverOIDC(issuer string) (*OIDCDiscovery, error) {
+	issuer = strings.TrimRight(issuer, "/")
+	discoveryMu.Lock()
+	cached, ok := discoveryCache[issuer]
+	discoveryMu.Unlock()
+	if ok && utils.Now().Sub(cached.fetchedAt) < discoveryCacheTTL {
+		return &cached.discovery, nil
+	}
+
+	if err := checkOIDCEndpoint("issuer", issuer); err != nil {
+		return nil, err
+	}
+	response, err := oidcHTTPClient.Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery returned status %d: %w", response.StatusCode, apperrors.ErrInvalidInput)
+	}
+
+	var discovery OIDCDiscovery
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("invalid OIDC discovery document: %v", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer %q does not match %q: %w", discovery.Issuer, issuer, apperrors.ErrInvalidInput)
+	}
+	// The browser follows the authorization endpoint, but the server calls
+	// the token endpoint
+	if !strings.HasPrefix(discovery.AuthorizationEndpoint, "https://") {
+		return nil, fmt.Errorf("OIDC endpoints must use https: %w", apperrors.ErrInvalidInput)
+	}
+	if err := checkOIDCEndpoint("token endpoint", discovery.TokenEndpoint); err != nil {
+		return nil, err
+	}
+
+	discoveryMu.Lock()
+	discoveryCache[issuer] = cachedDiscovery{discovery: discovery, fetchedAt: utils.Now()}
+	discoveryMu.Unlock()
+	return &discovery, nil
+}
+
+// NewOIDCRequestSecrets returns a random state, nonce and PKCE verifier for
+// one login attempt.
+func NewOIDCRequestSecrets() (state, nonce, verifier string, err error) {
+	values := make([]string, 3)
+	for i := range values {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return "", "", "", fmt.Errorf("failed to generate random value: %v", err)
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// OIDCAuthURL builds the authorization request sent to the team's IdP.
+func OIDCAuthURL(discovery *OIDCDiscovery, sso models.TeamSSOConfig, redirectURI, state, nonce, verifier, loginHint string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {sso.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if loginHint != "" {
+		params.Set("login_hint", loginHint)
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// ExchangeOIDCCode redeems an authorization code and validates the returned
+// ID token. The token comes straight from the token endpoint over TLS, which
+// OIDC Core 3.1.3.7 accepts in place of verifying its signature; the issuer,
+// audience, expiry and nonce are still checked.
+func ExchangeOIDCCode(discovery *OIDCDiscovery, sso models.TeamSSOConfig, redirectURI, code, verifier, nonce string) (*OIDCIdentity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	// Checked again since the discovery document is cached and DNS may have
+	// changed since
+	if err := checkOIDCEndpoint("token endpoint", discovery.TokenEndpoint); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(sso.ClientID), url.QueryEscape(sso.ClientSecret))
+
+	response, err := oidcHTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute token request: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d: %w", response.StatusCode, apperrors.ErrUnauthorized)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token: %w", apperrors.ErrUnauthorized)
+	}
+
+	parts := strings.Split(tokens.IDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed id_token: %w", apperrors.ErrUnauthorized)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id_token payload: %w", apperrors.ErrUnauthorized)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed id_token claims: %w", apperrors.ErrUnauthorized)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != strings.TrimRight(discovery.Issuer, "/") {
+		return nil, fmt.Errorf("id_token issuer mismatch: %w", apperrors.ErrUnauthorized)
+	}
+	if !audienceContains(claims["aud"], sso.ClientID) {
+		return nil, fmt.Errorf("id_token audience mismatch: %w", apperrors.ErrUnauthorized)
+	}
+	if exp, _ := claims["exp"].(float64); time.Unix(int64(exp), 0).Before(utils.Now()) {
+		return nil, fmt.Errorf("id_token expired: %w", apperrors.ErrUnauthorized)
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch: %w", apperrors.ErrUnauthorized)
+	}
+
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if identity.Subject == "" || identity.Email == "" {
+		return nil, fmt.Errorf("id_token lacks sub or email: %w", apperrors.ErrUnauthorized)
+	}
+	if sso.GroupsClaim != "" {
+		if groups, ok := claims[sso.GroupsClaim].([]interface{}); ok {
+			for _, group := range groups {
+				if name, ok := group.(string); ok {
+					identity.Groups = append(identity.Groups, name)
+				}
+			}
+		}
+	}
+	return identity, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MapTeamRole translates IdP groups into a team role. Admin wins over member;
+// ownership is never granted through the IdP.
+func MapTeamRole(sso models.TeamSSOConfig, groups []string) string {
+	role := sso.DefaultRole
+	if role != models.TeamRoleAdmin {
+		role = models.TeamRoleMember
+	}
+	for _, group := range groups {
+		if sso.RoleMapping[group] == models.TeamRoleAdmin {
+			return models.TeamRoleAdmin
+		}
+	}
+	return role
+}
+
+// EmailDomain returns the lower-cased domain part of an email address.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
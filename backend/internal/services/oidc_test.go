@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+)
+
+func TestDiscoverOIDCChecksHosts(t *testing.T) {
+	var tokenEndpoint string
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/good/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer": %q, "authorization_endpoint": "https://idp.example.com/auth", "token_endpoint": %q}`, server.URL+"/good", tokenEndpoint)
+	})
+	mux.HandleFunc("/moved/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://metadata.internal/latest/meta-data", http.StatusFound)
+	})
+
+	previousClient := oidcHTTPClient
+	oidcHTTPClient = server.Client()
+	oidcHTTPClient.CheckRedirect = checkOIDCRedirect
+	previousCheck := checkOIDCHost
+	checkOIDCHost = func(host string) error {
+		if host != "127.0.0.1" {
+			return fmt.Errorf("%s is internal", host)
+		}
+		return nil
+	}
+	defer func() { oidcHTTPClient, checkOIDCHost = previousClient, previousCheck }()
+
+	if _, err := DiscoverOIDC("https://metadata.internal"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("internal issuer: %v", err)
+	}
+	if _, err := DiscoverOIDC(server.URL + "/moved"); err == nil {
+		t.Error("followed a redirect to an internal host")
+	}
+	tokenEndpoint = "https://metadata.internal/token"
+	if _, err := DiscoverOIDC(server.URL + "/good"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("internal token endpoint: %v", err)
+	}
+	tokenEndpoint = server.URL + "/token"
+	if _, err := DiscoverOIDC(server.URL + "/good"); err != nil {
+		t.Errorf("public issuer: %v", err)
+	}
+}
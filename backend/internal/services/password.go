@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing is configured through the environment:
+//
+//	PASSWORD_HASH_ALGO  "bcrypt" (default) or "argon2id"
+//	BCRYPT_COST         bcrypt cost factor (default bcrypt.DefaultCost)
+//	ARGON2_TIME         argon2id iterations (default 1)
+//	ARGON2_MEMORY       argon2id memory in KiB (default 64 MiB)
+//	ARGON2_THREADS      argon2id parallelism (default 4)
+//
+// Stored hashes carry their own parameters, so changing the configuration
+// never breaks existing logins; VerifyPassword reports when a hash should be
+// upgraded to the current settings.
+
+const (
+	hashAlgoBcrypt   = "bcrypt"
+	hashAlgoArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+func passwordHashAlgo() string {
+	algo := strings.ToLower(strings.TrimSpace(os.Getenv("PASSWORD_HASH_ALGO")))
+	if algo == hashAlgoArgon2id {
+		return hashAlgoArgon2id
+	}
+	return hashAlgoBcrypt
+}
+
+func bcryptCost() int {
+	cost, err := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+func currentArgon2Params() argon2Params {
+	params := argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4}
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil && v > 0 {
+		params.Time = uint32(v)
+	}
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil && v > 0 {
+		params.Memory = uint32(v)
+	}
+	if v, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil && v > 0 {
+		params.Threads = uint8(v)
+	}
+	return params
+}
+
+// HashPassword hashes the password with the currently configured algorithm.
+func HashPassword(password string) (string, error) {
+	if passwordHashAlgo() == hashAlgoArgon2id {
+		return hashArgon2id(password, currentArgon2Params())
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// VerifyPassword checks the password against a stored bcrypt or argon2id hash.
+// needsRehash is true when the password matched but the hash was produced with
+// a different algorithm or parameters than the ones currently configured.
+func VerifyPassword(hash, password string) (match bool, needsRehash bool, err error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, computed) != 1 {
+			return false, false, nil
+		}
+		return true, passwordHashAlgo() != hashAlgoArgon2id || params != currentArgon2Params(), nil
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if passwordHashAlgo() != hashAlgoBcrypt {
+		return true, true, nil
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true, true, nil
+	}
+	return true, cost != bcryptCost(), nil
+}
+
+func hashArgon2id(password string, params argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id version: %v", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %v", err)
+	}
+	// argon2 panics on these rather than return an error
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key: %v", err)
+	}
+	if len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: empty key")
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useHashing configures the password hashing for the test, with cheap
+// parameters.
+func useHashing(t *testing.T, algo string) {
+	t.Helper()
+	t.Setenv("PASSWORD_HASH_ALGO", algo)
+	t.Setenv("BCRYPT_COST", "4")
+	t.Setenv("ARGON2_TIME", "1")
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_THREADS", "1")
+}
+
+func TestPasswordRoundTrip(t *testing.T) {
+	for _, algo := range []string{hashAlgoBcrypt, hashAlgoArgon2id} {
+		useHashing(t, algo)
+		hash, err := HashPassword("correct horse")
+		if err != nil {
+			t.Fatalf("%s: %v", algo, err)
+		}
+		if match, needsRehash, err := VerifyPassword(hash, "correct horse"); !match || needsRehash || err != nil {
+			t.Errorf("%s: VerifyPassword = %t, %t, %v; want a current match", algo, match, needsRehash, err)
+		}
+		if match, _, err := VerifyPassword(hash, "correct horse "); match || err != nil {
+			t.Errorf("%s: a wrong password = %t, %v", algo, match, err)
+		}
+		other, err := HashPassword("correct horse")
+		if err != nil || other == hash {
+			t.Errorf("%s: hashing again gave %q, %v; want a new salt", algo, other, err)
+		}
+	}
+}
+
+func TestPasswordRehash(t *testing.T) {
+	useHashing(t, hashAlgoBcrypt)
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, needsRehash, err := VerifyPassword(string(legacy), "correct horse"); !match || !needsRehash || err != nil {
+		t.Errorf("bcrypt at another cost = %t, %t, %v; want an upgrade", match, needsRehash, err)
+	}
+	if match, needsRehash, _ := VerifyPassword(string(legacy), "wrong"); match || needsRehash {
+		t.Errorf("a wrong password asked for an upgrade")
+	}
+
+	useHashing(t, hashAlgoArgon2id)
+	if match, needsRehash, err := VerifyPassword(string(legacy), "correct horse"); !match || !needsRehash || err != nil {
+		t.Errorf("bcrypt with argon2id configured = %t, %t, %v; want an upgrade", match, needsRehash, err)
+	}
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARGON2_TIME", "2")
+	if match, needsRehash, err := VerifyPassword(hash, "correct horse"); !match || !needsRehash || err != nil {
+		t.Errorf("argon2id with other parameters = %t, %t, %v; want an upgrade", match, needsRehash, err)
+	}
+	t.Setenv("PASSWORD_HASH_ALGO", hashAlgoBcrypt)
+	if match, needsRehash, err := VerifyPassword(hash, "correct horse"); !match || !needsRehash || err != nil {
+		t.Errorf("argon2id with bcrypt configured = %t, %t, %v; want an upgrade", match, needsRehash, err)
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	params, salt, key, err := decodeArgon2id("$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != (argon2Params{Time: 3, Memory: 65536, Threads: 2}) || string(salt) != "saltsaltsaltsalt" || len(key) != 29 {
+		t.Errorf("decoded %+v, salt %q, key of %d bytes", params, salt, len(key))
+	}
+
+	for name, hash := range map[string]string{
+		"too few fields": "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA",
+		"other version":  "$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"bad parameters": "$argon2id$v=19$t=3$c2FsdA$a2V5",
+		"no parallelism": "$argon2id$v=19$m=65536,t=3,p=0$c2FsdA$a2V5",
+		"no iterations":  "$argon2id$v=19$m=65536,t=0,p=2$c2FsdA$a2V5",
+		"no memory":      "$argon2id$v=19$m=0,t=3,p=2$c2FsdA$a2V5",
+		"bad salt":       "$argon2id$v=19$m=65536,t=3,p=2$!!$a2V5",
+		"bad key":        "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$!!",
+		"empty key":      "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$",
+	} {
+		if _, _, _, err := decodeArgon2id(hash); err == nil {
+			t.Errorf("%s: decoded %q", name, hash)
+		}
+		if match, _, err := VerifyPassword(hash, "password"); match || err == nil {
+			t.Errorf("%s: VerifyPassword = %t, %v; want an error", name, match, err)
+		}
+	}
+	if match, _, err := VerifyPassword("not a hash", "password"); match || err == nil {
+		t.Errorf("an unknown hash format: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+// interactions counts every reaction to a share alike.
+func interactions(engagement models.ShareEngagement) int {
+	return engagement.Likes + engagement.Reposts + engagement.Comments
+}
+
+// platformEngagement is the engagement of a share on each platform. Shares
+// to a single platform recorded before engagement was split by platform are
+// attributed to it.
+func platformEngagement(blog models.SharedBlog) map[string]models.ShareEngagement {
+	if len(blog.PlatformEngagement) > 0 {
+		return blog.PlatformEngagement
+	}
+	if blog.Engagement != nil && len(blog.Platforms) == 1 {
+		return map[string]models.ShareEngagement{blog.Platforms[0]: *blog.Engagement}
+	}
+	return nil
+}
+
+// roundRate keeps two decimals, which is all the summaries are precise to.
+func roundRate(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// CompareShareEngagement compares the engagement of the user's blogs shared
+// between from and to across the platforms they were shared to. Shares
+// without known engagement are left out.
+func CompareShareEngagement(user *models.User, from, to time.Time) models.PlatformAnalytics {
+	analytics := models.PlatformAnalytics{
+		From:      from,
+		To:        to,
+		Platforms: map[string]models.PlatformSummary{},
+		Articles:  []models.ArticleComparison{},
+	}
+	type engagedShare struct {
+		blog       models.SharedBlog
+		sharedAt   time.Time
+		engagement map[string]models.ShareEngagement
+	}
+	var shares []engagedShare
+	counts := map[string][]int{}
+	for _, blog := range user.SharedBlogs {
+		sharedAt, err := time.Parse(time.RFC3339, blog.SharedTime)
+		if err != nil || sharedAt.Before(from) || !sharedAt.Before(to) {
+			continue
+		}
+		engagement := platformEngagement(blog)
+		if len(engagement) == 0 {
+			continue
+		}
+		shares = append(shares, engagedShare{blog: blog, sharedAt: sharedAt, engagement: engagement})
+		for platform, onPlatform := range engagement {
+			counts[platform] = append(counts[platform], interactions(onPlatform))
+			summary := analytics.Platforms[platform]
+			summary.Shares++
+			addEngagement(&summary.Engagement, onPlatform)
+			analytics.Platforms[platform] = summary
+		}
+	}
+
+	means := map[string]float64{}
+	for platform, values := range counts {
+		sort.Ints(values)
+		summary := analytics.Platforms[platform]
+		sum := 0
+		for _, value := range values {
+			sum += value
+		}
+		mean := float64(sum) / float64(len(values))
+		variance := 0.0
+		for _, value := range values {
+			variance += (float64(value) - mean) * (float64(value) - mean)
+		}
+		middle := len(values) / 2
+		summary.Median = float64(values[middle])
+		if len(values)%2 == 0 {
+			summary.Median = float64(values[middle-1]+values[middle]) / 2
+		}
+		summary.Mean = roundRate(mean)
+		summary.StdDev = roundRate(math.Sqrt(variance / float64(len(values))))
+		summary.Min, summary.Max = values[0], values[len(values)-1]
+		analytics.Platforms[platform] = summary
+		means[platform] = mean
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool { return shares[i].sharedAt.After(shares[j].sharedAt) })
+	for _, share := range shares {
+		if len(share.engagement) < 2 {
+			continue
+		}
+		article := models.ArticleComparison{
+			BlogID:     share.blog.Id,
+			Title:      share.blog.Title,
+			URL:        share.blog.Url,
+			SharedTime: share.blog.SharedTime,
+			Platforms:  map[string]models.ArticlePlatform{},
+		}
+		bestRate := 0.0
+		for platform, engagement := range share.engagement {
+			count := interactions(engagement)
+			rate := 0.0
+			if means[platform] > 0 {
+				rate = float64(count) / means[platform]
+			}
+			article.Platforms[platform] = models.ArticlePlatform{
+				Engagement:   engagement,
+				Interactions: count,
+				Rate:         roundRate(rate),
+			}
+			if rate > bestRate || (rate == bestRate && rate > 0 && platform < article.BestPlatform) {
+				bestRate = rate
+				article.BestPlatform = platform
+			}
+		}
+		analytics.Articles = append(analytics.Articles, article)
+	}
+	return analytics
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestCompareShareEngagement(t *testing.T) {
+	user := &models.User{
+		SharedBlogs: []models.SharedBlog{
+			{Blog: models.Blog{Id: "a", Title: "Queues"}, Platforms: []string{"twitter", "linkedin"}, SharedTime: "2026-10-01T09:00:00Z",
+				PlatformEngagement: map[string]models.ShareEngagement{"twitter": {Likes: 2}, "linkedin": {Likes: 8, Comments: 4}}},
+			{Blog: models.Blog{Id: "b", Title: "Scheduling"}, Platforms: []string{"twitter", "linkedin"}, SharedTime: "2026-10-03T09:00:00Z",
+				PlatformEngagement: map[string]models.ShareEngagement{"twitter": {Likes: 6, Reposts: 2}, "linkedin": {Likes: 4}}},
+			// Recorded before engagement was split by platform
+			{Blog: models.Blog{Id: "c"}, Platforms: []string{"twitter"}, SharedTime: "2026-10-02T09:00:00Z",
+				Engagement: &models.ShareEngagement{Likes: 5}},
+			// Which platform engaged is unknown
+			{Blog: models.Blog{Id: "d"}, Platforms: []string{"twitter", "linkedin"}, SharedTime: "2026-10-02T10:00:00Z",
+				Engagement: &models.ShareEngagement{Likes: 90}},
+			{Blog: models.Blog{Id: "old"}, Platforms: []string{"twitter"}, SharedTime: "2026-08-01T09:00:00Z",
+				Engagement: &models.ShareEngagement{Likes: 70}},
+		},
+	}
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	got := CompareShareEngagement(user, from, from.AddDate(0, 1, 15))
+
+	twitter := got.Platforms["twitter"]
+	if twitter.Shares != 3 || twitter.Engagement != (models.ShareEngagement{Likes: 13, Reposts: 2}) ||
+		twitter.Mean != 5 || twitter.Median != 5 || twitter.StdDev != 2.45 || twitter.Min != 2 || twitter.Max != 8 {
+		t.Errorf("twitter = %+v", twitter)
+	}
+	linkedin := got.Platforms["linkedin"]
+	if linkedin.Shares != 2 || linkedin.Mean != 8 || linkedin.Median != 8 || linkedin.StdDev != 4 {
+		t.Errorf("linkedin = %+v", linkedin)
+	}
+
+	if len(got.Articles) != 2 || got.Articles[0].BlogID != "b" || got.Articles[1].BlogID != "a" {
+		t.Fatalf("articles = %+v", got.Articles)
+	}
+	queues := got.Articles[1]
+	if queues.Platforms["twitter"].Rate != 0.4 || queues.Platforms["linkedin"].Rate != 1.5 || queues.BestPlatform != "linkedin" {
+		t.Errorf("Queues = %+v", queues)
+	}
+	if scheduling := got.Articles[0]; scheduling.BestPlatform != "twitter" || scheduling.Platforms["twitter"].Interactions != 8 {
+		t.Errorf("Scheduling = %+v", scheduling)
+	}
+}
@@ -0,0 +1,609 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+)
+
+// Platform is a network blogs are shared to. The share pipeline only knows
+// platforms through this interface, so adding one to the registry is all it
+// takes to share there.
+type Platform interface {
+	// Name is the platform's id in share requests and settings, such as
+	// "twitter".
+	Name() string
+	// Title is the platform's name as users know it.
+	Title() string
+	// Connected reports whether the user has connected the platform.
+	Connected(user *models.User) bool
+	// Connect marks the platform connected once its connection flow has
+	// stored the user's credentials.
+	Connect(user *models.User)
+	// Disconnect drops the user's credentials for the platform.
+	Disconnect(user *models.User)
+	// Capabilities describes what posts on the platform can hold.
+	Capabilities() PlatformCapabilities
+	// ValidateContent checks that the blog can be shared to the platform.
+	// Every platform of a share is checked before anything is posted.
+	ValidateContent(share *Share) error
+	// Post shares the blog and returns the post's URL, which is empty if
+	// the platform doesn't say where the post lives.
+	Post(user *models.User, share *Share) (string, error)
+}
+
+// Share is a blog as platforms get it: the blog itself, the caption written
+// for it and the user's record of its earlier shares.
+type Share struct {
+	BlogID     string
+	Title      string
+	URL        string
+	Brief      string
+	Caption    string
+	Author     string
+	ReadTime   int
+	CoverImage string
+	// CardImage is the image link previews show, the cover image or a
+	// library asset replacing it.
+	CardImage   string
+	HTML        string
+	Markdown    string
+	Tags        []string
+	CampaignTag string
+	// LinkedInPage is the LinkedIn Page to post for, as in
+	// models.ScheduledBlog.
+	LinkedInPage string
+	// XThread posts the blog to X as a thread rather than one tweet.
+	XThread bool
+	// Record is the user's record of the blog's shares. Platforms that
+	// update their copy on a reshare keep its id there.
+	Record *models.SharedBlog
+}
+
+// Link is the blog's URL tagged for the platform's campaign analytics.
+func (s *Share) Link(platform string) string {
+	return CampaignURL(s.URL, s.CampaignTag, platform)
+}
+
+// PlatformCapabilities describes what posts on a platform can hold, for
+// clients to offer only the share options that apply.
+type PlatformCapabilities struct {
+	// MaxLength is the most characters of text a post takes, 0 when the
+	// platform sets no limit the share has to fit.
+	MaxLength int `json:"max_length"`
+	// Images tells whether an image of the blog is posted with it.
+	Images bool `json:"images"`
+	// Threads tells whether the blog can be posted as a thread.
+	Threads bool `json:"threads"`
+	// LinkCards tells whether the blog's link is shown as a preview card.
+	LinkCards bool `json:"link_cards"`
+	// FullArticle tells whether the whole blog is republished rather than
+	// linked.
+	FullArticle bool `json:"full_article"`
+	// RequiresApproval tells whether posts wait for the user to put them
+	// up by hand, as the platform can't be posted to for them.
+	RequiresApproval bool `json:"requires_approval"`
+}
+
+// sharePlatform implements Platform with the functions of one network.
+type sharePlatform struct {
+	name         string
+	title        string
+	capabilities PlatformCapabilities
+	// verified points at the user's flag for the platform.
+	verified func(user *models.User) *bool
+	// clear drops the user's credentials for the platform.
+	clear    func(user *models.User)
+	validate func(share *Share) error
+	post     func(user *models.User, share *Share) (string, error)
+}
+
+func (p *sharePlatform) Name() string  { return p.name }
+func (p *sharePlatform) Title() string { return p.title }
+
+func (p *sharePlatform) Capabilities() PlatformCapabilities { return p.capabilities }
+
+func (p *sharePlatform) Connected(user *models.User) bool {
+	return *p.verified(user)
+}
+
+func (p *sharePlatform) Connect(user *models.User) {
+	*p.verified(user) = true
+	RefreshVerified(user)
+}
+
+func (p *sharePlatform) Disconnect(user *models.User) {
+	p.clear(user)
+	*p.verified(user) = false
+	RefreshVerified(user)
+}
+
+func (p *sharePlatform) ValidateContent(share *Share) error {
+	if share.URL == "" {
+		return fmt.Errorf("%s needs the blog's URL: %w", p.title, apperrors.ErrInvalidInput)
+	}
+	if p.validate == nil {
+		return nil
+	}
+	if err := p.validate(share); err != nil {
+		return fmt.Errorf("%s needs %w", p.title, err)
+	}
+	return nil
+}
+
+func (p *sharePlatform) Post(user *models.User, share *Share) (string, error) {
+	return p.post(user, share)
+}
+
+// requireCaption is for platforms that post the caption as the whole post.
+func requireCaption(share *Share) error {
+	if strings.TrimSpace(share.Caption) == "" {
+		return fmt.Errorf("a caption: %w", apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// requireTitle is for platforms that post links under the blog's title.
+func requireTitle(share *Share) error {
+	if strings.TrimSpace(share.Title) == "" {
+		return fmt.Errorf("the blog's title: %w", apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// requireMarkdown is for platforms that republish the whole article.
+func requireMarkdown(share *Share) error {
+	if err := requireTitle(share); err != nil {
+		return err
+	}
+	if strings.TrimSpace(share.Markdown) == "" {
+		return fmt.Errorf("the blog's content: %w", apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+func requireHTML(share *Share) error {
+	if err := requireTitle(share); err != nil {
+		return err
+	}
+	if strings.TrimSpace(share.HTML) == "" {
+		return fmt.Errorf("the blog's content: %w", apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+var twitterPlatform = &sharePlatform{
+	name:         "twitter",
+	title:        "X (Twitter)",
+	capabilities: PlatformCapabilities{MaxLength: MaxTweetLength, Images: true, Threads: true, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.XVerified },
+	clear: func(user *models.User) {
+		user.SealedXOAuthToken, user.PlaintextXOAuthToken = "", ""
+		user.SealedXOAuthSecret, user.PlaintextXOAuthSecret = "", ""
+	},
+	validate: func(share *Share) error {
+		if err := requireCaption(share); err != nil {
+			return err
+		}
+		_, err := tweetsFor(share)
+		return err
+	},
+	post: func(user *models.User, share *Share) (string, error) {
+		tweets, err := tweetsFor(share)
+		if err != nil {
+			return "", err
+		}
+		twitter, err := TwitterConfigFor(user)
+		if err != nil {
+			return "", err
+		}
+		token, err := XToken(user)
+		if err != nil {
+			return "", err
+		}
+		return postTweetHandler(user.Id.Hex(), tweets, share.BlogID, share.CoverImage, twitter, token)
+	},
+}
+
+// tweetsFor returns the tweets a share posts to X: the caption, or the
+// thread the blog is split into.
+func tweetsFor(share *Share) ([]string, error) {
+	if !share.XThread {
+		return []string{share.Caption}, nil
+	}
+	return TweetThread(share.Caption, share.Brief, share.Link("twitter"))
+}
+
+var linkedinPlatform = &sharePlatform{
+	name:         "linkedin",
+	title:        "LinkedIn",
+	capabilities: PlatformCapabilities{MaxLength: maxLinkedInCommentary, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.LinkedinVerified },
+	clear: func(user *models.User) {
+		user.SealedLinkedInOauthKey, user.PlaintextLinkedInOauthKey = "", ""
+		user.LinkedInPages = nil
+		user.LinkedInPageID = ""
+	},
+	validate: requireCaption,
+	post: func(user *models.User, share *Share) (string, error) {
+		author, err := linkedInAuthor(user, share.LinkedInPage)
+		if err != nil {
+			return "", fmt.Errorf("failed to find who to post as: %w", err)
+		}
+		token, err := LinkedInToken(user)
+		if err != nil {
+			return "", err
+		}
+		article := &linkedInArticle{URL: share.Link("linkedin"), Title: share.Title, ImageURL: share.CardImage}
+		return linkedPostHandler(user.Id.Hex(), author, share.Caption, token, article)
+	},
+}
+
+var mastodonPlatform = &sharePlatform{
+	name:         "mastodon",
+	title:        "Mastodon",
+	capabilities: PlatformCapabilities{MaxLength: MaxTootLength, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.MastodonVerified },
+	clear: func(user *models.User) {
+		user.MastodonInstance = ""
+		user.MastodonAccount = ""
+		user.SealedMastodonAccessToken, user.PlaintextMastodonAccessToken = "", ""
+	},
+	validate: requireCaption,
+	post: func(user *models.User, share *Share) (string, error) {
+		token, err := OpenCredential(user, user.SealedMastodonAccessToken, user.PlaintextMastodonAccessToken)
+		if err != nil {
+			return "", err
+		}
+		status := TootText(share.Caption, share.Link("mastodon"))
+		return publicServers.postTootHandler(user.Id.Hex(), status, user.MastodonInstance, token)
+	},
+}
+
+var redditPlatform = &sharePlatform{
+	name:         "reddit",
+	title:        "Reddit",
+	capabilities: PlatformCapabilities{MaxLength: maxRedditTitle, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.RedditVerified },
+	clear:        func(user *models.User) { user.Reddit = models.RedditAccount{} },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		return submitRedditLink(user, share.Title, share.Link("reddit"))
+	},
+}
+
+var devtoPlatform = &sharePlatform{
+	name:         "devto",
+	title:        "DEV",
+	capabilities: PlatformCapabilities{Images: true, FullArticle: true},
+	verified:     func(user *models.User) *bool { return &user.DevtoVerified },
+	clear:        func(user *models.User) { user.Devto = nil },
+	validate: func(share *Share) error {
+		if _, ok := DevtoArticleID(share.BlogID); ok {
+			return fmt.Errorf("the article is already on Dev.to: %w", apperrors.ErrInvalidInput)
+		}
+		return requireMarkdown(share)
+	},
+	post: func(user *models.User, share *Share) (string, error) {
+		// The canonical URL points search engines at the original
+		article := devtoArticle{Title: share.Title, BodyMarkdown: portableMarkdown(share.Markdown), Published: true, CanonicalURL: share.URL, MainImage: share.CardImage, Tags: devtoTags(share.Tags)}
+		articleID, postURL, err := publishDevtoArticle(user, share.Record.DevtoArticleID, article)
+		if err != nil {
+			return "", err
+		}
+		share.Record.DevtoArticleID = articleID
+		return postURL, nil
+	},
+}
+
+var mediumPlatform = &sharePlatform{
+	name:         "medium",
+	title:        "Medium",
+	capabilities: PlatformCapabilities{Images: true, FullArticle: true},
+	verified:     func(user *models.User) *bool { return &user.MediumVerified },
+	clear:        func(user *models.User) { user.Medium = nil },
+	validate: func(share *Share) error {
+		if _, ok := MediumStoryID(share.BlogID); ok {
+			return fmt.Errorf("the story is already on Medium: %w", apperrors.ErrInvalidInput)
+		}
+		return requireMarkdown(share)
+	},
+	post: func(user *models.User, share *Share) (string, error) {
+		// Medium can't update stories, so a blog is published there once
+		if share.Record.MediumPostURL != "" {
+			return share.Record.MediumPostURL, nil
+		}
+		story := newMediumStory(share.Title, share.Markdown, share.URL, share.Tags)
+		postURL, err := publishMediumStory(user, story)
+		if err != nil {
+			return "", err
+		}
+		share.Record.MediumPostURL = postURL
+		return postURL, nil
+	},
+}
+
+var nostrPlatform = &sharePlatform{
+	name:         "nostr",
+	title:        "Nostr",
+	capabilities: PlatformCapabilities{},
+	verified:     func(user *models.User) *bool { return &user.NostrVerified },
+	clear:        func(user *models.User) { user.Nostr = nil },
+	validate:     requireCaption,
+	post: func(user *models.User, share *Share) (string, error) {
+		return publishNostrNote(user, newNostrNote(share.Caption, share.Link("nostr"), share.Tags))
+	},
+}
+
+var lemmyPlatform = &sharePlatform{
+	name:         "lemmy",
+	title:        "Lemmy",
+	capabilities: PlatformCapabilities{MaxLength: maxLemmyTitleLength, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.LemmyVerified },
+	clear:        func(user *models.User) { user.Lemmy = nil },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		return publicServers.submitLemmyLink(user, share.Title, share.Link("lemmy"))
+	},
+}
+
+var wordpressPlatform = &sharePlatform{
+	name:         "wordpress",
+	title:        "WordPress",
+	capabilities: PlatformCapabilities{Images: true, FullArticle: true},
+	verified:     func(user *models.User) *bool { return &user.WordPressVerified },
+	clear:        func(user *models.User) { user.WordPress = nil },
+	validate:     requireHTML,
+	post: func(user *models.User, share *Share) (string, error) {
+		post := newWordPressPost(share.Title, share.HTML, share.Brief, share.URL)
+		postID, postURL, err := publishWordPressPost(user, share.Record.WordPressPostID, post, share.CoverImage)
+		if err != nil {
+			return "", err
+		}
+		share.Record.WordPressPostID = postID
+		return postURL, nil
+	},
+}
+
+var matrixPlatform = &sharePlatform{
+	name:         "matrix",
+	title:        "Matrix",
+	capabilities: PlatformCapabilities{LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.MatrixVerified },
+	clear:        func(user *models.User) { user.Matrix = nil },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		return postMatrixMessage(user, matrixAnnouncement(share.Title, share.Link("matrix"), share.Caption, share.Author, share.ReadTime))
+	},
+}
+
+var tumblrPlatform = &sharePlatform{
+	name:         "tumblr",
+	title:        "Tumblr",
+	capabilities: PlatformCapabilities{LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.TumblrVerified },
+	clear:        func(user *models.User) { user.Tumblr = nil },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		return postTumblrLink(user, share.Title, share.Link("tumblr"), share.Caption, share.Tags)
+	},
+}
+
+var discordPlatform = &sharePlatform{
+	name:         "discord",
+	title:        "Discord",
+	capabilities: PlatformCapabilities{MaxLength: maxDiscordEmbedDescription, Images: true, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.DiscordVerified },
+	clear:        func(user *models.User) { user.DiscordWebhook = nil },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		embed := discordAnnouncement(share.Title, share.Link("discord"), share.Caption, share.Author, share.CardImage, share.ReadTime)
+		return postDiscordMessage(user, "", []discordEmbed{embed})
+	},
+}
+
+var teamsPlatform = &sharePlatform{
+	name:         "teams",
+	title:        "Microsoft Teams",
+	capabilities: PlatformCapabilities{MaxLength: maxTeamsCardSummary, Images: true, LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.TeamsVerified },
+	clear:        func(user *models.User) { user.TeamsWebhook = nil },
+	validate:     requireTitle,
+	post: func(user *models.User, share *Share) (string, error) {
+		card := teamsAnnouncement(share.Title, share.Link("teams"), share.Caption, share.Author, share.CardImage, share.ReadTime)
+		return "", postTeamsCard(user, card)
+	},
+}
+
+var newsletterPlatform = &sharePlatform{
+	name:         "newsletter",
+	title:        "Email newsletter",
+	capabilities: PlatformCapabilities{Images: true},
+	verified:     func(user *models.User) *bool { return &user.NewsletterVerified },
+	clear:        func(user *models.User) { user.Newsletter = nil },
+	validate:     requireTitle,
+	post:         sendNewsletter,
+}
+
+var substackPlatform = &sharePlatform{
+	name:         "substack",
+	title:        "Substack Notes",
+	capabilities: PlatformCapabilities{LinkCards: true},
+	verified:     func(user *models.User) *bool { return &user.SubstackVerified },
+	clear:        func(user *models.User) { user.Substack = nil },
+	validate:     requireCaption,
+	post: func(user *models.User, share *Share) (string, error) {
+		return postSubstackNote(user, share.Caption, share.Link("substack"))
+	},
+}
+
+var googleBusinessPlatform = &sharePlatform{
+	name:         "google_business",
+	title:        "Google Business Profile",
+	capabilities: PlatformCapabilities{MaxLength: maxGoogleBusinessSummary},
+	verified:     func(user *models.User) *bool { return &user.GoogleBusinessVerified },
+	clear:        func(user *models.User) { user.GoogleBusiness = nil },
+	validate:     requireCaption,
+	post: func(user *models.User, share *Share) (string, error) {
+		return postGoogleBusinessUpdate(user, share.Caption, share.Link("google_business"))
+	},
+}
+
+// instagramPlatform posts nothing: Instagram has no API to share links from
+// personal accounts, so the user gets a caption kit to post by hand.
+var instagramPlatform = &sharePlatform{
+	name:         "instagram",
+	title:        "Instagram",
+	capabilities: PlatformCapabilities{MaxLength: maxInstagramCaption, Images: true, RequiresApproval: true},
+	verified:     func(user *models.User) *bool { return &user.InstagramVerified },
+	clear:        func(user *models.User) { user.Instagram = nil },
+	validate: func(share *Share) error {
+		if err := requireCaption(share); err != nil {
+			return err
+		}
+		if share.CardImage == "" {
+			return fmt.Errorf("an image for the card: %w", apperrors.ErrInvalidInput)
+		}
+		return nil
+	},
+	post: makeInstagramKit,
+}
+
+// webhookPlatform posts the share to an endpoint of the user's, as signed
+// JSON for their own automations.
+var webhookPlatform = &sharePlatform{
+	name:         "webhook",
+	title:        "Webhook",
+	capabilities: PlatformCapabilities{Images: true},
+	verified:     func(user *models.User) *bool { return &user.ShareWebhookVerified },
+	clear:        func(user *models.User) { user.ShareWebhook = nil },
+	validate:     requireTitle,
+	post:         postShareWebhook,
+}
+
+// youtubePlatform posts nothing either: the YouTube Data API can't create
+// community posts, so the user gets the post's text to publish by hand.
+var youtubePlatform = &sharePlatform{
+	name:         "youtube",
+	title:        "YouTube",
+	capabilities: PlatformCapabilities{RequiresApproval: true},
+	verified:     func(user *models.User) *bool { return &user.YouTubeVerified },
+	clear:        func(user *models.User) { user.YouTube = nil },
+	validate:     requireCaption,
+	post:         makeYouTubeKit,
+}
+
+// platformRegistry holds the platforms blogs are shared to, in the order
+// they are listed to users.
+var platformRegistry = newPlatformRegistry(
+	twitterPlatform,
+	linkedinPlatform,
+	mastodonPlatform,
+	redditPlatform,
+	discordPlatform,
+	devtoPlatform,
+	mediumPlatform,
+	nostrPlatform,
+	lemmyPlatform,
+	wordpressPlatform,
+	matrixPlatform,
+	tumblrPlatform,
+	teamsPlatform,
+	newsletterPlatform,
+	substackPlatform,
+	googleBusinessPlatform,
+	instagramPlatform,
+	webhookPlatform,
+	youtubePlatform,
+)
+
+type registry struct {
+	byName map[string]Platform
+	order  []Platform
+}
+
+func newPlatformRegistry(platforms ...Platform) *registry {
+	r := &registry{byName: map[string]Platform{}}
+	for _, platform := range platforms {
+		r.register(platform)
+	}
+	return r
+}
+
+// register adds a platform. Names are unique and must be share platforms
+// of the models, which validate scheduled shares with them.
+func (r *registry) register(platform Platform) {
+	if _, taken := r.byName[platform.Name()]; taken {
+		panic("platform " + platform.Name() + " is registered twice")
+	}
+	if !models.IsSharePlatform(platform.Name()) {
+		panic("platform " + platform.Name() + " is not a share platform of the models")
+	}
+	r.byName[platform.Name()] = platform
+	r.order = append(r.order, platform)
+}
+
+// LookupPlatform returns the platform registered under name.
+func LookupPlatform(name string) (Platform, bool) {
+	platform, ok := platformRegistry.byName[name]
+	return platform, ok
+}
+
+// Platforms returns every registered platform.
+func Platforms() []Platform {
+	return append([]Platform(nil), platformRegistry.order...)
+}
+
+// PlatformTitle is the name users know the platform by, or its id for an
+// unknown platform.
+func PlatformTitle(name string) string {
+	if platform, ok := LookupPlatform(name); ok {
+		return platform.Title()
+	}
+	return name
+}
+
+// CheckPlatformEnabled fails when operators turned the platform's connector
+// off in the config, giving their reason.
+func CheckPlatformEnabled(name string) error {
+	reason, disabled := config.Get().PlatformDisabled(name)
+	if !disabled {
+		return nil
+	}
+	if reason == "" {
+		return fmt.Errorf("sharing to %s is turned off for now: %w", PlatformTitle(name), apperrors.ErrForbidden)
+	}
+	return fmt.Errorf("sharing to %s is turned off for now (%s): %w", PlatformTitle(name), reason, apperrors.ErrForbidden)
+}
+
+// DisabledPlatforms maps the registered platforms whose connectors are
+// turned off to the reason why, or is nil when none are.
+func DisabledPlatforms() map[string]string {
+	var disabled map[string]string
+	for _, platform := range platformRegistry.order {
+		if reason, ok := config.Get().PlatformDisabled(platform.Name()); ok {
+			if disabled == nil {
+				disabled = map[string]string{}
+			}
+			disabled[platform.Name()] = reason
+		}
+	}
+	return disabled
+}
+
+// RefreshVerified updates whether the user may share: they need their
+// Hashnode blog, Ghost site, Medium profile or a feed, and at least one
+// platform connected.
+func RefreshVerified(user *models.User) {
+	connected := false
+	for _, platform := range platformRegistry.order {
+		if platform.Connected(user) {
+			connected = true
+			break
+		}
+	}
+	user.Verified = connected && (user.HashnodeVerified || user.Ghost != nil || user.Feed != nil || user.MediumSource != nil)
+}
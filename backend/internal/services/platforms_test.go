@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/config"
+	"social-scribe/backend/internal/models"
+)
+
+func TestPlatformConnections(t *testing.T) {
+	user := &models.User{HashnodeVerified: true, PlaintextXOAuthToken: "token", PlaintextXOAuthSecret: "secret", Tumblr: &models.TumblrAccount{Blog: "ada"}}
+	twitter, _ := LookupPlatform("twitter")
+	tumblr, _ := LookupPlatform("tumblr")
+	twitter.Connect(user)
+	tumblr.Connect(user)
+	if !user.Verified || !user.XVerified || !tumblr.Connected(user) {
+		t.Fatalf("connected %+v", user)
+	}
+
+	twitter.Disconnect(user)
+	if user.PlaintextXOAuthToken != "" || user.PlaintextXOAuthSecret != "" || user.XVerified || !user.Verified {
+		t.Errorf("disconnecting X left %+v", user)
+	}
+	tumblr.Disconnect(user)
+	if user.Tumblr != nil || user.Verified {
+		t.Errorf("disconnecting the last platform left %+v", user)
+	}
+
+	if IsValidPlatform("myspace") || !IsValidPlatform("teams") || PlatformTitle("devto") != "DEV" {
+		t.Error("the registry doesn't match the platforms")
+	}
+}
+
+func TestPlatformValidateContent(t *testing.T) {
+	share := &Share{Title: "Scheduling posts", URL: "https://blog.example.com/scheduling", HTML: "<p>Hello</p>"}
+	for name, valid := range map[string]bool{
+		"reddit":    true,
+		"wordpress": true,
+		"twitter":   false,
+		"devto":     false,
+	} {
+		platform, _ := LookupPlatform(name)
+		err := platform.ValidateContent(share)
+		if valid && err != nil {
+			t.Errorf("%s refused the share: %v", name, err)
+		}
+		if !valid && !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Errorf("%s took a share it can't post: %v", name, err)
+		}
+	}
+}
+
+func TestDisabledPlatforms(t *testing.T) {
+	if err := CheckPlatformEnabled("twitter"); err != nil || DisabledPlatforms() != nil {
+		t.Fatalf("without a config twitter gave %v, disabled %v", err, DisabledPlatforms())
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"disabled_platforms": {"twitter": "Our X keys are suspended", "myspace": ""}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	if err := config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Setenv("CONFIG_FILE", "")
+		config.Reload()
+	}()
+
+	err := CheckPlatformEnabled("twitter")
+	if !errors.Is(err, apperrors.ErrForbidden) || err.Error() != "sharing to X (Twitter) is turned off for now (Our X keys are suspended): forbidden" {
+		t.Errorf("a disabled platform gave %v", err)
+	}
+	if err := CheckPlatformEnabled("linkedin"); err != nil {
+		t.Errorf("an enabled platform gave %v", err)
+	}
+	// Unknown platforms in the config aren't reported
+	if disabled := DisabledPlatforms(); len(disabled) != 1 || disabled["twitter"] != "Our X keys are suspended" {
+		t.Errorf("DisabledPlatforms() = %v", disabled)
+	}
+}
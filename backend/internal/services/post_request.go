@@ -2,9 +2,12 @@ package services
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
 )
 
 func MakePostRequest(url string, body []byte, headers map[string]string) ([]byte, error) {
@@ -17,13 +20,16 @@ func MakePostRequest(url string, body []byte, headers map[string]string) ([]byte
 		request.Header.Set(key, value)
 	}
 
-	client := &http.Client{}
+	client := getProviderClient(ProviderHashnode)
 	response, err := client.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute HTTP request: %v", err)
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("request to %s was throttled: %w", url, apperrors.ErrProviderRateLimited)
+	}
 	if response.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(response.Body)
 		return nil, fmt.Errorf("GraphQL query failed with status code %d: %s", response.StatusCode, string(body))
@@ -31,3 +37,102 @@ func MakePostRequest(url string, body []byte, headers map[string]string) ([]byte
 
 	return ioutil.ReadAll(response.Body)
 }
+
+// FetchPublicationPosts lists the posts of the Hashnode publication at host.
+func FetchPublicationPosts(host string) ([]models.PostNode, error) {
+	query := models.GraphQLQuery{
+		Query: fmt.Sprintf(`
+                query Publication {
+                    publication(host: "%s") {
+                        posts(first: 0) {
+                            edges {
+                                node {
+                                    title
+                                    url
+                                    id
+                                    coverImage { url }
+                                    author { name }
+                                    readTimeInMinutes
+                                }
+                            }
+                        }
+                    }
+                }`, host),
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	gqlResponse, err := MakePostRequest("https://gql.hashnode.com", queryBytes, headers)
+	if err != nil {
+		return nil, err
+	}
+	var gqlData models.GraphQLResponse
+	if err := json.Unmarshal(gqlResponse, &gqlData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	var posts []models.PostNode
+	for _, edge := range gqlData.Data.Publication.Posts.Edges {
+		posts = append(posts, edge.Node)
+	}
+	return posts, nil
+}
+
+// FetchPublicationPostsPage lists up to first posts of the Hashnode
+// publication at host, starting after the cursor of an earlier page. The
+// returned page info tells whether more posts follow.
+func FetchPublicationPostsPage(host string, first int, after string) ([]models.PostNode, models.PageInfo, error) {
+	var pageInfo models.PageInfo
+	afterArg := ""
+	if after != "" {
+		afterArg = fmt.Sprintf(", after: %q", after)
+	}
+	query := models.GraphQLQuery{
+		Query: fmt.Sprintf(`
+                query PublicationPosts {
+                    publication(host: %q) {
+                        posts(first: %d%s) {
+                            edges {
+                                node {
+                                    title
+                                    url
+                                    id
+                                    coverImage { url }
+                                    author { name }
+                                    readTimeInMinutes
+                                    publishedAt
+                                    brief
+                                }
+                            }
+                            pageInfo {
+                                hasNextPage
+                                endCursor
+                            }
+                        }
+                    }
+                }`, host, first, afterArg),
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, pageInfo, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	gqlResponse, err := MakePostRequest("https://gql.hashnode.com", queryBytes, headers)
+	if err != nil {
+		return nil, pageInfo, err
+	}
+	var gqlData models.GraphQLResponse
+	if err := json.Unmarshal(gqlResponse, &gqlData); err != nil {
+		return nil, pageInfo, fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	var posts []models.PostNode
+	for _, edge := range gqlData.Data.Publication.Posts.Edges {
+		posts = append(posts, edge.Node)
+	}
+	return posts, gqlData.Data.Publication.Posts.PageInfo, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+const maxArchivedBodySize = 8 * 1024
+
+// providerArchiveSize is the number of responses kept per user and platform,
+// configured via PROVIDER_ARCHIVE_SIZE. Archiving is disabled when it is 0.
+func providerArchiveSize() int {
+	size, err := strconv.Atoi(os.Getenv("PROVIDER_ARCHIVE_SIZE"))
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// archiveProviderResponse stores a redacted copy of a provider response for
+// later debugging. Failures are logged and never affect the caller.
+func archiveProviderResponse(userId, platform, endpoint string, statusCode int, body []byte) {
+	keep := providerArchiveSize()
+	if keep == 0 || userId == "" {
+		return
+	}
+
+	response := models.ProviderResponse{
+		UserID:     userId,
+		Platform:   platform,
+		Endpoint:   endpoint,
+		StatusCode: statusCode,
+		Body:       utils.RedactBody(body, maxArchivedBodySize),
+		CreatedAt:  utils.Now(),
+	}
+	if err := repositories.ArchiveProviderResponse(response, keep); err != nil {
+		log.Printf("[WARN] Failed to archive %s response for the user %s: %v", platform, userId, err)
+	}
+}
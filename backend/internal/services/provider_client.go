@@ -0,0 +1,172 @@
+package services
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Providers with a client of their own. The clients share one transport and
+// differ in how long a call may take.
+const (
+	ProviderHashnode       = "hashnode"
+	ProviderAI             = "ai"
+	ProviderLinkedIn       = "linkedin"
+	ProviderTwitter        = "twitter"
+	ProviderMastodon       = "mastodon"
+	ProviderReddit         = "reddit"
+	ProviderDiscord        = "discord"
+	ProviderDevto          = "devto"
+	ProviderMedium         = "medium"
+	ProviderNostr          = "nostr"
+	ProviderLemmy          = "lemmy"
+	ProviderWordPress      = "wordpress"
+	ProviderMatrix         = "matrix"
+	ProviderTumblr         = "tumblr"
+	ProviderTeams          = "teams"
+	ProviderButtondown     = "buttondown"
+	ProviderMailchimp      = "mailchimp"
+	ProviderSubstack       = "substack"
+	ProviderGoogleBusiness = "google_business"
+	ProviderYouTube        = "youtube"
+	ProviderGhost          = "ghost"
+	// ProviderWeb is any other site fetched for a user, such as the host of
+	// an inline image.
+	ProviderWeb = "web"
+)
+
+var providerTimeouts = map[string]time.Duration{
+	ProviderHashnode:       30 * time.Second,
+	ProviderAI:             60 * time.Second,
+	ProviderLinkedIn:       30 * time.Second,
+	ProviderTwitter:        30 * time.Second,
+	ProviderMastodon:       20 * time.Second,
+	ProviderReddit:         30 * time.Second,
+	ProviderDiscord:        15 * time.Second,
+	ProviderDevto:          30 * time.Second,
+	ProviderMedium:         30 * time.Second,
+	ProviderNostr:          15 * time.Second,
+	ProviderLemmy:          20 * time.Second,
+	ProviderWordPress:      60 * time.Second,
+	ProviderMatrix:         15 * time.Second,
+	ProviderTumblr:         30 * time.Second,
+	ProviderTeams:          15 * time.Second,
+	ProviderButtondown:     30 * time.Second,
+	ProviderMailchimp:      30 * time.Second,
+	ProviderSubstack:       30 * time.Second,
+	ProviderGoogleBusiness: 30 * time.Second,
+	ProviderYouTube:        30 * time.Second,
+	ProviderGhost:          30 * time.Second,
+	ProviderWeb:            15 * time.Second,
+}
+
+// outboundTransport makes every outbound call. Keeping one transport keeps
+// connections to each provider alive between calls, so a burst of posts
+// doesn't pay a TCP and TLS handshake per request.
+var outboundTransport = newOutboundTransport()
+
+func newOutboundTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		// A custom dialer turns HTTP/2 off unless asked for
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          256,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// newGuardedTransport is an outbound transport that refuses to connect to
+// addresses check rejects. The address checked is the one dialled, after DNS
+// resolution, so a host can't pass a check by name and then resolve to an
+// internal address for the connection. It uses no proxy, since the proxy
+// would be dialled instead.
+func newGuardedTransport(check func(host string) error) *http.Transport {
+	transport := newOutboundTransport()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return check(host)
+		},
+	}).DialContext
+	return transport
+}
+
+// userServers is how the servers users name, such as their Mastodon instance
+// or WordPress site, are reached. Tests make their own to reach local servers.
+type userServers struct {
+	// scheme is that of the calls: https, but for tests, whose servers don't
+	// speak TLS.
+	scheme string
+	// checkHost fails for the hosts that mustn't be called.
+	checkHost func(host string) error
+}
+
+// publicServers calls hosts resolving to public addresses over https.
+var publicServers = userServers{scheme: "https", checkHost: checkPublicHost}
+
+var (
+	providerClientMu sync.RWMutex
+	// providerTransport carries the provider calls. Benchmarks and tests
+	// swap it for fake connectors.
+	providerTransport http.RoundTripper = outboundTransport
+	providerClients                     = newProviderClients(outboundTransport)
+)
+
+func newProviderClients(transport http.RoundTripper) map[string]*http.Client {
+	clients := make(map[string]*http.Client, len(providerTimeouts))
+	for provider, timeout := range providerTimeouts {
+		clients[provider] = &http.Client{Transport: transport, Timeout: timeout}
+	}
+	return clients
+}
+
+func getProviderClient(provider string) *http.Client {
+	providerClientMu.RLock()
+	defer providerClientMu.RUnlock()
+	if client, ok := providerClients[provider]; ok {
+		return client
+	}
+	return providerClients[ProviderWeb]
+}
+
+func getProviderTransport() http.RoundTripper {
+	providerClientMu.RLock()
+	defer providerClientMu.RUnlock()
+	return providerTransport
+}
+
+// ProviderClient returns the client for calls to provider made outside this
+// package, such as the OAuth token exchanges.
+func ProviderClient(provider string) *http.Client {
+	return getProviderClient(provider)
+}
+
+// SetProviderTransport replaces the transport used for provider calls,
+// returning the previous one so callers can restore it. A nil transport
+// means the shared outbound transport.
+func SetProviderTransport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = outboundTransport
+	}
+	providerClientMu.Lock()
+	defer providerClientMu.Unlock()
+	previous := providerTransport
+	providerTransport = transport
+	providerClients = newProviderClients(transport)
+	return previous
+}
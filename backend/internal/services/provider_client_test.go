@@ -0,0 +1,109 @@
+package services
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// countConnections serves 200s and counts the connections clients opened.
+func countConnections(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	var opened atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &opened
+}
+
+// localServers reaches the plain http servers of tests on any host.
+func localServers() userServers {
+	return userServers{scheme: "http", checkHost: func(string) error { return nil }}
+}
+
+func drain(t testing.TB, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestProviderClientsShareConnections(t *testing.T) {
+	server, opened := countConnections(t)
+	for i := 0; i < 10; i++ {
+		drain(t, getProviderClient(ProviderLinkedIn), server.URL)
+		drain(t, getProviderClient(ProviderTwitter), server.URL)
+	}
+	if n := opened.Load(); n != 1 {
+		t.Errorf("20 sequential calls opened %d connections, want 1", n)
+	}
+}
+
+func TestSetProviderTransport(t *testing.T) {
+	var calls atomic.Int64
+	fake := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	previous := SetProviderTransport(fake)
+	defer SetProviderTransport(previous)
+
+	for provider := range providerTimeouts {
+		drain(t, getProviderClient(provider), "https://provider.invalid/")
+	}
+	drain(t, getProviderClient("unknown"), "https://provider.invalid/")
+	if n := calls.Load(); n != int64(len(providerTimeouts))+1 {
+		t.Errorf("fake transport got %d calls, want %d", n, len(providerTimeouts)+1)
+	}
+	for provider, timeout := range providerTimeouts {
+		if timeout <= 0 || getProviderClient(provider).Timeout != timeout {
+			t.Errorf("the %s client has a timeout of %v, want %v", provider, getProviderClient(provider).Timeout, timeout)
+		}
+	}
+	if getProviderClient("unknown").Timeout <= 0 {
+		t.Error("the client of unknown providers has no timeout")
+	}
+
+	SetProviderTransport(nil)
+	if getProviderTransport() != outboundTransport {
+		t.Error("a nil transport didn't restore the shared one")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// BenchmarkBurstPooled and BenchmarkBurstFreshClient compare a burst of calls
+// through the shared transport against paying for a new connection per call.
+func BenchmarkBurstPooled(b *testing.B) {
+	server, _ := countConnections(b)
+	client := getProviderClient(ProviderLinkedIn)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		drain(b, client, server.URL)
+	}
+}
+
+func BenchmarkBurstFreshClient(b *testing.B) {
+	server, _ := countConnections(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transport := newOutboundTransport()
+		drain(b, &http.Client{Transport: transport}, server.URL)
+		transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,37 @@
+//go:build e2e
+
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// UseProviderStub sends every provider call to the stub server at rawURL
+// instead, with the original host as the first path segment:
+// https://api.linkedin.com/v2/ugcPosts becomes <rawURL>/api.linkedin.com/v2/ugcPosts.
+// End-to-end runs use it to point a real binary at recorded fakes. It is only
+// built with the e2e tag, so a production binary can't be pointed at a stub.
+func UseProviderStub(rawURL string) error {
+	base, err := url.Parse(rawURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return fmt.Errorf("invalid provider stub URL %q", rawURL)
+	}
+	SetProviderTransport(&stubTransport{base: base})
+	return nil
+}
+
+type stubTransport struct {
+	base *url.URL
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	stubbed := req.Clone(req.Context())
+	stubbed.URL.Scheme = t.base.Scheme
+	stubbed.URL.Host = t.base.Host
+	stubbed.URL.Path = t.base.Path + "/" + req.URL.Host + req.URL.Path
+	stubbed.URL.RawPath = ""
+	stubbed.Host = t.base.Host
+	return outboundTransport.RoundTrip(stubbed)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueueExportBuffer(t *testing.T) {
+	export := "\ufeffText,Link,Profile,Scheduled At\n" +
+		"New post,https://blog.example.com/perf?utm_source=buffer,Twitter,2026-10-20 09:30\n" +
+		"New post,https://blog.example.com/perf,LinkedIn Page,2026-10-20 09:30\n" +
+		"Old post,https://blog.example.com/old,Pinterest,2026-10-21 10:00\n" +
+		"Broken,https://blog.example.com/broken,Twitter,next tuesday\n"
+	rows, rowErrors, err := ParseQueueExport(strings.NewReader(export), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseQueueExport: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("%d rows, want 2", len(rows))
+	}
+	want := time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC)
+	if !rows[0].ScheduledTime.Equal(want) || rows[0].Platforms[0] != "twitter" || rows[1].Platforms[0] != "linkedin" {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+	if NormalizePostURL(rows[0].Link) != NormalizePostURL("http://www.blog.example.com/perf/") {
+		t.Errorf("links don't normalize alike: %q", rows[0].Link)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 4 || rowErrors[1].Line != 5 {
+		t.Errorf("unexpected row errors: %+v", rowErrors)
+	}
+}
+
+func TestParseQueueExportHootsuite(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+	export := "20/10/2026 09:30,Read this https://blog.example.com/perf,\n"
+	rows, rowErrors, err := ParseQueueExport(strings.NewReader(export), berlin)
+	if err != nil || len(rowErrors) != 0 || len(rows) != 1 {
+		t.Fatalf("got %+v, %+v, %v", rows, rowErrors, err)
+	}
+	if rows[0].Link != "https://blog.example.com/perf" {
+		t.Errorf("link %q not taken from the message", rows[0].Link)
+	}
+	if want := time.Date(2026, 10, 20, 7, 30, 0, 0, time.UTC); !rows[0].ScheduledTime.Equal(want) {
+		t.Errorf("scheduled at %s, want %s", rows[0].ScheduledTime, want)
+	}
+}
+
+func TestParseQueueExportClockChanges(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+	// Berlin springs forward from 02:00 to 03:00 on 29 March 2026 and falls
+	// back from 03:00 to 02:00 on 25 October 2026
+	export := "29/03/2026 02:30,Skipped https://blog.example.com/a,\n" +
+		"25/10/2026 02:30,Twice https://blog.example.com/b,\n" +
+		"2026-10-25T02:30:00+01:00,With its offset https://blog.example.com/c,\n" +
+		"25/10/2026 03:30,After https://blog.example.com/d,\n"
+	rows, rowErrors, err := ParseQueueExport(strings.NewReader(export), berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrors) != 2 || !strings.Contains(rowErrors[0].Reason, "skipped") || !strings.Contains(rowErrors[1].Reason, "twice") {
+		t.Fatalf("row errors = %+v", rowErrors)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if want := time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC); !rows[0].ScheduledTime.Equal(want) {
+		t.Errorf("scheduled at %s, want %s", rows[0].ScheduledTime, want)
+	}
+	if want := time.Date(2026, 10, 25, 2, 30, 0, 0, time.UTC); !rows[1].ScheduledTime.Equal(want) {
+		t.Errorf("scheduled at %s, want %s", rows[1].ScheduledTime, want)
+	}
+
+	if _, rowErrors, _ := ParseQueueExport(strings.NewReader(export), time.UTC); len(rowErrors) != 0 {
+		t.Errorf("UTC has no clock changes, got %+v", rowErrors)
+	}
+}
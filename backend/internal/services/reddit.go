@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// RedditScopes are what SocialScribe asks Reddit for: the username, link
+// submission and the flairs of the chosen subreddit.
+var RedditScopes = []string{"identity", "submit", "flair"}
+
+// RedditEndpoint is Reddit's OAuth endpoint. Reddit wants the client
+// credentials as basic auth.
+var RedditEndpoint = oauth2.Endpoint{
+	AuthURL:   "https://www.reddit.com/api/v1/authorize",
+	TokenURL:  "https://www.reddit.com/api/v1/access_token",
+	AuthStyle: oauth2.AuthStyleInHeader,
+}
+
+// redditUserAgent identifies SocialScribe as Reddit's API rules ask; generic
+// agents are throttled hard.
+const redditUserAgent = "web:social-scribe:v1 (cross-posting for Hashnode bloggers)"
+
+// maxRedditTitle is the longest title Reddit takes on a post.
+const maxRedditTitle = 300
+
+var (
+	redditConfig = &oauth2.Config{}
+	// redditAPI is replaced by tests.
+	redditAPI = "https://oauth.reddit.com"
+)
+
+var (
+	redditLimitMu sync.Mutex
+	// redditLimitedUntil is when the used-up rate limit of a user resets.
+	redditLimitedUntil = map[string]time.Time{}
+)
+
+var subredditPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]{1,20}$`)
+
+func InitRedditConfig(config *oauth2.Config) {
+	redditConfig = config
+}
+
+// redditTransport sets the User-Agent on every call to Reddit.
+type redditTransport struct{}
+
+func (redditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", redditUserAgent)
+	return getProviderTransport().RoundTrip(req)
+}
+
+func redditClient() *http.Client {
+	return &http.Client{Transport: redditTransport{}, Timeout: providerTimeouts[ProviderReddit]}
+}
+
+// RedditContext carries the client to use for Reddit's token endpoint.
+func RedditContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, redditClient())
+}
+
+// NormalizeSubreddit reduces "r/golang" or "/r/golang/" to "golang".
+func NormalizeSubreddit(raw string) (string, error) {
+	name := strings.Trim(strings.TrimSpace(raw), "/")
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "r/"), "R/")
+	if !subredditPattern.MatchString(name) {
+		return "", fmt.Errorf("%q is not a subreddit name: %w", raw, apperrors.ErrInvalidInput)
+	}
+	return name, nil
+}
+
+// SetRedditTokens seals the user's Reddit tokens. The caller saves the user.
+func SetRedditTokens(user *models.User, accessToken, refreshToken string, expiry time.Time) error {
+	account := &user.Reddit
+	if err := SealCredential(user, &account.SealedAccessToken, &account.PlaintextAccessToken, accessToken); err != nil {
+		return err
+	}
+	if err := SealCredential(user, &account.SealedRefreshToken, &account.PlaintextRefreshToken, refreshToken); err != nil {
+		return err
+	}
+	account.TokenExpiry = expiry
+	return nil
+}
+
+// redditToken returns a live access token of the user, refreshing and
+// saving it when it has run out.
+func redditToken(user *models.User) (string, error) {
+	account := &user.Reddit
+	accessToken, err := OpenCredential(user, account.SealedAccessToken, account.PlaintextAccessToken)
+	if err != nil {
+		return "", err
+	}
+	refreshToken, err := OpenCredential(user, account.SealedRefreshToken, account.PlaintextRefreshToken)
+	if err != nil {
+		return "", err
+	}
+	if refreshToken == "" && accessToken == "" {
+		return "", fmt.Errorf("Reddit is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	current := &oauth2.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Expiry:       account.TokenExpiry,
+	}
+	token, err := redditConfig.TokenSource(RedditContext(context.Background()), current).Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh the Reddit token: %w", apperrors.ErrProviderAuth)
+	}
+	if token.AccessToken != accessToken {
+		if token.RefreshToken != "" {
+			refreshToken = token.RefreshToken
+		}
+		if err := SetRedditTokens(user, token.AccessToken, refreshToken, token.Expiry); err != nil {
+			return "", err
+		}
+		if err := repositories.UpdateUser(user.Id.Hex(), user); err != nil {
+			log.Printf("[WARN] Failed to save the refreshed Reddit token of user %s: %v", user.Id.Hex(), err)
+		}
+	}
+	return token.AccessToken, nil
+}
+
+// redditRateLimit records the rate limit Reddit reported for the user, who
+// has no calls left once remaining drops below one.
+func redditRateLimit(userId string, header http.Header, limited bool) {
+	remaining, err := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining"), 64)
+	if !limited && (err != nil || remaining >= 1) {
+		return
+	}
+	reset, err := strconv.Atoi(header.Get("X-Ratelimit-Reset"))
+	if err != nil || reset <= 0 {
+		reset = 60
+	}
+	redditLimitMu.Lock()
+	redditLimitedUntil[userId] = utils.Now().Add(time.Duration(reset) * time.Second)
+	redditLimitMu.Unlock()
+	log.Printf("[WARN] Reddit rate limit of user %s is used up for %ds", userId, reset)
+}
+
+// redditLimited returns when the user's used-up rate limit resets, or the
+// zero time if they have calls left.
+func redditLimited(userId string) time.Time {
+	redditLimitMu.Lock()
+	defer redditLimitMu.Unlock()
+	until, ok := redditLimitedUntil[userId]
+	if ok && !utils.Now().Before(until) {
+		delete(redditLimitedUntil, userId)
+		return time.Time{}
+	}
+	return until
+}
+
+// redditCall sends an authenticated request to Reddit's API and decodes the
+// JSON response into out. Reddit reports the calls left in the current
+// period; once they are used up, calls fail as rate limited without being
+// sent until the period ends.
+func redditCall(userId, accessToken string, req *http.Request, out interface{}) error {
+	if until := redditLimited(userId); !until.IsZero() {
+		return fmt.Errorf("Reddit rate limit resets in %v: %w", until.Sub(utils.Now()).Round(time.Second), apperrors.ErrProviderRateLimited)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := redditClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	archiveProviderResponse(userId, "reddit", req.URL.String(), resp.StatusCode, body)
+	redditRateLimit(userId, resp.Header, resp.StatusCode == http.StatusTooManyRequests)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("Reddit asks to retry in %ss: %w", resp.Header.Get("X-Ratelimit-Reset"), apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("Reddit refused the request with %s: %w", resp.Status, apperrors.ErrProviderAuth)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Reddit returned %s: %s", resp.Status, string(body))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// RedditUsername looks up the name of the account an access token belongs
+// to.
+func RedditUsername(userId, accessToken string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, redditAPI+"/api/v1/me", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	var me struct {
+		Name string `json:"name"`
+	}
+	if err := redditCall(userId, accessToken, req, &me); err != nil {
+		return "", fmt.Errorf("failed to look up the Reddit account: %w", err)
+	}
+	return me.Name, nil
+}
+
+// RedditFlair is a link flair users can pick in a subreddit.
+type RedditFlair struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Editable bool   `json:"text_editable"`
+}
+
+// RedditFlairs lists the link flairs of the user's subreddit.
+func RedditFlairs(user *models.User, subreddit string) ([]RedditFlair, error) {
+	accessToken, err := redditToken(user)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, redditAPI+"/r/"+url.PathEscape(subreddit)+"/api/link_flair_v2", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	flairs := []RedditFlair{}
+	if err := redditCall(user.Id.Hex(), accessToken, req, &flairs); err != nil {
+		// Reddit refuses subreddits without user-picked flairs
+		if errors.Is(err, apperrors.ErrProviderAuth) {
+			return nil, fmt.Errorf("r/%s doesn't let you pick a flair: %w", subreddit, apperrors.ErrForbidden)
+		}
+		return nil, fmt.Errorf("failed to list the flairs of r/%s: %w", subreddit, err)
+	}
+	return flairs, nil
+}
+
+// submitRedditLink posts the blog link to the user's subreddit and returns
+// the post's URL.
+func submitRedditLink(user *models.User, title, link string) (string, error) {
+	account := user.Reddit
+	if account.Subreddit == "" {
+		return "", fmt.Errorf("no subreddit is chosen for Reddit: %w", apperrors.ErrInvalidInput)
+	}
+	accessToken, err := redditToken(user)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"api_type": {"json"},
+		"kind":     {"link"},
+		"sr":       {account.Subreddit},
+		"title":    {truncateRunes(title, maxRedditTitle)},
+		"url":      {link},
+		"resubmit": {"true"},
+	}
+	if account.FlairID != "" {
+		form.Set("flair_id", account.FlairID)
+		if account.FlairText != "" {
+			form.Set("flair_text", account.FlairText)
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, redditAPI+"/api/submit", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var submitted struct {
+		JSON struct {
+			// Errors are [code, message, field] triples
+			Errors [][]interface{} `json:"errors"`
+			Data   struct {
+				URL string `json:"url"`
+			} `json:"data"`
+		} `json:"json"`
+	}
+	if err := redditCall(user.Id.Hex(), accessToken, req, &submitted); err != nil {
+		return "", fmt.Errorf("failed to submit to r/%s: %w", account.Subreddit, err)
+	}
+	if len(submitted.JSON.Errors) > 0 {
+		return "", redditSubmitError(account.Subreddit, submitted.JSON.Errors[0])
+	}
+	return submitted.JSON.Data.URL, nil
+}
+
+// redditSubmitError turns the first error Reddit gave for a submission into
+// a domain error.
+func redditSubmitError(subreddit string, triple []interface{}) error {
+	var code, message string
+	if len(triple) > 0 {
+		code, _ = triple[0].(string)
+	}
+	if len(triple) > 1 {
+		message, _ = triple[1].(string)
+	}
+	switch code {
+	case "RATELIMIT":
+		return fmt.Errorf("r/%s: %s: %w", subreddit, message, apperrors.ErrProviderRateLimited)
+	case "SUBREDDIT_NOEXIST", "SUBREDDIT_NOTALLOWED", "SUBMIT_VALIDATION_FLAIR_REQUIRED", "NO_LINKS", "BAD_FLAIR_TARGET", "ALREADY_SUB":
+		return fmt.Errorf("r/%s: %s: %w", subreddit, message, apperrors.ErrInvalidInput)
+	}
+	return fmt.Errorf("r/%s refused the post: %s %s", subreddit, code, message)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeSubreddit(t *testing.T) {
+	for raw, want := range map[string]string{
+		"golang":                   "golang",
+		" r/golang ":               "golang",
+		"/r/Golang/":               "Golang",
+		"learn_go":                 "learn_go",
+		"r/":                       "",
+		"_golang":                  "",
+		"go lang":                  "",
+		"golang/hot":               "",
+		"a":                        "",
+		"averyveryverylongsubname": "",
+	} {
+		got, err := NormalizeSubreddit(raw)
+		if want == "" {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("NormalizeSubreddit(%q) = %q, %v; want invalid input", raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizeSubreddit(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+}
+
+func TestSubmitRedditLink(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/submit" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("User-Agent") != redditUserAgent {
+			http.Error(w, `{"error": 401}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.FormValue("sr") {
+		case "golang":
+			if r.FormValue("kind") != "link" || r.FormValue("flair_id") != "flair-1" || r.FormValue("flair_text") != "Tutorial" {
+				w.Write([]byte(`{"json": {"errors": [["BAD_FLAIR_TARGET", "bad flair", "flair"]]}}`))
+				return
+			}
+			w.Write([]byte(`{"json": {"errors": [], "data": {"url": "https://www.reddit.com/r/golang/comments/abc/"}}}`))
+		case "busy":
+			w.Write([]byte(`{"json": {"errors": [["RATELIMIT", "you are doing that too much", "ratelimit"]]}}`))
+		default:
+			w.Write([]byte(`{"json": {"errors": [["SUBREDDIT_NOEXIST", "that subreddit doesn't exist", "sr"]]}}`))
+		}
+	}))
+	defer server.Close()
+	previous := redditAPI
+	redditAPI = server.URL
+	defer func() { redditAPI = previous }()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	user.Reddit = models.RedditAccount{
+		Subreddit: "golang",
+		FlairID:   "flair-1",
+		FlairText: "Tutorial",
+	}
+	if err := SetRedditTokens(user, "token-1", "", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	postURL, err := submitRedditLink(user, "Scheduling posts", "https://blog.example.com/scheduling")
+	if err != nil || postURL != "https://www.reddit.com/r/golang/comments/abc/" {
+		t.Errorf("submitted to %q, %v", postURL, err)
+	}
+
+	user.Reddit.Subreddit = "nosuchsub"
+	if _, err := submitRedditLink(user, "Scheduling posts", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("missing subreddit: %v, want invalid input", err)
+	}
+	user.Reddit.Subreddit = "busy"
+	if _, err := submitRedditLink(user, "Scheduling posts", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrProviderRateLimited) {
+		t.Errorf("RATELIMIT: %v, want rate limited", err)
+	}
+	user.Reddit.Subreddit = ""
+	if _, err := submitRedditLink(user, "Scheduling posts", "https://blog.example.com/scheduling"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("no subreddit: %v, want invalid input", err)
+	}
+}
+
+func TestRedditRateLimitShortCircuits(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Ratelimit-Remaining", "0")
+		w.Header().Set("X-Ratelimit-Reset", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	previous := redditAPI
+	redditAPI = server.URL
+	defer func() { redditAPI = previous }()
+
+	userId := primitive.NewObjectID().Hex()
+	defer func() {
+		redditLimitMu.Lock()
+		delete(redditLimitedUntil, userId)
+		redditLimitMu.Unlock()
+	}()
+	for i := 0; i < 3; i++ {
+		if _, err := RedditUsername(userId, "token-1"); !errors.Is(err, apperrors.ErrProviderRateLimited) {
+			t.Fatalf("call %d: %v, want rate limited", i, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Reddit was called %d times while the limit was used up, want 1", calls)
+	}
+	if until := redditLimited(userId); until.IsZero() {
+		t.Error("the used-up limit was not recorded")
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+// RetentionResult counts what enforcing a retention policy removed.
+type RetentionResult struct {
+	Notifications int `json:"notifications"`
+	SharedBlogs   int `json:"shared_blogs"`
+}
+
+// ApplyRetention drops the notifications and share history the user's
+// retention policy no longer keeps. Notifications from before their times
+// were recorded are stamped with now, so they expire a full period after the
+// policy first sees them. It reports whether the user changed.
+func ApplyRetention(user *models.User, now time.Time) (RetentionResult, bool) {
+	var result RetentionResult
+	policy := user.Preferences.Retention
+	changed := false
+
+	if policy.NotificationDays > 0 {
+		if extra := len(user.NotificationTimes) - len(user.Notifications); extra > 0 {
+			user.NotificationTimes = user.NotificationTimes[extra:]
+			changed = true
+		}
+		if untimed := len(user.Notifications) - len(user.NotificationTimes); untimed > 0 {
+			stamped := make([]time.Time, untimed, len(user.Notifications))
+			for i := range stamped {
+				stamped[i] = now
+			}
+			user.NotificationTimes = append(stamped, user.NotificationTimes...)
+			changed = true
+		}
+		cutoff := now.AddDate(0, 0, -policy.NotificationDays)
+		notifications := make([]string, 0, len(user.Notifications))
+		times := make([]time.Time, 0, len(user.NotificationTimes))
+		for i, notifiedAt := range user.NotificationTimes {
+			if notifiedAt.Before(cutoff) {
+				result.Notifications++
+				continue
+			}
+			notifications = append(notifications, user.Notifications[i])
+			times = append(times, notifiedAt)
+		}
+		if result.Notifications > 0 {
+			user.Notifications = notifications
+			user.NotificationTimes = times
+			changed = true
+		}
+	}
+
+	if policy.ShareHistoryMonths > 0 {
+		cutoff := now.AddDate(0, -policy.ShareHistoryMonths, 0)
+		kept := make([]models.SharedBlog, 0, len(user.SharedBlogs))
+		for _, blog := range user.SharedBlogs {
+			// History with an unreadable time is kept rather than guessed at
+			sharedAt, err := time.Parse(time.RFC3339, blog.SharedTime)
+			if err == nil && sharedAt.Before(cutoff) {
+				result.SharedBlogs++
+				continue
+			}
+			kept = append(kept, blog)
+		}
+		if result.SharedBlogs > 0 {
+			user.SharedBlogs = kept
+			changed = true
+		}
+	}
+	return result, changed
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	user := &models.User{
+		Notifications: []string{"legacy", "old", "recent"},
+		// The legacy notification predates recorded times
+		NotificationTimes: []time.Time{now.Add(-40 * day), now.Add(-2 * day)},
+		SharedBlogs: []models.SharedBlog{
+			{Blog: models.Blog{Id: "a"}, SharedTime: now.AddDate(0, -7, 0).Format(time.RFC3339)},
+			{Blog: models.Blog{Id: "b"}, SharedTime: "not a time"},
+			{Blog: models.Blog{Id: "c"}, SharedTime: now.AddDate(0, -1, 0).Format(time.RFC3339)},
+		},
+		Preferences: models.Preferences{Retention: models.RetentionPolicy{NotificationDays: 30, ShareHistoryMonths: 6}},
+	}
+
+	result, changed := ApplyRetention(user, now)
+	if !changed {
+		t.Fatal("expected the user to change")
+	}
+	if result.Notifications != 1 || result.SharedBlogs != 1 {
+		t.Errorf("removed %+v, want 1 notification and 1 shared blog", result)
+	}
+	if got := user.Notifications; len(got) != 2 || got[0] != "legacy" || got[1] != "recent" {
+		t.Errorf("notifications = %q, want legacy and recent", got)
+	}
+	if len(user.NotificationTimes) != 2 || !user.NotificationTimes[0].Equal(now) {
+		t.Errorf("notification times = %v, want the legacy one stamped with now", user.NotificationTimes)
+	}
+	if len(user.SharedBlogs) != 2 || user.SharedBlogs[0].Id != "b" || user.SharedBlogs[1].Id != "c" {
+		t.Errorf("shared blogs = %+v, want b and c", user.SharedBlogs)
+	}
+
+	// Running again within the period removes nothing more
+	if _, changed := ApplyRetention(user, now.Add(day)); changed {
+		t.Error("expected a second run to leave the user alone")
+	}
+	// The legacy notification expires a full period after it was stamped
+	result, _ = ApplyRetention(user, now.Add(31*day))
+	if result.Notifications != 2 || len(user.Notifications) != 0 {
+		t.Errorf("removed %d notifications, left %q", result.Notifications, user.Notifications)
+	}
+}
+
+func TestApplyRetentionWithoutPolicy(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	user := &models.User{
+		Notifications: []string{"legacy"},
+		SharedBlogs:   []models.SharedBlog{{SharedTime: now.AddDate(-5, 0, 0).Format(time.RFC3339)}},
+	}
+	if _, changed := ApplyRetention(user, now); changed {
+		t.Error("expected no change without a policy")
+	}
+	if len(user.NotificationTimes) != 0 {
+		t.Error("expected untimed notifications to stay untimed")
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits a search query into lower-case words.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// MatchesSearch reports whether every term appears in at least one of the
+// fields, ignoring case. Terms match word prefixes, so "sched" finds
+// "Scheduling".
+func MatchesSearch(terms []string, fields ...string) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	words := map[string]bool{}
+	for _, field := range fields {
+		for _, word := range SearchTerms(field) {
+			words[word] = true
+		}
+	}
+	for _, term := range terms {
+		found := false
+		for word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
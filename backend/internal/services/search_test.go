@@ -0,0 +1,35 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	got := SearchTerms("  Go-routines, Mongo's TEXT index! ")
+	want := []string{"go", "routines", "mongo", "s", "text", "index"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SearchTerms = %q, want %q", got, want)
+	}
+}
+
+func TestMatchesSearch(t *testing.T) {
+	title := "Scheduling posts with Go"
+	caption := "New on the blog: how we queue shares across time zones"
+	cases := []struct {
+		query string
+		want  bool
+	}{
+		{"sched", true},
+		{"GO queue", true},
+		{"queue rust", false},
+		{"zones scheduling", true},
+		{"hare", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := MatchesSearch(SearchTerms(tc.query), title, caption); got != tc.want {
+			t.Errorf("MatchesSearch(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+// SecurityWebhookSignatureHeader carries the signature of a delivery in the
+// same "t=<unix millis>,v1=<hex hmac-sha256 of "<t>.<payload>">" form Hashnode
+// uses, so receivers can verify it the same way.
+const SecurityWebhookSignatureHeader = "X-SocialScribe-Signature"
+
+// SecurityWebhookSecretPrefix starts every security webhook signing secret.
+const SecurityWebhookSecretPrefix = "sswhsec_"
+
+const securityWebhookAttempts = 3
+
+var (
+	securityWebhookClient = &http.Client{
+		Transport: outboundTransport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	// checkWebhookHost keeps deliveries off internal addresses. Tests
+	// replace it to reach local servers.
+	checkWebhookHost = checkPublicHost
+	// securityWebhookRetryDelay is the wait before the second attempt,
+	// doubled before each later one.
+	securityWebhookRetryDelay = 5 * time.Second
+)
+
+// ValidateSecurityWebhookURL checks that a webhook URL is https and points at
+// a public host.
+func ValidateSecurityWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" || parsed.User != nil {
+		return fmt.Errorf("webhook URL must be an https URL: %w", apperrors.ErrInvalidInput)
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return fmt.Errorf("webhook URL must point at a public host: %w", apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// SignSecurityWebhook returns the signature header value of a delivery sent
+// at the given time.
+func SignSecurityWebhook(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func NewSecurityEvent(user *models.User, eventType, ip, userAgent string, details map[string]string) models.SecurityEvent {
+	return models.SecurityEvent{
+		Id:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     user.Id.Hex(),
+		OccurredAt: utils.Now(),
+		IP:         ip,
+		UserAgent:  userAgent,
+		Details:    details,
+	}
+}
+
+// NotifySecurityEvent sends the event to the user's security webhook in the
+// background, retrying failed deliveries. It does nothing when the user has
+// no webhook or it did not subscribe to the event type.
+func NotifySecurityEvent(user *models.User, eventType, ip, userAgent string, details map[string]string) {
+	if user == nil || user.SecurityWebhook == nil || !user.SecurityWebhook.Wants(eventType) {
+		return
+	}
+	webhook := *user.SecurityWebhook
+	secret, err := OpenCredential(user, webhook.SealedSecret, webhook.PlaintextSecret)
+	if err != nil {
+		log.Printf("[WARN] Failed to open the security webhook secret of user %s: %v", user.Id.Hex(), err)
+		return
+	}
+	event := NewSecurityEvent(user, eventType, ip, userAgent, details)
+	go func() {
+		delay := securityWebhookRetryDelay
+		for attempt := 1; ; attempt++ {
+			status, err := DeliverSecurityEvent(&webhook, secret, event)
+			if err == nil {
+				return
+			}
+			if attempt == securityWebhookAttempts || (status >= 400 && status < 500 && status != http.StatusTooManyRequests) {
+				log.Printf("[WARN] Giving up delivering security event %s of user %s after %d attempts: %v", event.Id, event.UserID, attempt, err)
+				return
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}()
+}
+
+// DeliverSecurityEvent posts the event to the webhook once, signed with its
+// opened secret, and returns the response status. Any status other than 2xx
+// is an error.
+func DeliverSecurityEvent(webhook *models.SecurityWebhook, secret string, event models.SecurityEvent) (int, error) {
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SocialScribe-Webhook/1.0")
+	req.Header.Set("X-SocialScribe-Event", event.Type)
+	req.Header.Set(SecurityWebhookSignatureHeader, SignSecurityWebhook(payload, secret, utils.Now()))
+
+	resp, err := securityWebhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// SessionJWTClaims are the claims of a session token issued in JWT mode.
+type SessionJWTClaims struct {
+	Subject string `json:"sub"`
+	// IssuedAt is fractional, to the millisecond, so tokens issued within
+	// the second a user was signed out everywhere can be told apart. Tokens
+	// issued before that have whole seconds.
+	IssuedAt  float64 `json:"iat"`
+	ExpiresAt int64   `json:"exp"`
+	ID        string  `json:"jti"`
+}
+
+// IssuedTime returns the time the token was issued.
+func (c SessionJWTClaims) IssuedTime() time.Time {
+	return time.UnixMilli(int64(math.Round(c.IssuedAt * 1000)))
+}
+
+type sessionJWTHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type sessionJWTKey struct {
+	id     string
+	secret []byte
+}
+
+// sessionJWTKeys reads SESSION_JWT_SECRETS, a comma separated list whose
+// first secret signs new tokens. Older secrets stay listed after a rotation
+// so tokens they signed keep verifying until they expire.
+func sessionJWTKeys() []sessionJWTKey {
+	var keys []sessionJWTKey
+	for _, secret := range strings.Split(os.Getenv("SESSION_JWT_SECRETS"), ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(secret))
+		keys = append(keys, sessionJWTKey{id: hex.EncodeToString(sum[:4]), secret: []byte(secret)})
+	}
+	return keys
+}
+
+// IsSessionJWT tells session JWTs apart from opaque session tokens, which are
+// UUIDs.
+func IsSessionJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// IssueSessionJWT signs a session token for the user that expires after ttl,
+// returning it with its id.
+func IssueSessionJWT(userID string, ttl time.Duration) (token, jti string, err error) {
+	keys := sessionJWTKeys()
+	if len(keys) == 0 {
+		return "", "", fmt.Errorf("SESSION_JWT_SECRETS is not set")
+	}
+	now := utils.Now()
+	header, err := json.Marshal(sessionJWTHeader{Alg: "HS256", Typ: "JWT", Kid: keys[0].id})
+	if err != nil {
+		return "", "", err
+	}
+	jti = uuid.New().String()
+	claims, err := json.Marshal(SessionJWTClaims{
+		Subject:   userID,
+		IssuedAt:  float64(now.UnixMilli()) / 1000,
+		ExpiresAt: now.Add(ttl).Unix(),
+		ID:        jti,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signingInput + "." + signSessionJWT(keys[0].secret, signingInput), jti, nil
+}
+
+func signSessionJWT(secret []byte, signingInput string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseSessionJWT checks the signature and expiry of a session token. It
+// doesn't consult revocations; use VerifySessionJWT to authenticate.
+func ParseSessionJWT(token string) (*SessionJWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed session token: %w", apperrors.ErrUnauthorized)
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed session token header: %w", apperrors.ErrUnauthorized)
+	}
+	var header sessionJWTHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported session token header: %w", apperrors.ErrUnauthorized)
+	}
+
+	var key *sessionJWTKey
+	keys := sessionJWTKeys()
+	for i := range keys {
+		if keys[i].id == header.Kid {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("session token signed with an unknown key: %w", apperrors.ErrUnauthorized)
+	}
+	expected := signSessionJWT(key.secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, fmt.Errorf("invalid session token signature: %w", apperrors.ErrUnauthorized)
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed session token claims: %w", apperrors.ErrUnauthorized)
+	}
+	var claims SessionJWTClaims
+	if err := json.Unmarshal(rawClaims, &claims); err != nil || claims.Subject == "" {
+		return nil, fmt.Errorf("malformed session token claims: %w", apperrors.ErrUnauthorized)
+	}
+	if !utils.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("session token expired: %w", apperrors.ErrUnauthorized)
+	}
+	return &claims, nil
+}
+
+// VerifySessionJWT authenticates a session token and returns the user id it
+// was issued to. Tokens ended by logout, or issued before the user was signed
+// out everywhere, are rejected, as are all tokens while revocations can't be
+// checked.
+func VerifySessionJWT(token string) (string, error) {
+	claims, err := ParseSessionJWT(token)
+	if err != nil {
+		return "", err
+	}
+	revoked, err := repositories.IsSessionJWTRevoked(claims.Subject, claims.ID, claims.IssuedTime())
+	if err != nil {
+		// Fail closed: a token that may have been revoked isn't accepted
+		log.Printf("[ERROR] Failed to check revocations of session token %s: %v", claims.ID, err)
+		return "", fmt.Errorf("checking session token revocations: %w", apperrors.ErrUnauthorized)
+	}
+	if revoked {
+		return "", fmt.Errorf("session token revoked: %w", apperrors.ErrUnauthorized)
+	}
+	return claims.Subject, nil
+}
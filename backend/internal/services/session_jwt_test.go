@@ -0,0 +1,213 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// useTestDB points the repositories, where session revocations are kept, at
+// a throwaway database on the MongoDB named by MONGO_TEST_URI, skipping the
+// test when it isn't set, and returns its name.
+func useTestDB(t *testing.T) string {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	dbName := fmt.Sprintf("social-scribe-test-%d", time.Now().UnixNano())
+	if err := repositories.Connect(uri, dbName); err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { repositories.Disconnect(dbName) })
+	return dbName
+}
+
+func useFakeClock(t *testing.T) *utils.FakeClock {
+	t.Helper()
+	clock := utils.NewFakeClock(time.Date(2026, 5, 1, 12, 0, 0, 300*int(time.Millisecond), time.UTC))
+	previous := utils.SetClock(clock)
+	t.Cleanup(func() { utils.SetClock(previous) })
+	return clock
+}
+
+func issueSessionJWT(t *testing.T, userID string) (string, string) {
+	t.Helper()
+	token, jti, err := IssueSessionJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, jti
+}
+
+func wantUnauthorized(t *testing.T, err error, what string) {
+	t.Helper()
+	if !errors.Is(err, apperrors.ErrUnauthorized) {
+		t.Errorf("%s: error = %v, want unauthorized", what, err)
+	}
+}
+
+func TestSessionJWTRoundTrip(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	useTestDB(t)
+	clock := useFakeClock(t)
+	userID := primitive.NewObjectID().Hex()
+	token, jti := issueSessionJWT(t, userID)
+
+	if !IsSessionJWT(token) {
+		t.Fatalf("IsSessionJWT(%q) = false", token)
+	}
+	claims, err := ParseSessionJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != userID || claims.ID != jti {
+		t.Errorf("claims = %+v, want subject %s and id %s", claims, userID, jti)
+	}
+	if !claims.IssuedTime().Equal(clock.Now()) {
+		t.Errorf("issued at %v, want %v", claims.IssuedTime(), clock.Now())
+	}
+	if got, err := VerifySessionJWT(token); err != nil || got != userID {
+		t.Errorf("VerifySessionJWT = %q, %v, want %q", got, err, userID)
+	}
+}
+
+func TestParseSessionJWTRejectsTampering(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	useFakeClock(t)
+	token, _ := issueSessionJWT(t, primitive.NewObjectID().Hex())
+	parts := strings.Split(token, ".")
+
+	otherClaims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"someone-else","iat":0,"exp":9999999999,"jti":"x"}`))
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	for name, tampered := range map[string]string{
+		"claims":    parts[0] + "." + otherClaims + "." + parts[2],
+		"signature": parts[0] + "." + parts[1] + "." + parts[2][1:] + "A",
+		"algorithm": noneHeader + "." + parts[1] + ".",
+		"shape":     parts[0] + "." + parts[1],
+	} {
+		_, err := ParseSessionJWT(tampered)
+		wantUnauthorized(t, err, name)
+	}
+}
+
+func TestParseSessionJWTExpiry(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	clock := useFakeClock(t)
+	token, _ := issueSessionJWT(t, primitive.NewObjectID().Hex())
+
+	clock.Advance(59 * time.Minute)
+	if _, err := ParseSessionJWT(token); err != nil {
+		t.Fatalf("before expiry: %v", err)
+	}
+	clock.Advance(time.Minute)
+	_, err := ParseSessionJWT(token)
+	wantUnauthorized(t, err, "at expiry")
+}
+
+func TestSessionJWTKeyRotation(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	useTestDB(t)
+	useFakeClock(t)
+	userID := primitive.NewObjectID().Hex()
+	old, _ := issueSessionJWT(t, userID)
+
+	// The new secret signs; the old one still verifies what it signed
+	t.Setenv("SESSION_JWT_SECRETS", "second, first")
+	if _, err := VerifySessionJWT(old); err != nil {
+		t.Fatalf("token signed with the previous secret: %v", err)
+	}
+	current, _ := issueSessionJWT(t, userID)
+	if _, err := VerifySessionJWT(current); err != nil {
+		t.Fatalf("token signed with the new secret: %v", err)
+	}
+
+	// Once the old secret is dropped, only its tokens stop verifying
+	t.Setenv("SESSION_JWT_SECRETS", "second")
+	_, err := VerifySessionJWT(old)
+	wantUnauthorized(t, err, "token signed with a dropped secret")
+	if _, err := VerifySessionJWT(current); err != nil {
+		t.Errorf("token signed with the remaining secret: %v", err)
+	}
+}
+
+func TestSessionJWTRevocation(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	useTestDB(t)
+	clock := useFakeClock(t)
+	user := primitive.NewObjectID()
+	loggedOut, loggedOutID := issueSessionJWT(t, user.Hex())
+	other, _ := issueSessionJWT(t, user.Hex())
+
+	if err := repositories.RevokeSessionJWT(loggedOutID, clock.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := VerifySessionJWT(loggedOut)
+	wantUnauthorized(t, err, "logged out token")
+	if _, err := VerifySessionJWT(other); err != nil {
+		t.Fatalf("token of another session: %v", err)
+	}
+
+	// Signing out everywhere rejects the tokens issued until then, even
+	// within the same second, but not the one the caller is issued after
+	clock.Advance(100 * time.Millisecond)
+	sameSecond, _ := issueSessionJWT(t, user.Hex())
+	clock.Advance(100 * time.Millisecond)
+	if err := repositories.DeleteOtherUserSessions(user, ""); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(100 * time.Millisecond)
+	reissued, _ := issueSessionJWT(t, user.Hex())
+
+	_, err = VerifySessionJWT(other)
+	wantUnauthorized(t, err, "token issued before signing out everywhere")
+	_, err = VerifySessionJWT(sameSecond)
+	wantUnauthorized(t, err, "token issued earlier in the same second")
+	if _, err := VerifySessionJWT(reissued); err != nil {
+		t.Errorf("token reissued after signing out everywhere: %v", err)
+	}
+}
+
+func TestSessionJWTLegacyRevocation(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	useTestDB(t)
+	clock := useFakeClock(t)
+	user := primitive.NewObjectID().Hex()
+	token, _ := issueSessionJWT(t, user)
+
+	// Revocations stored in whole seconds cover the rest of their second
+	if err := repositories.SetCache("sessions_revoked_"+user, clock.Now().Unix(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	_, err := VerifySessionJWT(token)
+	wantUnauthorized(t, err, "token issued within a legacy revocation's second")
+
+	clock.Advance(time.Second)
+	later, _ := issueSessionJWT(t, user)
+	if _, err := VerifySessionJWT(later); err != nil {
+		t.Errorf("token issued after a legacy revocation: %v", err)
+	}
+}
+
+func TestVerifySessionJWTFailsClosed(t *testing.T) {
+	t.Setenv("SESSION_JWT_SECRETS", "first")
+	dbName := useTestDB(t)
+	useFakeClock(t)
+	token, _ := issueSessionJWT(t, primitive.NewObjectID().Hex())
+
+	// Revocations can't be looked up once the database is gone
+	if err := repositories.Disconnect(dbName); err != nil {
+		t.Fatal(err)
+	}
+	_, err := VerifySessionJWT(token)
+	wantUnauthorized(t, err, "revocations unavailable")
+}
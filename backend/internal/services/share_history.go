@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+
+	"github.com/dghubble/oauth1"
+)
+
+const (
+	tweetHistoryCount    = 200
+	linkedInHistoryCount = 50
+)
+
+// historicalShare is an earlier post on a platform, with the links it carried.
+type historicalShare struct {
+	Links      []string
+	PostedAt   time.Time
+	Engagement models.ShareEngagement
+	// linkedInURN identifies LinkedIn posts, whose engagement is looked up
+	// separately once the post is known to link to the blog
+	linkedInURN string
+}
+
+// BackfillShareHistory adds the user's recent posts on platform that link to
+// their Hashnode blog to the shared-blog history, with their engagement. It
+// returns the number of blog posts whose history changed.
+func BackfillShareHistory(userId, platform string) (int, error) {
+	user, err := repositories.GetUserById(userId)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil || user.HashnodeBlog == "" {
+		return 0, fmt.Errorf("user %s has no Hashnode blog: %w", userId, apperrors.ErrInvalidInput)
+	}
+
+	var history []historicalShare
+	var linkedInKey string
+	switch platform {
+	case "twitter":
+		var twitter *oauth1.Config
+		var token *oauth1.Token
+		twitter, err = TwitterConfigFor(user)
+		if err == nil {
+			token, err = XToken(user)
+		}
+		if err == nil {
+			history, err = fetchTweetHistory(userId, twitter, token)
+		}
+	case "linkedin":
+		if linkedInKey, err = LinkedInToken(user); err == nil {
+			history, err = fetchLinkedInHistory(userId, linkedInKey)
+		}
+	default:
+		return 0, fmt.Errorf("invalid platform %q: %w", platform, apperrors.ErrInvalidInput)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	postsByURL := map[string]models.PostNode{}
+	for _, host := range PublicationHosts(user) {
+		posts, err := FetchPublicationPosts(host)
+		if err != nil {
+			return 0, err
+		}
+		for _, post := range posts {
+			postsByURL[NormalizePostURL(post.URL)] = post
+		}
+	}
+
+	// Several posts may link to the same blog post; their engagement adds up
+	// and the earliest counts as when it was shared
+	matched := map[string]*models.SharedBlog{}
+	var order []string
+	for _, share := range history {
+		for _, link := range share.Links {
+			post, ok := postsByURL[NormalizePostURL(link)]
+			if !ok {
+				continue
+			}
+			if platform == "linkedin" {
+				share.Engagement = fetchLinkedInEngagement(userId, linkedInKey, share.linkedInURN)
+			}
+			blog, ok := matched[post.ID]
+			if !ok {
+				blog = &models.SharedBlog{
+					Blog: models.Blog{
+						Id:                post.ID,
+						Title:             post.Title,
+						Url:               post.URL,
+						CoverImage:        models.Image{URL: post.CoverImage.URL},
+						Author:            post.Author,
+						ReadTimeInMinutes: post.ReadTimeInMinutes,
+					},
+					Platforms:          []string{platform},
+					SharedTime:         share.PostedAt.UTC().Format(time.RFC3339),
+					Imported:           true,
+					Engagement:         &models.ShareEngagement{},
+					PlatformEngagement: map[string]models.ShareEngagement{},
+				}
+				matched[post.ID] = blog
+				order = append(order, post.ID)
+			}
+			if postedAt := share.PostedAt.UTC().Format(time.RFC3339); postedAt < blog.SharedTime {
+				blog.SharedTime = postedAt
+			}
+			addEngagement(blog.Engagement, share.Engagement)
+			platformEngagement := blog.PlatformEngagement[platform]
+			addEngagement(&platformEngagement, share.Engagement)
+			blog.PlatformEngagement[platform] = platformEngagement
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return 0, nil
+	}
+
+	// Reload so shares recorded while the history was fetched aren't lost
+	user, err = repositories.GetUserById(userId)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, fmt.Errorf("user %s not found: %w", userId, apperrors.ErrNotFound)
+	}
+	for _, postID := range order {
+		imported := matched[postID]
+		merged := false
+		for i := range user.SharedBlogs {
+			existing := &user.SharedBlogs[i]
+			if existing.Id != postID {
+				continue
+			}
+			merged = true
+			if !containsPlatform(existing.Platforms, platform) {
+				existing.Platforms = append(existing.Platforms, platform)
+			}
+			if existing.Engagement == nil {
+				existing.Engagement = &models.ShareEngagement{}
+			}
+			addEngagement(existing.Engagement, *imported.Engagement)
+			// The history holds the post's current engagement on the platform
+			if existing.PlatformEngagement == nil {
+				existing.PlatformEngagement = map[string]models.ShareEngagement{}
+			}
+			existing.PlatformEngagement[platform] = imported.PlatformEngagement[platform]
+			break
+		}
+		if !merged {
+			user.SharedBlogs = append(user.SharedBlogs, *imported)
+		}
+	}
+	sort.SliceStable(user.SharedBlogs, func(i, j int) bool {
+		return user.SharedBlogs[i].SharedTime < user.SharedBlogs[j].SharedTime
+	})
+	if err := repositories.UpdateUser(userId, user); err != nil {
+		return 0, fmt.Errorf("failed to update user with imported shares: %w", err)
+	}
+	return len(matched), nil
+}
+
+func addEngagement(total *models.ShareEngagement, add models.ShareEngagement) {
+	total.Likes += add.Likes
+	total.Reposts += add.Reposts
+	total.Comments += add.Comments
+}
+
+func containsPlatform(platforms []string, platform string) bool {
+	for _, p := range platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchTweetHistory lists the user's recent original tweets.
+func fetchTweetHistory(userId string, config *oauth1.Config, userToken *oauth1.Token) ([]historicalShare, error) {
+	ctx := context.WithValue(oauth1.NoContext, oauth1.HTTPClient, getProviderClient(ProviderTwitter))
+	client := config.Client(ctx, userToken)
+
+	timelineURL := fmt.Sprintf("https://api.twitter.com/1.1/statuses/user_timeline.json?count=%d&include_rts=false&exclude_replies=true&tweet_mode=extended", tweetHistoryCount)
+	resp, err := client.Get(timelineURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tweets: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	archiveProviderResponse(userId, "twitter", timelineURL, resp.StatusCode, body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("failed to fetch tweets: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch tweets, status code: %d", resp.StatusCode)
+	}
+
+	var tweets []struct {
+		CreatedAt string `json:"created_at"`
+		Entities  struct {
+			URLs []struct {
+				ExpandedURL string `json:"expanded_url"`
+			} `json:"urls"`
+		} `json:"entities"`
+		FavoriteCount int `json:"favorite_count"`
+		RetweetCount  int `json:"retweet_count"`
+		ReplyCount    int `json:"reply_count"`
+	}
+	if err := json.Unmarshal(body, &tweets); err != nil {
+		return nil, fmt.Errorf("failed to parse tweets: %v", err)
+	}
+
+	history := make([]historicalShare, 0, len(tweets))
+	for _, tweet := range tweets {
+		postedAt, err := time.Parse(time.RubyDate, tweet.CreatedAt)
+		if err != nil {
+			continue
+		}
+		share := historicalShare{
+			PostedAt: postedAt,
+			Engagement: models.ShareEngagement{
+				Likes:    tweet.FavoriteCount,
+				Reposts:  tweet.RetweetCount,
+				Comments: tweet.ReplyCount,
+			},
+		}
+		for _, link := range tweet.Entities.URLs {
+			share.Links = append(share.Links, link.ExpandedURL)
+		}
+		history = append(history, share)
+	}
+	return history, nil
+}
+
+// fetchLinkedInHistory lists the member's recent LinkedIn posts.
+func fetchLinkedInHistory(userId, accessToken string) ([]historicalShare, error) {
+	userURN, err := getUserURN(userId, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user ID: %w", err)
+	}
+
+	postsURL := fmt.Sprintf("https://api.linkedin.com/v2/ugcPosts?q=authors&authors=List(%s)&count=%d", url.QueryEscape(userURN), linkedInHistoryCount)
+	body, err := linkedInGet(userId, accessToken, postsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch LinkedIn posts: %w", err)
+	}
+
+	var response struct {
+		Elements []struct {
+			ID      string `json:"id"`
+			Created struct {
+				Time int64 `json:"time"`
+			} `json:"created"`
+			SpecificContent struct {
+				ShareContent struct {
+					ShareCommentary struct {
+						Text string `json:"text"`
+					} `json:"shareCommentary"`
+					Media []struct {
+						OriginalURL string `json:"originalUrl"`
+					} `json:"media"`
+				} `json:"com.linkedin.ugc.ShareContent"`
+			} `json:"specificContent"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse LinkedIn posts: %v", err)
+	}
+
+	history := make([]historicalShare, 0, len(response.Elements))
+	for _, element := range response.Elements {
+		content := element.SpecificContent.ShareContent
+		share := historicalShare{
+			PostedAt:    time.UnixMilli(element.Created.Time),
+			Links:       linkInText.FindAllString(content.ShareCommentary.Text, -1),
+			linkedInURN: element.ID,
+		}
+		for _, media := range content.Media {
+			share.Links = append(share.Links, media.OriginalURL)
+		}
+		history = append(history, share)
+	}
+	return history, nil
+}
+
+// fetchLinkedInEngagement looks up the reactions to a LinkedIn post. Missing
+// engagement doesn't stop the import, so errors leave it empty.
+func fetchLinkedInEngagement(userId, accessToken, postURN string) models.ShareEngagement {
+	var engagement models.ShareEngagement
+	body, err := linkedInGet(userId, accessToken, "https://api.linkedin.com/v2/socialActions/"+url.PathEscape(postURN))
+	if err != nil {
+		return engagement
+	}
+	var response struct {
+		LikesSummary struct {
+			TotalLikes int `json:"totalLikes"`
+		} `json:"likesSummary"`
+		CommentsSummary struct {
+			AggregatedTotalComments int `json:"aggregatedTotalComments"`
+		} `json:"commentsSummary"`
+	}
+	if json.Unmarshal(body, &response) == nil {
+		engagement.Likes = response.LikesSummary.TotalLikes
+		engagement.Comments = response.CommentsSummary.AggregatedTotalComments
+	}
+	return engagement
+}
+
+func linkedInGet(userId, accessToken, requestURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("X-Restli-Protocol-Version", "2.0.0")
+
+	resp, err := getProviderClient(ProviderLinkedIn).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	archiveProviderResponse(userId, "linkedin", req.URL.String(), resp.StatusCode, body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("LinkedIn throttled the request: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("LinkedIn access token was rejected: %w", apperrors.ErrProviderAuth)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
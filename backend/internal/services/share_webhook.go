@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/utils"
+)
+
+// ShareWebhookSecretPrefix starts every share webhook signing secret.
+const ShareWebhookSecretPrefix = "ssshare_"
+
+// Share webhook event types, sent in the X-SocialScribe-Event header.
+const (
+	ShareWebhookEventShared = "blog.shared"
+	ShareWebhookEventPing   = "ping"
+)
+
+var shareWebhookClient = &http.Client{
+	Transport: outboundTransport,
+	Timeout:   15 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ShareWebhookEvent is the payload of a share webhook delivery. Pings carry
+// no blog.
+type ShareWebhookEvent struct {
+	Id         string            `json:"id"`
+	Type       string            `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Blog       *ShareWebhookBlog `json:"blog,omitempty"`
+	// Caption is the text written for the share.
+	Caption string `json:"caption,omitempty"`
+}
+
+type ShareWebhookBlog struct {
+	Id         string   `json:"id"`
+	Title      string   `json:"title"`
+	URL        string   `json:"url"`
+	Brief      string   `json:"brief,omitempty"`
+	Author     string   `json:"author,omitempty"`
+	ReadTime   int      `json:"read_time_minutes,omitempty"`
+	CoverImage string   `json:"cover_image,omitempty"`
+	CardImage  string   `json:"card_image,omitempty"`
+	Tags       []string `json:"tags"`
+}
+
+// NewShareWebhookEvent is the delivery of a share. The blog's URL carries
+// the campaign tag of the "webhook" platform.
+func NewShareWebhookEvent(share *Share) ShareWebhookEvent {
+	tags := share.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ShareWebhookEvent{
+		Id:         uuid.New().String(),
+		Type:       ShareWebhookEventShared,
+		OccurredAt: utils.Now(),
+		Blog: &ShareWebhookBlog{
+			Id:         share.BlogID,
+			Title:      share.Title,
+			URL:        share.Link("webhook"),
+			Brief:      share.Brief,
+			Author:     share.Author,
+			ReadTime:   share.ReadTime,
+			CoverImage: share.CoverImage,
+			CardImage:  share.CardImage,
+			Tags:       tags,
+		},
+		Caption: share.Caption,
+	}
+}
+
+// NewShareWebhookPing is the delivery testing a webhook.
+func NewShareWebhookPing() ShareWebhookEvent {
+	return ShareWebhookEvent{Id: uuid.New().String(), Type: ShareWebhookEventPing, OccurredAt: utils.Now()}
+}
+
+// DeliverShareWebhook posts the event to the user's share webhook, signed
+// the way security webhook deliveries are, and returns the response status.
+// Any status other than 2xx is an error. A receiver may answer with
+// {"url": ...} naming where it posted the blog, which is returned.
+func DeliverShareWebhook(user *models.User, event ShareWebhookEvent) (int, string, error) {
+	webhook := user.ShareWebhook
+	if webhook == nil {
+		return 0, "", fmt.Errorf("no share webhook is registered: %w", apperrors.ErrInvalidInput)
+	}
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return 0, "", err
+	}
+	secret, err := OpenUserSecret(user, webhook.SealedSecret)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open the share webhook secret: %v", err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to marshal the delivery: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SocialScribe-Webhook/1.0")
+	req.Header.Set("X-SocialScribe-Event", event.Type)
+	req.Header.Set("X-SocialScribe-Delivery", event.Id)
+	req.Header.Set(SecurityWebhookSignatureHeader, SignSecurityWebhook(payload, secret, utils.Now()))
+
+	resp, err := shareWebhookClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to reach the webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return resp.StatusCode, "", fmt.Errorf("the webhook throttled the delivery: %w", apperrors.ErrProviderRateLimited)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, "", fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	var answer struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(body, &answer)
+	if parsed, err := url.Parse(answer.URL); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		answer.URL = ""
+	}
+	return resp.StatusCode, answer.URL, nil
+}
+
+// postShareWebhook delivers the share to the user's webhook.
+func postShareWebhook(user *models.User, share *Share) (string, error) {
+	_, postURL, err := DeliverShareWebhook(user, NewShareWebhookEvent(share))
+	return postURL, err
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func TestPostShareWebhook(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	previous := checkWebhookHost
+	checkWebhookHost = func(string) error { return nil }
+	defer func() { checkWebhookHost = previous }()
+
+	const secret = ShareWebhookSecretPrefix + "test"
+	var received ShareWebhookEvent
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		if err := VerifyHashnodeSignature(payload, r.Header.Get(SecurityWebhookSignatureHeader), secret); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		if event := r.Header.Get("X-SocialScribe-Event"); event != ShareWebhookEventShared {
+			t.Errorf("event header = %q", event)
+		}
+		if err := json.Unmarshal(payload, &received); err != nil {
+			t.Errorf("payload: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"url": "https://automations.example.com/runs/7"}`))
+	}))
+	defer server.Close()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	sealed, err := SealUserSecret(user, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.ShareWebhook = &models.ShareWebhook{URL: server.URL, SealedSecret: sealed}
+	share := &Share{BlogID: "blog-1", Title: "Queues", URL: "https://blog.example.com/queues", Caption: "On queues", Tags: []string{"go"}, CampaignTag: "launch"}
+
+	postURL, err := postShareWebhook(user, share)
+	if err != nil || postURL != "https://automations.example.com/runs/7" {
+		t.Fatalf("postShareWebhook = %q, %v", postURL, err)
+	}
+	if received.Blog == nil || received.Blog.Id != "blog-1" || received.Blog.Title != "Queues" || received.Caption != "On queues" || len(received.Blog.Tags) != 1 {
+		t.Fatalf("received %+v", received)
+	}
+	if received.Blog.URL != share.Link("webhook") {
+		t.Errorf("blog URL = %q, want %q", received.Blog.URL, share.Link("webhook"))
+	}
+
+	status = http.StatusBadGateway
+	if _, err := postShareWebhook(user, share); err == nil {
+		t.Error("a failed delivery was reported as posted")
+	}
+}
@@ -3,31 +3,212 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
-	"github.com/dghubble/oauth1"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/metrics"
 	"social-scribe/backend/internal/models"
 	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
 )
 
-func ProcessSharedBlog(user *models.User, blogId string, platforms []string) error {
+func IsValidPlatform(platform string) bool {
+	_, ok := LookupPlatform(platform)
+	return ok
+}
+
+func ProcessSharedBlog(user *models.User, blogId string, platforms []string, assetIDs []string, linkedInPage string, xThread bool) error {
+	_, err := ShareBlog(user, blogId, platforms, assetIDs, linkedInPage, xThread)
+	return err
+}
+
+// ShareBlog posts a caption for the blog on each platform and records the
+// share on the user. The blog is a Hashnode post or, for a Dev.to, Ghost or
+// feed blog id, one of the user's posts there. Team library assets given by
+// assetIDs are applied to the caption and card image, LinkedIn posts go to
+// linkedInPage as described on models.ScheduledBlog, and xThread posts to X
+// as a thread. The receipt lists where the posts went live.
+func ShareBlog(user *models.User, blogId string, platforms []string, assetIDs []string, linkedInPage string, xThread bool) (*models.DeliveryReceipt, error) {
 	userId := user.Id.Hex()
 
 	if !user.Verified {
-		return fmt.Errorf("user is not verified")
-	}
-	validPlatforms := map[string]bool{
-		"twitter":  true,
-		"linkedin": true,
+		return nil, fmt.Errorf("user is not verified: %w", apperrors.ErrForbidden)
 	}
 	if len(platforms) == 0 {
-		return fmt.Errorf("at least one platform must be specified")
+		return nil, fmt.Errorf("at least one platform must be specified: %w", apperrors.ErrInvalidInput)
 	}
 	for _, platform := range platforms {
-		if !validPlatforms[platform] {
-			return fmt.Errorf("invalid platform specified")
+		if !IsValidPlatform(platform) {
+			return nil, fmt.Errorf("invalid platform specified: %w", apperrors.ErrInvalidInput)
+		}
+		if err := CheckPlatformEnabled(platform); err != nil {
+			return nil, err
+		}
+	}
+	assets, err := ResolveShareAssets(user, assetIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateLinkedInPage(user, linkedInPage); err != nil {
+		return nil, err
+	}
+	post, err := fetchSourcePost(user, blogId)
+	if err != nil {
+		return nil, err
+	}
+	const maxContentLength = 150
+	content := post.Text
+	if len(content) > maxContentLength {
+		content = content[:maxContentLength] + "..."
+	}
+	prompt := fmt.Sprintf(
+		"Write a post that i can post it in linkedin and twitter (X) for this blog:\n\n"+
+			"Title: %s\n"+
+			"Subtitle: %s\n"+
+			"Brief: %s\n"+
+			"Content snippet: %s\n\n"+
+			"Note: The tone should be human, engaging, and conversational. Encourage readers to click the blog link for more details. Avoid sounding robotic or generic. Mention the blog’s key takeaway and invite readers to check it out and make sure it was short enough and dont be too verbose as twitter and linkedin has character limit on how much we can tweet or post so please keep it short and also make sure to generate single post that can be used for both linkedin and twitter rather seperately and dont use any wild card characters like * and without commentary.",
+		post.Title,
+		post.Subtitle,
+		post.Brief,
+		content,
+	)
+	aiResponse, err := invokeAi(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate post content: %w", err)
+	}
+	cardImage := SelectCardImage(post.CoverImage, post.SocialImage, post.HTML, post.Markdown)
+	aiResponse, cardImage = ApplyLibraryAssets(aiResponse, cardImage, assets)
+	var campaignTag string
+	campaign, err := repositories.GetCampaignForBlog(userId, blogId)
+	if err != nil {
+		log.Printf("[WARN] Sharing blog %s without its campaign tag: %v", blogId, err)
+	} else if campaign != nil {
+		campaignTag = campaign.UTMCampaign
+	}
+	receipt := &models.DeliveryReceipt{
+		BlogID:    post.Id,
+		BlogTitle: post.Title,
+		BlogURL:   post.Url,
+		Caption:   aiResponse,
+	}
+	share := &Share{
+		BlogID:       post.Id,
+		Title:        post.Title,
+		URL:          post.Url,
+		Brief:        post.Brief,
+		Caption:      aiResponse,
+		Author:       post.Author,
+		ReadTime:     post.ReadTime,
+		CoverImage:   post.CoverImage,
+		CardImage:    cardImage,
+		HTML:         post.HTML,
+		Markdown:     post.Markdown,
+		Tags:         post.Tags,
+		CampaignTag:  campaignTag,
+		LinkedInPage: linkedInPage,
+		XThread:      xThread,
+	}
+	recordIndex := -1
+	for i := range user.SharedBlogs {
+		if user.SharedBlogs[i].Id == post.Id {
+			recordIndex = i
+			break
+		}
+	}
+	// Platforms update a copy of the record, which is only saved once every
+	// platform posted
+	var record models.SharedBlog
+	if recordIndex >= 0 {
+		record = user.SharedBlogs[recordIndex]
+	} else {
+		record.Id = post.Id
+		record.Title = post.Title
+		record.Url = post.Url
+		record.CoverImage = models.Image{URL: cardImage}
+		record.Author = models.Author{Name: post.Author}
+		record.ReadTimeInMinutes = post.ReadTime
+	}
+	share.Record = &record
+
+	sharePlatforms := make([]Platform, len(platforms))
+	for i, name := range platforms {
+		sharePlatforms[i], _ = LookupPlatform(name)
+		if err := sharePlatforms[i].ValidateContent(share); err != nil {
+			return nil, err
+		}
+	}
+	plan, signupWeek := metrics.Cohort(user)
+	for _, platform := range sharePlatforms {
+		postURL, err := platform.Post(user, share)
+		if shares, err := metrics.Shares.GetMetricWithLabelValues(platform.Name(), metrics.Outcome(err), plan, signupWeek); err != nil {
+			log.Printf("[WARN] Failed to count the share: %v", err)
+		} else {
+			shares.Inc()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to post content to %s: %w", platform.Title(), err)
 		}
+		receipt.Deliveries = append(receipt.Deliveries, models.PlatformDelivery{Platform: platform.Name(), PostURL: postURL})
+	}
+	receipt.DeliveredAt = utils.Now()
+
+	record.SharedTime = utils.Now().Format(time.RFC3339)
+	record.Caption = aiResponse
+	if len(record.Topics) == 0 {
+		record.Topics = shareTopics(post)
+	}
+	if recordIndex >= 0 {
+		user.SharedBlogs[recordIndex] = record
+	} else {
+		user.SharedBlogs = append(user.SharedBlogs, record)
+	}
+	if err := repositories.UpdateUser(userId, user); err != nil {
+		return nil, fmt.Errorf("failed to update user with shared blog: %w", err)
+	}
+	return receipt, nil
+}
+
+// sourcePost is a blog as shares need it, from whichever source it lives
+// on.
+type sourcePost struct {
+	Id          string
+	Title       string
+	Url         string
+	CoverImage  string
+	SocialImage string
+	Author      string
+	ReadTime    int
+	Subtitle    string
+	Brief       string
+	Text        string
+	HTML        string
+	Markdown    string
+	Tags        []string
+}
+
+// fetchSourcePost fetches the blog to share: a Dev.to article of the user
+// for a Dev.to blog id, a post of their Ghost site for a Ghost blog id, a
+// story of their Medium profile for a Medium blog id, an item of their feed
+// for a feed blog id, and a Hashnode post otherwise.
+func fetchSourcePost(user *models.User, blogId string) (*sourcePost, error) {
+	if articleID, ok := DevtoArticleID(blogId); ok {
+		return fetchDevtoPost(user, articleID)
+	}
+	if postID, ok := GhostPostID(blogId); ok {
+		return fetchGhostPost(user, postID)
 	}
+	if storyID, ok := MediumStoryID(blogId); ok {
+		return fetchMediumPost(user, storyID)
+	}
+	if IsFeedBlogID(blogId) {
+		return fetchFeedPost(user, blogId)
+	}
+	return fetchHashnodePost(blogId)
+}
+
+func fetchHashnodePost(blogId string) (*sourcePost, error) {
 	query := models.GraphQLQuery{
 		Query: `query Post($id: ID!) {
             post(id: $id) {
@@ -45,6 +226,14 @@ func ProcessSharedBlog(user *models.User, blogId string, platforms []string) err
                 brief
                 content {
                     text
+                    html
+                    markdown
+                }
+                ogMetaData {
+                    image
+                }
+                tags {
+                    slug
                 }
             }
         }`,
@@ -54,13 +243,13 @@ func ProcessSharedBlog(user *models.User, blogId string, platforms []string) err
 	}
 	queryBytes, err := json.Marshal(query)
 	if err != nil {
-		return fmt.Errorf("failed to marshal query: %v", err)
+		return nil, fmt.Errorf("failed to marshal query: %v", err)
 	}
 	endpoint := "https://gql.hashnode.com"
 	headers := map[string]string{"Content-Type": "application/json"}
 	gqlResponse, err := MakePostRequest(endpoint, queryBytes, headers)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %v", err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	var response struct {
 		Data struct {
@@ -78,76 +267,40 @@ func ProcessSharedBlog(user *models.User, blogId string, platforms []string) err
 				SubTitle          string `json:"subtitle"`
 				Brief             string `json:"brief"`
 				Content           struct {
-					Text string `json:"text"`
+					Text     string `json:"text"`
+					HTML     string `json:"html"`
+					Markdown string `json:"markdown"`
 				} `json:"content"`
+				OgMetaData struct {
+					Image string `json:"image"`
+				} `json:"ogMetaData"`
+				Tags []struct {
+					Slug string `json:"slug"`
+				} `json:"tags"`
 			} `json:"post"`
 		} `json:"data"`
 	}
 	if err := json.Unmarshal(gqlResponse, &response); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	const maxContentLength = 150
-	content := response.Data.Post.Content.Text
-	if len(content) > maxContentLength {
-		content = content[:maxContentLength] + "..."
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
 	}
-	prompt := fmt.Sprintf(
-		"Write a post that i can post it in linkedin and twitter (X) for this blog:\n\n"+
-			"Title: %s\n"+
-			"Subtitle: %s\n"+
-			"Brief: %s\n"+
-			"Content snippet: %s\n\n"+
-			"Note: The tone should be human, engaging, and conversational. Encourage readers to click the blog link for more details. Avoid sounding robotic or generic. Mention the blog’s key takeaway and invite readers to check it out and make sure it was short enough and dont be too verbose as twitter and linkedin has character limit on how much we can tweet or post so please keep it short and also make sure to generate single post that can be used for both linkedin and twitter rather seperately and dont use any wild card characters like * and without commentary.",
-		response.Data.Post.Title,
-		response.Data.Post.SubTitle,
-		response.Data.Post.Brief,
-		content,
-	)
-	aiResponse, err := invokeAi(prompt)
-	if err != nil {
-		return fmt.Errorf("failed to generate post content: %v", err)
-	}
-	for _, platform := range platforms {
-		switch platform {
-		case "linkedin":
-			err = linkedPostHandler(aiResponse, user.LinkedInOauthKey)
-			if err != nil {
-				return fmt.Errorf("failed to post content to LinkedIn: %v", err)
-			}
-		case "twitter":
-			token := oauth1.NewToken(user.XOAuthToken, user.XOAuthSecret)
-			err = postTweetHandler(aiResponse, blogId, token)
-			if err != nil {
-				return fmt.Errorf("failed to post content to Twitter: %v", err)
-			}
-		}
-	}
-	var isFound bool
-	for i := range user.SharedBlogs {
-		if user.SharedBlogs[i].Id == response.Data.Post.Id {
-			user.SharedBlogs[i].SharedTime = time.Now().Format(time.RFC3339)
-			err = repositories.UpdateUser(userId, user)
-			isFound = true
-			if err != nil {
-				return fmt.Errorf("failed to update user with shared blog: %v", err)
-			}
-			break
-		}
-	}
-	if !isFound {
-		var newSharedBlog models.SharedBlog
-		newSharedBlog.Id = response.Data.Post.Id
-		newSharedBlog.Title = response.Data.Post.Title
-		newSharedBlog.Url = response.Data.Post.Url
-		newSharedBlog.CoverImage = models.Image{URL: response.Data.Post.CoverImage.Url}
-		newSharedBlog.Author = models.Author{Name: response.Data.Post.Author.Name}
-		newSharedBlog.ReadTimeInMinutes = response.Data.Post.ReadTimeInMinutes
-		newSharedBlog.SharedTime = time.Now().Format(time.RFC3339)
-		user.SharedBlogs = append(user.SharedBlogs, newSharedBlog)
-		err = repositories.UpdateUser(userId, user)
-		if err != nil {
-			return fmt.Errorf("failed to update user with shared blog: %v", err)
-		}
+	post := response.Data.Post
+	tags := make([]string, len(post.Tags))
+	for i, tag := range post.Tags {
+		tags[i] = tag.Slug
 	}
-	return nil
+	return &sourcePost{
+		Id:          post.Id,
+		Title:       post.Title,
+		Url:         post.Url,
+		CoverImage:  post.CoverImage.Url,
+		SocialImage: post.OgMetaData.Image,
+		Author:      post.Author.Name,
+		ReadTime:    post.ReadTimeInMinutes,
+		Subtitle:    post.SubTitle,
+		Brief:       post.Brief,
+		Text:        post.Content.Text,
+		HTML:        post.Content.HTML,
+		Markdown:    post.Content.Markdown,
+		Tags:        tags,
+	}, nil
 }
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// substackAPI is replaced by tests.
+var substackAPI = "https://substack.com/api/v1"
+
+// substackSessionCookie is the cookie Substack keeps a browser signed in
+// with.
+const substackSessionCookie = "substack.sid"
+
+var substackSessionPattern = regexp.MustCompile(`^[A-Za-z0-9%._:+/=-]{20,1000}$`)
+
+// NormalizeSubstackSession takes the session cookie a user copied from a
+// signed-in browser, either its value or a whole Cookie header, and returns
+// its value.
+func NormalizeSubstackSession(raw string) (string, error) {
+	session := strings.TrimSpace(raw)
+	if strings.Contains(session, "=") && strings.Contains(session, substackSessionCookie) {
+		session = ""
+		for _, cookie := range strings.Split(raw, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(cookie), "=")
+			if name == substackSessionCookie {
+				session = value
+			}
+		}
+	}
+	if !substackSessionPattern.MatchString(session) {
+		return "", fmt.Errorf("session must be the %s cookie of a browser signed in to Substack: %w", substackSessionCookie, apperrors.ErrInvalidInput)
+	}
+	return session, nil
+}
+
+// substackCall sends a request to Substack as the signed-in user and
+// decodes its JSON response into out.
+func substackCall(userId, session string, req *http.Request, out interface{}) error {
+	req.AddCookie(&http.Cookie{Name: substackSessionCookie, Value: session})
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(ProviderSubstack).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Substack: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "substack", req.URL.String(), resp.StatusCode, body)
+	var failure struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &failure)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Substack throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		// Sessions end when the user signs out of the browser they came from
+		return fmt.Errorf("Substack rejected the session, sign in and connect again: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("Substack refused the request, %s: %w", failure.Error, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Substack answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Substack: %v", err)
+	}
+	return nil
+}
+
+// LookupSubstackAccount returns the Substack account the session belongs
+// to.
+func LookupSubstackAccount(userId, session string) (*models.SubstackAccount, error) {
+	req, err := http.NewRequest(http.MethodGet, substackAPI+"/user/profile/self", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var profile struct {
+		ID     int64  `json:"id"`
+		Name   string `json:"name"`
+		Handle string `json:"handle"`
+	}
+	if err := substackCall(userId, session, req, &profile); err != nil {
+		return nil, fmt.Errorf("failed to look up the Substack account: %w", err)
+	}
+	if profile.ID == 0 {
+		return nil, fmt.Errorf("Substack didn't say whose session it is: %w", apperrors.ErrProviderAuth)
+	}
+	return &models.SubstackAccount{UserID: profile.ID, Handle: profile.Handle, Name: profile.Name}, nil
+}
+
+// substackNote is a Note as the Substack editor sends it: a ProseMirror
+// document with its link cards attached by id.
+type substackNote struct {
+	BodyJSON         substackNode `json:"bodyJson"`
+	AttachmentIDs    []string     `json:"attachmentIds,omitempty"`
+	ReplyMinimumRole string       `json:"replyMinimumRole"`
+}
+
+type substackNode struct {
+	Type    string            `json:"type"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+	Text    string            `json:"text,omitempty"`
+	Content []substackNode    `json:"content,omitempty"`
+}
+
+// newSubstackNote turns the text into a Note, a paragraph per line.
+func newSubstackNote(text string) substackNote {
+	doc := substackNode{Type: "doc", Attrs: map[string]string{"schemaVersion": "v1"}}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		paragraph := substackNode{Type: "paragraph"}
+		if line = strings.TrimSpace(line); line != "" {
+			paragraph.Content = []substackNode{{Type: "text", Text: line}}
+		}
+		doc.Content = append(doc.Content, paragraph)
+	}
+	return substackNote{BodyJSON: doc, ReplyMinimumRole: "everyone"}
+}
+
+// attachSubstackLink creates the link card of the URL and returns its id.
+func attachSubstackLink(userId, session, link string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"url": link, "type": "link"})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attachment: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, substackAPI+"/comment/attachment", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var attachment struct {
+		ID string `json:"id"`
+	}
+	if err := substackCall(userId, session, req, &attachment); err != nil {
+		return "", err
+	}
+	if attachment.ID == "" {
+		return "", errors.New("Substack didn't return the id of the link card")
+	}
+	return attachment.ID, nil
+}
+
+// postSubstackNote posts the caption as a Note with a card linking to the
+// blog and returns the Note's URL. When Substack can't make the card, the
+// link goes in the text instead.
+func postSubstackNote(user *models.User, caption, link string) (string, error) {
+	account := user.Substack
+	if account == nil {
+		return "", fmt.Errorf("Substack is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	userId := user.Id.Hex()
+	session, err := OpenUserSecret(user, account.SealedSession)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Substack session: %v", err)
+	}
+
+	note := newSubstackNote(caption)
+	attachmentID, err := attachSubstackLink(userId, session, link)
+	switch {
+	case err == nil:
+		note.AttachmentIDs = []string{attachmentID}
+	case errors.Is(err, apperrors.ErrProviderAuth) || errors.Is(err, apperrors.ErrProviderRateLimited):
+		return "", err
+	default:
+		log.Printf("[WARN] Posting the Substack Note of user %s without a link card: %v", userId, err)
+		note = newSubstackNote(caption + "\n" + link)
+	}
+	payload, err := json.Marshal(note)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal note: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, substackAPI+"/comment/feed", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var posted struct {
+		ID int64 `json:"id"`
+	}
+	if err := substackCall(userId, session, req, &posted); err != nil {
+		return "", err
+	}
+	if posted.ID == 0 || account.Handle == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("https://substack.com/@%s/note/c-%d", account.Handle, posted.ID), nil
+}
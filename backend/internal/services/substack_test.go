@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const testSubstackSession = "s%3AbP2kQ7xZ9yR4tW1v.Hf8sLmN3cD6gJ0aE"
+
+func TestNormalizeSubstackSession(t *testing.T) {
+	for _, raw := range []string{
+		" " + testSubstackSession + "\n",
+		"ajs_anonymous_id=abc; substack.sid=" + testSubstackSession + "; substack.lli=1",
+	} {
+		if session, err := NormalizeSubstackSession(raw); err != nil || session != testSubstackSession {
+			t.Errorf("normalized %q as %q, %v", raw, session, err)
+		}
+	}
+	if _, err := NormalizeSubstackSession("ajs_anonymous_id=abc; substack.lli=1"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("accepted cookies without the session: %v", err)
+	}
+}
+
+func TestPostSubstackNote(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var note substackNote
+	cardFails := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("substack.sid"); err != nil || cookie.Value != testSubstackSession {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "Not authorized"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/user/profile/self":
+			w.Write([]byte(`{"id": 8412, "name": "Ada Lovelace", "handle": "ada"}`))
+		case "/comment/attachment":
+			if cardFails {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "Could not fetch URL"}`))
+				return
+			}
+			w.Write([]byte(`{"id": "7b6c1f2e-attachment", "type": "link"}`))
+		case "/comment/feed":
+			json.NewDecoder(r.Body).Decode(&note)
+			w.Write([]byte(`{"id": 91234567, "body": "New post"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := substackAPI
+	substackAPI = server.URL
+	defer func() { substackAPI = previous }()
+
+	if _, err := LookupSubstackAccount("", "s%3Aexpired-session-value"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up with an expired session: %v", err)
+	}
+	account, err := LookupSubstackAccount("", testSubstackSession)
+	if err != nil || account.UserID != 8412 || account.Handle != "ada" {
+		t.Fatalf("looked up %+v, %v", account, err)
+	}
+	user := &models.User{Id: primitive.NewObjectID(), Substack: account}
+	if account.SealedSession, err = SealUserSecret(user, testSubstackSession); err != nil {
+		t.Fatal(err)
+	}
+
+	noteURL, err := postSubstackNote(user, "Scheduling posts, explained.\n\nWorth a read", "https://blog.example.com/scheduling")
+	if err != nil || noteURL != "https://substack.com/@ada/note/c-91234567" {
+		t.Fatalf("posted at %q, %v", noteURL, err)
+	}
+	if len(note.AttachmentIDs) != 1 || note.AttachmentIDs[0] != "7b6c1f2e-attachment" || len(note.BodyJSON.Content) != 3 ||
+		note.BodyJSON.Content[2].Content[0].Text != "Worth a read" {
+		t.Errorf("posted %+v", note)
+	}
+
+	cardFails = true
+	note = substackNote{}
+	if _, err := postSubstackNote(user, "Scheduling posts, explained.", "https://blog.example.com/scheduling"); err != nil {
+		t.Fatal(err)
+	}
+	if paragraphs := note.BodyJSON.Content; len(note.AttachmentIDs) != 0 || len(paragraphs) != 2 || paragraphs[1].Content[0].Text != "https://blog.example.com/scheduling" {
+		t.Errorf("posted without a card %+v", note)
+	}
+}
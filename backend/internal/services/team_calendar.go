@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"social-scribe/backend/internal/models"
+)
+
+// BuildTeamCalendar lists the pending shares the members scheduled between
+// from and to. Shares of different members to the same platform that follow
+// each other within window are reported as a conflict, since a team usually
+// posts to one company account per platform.
+func BuildTeamCalendar(members []models.User, from, to time.Time, window time.Duration) models.TeamCalendar {
+	calendar := models.TeamCalendar{
+		From:      from,
+		To:        to,
+		Entries:   []models.TeamCalendarEntry{},
+		Conflicts: []models.TeamCalendarConflict{},
+	}
+	add := func(member *models.User, blog *models.ScheduledBlog, platform string, at time.Time) {
+		if at.Before(from) || !at.Before(to) {
+			return
+		}
+		calendar.Entries = append(calendar.Entries, models.TeamCalendarEntry{
+			UserID:        member.Id.Hex(),
+			Username:      member.UserName,
+			BlogID:        blog.Id,
+			BlogTitle:     blog.Title,
+			Platform:      platform,
+			ScheduledTime: at,
+		})
+	}
+	for i := range members {
+		member := &members[i]
+		if member.Disabled {
+			continue
+		}
+		for j := range member.ScheduledBlogs {
+			blog := &member.ScheduledBlogs[j]
+			if len(blog.Children) == 0 {
+				for _, platform := range blog.Platforms {
+					add(member, blog, platform, blog.ScheduledTime)
+				}
+				continue
+			}
+			for _, child := range blog.Children {
+				if child.Status == models.SchedulePending {
+					add(member, blog, child.Platform, child.ScheduledTime)
+				}
+			}
+		}
+	}
+	sort.SliceStable(calendar.Entries, func(i, j int) bool {
+		return calendar.Entries[i].ScheduledTime.Before(calendar.Entries[j].ScheduledTime)
+	})
+
+	byPlatform := map[string][]int{}
+	var platforms []string
+	for i, entry := range calendar.Entries {
+		if _, ok := byPlatform[entry.Platform]; !ok {
+			platforms = append(platforms, entry.Platform)
+		}
+		byPlatform[entry.Platform] = append(byPlatform[entry.Platform], i)
+	}
+	for _, platform := range platforms {
+		indexes := byPlatform[platform]
+		start := 0
+		for k := 1; k <= len(indexes); k++ {
+			if k < len(indexes) && calendar.Entries[indexes[k]].ScheduledTime.Sub(calendar.Entries[indexes[k-1]].ScheduledTime) <= window {
+				continue
+			}
+			recordConflict(&calendar, platform, indexes[start:k])
+			start = k
+		}
+	}
+	sort.SliceStable(calendar.Conflicts, func(i, j int) bool {
+		return calendar.Conflicts[i].Start.Before(calendar.Conflicts[j].Start)
+	})
+	return calendar
+}
+
+// recordConflict adds the run of entries as a conflict when more than one
+// member scheduled them.
+func recordConflict(calendar *models.TeamCalendar, platform string, indexes []int) {
+	var userIDs []string
+	seen := map[string]bool{}
+	for _, i := range indexes {
+		if userID := calendar.Entries[i].UserID; !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) < 2 {
+		return
+	}
+	conflict := models.TeamCalendarConflict{
+		Platform: platform,
+		Start:    calendar.Entries[indexes[0]].ScheduledTime,
+		End:      calendar.Entries[indexes[len(indexes)-1]].ScheduledTime,
+		UserIDs:  userIDs,
+	}
+	for _, i := range indexes {
+		calendar.Entries[i].Conflict = true
+		conflict.Entries = append(conflict.Entries, calendar.Entries[i])
+	}
+	calendar.Conflicts = append(calendar.Conflicts, conflict)
+}
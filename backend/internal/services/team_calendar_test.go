@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/models"
+)
+
+func calendarMember(name string, blogs ...models.ScheduledBlog) models.User {
+	return models.User{Id: primitive.NewObjectID(), UserName: name, ScheduledBlogs: blogs}
+}
+
+func TestBuildTeamCalendar(t *testing.T) {
+	from := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	at := from.Add(9 * time.Hour)
+	withOffsets := models.ScheduledBlog{
+		Blog:            models.Blog{Id: "b2"},
+		Platforms:       []string{"twitter", "linkedin"},
+		ScheduledTime:   at.Add(10 * time.Minute),
+		PlatformOffsets: map[string]int{"linkedin": 180},
+	}
+	withOffsets.PlanChildren()
+	withOffsets.Children[0].Status = models.ScheduleShared
+
+	alice := calendarMember("alice",
+		models.ScheduledBlog{Blog: models.Blog{Id: "a1"}, Platforms: []string{"linkedin", "twitter"}, ScheduledTime: at},
+		models.ScheduledBlog{Blog: models.Blog{Id: "a2"}, Platforms: []string{"linkedin"}, ScheduledTime: from.Add(-time.Hour)},
+	)
+	bob := calendarMember("bob", withOffsets,
+		models.ScheduledBlog{Blog: models.Blog{Id: "b1"}, Platforms: []string{"linkedin"}, ScheduledTime: at.Add(20 * time.Minute)},
+	)
+	carol := calendarMember("carol",
+		models.ScheduledBlog{Blog: models.Blog{Id: "c1"}, Platforms: []string{"twitter"}, ScheduledTime: at.Add(5 * time.Hour)},
+	)
+	carol.Disabled = true
+
+	calendar := BuildTeamCalendar([]models.User{alice, bob, carol}, from, from.Add(24*time.Hour), 30*time.Minute)
+
+	// a1 to both platforms, b1 and b2's pending linkedin child
+	if len(calendar.Entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(calendar.Entries), calendar.Entries)
+	}
+	if len(calendar.Conflicts) != 1 {
+		t.Fatalf("got %d conflicts, want 1: %+v", len(calendar.Conflicts), calendar.Conflicts)
+	}
+	conflict := calendar.Conflicts[0]
+	if conflict.Platform != "linkedin" || len(conflict.Entries) != 2 || len(conflict.UserIDs) != 2 {
+		t.Errorf("conflict = %+v", conflict)
+	}
+	if !conflict.Start.Equal(at) || !conflict.End.Equal(at.Add(20*time.Minute)) {
+		t.Errorf("conflict spans %v to %v", conflict.Start, conflict.End)
+	}
+	for _, entry := range calendar.Entries {
+		wantConflict := entry.Platform == "linkedin" && entry.ScheduledTime.Before(at.Add(time.Hour))
+		if entry.Conflict != wantConflict {
+			t.Errorf("%s on %s at %v: conflict %t", entry.BlogID, entry.Platform, entry.ScheduledTime, entry.Conflict)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	maxTeamsCardTitle   = 256
+	maxTeamsCardSummary = 2000
+)
+
+// teamsWebhookHosts are the host suffixes Teams incoming webhooks live on:
+// the Office 365 connectors and the Power Automate workflows replacing them.
+var teamsWebhookHosts = []string{".webhook.office.com", ".logic.azure.com", ".api.powerplatform.com"}
+
+// teamsServers reaches Teams webhooks, which are only called on Microsoft's
+// hosts.
+var teamsServers = userServers{scheme: "https", checkHost: checkTeamsWebhookHost}
+
+func checkTeamsWebhookHost(host string) error {
+	for _, suffix := range teamsWebhookHosts {
+		if strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a Teams webhook host", host)
+}
+
+// ParseTeamsWebhookURL checks that a URL is a Microsoft Teams incoming
+// webhook and returns it along with its host. Only Microsoft's hosts are
+// accepted, so announcements can't be pointed anywhere else.
+func ParseTeamsWebhookURL(raw string) (string, string, error) {
+	return teamsServers.parseTeamsWebhookURL(raw)
+}
+
+func (s userServers) parseTeamsWebhookURL(raw string) (string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Scheme != s.scheme || parsed.User != nil || parsed.Fragment != "" ||
+		s.checkHost(strings.ToLower(parsed.Hostname())) != nil || len(parsed.Path) < 2 {
+		return "", "", fmt.Errorf("webhook URL must be a Microsoft Teams incoming webhook or workflow URL: %w", apperrors.ErrInvalidInput)
+	}
+	return parsed.String(), strings.ToLower(parsed.Hostname()), nil
+}
+
+// teamsCard is an Adaptive Card, the message format Teams webhooks take.
+type teamsCard struct {
+	Schema  string             `json:"$schema"`
+	Type    string             `json:"type"`
+	Version string             `json:"version"`
+	Body    []teamsCardElement `json:"body"`
+	Actions []teamsCardAction  `json:"actions,omitempty"`
+	MSTeams map[string]string  `json:"msteams,omitempty"`
+}
+
+// teamsCardElement is a TextBlock or an Image.
+type teamsCardElement struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"`
+	AltText  string `json:"altText,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Weight   string `json:"weight,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Wrap     bool   `json:"wrap,omitempty"`
+	Spacing  string `json:"spacing,omitempty"`
+}
+
+type teamsCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// teamsAnnouncement is the card announcing a blog: its title, a byline, the
+// cover image, the caption as its summary and a button to read it.
+func teamsAnnouncement(title, link, caption, author, imageURL string, readTime int) teamsCard {
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    []teamsCardElement{{Type: "TextBlock", Text: truncateRunes(title, maxTeamsCardTitle), Size: "Large", Weight: "Bolder", Wrap: true}},
+		Actions: []teamsCardAction{{Type: "Action.OpenUrl", Title: "Read the post", URL: link}},
+		MSTeams: map[string]string{"width": "Full"},
+	}
+	var byline []string
+	if author != "" {
+		byline = append(byline, "by "+author)
+	}
+	if readTime > 0 {
+		byline = append(byline, fmt.Sprintf("%d min read", readTime))
+	}
+	if len(byline) > 0 {
+		card.Body = append(card.Body, teamsCardElement{Type: "TextBlock", Text: strings.Join(byline, " · "), IsSubtle: true, Wrap: true, Spacing: "None"})
+	}
+	if imageURL != "" {
+		card.Body = append(card.Body, teamsCardElement{Type: "Image", URL: imageURL, AltText: truncateRunes(title, maxTeamsCardTitle), Size: "Stretch"})
+	}
+	if caption = strings.TrimSpace(caption); caption != "" {
+		card.Body = append(card.Body, teamsCardElement{Type: "TextBlock", Text: truncateRunes(caption, maxTeamsCardSummary), Wrap: true})
+	}
+	return card
+}
+
+// postTeamsCard posts the card through the user's webhook. Teams doesn't say
+// where the message went, so there is no link to return.
+func postTeamsCard(user *models.User, card teamsCard) error {
+	webhook := user.TeamsWebhook
+	if webhook == nil {
+		return fmt.Errorf("Microsoft Teams is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	webhookURL, err := OpenUserSecret(user, webhook.SealedURL)
+	if err != nil {
+		return fmt.Errorf("failed to open the Teams webhook URL: %v", err)
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := getProviderClient(ProviderTeams).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Teams: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	// The URL is what lets anyone post, so only its host is archived
+	archiveProviderResponse(user.Id.Hex(), "teams", req.URL.Scheme+"://"+webhook.Host+"/[REDACTED]", resp.StatusCode, body)
+	// Office 365 connectors answer 200 with "1" on success and with the
+	// failure as text otherwise
+	failure := strings.TrimSpace(string(body))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || strings.Contains(failure, "HTTP error 429"):
+		return fmt.Errorf("Teams throttled the webhook: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("Teams doesn't know the webhook anymore: %w", apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return fmt.Errorf("Teams refused the card, %s: %w", truncateRunes(failure, 200), apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Teams answered %s", resp.Status)
+	case resp.StatusCode == http.StatusOK && failure != "" && failure != "1":
+		return fmt.Errorf("Teams failed to deliver the card: %s", truncateRunes(failure, 200))
+	}
+	return nil
+}
+
+// PingTeamsWebhook posts a short card confirming the webhook works.
+func PingTeamsWebhook(user *models.User) error {
+	return postTeamsCard(user, teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    []teamsCardElement{{Type: "TextBlock", Text: "SocialScribe is connected. New posts will be announced here.", Wrap: true}},
+	})
+}
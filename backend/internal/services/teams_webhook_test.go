@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestParseTeamsWebhookURL(t *testing.T) {
+	for raw, host := range map[string]string{
+		"https://contoso.webhook.office.com/webhookb2/a1b2@c3d4/IncomingWebhook/e5f6/g7h8":                                       "contoso.webhook.office.com",
+		" https://prod-12.westus.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke?api-version=2016-06-01&sig=xyz ": "prod-12.westus.logic.azure.com",
+		"http://contoso.webhook.office.com/webhookb2/a1b2":                                                                       "",
+		"https://webhook.office.com.evil.example/webhookb2/a1b2":                                                                 "",
+		"https://contoso.webhook.office.com/":                                                                                    "",
+		"https://user@contoso.webhook.office.com/webhookb2/a1b2":                                                                 "",
+	} {
+		_, got, err := ParseTeamsWebhookURL(raw)
+		if host == "" {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("ParseTeamsWebhookURL(%q) = %q, %v; want invalid input", raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != host {
+			t.Errorf("ParseTeamsWebhookURL(%q) = %q, %v; want %q", raw, got, err, host)
+		}
+	}
+}
+
+func TestTeamsAnnouncement(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var posted struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string    `json:"contentType"`
+			Content     teamsCard `json:"content"`
+		} `json:"attachments"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/webhookb2/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/webhookb2/throttled":
+			w.Write([]byte("Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 429 with ContextId tcid=0"))
+		default:
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte("1"))
+		}
+	}))
+	defer server.Close()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	connect := func(path string) {
+		webhookURL, host, err := localServers().parseTeamsWebhookURL(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.TeamsWebhook = &models.TeamsWebhook{Host: host}
+		if user.TeamsWebhook.SealedURL, err = SealUserSecret(user, webhookURL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connect("/webhookb2/ok")
+	card := teamsAnnouncement("Scheduling posts", "https://blog.example.com/scheduling", " New post ", "Ada", "https://cdn.example.com/cover.png", 4)
+	if err := postTeamsCard(user, card); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted.Attachments) != 1 || posted.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("posted %+v", posted)
+	}
+	body := posted.Attachments[0].Content.Body
+	if len(body) != 4 || body[0].Text != "Scheduling posts" || body[1].Text != "by Ada · 4 min read" ||
+		body[2].Type != "Image" || body[2].URL != "https://cdn.example.com/cover.png" || body[3].Text != "New post" {
+		t.Errorf("card body %+v", body)
+	}
+	if actions := posted.Attachments[0].Content.Actions; len(actions) != 1 || actions[0].URL != "https://blog.example.com/scheduling" {
+		t.Errorf("card actions %+v", actions)
+	}
+
+	connect("/webhookb2/gone")
+	if err := PingTeamsWebhook(user); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("pinged a deleted webhook: %v", err)
+	}
+	connect("/webhookb2/throttled")
+	if err := PingTeamsWebhook(user); !errors.Is(err, apperrors.ErrProviderRateLimited) || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("pinged a throttled webhook: %v", err)
+	}
+}
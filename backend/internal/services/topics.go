@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+const (
+	// MaxShareTopics caps the topics of a shared blog.
+	MaxShareTopics = 5
+	maxTopicLength = 40
+	// maxClassifiedTopics is how many topics the AI is asked for.
+	maxClassifiedTopics = 3
+	// minTopicShares is how many shares of a topic a platform needs before
+	// the topic's insights mention it.
+	minTopicShares = 2
+	// topicInsightLift is how far a topic's engagement on a platform has to
+	// stray from the platform's usual for an insight to point it out.
+	topicInsightLift = 1.5
+)
+
+var topicSeparator = regexp.MustCompile(`[^a-z0-9+#.]+`)
+
+// topicClassifier asks the AI for a blog's topics; tests replace it.
+var topicClassifier = invokeAi
+
+// NormalizeTopic turns a tag or topic name into a topic, e.g.
+// "Cloud Native" -> "cloud-native". It returns "" for names without letters
+// or digits.
+func NormalizeTopic(name string) string {
+	topic := strings.Trim(topicSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if len(topic) > maxTopicLength {
+		topic = strings.TrimRight(topic[:maxTopicLength], "-.")
+	}
+	return topic
+}
+
+// NormalizeTopics normalizes topic names, dropping duplicates and blanks,
+// and keeps at most MaxShareTopics.
+func NormalizeTopics(names []string) []string {
+	topics := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		topic := NormalizeTopic(name)
+		if topic == "" || seen[topic] || len(topics) == MaxShareTopics {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// ValidateTopics checks topics the user assigned to a shared blog.
+func ValidateTopics(names []string) ([]string, error) {
+	if len(names) > MaxShareTopics {
+		return nil, fmt.Errorf("a blog takes at most %d topics: %w", MaxShareTopics, apperrors.ErrInvalidInput)
+	}
+	topics := NormalizeTopics(names)
+	if len(topics) != len(names) {
+		return nil, fmt.Errorf("topics must be distinct names with letters or digits: %w", apperrors.ErrInvalidInput)
+	}
+	return topics, nil
+}
+
+// classifyTopics asks the AI for the topics of the blog, as a comma
+// separated list.
+func classifyTopics(post *sourcePost) ([]string, error) {
+	const maxContentLength = 500
+	content := post.Text
+	if len(content) > maxContentLength {
+		content = content[:maxContentLength]
+	}
+	prompt := fmt.Sprintf(
+		"List up to %d topics this blog is about, such as kubernetes, react or career, "+
+			"as lowercase names separated by commas, without commentary:\n\n"+
+			"Title: %s\n"+
+			"Brief: %s\n"+
+			"Content snippet: %s",
+		maxClassifiedTopics,
+		post.Title,
+		post.Brief,
+		content,
+	)
+	response, err := topicClassifier(prompt)
+	if err != nil {
+		return nil, err
+	}
+	names := strings.FieldsFunc(response, func(r rune) bool { return r == ',' || r == '\n' })
+	if len(names) > maxClassifiedTopics {
+		names = names[:maxClassifiedTopics]
+	}
+	return names, nil
+}
+
+// shareTopics are the topics of a blog: its own tags, then those the AI
+// finds in it. A failing classification leaves the tags alone.
+func shareTopics(post *sourcePost) []string {
+	names := append([]string{}, post.Tags...)
+	classified, err := classifyTopics(post)
+	if err != nil {
+		log.Printf("[WARN] Recording blog %s with its tags as topics only: %v", post.Id, err)
+	}
+	return NormalizeTopics(append(names, classified...))
+}
+
+// engagementTally adds up the interactions with a number of shares.
+type engagementTally struct {
+	shares       int
+	interactions int
+}
+
+func (t *engagementTally) add(engagement models.ShareEngagement) {
+	t.shares++
+	t.interactions += interactions(engagement)
+}
+
+func (t engagementTally) mean() float64 {
+	return float64(t.interactions) / float64(t.shares)
+}
+
+// TopicInsights compares the engagement of the user's blogs shared between
+// from and to by topic: for each topic and platform, how its shares did
+// against all the shares on the platform. Shares without topics or known
+// engagement are left out.
+func TopicInsights(user *models.User, from, to time.Time) models.TopicAnalytics {
+	analytics := models.TopicAnalytics{
+		From:     from,
+		To:       to,
+		Topics:   map[string]models.TopicSummary{},
+		Insights: []models.TopicInsight{},
+	}
+	platformTotals := map[string]*engagementTally{}
+	topicTotals := map[string]map[string]*engagementTally{}
+	for _, blog := range user.SharedBlogs {
+		sharedAt, err := time.Parse(time.RFC3339, blog.SharedTime)
+		if err != nil || sharedAt.Before(from) || !sharedAt.Before(to) {
+			continue
+		}
+		engagement := platformEngagement(blog)
+		if len(engagement) == 0 {
+			continue
+		}
+		for platform, onPlatform := range engagement {
+			if platformTotals[platform] == nil {
+				platformTotals[platform] = &engagementTally{}
+			}
+			platformTotals[platform].add(onPlatform)
+		}
+		for _, topic := range blog.Topics {
+			summary := analytics.Topics[topic]
+			summary.Shares++
+			if summary.Platforms == nil {
+				summary.Platforms = map[string]models.TopicPlatform{}
+				topicTotals[topic] = map[string]*engagementTally{}
+			}
+			for platform, onPlatform := range engagement {
+				if topicTotals[topic][platform] == nil {
+					topicTotals[topic][platform] = &engagementTally{}
+				}
+				topicTotals[topic][platform].add(onPlatform)
+			}
+			analytics.Topics[topic] = summary
+		}
+	}
+
+	for topic, platforms := range topicTotals {
+		summary := analytics.Topics[topic]
+		for platform, total := range platforms {
+			mean := total.mean()
+			platformMean := platformTotals[platform].mean()
+			lift := 0.0
+			if platformMean > 0 {
+				lift = mean / platformMean
+			}
+			summary.Platforms[platform] = models.TopicPlatform{
+				Shares: total.shares,
+				Mean:   roundRate(mean),
+				Lift:   roundRate(lift),
+			}
+			if total.shares < minTopicShares || platformMean == 0 || (lift < topicInsightLift && lift > 1/topicInsightLift) {
+				continue
+			}
+			analytics.Insights = append(analytics.Insights, models.TopicInsight{
+				Topic:    topic,
+				Platform: platform,
+				Shares:   total.shares,
+				Lift:     roundRate(lift),
+				Summary:  topicInsightSummary(topic, platform, lift),
+			})
+		}
+		analytics.Topics[topic] = summary
+	}
+	// The strongest effects first, either way
+	sort.Slice(analytics.Insights, func(i, j int) bool {
+		a, b := analytics.Insights[i], analytics.Insights[j]
+		strengthA, strengthB := math.Abs(math.Log(a.Lift)), math.Abs(math.Log(b.Lift))
+		if strengthA != strengthB {
+			return strengthA > strengthB
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Platform < b.Platform
+	})
+	return analytics
+}
+
+// topicInsightSummary words an insight, e.g. "Your kubernetes posts do 2.1x
+// better on LinkedIn".
+func topicInsightSummary(topic, platform string, lift float64) string {
+	title := platform
+	if sharePlatform, ok := LookupPlatform(platform); ok {
+		title = sharePlatform.Title()
+	}
+	if lift >= 1 {
+		return fmt.Sprintf("Your %s posts do %.1fx better on %s", topic, lift, title)
+	}
+	if lift == 0 {
+		return fmt.Sprintf("Your %s posts get no engagement on %s", topic, title)
+	}
+	return fmt.Sprintf("Your %s posts do %.1fx worse on %s", topic, 1/lift, title)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestNormalizeTopics(t *testing.T) {
+	got := NormalizeTopics([]string{"Kubernetes", " Cloud Native ", "C#", "kubernetes", "!!!", "node.js", "go", "rust"})
+	if want := []string{"kubernetes", "cloud-native", "c#", "node.js", "go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTopics = %q, want %q", got, want)
+	}
+	if _, err := ValidateTopics([]string{"go", "Go"}); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("duplicate topics gave %v", err)
+	}
+}
+
+func TestShareTopics(t *testing.T) {
+	previous := topicClassifier
+	defer func() { topicClassifier = previous }()
+	post := &sourcePost{Id: "post-1", Title: "Scaling pods", Tags: []string{"kubernetes"}}
+
+	topicClassifier = func(string) (string, error) { return "Kubernetes, autoscaling,\ndevops, cloud", nil }
+	if got, want := shareTopics(post), []string{"kubernetes", "autoscaling", "devops"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shareTopics = %q, want %q", got, want)
+	}
+	topicClassifier = func(string) (string, error) { return "", errors.New("unavailable") }
+	if got := shareTopics(post); !reflect.DeepEqual(got, []string{"kubernetes"}) {
+		t.Errorf("without the AI shareTopics = %q", got)
+	}
+}
+
+func TestTopicInsights(t *testing.T) {
+	shared := func(id, at string, topics []string, twitter, linkedin int) models.SharedBlog {
+		return models.SharedBlog{
+			Blog: models.Blog{Id: id}, Platforms: []string{"twitter", "linkedin"}, SharedTime: at, Topics: topics,
+			PlatformEngagement: map[string]models.ShareEngagement{"twitter": {Likes: twitter}, "linkedin": {Likes: linkedin}},
+		}
+	}
+	user := &models.User{
+		SharedBlogs: []models.SharedBlog{
+			shared("a", "2026-10-01T09:00:00Z", []string{"kubernetes"}, 4, 20),
+			shared("b", "2026-10-02T09:00:00Z", []string{"kubernetes", "go"}, 4, 20),
+			shared("c", "2026-10-03T09:00:00Z", []string{"career"}, 4, 0),
+			shared("d", "2026-10-04T09:00:00Z", []string{"career"}, 4, 0),
+			{Blog: models.Blog{Id: "untagged"}, Platforms: []string{"twitter"}, SharedTime: "2026-10-05T09:00:00Z"},
+		},
+	}
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	got := TopicInsights(user, from, from.AddDate(0, 2, 0))
+
+	kubernetes := got.Topics["kubernetes"]
+	if kubernetes.Shares != 2 || kubernetes.Platforms["linkedin"] != (models.TopicPlatform{Shares: 2, Mean: 20, Lift: 2}) ||
+		kubernetes.Platforms["twitter"] != (models.TopicPlatform{Shares: 2, Mean: 4, Lift: 1}) {
+		t.Errorf("kubernetes = %+v", kubernetes)
+	}
+	if got.Topics["go"].Shares != 1 {
+		t.Errorf("go = %+v", got.Topics["go"])
+	}
+
+	// A single go share is too few for an insight, and twitter does the
+	// same whatever the topic
+	want := []models.TopicInsight{
+		{Topic: "career", Platform: "linkedin", Shares: 2, Lift: 0, Summary: "Your career posts get no engagement on LinkedIn"},
+		{Topic: "kubernetes", Platform: "linkedin", Shares: 2, Lift: 2, Summary: "Your kubernetes posts do 2.0x better on LinkedIn"},
+	}
+	if !reflect.DeepEqual(got.Insights, want) {
+		t.Errorf("insights = %+v", got.Insights)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dghubble/oauth1"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// TumblrEndpoint is where Tumblr hands out OAuth1 tokens.
+var TumblrEndpoint = oauth1.Endpoint{
+	RequestTokenURL: "https://www.tumblr.com/oauth/request_token",
+	AuthorizeURL:    "https://www.tumblr.com/oauth/authorize",
+	AccessTokenURL:  "https://www.tumblr.com/oauth/access_token",
+}
+
+// tumblrAPI is replaced by tests.
+var tumblrAPI = "https://api.tumblr.com/v2"
+
+var tumblrConfig = &oauth1.Config{}
+
+// InitTumblrConfig sets the Tumblr app posts are signed with.
+func InitTumblrConfig(config *oauth1.Config) {
+	tumblrConfig = config
+}
+
+// TumblrOAuth returns the Tumblr app configuration, with token requests
+// going through the provider client.
+func TumblrOAuth() *oauth1.Config {
+	config := *tumblrConfig
+	config.HTTPClient = getProviderClient(ProviderTumblr)
+	return &config
+}
+
+// tumblrCall sends a request signed with the user's access token and
+// decodes the response field of Tumblr's envelope into out.
+func tumblrCall(userId, token, secret string, req *http.Request, out interface{}) error {
+	ctx := context.WithValue(oauth1.NoContext, oauth1.HTTPClient, getProviderClient(ProviderTumblr))
+	req.Header.Set("Accept", "application/json")
+	resp, err := tumblrConfig.Client(ctx, oauth1.NewToken(token, secret)).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Tumblr: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "tumblr", req.URL.String(), resp.StatusCode, body)
+	var envelope struct {
+		Meta struct {
+			Msg string `json:"msg"`
+		} `json:"meta"`
+		Response json.RawMessage `json:"response"`
+		Errors   []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(body, &envelope)
+	reason := envelope.Meta.Msg
+	if len(envelope.Errors) > 0 && envelope.Errors[0].Detail != "" {
+		reason = envelope.Errors[0].Detail
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Tumblr throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("Tumblr rejected the access token, %s: %w", reason, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Tumblr refused, %s: %w", reason, apperrors.ErrForbidden)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("Tumblr doesn't know it, %s: %w", reason, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("Tumblr refused the request, %s: %w", reason, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Tumblr answered %s", resp.Status)
+	}
+	if err := json.Unmarshal(envelope.Response, out); err != nil {
+		return fmt.Errorf("failed to parse the response of Tumblr: %v", err)
+	}
+	return nil
+}
+
+// LookupTumblrBlog returns the primary blog of the account the access token
+// belongs to, which is where links are posted.
+func LookupTumblrBlog(userId, token, secret string) (*models.TumblrAccount, error) {
+	req, err := http.NewRequest(http.MethodGet, tumblrAPI+"/user/info", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var info struct {
+		User struct {
+			Blogs []struct {
+				Name    string `json:"name"`
+				Title   string `json:"title"`
+				URL     string `json:"url"`
+				Primary bool   `json:"primary"`
+			} `json:"blogs"`
+		} `json:"user"`
+	}
+	if err := tumblrCall(userId, token, secret, req, &info); err != nil {
+		return nil, fmt.Errorf("failed to look up the Tumblr account: %w", err)
+	}
+	for _, blog := range info.User.Blogs {
+		if blog.Primary {
+			return &models.TumblrAccount{Blog: blog.Name, Title: blog.Title, URL: strings.TrimRight(blog.URL, "/")}, nil
+		}
+	}
+	return nil, fmt.Errorf("the Tumblr account has no blog: %w", apperrors.ErrInvalidInput)
+}
+
+// postTumblrLink posts the blog as a link post on the user's Tumblr blog,
+// with the caption as its description, and returns the post's URL.
+func postTumblrLink(user *models.User, title, link, caption string, tags []string) (string, error) {
+	account := user.Tumblr
+	if account == nil {
+		return "", fmt.Errorf("Tumblr is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	token, err := OpenUserSecret(user, account.SealedToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Tumblr access token: %v", err)
+	}
+	secret, err := OpenUserSecret(user, account.SealedSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the Tumblr token secret: %v", err)
+	}
+	form := url.Values{
+		"type":        {"link"},
+		"url":         {link},
+		"title":       {title},
+		"description": {strings.TrimSpace(caption)},
+	}
+	if len(tags) > 0 {
+		form.Set("tags", strings.Join(tags, ","))
+	}
+	req, err := http.NewRequest(http.MethodPost, tumblrAPI+"/blog/"+url.PathEscape(account.Blog)+"/post", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var posted struct {
+		ID string `json:"id_string"`
+	}
+	if err := tumblrCall(user.Id.Hex(), token, secret, req, &posted); err != nil {
+		return "", err
+	}
+	return account.URL + "/post/" + posted.ID, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dghubble/oauth1"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestTumblrConnectAndPost(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var posted http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "OAuth ") || !strings.Contains(authorization, `oauth_consumer_key="consumer"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(authorization, `oauth_token="access"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"meta": {"status": 401, "msg": "Unauthorized"}, "response": []}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/user/info":
+			w.Write([]byte(`{"meta": {"status": 200, "msg": "OK"}, "response": {"user": {"name": "ada", "blogs": [
+				{"name": "ada-drafts", "title": "Drafts", "url": "https://ada-drafts.tumblr.com/", "primary": false},
+				{"name": "ada", "title": "Ada writes", "url": "https://ada.tumblr.com/", "primary": true}]}}}`))
+		case "/v2/blog/ada/post":
+			r.ParseForm()
+			posted = *r
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"meta": {"status": 201, "msg": "Created"}, "response": {"id": 7123456789, "id_string": "7123456789"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"meta": {"status": 404, "msg": "Not Found"}, "response": []}`))
+		}
+	}))
+	defer server.Close()
+	previousAPI, previousConfig := tumblrAPI, tumblrConfig
+	tumblrAPI = server.URL + "/v2"
+	InitTumblrConfig(&oauth1.Config{ConsumerKey: "consumer", ConsumerSecret: "secret", Endpoint: TumblrEndpoint})
+	defer func() { tumblrAPI, tumblrConfig = previousAPI, previousConfig }()
+
+	if _, err := LookupTumblrBlog("", "revoked", "secret"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up with a revoked token: %v", err)
+	}
+	account, err := LookupTumblrBlog("", "access", "access-secret")
+	if err != nil || account.Blog != "ada" || account.Title != "Ada writes" || account.URL != "https://ada.tumblr.com" {
+		t.Fatalf("looked up %+v, %v", account, err)
+	}
+
+	user := &models.User{Id: primitive.NewObjectID(), Tumblr: account}
+	if account.SealedToken, err = SealUserSecret(user, "access"); err != nil {
+		t.Fatal(err)
+	}
+	if account.SealedSecret, err = SealUserSecret(user, "access-secret"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := postTumblrLink(user, "Scheduling posts", "https://blog.example.com/scheduling", " New post ", []string{"go", "scheduling"})
+	if err != nil || link != "https://ada.tumblr.com/post/7123456789" {
+		t.Fatalf("posted at %q, %v", link, err)
+	}
+	if posted.PostForm.Get("type") != "link" || posted.PostForm.Get("url") != "https://blog.example.com/scheduling" ||
+		posted.PostForm.Get("description") != "New post" || posted.PostForm.Get("tags") != "go,scheduling" {
+		t.Errorf("posted %v", posted.PostForm)
+	}
+}
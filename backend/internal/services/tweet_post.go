@@ -1,34 +1,248 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"github.com/dghubble/oauth1"
+	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dghubble/oauth1"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// twitterAPI and twitterOAuthAPI are replaced by tests.
+var (
+	twitterAPI      = "https://api.twitter.com/2"
+	twitterOAuthAPI = "https://api.twitter.com/1.1/oauth"
 )
 
+// maxTweetImageSize is the largest image X attaches to a tweet.
+const maxTweetImageSize = 5 << 20
+
 var twitterConfig = &oauth1.Config{}
 
 func InitTwitterConfig(config *oauth1.Config) {
 	twitterConfig = config
 }
 
-func postTweetHandler(message string, blogId string, userToken *oauth1.Token) error {
+// twitterCall sends a request signed with the user's token to the X API v2
+// and decodes the data field of the response into out, unless out is nil.
+func twitterCall(userId string, config *oauth1.Config, userToken *oauth1.Token, req *http.Request, out interface{}) error {
+	ctx := context.WithValue(oauth1.NoContext, oauth1.HTTPClient, getProviderClient(ProviderTwitter))
+	resp, err := config.Client(ctx, userToken).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach X: %v", err)
+	}
+	defer resp.Body.Close()
 
-	client := twitterConfig.Client(oauth1.NoContext, userToken)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "twitter", req.URL.String(), resp.StatusCode, body)
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Title  string          `json:"title"`
+		Detail string          `json:"detail"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(body, &envelope)
+	reason := envelope.Detail
+	if reason == "" && len(envelope.Errors) > 0 {
+		reason = envelope.Errors[0].Message
+	}
+	if reason == "" {
+		reason = resp.Status
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Rate-Limit-Reset"), 10, 64); err == nil {
+			return fmt.Errorf("X asks to retry after %s: %w", time.Unix(reset, 0).UTC().Format(time.RFC3339), apperrors.ErrProviderRateLimited)
+		}
+		return fmt.Errorf("X throttled the request: %w", apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("X rejected the access token, %s: %w", reason, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusForbidden:
+		// X answers 403 for duplicate tweets and for apps without write
+		// access alike, so its reason is passed on
+		return fmt.Errorf("X refused, %s: %w", reason, apperrors.ErrForbidden)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("X refused the request, %s: %w", reason, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("X answered %s", reason)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to parse the response of X: %v", err)
+	}
+	return nil
+}
 
-	tweetURL := "https://api.twitter.com/1.1/statuses/update.json"
-	resp, err := client.PostForm(tweetURL, map[string][]string{"status": {message}})
+// uploadTweetImage uploads the image at imageURL to X and returns its media
+// id.
+func uploadTweetImage(userId string, config *oauth1.Config, userToken *oauth1.Token, imageURL string) (string, error) {
+	image, contentType, name, err := fetchCoverImage(imageURL)
 	if err != nil {
-		log.Printf("[ERROR] Failed to post tweet for the blog id : %s and the error is %s", blogId, err)
-		return err
+		return "", err
+	}
+	if len(image) > maxTweetImageSize {
+		return "", fmt.Errorf("the cover image is larger than the %d bytes X accepts", maxTweetImageSize)
+	}
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("media_category", "tweet_image")
+	writer.WriteField("media_type", contentType)
+	part, err := writer.CreateFormFile("media", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	part.Write(image)
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("Failed to post tweet: " + resp.Status)
+	req, err := http.NewRequest(http.MethodPost, twitterAPI+"/media/upload", &form)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	var media struct {
+		ID string `json:"id"`
+	}
+	if err := twitterCall(userId, config, userToken, req, &media); err != nil {
+		return "", fmt.Errorf("failed to upload the cover image: %w", err)
+	}
+	if media.ID == "" {
+		return "", errors.New("X didn't return the id of the uploaded cover image")
+	}
+	return media.ID, nil
+}
+
+// createTweet posts the tweet and returns its id, which is empty if X
+// didn't say.
+func createTweet(userId string, config *oauth1.Config, userToken *oauth1.Token, tweet map[string]interface{}) (string, error) {
+	payload, err := json.Marshal(tweet)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tweet: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, twitterAPI+"/tweets", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := twitterCall(userId, config, userToken, req, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// postTweetHandler posts the messages with the user's X app configuration,
+// each after the first as a reply to the one before, so several make a
+// thread. The cover image is attached to the first when there is one; a
+// cover that fails to upload leaves the tweet without it. It returns the
+// URL of the first tweet, which is empty if X didn't say where it lives.
+// A thread that fails halfway isn't taken down.
+func postTweetHandler(userId string, messages []string, blogId string, coverURL string, config *oauth1.Config, userToken *oauth1.Token) (string, error) {
+	var firstID, previousID string
+	for i, message := range messages {
+		tweet := map[string]interface{}{"text": message}
+		if i == 0 && coverURL != "" {
+			mediaID, err := uploadTweetImage(userId, config, userToken, coverURL)
+			switch {
+			case errors.Is(err, apperrors.ErrProviderAuth) || errors.Is(err, apperrors.ErrProviderRateLimited):
+				return "", err
+			case err != nil:
+				log.Printf("[WARN] Posting the tweet for blog %s without the cover image: %v", blogId, err)
+			default:
+				tweet["media"] = map[string][]string{"media_ids": {mediaID}}
+			}
+		}
+		if previousID != "" {
+			tweet["reply"] = map[string]string{"in_reply_to_tweet_id": previousID}
+		} else if i > 0 {
+			return "", fmt.Errorf("X didn't return the id of tweet %d, so the thread stops there", i)
+		}
+
+		id, err := createTweet(userId, config, userToken, tweet)
+		if err != nil {
+			log.Printf("[ERROR] Failed to post tweet for the blog id : %s and the error is %s", blogId, err)
+			if i > 0 {
+				// The tweets posted so far stay up
+				return "", fmt.Errorf("failed to post tweet %d of %d of the thread started at %s: %w", i+1, len(messages), tweetURL(firstID), err)
+			}
+			return "", fmt.Errorf("failed to post tweet: %w", err)
+		}
+		if i == 0 {
+			firstID = id
+		}
+		previousID = id
 	}
 
 	log.Printf("[INFO] Blog with ID %s shared on X(twitter) Successfully", blogId)
+	return tweetURL(firstID), nil
+}
+
+// tweetURL links the tweet. The v2 response doesn't name the account, and
+// X redirects this link to the tweet under it.
+func tweetURL(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "https://twitter.com/i/web/status/" + id
+}
+
+// SetXToken seals the user's X token. The caller saves the user.
+func SetXToken(user *models.User, token, secret string) error {
+	if err := SealCredential(user, &user.SealedXOAuthToken, &user.PlaintextXOAuthToken, token); err != nil {
+		return err
+	}
+	return SealCredential(user, &user.SealedXOAuthSecret, &user.PlaintextXOAuthSecret, secret)
+}
+
+// XToken opens the user's X token: the access token once X is connected,
+// the request token while it is being connected.
+func XToken(user *models.User) (*oauth1.Token, error) {
+	token, err := OpenCredential(user, user.SealedXOAuthToken, user.PlaintextXOAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := OpenCredential(user, user.SealedXOAuthSecret, user.PlaintextXOAuthSecret)
+	if err != nil {
+		return nil, err
+	}
+	return oauth1.NewToken(token, secret), nil
+}
+
+// RevokeTwitterToken invalidates the user's X access token. A token X no
+// longer accepts counts as revoked.
+func RevokeTwitterToken(user *models.User) error {
+	token, err := XToken(user)
+	if err != nil || token.Token == "" {
+		return err
+	}
+	config, err := TwitterConfigFor(user)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, twitterOAuthAPI+"/invalidate_token", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := twitterCall(user.Id.Hex(), config, token, req, nil); err != nil && !errors.Is(err, apperrors.ErrProviderAuth) {
+		return fmt.Errorf("failed to revoke the X token: %w", err)
+	}
 	return nil
 }
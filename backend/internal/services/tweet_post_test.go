@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dghubble/oauth1"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestPostTweet(t *testing.T) {
+	var uploaded string
+	var tweet struct {
+		Text  string `json:"text"`
+		Media struct {
+			MediaIDs []string `json:"media_ids"`
+		} `json:"media"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cover.png" && !strings.Contains(r.Header.Get("Authorization"), `oauth_token="token"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"title": "Unauthorized", "detail": "Unauthorized"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/cover.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG cover"))
+		case "/2/media/upload":
+			file, _, err := r.FormFile("media")
+			if err != nil || r.FormValue("media_category") != "tweet_image" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			file.Close()
+			uploaded = r.FormValue("media_type")
+			w.Write([]byte(`{"data": {"id": "1146654567674912769", "media_key": "3_1146654567674912769"}}`))
+		case "/2/tweets":
+			json.NewDecoder(r.Body).Decode(&tweet)
+			if tweet.Text == "again" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"title": "Forbidden", "detail": "You are not allowed to create a Tweet with duplicate content."}`))
+				return
+			}
+			if tweet.Text == "throttled" {
+				w.Header().Set("X-Rate-Limit-Reset", "1767225600")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data": {"id": "1445880548472328192", "text": "New post"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	previous := twitterAPI
+	twitterAPI = server.URL + "/2"
+	defer func() { twitterAPI = previous }()
+	previousCheck := checkImageHost
+	checkImageHost = func(string) error { return nil }
+	defer func() { checkImageHost = previousCheck }()
+
+	config := &oauth1.Config{ConsumerKey: "key", ConsumerSecret: "secret"}
+	token := oauth1.NewToken("token", "token-secret")
+	link, err := postTweetHandler("", []string{"New post"}, "blog-1", server.URL+"/cover.png", config, token)
+	if err != nil || link != "https://twitter.com/i/web/status/1445880548472328192" {
+		t.Fatalf("posted at %q, %v", link, err)
+	}
+	if uploaded != "image/png" || len(tweet.Media.MediaIDs) != 1 || tweet.Media.MediaIDs[0] != "1146654567674912769" {
+		t.Errorf("uploaded %q, tweeted %+v", uploaded, tweet)
+	}
+
+	tweet.Media.MediaIDs = nil
+	if _, err := postTweetHandler("", []string{"New post"}, "blog-1", server.URL+"/missing.png", config, token); err != nil || len(tweet.Media.MediaIDs) != 0 {
+		t.Errorf("a missing cover failed the tweet or was attached: %v, %+v", err, tweet)
+	}
+	_, err = postTweetHandler("", []string{"again"}, "blog-1", "", config, token)
+	if !errors.Is(err, apperrors.ErrForbidden) || !strings.Contains(err.Error(), "duplicate content") {
+		t.Errorf("a duplicate tweet failed with %v", err)
+	}
+	_, err = postTweetHandler("", []string{"throttled"}, "blog-1", "", config, token)
+	if !errors.Is(err, apperrors.ErrProviderRateLimited) || !strings.Contains(err.Error(), "2026-01-01T00:00:00Z") {
+		t.Errorf("a throttled tweet failed with %v", err)
+	}
+	if _, err := postTweetHandler("", []string{"New post"}, "blog-1", server.URL+"/cover.png", config, oauth1.NewToken("revoked", "secret")); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("tweeted with a revoked token: %v", err)
+	}
+}
+
+func TestRevokeTwitterToken(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/1.1/oauth/invalidate_token" {
+			http.NotFound(w, r)
+			return
+		}
+		token := r.Header.Get("Authorization")
+		if strings.Contains(token, `oauth_token="expired"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"code": 89, "message": "Invalid or expired token."}]}`))
+			return
+		}
+		if strings.Contains(token, `oauth_token="throttled"`) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		revoked = append(revoked, token)
+		w.Write([]byte(`{"access_token": "token"}`))
+	}))
+	defer server.Close()
+	previous := twitterOAuthAPI
+	twitterOAuthAPI = server.URL + "/1.1/oauth"
+	defer func() { twitterOAuthAPI = previous }()
+	previousConfig := twitterConfig
+	twitterConfig = &oauth1.Config{ConsumerKey: "key", ConsumerSecret: "secret"}
+	defer func() { twitterConfig = previousConfig }()
+
+	user := &models.User{Id: primitive.NewObjectID()}
+	if err := SetXToken(user, "token", "token-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RevokeTwitterToken(user); err != nil || len(revoked) != 1 || !strings.Contains(revoked[0], `oauth_token="token"`) {
+		t.Fatalf("revoked %q, %v", revoked, err)
+	}
+	// A token stored before tokens were sealed is revoked too
+	user.SealedXOAuthToken, user.PlaintextXOAuthToken = "", "expired"
+	if err := RevokeTwitterToken(user); err != nil {
+		t.Errorf("a token X no longer accepts failed to revoke: %v", err)
+	}
+	user.PlaintextXOAuthToken = "throttled"
+	if err := RevokeTwitterToken(user); !errors.Is(err, apperrors.ErrProviderRateLimited) {
+		t.Errorf("a throttled revocation returned %v", err)
+	}
+}
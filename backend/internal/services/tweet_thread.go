@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"social-scribe/backend/internal/apperrors"
+)
+
+const (
+	// MaxTweetLength is how long a tweet may be, as X weighs characters.
+	MaxTweetLength = 280
+	// MaxThreadTweets caps how many tweets a blog is split into.
+	MaxThreadTweets = 10
+	// tweetURLLength is how much X counts a link for once it is shortened.
+	tweetURLLength = 23
+	// threadCounterRoom is kept free in each tweet for its " 1/3" counter.
+	threadCounterRoom = 6
+)
+
+var tweetURLPattern = regexp.MustCompile(`https?://\S+`)
+
+// tweetLength is the length X counts for the text: links count as
+// shortened, and characters outside the Latin and common punctuation
+// ranges count twice, as in X's twitter-text rules.
+func tweetLength(text string) int {
+	length := 0
+	for _, url := range tweetURLPattern.FindAllString(text, -1) {
+		length += tweetURLLength - weighRunes(url)
+	}
+	return length + weighRunes(text)
+}
+
+func weighRunes(text string) int {
+	weight := 0
+	for _, r := range text {
+		switch {
+		case r <= 0x10FF, r >= 0x2000 && r <= 0x200D, r >= 0x2010 && r <= 0x201F, r >= 0x2032 && r <= 0x2037:
+			weight++
+		default:
+			weight += 2
+		}
+	}
+	return weight
+}
+
+// ValidateTweet checks that X takes the text as one tweet.
+func ValidateTweet(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("a tweet can't be empty: %w", apperrors.ErrInvalidInput)
+	}
+	if n := tweetLength(text); n > MaxTweetLength {
+		return fmt.Errorf("a tweet is %d characters long, over X's %d: %w", n, MaxTweetLength, apperrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// TweetThread splits the caption and the blog's brief into a thread of
+// numbered tweets, breaking between words, with the link to the blog in
+// the last tweet.
+func TweetThread(caption, brief, link string) ([]string, error) {
+	text := strings.TrimSpace(caption)
+	if brief = strings.TrimSpace(brief); brief != "" && !strings.Contains(text, brief) {
+		text += "\n\n" + brief
+	}
+	if link != "" {
+		text += "\n\n" + link
+	}
+
+	room := MaxTweetLength - threadCounterRoom
+	var tweets []string
+	var current strings.Builder
+	flush := func() {
+		if tweet := strings.TrimSpace(current.String()); tweet != "" {
+			tweets = append(tweets, tweet)
+		}
+		current.Reset()
+	}
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, word := range strings.Fields(paragraph) {
+			for _, piece := range splitLongWord(word, room) {
+				separator := " "
+				if current.Len() == 0 || strings.HasSuffix(current.String(), "\n") {
+					separator = ""
+				}
+				if tweetLength(current.String()+separator+piece) > room {
+					flush()
+					separator = ""
+				}
+				current.WriteString(separator + piece)
+			}
+		}
+		// Paragraphs stay apart within a tweet
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+	}
+	flush()
+
+	if len(tweets) > MaxThreadTweets {
+		return nil, fmt.Errorf("the thread would take %d tweets, over the %d allowed: %w", len(tweets), MaxThreadTweets, apperrors.ErrInvalidInput)
+	}
+	if len(tweets) > 1 {
+		for i := range tweets {
+			tweets[i] += fmt.Sprintf(" %d/%d", i+1, len(tweets))
+		}
+	}
+	for i, tweet := range tweets {
+		if err := ValidateTweet(tweet); err != nil {
+			return nil, fmt.Errorf("tweet %d of the thread: %w", i+1, err)
+		}
+	}
+	return tweets, nil
+}
+
+// splitLongWord cuts a word that doesn't fit a tweet on its own into pieces
+// that do. Links are never cut, since X counts them the same at any length.
+func splitLongWord(word string, room int) []string {
+	if tweetURLPattern.MatchString(word) || tweetLength(word) <= room {
+		return []string{word}
+	}
+	var pieces []string
+	for word != "" {
+		end, weight := 0, 0
+		for end < len(word) {
+			r, size := utf8.DecodeRuneInString(word[end:])
+			if weight+weighRunes(string(r)) > room {
+				break
+			}
+			weight += weighRunes(string(r))
+			end += size
+		}
+		pieces = append(pieces, word[:end])
+		word = word[end:]
+	}
+	return pieces
+}
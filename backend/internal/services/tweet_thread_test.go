@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dghubble/oauth1"
+	"social-scribe/backend/internal/apperrors"
+)
+
+func TestTweetLength(t *testing.T) {
+	for text, want := range map[string]int{
+		"Scheduling posts": 16,
+		"Read it: https://blog.example.com/" + strings.Repeat("a", 100): 9 + tweetURLLength,
+		"投稿の予約":           10,
+		"“Quoted” — dash": 15,
+	} {
+		if got := tweetLength(text); got != want {
+			t.Errorf("tweetLength(%q) = %d, want %d", text, got, want)
+		}
+	}
+	if err := ValidateTweet(strings.Repeat("投", 141)); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("took 282 weighted characters: %v", err)
+	}
+}
+
+func TestTweetThread(t *testing.T) {
+	link := "https://blog.example.com/scheduling?utm_source=twitter"
+	tweets, err := TweetThread("Short and sweet.", "", link)
+	if err != nil || len(tweets) != 1 || tweets[0] != "Short and sweet.\n\n"+link {
+		t.Fatalf("a short caption made %q, %v", tweets, err)
+	}
+
+	caption := strings.Repeat("Scheduling posts keeps a blog in front of readers. ", 8)
+	brief := strings.Repeat("The scheduler queues each share and retries the ones that fail. ", 4)
+	tweets, err = TweetThread(caption, brief, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tweets) < 3 {
+		t.Fatalf("split into %d tweets: %q", len(tweets), tweets)
+	}
+	var words []string
+	for i, tweet := range tweets {
+		counter := fmt.Sprintf(" %d/%d", i+1, len(tweets))
+		if tweetLength(tweet) > MaxTweetLength || !strings.HasSuffix(tweet, counter) {
+			t.Errorf("tweet %d is %d long: %q", i+1, tweetLength(tweet), tweet)
+		}
+		words = append(words, strings.Fields(strings.TrimSuffix(tweet, counter))...)
+	}
+	if strings.Join(words, " ") != strings.Join(strings.Fields(caption+brief+link), " ") {
+		t.Errorf("the thread lost words: %q", tweets)
+	}
+	if !strings.Contains(tweets[len(tweets)-1], link) {
+		t.Errorf("the link isn't in the last tweet: %q", tweets)
+	}
+
+	if _, err := TweetThread(strings.Repeat("word ", 1000), "", link); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("made a thread too long: %v", err)
+	}
+	tweets, err = TweetThread(strings.Repeat("a", 600), "", "")
+	if err != nil || len(tweets) != 3 {
+		t.Errorf("split a long word into %q, %v", tweets, err)
+	}
+}
+
+func TestPostTweetThread(t *testing.T) {
+	var replies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tweet struct {
+			Text  string `json:"text"`
+			Reply struct {
+				InReplyTo string `json:"in_reply_to_tweet_id"`
+			} `json:"reply"`
+		}
+		json.NewDecoder(r.Body).Decode(&tweet)
+		if tweet.Text == "broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		replies = append(replies, tweet.Reply.InReplyTo)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data": {"id": "%d", "text": %q}}`, 100+len(replies), tweet.Text)
+	}))
+	defer server.Close()
+	previous := twitterAPI
+	twitterAPI = server.URL + "/2"
+	defer func() { twitterAPI = previous }()
+
+	config := &oauth1.Config{ConsumerKey: "key", ConsumerSecret: "secret"}
+	token := oauth1.NewToken("token", "token-secret")
+	link, err := postTweetHandler("", []string{"one 1/3", "two 2/3", "three 3/3"}, "blog-1", "", config, token)
+	if err != nil || link != "https://twitter.com/i/web/status/101" {
+		t.Fatalf("posted at %q, %v", link, err)
+	}
+	if strings.Join(replies, ",") != ",101,102" {
+		t.Errorf("replied to %q", replies)
+	}
+
+	_, err = postTweetHandler("", []string{"one 1/2", "broken"}, "blog-1", "", config, token)
+	if err == nil || !strings.Contains(err.Error(), "tweet 2 of 2") || !strings.Contains(err.Error(), "status/104") {
+		t.Errorf("a thread failing halfway returned %v", err)
+	}
+}
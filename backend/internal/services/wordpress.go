@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+// maxCoverImageSize is the largest cover image sideloaded to WordPress.
+const maxCoverImageSize = 10 << 20
+
+// NormalizeWordPressSite reduces what a user typed for their site, such as
+// "Example.com/blog/", to the address its REST API hangs off. Only hosts
+// resolving to public addresses are accepted.
+func NormalizeWordPressSite(raw string) (string, error) {
+	return publicServers.normalizeWordPressSite(raw)
+}
+
+func (s userServers) normalizeWordPressSite(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" ||
+		parsed.RawQuery != "" || parsed.Fragment != "" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("site must be the https address of a WordPress site: %w", apperrors.ErrInvalidInput)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if err := s.checkHost(host); err != nil {
+		return "", fmt.Errorf("site must be a public WordPress site: %w", apperrors.ErrInvalidInput)
+	}
+	return s.scheme + "://" + host + strings.TrimRight(parsed.EscapedPath(), "/"), nil
+}
+
+// NormalizeWordPressPassword drops the spaces WordPress shows application
+// passwords with.
+func NormalizeWordPressPassword(raw string) (string, error) {
+	password := strings.ReplaceAll(strings.TrimSpace(raw), " ", "")
+	if len(password) != 24 {
+		return "", fmt.Errorf("password must be an application password from the profile page of the WordPress site: %w", apperrors.ErrInvalidInput)
+	}
+	return password, nil
+}
+
+// wordpressCall sends a request to the WordPress REST API of a site with the
+// application password and decodes its JSON response into out.
+func wordpressCall(userId, username, password string, req *http.Request, out interface{}) error {
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Accept", "application/json")
+	resp, err := getProviderClient(ProviderWordPress).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	archiveProviderResponse(userId, "wordpress", req.URL.String(), resp.StatusCode, body)
+	var failure struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &failure)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%s throttled the request: %w", req.URL.Host, apperrors.ErrProviderRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s refused the application password, %s: %w", req.URL.Host, failure.Code, apperrors.ErrProviderAuth)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s doesn't know it, %s: %w", req.URL.Host, failure.Code, apperrors.ErrNotFound)
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%s refused the request, %s: %w", req.URL.Host, failure.Message, apperrors.ErrInvalidInput)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse the response of %s: %v", req.URL.Host, err)
+	}
+	return nil
+}
+
+// LookupWordPressAccount checks the application password with the site and
+// returns the account it belongs to.
+func LookupWordPressAccount(userId, siteURL, username, password string) (*models.WordPressAccount, error) {
+	req, err := http.NewRequest(http.MethodGet, siteURL+"/wp-json/wp/v2/users/me?context=edit", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var me struct {
+		Username     string          `json:"username"`
+		Capabilities map[string]bool `json:"capabilities"`
+	}
+	if err := wordpressCall(userId, username, password, req, &me); err != nil {
+		return nil, fmt.Errorf("failed to look up the WordPress account: %w", err)
+	}
+	if !me.Capabilities["publish_posts"] || !me.Capabilities["upload_files"] {
+		return nil, fmt.Errorf("the WordPress account can't publish posts with images: %w", apperrors.ErrForbidden)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, siteURL+"/wp-json/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var site struct {
+		Name string `json:"name"`
+	}
+	if err := wordpressCall(userId, username, password, req, &site); err != nil {
+		return nil, fmt.Errorf("failed to look up the WordPress site: %w", err)
+	}
+	return &models.WordPressAccount{SiteURL: siteURL, SiteName: html.UnescapeString(site.Name), Username: me.Username}, nil
+}
+
+// wordpressPost is a post as created through the WordPress REST API.
+type wordpressPost struct {
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	Excerpt       string `json:"excerpt,omitempty"`
+	Status        string `json:"status"`
+	FeaturedMedia int    `json:"featured_media,omitempty"`
+}
+
+// newWordPressPost republishes a blog's HTML as a published post, ending
+// with a link to the original since WordPress has no canonical URL field.
+func newWordPressPost(title, content, brief, canonicalURL string) wordpressPost {
+	content += fmt.Sprintf("\n<p><em>Originally published at <a href=\"%s\">%s</a>.</em></p>", html.EscapeString(canonicalURL), html.EscapeString(canonicalURL))
+	return wordpressPost{Title: title, Content: content, Excerpt: brief, Status: "publish"}
+}
+
+// fetchCoverImage downloads the blog's cover image for sideloading.
+func fetchCoverImage(imageURL string) ([]byte, string, string, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, "", "", fmt.Errorf("cover image %q is not a web address", imageURL)
+	}
+	if err := checkImageHost(parsed.Hostname()); err != nil {
+		return nil, "", "", err
+	}
+	// The image ends up readable in the media library, so a redirect to an
+	// internal address must not be followed either
+	resp, err := imageClient().Get(imageURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to download the cover image: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("the cover image answered %s", resp.Status)
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", "", fmt.Errorf("the cover image is %q, not an image", contentType)
+	}
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverImageSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to download the cover image: %v", err)
+	}
+	if len(image) > maxCoverImageSize {
+		return nil, "", "", fmt.Errorf("the cover image is larger than %d bytes", maxCoverImageSize)
+	}
+	name := path.Base(parsed.Path)
+	if name == "." || name == "/" || !strings.Contains(name, ".") {
+		extensions, _ := mime.ExtensionsByType(contentType)
+		name = "cover"
+		if len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+	return image, contentType, name, nil
+}
+
+// sideloadWordPressImage uploads an image to the site's media library and
+// returns its id.
+func sideloadWordPressImage(userId string, account *models.WordPressAccount, password, imageURL string) (int, error) {
+	image, contentType, name, err := fetchCoverImage(imageURL)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, account.SiteURL+"/wp-json/wp/v2/media", bytes.NewReader(image))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	var media struct {
+		ID int `json:"id"`
+	}
+	if err := wordpressCall(userId, account.Username, password, req, &media); err != nil {
+		return 0, fmt.Errorf("failed to upload the cover image: %w", err)
+	}
+	return media.ID, nil
+}
+
+// publishWordPressPost publishes the post to the user's WordPress site, or
+// updates it when the blog was published there before. The cover image is
+// sideloaded as the featured image of a new post; a cover that fails to
+// load leaves the post without one. It returns the post's id and URL.
+func publishWordPressPost(user *models.User, postID int, post wordpressPost, coverURL string) (int, string, error) {
+	account := user.WordPress
+	if account == nil {
+		return 0, "", fmt.Errorf("WordPress is not connected: %w", apperrors.ErrInvalidInput)
+	}
+	userId := user.Id.Hex()
+	password, err := OpenUserSecret(user, account.SealedPassword)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open the WordPress application password: %v", err)
+	}
+	if postID == 0 && coverURL != "" {
+		post.FeaturedMedia, err = sideloadWordPressImage(userId, account, password, coverURL)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrProviderAuth) {
+				return 0, "", err
+			}
+			log.Printf("[WARN] Publishing to WordPress for user %s without the cover image: %v", userId, err)
+		}
+	}
+	payload, err := json.Marshal(post)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to marshal post: %v", err)
+	}
+	endpoint := account.SiteURL + "/wp-json/wp/v2/posts"
+	if postID != 0 {
+		endpoint += "/" + strconv.Itoa(postID)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var published struct {
+		ID   int    `json:"id"`
+		Link string `json:"link"`
+	}
+	err = wordpressCall(userId, account.Username, password, req, &published)
+	if errors.Is(err, apperrors.ErrNotFound) && postID != 0 {
+		// The copy was deleted on WordPress, so it is published anew
+		return publishWordPressPost(user, 0, post, coverURL)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return published.ID, published.Link, nil
+}
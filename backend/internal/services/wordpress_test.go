@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestNormalizeWordPressSite(t *testing.T) {
+	servers := userServers{scheme: "https", checkHost: func(string) error { return nil }}
+
+	for raw, want := range map[string]string{
+		"Example.com":                 "https://example.com",
+		" https://example.com/blog/ ": "https://example.com/blog",
+		"http://example.com":          "",
+		"https://example.com:8443":    "",
+		"https://example.com/?p=1":    "",
+	} {
+		got, err := servers.normalizeWordPressSite(raw)
+		if want == "" {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("NormalizeWordPressSite(%q) = %q, %v; want invalid input", raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizeWordPressSite(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if password, err := NormalizeWordPressPassword("abcd EFGH 1234 ijkl MNOP 5678"); err != nil || password != "abcdEFGH1234ijklMNOP5678" {
+		t.Errorf("normalized the password to %q, %v", password, err)
+	}
+}
+
+func TestPublishWordPressPost(t *testing.T) {
+	t.Setenv("APP_CREDENTIALS_SECRETS", "key")
+	const password = "abcdEFGH1234ijklMNOP5678"
+	var uploaded, filename string
+	var posted []wordpressPost
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cover.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG cover"))
+			return
+		}
+		if username, pass, _ := r.BasicAuth(); username != "ada" || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": "incorrect_password", "message": "The provided password is an invalid application password."}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/blog/wp-json/wp/v2/users/me":
+			w.Write([]byte(`{"id": 1, "username": "ada", "capabilities": {"publish_posts": true, "upload_files": true}}`))
+		case "/blog/wp-json/":
+			w.Write([]byte(`{"name": "Ada &amp; Friends"}`))
+		case "/blog/wp-json/wp/v2/media":
+			body, _ := io.ReadAll(r.Body)
+			uploaded = r.Header.Get("Content-Type") + " " + string(body)
+			filename = r.Header.Get("Content-Disposition")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 31}`))
+		case "/blog/wp-json/wp/v2/posts", "/blog/wp-json/wp/v2/posts/77":
+			var post wordpressPost
+			json.NewDecoder(r.Body).Decode(&post)
+			posted = append(posted, post)
+			if strings.HasSuffix(r.URL.Path, "/posts") {
+				w.WriteHeader(http.StatusCreated)
+			}
+			w.Write([]byte(`{"id": 77, "link": "https://example.com/blog/scheduling-posts/"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": "rest_post_invalid_id", "message": "Invalid post ID."}`))
+		}
+	}))
+	defer server.Close()
+	previous := checkImageHost
+	checkImageHost = func(string) error { return nil }
+	defer func() { checkImageHost = previous }()
+	siteURL := server.URL + "/blog"
+
+	if _, err := LookupWordPressAccount("", siteURL, "ada", "wrong"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("looked up with a wrong password: %v", err)
+	}
+	account, err := LookupWordPressAccount("", siteURL, "ada", password)
+	if err != nil || account.SiteName != "Ada & Friends" || account.Username != "ada" {
+		t.Fatalf("looked up %+v, %v", account, err)
+	}
+	user := &models.User{Id: primitive.NewObjectID(), WordPress: account}
+	if account.SealedPassword, err = SealUserSecret(user, password); err != nil {
+		t.Fatal(err)
+	}
+
+	post := newWordPressPost("Scheduling posts", "<p>Hello</p>", "A brief", "https://blog.example.com/scheduling")
+	postID, link, err := publishWordPressPost(user, 0, post, server.URL+"/cover.png")
+	if err != nil || postID != 77 || link != "https://example.com/blog/scheduling-posts/" {
+		t.Fatalf("published %d at %q, %v", postID, link, err)
+	}
+	if uploaded != "image/png \x89PNG cover" || filename != `attachment; filename=cover.png` {
+		t.Errorf("sideloaded %q as %q", uploaded, filename)
+	}
+	if posted[0].FeaturedMedia != 31 || !strings.Contains(posted[0].Content, `Originally published at <a href="https://blog.example.com/scheduling">`) {
+		t.Errorf("posted %+v", posted[0])
+	}
+
+	// Sharing again updates the copy without uploading the cover again
+	uploaded = ""
+	if postID, _, err = publishWordPressPost(user, postID, post, server.URL+"/cover.png"); err != nil || postID != 77 || uploaded != "" {
+		t.Errorf("updated %d, uploaded %q, %v", postID, uploaded, err)
+	}
+
+	// A copy deleted on WordPress is published anew, and a cover that fails
+	// to load leaves the post without one
+	postID, _, err = publishWordPressPost(user, 12, post, server.URL+"/missing.png")
+	if err != nil || postID != 77 || posted[len(posted)-1].FeaturedMedia != 0 {
+		t.Errorf("republished %d with %+v, %v", postID, posted[len(posted)-1], err)
+	}
+}
+
+func TestFetchCoverImageRejectsInternalRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.0.0.5/admin/export", http.StatusFound)
+	}))
+	defer server.Close()
+	previous := checkImageHost
+	checkImageHost = func(host string) error {
+		if host == "127.0.0.1" {
+			return nil
+		}
+		return checkPublicHost(host)
+	}
+	defer func() { checkImageHost = previous }()
+
+	if _, _, _, err := fetchCoverImage(server.URL + "/cover.png"); err == nil {
+		t.Fatal("sideloaded a redirect to an internal address")
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/utils"
+)
+
+// YouTubeScopes only let SocialScribe read which channel the user owns: the
+// YouTube Data API has no way to create community posts, so nothing is
+// written with the token.
+var YouTubeScopes = []string{"https://www.googleapis.com/auth/youtube.readonly"}
+
+// youtubeAPI is the YouTube Data API, replaced by tests.
+var youtubeAPI = "https://www.googleapis.com/youtube/v3"
+
+// YouTubeContext carries the client to use for Google's token endpoint
+// when connecting a channel.
+func YouTubeContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, getProviderClient(ProviderYouTube))
+}
+
+// LookupYouTubeChannel finds the channel of the access token's user.
+func LookupYouTubeChannel(userId, accessToken string) (*models.YouTubeChannel, error) {
+	req, err := http.NewRequest(http.MethodGet, youtubeAPI+"/channels?part=snippet&mine=true", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	var channels struct {
+		Items []struct {
+			Id      string `json:"id"`
+			Snippet struct {
+				Title     string `json:"title"`
+				CustomURL string `json:"customUrl"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := googleCall(ProviderYouTube, userId, accessToken, req, &channels); err != nil {
+		return nil, fmt.Errorf("failed to look up the YouTube channel: %w", err)
+	}
+	if len(channels.Items) == 0 {
+		return nil, fmt.Errorf("the Google account has no YouTube channel: %w", apperrors.ErrInvalidInput)
+	}
+	channel := channels.Items[0]
+	return &models.YouTubeChannel{
+		ChannelID:   channel.Id,
+		Title:       channel.Snippet.Title,
+		Handle:      channel.Snippet.CustomURL,
+		ConnectedAt: utils.Now(),
+	}, nil
+}
+
+// YouTubeCommunityPost is the text of a community post for a blog. Links in
+// community posts are clickable, so the blog's link goes at the end.
+func YouTubeCommunityPost(caption, link string) string {
+	return strings.TrimSpace(caption) + "\n\n" + link
+}
+
+// makeYouTubeKit stores the community post of a blog as a manual task for
+// the user to post on their channel, and reminds them as Instagram kits do.
+func makeYouTubeKit(user *models.User, share *Share) (string, error) {
+	link := share.Link("youtube")
+	task := models.ManualTask{
+		UserID:    user.Id.Hex(),
+		Platform:  "youtube",
+		BlogID:    share.BlogID,
+		BlogTitle: share.Title,
+		BlogURL:   link,
+		Caption:   YouTubeCommunityPost(share.Caption, link),
+		Status:    models.ManualTaskPending,
+		CreatedAt: utils.Now(),
+	}
+	if _, err := repositories.CreateManualTask(task); err != nil {
+		return "", fmt.Errorf("failed to store the YouTube community post: %w", err)
+	}
+	channel := ""
+	if user.YouTube != nil {
+		channel = " on " + user.YouTube.Title
+	}
+	user.AddNotification(fmt.Sprintf("Time to post %q to your YouTube community tab%s: its text is ready in your manual tasks", share.Title, channel), task.CreatedAt)
+	SendManualTaskEmail(user, &task, "YouTube")
+	log.Printf("[INFO] YouTube community post of blog %s is ready for user %s", share.BlogID, task.UserID)
+	return "", nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"social-scribe/backend/internal/apperrors"
+	"social-scribe/backend/internal/models"
+)
+
+func TestLookupYouTubeChannel(t *testing.T) {
+	empty := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials."}}`))
+			return
+		}
+		if r.URL.Path != "/youtube/v3/channels" || r.URL.Query().Get("mine") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		if empty {
+			w.Write([]byte(`{"kind": "youtube#channelListResponse", "items": []}`))
+			return
+		}
+		w.Write([]byte(`{"items": [{"id": "UC123", "snippet": {"title": "Ada Codes", "customUrl": "@adacodes"}}]}`))
+	}))
+	defer server.Close()
+	previous := youtubeAPI
+	youtubeAPI = server.URL + "/youtube/v3"
+	defer func() { youtubeAPI = previous }()
+
+	channel, err := LookupYouTubeChannel("user-1", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.ChannelID != "UC123" || channel.Title != "Ada Codes" || channel.Handle != "@adacodes" {
+		t.Errorf("channel = %+v", channel)
+	}
+	if _, err := LookupYouTubeChannel("user-1", "expired"); !errors.Is(err, apperrors.ErrProviderAuth) {
+		t.Errorf("an expired token gave %v", err)
+	}
+	empty = true
+	if _, err := LookupYouTubeChannel("user-1", "access"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("an account without a channel gave %v", err)
+	}
+}
+
+func TestYouTubeManualTaskEmail(t *testing.T) {
+	link := "https://blog.example.com/queues?utm_source=youtube"
+	task := &models.ManualTask{BlogTitle: "Queues", BlogURL: link, Caption: YouTubeCommunityPost(" New post on queues ", link)}
+	if task.Caption != "New post on queues\n\n"+link {
+		t.Errorf("community post = %q", task.Caption)
+	}
+	_, body, err := RenderManualTaskEmail(task, "YouTube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, task.Caption) || strings.Contains(body, "bio") || strings.Contains(body, "card image") {
+		t.Errorf("the reminder of a text-only task reads %q", body)
+	}
+}
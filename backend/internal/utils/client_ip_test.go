@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{name: "direct client", remote: "198.51.100.4:5123", want: "198.51.100.4"},
+		{name: "spoofed header from a client", remote: "198.51.100.4:5123", forwarded: "203.0.113.9", want: "198.51.100.4"},
+		{name: "through a trusted proxy", remote: "10.1.2.3:443", forwarded: "203.0.113.9", want: "203.0.113.9"},
+		{name: "spoofed entry before the proxy's", remote: "10.1.2.3:443", forwarded: "1.2.3.4, 203.0.113.9", want: "203.0.113.9"},
+		{name: "chain of trusted proxies", remote: "192.0.2.1:443", forwarded: "203.0.113.9, 10.4.4.4", want: "203.0.113.9"},
+		{name: "trusted proxy without a header", remote: "10.1.2.3:443", want: "10.1.2.3"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/login", nil)
+		r.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if got := TrustedClientIP(r); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source used by the scheduler, session expiry and OTP TTLs
+// so that tests can control time instead of relying on time.Now().
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer mirrors the subset of *time.Timer used by the scheduler.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return &systemTimer{t: time.NewTimer(d)} }
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (st *systemTimer) C() <-chan time.Time        { return st.t.C }
+func (st *systemTimer) Stop() bool                 { return st.t.Stop() }
+func (st *systemTimer) Reset(d time.Duration) bool { return st.t.Reset(d) }
+
+// SystemClock is the real wall clock.
+var SystemClock Clock = systemClock{}
+
+var (
+	clockMu      sync.RWMutex
+	currentClock = SystemClock
+)
+
+// SetClock replaces the package-wide clock, returning the previous one so
+// tests can restore it.
+func SetClock(c Clock) Clock {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	previous := currentClock
+	currentClock = c
+	return previous
+}
+
+// GetClock returns the package-wide clock.
+func GetClock() Clock {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return currentClock
+}
+
+// Now returns the current time according to the package-wide clock.
+func Now() time.Time {
+	return GetClock().Now()
+}
+
+// FakeClock is a manually advanced Clock for tests. Timers fire synchronously
+// from Advance once their deadline has been reached.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *FakeClock) NewTimer(d time.Duration) Timer {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	ft := &fakeTimer{clock: fc, ch: make(chan time.Time, 1), deadline: fc.now.Add(d), active: true}
+	fc.timers = append(fc.timers, ft)
+	fc.fireLocked()
+	return ft
+}
+
+// Advance moves the clock forward and fires every timer that became due.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+	fc.fireLocked()
+}
+
+// Set moves the clock to an absolute time and fires every due timer.
+func (fc *FakeClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = t
+	fc.fireLocked()
+}
+
+// ActiveTimers counts the timers waiting to fire, so a test can wait for a
+// goroutine to arm its timer before advancing the clock.
+func (fc *FakeClock) ActiveTimers() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	active := 0
+	for _, ft := range fc.timers {
+		if ft.active {
+			active++
+		}
+	}
+	return active
+}
+
+func (fc *FakeClock) fireLocked() {
+	for _, ft := range fc.timers {
+		if ft.active && !ft.deadline.After(fc.now) {
+			ft.active = false
+			select {
+			case ft.ch <- fc.now:
+			default:
+			}
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func (ft *fakeTimer) C() <-chan time.Time { return ft.ch }
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	wasActive := ft.active
+	ft.active = false
+	return wasActive
+}
+
+func (ft *fakeTimer) Reset(d time.Duration) bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	wasActive := ft.active
+	ft.deadline = ft.clock.now.Add(d)
+	ft.active = true
+	ft.clock.fireLocked()
+	return wasActive
+}
@@ -9,6 +9,10 @@ type contextKey string
 
 const userIDKey contextKey = "userID"
 
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
 func GetUserID(ctx context.Context) (string, error) {
 	userID, ok := ctx.Value(userIDKey).(string)
 	if !ok {
@@ -16,3 +20,16 @@ func GetUserID(ctx context.Context) (string, error) {
 	}
 	return userID, nil
 }
+
+const requestIDKey contextKey = "requestID"
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// GetRequestID returns the request ID assigned by the request ID middleware,
+// or an empty string outside of a request.
+func GetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|authorization|email|cookie|key|otp)`)
+
+// sensitiveParamPattern matches the query and form parameters that carry OAuth
+// codes, states and verifiers, on top of the keys IsSensitiveKey matches.
+var sensitiveParamPattern = regexp.MustCompile(`(?i)^(code|state|sig|signature)$|verifier`)
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+
+// IsSensitiveKey reports whether a JSON field or header name is likely to hold
+// a credential or personal data.
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeyPattern.MatchString(key)
+}
+
+// RedactBody masks sensitive JSON fields and bearer tokens in a payload and
+// truncates the result to maxSize bytes. Non-JSON payloads only get bearer
+// tokens masked.
+func RedactBody(body []byte, maxSize int) string {
+	redacted, ok := redactJSON(body)
+	if !ok {
+		redacted = bearerPattern.ReplaceAllString(string(body), "${1}[REDACTED]")
+	}
+	return truncateRedacted(redacted, maxSize)
+}
+
+// RedactCapturedBody masks sensitive fields of a whole JSON or form-encoded
+// body and truncates the result to maxSize bytes. Other bodies are dropped,
+// since nothing in them can be masked reliably.
+func RedactCapturedBody(body []byte, contentType string, maxSize int) string {
+	if strings.TrimSpace(string(body)) == "" {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		return truncateRedacted(RedactQuery(string(body)), maxSize)
+	}
+	redacted, ok := redactJSON(body)
+	if !ok {
+		return "[body dropped: not JSON or form-encoded]"
+	}
+	return truncateRedacted(redacted, maxSize)
+}
+
+// RedactQuery masks the values of sensitive parameters in a URL query or
+// form-encoded body, keeping the parameters in order. A query that doesn't
+// parse is dropped.
+func RedactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if _, err := url.ParseQuery(raw); err != nil {
+		return "[query dropped: unparseable]"
+	}
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		key, _ := url.QueryUnescape(name)
+		if IsSensitiveKey(key) || sensitiveParamPattern.MatchString(key) {
+			params[i] = name + "=[REDACTED]"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// pathVarPattern matches a variable of a route template, such as {id} or
+// {id:[0-9]+}.
+var pathVarPattern = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*)?\}`)
+
+// RedactPath fills the route template in with the request's path variables,
+// masking those whose names mark a credential or personal data, such as an
+// email or a token.
+func RedactPath(template string, vars map[string]string) string {
+	return pathVarPattern.ReplaceAllStringFunc(template, func(variable string) string {
+		name := pathVarPattern.FindStringSubmatch(variable)[1]
+		if IsSensitiveKey(name) || sensitiveParamPattern.MatchString(name) {
+			return "[REDACTED]"
+		}
+		return url.PathEscape(vars[name])
+	})
+}
+
+func redactJSON(body []byte) (string, bool) {
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", false
+	}
+	out, err := json.Marshal(redactValue(parsed))
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+func truncateRedacted(redacted string, maxSize int) string {
+	if maxSize > 0 && len(redacted) > maxSize {
+		redacted = redacted[:maxSize] + "...[truncated]"
+	}
+	return strings.ToValidUTF8(redacted, "")
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if IsSensitiveKey(key) {
+				v[key] = "[REDACTED]"
+				continue
+			}
+			v[key] = redactValue(inner)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+		return v
+	case string:
+		return bearerPattern.ReplaceAllString(v, "${1}[REDACTED]")
+	default:
+		return v
+	}
+}
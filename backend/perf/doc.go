@@ -0,0 +1,18 @@
+// Package perf holds the share and schedule pipeline benchmarks and the
+// performance regression suite. Provider calls go to in-process fake
+// connectors, so the numbers cover our handlers, services and MongoDB only.
+//
+// Everything needs a MongoDB to write to and is skipped unless
+// MONGO_TEST_URI is set; each run uses, then drops, its own database.
+//
+//	# fail the build when p95 latency or allocations exceed budgets.json
+//	MONGO_TEST_URI=mongodb://localhost:27017 go test ./perf -run Budget -v
+//
+//	# benchmarks, reporting allocations and p95 latency per endpoint
+//	MONGO_TEST_URI=mongodb://localhost:27017 go test ./perf -run '^$' -bench . -benchmem
+//
+// PERF_REQUESTS (default 200) and PERF_CONCURRENCY (default 4) size the
+// budget run, and PERF_CONNECTOR_LATENCY (e.g. 20ms) delays every fake
+// provider response. The budgets started out generous; tighten them from
+// CI results as the numbers settle.
+package perf
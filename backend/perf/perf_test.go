@@ -0,0 +1,385 @@
+package perf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"social-scribe/backend/internal/handlers"
+	"social-scribe/backend/internal/models"
+	"social-scribe/backend/internal/providertest"
+	repo "social-scribe/backend/internal/repositories"
+	"social-scribe/backend/internal/scheduler"
+	"social-scribe/backend/internal/services"
+	"social-scribe/backend/internal/utils"
+)
+
+var (
+	// connected is false when MONGO_TEST_URI isn't set and everything skips
+	connected  bool
+	api        *handlers.Handlers
+	connectors = &providertest.Transport{}
+	userSeq    atomic.Int64
+	scheduleID atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		os.Exit(m.Run())
+	}
+
+	if latency := os.Getenv("PERF_CONNECTOR_LATENCY"); latency != "" {
+		d, err := time.ParseDuration(latency)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid PERF_CONNECTOR_LATENCY %q: %v\n", latency, err)
+			os.Exit(2)
+		}
+		connectors.Latency = d
+	}
+
+	dbName := fmt.Sprintf("social-scribe-perf-%d", time.Now().UnixNano())
+	if err := repo.Connect(uri, dbName); err != nil {
+		fmt.Fprintf(os.Stderr, "connecting to %s: %v\n", uri, err)
+		os.Exit(2)
+	}
+	services.SetProviderTransport(connectors)
+	// The credentials of the users are sealed
+	os.Setenv("APP_CREDENTIALS_SECRETS", "perf-key")
+	api = handlers.New(handlers.Deps{Scheduler: scheduler.NewScheduler()})
+	connected = true
+
+	// Every request logs; keep benchmark output readable
+	log.SetOutput(io.Discard)
+	code := m.Run()
+	log.SetOutput(os.Stderr)
+
+	if err := repo.Disconnect(dbName); err != nil {
+		log.Printf("[WARN] Failed to drop perf database %s: %v", dbName, err)
+	}
+	os.Exit(code)
+}
+
+func requireMongo(tb testing.TB) {
+	tb.Helper()
+	if !connected {
+		tb.Skip("MONGO_TEST_URI not set")
+	}
+}
+
+// newPerfUser creates a verified user connected to LinkedIn and X.
+func newPerfUser(tb testing.TB) string {
+	tb.Helper()
+	now := utils.Now()
+	user := models.User{
+		Id:               primitive.NewObjectID(),
+		UserName:         fmt.Sprintf("perf-%d-%d", now.UnixNano(), userSeq.Add(1)),
+		PassWord:         "unused",
+		Verified:         true,
+		EmailVerified:    true,
+		HashnodeVerified: true,
+		LinkedinVerified: true,
+		XVerified:        true,
+		Plan:             models.PlanFree,
+		Region:           models.RegionDefault,
+		CreatedAt:        now,
+		LastActiveAt:     now,
+	}
+	if err := services.SetXToken(&user, "perf-x-token", "perf-x-secret"); err != nil {
+		tb.Fatal(err)
+	}
+	if err := services.SealCredential(&user, &user.SealedLinkedInOauthKey, &user.PlaintextLinkedInOauthKey, "perf-linkedin-token"); err != nil {
+		tb.Fatal(err)
+	}
+	id, err := repo.CreateUser(user)
+	if err != nil {
+		tb.Fatalf("creating perf user: %v", err)
+	}
+	return id
+}
+
+// serve calls the handler the way AuthMiddleware would after a successful
+// login. Anything but 200 is an error.
+func serve(handler http.HandlerFunc, userID, path string, body []byte) error {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req = req.WithContext(repo.WithRequestUserCache(utils.WithUserID(req.Context(), userID)))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", path, rec.Code, rec.Body.String())
+	}
+	return nil
+}
+
+var shareBody = []byte(`{"id":"` + providertest.PostID + `","platforms":["linkedin","twitter"]}`)
+
+func share(userID string) error {
+	return serve(api.ShareBlogHandler, userID, "/user/share", shareBody)
+}
+
+// schedule schedules a new blog six days out, inside the seven day limit.
+func schedule(userID string) error {
+	data := models.ScheduledBlogData{
+		ScheduledBlog: models.ScheduledBlog{
+			Blog: models.Blog{
+				Id:                fmt.Sprintf("perf-scheduled-%d", scheduleID.Add(1)),
+				Title:             "Benchmarking the share pipeline",
+				Url:               "https://blog.example.com/perf",
+				CoverImage:        models.Image{URL: "https://cdn.example.com/cover.png"},
+				Author:            models.Author{Name: "Perf Author"},
+				ReadTimeInMinutes: 4,
+			},
+			Platforms:     []string{"linkedin", "twitter"},
+			ScheduledTime: utils.Now().Add(6 * 24 * time.Hour).Truncate(time.Second),
+		},
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return serve(api.ScheduleBlogHandler, userID, "/user/schedule", body)
+}
+
+// resetSchedules empties the user's schedule so the document, and the cost
+// of saving it, doesn't grow with the number of requests.
+func resetSchedules(userID string) error {
+	user, err := repo.GetUserById(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("perf user %s not found", userID)
+	}
+	user.ScheduledBlogs = nil
+	return repo.UpdateUser(userID, user)
+}
+
+func p95(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func BenchmarkShareEndpoint(b *testing.B) {
+	requireMongo(b)
+	userID := newPerfUser(b)
+	if err := share(userID); err != nil {
+		b.Fatal(err)
+	}
+
+	latencies := make([]time.Duration, 0, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		if err := share(userID); err != nil {
+			b.Fatal(err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	b.ReportMetric(milliseconds(p95(latencies)), "p95-ms")
+}
+
+func BenchmarkScheduleEndpoint(b *testing.B) {
+	requireMongo(b)
+	userID := newPerfUser(b)
+	if err := schedule(userID); err != nil {
+		b.Fatal(err)
+	}
+
+	latencies := make([]time.Duration, 0, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := resetSchedules(userID); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		start := time.Now()
+		if err := schedule(userID); err != nil {
+			b.Fatal(err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	b.ReportMetric(milliseconds(p95(latencies)), "p95-ms")
+}
+
+type budget struct {
+	P95Ms       float64 `json:"p95_ms"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+}
+
+func loadBudgets(t *testing.T) map[string]budget {
+	t.Helper()
+	raw, err := os.ReadFile("budgets.json")
+	if err != nil {
+		t.Fatalf("reading budgets: %v", err)
+	}
+	var budgets map[string]budget
+	if err := json.Unmarshal(raw, &budgets); err != nil {
+		t.Fatalf("parsing budgets.json: %v", err)
+	}
+	return budgets
+}
+
+func envInt(t *testing.T, name string, fallback int) int {
+	t.Helper()
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		t.Fatalf("%s must be a positive integer, got %q", name, value)
+	}
+	return n
+}
+
+// endpointScenario drives one endpoint for the budget run. after runs
+// outside the measurement, once per request.
+type endpointScenario struct {
+	name  string
+	call  func(userID string) error
+	after func(userID string) error
+}
+
+// once sends one request and runs the scenario's cleanup, returning how long
+// the request took.
+func (s endpointScenario) once(userID string) (time.Duration, error) {
+	start := time.Now()
+	if err := s.call(userID); err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+	if s.after != nil {
+		if err := s.after(userID); err != nil {
+			return 0, err
+		}
+	}
+	return elapsed, nil
+}
+
+// run sends requests spread over concurrency workers, one user per worker,
+// and returns every request's latency.
+func (s endpointScenario) run(t *testing.T, requests, concurrency int) []time.Duration {
+	users := make([]string, concurrency)
+	for i := range users {
+		users[i] = newPerfUser(t)
+		// Warm up: the first share also creates the shared blog entry
+		if _, err := s.once(users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, requests)
+		firstErr  error
+		next      atomic.Int64
+		wg        sync.WaitGroup
+	)
+	for _, userID := range users {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			for next.Add(1) <= int64(requests) {
+				elapsed, err := s.once(userID)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				latencies = append(latencies, elapsed)
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}(userID)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		t.Fatal(firstErr)
+	}
+	return latencies
+}
+
+// allocsPerRequest averages heap allocations over sequential requests,
+// counting only the requests themselves.
+func (s endpointScenario) allocsPerRequest(t *testing.T, runs int) float64 {
+	userID := newPerfUser(t)
+	if _, err := s.once(userID); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	var total uint64
+	for i := 0; i < runs; i++ {
+		runtime.ReadMemStats(&before)
+		err := s.call(userID)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += after.Mallocs - before.Mallocs
+		if s.after != nil {
+			if err := s.after(userID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return float64(total) / float64(runs)
+}
+
+func TestSharePipelineBudgets(t *testing.T) {
+	requireMongo(t)
+	budgets := loadBudgets(t)
+	requests := envInt(t, "PERF_REQUESTS", 200)
+	concurrency := envInt(t, "PERF_CONCURRENCY", 4)
+
+	scenarios := []endpointScenario{
+		{name: "share", call: share},
+		{name: "schedule", call: schedule, after: resetSchedules},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			limits, ok := budgets[s.name]
+			if !ok {
+				t.Fatalf("budgets.json has no entry for %s", s.name)
+			}
+
+			latency := milliseconds(p95(s.run(t, requests, concurrency)))
+			allocs := s.allocsPerRequest(t, 50)
+			t.Logf("%s: p95 %.2fms (budget %.2fms), %.0f allocs/op (budget %.0f) over %d requests at concurrency %d",
+				s.name, latency, limits.P95Ms, allocs, limits.AllocsPerOp, requests, concurrency)
+
+			if latency > limits.P95Ms {
+				t.Errorf("%s p95 latency %.2fms exceeds the %.2fms budget", s.name, latency, limits.P95Ms)
+			}
+			if allocs > limits.AllocsPerOp {
+				t.Errorf("%s allocations %.0f/op exceed the %.0f budget", s.name, allocs, limits.AllocsPerOp)
+			}
+		})
+	}
+}
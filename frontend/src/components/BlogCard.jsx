@@ -18,6 +18,7 @@ import {
 import AccessTimeIcon from '@mui/icons-material/AccessTime';
 import CloseIcon from '@mui/icons-material/Close';
 import { toast } from 'react-toastify';
+import { csrfHeaders } from '../utils/csrf';
 import dayjs from 'dayjs';
 import utc from 'dayjs/plugin/utc';
 dayjs.extend(utc);
@@ -54,7 +55,7 @@ const BlogCard = ({ blog }) => {
     try {
       const response = await fetch("http://localhost:9696/api/v1/blogs/user/share", {
         method: "POST",
-        headers: { "Content-Type": "application/json" },
+        headers: { "Content-Type": "application/json", ...(await csrfHeaders()) },
         credentials: "include",
         body: JSON.stringify({ id: blog.id, platforms }),
       });
@@ -113,7 +114,7 @@ const BlogCard = ({ blog }) => {
 
       const response = await fetch("http://localhost:9696/api/v1/blogs/schedule", {
         method: "POST",
-        headers: { "Content-Type": "application/json" },
+        headers: { "Content-Type": "application/json", ...(await csrfHeaders()) },
         credentials: "include",
         body: JSON.stringify(payload),
       });
@@ -136,7 +137,7 @@ const BlogCard = ({ blog }) => {
     try {
       const response = await fetch("http://localhost:9696/api/v1/user/scheduled-blogs/cancel", {
         method: "DELETE",
-        headers: { "Content-Type": "application/json" },
+        headers: { "Content-Type": "application/json", ...(await csrfHeaders()) },
         credentials: "include",
         body: JSON.stringify(payload),
       });
@@ -1,11 +1,12 @@
 import React, { useState } from 'react';
-import { Modal, Box, Typography, TextField, Button, IconButton } from '@mui/material';
+import { Modal, Box, Typography, TextField, Button, IconButton, Checkbox, FormControlLabel } from '@mui/material';
 import CloseIcon from '@mui/icons-material/Close';
 import { useNavigate } from 'react-router-dom';
 
 const Login = ({ open, handleClose, setUser, setIsLoggedIn }) => {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [rememberMe, setRememberMe] = useState(false);
   const [error, setError] = useState('');
   const navigate = useNavigate();
 
@@ -17,6 +18,7 @@ const Login = ({ open, handleClose, setUser, setIsLoggedIn }) => {
         body: JSON.stringify({
           username: email,
           password: password,
+          remember_me: rememberMe,
       }),
         credentials: 'include',
       });
@@ -121,6 +123,17 @@ const Login = ({ open, handleClose, setUser, setIsLoggedIn }) => {
             style: { color: '#FFFFFF' } 
           }}
         />
+        <FormControlLabel
+          control={
+            <Checkbox
+              checked={rememberMe}
+              onChange={(e) => setRememberMe(e.target.checked)}
+              sx={{ color: '#FF6B6B', '&.Mui-checked': { color: '#FF6B6B' } }}
+            />
+          }
+          label="Remember me"
+          sx={{ display: 'flex', color: '#FFFFFF' }}
+        />
         {error && <Typography color="red" variant="body2">{error}</Typography>}
         
         <Button 
@@ -0,0 +1,12 @@
+// State-changing API requests must carry the session's CSRF token. The token
+// changes with the session, so it is fetched right before each request.
+export async function csrfHeaders() {
+  const response = await fetch("http://localhost:9696/api/v1/csrf", {
+    credentials: "include",
+  });
+  if (!response.ok) {
+    throw new Error("Failed to fetch the CSRF token");
+  }
+  const data = await response.json();
+  return { "X-CSRF-Token": data.csrf_token };
+}
@@ -9,6 +9,7 @@ import {
 import TwitterIcon from "@mui/icons-material/Twitter";
 import LinkedInIcon from "@mui/icons-material/LinkedIn";
 import CheckCircleIcon from "@mui/icons-material/CheckCircle";
+import { csrfHeaders } from "../utils/csrf";
 
 const VerificationPage = ({user, setUser}) => {
   const [twitterConnected, ] = useState(user?.x_verified);
@@ -40,6 +41,7 @@ const VerificationPage = ({user, setUser}) => {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          ...(await csrfHeaders()),
         },
         body: JSON.stringify({ 
           key : hashnodeApiKey, 